@@ -1,24 +1,48 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/treaz/jenkins-flow/pkg/config"
 	"github.com/treaz/jenkins-flow/pkg/logger"
 	"github.com/treaz/jenkins-flow/pkg/server"
+	"github.com/treaz/jenkins-flow/pkg/settings"
+	"github.com/treaz/jenkins-flow/pkg/version"
 )
 
 func main() {
 	// Define flags
 	port := flag.Int("port", 32567, "Port to run the dashboard server on")
+	bind := flag.String("bind", "127.0.0.1", "Address to listen on; 0.0.0.0 listens on all interfaces")
+	tlsCert := flag.String("tls-cert", "", "Path to a PEM certificate; with -tls-key, serves the dashboard over HTTPS")
+	tlsKey := flag.String("tls-key", "", "Path to the PEM private key for -tls-cert")
 	instancesPath := flag.String("instances", "instances.yaml", "Path to instances configuration file")
 	workflowsDir := flag.String("workflows-dir", "workflows,examples", "Directory containing workflow files")
 	dbPath := flag.String("db-path", "", "Path to SQLite database file (default: ~/.config/jenkins-flow/jenkins-flow.db)")
 	debug := flag.Bool("debug", false, "Enable debug logging")
 	trace := flag.Bool("trace", false, "Enable trace logging (includes HTTP dumps)")
+	color := flag.Bool("color", false, "Color step progress in the console output (ignored when it is not a terminal)")
+	envFile := flag.String("env-file", "", "Path to a .env file for auth_env tokens (default: env_file from settings)")
+	lenient := flag.Bool("lenient", false, "Ignore unknown keys in instances and workflow files instead of rejecting them")
+	preferGlobal := flag.Bool("prefer-global-instances", false, "Let the instances file win over instances defined in workflow files")
+	allowEdit := flag.Bool("allow-workflow-edit", false, "Allow creating and replacing workflow files through the API")
+	apiKey := flag.String("api-key", "", "Require this key on API requests, in addition to api_keys from settings")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma-separated IPs or CIDR ranges of proxies whose X-Forwarded-User header the audit log trusts")
+	shutdownMode := flag.String("shutdown-mode", "graceful", "On SIGINT/SIGTERM, let the running workflow finish its current item (graceful), finish entirely (wait), or stop now (now)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long to wait on shutdown before cancelling the running workflow")
+	jenkinsPoll := flag.Int("jenkins-poll-secs", 0, "Seconds between Jenkins queue and build checks (default: jenkins_poll_secs from settings, else 2 for queue items and 5 for builds)")
+	prPoll := flag.Int("pr-poll-secs", 0, "Default poll_secs of wait_for_pr items (default: pr_poll_secs from settings, else 30)")
 	help := flag.Bool("help", false, "Show help message")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
 
 	flag.Parse()
 
@@ -26,19 +50,91 @@ func main() {
 		printUsage()
 		return
 	}
+	if *showVersion {
+		fmt.Println(version.String())
+		return
+	}
 
-	l := initLogger(*debug, *trace)
-	startServer(*port, *instancesPath, *workflowsDir, *dbPath, l)
+	l := initLogger(*debug, *trace, *color)
+	env := loadEnvFile(*envFile, l)
+	startServer(*port, *bind, *tlsCert, *tlsKey, *instancesPath, *workflowsDir, *dbPath, *lenient, *preferGlobal, *allowEdit, loadAPIKeys(*apiKey, l), *trustedProxies, loadPollDefaults(*jenkinsPoll, *prPoll, l), env, *shutdownMode, *shutdownTimeout, l)
 }
 
-func initLogger(debug, trace bool) *logger.Logger {
+func initLogger(debug, trace, color bool) *logger.Logger {
 	level := logger.Info
 	if trace {
 		level = logger.Trace
 	} else if debug {
 		level = logger.Debug
 	}
-	return logger.New(level)
+	l := logger.New(level)
+	// Only the console is colored; run logs teed from l stay plain.
+	if color && logger.ColorSupported(os.Stderr) {
+		l.SetOutput(logger.NewColorWriter(os.Stderr))
+	}
+	return l
+}
+
+// loadEnvFile returns the environment with the variables of path, or of the
+// env_file setting when path is empty, layered under it for auth_env.
+// Variables already set are kept. It returns nil when there is no file.
+func loadEnvFile(path string, l *logger.Logger) config.EnvLookup {
+	if path == "" {
+		s, err := settings.Load()
+		if err != nil {
+			l.Errorf("Could not read settings: %v", err)
+			return nil
+		}
+		path = s.EnvFile
+	}
+	if path == "" {
+		return nil
+	}
+	env, err := config.LoadEnvFile(path, nil)
+	if err != nil {
+		log.Fatalf("Failed to load env file: %v", err)
+	}
+	l.Infof("Loaded %d variable(s) from %s", len(env.Values), path)
+	return env
+}
+
+// loadAPIKeys returns the API keys the server requires: key, when set, and
+// the api_keys setting. Unreadable settings stop the server rather than
+// leaving the API open.
+func loadAPIKeys(key string, l *logger.Logger) []string {
+	s, err := settings.Load()
+	if err != nil {
+		log.Fatalf("Failed to read api_keys from settings: %v", err)
+	}
+	var keys []string
+	for _, k := range append([]string{key}, s.APIKeys...) {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		l.Infof("API key authentication enabled (%d key(s))", len(keys))
+	}
+	return keys
+}
+
+// loadPollDefaults returns the poll intervals for what workflows leave unset:
+// the flags where they are set, otherwise the settings.
+func loadPollDefaults(jenkinsSecs, prSecs int, l *logger.Logger) config.PollDefaults {
+	p := config.PollDefaults{JenkinsSecs: jenkinsSecs, PRSecs: prSecs}
+	if s, err := settings.Load(); err != nil {
+		l.Errorf("Could not read settings: %v", err)
+	} else {
+		p.JenkinsSecs = cmp.Or(p.JenkinsSecs, s.JenkinsPollSecs)
+		p.PRSecs = cmp.Or(p.PRSecs, s.PRPollSecs)
+	}
+	if p.JenkinsSecs > 0 {
+		l.Infof("Polling Jenkins every %ds", p.JenkinsSecs)
+	}
+	if p.PRSecs > 0 {
+		l.Infof("Polling PRs every %ds unless a wait sets poll_secs", p.PRSecs)
+	}
+	return p
 }
 
 func printUsage() {
@@ -49,25 +145,97 @@ Usage:
 
 Options:
   -port int           Port to run the dashboard server on (default 32567)
+  -bind string        Address to listen on; 0.0.0.0 listens on all interfaces (default "127.0.0.1")
+  -tls-cert string    Path to a PEM certificate; with -tls-key, serves the dashboard over HTTPS
+  -tls-key string     Path to the PEM private key for -tls-cert
   -instances string   Path to instances configuration file (default "instances.yaml")
   -workflows-dir string  Directory containing workflow files (default "workflows,examples")
   -db-path string     Path to SQLite database file (default "~/.config/jenkins-flow/jenkins-flow.db")
+  -env-file string    Path to a .env file for auth_env tokens (default: env_file from settings)
   -debug              Enable debug logging
   -trace              Enable trace logging (includes HTTP dumps)
+  -color              Color step progress in the console output (off when not a terminal or NO_COLOR is set)
+  -lenient            Ignore unknown keys in instances and workflow files
+  -prefer-global-instances  Let the instances file win over workflow file instances
+  -allow-workflow-edit  Allow creating and replacing workflow files through the API
+  -api-key string     Require this key on API requests, in addition to api_keys from settings
+  -trusted-proxies string  IPs or CIDR ranges of proxies whose X-Forwarded-User header the audit log trusts
+  -shutdown-mode string  On SIGINT/SIGTERM: graceful (finish the current item), wait (finish the run) or now (default "graceful")
+  -shutdown-timeout duration  How long to wait on shutdown before cancelling the running workflow (default 30s)
+  -jenkins-poll-secs int  Seconds between Jenkins queue and build checks (default: jenkins_poll_secs from settings, else 2 and 5)
+  -pr-poll-secs int   Default poll_secs of wait_for_pr items (default: pr_poll_secs from settings, else 30)
+  -version            Print version information and exit
   -help               Show this help message
 
 Examples:
   jenkins-flow -port 3000
+  jenkins-flow -bind 0.0.0.0 -tls-cert server.crt -tls-key server.key
   jenkins-flow -instances my-instances.yaml
-  jenkins-flow -db-path /custom/path/db.sqlite`)
+  jenkins-flow -db-path /custom/path/db.sqlite
+  jenkins-flow -env-file ~/.config/jenkins-flow/tokens.env`)
 }
 
-func startServer(port int, instancesPath, workflowsDir, dbPath string, l *logger.Logger) {
+func startServer(port int, bind, tlsCert, tlsKey, instancesPath, workflowsDir, dbPath string, lenient, preferGlobal, allowEdit bool, apiKeys []string, trustedProxies string, pollDefaults config.PollDefaults, env config.EnvLookup, shutdownMode string, shutdownTimeout time.Duration, l *logger.Logger) {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
 	workflowDirsList := strings.Split(workflowsDir, ",")
 	srv := server.NewServer(port, instancesPath, workflowDirsList, dbPath, l)
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	srv.SetLenient(lenient)
+	srv.SetPreferGlobalInstances(preferGlobal)
+	srv.SetAllowWorkflowEdit(allowEdit)
+	srv.SetAPIKeys(apiKeys)
+	srv.SetEnv(env)
+	if err := srv.SetTrustedProxies(strings.Split(trustedProxies, ",")); err != nil {
+		log.Fatalf("Invalid -trusted-proxies: %v", err)
+	}
+	srv.SetBindAddress(bind)
+	if err := srv.SetPollDefaults(pollDefaults); err != nil {
+		log.Fatalf("Invalid poll interval: %v", err)
+	}
+	if tlsCert != "" || tlsKey != "" {
+		if err := srv.SetTLS(tlsCert, tlsKey); err != nil {
+			log.Fatalf("Invalid -tls-cert/-tls-key: %v", err)
+		}
+	}
+	if err := srv.SetShutdownMode(shutdownMode); err != nil {
+		log.Fatalf("Invalid -shutdown-mode: %v", err)
+	}
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		l.Errorf("%v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	served := make(chan error, 1)
+	go func() { served <- srv.Start() }()
+
+	for {
+		select {
+		case err := <-served:
+			if err != nil {
+				log.Fatalf("Server error: %v", err)
+			}
+			return
+		case <-hangups:
+			l.Infof("Received SIGHUP; reloading configuration")
+			if err := srv.Reload(); err != nil {
+				log.Printf("Warning: Reload skipped: %v", err)
+			}
+		case sig := <-signals:
+			// A second signal kills the process without waiting.
+			signal.Stop(signals)
+			l.Infof("Received %s; shutting down (mode %s, timeout %s)", sig, shutdownMode, shutdownTimeout)
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := srv.Stop(ctx)
+			cancel()
+			if err != nil {
+				l.Errorf("Shutdown: %v", err)
+				os.Exit(1)
+			}
+			l.Infof("Shutdown complete")
+			return
+		}
 	}
 }
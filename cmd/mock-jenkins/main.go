@@ -74,7 +74,7 @@ func main() {
 	log.Printf("  Build duration  : %s", buildDuration)
 	log.Printf("  Build result    : %s", buildResult)
 	log.Printf("")
-	log.Printf("Configure instances.yaml:")
+	log.Printf("Configure instances.yaml, or a workflow's own instances block:")
 	log.Printf("  instances:")
 	log.Printf("    mock:")
 	log.Printf("      url: http://localhost:%d", listenPort)
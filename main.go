@@ -13,8 +13,10 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
 	"github.com/wailsapp/wails/v2/pkg/options/mac"
 
+	"github.com/treaz/jenkins-flow/pkg/config"
 	"github.com/treaz/jenkins-flow/pkg/logger"
 	"github.com/treaz/jenkins-flow/pkg/server"
+	"github.com/treaz/jenkins-flow/pkg/settings"
 )
 
 // App is the Wails application struct.
@@ -31,6 +33,18 @@ func main() {
 
 	l := logger.New(logger.Info)
 	srv := server.NewServer(0, instancesPath, workflowDirs, "", l)
+	if s, err := settings.Load(); err != nil {
+		l.Errorf("Could not read settings: %v", err)
+	} else if s.EnvFile != "" {
+		env, err := config.LoadEnvFile(s.EnvFile, nil)
+		if err != nil {
+			log.Fatalf("Failed to load env file: %v", err)
+		}
+		srv.SetEnv(env)
+	}
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		l.Errorf("%v", err)
+	}
 	router := srv.BuildRouter()
 
 	// Get the static subdirectory from embedded files (strip "static/" prefix)
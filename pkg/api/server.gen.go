@@ -6,6 +6,7 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -19,6 +20,45 @@ import (
 	"github.com/oapi-codegen/runtime"
 )
 
+const (
+	ApiKeyAuthScopes = "apiKeyAuth.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// ConfigItemChange defines model for ConfigItemChange.
+type ConfigItemChange struct {
+	Change *string `json:"change,omitempty"`
+
+	// Fields Keys of a changed item whose values differ, other than params
+	Fields *[]string `json:"fields,omitempty"`
+
+	// Index Index in the current file, or in the snapshot for removed items
+	Index   *int                 `json:"index,omitempty"`
+	Name    *string              `json:"name,omitempty"`
+	Params  *[]ConfigParamChange `json:"params,omitempty"`
+	Section *string              `json:"section,omitempty"`
+}
+
+// ConfigParamChange defines model for ConfigParamChange.
+type ConfigParamChange struct {
+	Change *string `json:"change,omitempty"`
+	Name   *string `json:"name,omitempty"`
+	New    *string `json:"new,omitempty"`
+	Old    *string `json:"old,omitempty"`
+
+	// Step Step of a parallel group the param belongs to; empty for the item's own params
+	Step *string `json:"step,omitempty"`
+}
+
+// CreateWorkflowRequest defines model for CreateWorkflowRequest.
+type CreateWorkflowRequest struct {
+	// Content Workflow YAML or JSON to write
+	Content string `json:"content"`
+
+	// Path Path of the new file inside a workflow directory, e.g. workflows/team-a/deploy.yaml; missing folders are created
+	Path string `json:"path"`
+}
+
 // DBPathRequest defines model for DBPathRequest.
 type DBPathRequest struct {
 	Path *string `json:"path,omitempty"`
@@ -29,12 +69,27 @@ type DBPathResponse struct {
 	Path *string `json:"path,omitempty"`
 }
 
+// DeleteWorkflowResponse defines model for DeleteWorkflowResponse.
+type DeleteWorkflowResponse struct {
+	// ArchivedTo Where the file was moved; absent when it was purged
+	ArchivedTo *string `json:"archivedTo,omitempty"`
+
+	// Path Path of the removed workflow file
+	Path string `json:"path"`
+}
+
 // DisabledStep defines model for DisabledStep.
 type DisabledStep struct {
 	ItemIndex *int `json:"itemIndex,omitempty"`
 	StepIndex *int `json:"stepIndex,omitempty"`
 }
 
+// Health defines model for Health.
+type Health struct {
+	// Status Always "ok"
+	Status string `json:"status"`
+}
+
 // LogLevelRequest defines model for LogLevelRequest.
 type LogLevelRequest struct {
 	Level *string `json:"level,omitempty"`
@@ -62,12 +117,21 @@ type PRWaitState struct {
 	HtmlUrl          *string    `json:"htmlUrl,omitempty"`
 	Name             *string    `json:"name,omitempty"`
 	Owner            *string    `json:"owner,omitempty"`
-	PrNumber         *int       `json:"prNumber,omitempty"`
-	Repo             *string    `json:"repo,omitempty"`
-	StartedAt        *time.Time `json:"startedAt,omitempty"`
-	Status           *string    `json:"status,omitempty"`
-	Title            *string    `json:"title,omitempty"`
-	WaitFor          *string    `json:"waitFor,omitempty"`
+
+	// PollMaxSecs Cap on the backed-off interval; set only with poll_backoff
+	PollMaxSecs *int `json:"pollMaxSecs,omitempty"`
+
+	// PollSecs Interval between checks, after defaults are applied
+	PollSecs *int    `json:"pollSecs,omitempty"`
+	PrNumber *int    `json:"prNumber,omitempty"`
+	Repo     *string `json:"repo,omitempty"`
+
+	// SkipReason Why a skipped wait did not run
+	SkipReason *string    `json:"skipReason,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	Status     *string    `json:"status,omitempty"`
+	Title      *string    `json:"title,omitempty"`
+	WaitFor    *string    `json:"waitFor,omitempty"`
 }
 
 // ParallelGroupState defines model for ParallelGroupState.
@@ -77,12 +141,123 @@ type ParallelGroupState struct {
 	Steps  *[]StepState `json:"steps,omitempty"`
 }
 
+// PlanItem defines model for PlanItem.
+type PlanItem struct {
+	// Index Position of the item in the run (workflow items followed by finally items)
+	Index     *int    `json:"index,omitempty"`
+	IsFinally *bool   `json:"isFinally,omitempty"`
+	Kind      *string `json:"kind,omitempty"`
+	Name      *string `json:"name,omitempty"`
+
+	// PrWait Present for wait_for_pr items
+	PrWait  *PRWaitState `json:"prWait,omitempty"`
+	Skipped *bool        `json:"skipped,omitempty"`
+
+	// Steps Steps with effective instances and params; one for single steps and GitHub statuses
+	Steps *[]PlanStep `json:"steps,omitempty"`
+}
+
+// PlanStep defines model for PlanStep.
+type PlanStep struct {
+	Instance    *string `json:"instance,omitempty"`
+	InstanceUrl *string `json:"instanceUrl,omitempty"`
+	Job         *string `json:"job,omitempty"`
+	Name        *string `json:"name,omitempty"`
+
+	// Params Effective job parameters; ${steps.<id>.<field>} references are resolved at run time
+	Params  *map[string]string `json:"params,omitempty"`
+	Skipped *bool              `json:"skipped,omitempty"`
+}
+
+// RerunResponse defines model for RerunResponse.
+type RerunResponse struct {
+	// RerunOf ID of the run that was rerun
+	RerunOf int64 `json:"rerunOf"`
+
+	// RunId ID of the new run
+	RunId int64 `json:"runId"`
+
+	// Warnings Differences between the snapshot and the current workflow file, which the rerun does not pick up
+	Warnings *[]string `json:"warnings,omitempty"`
+}
+
+// ResetResponse defines model for ResetResponse.
+type ResetResponse struct {
+	// Interrupted Number of runs recorded as running that were marked interrupted
+	Interrupted int `json:"interrupted"`
+}
+
+// RunArtifact defines model for RunArtifact.
+type RunArtifact struct {
+	FileName  string `json:"file_name"`
+	ItemIndex int    `json:"item_index"`
+
+	// RelativePath Path inside the build's archive
+	RelativePath string `json:"relative_path"`
+
+	// StepIndex Position inside a parallel group (0 for single steps)
+	StepIndex int    `json:"step_index"`
+	StepName  string `json:"step_name"`
+
+	// Url Jenkins URL to download the artifact from
+	Url string `json:"url"`
+}
+
+// RunConfigDiff defines model for RunConfigDiff.
+type RunConfigDiff struct {
+	// Identical True when the current file is byte for byte the snapshot
+	Identical *bool `json:"identical,omitempty"`
+
+	// Items Added, removed and changed items; items that only moved are not listed
+	Items *[]ConfigItemChange `json:"items,omitempty"`
+	RunId *int                `json:"runId,omitempty"`
+
+	// Unified Unified diff from the snapshot to the current file; empty when identical
+	Unified      *string `json:"unified,omitempty"`
+	WorkflowPath *string `json:"workflowPath,omitempty"`
+}
+
 // RunRequest defines model for RunRequest.
 type RunRequest struct {
-	DisabledSteps   *[]DisabledStep    `json:"disabledSteps,omitempty"`
-	Inputs          *map[string]string `json:"inputs,omitempty"`
-	PrWaitOverrides *[]PRWaitOverride  `json:"prWaitOverrides,omitempty"`
-	Workflow        *string            `json:"workflow,omitempty"`
+	// Description Free-form reason for the run, shown in the dashboard and notifications
+	Description   *string         `json:"description,omitempty"`
+	DisabledSteps *[]DisabledStep `json:"disabledSteps,omitempty"`
+
+	// Initiator Who or what started the run, recorded in the history
+	Initiator *string            `json:"initiator,omitempty"`
+	Inputs    *map[string]string `json:"inputs,omitempty"`
+
+	// Labels Labels for filtering the run history
+	Labels          *[]string         `json:"labels,omitempty"`
+	PrWaitOverrides *[]PRWaitOverride `json:"prWaitOverrides,omitempty"`
+
+	// Profile Profile selecting instance aliases from instances.yaml and the overlay from the workflow's profiles section; empty uses the defaults
+	Profile *string `json:"profile,omitempty"`
+
+	// VerifyJobs Check that every step's job exists before starting, as with verify_jobs in the workflow file
+	VerifyJobs *bool `json:"verifyJobs,omitempty"`
+
+	// VerifyParams Also check each step's params against the parameters its job declares, as with verify_params in the workflow file
+	VerifyParams *bool   `json:"verifyParams,omitempty"`
+	Workflow     *string `json:"workflow,omitempty"`
+}
+
+// RunStep defines model for RunStep.
+type RunStep struct {
+	BuildNumber *int    `json:"build_number,omitempty"`
+	BuildUrl    *string `json:"build_url,omitempty"`
+	ItemIndex   *int    `json:"item_index,omitempty"`
+
+	// Params Params the step was triggered with, after substitution
+	Params *map[string]string `json:"params,omitempty"`
+	Result *string            `json:"result,omitempty"`
+
+	// SkipReason Why a skipped step did not run
+	SkipReason *string `json:"skip_reason,omitempty"`
+
+	// StepIndex Position inside a parallel group (0 for single steps)
+	StepIndex *int    `json:"step_index,omitempty"`
+	StepName  *string `json:"step_name,omitempty"`
 }
 
 // StatusResponse defines model for StatusResponse.
@@ -94,54 +269,277 @@ type StatusResponse struct {
 // StepState defines model for StepState.
 type StepState struct {
 	// BuildNumber Jenkins build number captured after the job completes
-	BuildNumber *int    `json:"buildNumber,omitempty"`
-	BuildUrl    *string `json:"buildUrl,omitempty"`
-	Error       *string `json:"error,omitempty"`
-	Instance    *string `json:"instance,omitempty"`
-	Job         *string `json:"job,omitempty"`
-	Name        *string `json:"name,omitempty"`
+	BuildNumber *int       `json:"buildNumber,omitempty"`
+	BuildUrl    *string    `json:"buildUrl,omitempty"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	Instance    *string    `json:"instance,omitempty"`
+	Job         *string    `json:"job,omitempty"`
+	Name        *string    `json:"name,omitempty"`
+
+	// Output Combined stdout and stderr of a command item, truncated to the last 64 KiB
+	Output *string `json:"output,omitempty"`
+
+	// Params Params the step was triggered with, after substitution
+	Params *map[string]string `json:"params,omitempty"`
+
+	// QueueStatus Jenkins queue flag while the build waits in the queue; absent once it starts
+	QueueStatus *string `json:"queueStatus,omitempty"`
 	Result      *string `json:"result,omitempty"`
-	Status      *string `json:"status,omitempty"`
+
+	// SkipReason Why a skipped step did not run
+	SkipReason *string `json:"skipReason,omitempty"`
+
+	// Stage Label from the workflow file's stage key for grouping steps; absent when unset
+	Stage     *string    `json:"stage,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	Status    *string    `json:"status,omitempty"`
+
+	// Tests JUnit counts from the build's test report; absent when the build published none
+	Tests *TestResults `json:"tests,omitempty"`
 
 	// UsedInputs Workflow inputs referenced by this step's params (key -> resolved value)
 	UsedInputs *map[string]string `json:"usedInputs,omitempty"`
+
+	// Wait Set when the step does not wait for its build to finish; it succeeds once the build is queued or started
+	Wait *string `json:"wait,omitempty"`
+}
+
+// TestResults JUnit counts from the build's test report; absent when the build published none
+type TestResults struct {
+	Failed  *int `json:"failed,omitempty"`
+	Passed  *int `json:"passed,omitempty"`
+	Skipped *int `json:"skipped,omitempty"`
+	Total   *int `json:"total,omitempty"`
+}
+
+// TriggerRequest defines model for TriggerRequest.
+type TriggerRequest struct {
+	// Instance Instance or alias from instances.yaml
+	Instance string `json:"instance"`
+
+	// Job Job path, e.g. /job/folder/job/name
+	Job string `json:"job"`
+
+	// Params Build parameters, merged over the instance's default_params
+	Params *map[string]string `json:"params,omitempty"`
+
+	// Profile Profile selecting instance aliases from instances.yaml; empty uses the defaults
+	Profile *string `json:"profile,omitempty"`
+
+	// Wait How far to follow the build before responding, as with a step's wait; defaults to started
+	Wait *string `json:"wait,omitempty"`
+}
+
+// TriggerResult defines model for TriggerResult.
+type TriggerResult struct {
+	// BuildNumber Set once the build has completed
+	BuildNumber *int `json:"buildNumber,omitempty"`
+
+	// BuildUrl Absent when not waiting for the build to start
+	BuildUrl *string `json:"buildUrl,omitempty"`
+	QueueUrl *string `json:"queueUrl,omitempty"`
+
+	// Result The build's result, or QUEUED or STARTED when not waiting for it to finish
+	Result *string `json:"result,omitempty"`
+
+	// Tests JUnit counts from the build's test report; absent when the build published none
+	Tests *TestResults `json:"tests,omitempty"`
+}
+
+// ValidateRequest defines model for ValidateRequest.
+type ValidateRequest struct {
+	// Content Raw workflow YAML to validate instead of the file; steps naming instances the server does not define are warnings, not errors
+	Content *string `json:"content,omitempty"`
+
+	// Path Workflow file to validate; with content, only names the content in issues and anchors its relative paths
+	Path *string `json:"path,omitempty"`
+
+	// Profile Profile to validate with, as for a run
+	Profile *string `json:"profile,omitempty"`
+}
+
+// ValidationIssue defines model for ValidationIssue.
+type ValidationIssue struct {
+	// File File the issue is in; empty when unknown
+	File *string `json:"file,omitempty"`
+
+	// Line Line the issue is on; 0 when unknown
+	Line    *int   `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationResult defines model for ValidationResult.
+type ValidationResult struct {
+	// Errors Problems that keep the workflow from loading; validation stops at the first, except that every unset environment variable is listed
+	Errors []ValidationIssue `json:"errors"`
+
+	// Valid Whether the workflow loads; warnings do not make it invalid
+	Valid    bool              `json:"valid"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// VersionInfo defines model for VersionInfo.
+type VersionInfo struct {
+	// BuildDate Build timestamp, or empty when not set
+	BuildDate *string `json:"buildDate,omitempty"`
+
+	// Commit Git commit the binary was built from, or empty when not set
+	Commit *string `json:"commit,omitempty"`
+
+	// Version Release version, "dev" for local builds
+	Version *string `json:"version,omitempty"`
 }
 
 // WorkflowInfo defines model for WorkflowInfo.
 type WorkflowInfo struct {
-	Error *string `json:"error,omitempty"`
-	Name  *string `json:"name,omitempty"`
-	Path  *string `json:"path,omitempty"`
-	Valid *bool   `json:"valid,omitempty"`
+	// Description Optional one-line summary from the workflow file; empty when not set
+	Description *string `json:"description,omitempty"`
+
+	// DisplayPath Path relative to the parent of the workflow directory it was found in, e.g. workflows/team-a/deploy.yaml
+	DisplayPath *string `json:"displayPath,omitempty"`
+	Error       *string `json:"error,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	Path        *string `json:"path,omitempty"`
+
+	// Schema Schema version declared by the workflow file; omitted for legacy files without one
+	Schema *int `json:"schema,omitempty"`
+
+	// Tags Tags declared in the workflow file, for grouping and filtering
+	Tags  *[]string `json:"tags,omitempty"`
+	Valid *bool     `json:"valid,omitempty"`
 }
 
 // WorkflowItemState defines model for WorkflowItemState.
 type WorkflowItemState struct {
-	IsPRWait   *bool               `json:"isPRWait,omitempty"`
-	IsParallel *bool               `json:"isParallel,omitempty"`
-	Parallel   *ParallelGroupState `json:"parallel,omitempty"`
-	PrWait     *PRWaitState        `json:"prWait,omitempty"`
-	Step       *StepState          `json:"step,omitempty"`
+	// IsFinally True for items from the workflow's finally section, which run even if the workflow fails
+	IsFinally  *bool `json:"isFinally,omitempty"`
+	IsPRWait   *bool `json:"isPRWait,omitempty"`
+	IsParallel *bool `json:"isParallel,omitempty"`
+
+	// Name Stable, unique item name (step, parallel group, or PR wait name); usable instead of the item index
+	Name     *string             `json:"name,omitempty"`
+	Parallel *ParallelGroupState `json:"parallel,omitempty"`
+	PrWait   *PRWaitState        `json:"prWait,omitempty"`
+	Step     *StepState          `json:"step,omitempty"`
+}
+
+// WorkflowPlan defines model for WorkflowPlan.
+type WorkflowPlan struct {
+	Inputs   *map[string]string `json:"inputs,omitempty"`
+	Items    *[]PlanItem        `json:"items,omitempty"`
+	Workflow *string            `json:"workflow,omitempty"`
 }
 
 // WorkflowRun defines model for WorkflowRun.
 type WorkflowRun struct {
+	// Attempt Attempt the run is on or ended on, from 1; above 1 when the workflow was retried
+	Attempt        *int               `json:"attempt,omitempty"`
 	ConfigSnapshot *string            `json:"config_snapshot,omitempty"`
+	Description    *string            `json:"description,omitempty"`
 	EndTime        *time.Time         `json:"end_time,omitempty"`
+	FinalState     *WorkflowState     `json:"final_state,omitempty"`
 	Id             *int64             `json:"id,omitempty"`
+	Initiator      *string            `json:"initiator,omitempty"`
 	Inputs         *map[string]string `json:"inputs,omitempty"`
-	StartTime      *time.Time         `json:"start_time,omitempty"`
-	Status         *string            `json:"status,omitempty"`
-	WorkflowName   *string            `json:"workflow_name,omitempty"`
-	WorkflowPath   *string            `json:"workflow_path,omitempty"`
+	Labels         *[]string          `json:"labels,omitempty"`
+
+	// Profile Instance profile the run was started with
+	Profile *string `json:"profile,omitempty"`
+
+	// RerunOf ID of the run this run reran; absent for a fresh run
+	RerunOf *int64 `json:"rerun_of,omitempty"`
+
+	// SkipPrCheck Whether PR checks were skipped for the run
+	SkipPrCheck *bool      `json:"skip_pr_check,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	Status      *string    `json:"status,omitempty"`
+
+	// Steps Recorded outcome of each step; only included when fetching a single run
+	Steps *[]RunStep `json:"steps,omitempty"`
+
+	// StopMode How the run was stopped (graceful or now); empty if it was not stopped
+	StopMode     *string `json:"stop_mode,omitempty"`
+	WorkflowName *string `json:"workflow_name,omitempty"`
+	WorkflowPath *string `json:"workflow_path,omitempty"`
+}
+
+// WorkflowSettings Effective settings after merging the defaults, instances and workflow files; set on workflow definitions only
+type WorkflowSettings struct {
+	// DefaultsFile Defaults file merged beneath the other two; omitted when there was none
+	DefaultsFile *string `json:"defaultsFile,omitempty"`
+
+	// GithubAuth Where the GitHub credentials come from, such as "auth_env GITHUB_TOKEN"; omitted without a github block
+	GithubAuth *string `json:"githubAuth,omitempty"`
+
+	// Instances URL of each instance the workflow's steps use
+	Instances     *map[string]string `json:"instances,omitempty"`
+	SlackChannel  *string            `json:"slackChannel,omitempty"`
+	SlackUsername *string            `json:"slackUsername,omitempty"`
+
+	// SlackWebhook Webhook for the completion notification, with its path masked
+	SlackWebhook *string `json:"slackWebhook,omitempty"`
 }
 
 // WorkflowState defines model for WorkflowState.
 type WorkflowState struct {
-	Inputs *map[string]string   `json:"inputs,omitempty"`
-	Items  *[]WorkflowItemState `json:"items,omitempty"`
-	Name   *string              `json:"name,omitempty"`
-	Status *string              `json:"status,omitempty"`
+	// Attempt Attempt of a workflow with retries, set from the first retry on
+	Attempt *int `json:"attempt,omitempty"`
+
+	// CompletedItems Items that have finished, whatever the outcome; a parallel group once all of its steps have
+	CompletedItems *int `json:"completedItems,omitempty"`
+
+	// CompletedSteps Steps that have finished, whatever the outcome
+	CompletedSteps *int       `json:"completedSteps,omitempty"`
+	Description    *string    `json:"description,omitempty"`
+	EndedAt        *time.Time `json:"endedAt,omitempty"`
+
+	// Error Why the run failed or stopped
+	Error *string `json:"error,omitempty"`
+
+	// ErrorKind What made a failed run fail; omitted when the error is not one of these
+	ErrorKind *string `json:"errorKind,omitempty"`
+
+	// EstimatedDuration Median duration in seconds of recent successful runs; omitted without history
+	EstimatedDuration *int64 `json:"estimatedDuration,omitempty"`
+
+	// FailedItem Index of the item that failed, when the error names one
+	FailedItem *int `json:"failedItem,omitempty"`
+
+	// FailedItems Completed items that failed or were aborted
+	FailedItems *int `json:"failedItems,omitempty"`
+
+	// FailedSteps Completed steps that failed or were aborted
+	FailedSteps *int                 `json:"failedSteps,omitempty"`
+	Initiator   *string              `json:"initiator,omitempty"`
+	Inputs      *map[string]string   `json:"inputs,omitempty"`
+	Items       *[]WorkflowItemState `json:"items,omitempty"`
+	Labels      *[]string            `json:"labels,omitempty"`
+	Name        *string              `json:"name,omitempty"`
+
+	// Settings Effective settings after merging the defaults, instances and workflow files; set on workflow definitions only
+	Settings  *WorkflowSettings `json:"settings,omitempty"`
+	StartedAt *time.Time        `json:"startedAt,omitempty"`
+	Status    *string           `json:"status,omitempty"`
+
+	// StopMode Set once a stop was requested (graceful or now)
+	StopMode *string `json:"stopMode,omitempty"`
+
+	// TotalItems Number of items; a parallel group counts as one
+	TotalItems *int `json:"totalItems,omitempty"`
+
+	// TotalSteps Number of steps, counting each step of a parallel group and each PR wait
+	TotalSteps *int `json:"totalSteps,omitempty"`
+}
+
+// WorkflowStats defines model for WorkflowStats.
+type WorkflowStats struct {
+	// MedianDuration Median duration in seconds; omitted when there are no successful runs
+	MedianDuration *int64 `json:"medianDuration,omitempty"`
+
+	// SampleSize Number of recent successful runs the estimate is based on
+	SampleSize   int    `json:"sampleSize"`
+	WorkflowPath string `json:"workflowPath"`
 }
 
 // GetHistoryParams defines parameters for GetHistory.
@@ -155,21 +553,132 @@ type GetHistoryParams struct {
 	// WorkflowPath Filter by workflow path
 	WorkflowPath *string `form:"workflow_path,omitempty" json:"workflow_path,omitempty"`
 
-	// Status Filter by status (running, success, failed, stopped)
+	// Status Filter by status (running, success, failed, stopped, interrupted)
+	Status *string `form:"status,omitempty" json:"status,omitempty"`
+
+	// Label Only return runs carrying this label
+	Label *string `form:"label,omitempty" json:"label,omitempty"`
+
+	// From Only return runs started at or after this time (RFC 3339)
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Only return runs started before this time (RFC 3339)
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Sort Sort order (start_time_desc, start_time_asc, duration_desc, duration_asc)
+	Sort *string `form:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// ListRunsParams defines parameters for ListRuns.
+type ListRunsParams struct {
+	// Limit Maximum number of results to return
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset Offset for pagination
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+
+	// WorkflowPath Filter by workflow path
+	WorkflowPath *string `form:"workflow_path,omitempty" json:"workflow_path,omitempty"`
+
+	// Status Filter by status (running, success, failed, stopped, interrupted)
+	Status *string `form:"status,omitempty" json:"status,omitempty"`
+
+	// Label Only return runs carrying this label
+	Label *string `form:"label,omitempty" json:"label,omitempty"`
+
+	// From Only return runs started at or after this time (RFC 3339)
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Only return runs started before this time (RFC 3339)
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Sort Sort order (start_time_desc, start_time_asc, duration_desc, duration_asc)
+	Sort *string `form:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// ExportRunsParams defines parameters for ExportRuns.
+type ExportRunsParams struct {
+	// Format Export format (csv or json)
+	Format *string `form:"format,omitempty" json:"format,omitempty"`
+
+	// WorkflowPath Filter by workflow path
+	WorkflowPath *string `form:"workflow_path,omitempty" json:"workflow_path,omitempty"`
+
+	// Status Filter by status (running, success, failed, stopped, interrupted)
 	Status *string `form:"status,omitempty" json:"status,omitempty"`
+
+	// Label Only export runs carrying this label
+	Label *string `form:"label,omitempty" json:"label,omitempty"`
+
+	// From Only export runs started at or after this time (RFC 3339)
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Only export runs started before this time (RFC 3339)
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Sort Sort order (start_time_desc, start_time_asc, duration_desc, duration_asc)
+	Sort *string `form:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// StopRunParams defines parameters for StopRun.
+type StopRunParams struct {
+	// Mode now cancels running builds immediately; graceful lets the current item finish and skips the rest
+	Mode *string `form:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// StopWorkflowParams defines parameters for StopWorkflow.
+type StopWorkflowParams struct {
+	// Mode now cancels running builds immediately; graceful lets the current item finish and skips the rest
+	Mode *string `form:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// ListWorkflowsParams defines parameters for ListWorkflows.
+type ListWorkflowsParams struct {
+	// Tag Only list workflows carrying this tag
+	Tag *string `form:"tag,omitempty" json:"tag,omitempty"`
+}
+
+// DeleteWorkflowParams defines parameters for DeleteWorkflow.
+type DeleteWorkflowParams struct {
+	// Purge Delete the file instead of moving it to the archive folder
+	Purge *bool `form:"purge,omitempty" json:"purge,omitempty"`
+}
+
+// GetWorkflowGraphParams defines parameters for GetWorkflowGraph.
+type GetWorkflowGraphParams struct {
+	// Format Diagram format (mermaid or dot)
+	Format *string `form:"format,omitempty" json:"format,omitempty"`
 }
 
 // RunWorkflowJSONRequestBody defines body for RunWorkflow for application/json ContentType.
 type RunWorkflowJSONRequestBody = RunRequest
 
+// PlanWorkflowJSONRequestBody defines body for PlanWorkflow for application/json ContentType.
+type PlanWorkflowJSONRequestBody = RunRequest
+
 // SetDBPathJSONRequestBody defines body for SetDBPath for application/json ContentType.
 type SetDBPathJSONRequestBody = DBPathRequest
 
 // SetLogLevelJSONRequestBody defines body for SetLogLevel for application/json ContentType.
 type SetLogLevelJSONRequestBody = LogLevelRequest
 
+// TriggerJobJSONRequestBody defines body for TriggerJob for application/json ContentType.
+type TriggerJobJSONRequestBody = TriggerRequest
+
+// ValidateWorkflowJSONRequestBody defines body for ValidateWorkflow for application/json ContentType.
+type ValidateWorkflowJSONRequestBody = ValidateRequest
+
+// CreateWorkflowJSONRequestBody defines body for CreateWorkflow for application/json ContentType.
+type CreateWorkflowJSONRequestBody = CreateWorkflowRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Force-reset a stuck workflow state
+	// (POST /api/admin/reset)
+	AdminReset(w http.ResponseWriter, r *http.Request)
+	// Check that the server is up
+	// (GET /api/health)
+	GetHealth(w http.ResponseWriter, r *http.Request)
 	// List workflow run history
 	// (GET /api/history)
 	GetHistory(w http.ResponseWriter, r *http.Request, params GetHistoryParams)
@@ -179,6 +688,36 @@ type ServerInterface interface {
 	// Start a workflow
 	// (POST /api/run)
 	RunWorkflow(w http.ResponseWriter, r *http.Request)
+	// List workflow runs
+	// (GET /api/runs)
+	ListRuns(w http.ResponseWriter, r *http.Request, params ListRunsParams)
+	// Export run history as CSV or JSON
+	// (GET /api/runs/export)
+	ExportRuns(w http.ResponseWriter, r *http.Request, params ExportRunsParams)
+	// Get a workflow run
+	// (GET /api/runs/{id})
+	GetRun(w http.ResponseWriter, r *http.Request, id int)
+	// List the build artifacts of a workflow run
+	// (GET /api/runs/{id}/artifacts)
+	GetRunArtifacts(w http.ResponseWriter, r *http.Request, id int)
+	// Download the workflow config snapshot of a run
+	// (GET /api/runs/{id}/config)
+	GetRunConfig(w http.ResponseWriter, r *http.Request, id int)
+	// Compare a run's config snapshot with the current workflow file
+	// (GET /api/runs/{id}/diff)
+	GetRunDiff(w http.ResponseWriter, r *http.Request, id int)
+	// Get the captured log of a workflow run
+	// (GET /api/runs/{id}/log)
+	GetRunLog(w http.ResponseWriter, r *http.Request, id int)
+	// Rerun a historical run with its original config and inputs
+	// (POST /api/runs/{id}/rerun)
+	RerunRun(w http.ResponseWriter, r *http.Request, id int)
+	// Get the detailed state of a workflow run
+	// (GET /api/runs/{id}/status)
+	GetRunStatus(w http.ResponseWriter, r *http.Request, id int)
+	// Stop a running workflow run
+	// (POST /api/runs/{id}/stop)
+	StopRun(w http.ResponseWriter, r *http.Request, id int, params StopRunParams)
 	// Get current database path
 	// (GET /api/settings/db-path)
 	GetDBPath(w http.ResponseWriter, r *http.Request)
@@ -194,21 +733,75 @@ type ServerInterface interface {
 	// Get current workflow status
 	// (GET /api/status)
 	GetStatus(w http.ResponseWriter, r *http.Request)
+	// Clear the state of the last completed workflow run
+	// (DELETE /api/status/last)
+	ClearLastStatus(w http.ResponseWriter, r *http.Request)
+	// Download the current workflow state as a JSON file
+	// (GET /api/status/export)
+	ExportStatus(w http.ResponseWriter, r *http.Request)
+	// Get the state of the last completed workflow run
+	// (GET /api/status/last)
+	GetLastStatus(w http.ResponseWriter, r *http.Request)
+	// Get the log of the current workflow run
+	// (GET /api/status/log)
+	GetStatusLog(w http.ResponseWriter, r *http.Request)
 	// Stop the running workflow
 	// (POST /api/stop)
-	StopWorkflow(w http.ResponseWriter, r *http.Request)
+	StopWorkflow(w http.ResponseWriter, r *http.Request, params StopWorkflowParams)
+	// Trigger a single Jenkins job outside of any workflow
+	// (POST /api/trigger)
+	TriggerJob(w http.ResponseWriter, r *http.Request)
+	// Get build version information
+	// (GET /api/version)
+	GetVersion(w http.ResponseWriter, r *http.Request)
 	// List available workflows
 	// (GET /api/workflows)
-	ListWorkflows(w http.ResponseWriter, r *http.Request)
+	ListWorkflows(w http.ResponseWriter, r *http.Request, params ListWorkflowsParams)
+	// Create a workflow file
+	// (POST /api/workflows)
+	CreateWorkflow(w http.ResponseWriter, r *http.Request)
+	// Preview the resolved execution plan for a run request
+	// (POST /api/workflows/plan)
+	PlanWorkflow(w http.ResponseWriter, r *http.Request)
+	// Rescan workflow files, discarding cached parses
+	// (POST /api/workflows/refresh)
+	RefreshWorkflows(w http.ResponseWriter, r *http.Request)
+	// Validate a workflow file or unsaved workflow content
+	// (POST /api/workflows/validate)
+	ValidateWorkflow(w http.ResponseWriter, r *http.Request)
+	// Archive or delete a workflow file
+	// (DELETE /api/workflows/{name})
+	DeleteWorkflow(w http.ResponseWriter, r *http.Request, name string, params DeleteWorkflowParams)
+	// Replace a workflow file
+	// (PUT /api/workflows/{name})
+	UpdateWorkflow(w http.ResponseWriter, r *http.Request, name string)
 	// Get workflow definition
 	// (GET /api/workflows/{name}/definition)
 	GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, name string)
+	// Render a workflow as a Mermaid or Graphviz DOT diagram
+	// (GET /api/workflows/{name}/graph)
+	GetWorkflowGraph(w http.ResponseWriter, r *http.Request, name string, params GetWorkflowGraphParams)
+	// Get duration statistics from a workflow's run history
+	// (GET /api/workflows/{name}/stats)
+	GetWorkflowStats(w http.ResponseWriter, r *http.Request, name string)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
 
 type Unimplemented struct{}
 
+// Force-reset a stuck workflow state
+// (POST /api/admin/reset)
+func (_ Unimplemented) AdminReset(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Check that the server is up
+// (GET /api/health)
+func (_ Unimplemented) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // List workflow run history
 // (GET /api/history)
 func (_ Unimplemented) GetHistory(w http.ResponseWriter, r *http.Request, params GetHistoryParams) {
@@ -227,6 +820,66 @@ func (_ Unimplemented) RunWorkflow(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// List workflow runs
+// (GET /api/runs)
+func (_ Unimplemented) ListRuns(w http.ResponseWriter, r *http.Request, params ListRunsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Export run history as CSV or JSON
+// (GET /api/runs/export)
+func (_ Unimplemented) ExportRuns(w http.ResponseWriter, r *http.Request, params ExportRunsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get a workflow run
+// (GET /api/runs/{id})
+func (_ Unimplemented) GetRun(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// List the build artifacts of a workflow run
+// (GET /api/runs/{id}/artifacts)
+func (_ Unimplemented) GetRunArtifacts(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Download the workflow config snapshot of a run
+// (GET /api/runs/{id}/config)
+func (_ Unimplemented) GetRunConfig(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Compare a run's config snapshot with the current workflow file
+// (GET /api/runs/{id}/diff)
+func (_ Unimplemented) GetRunDiff(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get the captured log of a workflow run
+// (GET /api/runs/{id}/log)
+func (_ Unimplemented) GetRunLog(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Rerun a historical run with its original config and inputs
+// (POST /api/runs/{id}/rerun)
+func (_ Unimplemented) RerunRun(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get the detailed state of a workflow run
+// (GET /api/runs/{id}/status)
+func (_ Unimplemented) GetRunStatus(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Stop a running workflow run
+// (POST /api/runs/{id}/stop)
+func (_ Unimplemented) StopRun(w http.ResponseWriter, r *http.Request, id int, params StopRunParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Get current database path
 // (GET /api/settings/db-path)
 func (_ Unimplemented) GetDBPath(w http.ResponseWriter, r *http.Request) {
@@ -257,75 +910,1016 @@ func (_ Unimplemented) GetStatus(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Clear the state of the last completed workflow run
+// (DELETE /api/status/last)
+func (_ Unimplemented) ClearLastStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Download the current workflow state as a JSON file
+// (GET /api/status/export)
+func (_ Unimplemented) ExportStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get the state of the last completed workflow run
+// (GET /api/status/last)
+func (_ Unimplemented) GetLastStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get the log of the current workflow run
+// (GET /api/status/log)
+func (_ Unimplemented) GetStatusLog(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Stop the running workflow
 // (POST /api/stop)
-func (_ Unimplemented) StopWorkflow(w http.ResponseWriter, r *http.Request) {
+func (_ Unimplemented) StopWorkflow(w http.ResponseWriter, r *http.Request, params StopWorkflowParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Trigger a single Jenkins job outside of any workflow
+// (POST /api/trigger)
+func (_ Unimplemented) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get build version information
+// (GET /api/version)
+func (_ Unimplemented) GetVersion(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// List available workflows
+// (GET /api/workflows)
+func (_ Unimplemented) ListWorkflows(w http.ResponseWriter, r *http.Request, params ListWorkflowsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Create a workflow file
+// (POST /api/workflows)
+func (_ Unimplemented) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Preview the resolved execution plan for a run request
+// (POST /api/workflows/plan)
+func (_ Unimplemented) PlanWorkflow(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Rescan workflow files, discarding cached parses
+// (POST /api/workflows/refresh)
+func (_ Unimplemented) RefreshWorkflows(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Validate a workflow file or unsaved workflow content
+// (POST /api/workflows/validate)
+func (_ Unimplemented) ValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Archive or delete a workflow file
+// (DELETE /api/workflows/{name})
+func (_ Unimplemented) DeleteWorkflow(w http.ResponseWriter, r *http.Request, name string, params DeleteWorkflowParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Replace a workflow file
+// (PUT /api/workflows/{name})
+func (_ Unimplemented) UpdateWorkflow(w http.ResponseWriter, r *http.Request, name string) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-// List available workflows
-// (GET /api/workflows)
-func (_ Unimplemented) ListWorkflows(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+// Get workflow definition
+// (GET /api/workflows/{name}/definition)
+func (_ Unimplemented) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, name string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Render a workflow as a Mermaid or Graphviz DOT diagram
+// (GET /api/workflows/{name}/graph)
+func (_ Unimplemented) GetWorkflowGraph(w http.ResponseWriter, r *http.Request, name string, params GetWorkflowGraphParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get duration statistics from a workflow's run history
+// (GET /api/workflows/{name}/stats)
+func (_ Unimplemented) GetWorkflowStats(w http.ResponseWriter, r *http.Request, name string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// AdminReset operation middleware
+func (siw *ServerInterfaceWrapper) AdminReset(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminReset(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetHealth operation middleware
+func (siw *ServerInterfaceWrapper) GetHealth(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetHealth(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetHistory operation middleware
+func (siw *ServerInterfaceWrapper) GetHistory(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetHistoryParams
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", r.URL.Query(), &params.Offset)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "offset", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "workflow_path" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "workflow_path", r.URL.Query(), &params.WorkflowPath)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workflow_path", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "label" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "label", r.URL.Query(), &params.Label)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "label", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sort", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetHistory(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetHistoryRun operation middleware
+func (siw *ServerInterfaceWrapper) GetHistoryRun(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetHistoryRun(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RunWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) RunWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RunWorkflow(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListRuns operation middleware
+func (siw *ServerInterfaceWrapper) ListRuns(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListRunsParams
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", r.URL.Query(), &params.Offset)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "offset", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "workflow_path" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "workflow_path", r.URL.Query(), &params.WorkflowPath)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workflow_path", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "label" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "label", r.URL.Query(), &params.Label)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "label", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sort", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListRuns(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExportRuns operation middleware
+func (siw *ServerInterfaceWrapper) ExportRuns(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportRunsParams
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "format", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "workflow_path" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "workflow_path", r.URL.Query(), &params.WorkflowPath)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workflow_path", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "label" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "label", r.URL.Query(), &params.Label)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "label", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sort", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportRuns(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRun operation middleware
+func (siw *ServerInterfaceWrapper) GetRun(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRun(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRunArtifacts operation middleware
+func (siw *ServerInterfaceWrapper) GetRunArtifacts(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRunArtifacts(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRunConfig operation middleware
+func (siw *ServerInterfaceWrapper) GetRunConfig(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRunConfig(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRunDiff operation middleware
+func (siw *ServerInterfaceWrapper) GetRunDiff(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRunDiff(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRunLog operation middleware
+func (siw *ServerInterfaceWrapper) GetRunLog(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRunLog(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RerunRun operation middleware
+func (siw *ServerInterfaceWrapper) RerunRun(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RerunRun(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRunStatus operation middleware
+func (siw *ServerInterfaceWrapper) GetRunStatus(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRunStatus(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// StopRun operation middleware
+func (siw *ServerInterfaceWrapper) StopRun(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params StopRunParams
+
+	// ------------- Optional query parameter "mode" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "mode", r.URL.Query(), &params.Mode)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "mode", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StopRun(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetDBPath operation middleware
+func (siw *ServerInterfaceWrapper) GetDBPath(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDBPath(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// SetDBPath operation middleware
+func (siw *ServerInterfaceWrapper) SetDBPath(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SetDBPath(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetLogLevel operation middleware
+func (siw *ServerInterfaceWrapper) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetLogLevel(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// SetLogLevel operation middleware
+func (siw *ServerInterfaceWrapper) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SetLogLevel(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetStatus operation middleware
+func (siw *ServerInterfaceWrapper) GetStatus(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ClearLastStatus operation middleware
+func (siw *ServerInterfaceWrapper) ClearLastStatus(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ClearLastStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExportStatus operation middleware
+func (siw *ServerInterfaceWrapper) ExportStatus(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetLastStatus operation middleware
+func (siw *ServerInterfaceWrapper) GetLastStatus(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetLastStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetStatusLog operation middleware
+func (siw *ServerInterfaceWrapper) GetStatusLog(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetStatusLog(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
 }
 
-// Get workflow definition
-// (GET /api/workflows/{name}/definition)
-func (_ Unimplemented) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, name string) {
-	w.WriteHeader(http.StatusNotImplemented)
-}
+// StopWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) StopWorkflow(w http.ResponseWriter, r *http.Request) {
 
-// ServerInterfaceWrapper converts contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler            ServerInterface
-	HandlerMiddlewares []MiddlewareFunc
-	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
-}
+	var err error
 
-type MiddlewareFunc func(http.Handler) http.Handler
+	ctx := r.Context()
 
-// GetHistory operation middleware
-func (siw *ServerInterfaceWrapper) GetHistory(w http.ResponseWriter, r *http.Request) {
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
-	var err error
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetHistoryParams
+	var params StopWorkflowParams
 
-	// ------------- Optional query parameter "limit" -------------
+	// ------------- Optional query parameter "mode" -------------
 
-	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	err = runtime.BindQueryParameter("form", true, false, "mode", r.URL.Query(), &params.Mode)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "mode", Err: err})
 		return
 	}
 
-	// ------------- Optional query parameter "offset" -------------
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StopWorkflow(w, r, params)
+	}))
 
-	err = runtime.BindQueryParameter("form", true, false, "offset", r.URL.Query(), &params.Offset)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "offset", Err: err})
-		return
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	// ------------- Optional query parameter "workflow_path" -------------
+	handler.ServeHTTP(w, r)
+}
 
-	err = runtime.BindQueryParameter("form", true, false, "workflow_path", r.URL.Query(), &params.WorkflowPath)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workflow_path", Err: err})
-		return
-	}
+// TriggerJob operation middleware
+func (siw *ServerInterfaceWrapper) TriggerJob(w http.ResponseWriter, r *http.Request) {
 
-	// ------------- Optional query parameter "status" -------------
+	ctx := r.Context()
 
-	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
-		return
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.TriggerJob(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
+	handler.ServeHTTP(w, r)
+}
+
+// GetVersion operation middleware
+func (siw *ServerInterfaceWrapper) GetVersion(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetHistory(w, r, params)
+		siw.Handler.GetVersion(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -335,22 +1929,32 @@ func (siw *ServerInterfaceWrapper) GetHistory(w http.ResponseWriter, r *http.Req
 	handler.ServeHTTP(w, r)
 }
 
-// GetHistoryRun operation middleware
-func (siw *ServerInterfaceWrapper) GetHistoryRun(w http.ResponseWriter, r *http.Request) {
+// ListWorkflows operation middleware
+func (siw *ServerInterfaceWrapper) ListWorkflows(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
-	// ------------- Path parameter "id" -------------
-	var id int
+	ctx := r.Context()
 
-	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListWorkflowsParams
+
+	// ------------- Optional query parameter "tag" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag", r.URL.Query(), &params.Tag)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "tag", Err: err})
 		return
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetHistoryRun(w, r, id)
+		siw.Handler.ListWorkflows(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -360,11 +1964,19 @@ func (siw *ServerInterfaceWrapper) GetHistoryRun(w http.ResponseWriter, r *http.
 	handler.ServeHTTP(w, r)
 }
 
-// RunWorkflow operation middleware
-func (siw *ServerInterfaceWrapper) RunWorkflow(w http.ResponseWriter, r *http.Request) {
+// CreateWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.RunWorkflow(w, r)
+		siw.Handler.CreateWorkflow(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -374,11 +1986,19 @@ func (siw *ServerInterfaceWrapper) RunWorkflow(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// GetDBPath operation middleware
-func (siw *ServerInterfaceWrapper) GetDBPath(w http.ResponseWriter, r *http.Request) {
+// PlanWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) PlanWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetDBPath(w, r)
+		siw.Handler.PlanWorkflow(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -388,11 +2008,19 @@ func (siw *ServerInterfaceWrapper) GetDBPath(w http.ResponseWriter, r *http.Requ
 	handler.ServeHTTP(w, r)
 }
 
-// SetDBPath operation middleware
-func (siw *ServerInterfaceWrapper) SetDBPath(w http.ResponseWriter, r *http.Request) {
+// RefreshWorkflows operation middleware
+func (siw *ServerInterfaceWrapper) RefreshWorkflows(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.SetDBPath(w, r)
+		siw.Handler.RefreshWorkflows(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -402,11 +2030,19 @@ func (siw *ServerInterfaceWrapper) SetDBPath(w http.ResponseWriter, r *http.Requ
 	handler.ServeHTTP(w, r)
 }
 
-// GetLogLevel operation middleware
-func (siw *ServerInterfaceWrapper) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+// ValidateWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) ValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetLogLevel(w, r)
+		siw.Handler.ValidateWorkflow(w, r)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -416,11 +2052,41 @@ func (siw *ServerInterfaceWrapper) GetLogLevel(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// SetLogLevel operation middleware
-func (siw *ServerInterfaceWrapper) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+// DeleteWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) DeleteWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "name", chi.URLParam(r, "name"), &name, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteWorkflowParams
+
+	// ------------- Optional query parameter "purge" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "purge", r.URL.Query(), &params.Purge)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "purge", Err: err})
+		return
+	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.SetLogLevel(w, r)
+		siw.Handler.DeleteWorkflow(w, r, name, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -430,11 +2096,30 @@ func (siw *ServerInterfaceWrapper) SetLogLevel(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// GetStatus operation middleware
-func (siw *ServerInterfaceWrapper) GetStatus(w http.ResponseWriter, r *http.Request) {
+// UpdateWorkflow operation middleware
+func (siw *ServerInterfaceWrapper) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "name", chi.URLParam(r, "name"), &name, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetStatus(w, r)
+		siw.Handler.UpdateWorkflow(w, r, name)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -444,11 +2129,30 @@ func (siw *ServerInterfaceWrapper) GetStatus(w http.ResponseWriter, r *http.Requ
 	handler.ServeHTTP(w, r)
 }
 
-// StopWorkflow operation middleware
-func (siw *ServerInterfaceWrapper) StopWorkflow(w http.ResponseWriter, r *http.Request) {
+// GetWorkflowDefinition operation middleware
+func (siw *ServerInterfaceWrapper) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "name", chi.URLParam(r, "name"), &name, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.StopWorkflow(w, r)
+		siw.Handler.GetWorkflowDefinition(w, r, name)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -458,11 +2162,41 @@ func (siw *ServerInterfaceWrapper) StopWorkflow(w http.ResponseWriter, r *http.R
 	handler.ServeHTTP(w, r)
 }
 
-// ListWorkflows operation middleware
-func (siw *ServerInterfaceWrapper) ListWorkflows(w http.ResponseWriter, r *http.Request) {
+// GetWorkflowGraph operation middleware
+func (siw *ServerInterfaceWrapper) GetWorkflowGraph(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "name", chi.URLParam(r, "name"), &name, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetWorkflowGraphParams
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "format", Err: err})
+		return
+	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.ListWorkflows(w, r)
+		siw.Handler.GetWorkflowGraph(w, r, name, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -472,8 +2206,8 @@ func (siw *ServerInterfaceWrapper) ListWorkflows(w http.ResponseWriter, r *http.
 	handler.ServeHTTP(w, r)
 }
 
-// GetWorkflowDefinition operation middleware
-func (siw *ServerInterfaceWrapper) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request) {
+// GetWorkflowStats operation middleware
+func (siw *ServerInterfaceWrapper) GetWorkflowStats(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
@@ -486,8 +2220,16 @@ func (siw *ServerInterfaceWrapper) GetWorkflowDefinition(w http.ResponseWriter,
 		return
 	}
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetWorkflowDefinition(w, r, name)
+		siw.Handler.GetWorkflowStats(w, r, name)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -610,6 +2352,12 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/admin/reset", wrapper.AdminReset)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/health", wrapper.GetHealth)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/history", wrapper.GetHistory)
 	})
@@ -619,6 +2367,36 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/run", wrapper.RunWorkflow)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs", wrapper.ListRuns)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/export", wrapper.ExportRuns)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}", wrapper.GetRun)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}/artifacts", wrapper.GetRunArtifacts)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}/config", wrapper.GetRunConfig)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}/diff", wrapper.GetRunDiff)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}/log", wrapper.GetRunLog)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/runs/{id}/rerun", wrapper.RerunRun)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/runs/{id}/status", wrapper.GetRunStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/runs/{id}/stop", wrapper.StopRun)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/settings/db-path", wrapper.GetDBPath)
 	})
@@ -634,15 +2412,57 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/status", wrapper.GetStatus)
 	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/api/status/last", wrapper.ClearLastStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/status/export", wrapper.ExportStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/status/last", wrapper.GetLastStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/status/log", wrapper.GetStatusLog)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/stop", wrapper.StopWorkflow)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/trigger", wrapper.TriggerJob)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/version", wrapper.GetVersion)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/workflows", wrapper.ListWorkflows)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/workflows", wrapper.CreateWorkflow)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/workflows/plan", wrapper.PlanWorkflow)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/workflows/refresh", wrapper.RefreshWorkflows)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/workflows/validate", wrapper.ValidateWorkflow)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/api/workflows/{name}", wrapper.DeleteWorkflow)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/api/workflows/{name}", wrapper.UpdateWorkflow)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/workflows/{name}/definition", wrapper.GetWorkflowDefinition)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/workflows/{name}/graph", wrapper.GetWorkflowGraph)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/workflows/{name}/stats", wrapper.GetWorkflowStats)
+	})
 
 	return r
 }
@@ -650,32 +2470,132 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xYW2/bNhT+KwQ3YC3g1N7aDpjf0gXtPGRtEKPIw1YUtHhkM6VIlhenRuD/PpC6WLJI",
-	"R26cYU9xxCOew+/7zkW8x5kslBQgrMHTe2yyFRQk/Lx4c0Xs6hq+OjDWP1BaKtCWQVhWxK78X7tRgKfY",
-	"WM3EEm+3o/qJXNxCZvF21OxklBQGHrcVM2TBgc4tqP5GzEIxExS+tXZjwsIStH/ZWFDJ5Zi3S7m8hDXw",
-	"JAjcrw4M/er6hjD7YQ1aMxpBYQWEvtFEZDEsRg+dTd4J0NEXleR8DpmJv6f0e1csOq+2VjUoGd30jjD7",
-	"Vuqjjj63xEbODYICPQ/g5lIXxOIppsTCmWUF4FHfN2gt40d9AMKVLfhHzaNrghQQXTgA7PdBZyzR9rgT",
-	"G0usM9HdLLMcTkER0YRz4O+0dCrBVBKjA/H5nGtyM/z4UUOOp/iH8a7yjKuyM/ZpXTrfxUi0Jpt40NdO",
-	"JFOTtirF8AA69aUXwwgzoVxZKQmlzDIpCL/q+O0ztB+10u1CMDy2vQISie5O6i85l3cDOZ8H2tJ1WTsh",
-	"/Mu73RZSciBi39ehqG8qu4rWeByQktzCMU53aUbBZJopjzue4j9BfGHCoGCERLBCGVHWaaCI5BY0sitA",
-	"t3KBfGQcLJhdfrWSNeyQKg3pesOEsURk8aS4lYvjKo0G47g9NsGcATo7XpVdJGuSUKlvpCEHDSIDihYb",
-	"ZFfMIJ/IPxmkiCaFQc++wAad/eMmk5eANBjJ10DRmnAHz3GP4hjptcuZyGWkKSQxT8KXmCJGeE04ozER",
-	"HwzLQpHQJDNlJsbzgpm6lMbXVWv1YLb3C3JTO4ZViuYlUw1LAyvvIViunegDkkmRs+VnI4gyKxlXMAj6",
-	"ObS3wU2v5KyxZcL++iqavKeoyaEpHxnggaSsi+PnpFobiyOG324p7evyBDg0jWhQR+onS6QpHT819APb",
-	"BprLQtGtXOdXM5RLjepe8NZXsQtiVgtJNMXNgIQ7BudXMzzCa9Cm3OXnF5MXkzDtKRBEMTzFL8OjsqyE",
-	"IMdEsfGKGSv1xv+/hKB0jy/xwcwonuJ3YP+oTMpUL8CCNnj6937gf5FvrHBF3bZkjsr6b5CVSIN1WmB/",
-	"aDzFXx2E/UokMWcFs3hUfauVkOQktI7Xk1Hkw2bf9Yc8N2ADbIosmQjhJ5zJYBv3NsjZW8Z9H15sUC15",
-	"FCQfd9dNi7bXnkbSjkphoWfV/DJCxmUZGDNCOWEc6AgZK5UC+jwRRaXMQ+4/hX4dBqegjl8mk6oUWhBB",
-	"GEQpzrKA7fjWSLH7vD46v3zV7Q/EvS5+yYz1QmqA1k4Y/+LrMriu9Rz0GjQqO63fzLiiIF7b5UbtXVCt",
-	"e2/XToTxPaPbAdngT/BAQty0/c0uamoqIVTMMJ/SGr46poHiqdUOIizt5PhYmgazs03PVP48FCxhPJDx",
-	"avKqT0bHWEifnE7Q7+HuHVhkFGQsZ1mXwyaGmkNdNXNpItxdO1EHVUEOxr6RdHMy/FpfbwG+Lq3bRzLX",
-	"7Y1H9poEOdWne8lihJaZCLMm0vWxvN1vB9gmXAOhG1R/aXWpnHt3iDQs7pgzYC0TSzOmi7N6gEhlYHn7",
-	"hp8wEfbu9yII/u60BmERJZYsiIGyBXynurPUZspFEJh3EDi9irvXpE8g5Mchf9EGCTnlJ9qjBHwsQx+D",
-	"i31yesLlcnnW3J6mpFvfv+KT1oLhl7ZpIXO5ROU+aX22bEaJIjvfO+Pp5bl/hf3klfYx6F7WiCE/cz4k",
-	"0hQHc9jnp5Re0wJScpvXY9+T5evejdsBgVXRptV112pKtWV1TqnSbX1uper09f9diw2TeXJQei87Q020",
-	"a0oVrv2q5Uj7rJ+k1eAn4JvG6r+c9cN92IBh/xzxvXHfxKZ4siaMkwWHPbMuDuN7P19vxxRyJljpIZ0n",
-	"dagXO+sHZnsQmaRAyyYkNfLefOieprudGiMDf/gzYOQ/2YfZMXfZaRm3gHxw5G+N+71sv4ttGMxCBy6x",
-	"dprjKR7j7aftvwEAAP//41e8OtAdAAA=",
+	"H4sIAAAAAAAC/+x9e2/cNvboVyF0f0BSQI7Txy6w8V9unLRu09bXdjb3og0MjnRmhrGGVEnKk9nA3/2H",
+	"c0jqSc1oHDub7uavxCOKj/N+8ehDkqlVqSRIa5JnHxKTLWHF6b/PlZyLxamF1fMllwvA30qtStBWAI3I",
+	"6t9BVqvk2e8Jz3PIkzTRsFI39D83Jk/epondlJA8S4zVQi6S2zSZCyhymikHk2lRWqFk8iz5GTaGqTnj",
+	"zL/NhIUVWy+VAXbDiwoMy8V8Djplyi5BM7vkkpVc85VJ0gRH07SDFf0PXGu+wb+FzOH9cAOn+DMTktkl",
+	"sKzSGqRlc1FAypQOvxvJS7NUls2VZv7AzC1dLySkhQVoXEryFUT35Lf97EOz7//RME+eJf/nsEHOocfM",
+	"oUPLGb7k8RI5l4HMHaVBzVrp63mh1gnCXfKi2ERw0kylZu8gszjXcMF7J4RR2EhYR39XRR793Vgoh+i8",
+	"sFA6ekJYFwUUbKFVVRIaCfxsBoWSC8OsOmKwKu2GsIrPESmPDFPrFoFNgZoGbuGNB/o5/FmBsRHIKWlB",
+	"2uGew5vs/x//8gqp7qeL335lVrG1FhaSNEZHdjmc54zbJZ4djyJhTVTMhDQiB8ZZIAqWCw2ZVXqTMniy",
+	"eFI/MIcW+OqAH+ZQFmrzZMNXxRFbCWOEXLC5KnLQhnENLKMD51HoaPizEhpypAzaZlof/G0Ediff465H",
+	"YRYOOgELYSZTKmng46aCAtoIHZuS62wpbiC/VBGkLkEDoYLQsOaGEXscMT4zIC1bL0EyYelJWekF5HfD",
+	"dBBHNX5xvWmoiSJEGD4rIL/w7NU9MXLIaRCkQ8GHPDn6OAbpH4EXdjlcx1huq4i6OC7WfGPYH4m6/iPZ",
+	"eUg/S+yYr9TiFdxAMUp5BT6dSC9n52+4sL/dgNYij9FJZdXrMucWvtdcZssotUhmdQXscQ5zXhX2q5Sw",
+	"uwSesxm9xYRhONPBCpBa2FyrFZtxA46UcPTZOQ6awVLI/Al7yUVRaWB8prQ1NGDNhX3SwG2mVAFc4hlw",
+	"oWZ3AzrcgXi1lqCjL5aqKC4gM/H3Sv1rtZqBjj/VUKropHiMl0rvhZ4Ly+1E3AyhAzKH/JjIZK70itvk",
+	"WYLvHFixikpo0FrFAbID0Eu7Kl7rIvpsVHduB/8v/H3AQJfqnvOSKUc5M55dQ36g5nOGCNA3vDhiBixT",
+	"stiwtbBLhlNd4Tg1n0fNnjaq+0aWm5LNwK4BJMuWkF2blPG5Bc08yTvlwsuyEJDHV7gbuZhrUZ4DN0oO",
+	"t/ZmuWGc4YgShSgXluUiZ1JZpisZQ62xXNv9qKERZ4NHVtgC7oPIvbnzA1o7I7Q+Sj9b9ocifbrFilrD",
+	"LT6wVKObLrhEr2O41RFr/UwZgf8Nyg83FYx0XUn2uNaCtF20Wgq1hpzNNsybwu7JV1H6EualGxSXAtdC",
+	"5m3LF2GTOKseQZ84nF3Nlb4qdZImC2GX1ezKQxcNodWKyz3t4lKj9NoF+LaM8xRfQh4/Ro3SoelsHKvD",
+	"fI5exQ2Zj5bLDAzjMvdW8RFTEshoRsuwAEYTpuwHYX+sZswd17/hj9xx1baeo+ASNzKdfkYMFb/vuDLz",
+	"D8fk7Ds120/+Nn4dz3MiUF6cdfYzeKUL+xc1wN+pmQMzWNDmiP3PB4Lukz+qp0+/zURO/4L/k7xq98st",
+	"0zAHDQ5XGpgGowo0DDlJMtaVTQ0Mt5BKDOTnoCs5bhJrfPzbPKICTmqDFTez5M7ypfFJ2shRIe3fv4sy",
+	"p67kab5tYvR5Js+25loKuYjwwYmY14AM+qoTBEC6bkcLOpZ3ytZLkS3dSfFwLFdgSJ+UIrtmVblH1KJn",
+	"zzoIpDWQ30bxY8CO44eUu65KCxFQOuWK4NSVRNxkSudIQQZ/QHB5xIEGtuL6GnLWnjCN2fztA7QHRzdf",
+	"yWNtxZxnEYscoXs1yoII0isxbqFqKDgy2NUWj8r7ymQPVaLIHxnmXby4Uofyapeeqt3vXjji8dOBAI2r",
+	"JFpl9NSVLoaL/wTyWkjDXp+/YlaxXK1lobijWe7BS97DTgeqBdTOedvbSluI6YPZbXAE0y7YhNwWIdMc",
+	"pBUZj5zuUlctj6cdsSPfZ2OdaqL/tNk26vXUjNhzMzGkldauNSmyVnjSHLl/HDeQhewHaiBOL4Rx/LBH",
+	"mK8VfY1E+WrhN6SQSoq5iLHza/eA4qfOW+yIMasGEAwBMQJvg4MI8QeZdzY9qnJeyVFvu7Px3p/JSw1w",
+	"gCKdaTLi63idrmTKzBLjdd4GzLlZzhTXDmdSWTEXGcd5TOwUeSvYMd3I7YRIopFmYQW3Sg+P8mapmNJs",
+	"jXTj/YjmJLW89YdZCmOV3sQ2LmRZ2f0MjgE+Cj6DIkL8r+h3AvJcFBa0k/tOcTd7mh55L3U7PDIdzr2w",
+	"SnRmhWQbkb/uATNQoF0lF7Uhy3ghuAHjGCL8aijaWWt2dQO64JuGaQK5PzLMr2mYD7wHniGTl2jQe7Mx",
+	"vN2AFvPNT2oW88bRJ3YiBW5Ab0grPDJkEcJ7YSyaI3OlwVGOkIuUcW+wu3mv3qmZCdQzEg9sCT/30llt",
+	"u/ZDbUY5P50Bz5ZhN87UZXzBEXZNWJ3MVSas228OWcE1mMEO/euT9xhGTJcxcX+A9PmV3BI9cCOqEZdg",
+	"l3nx0R6AQ4OT0BZKMo6tFosFaIxLCLsM0RJTzYwVtqIXIzDQYKrCjgZCrvSkSAhtYmck5N9tAcVo4IL8",
+	"zy0uijNl475xm962yaaQHPAed3wfMBaIIVprQllx840GMUeyLOOlrZASHA0gmSCf4c4KsBDPQ9IMY07u",
+	"PYY0tzrbezvTqrJlFUmTPVermZBEmrmqnBtmbA5a+wSyCzWQaZYyqyuZcdKvzs4puLHs79+xn8X38UTL",
+	"Z8PAf1ZQwcVIBiRQBw1i84Iv0OEsWl4LBTFrEUvj6qSTQg0ovO1hkrQVyqqy6yRNZoXCQHDiiQftnGi4",
+	"aoeQOb9HGcMXMGKkDPUzKZJHhtFb7BpcbpcEDhoBuKDpZuAqacB+gjAvGLvT4LkEg647GQ9o2BvIT/c3",
+	"9EaSy85kbKJEFBO1S2F6mv0xQu3ABZWaIBKVYnwVo9a1j072golgGyfN4TmEQfAFQgtSqaNYq9hcSGGW",
+	"R0ScVZYB5MZRa0PXwlN9zpQO1vO0DH0bsEOOei2FZZmqpDUNRYUAACKOaSiVtl3CafZVVrNCmCUgHUtI",
+	"0n7cgosC8jGzwZixZ8PAXOuhVZYXU7Osl04MjXpfbfndT9y4JwhxMpxjZnOSjkr9HqQptmmXvvDg8J2a",
+	"Hbq6AvqvDyHcv2j+3qGpNlNT5lOoaOcTIsOBHplgvl/1K0AacN6z17GXAxHnth9R8nFNfEQZjxZ1ep9B",
+	"kz2Ud7wGHlgfZz2q18VpGvYKGsKxXlILRpfPIOsjT97ujCQFEnOk8XYblQbNsofNdAG2Ly2W3LBmg7vM",
+	"o57v0+LzILJcEYxurRDAFMMTQWvM9GqUZy+01ZI7bgwVof3f1y9evzjB/11cHp9fvjiJb0zYRo7GtrS/",
+	"CorJkn/yQqAS3L/M6Zyv2bpT6mQVu/HTEU8Az0Ms30WjSF8zyVdtXvI2Fmhk3lqr5DAXEigCF0L7KT0g",
+	"s9VMr6150zYl2ls8ckzjz5e6uB/KLLch/zsTkgljKp//wjy/8o5xiI2SDIzvaJdkaUPMm5QuUsPj9tMW",
+	"DAolT3Gf8UB7JAoXjEw6HROGCdmJF1byWqp11IgrhIzZcEL2ZsRoytOR2VqMuwJjvFW4XeqEgW+3gmFM",
+	"4njKiSFjVtTh32uAsmeDoozHiLuQi6OAL6EkM1aVhnHrKVwbmzJ4n0Fp22EfskgZyBuhlVwhSd1wLdAS",
+	"RxDtF1ru4zoSQ6P9RSuSfLlt62R4KHNUMxjLFbHYil+TVyGkmywayGnl2+5n6z1ch6Vrfq9XjGIftMGp",
+	"5VyNqJoT77nHTAl0AIzlq5Lkc4sHEBoj/gS6pzHd/QMZn/jMqRYhud6Q94jbcImaPZa5cQeLiF8ogGNR",
+	"tRuQsj+SHG7+SEh8FCrD6hw83MSi1yAm4xDcGs//rXQ2HFMSDlA0MFOtVnjquD93NPHouTBlwTdn4wm+",
+	"WgL7mEDJKfWh5t1F6wrZUJ85V5XMmZATKmb3i5xsqSiw8fowxx4RC4h+D9gNAVjv5A0AqlbCWsgd7mHB",
+	"sw09cHahqixzjkzE7+CxlPklX5hmyVhwN+064qga6+TCXsmEWlxNKVaoidTCaiQU1yn5iWQanWVFtUSR",
+	"ZECoKfK5gJD815VEYS6Z6FEW+oImnok0Lt0RD0sKE+q74s8DHfVowqLWSFklxZ+VL5bCkeyxsVCmvYgs",
+	"CZmzc+ef47CvjlhlnN7pGme+6splgqPuWtjp1vTOsGLtzuVOPuY/sTRtG6lgSVHMQ/74fFtN45MLoZBs",
+	"YzywZ1KkLmSvIifj1qJ0jfhA7kGd+SPzjPQQBo4ZEjtxxNcYFlE3wL5u4iI1vbsSH6vHCjozSn1f1Tn6",
+	"nb58PJB9RRG5yXE64loqy4M94/xpIvLOOuMFRp0s8AMncPfJxY54GHWkpwyuhsc7ojCkqlE7JNFosK7k",
+	"lZpQ9iWolIhp0FzW8TTnwMw1mOX0Ai7KZZX6ilKU4/br2bkvNnZVSyH03CohiIpjOvGeZDWlirZvk/mM",
+	"v6psplaAsKqTrUfOwxQyKyocQ9w1B5stSYOGzJk7wSSZErKjEbowVpVXK5VDPLjUJQZFIHy80DyDeVUw",
+	"pZlU66+CnSbmwW4iY80N31ZEMl7fVI/Y4/ZOzbJgbbzCr6m2NH6Mz8xgWDBUO4RoWNqrge2YNSYUyTc/",
+	"UyCCONoQAgfx4DDxyygfnvinNH2IU85AAtqvuC9/C3KtGisuyF0NHuoySp+uHPm4sstt15V8FW+mgep/",
+	"eEFhNPB+iKmyJeN4A4dXdnkF8ob9cHr54+vvry5/+/nFr38krV15U5IztzCj3FK8msUD+CNivFjsFrgn",
+	"zNe31lw4qTLxOtiCZ9dYgCUhHrWjAa8N6FFqpRFvYLZUKiaR3INa9PjgpFCyU6iUEuAoYIREz1bcXE/N",
+	"c3S11f66nvKojfIWdum1t0mJzmsTmIIX9GzDlBxR7T72ehqvsTttKuiW/AZ84BLylKqjIETmvWQ8GhYQ",
+	"KBdXL3DXwgbs4lzb93Oxrfx96n6iS0ywVO6Ych9mUYNEdtkllxEbFbQ0z8/+9kJ/Lo4BHKrQ8HOFaYcC",
+	"xgVTmXCSXUnwyt1AK1EQbkXoK0pW+Fg4bkusQFX4C91Io62+UzOvwWNZZjBWrDCJf1JpHg8m/AK54JLl",
+	"fgATEp0xJXO6Vq4hA+kzisagrtKVNEMZ1ZS1TbA8HJjCvZXYdfK2n0QU5V5J+4B00eMxX7tZx0SrIRw5",
+	"t0tRG2IgY6cB89jkI7zQTG4arthj8oe3ffdzpoZxgIgJdBdzelwVtIyPSf5FGH/vlQcoFn6JGnZ10oyT",
+	"7PDOGqV1YuZdbF1KQ49QaHObwNdMDyS4z7nzcRag+UeItJnf3z+i6dB8q03oaCcANOJohI91xK8tbNWt",
+	"ZqhbVySH7iKnonacqyTvC66JnhFH7r0Q/4JtQIuLRiecvNilgnpuyNuPrrS7Crwdoe+M7mxzGKF3jS0q",
+	"LeyGIpvegCnFz7CJW7CYPDUY3Do+O6XKH4ygA9egcbyLP2aFQO5z8izj0geTGZcMBykt/uWwg5d0QeNN",
+	"aYFzuz+TwO/J/zs4Pjs9+Blatdlua5RWrheNhL5rlekTmI9M2K9JWQAWU9LngkDmpRLShjTRIS/F4ZIu",
+	"zTe6xM3ERNdPZwe8FAcICKUZL8UVruHIzomaJ++MknhEkkXk/9LOmzMtrS2T21sS2fNIjwPcOYI11KS9",
+	"RKvxJJTgJ/Xl1qQz4PjsNGnlKZKvnzx98hQBp0qQvBTJs+Rb+slFwQnxdG6er4Q81GDAJeuUiVUJouFf",
+	"mM7VBgpfkVNKMBJFweA9ZJWrocaLTMbD28UmhteeWheXUncdpACu3SoFOpL4G1UYZi3VyS0gfFFMuERW",
+	"joSCp6BbWkmauKoM46j7m6dPewl0ugvt3ILDd76grkkAbHX1O9fACIeD2LAFdwzIEfp/c6tvGZWyWVXH",
+	"AwksJDEyVRWuhG8GrFAKb4VVpWNhl9fB7LHSGRwQ7kjdVNl142iYOizbom/czALsaA8IqrrKUhdn7/OC",
+	"5yRDnO34a4iJBVjff+IBEeFXiGDgssO6VdkResmz39+24de6HGBj7znAeSu2gdzwwLWh25RDJc9+H2gr",
+	"/l6sqhWTLXVhQm2QBltpGWTjnxXoTSMaC7EildrOU1FEI3n2t6cxTTsQkfM5uZpKs5IvhKTtjyymaGx8",
+	"tUmLvaQcFCbJamL0F9diy3WjUe1VB4pvfCFnqrHHnofSoIHT2knwjlzaljpfjWypvlu+x15+w7CiQ6Ln",
+	"YK71xsW9hGFkB49h1z/7mNWCkuKWNJOvZxeGsurs8fnL5+zbb7/9x9iJ/cXFZgdTLOQ9tuXr5fbYkVX3",
+	"sJ8LpREeOWj2uAk+X+GglLV+4Ph3sCL94/pPbrJRQlF6hFGS3nKR3b79SAG5l5OGaaphscdAfL4ShgJW",
+	"NeMiFvHF72KK7NSVpjDECdNcLqik1dRAH9eATtK6SExXp9EO2svXYYS+RD78IPLbCWL5vJK7JPOb9nqn",
+	"JwHbXiJ5ZAt3SzyY3lZXEOHZRi6+fUAF2EHr7W267Tw5WEqQExa/21Kbh4Ml9eOrZH4X3P0AlpkSMgy7",
+	"snV0DwGHupJtc7OLO13JN023Pe89f6/yzf1Zcs212dvb2z5abz8Sc4OrUZNaLKR1EDtAyBCJ1/6KGdbK",
+	"t/iD7BbJb7go6ILJNK92LMQxdNa3UJmX8jvlhA4gx3Hfxr3NjsmAZ1KVrRsIDIqJEMQ02z+20DUvNPB8",
+	"EwzsHtFe4OZbEfoOjZpRg/kCvWJuWFsipaySOfRwSAcxAdcu0YRoq7E6tKELYey5i0x8MSi/GJRfDMov",
+	"BuUXg/LeDMquFWIO4X2JUB8zJN3jKeL4BY1kjrLY48zc4O4RzqNcQkNHEJ6ZmyTdR2b9twlHh5lPJRzb",
+	"q31GwjG2rf8W4fgZCsA0sfDeHiLvdqbub3UgKI+Lgq24r0NDbCLINPCVi5lzybi1PFuu8CRj8vO1u9/j",
+	"RVDKxAPI0xc1wdWeBzfs+cU/Q5Ptnnjteem7rWh6IxpY/uLJf0pPnne0ZgSrh6Hv2biXdN60T/S3Q9yN",
+	"iHbzG3fvM7T9bnVs6t0ID7VDT9hl62agsdxl4Hw67KhpzSZatVWVLsZI6rg+xV+RtqbWqIZTTjHaaog4",
+	"KeEuvCC2WOkb0HwCEiS7rcE+b/Y0n0CargZ+W3Swbpb3F0D7+wO6BbWfRul+AYGbUD/cCR55kyFlFFeK",
+	"K5rJaE5dVUvTDc8Vvzh2vgsNnLS7LNYod7htViGCGKGD3HdC3EIF1CzxP02tdFtBRqgD64ua9sO+E6OZ",
+	"1tHQZes5q1q9EPcmlTE6ISIaXHXD4YWSC9C+bdto2O9yrIlsc60dSeou5IgVfNRNHc/yyAwIkSpERpvY",
+	"xsizULtk1Cv1uQooMnTLggu5p2C6DBd9IWcgF0ICK9QiZRmnKxDcsm9++d4D0zWc8uXckO9JZXc1fQiH",
+	"oTlYoRbTdI5ruzxaREOBZsN4iPc3vBalpjQ0FyImdfeHUlYIee3ab9HVZq2qxZKFm0JP2AVkGqx/M1yc",
+	"RJI1VuFZXN17Sj9Zfg2tTURpdmgz0VqfjyH+zT0W97R7cEfI9hxaCvO+NWNUlB03yBCmn8ao5WQ9ZyMi",
+	"UcAZf696BhmvDKBir69wCNtubO724r4zdQeGcXDh3hvE3rbuVlO4cKG0wARBEQicy9wTaIyJmoxU1KFA",
+	"6c7dNSN/f5KqdvKGjqlyzJWJsZfeZXDhEdfAvWkAQMNrb8PZt1otNBhTNy2NsuaYJ3ERIln/mS5quOi7",
+	"NRFooXU38ROKa+cbQ97sYbfANlaV41lofPrvEnSD2JpEu9dXYYZCQddZgokVlUlbKDZHrK4vL8B26zXp",
+	"3oTzoYm68dKm8Sxg7EjIjS4vxsPTkpLz9x2C69V/+xL7LaX5XTCFa5RzpRm+zKRa+2h06OhEPwcwTbkB",
+	"FivzVGVT1L8zGofL07Kj7HDZXAf3XRujiWpVMh4ejhB3qEM+zGcH4XrnmHXpPu72kHWavc/HRUD53FNn",
+	"zi2nz27Rpu8oB7KxycoqAgHTgcD915h0v8L3AGUmHwf5kzaQWEVf69qrhGNfDLkPgvWRMyDcQi0O6u/E",
+	"jZFu+NJccq/iZvrn6cYJGb0FN884fbbGpGPKp3fG+yfP/sf6HrwO6mOg+ypAjBmwO4l0DAd4N6v7zJFe",
+	"3+QckFtt2D0Yv/b6a28hML/bcerq3ACoTP+c0/LdD3/inVbly6oouhalN/xJiRsbLlu5D1Y4L6AfwKTL",
+	"oE17gQOazvXhzep+Y/7rT7T91F9LCl9wxnMenAhTB763hzrGFPyvqkHLku5QgawV90i8M4pPypZx9zHb",
+	"blDJY7fgIfZQgIUheunayStuRjEc2f3zcKGlGw3DX30r4haSehd2umZKOi7RufksiI481c6BWrRWbFon",
+	"I7q7hrJVjinhvW3C6iaZSg7NpEaEFgv+NkrI6as2qNFjz6JICe7QdJT06Wd7RNKhyAUlP218cKcQSD6S",
+	"+QLoWmsNWLAHsa772FNZvgOc0iucLuJ6uptHhvpXDgktIF5XchhvwJdaJdJbHdQvruN/suvYJu4tfmP7",
+	"guGw0tl/UmE8FuIH/KRmD2SN9rqpf2JvqdslO4I2bLLefHeCOhZxYd2l4vYF/6n+E1M6NOOto7LOp/pm",
+	"/OsUzZ1Qv5X6iyWdvv+dq6PhW609mvAHbhpehTVoNl9xjzE0uYmQS6sd6pie8K1gH1KXt7vNRlDmH6e+",
+	"D6zLWToI5d0opZOI1CI2ohLcK/7ITEhXW4Vr1PAIEBr3IQph7Jt61A6BTXV9RbtstV/faPlirJCPnnwm",
+	"JXIONRPqTVjRKxM2sWKQ+m5Le1g6on4JiK03eneZ+w342AFHRjmoPQXIRSTOn2ngFh74ctLzziJ7icSv",
+	"74+7+p28Ry5bU2adykq0sBZkq5G1a/DrPqQTGnbLBROWMjGhBd9kkTlTObVdoBWJ3MfuEQXQMUQiLWlY",
+	"+CphnTb76PtFx24nIS3nvybHbT07vfnNN58cJX6xEE3mDvj14Y7wVyozbaGt79sRCbZTKF1ns2nWXIbW",
+	"rlG7AZ/+pa/yTRFz1N12pMTGNF0eSS27FhkQ+tG5DH/97SrIG+SI1peu9jErGnV9v7fsOvRxpuFGwDr4",
+	"AO5zRu5sQkmGaG++oNAEjYfko4E6lI5TkB/QVp6fnwLrgBR1WcpQKDBOe3fJ5lyY60Hm3GRcdnnMpDgy",
+	"4zpHIsh4hunrkmsDJgY/L1dhHIBhxAOzYf8TJp+YF6fIxhdaK+37jXZ01JErjuiKyaatDWe40zEW/BUE",
+	"3ekkTpJK1+J3IW5AjnIhtc7fk+sCjPty2XkTht+0gzsBrBGi+YDa87YbJXwA64nV9oEw/lvOvpjQF3sz",
+	"952q0Gsy8qmAShZgDCsrvaBZDESMMneIqeEQkJnKiafsMjgB/U+mRjL49ae4d+Twx6/HnNA26yL4dv/3",
+	"lbrxllEUQCO2PkElZu037fsfsizkpAP2bamLjp3oj0YRTYe5/FMact/t+jhRpxpltMK0LSXiYZdjj8L6",
+	"mENrqk5MfwrXxWV4PzcueTtVG00sgf+8NE6H8DWUBc/QxLuTh/RZssfn6N3YNtALmFtWSVfeng/sL8LI",
+	"FDfHqczDpg/5tuBXgNhJM3ovdnM9bPtsd58c92+vFGwBcifdtUhuEJ1bxyYcRd9C83I5BXM/0MDPB2lD",
+	"Y0Lwhear+rL5CvSKC6dUlb3TfXM/xZ0yM3fO9YVzGFXpDHb6uf64Azf3LuRzDtSspcX8lFr/pYEkUcGN",
+	"+Bc7+e2S5W6nW6jLhCa2u6jLdbv9CA38FxMFZmfRsDBWZGa3oivbXhN3qY2+EovWpdXVbmO1g3UP4WY7",
+	"zm/n7Y8ddPuRdVtLfug0qf39LTJtu7fu728R4M6IcwivdJE8Sw6T27e3/zsAlXD4aUicAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of parsed files a Cache keeps by default.
+const DefaultCacheSize = 1024
+
+// Cache keeps parsed config files in memory so that listing workflows and
+// loading them does not decode every file on every request. An entry is
+// reused while its file's modification time and size are unchanged, and is
+// parsed again as soon as either differs. A Cache is safe for concurrent use;
+// once it holds max entries, the least recently used one is dropped. A nil
+// *Cache reads every file afresh.
+type Cache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[cacheKey]*cacheEntry
+	clock   uint64 // Incremented on every access; orders entries for eviction
+}
+
+// cacheKey identifies a cached parse. Strict and lenient decodes of the same
+// file give different results, so they are kept apart.
+type cacheKey struct {
+	kind    string // "meta", "instances" or "defaults"
+	path    string
+	lenient bool
+}
+
+type cacheEntry struct {
+	modTime time.Time
+	size    int64 // -1 when the file did not exist
+	used    uint64
+	value   interface{}
+	err     error
+}
+
+// NewCache returns an empty cache holding at most max files, or
+// DefaultCacheSize when max is not positive.
+func NewCache(max int) *Cache {
+	if max <= 0 {
+		max = DefaultCacheSize
+	}
+	return &Cache{max: max, entries: make(map[cacheKey]*cacheEntry)}
+}
+
+// WorkflowMeta is ParseWorkflowMeta, served from the cache while the file is
+// unchanged.
+func (c *Cache) WorkflowMeta(path string, lenient bool) (WorkflowMeta, error) {
+	v, err := c.get(cacheKey{kind: "meta", path: path, lenient: lenient}, func() (interface{}, error) {
+		return ParseWorkflowMeta(path, lenient)
+	})
+	meta, _ := v.(WorkflowMeta)
+	meta.Tags = slices.Clone(meta.Tags)
+	return meta, err
+}
+
+// Clear drops every entry, so the next lookups read their files again.
+func (c *Cache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// Len returns the number of cached files.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// loadedInstances is a decoded instances file.
+type loadedInstances struct {
+	file    instancesFile
+	src     *sourceFile
+	missing bool // The file did not exist and counts as empty
+}
+
+// instances reads and decodes an instances file, through the cache when c is
+// not nil. Callers get their own copy of the maps, since loading modifies them.
+func (c *Cache) instances(path string, lenient bool) (*loadedInstances, error) {
+	v, err := c.get(cacheKey{kind: "instances", path: path, lenient: lenient}, func() (interface{}, error) {
+		return readInstances(path, lenient)
+	})
+	if err != nil {
+		return nil, err
+	}
+	loaded := *v.(*loadedInstances)
+	loaded.file = loaded.file.clone()
+	return &loaded, nil
+}
+
+// defaults reads and checks a defaults file, through the cache when c is not
+// nil. It returns nil when the file does not exist.
+func (c *Cache) defaults(path string, lenient bool) (*loadedDefaults, error) {
+	v, err := c.get(cacheKey{kind: "defaults", path: path, lenient: lenient}, func() (interface{}, error) {
+		return readDefaults(path, lenient)
+	})
+	if err != nil || v.(*loadedDefaults) == nil {
+		return nil, err
+	}
+	loaded := *v.(*loadedDefaults)
+	shared := instancesFile{Instances: loaded.file.Instances, GitHub: loaded.file.GitHub}.clone()
+	loaded.file.Instances, loaded.file.GitHub = shared.Instances, shared.GitHub
+	return &loaded, nil
+}
+
+// get returns the cached result of load for key, calling load when there is
+// no entry or the file has changed since it was made. Files that cannot be
+// stat'ed for a reason other than not existing are not cached.
+func (c *Cache) get(key cacheKey, load func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return load()
+	}
+	modTime, size, ok := fileVersion(key.path)
+	if !ok {
+		return load()
+	}
+
+	c.mu.Lock()
+	c.clock++
+	if e, hit := c.entries[key]; hit && e.modTime.Equal(modTime) && e.size == size {
+		e.used = c.clock
+		c.mu.Unlock()
+		return e.value, e.err
+	}
+	c.mu.Unlock()
+
+	// Parse without holding the lock; concurrent misses on the same file
+	// both parse it and the last one stored wins.
+	value, err := load()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock++
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.max {
+		c.evictLocked()
+	}
+	c.entries[key] = &cacheEntry{modTime: modTime, size: size, used: c.clock, value: value, err: err}
+	return value, err
+}
+
+// evictLocked drops the least recently used entry. c.mu must be held.
+func (c *Cache) evictLocked() {
+	var oldest cacheKey
+	var oldestUsed uint64
+	first := true
+	for key, e := range c.entries {
+		if first || e.used < oldestUsed {
+			oldest, oldestUsed, first = key, e.used, false
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// fileVersion returns what a cache entry for path is checked against. A
+// missing file has a size of -1. ok is false when path cannot be stat'ed.
+func fileVersion(path string) (modTime time.Time, size int64, ok bool) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, -1, true
+	}
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return info.ModTime(), info.Size(), true
+}
+
+// clone returns a copy of f whose maps can be modified without changing f.
+func (f instancesFile) clone() instancesFile {
+	out := f
+	if f.Instances != nil {
+		out.Instances = make(map[string]Instance, len(f.Instances))
+		for name, inst := range f.Instances {
+			inst.Headers = maps.Clone(inst.Headers)
+			inst.DefaultParams = maps.Clone(inst.DefaultParams)
+			out.Instances[name] = inst
+		}
+	}
+	out.Aliases = maps.Clone(f.Aliases)
+	if f.Profiles != nil {
+		out.Profiles = make(map[string]map[string]string, len(f.Profiles))
+		for name, aliases := range f.Profiles {
+			out.Profiles[name] = maps.Clone(aliases)
+		}
+	}
+	if f.GitHub != nil {
+		gh := *f.GitHub
+		if gh.App != nil {
+			app := *gh.App
+			gh.App = &app
+		}
+		out.GitHub = &gh
+	}
+	return out
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeWorkflow writes a minimal workflow named name and sets its
+// modification time, so tests do not depend on the file system's resolution.
+func writeWorkflow(t testing.TB, path, name string, modTime time.Time) {
+	t.Helper()
+	data := fmt.Sprintf("schema: 2\nname: %s\ntags: [prod]\nworkflow:\n  - name: Build\n    instance: local\n    job: /job/build\n", name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache_WorkflowMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wf.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeWorkflow(t, path, "First", start)
+
+	cache := NewCache(0)
+	meta, err := cache.WorkflowMeta(path, false)
+	if err != nil || meta.Name != "First" {
+		t.Fatalf("expected First, got %q, %v", meta.Name, err)
+	}
+	meta.Tags[0] = "changed"
+	if again, _ := cache.WorkflowMeta(path, false); again.Tags[0] != "prod" {
+		t.Errorf("expected callers not to share the cached tags, got %v", again.Tags)
+	}
+
+	// Same size and time: the cached parse is kept.
+	writeWorkflow(t, path, "Other", start)
+	if meta, _ := cache.WorkflowMeta(path, false); meta.Name != "First" {
+		t.Errorf("expected the cached parse while the file looks unchanged, got %q", meta.Name)
+	}
+	// A new modification time invalidates it.
+	writeWorkflow(t, path, "Other", start.Add(time.Second))
+	if meta, _ := cache.WorkflowMeta(path, false); meta.Name != "Other" {
+		t.Errorf("expected the file to be parsed again after it changed, got %q", meta.Name)
+	}
+	// Clear forces a reread even when nothing seems to have changed.
+	writeWorkflow(t, path, "Third", start.Add(time.Second))
+	cache.Clear()
+	if meta, _ := cache.WorkflowMeta(path, false); meta.Name != "Third" {
+		t.Errorf("expected Clear to drop the cached parse, got %q", meta.Name)
+	}
+
+	// Errors are cached and invalidated the same way.
+	if err := os.WriteFile(path, []byte("name: Broken\nowner: me\nworkflow: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.WorkflowMeta(path, false); err == nil {
+		t.Error("expected the unknown key to be reported")
+	}
+	if meta, err := cache.WorkflowMeta(path, true); err != nil || meta.Name != "Broken" {
+		t.Errorf("expected lenient lookups to be cached separately, got %q, %v", meta.Name, err)
+	}
+
+	var none *Cache
+	if meta, err := none.WorkflowMeta(path, true); err != nil || meta.Name != "Broken" {
+		t.Errorf("expected a nil cache to parse the file, got %q, %v", meta.Name, err)
+	}
+}
+
+func TestCache_SizeBound(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(3)
+	now := time.Now()
+	paths := make([]string, 5)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("wf%d.yaml", i))
+		writeWorkflow(t, paths[i], fmt.Sprintf("WF %d", i), now)
+	}
+	for _, p := range paths[:3] {
+		cache.WorkflowMeta(p, false)
+	}
+	cache.WorkflowMeta(paths[0], false) // wf1 is now the least recently used
+	cache.WorkflowMeta(paths[3], false)
+	if n := cache.Len(); n != 3 {
+		t.Fatalf("expected the cache to stay at 3 entries, got %d", n)
+	}
+	cache.mu.Lock()
+	_, kept := cache.entries[cacheKey{kind: "meta", path: paths[0]}]
+	_, evicted := cache.entries[cacheKey{kind: "meta", path: paths[1]}]
+	cache.mu.Unlock()
+	if !kept || evicted {
+		t.Errorf("expected the least recently used entry to be evicted (kept wf0: %v, kept wf1: %v)", kept, evicted)
+	}
+}
+
+func TestCache_Instances(t *testing.T) {
+	cache := NewCache(0)
+	opts := LoadOptions{Cache: cache}
+	cfg, err := LoadWithOptions(td("default_params_instances.yaml"), td("default_params_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for name, inst := range cfg.Instances {
+		inst.URL = "http://changed"
+		if inst.DefaultParams != nil {
+			inst.DefaultParams["INJECTED"] = "x"
+		}
+		cfg.Instances[name] = inst
+	}
+
+	again, err := LoadWithOptions(td("default_params_instances.yaml"), td("default_params_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("cached Load failed: %v", err)
+	}
+	fresh, err := Load(td("default_params_instances.yaml"), td("default_params_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for name, inst := range fresh.Instances {
+		got := again.Instances[name]
+		if got.URL != inst.URL || len(got.DefaultParams) != len(inst.DefaultParams) {
+			t.Errorf("instance %q: expected the cached load to match a fresh one, got %+v, want %+v", name, got, inst)
+		}
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf("expected only the instances file to be cached, got %d entries", n)
+	}
+
+	missing := filepath.Join(t.TempDir(), "instances.yaml")
+	if _, err := cache.instances(missing, false); err != nil {
+		t.Fatalf("expected a missing instances file to count as empty, got %v", err)
+	}
+	if err := os.WriteFile(missing, []byte("instances:\n  local:\n    url: http://localhost:8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := cache.instances(missing, false); err != nil || loaded.missing || len(loaded.file.Instances) != 1 {
+		t.Errorf("expected the new instances file to be read once it exists, got %+v, %v", loaded, err)
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		writeWorkflow(t, filepath.Join(dir, fmt.Sprintf("wf%d.yaml", i)), fmt.Sprintf("WF %d", i), now)
+	}
+	cache := NewCache(4)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				n := (g + i) % 10
+				path := filepath.Join(dir, fmt.Sprintf("wf%d.yaml", n))
+				if meta, err := cache.WorkflowMeta(path, false); err != nil || meta.Name != fmt.Sprintf("WF %d", n) {
+					t.Errorf("%s: got %q, %v", path, meta.Name, err)
+				}
+				if i%10 == 0 {
+					cache.Clear()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := cache.Len(); n > 4 {
+		t.Errorf("expected at most 4 entries, got %d", n)
+	}
+}
+
+// benchmarkWorkflowDir writes n workflow files and returns their paths.
+func benchmarkWorkflowDir(b *testing.B, n int) []string {
+	dir := b.TempDir()
+	now := time.Now()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("wf%d.yaml", i))
+		writeWorkflow(b, paths[i], fmt.Sprintf("WF %d", i), now)
+	}
+	return paths
+}
+
+// The two benchmarks below list 200 workflow files, as the dashboard does on
+// every poll, without and with a cache.
+func BenchmarkParseWorkflowMeta(b *testing.B) {
+	paths := benchmarkWorkflowDir(b, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			if _, err := ParseWorkflowMeta(p, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCacheWorkflowMeta(b *testing.B) {
+	paths := benchmarkWorkflowDir(b, 200)
+	cache := NewCache(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			if _, err := cache.WorkflowMeta(p, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Load(td("default_params_instances.yaml"), td("default_params_workflow.yaml")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadCached(b *testing.B) {
+	opts := LoadOptions{Cache: NewCache(0)}
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadWithOptions(td("default_params_instances.yaml"), td("default_params_workflow.yaml"), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
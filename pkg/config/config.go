@@ -1,10 +1,24 @@
 package config
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -22,17 +36,168 @@ func Slugify(s string) string {
 }
 
 type Instance struct {
-	URL     string `yaml:"url"`
-	AuthEnv string `yaml:"auth_env,omitempty"`
-	Token   string `yaml:"token,omitempty"` // Direct token storage
+	URL          string `yaml:"url"`
+	AuthEnv      string `yaml:"auth_env,omitempty"`
+	AuthCommand  string `yaml:"auth_command,omitempty"`  // Shell command printing the token, e.g. a vault CLI
+	AuthKeychain string `yaml:"auth_keychain,omitempty"` // Key of the token in the OS credential store; see LoadOptions.Credentials
+	Token        string `yaml:"token,omitempty"`         // Direct token storage
+
+	BuildTimeoutSecs    int `yaml:"build_timeout_secs,omitempty"`     // Max time a build may run before it is aborted (default: no limit)
+	QueueStuckGraceSecs int `yaml:"queue_stuck_grace_secs,omitempty"` // Max time a queue item may stay stuck before it is cancelled (default: defaultQueueStuckGrace)
+
+	Headers map[string]string `yaml:"headers,omitempty"` // Extra headers sent with every request, e.g. an API gateway key
+
+	// Crumb fetches a CSRF crumb before each POST and keeps the session
+	// cookies Jenkins sets, for instances whose proxy rejects crumbs sent
+	// without the session they were issued for.
+	Crumb bool `yaml:"crumb,omitempty"`
+
+	DefaultParams map[string]string `yaml:"default_params,omitempty"` // Job parameters added to every step on this instance; step params win
+
+	// Override lets a workflow file instance replace the url of the instances
+	// file's instance of the same name; see checkInstanceConflicts.
+	Override bool `yaml:"override,omitempty"`
+
+	sources *secretSources // Where GetToken looks tokens up; set by loads
+}
+
+// BuildTimeout returns the configured max build duration, or 0 for no limit.
+func (i Instance) BuildTimeout() time.Duration {
+	if i.BuildTimeoutSecs <= 0 {
+		return 0
+	}
+	return time.Duration(i.BuildTimeoutSecs) * time.Second
+}
+
+// defaultQueueStuckGrace is how long a queue item Jenkins flags as stuck is
+// waited on without queue_stuck_grace_secs. Jenkins flags items no online
+// agent can run, and items that have waited ten times their job's usual
+// duration.
+const defaultQueueStuckGrace = 5 * time.Minute
+
+// QueueStuckGrace returns how long a stuck queue item is waited on before it
+// is cancelled.
+func (i Instance) QueueStuckGrace() time.Duration {
+	if i.QueueStuckGraceSecs <= 0 {
+		return defaultQueueStuckGrace
+	}
+	return time.Duration(i.QueueStuckGraceSecs) * time.Second
+}
+
+// defaultRetryDelay is the pause before a workflow retry without
+// retry_delay_secs.
+const defaultRetryDelay = 30 * time.Second
+
+// RetryDelay returns the pause before each retry of a failed run.
+func (c *Config) RetryDelay() time.Duration {
+	if c.RetryDelaySecs > 0 {
+		return time.Duration(c.RetryDelaySecs) * time.Second
+	}
+	return defaultRetryDelay
 }
 
+// authCommandTimeout bounds how long an auth_command may run.
+const authCommandTimeout = 30 * time.Second
+
 type Step struct {
 	Name     string            `yaml:"name"`
 	ID       string            `yaml:"id,omitempty"` // Optional explicit ID for ${steps.<id>.<field>} references; defaults to Slugify(Name)
 	Instance string            `yaml:"instance"`
 	Job      string            `yaml:"job"`
 	Params   map[string]string `yaml:"params,omitempty"` // Job parameters
+	Wait     string            `yaml:"wait,omitempty"`   // How far to follow the build: "queued", "started" or "completed" (default)
+	Stage    string            `yaml:"stage,omitempty"`  // Label such as "build" or "deploy" the dashboard groups steps by; no effect on execution
+	// AggregateSubbuilds makes the step's result the worst of its build's and
+	// those of the build's matrix configurations, so a failed configuration
+	// fails the step even when Jenkins reports the parent build as a success.
+	AggregateSubbuilds bool `yaml:"aggregate_subbuilds,omitempty"`
+	// BuildToken is the job's "Trigger builds remotely" token, sent as the
+	// token query param alongside the instance's auth. It is treated as a secret.
+	BuildToken string `yaml:"build_token,omitempty"`
+
+	Template      string         `yaml:"template,omitempty"`       // Name of a StepTemplate merged under the step's own fields
+	IfRunning     string         `yaml:"if_running,omitempty"`     // What to do when the job already has a running build: "trigger" (default), "wait" or "fail"
+	ForwardInputs *ForwardInputs `yaml:"forward_inputs,omitempty"` // Inputs passed as params; see applyForwardedInputs
+
+	ParamRules   map[string]ParamRule `yaml:"param_rules,omitempty"`   // Constraints on param values, checked before triggering
+	SecretParams []string             `yaml:"secret_params,omitempty"` // Params whose values are masked outside the request to Jenkins; see MaskParams
+
+	Enabled *bool `yaml:"enabled,omitempty"` // false skips the step on every run without removing it; nil = default true
+}
+
+// Disabled reports whether the step is turned off with enabled: false.
+func (s Step) Disabled() bool {
+	return s.Enabled != nil && !*s.Enabled
+}
+
+// ForwardInputs selects the workflow inputs a step passes to its job as
+// parameters of the same name. In YAML it is either `true` for every input or
+// a list of input names.
+type ForwardInputs struct {
+	All   bool
+	Names []string
+}
+
+// UnmarshalYAML accepts a boolean or a list of input names.
+func (f *ForwardInputs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&f.Names)
+	}
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!bool" {
+		return value.Decode(&f.All)
+	}
+	return fmt.Errorf("line %d: forward_inputs must be true, false or a list of input names", value.Line)
+}
+
+// MarshalYAML writes the form UnmarshalYAML reads.
+func (f ForwardInputs) MarshalYAML() (interface{}, error) {
+	if f.Names != nil {
+		return f.Names, nil
+	}
+	return f.All, nil
+}
+
+// Selected returns the names of the inputs to forward, sorted.
+func (f *ForwardInputs) Selected(inputs map[string]string) []string {
+	if f == nil {
+		return nil
+	}
+	if f.All {
+		return sortedKeys(inputs)
+	}
+	return f.Names
+}
+
+// Step wait modes. A step succeeds as soon as its build reaches the chosen
+// point; only WaitCompleted reports the build's own result.
+const (
+	WaitQueued    = "queued"
+	WaitStarted   = "started"
+	WaitCompleted = "completed"
+)
+
+// WaitMode returns the step's wait mode, defaulting to WaitCompleted.
+func (s Step) WaitMode() string {
+	if s.Wait == "" {
+		return WaitCompleted
+	}
+	return s.Wait
+}
+
+// What a step does when its job already has a running build.
+const (
+	IfRunningTrigger = "trigger"
+	IfRunningWait    = "wait"
+	IfRunningFail    = "fail"
+)
+
+// IfRunningMode returns the step's if_running mode, defaulting to
+// IfRunningTrigger.
+func (s Step) IfRunningMode() string {
+	if s.IfRunning == "" {
+		return IfRunningTrigger
+	}
+	return s.IfRunning
 }
 
 // ResolvedID returns the explicit ID if set, otherwise the slugified Name.
@@ -45,38 +210,210 @@ func (s Step) ResolvedID() string {
 
 // GitHubConfig holds global GitHub authentication settings
 type GitHubConfig struct {
-	AuthEnv string `yaml:"auth_env,omitempty"` // Env var with GitHub token
-	Token   string `yaml:"token,omitempty"`    // Direct token (local only)
+	AuthEnv            string     `yaml:"auth_env,omitempty"`             // Env var with GitHub token
+	AuthKeychain       string     `yaml:"auth_keychain,omitempty"`        // Key of the token in the OS credential store
+	Token              string     `yaml:"token,omitempty"`                // Direct token (local only)
+	RequestTimeoutSecs int        `yaml:"request_timeout_secs,omitempty"` // Per-request HTTP timeout (default: 30)
+	App                *GitHubApp `yaml:"app,omitempty"`                  // Authenticate as a GitHub App installation instead of with a token
+	APIURL             string     `yaml:"api_url,omitempty"`              // API root for GitHub Enterprise Server (default: https://api.github.com)
+
+	sources *secretSources // Where GetToken looks tokens up; set by loads
+}
+
+// GitHubApp identifies a GitHub App installation to mint access tokens for
+type GitHubApp struct {
+	AppID          int64  `yaml:"app_id"`
+	InstallationID int64  `yaml:"installation_id"`
+	PrivateKeyPath string `yaml:"private_key_path"` // PEM key; relative paths are resolved against the file that sets it
+}
+
+// RequestTimeout returns the configured per-request timeout, or 0 for the client default.
+func (g *GitHubConfig) RequestTimeout() time.Duration {
+	if g == nil || g.RequestTimeoutSecs <= 0 {
+		return 0
+	}
+	return time.Duration(g.RequestTimeoutSecs) * time.Second
 }
 
-// GetToken retrieves the GitHub token from env var or direct config
+// GetToken retrieves the GitHub token from direct config, the credential
+// store or an env var (see LoadOptions.Env), in that order
 func (g GitHubConfig) GetToken() (string, error) {
 	if g.Token != "" {
 		return g.Token, nil
 	}
+	if g.AuthKeychain != "" {
+		return g.sources.lookupKeychain(g.AuthKeychain)
+	}
 	if g.AuthEnv != "" {
-		val := os.Getenv(g.AuthEnv)
-		if val == "" {
-			return "", fmt.Errorf("environment variable %q is not set", g.AuthEnv)
-		}
-		return val, nil
+		return g.sources.lookupAuthEnv(g.AuthEnv)
 	}
 	// Empty token is valid for public repos
 	return "", nil
 }
 
+// HasAuth reports whether g names a credential: a token, auth_env,
+// auth_keychain or app. Without one, requests are anonymous and only see
+// public repositories.
+func (g *GitHubConfig) HasAuth() bool {
+	return g != nil && (g.Token != "" || g.AuthEnv != "" || g.AuthKeychain != "" || g.App != nil)
+}
+
+// validate checks the request timeout, the app block and api_url.
+func (g *GitHubConfig) validate() error {
+	if g.RequestTimeoutSecs < 0 {
+		return fmt.Errorf("github: request_timeout_secs must not be negative")
+	}
+	if g.App != nil {
+		if err := g.validateApp(); err != nil {
+			return err
+		}
+	}
+	if g.APIURL != "" {
+		u, err := url.Parse(g.APIURL)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("github: api_url must be an http(s) URL such as https://github.example.com/api/v3, got %q", g.APIURL)
+		}
+	}
+	return nil
+}
+
+// validateApp checks that the app block is complete and not mixed with token auth.
+func (g *GitHubConfig) validateApp() error {
+	if g.Token != "" || g.AuthEnv != "" || g.AuthKeychain != "" {
+		return fmt.Errorf("github: 'app' cannot be combined with 'token', 'auth_keychain' or 'auth_env'")
+	}
+	if g.App.AppID <= 0 {
+		return fmt.Errorf("github.app: app_id is required")
+	}
+	if g.App.InstallationID <= 0 {
+		return fmt.Errorf("github.app: installation_id is required")
+	}
+	if g.App.PrivateKeyPath == "" {
+		return fmt.Errorf("github.app: private_key_path is required")
+	}
+	return nil
+}
+
+// SlackTLS configures how notifications verify a Slack-compatible webhook
+// server, such as a Mattermost instance with a private CA.
+type SlackTLS struct {
+	CACert             string `yaml:"ca_cert,omitempty"`              // PEM file of CAs trusted in addition to the system's; relative paths are resolved against the workflow file
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"` // Skip certificate verification entirely
+}
+
+// validate checks that the CA file, if any, holds at least one certificate,
+// so a bad path fails the load instead of every notification silently.
+func (t *SlackTLS) validate() error {
+	if t.CACert == "" {
+		return nil
+	}
+	pem, err := os.ReadFile(t.CACert)
+	if err != nil {
+		return fmt.Errorf("slack_tls: reading ca_cert: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+		return fmt.Errorf("slack_tls: ca_cert %s contains no PEM certificates", t.CACert)
+	}
+	return nil
+}
+
 // PRWait represents a wait condition for a GitHub PR
 type PRWait struct {
-	Name             string `yaml:"name"`
-	Owner            string `yaml:"owner"`                          // GitHub org/user
-	Repo             string `yaml:"repo"`                           // Repository name
-	PRNumber         int    `yaml:"pr_number"`                      // PR number to monitor
-	WaitFor          string `yaml:"wait_for"`                       // Target state: "merged", "closed"
-	PollSecs         int    `yaml:"poll_secs,omitempty"`            // Poll interval (default: 30)
-	HeadBranch       string `yaml:"head_branch,omitempty"`          // Optional branch name to resolve PR dynamically
-	AutoUpdateBranch *bool  `yaml:"auto_update_branch,omitempty"`   // Auto-merge base into head when PR is behind. nil = default true
-	ResolvedURL      string `yaml:"-"`
-	ResolvedTitle    string `yaml:"-"`
+	Name             string        `yaml:"name"`
+	Owner            string        `yaml:"owner"`                        // GitHub org/user
+	Repo             string        `yaml:"repo"`                         // Repository name
+	PRNumber         int           `yaml:"pr_number"`                    // PR number to monitor
+	WaitFor          string        `yaml:"wait_for"`                     // Target state: "merged", "closed"
+	PollSecs         int           `yaml:"poll_secs,omitempty"`          // Poll interval (default: 30)
+	PollBackoff      bool          `yaml:"poll_backoff,omitempty"`       // Double the poll interval after each check, with jitter
+	PollMaxSecs      int           `yaml:"poll_max_secs,omitempty"`      // Cap on the backed-off interval (default: 300)
+	HeadBranch       string        `yaml:"head_branch,omitempty"`        // Optional branch name to resolve PR dynamically
+	AutoUpdateBranch *bool         `yaml:"auto_update_branch,omitempty"` // Auto-merge base into head when PR is behind. nil = default true
+	GitHub           *GitHubConfig `yaml:"github,omitempty"`             // Overrides the global github config for this wait; see Config.GitHubFor
+	PRNumberTemplate string        `yaml:"-"`                            // Raw pr_number when written as a ${input} template
+	ResolvedURL      string        `yaml:"-"`
+	ResolvedTitle    string        `yaml:"-"`
+	ResolvedBranch   string        `yaml:"-"`
+	ResolvedMergeSHA string        `yaml:"-"` // Set once the PR is merged
+
+	unknownFields []string // Keys with no matching field, reported by strict loads
+}
+
+// prWaitFields are the keys a wait_for_pr mapping may use.
+var prWaitFields = yamlFieldNames(reflect.TypeOf(PRWait{}))
+
+// UnmarshalYAML accepts pr_number either as an integer or as a ${input} template
+// string. Templates are kept in PRNumberTemplate until ApplyInputs resolves them.
+// Node.Decode does not inherit the decoder's strictness, so unknown keys are
+// recorded here and rejected by strict loads.
+func (p *PRWait) UnmarshalYAML(value *yaml.Node) error {
+	type plain PRWait
+	node := *value
+	var numberTemplate string
+	var unknown []string
+	if node.Kind == yaml.MappingNode {
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			if !prWaitFields[key.Value] {
+				unknown = append(unknown, fmt.Sprintf("line %d: field %s not found in type config.PRWait", key.Line, key.Value))
+			}
+			if key.Value == "pr_number" && val.Kind == yaml.ScalarNode && strings.Contains(val.Value, "${") {
+				numberTemplate = val.Value
+				continue
+			}
+			content = append(content, key, val)
+		}
+		node.Content = content
+	}
+	if err := node.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	p.PRNumberTemplate = numberTemplate
+	p.unknownFields = unknown
+	return nil
+}
+
+// yamlFieldNames returns the yaml keys of a struct type's fields.
+func yamlFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// withInputs returns a copy of the PR wait with ${input} placeholders in its
+// owner/repo/pr_number/head_branch/wait_for fields replaced from inputs, which
+// may also carry vars.<name> keys (see Config.TemplateVars).
+func (p PRWait) withInputs(inputs map[string]string) (PRWait, error) {
+	p.Name = substituteIfTemplate(p.Name, inputs)
+	p.Owner = substituteIfTemplate(p.Owner, inputs)
+	p.Repo = substituteIfTemplate(p.Repo, inputs)
+	p.HeadBranch = substituteIfTemplate(p.HeadBranch, inputs)
+	p.WaitFor = substituteIfTemplate(p.WaitFor, inputs)
+	if p.PRNumberTemplate != "" {
+		raw := strings.TrimSpace(Substitute(p.PRNumberTemplate, inputs))
+		n := 0
+		if raw != "" {
+			var err error
+			n, err = strconv.Atoi(raw)
+			if err != nil {
+				return p, fmt.Errorf("pr_number %q resolved to %q, which is not an integer", p.PRNumberTemplate, raw)
+			}
+		}
+		p.PRNumber = n
+		p.PRNumberTemplate = ""
+	}
+	return p, nil
+}
+
+// templateFields returns the raw values of the PR wait fields that support ${input} substitution.
+func (p *PRWait) templateFields() []string {
+	return []string{p.Name, p.Owner, p.Repo, p.PRNumberTemplate, p.HeadBranch, p.WaitFor}
 }
 
 // ShouldAutoUpdate returns true unless explicitly set to false. Default is on.
@@ -87,6 +424,88 @@ func (p *PRWait) ShouldAutoUpdate() bool {
 	return *p.AutoUpdateBranch
 }
 
+// GitHubFor returns the github config the PR wait uses: its own github block
+// over the global one, or the global one when it has none. An override that
+// names a credential replaces the global credential entirely, so two orgs'
+// tokens never mix; api_url and request_timeout_secs fall back field by
+// field. It returns nil when neither is configured.
+func (c *Config) GitHubFor(pr *PRWait) *GitHubConfig {
+	if pr == nil || pr.GitHub == nil {
+		return c.GitHub
+	}
+	merged := *pr.GitHub
+	merged.sources = c.sources
+	if global := c.GitHub; global != nil {
+		if !merged.HasAuth() {
+			merged.Token, merged.AuthEnv, merged.AuthKeychain, merged.App = global.Token, global.AuthEnv, global.AuthKeychain, global.App
+		}
+		merged.APIURL = cmp.Or(merged.APIURL, global.APIURL)
+		merged.RequestTimeoutSecs = cmp.Or(merged.RequestTimeoutSecs, global.RequestTimeoutSecs)
+	}
+	return &merged
+}
+
+// GitHubStatus posts a result back to GitHub: a commit status on SHA and/or a
+// comment on PRNumber. String fields support ${input} and ${steps.<id>.<field>}
+// substitution at run time.
+type GitHubStatus struct {
+	Name        string `yaml:"name"`
+	Owner       string `yaml:"owner"`
+	Repo        string `yaml:"repo"`
+	SHA         string `yaml:"sha,omitempty"`         // Commit to set the status on
+	State       string `yaml:"state,omitempty"`       // "error", "failure", "pending", "success"
+	Context     string `yaml:"context,omitempty"`     // Status context (default: "jenkins-flow")
+	Description string `yaml:"description,omitempty"` // Short status description
+	TargetURL   string `yaml:"target_url,omitempty"`  // Link shown next to the status
+	PRNumber    int    `yaml:"pr_number,omitempty"`   // PR to comment on
+	Comment     string `yaml:"comment,omitempty"`     // Comment body
+}
+
+// Command runs a local shell command as a workflow item, e.g. a script that
+// invalidates a cache. Run supports ${input}, ${vars.<name>},
+// ${steps.<id>.<field>} and ${pr.<id>.<field>} substitution at run time; other
+// ${...} references are left for the shell.
+type Command struct {
+	Name        string `yaml:"name"`
+	ID          string `yaml:"id,omitempty"`           // Explicit ID; defaults to the slugified name
+	Run         string `yaml:"run"`                    // Command line, run with sh -c
+	Dir         string `yaml:"dir,omitempty"`          // Working directory (default: the server's)
+	TimeoutSecs int    `yaml:"timeout_secs,omitempty"` // Kill the command after this long (default: 600)
+}
+
+// validGitHubStates lists the commit status states accepted by the GitHub API.
+var validGitHubStates = map[string]bool{"error": true, "failure": true, "pending": true, "success": true}
+
+// validNotifyOutcomes lists the run outcomes accepted in `notify_on`.
+var validNotifyOutcomes = map[string]bool{"success": true, "failure": true, "unstable": true, "aborted": true}
+
+// Per-item notification events accepted in `notify.events`.
+const (
+	NotifyOnStart   = "on_start"
+	NotifyOnSuccess = "on_success"
+	NotifyOnFailure = "on_failure"
+)
+
+// ItemNotify configures Slack notifications for a single workflow item.
+type ItemNotify struct {
+	Events  []string `yaml:"events"`            // Any of on_start, on_success, on_failure
+	Channel string   `yaml:"channel,omitempty"` // Optional channel override
+	Webhook string   `yaml:"webhook,omitempty"` // Optional webhook override (default: workflow slack_webhook)
+}
+
+// Has reports whether the given event is enabled.
+func (n *ItemNotify) Has(event string) bool {
+	if n == nil {
+		return false
+	}
+	for _, e := range n.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
 // ParallelGroup represents a group of steps to run concurrently.
 // All steps must succeed before the workflow proceeds.
 type ParallelGroup struct {
@@ -94,8 +513,8 @@ type ParallelGroup struct {
 	Steps []Step `yaml:"steps"`
 }
 
-// WorkflowItem represents either a single step, a parallel group, or a PR wait.
-// Exactly one of Step, Parallel, or WaitForPR should be populated.
+// WorkflowItem represents either a single step, a parallel group, a PR wait, a GitHub status post,
+// or a local command. Exactly one of Step, Parallel, WaitForPR, GitHubStatus, or Command should be populated.
 type WorkflowItem struct {
 	// Inline step fields (when not using parallel)
 	Name     string            `yaml:"name,omitempty"`
@@ -103,10 +522,30 @@ type WorkflowItem struct {
 	Instance string            `yaml:"instance,omitempty"`
 	Job      string            `yaml:"job,omitempty"`
 	Params   map[string]string `yaml:"params,omitempty"`
+	Wait     string            `yaml:"wait,omitempty"`
+	Stage    string            `yaml:"stage,omitempty"`
+
+	AggregateSubbuilds bool   `yaml:"aggregate_subbuilds,omitempty"`
+	BuildToken         string `yaml:"build_token,omitempty"`
+
+	Template      string         `yaml:"template,omitempty"`
+	IfRunning     string         `yaml:"if_running,omitempty"`
+	ForwardInputs *ForwardInputs `yaml:"forward_inputs,omitempty"`
+
+	ParamRules   map[string]ParamRule `yaml:"param_rules,omitempty"`
+	SecretParams []string             `yaml:"secret_params,omitempty"`
+	// enabled: false skips the item, or every step of a parallel group
+	Enabled *bool `yaml:"enabled,omitempty"`
 	// Parallel group
 	Parallel *ParallelGroup `yaml:"parallel,omitempty"`
 	// PR wait (trigger on PR merge/close)
 	WaitForPR *PRWait `yaml:"wait_for_pr,omitempty"`
+	// GitHub commit status / PR comment
+	GitHubStatus *GitHubStatus `yaml:"github_status,omitempty"`
+	// Local shell command
+	Command *Command `yaml:"command,omitempty"`
+	// Optional per-item Slack notifications
+	Notify *ItemNotify `yaml:"notify,omitempty"`
 }
 
 // IsParallel returns true if this item is a parallel group.
@@ -119,6 +558,44 @@ func (w *WorkflowItem) IsPRWait() bool {
 	return w.WaitForPR != nil
 }
 
+// IsGitHubStatus returns true if this item posts a status back to GitHub.
+func (w *WorkflowItem) IsGitHubStatus() bool {
+	return w.GitHubStatus != nil
+}
+
+// IsCommand returns true if this item runs a local shell command.
+func (w *WorkflowItem) IsCommand() bool {
+	return w.Command != nil
+}
+
+// IsStep returns true if this item is a single Jenkins step.
+func (w *WorkflowItem) IsStep() bool {
+	return !w.IsParallel() && !w.IsPRWait() && !w.IsGitHubStatus() && !w.IsCommand()
+}
+
+// ItemName returns the name that addresses this item: the step name, the
+// parallel group name, the PR wait name, the GitHub status name, or the
+// command name. Unnamed parallel groups return "".
+func (w *WorkflowItem) ItemName() string {
+	switch {
+	case w.IsCommand():
+		return w.Command.Name
+	case w.IsPRWait():
+		return w.WaitForPR.Name
+	case w.IsGitHubStatus():
+		return w.GitHubStatus.Name
+	case w.IsParallel():
+		return w.Parallel.Name
+	default:
+		return w.Name
+	}
+}
+
+// Disabled reports whether the item is turned off with enabled: false.
+func (w *WorkflowItem) Disabled() bool {
+	return w.Enabled != nil && !*w.Enabled
+}
+
 // AsStep converts inline step fields to a Step struct.
 func (w *WorkflowItem) AsStep() Step {
 	return Step{
@@ -127,16 +604,122 @@ func (w *WorkflowItem) AsStep() Step {
 		Instance: w.Instance,
 		Job:      w.Job,
 		Params:   w.Params,
+		Wait:     w.Wait,
+		Stage:    w.Stage,
+
+		AggregateSubbuilds: w.AggregateSubbuilds,
+		BuildToken:         w.BuildToken,
+
+		Template:      w.Template,
+		IfRunning:     w.IfRunning,
+		ForwardInputs: w.ForwardInputs,
+
+		ParamRules:   w.ParamRules,
+		SecretParams: w.SecretParams,
+
+		Enabled: w.Enabled,
 	}
 }
 
 type Config struct {
-	Name         string              `yaml:"name"`
-	SlackWebhook string              `yaml:"slack_webhook,omitempty"`
-	Instances    map[string]Instance `yaml:"instances"`
-	GitHub       *GitHubConfig       `yaml:"github,omitempty"` // Global GitHub config
-	Inputs       map[string]string   `yaml:"inputs,omitempty"`
-	Workflow     []WorkflowItem      `yaml:"workflow"`
+	Name                string              `yaml:"name"`
+	Tags                []string            `yaml:"tags,omitempty"` // Labels for grouping and filtering the workflow list
+	SlackWebhook        string              `yaml:"slack_webhook,omitempty"`
+	SlackChannel        string              `yaml:"slack_channel,omitempty"`         // Overrides the webhook's default channel
+	SlackUsername       string              `yaml:"slack_username,omitempty"`        // Overrides the webhook's bot name
+	SlackTLS            *SlackTLS           `yaml:"slack_tls,omitempty"`             // TLS settings for a self-hosted Slack-compatible webhook
+	SlackWebhookHosts   []string            `yaml:"slack_webhook_hosts,omitempty"`   // Hosts other than hooks.slack.com that webhooks may point at
+	NotifyOn            []string            `yaml:"notify_on,omitempty"`             // Run outcomes that send the completion notification; empty means all
+	VerifyJobs          bool                `yaml:"verify_jobs,omitempty"`           // Check that every step's job exists before the run starts
+	VerifyParams        bool                `yaml:"verify_params,omitempty"`         // Also check each step's params against the parameters its job declares
+	VerifyWebhooks      bool                `yaml:"verify_webhooks,omitempty"`       // Check that every Slack webhook answers before the run starts
+	AllowDuplicateNames bool                `yaml:"allow_duplicate_names,omitempty"` // Skip the unique name checks; step IDs must still be unique
+	Retries             int                 `yaml:"retries,omitempty"`               // Times a failed run starts over from the beginning
+	RetryDelaySecs      int                 `yaml:"retry_delay_secs,omitempty"`      // Pause before each retry (default: 30)
+	Instances           map[string]Instance `yaml:"instances"`
+	GitHub              *GitHubConfig       `yaml:"github,omitempty"` // Global GitHub config
+	Inputs              map[string]string   `yaml:"inputs,omitempty"`
+	Vars                map[string]string   `yaml:"vars,omitempty"` // Workflow-level values referenced as ${vars.<name>}
+	Workflow            []WorkflowItem      `yaml:"workflow"`
+	Finally             []WorkflowItem      `yaml:"finally,omitempty"` // Always run after Workflow, even on failure
+	Profile             string              `yaml:"-"`                 // Instance profile used to resolve aliases; "" for the defaults
+	DefaultsFile        string              `yaml:"-"`                 // Defaults file merged beneath the other two; "" when there was none
+	JenkinsPollSecs     int                 `yaml:"-"`                 // Interval between Jenkins queue and build checks, from LoadOptions.PollDefaults; 0 for the built-in intervals
+	Warnings            []string            `yaml:"-"`                 // Problems the load worked around, for the caller to report
+
+	// Source files, for locating validation errors; nil when not loaded from files.
+	instancesSrc *sourceFile
+	// instancesMissing is set when the instances file did not exist.
+	instancesMissing bool
+	workflowSrc      *sourceFile
+	defaultsSrc      *sourceFile
+	// allowUnknownInstances is LoadOptions.AllowUnknownInstances.
+	allowUnknownInstances bool
+	// sources are where the instances and github config look up tokens.
+	sources *secretSources
+}
+
+// AllItems returns the workflow items followed by the finally items. The
+// engine and server state index items in this order, so finally item j has
+// index len(c.Workflow)+j.
+func (c *Config) AllItems() []WorkflowItem {
+	items := make([]WorkflowItem, 0, len(c.Workflow)+len(c.Finally))
+	items = append(items, c.Workflow...)
+	return append(items, c.Finally...)
+}
+
+// IsFinallyIndex reports whether index (as used by AllItems) is a finally item.
+func (c *Config) IsFinallyIndex(index int) bool {
+	return index >= len(c.Workflow) && index < len(c.Workflow)+len(c.Finally)
+}
+
+// ItemByName returns the workflow or finally item with the given name and its
+// index as used by AllItems. It reports false when no item, or more than one
+// (see allow_duplicate_names), has the name.
+func (c *Config) ItemByName(name string) (*WorkflowItem, int, bool) {
+	if name == "" {
+		return nil, -1, false
+	}
+	var found *WorkflowItem
+	index := -1
+	for i := range len(c.Workflow) + len(c.Finally) {
+		item := c.itemAt(i)
+		if item.ItemName() != name {
+			continue
+		}
+		if found != nil {
+			return nil, -1, false
+		}
+		found, index = item, i
+	}
+	return found, index, found != nil
+}
+
+// itemAt returns the item at index, as used by AllItems, in place.
+func (c *Config) itemAt(index int) *WorkflowItem {
+	if index < len(c.Workflow) {
+		return &c.Workflow[index]
+	}
+	return &c.Finally[index-len(c.Workflow)]
+}
+
+// IsDisabled reports whether step stepIndex of item itemIndex, indexed as in
+// AllItems, is turned off in the workflow file, on its own or with its
+// parallel group. Items other than parallel groups have the single step 0.
+func (c *Config) IsDisabled(itemIndex, stepIndex int) bool {
+	all := c.AllItems()
+	if itemIndex < 0 || itemIndex >= len(all) {
+		return false
+	}
+	item := all[itemIndex]
+	if item.Disabled() {
+		return true
+	}
+	if item.IsParallel() {
+		steps := item.Parallel.Steps
+		return stepIndex >= 0 && stepIndex < len(steps) && steps[stepIndex].Disabled()
+	}
+	return false
 }
 
 // FindTemplateVars extracts variable names from ${var} placeholders in text.
@@ -151,7 +734,7 @@ func FindTemplateVars(text string) []string {
 
 // Substitute replaces ${var} placeholders in text with values from vars.
 func Substitute(text string, vars map[string]string) string {
-	return os.Expand(text, func(key string) string {
+	return substituteFunc(text, func(key string) string {
 		if val, ok := vars[key]; ok {
 			return val
 		}
@@ -159,157 +742,1191 @@ func Substitute(text string, vars map[string]string) string {
 	})
 }
 
+// substituteFunc is Substitute with each placeholder's replacement returned
+// by lookup, which gets the text between the braces.
+func substituteFunc(text string, lookup func(key string) string) string {
+	return os.Expand(text, lookup)
+}
+
+func substituteIfTemplate(value string, inputs map[string]string) string {
+	if value == "" || !strings.Contains(value, "${") {
+		return value
+	}
+	return Substitute(value, inputs)
+}
+
+// ApplyInputs resolves ${input} and ${vars.<name>} placeholders in PR wait
+// fields and in the URLs of the instances the steps use against c.Inputs, and
+// re-validates each with the substituted values. Call it after any run-time
+// input overrides have been merged into c.Inputs.
+func (c *Config) ApplyInputs() error {
+	if _, err := c.resolveVars(c.Inputs); err != nil {
+		return c.workflowSrc.wrap(err, "vars")
+	}
+	if err := c.resolveInstanceURLs(true); err != nil {
+		return err
+	}
+	vars := c.TemplateVars(c.Inputs)
+	for i := range c.Workflow {
+		item := &c.Workflow[i]
+		if !item.IsPRWait() {
+			continue
+		}
+		resolved, err := item.WaitForPR.withInputs(vars)
+		if err != nil {
+			return c.workflowSrc.wrap(fmt.Errorf("wait_for_pr[%d] (%q): %w", i, item.WaitForPR.Name, err), "workflow", i)
+		}
+		if err := c.validatePRWait(&resolved, fmt.Sprintf("wait_for_pr[%d]", i)); err != nil {
+			return c.workflowSrc.wrap(err, "workflow", i)
+		}
+		*item.WaitForPR = resolved
+	}
+	return nil
+}
+
+// LoadOptions controls how Load reads the config files.
+type LoadOptions struct {
+	Profile string // Profile selecting instance aliases and the workflow's profile overlay; "" uses the defaults only
+	Lenient bool   // Ignore unknown keys instead of rejecting them
+	// PreferGlobalInstances merges a workflow file's instances under the
+	// instances file's instead of over them; see preferGlobalInstances.
+	PreferGlobalInstances bool
+	// Cache, if set, is used to reuse the decoded instances file while it is
+	// unchanged.
+	Cache *Cache
+	// DefaultsPath is the defaults file merged beneath both files; "" uses
+	// the package's DefaultsPath. A defaults file that cannot be read or
+	// parsed is ignored, with a warning in Config.Warnings.
+	DefaultsPath string
+	// WorkflowData, if set, is decoded instead of reading workflowPath, which
+	// still names the workflow in errors and anchors its relative paths. It is
+	// used to load the config snapshot of a past run.
+	WorkflowData []byte
+	// AllowUnknownInstances accepts steps naming instances that are not
+	// defined, for checking a workflow meant for another instances file. See
+	// Validate, which reports them as warnings.
+	AllowUnknownInstances bool
+	// PollDefaults are the operator's poll intervals, used where the workflow
+	// and the defaults file leave them unset.
+	PollDefaults PollDefaults
+	// Credentials, if set, resolves the auth_keychain keys of the loaded
+	// instances and github config; nil uses the macOS Keychain. Set it to use
+	// another backend such as pass or the Windows Credential Manager.
+	Credentials CredentialStore
+	// Env, if set, resolves auth_env variables and environment references in
+	// config values; nil uses the process environment. LoadEnvFile layers a
+	// .env file over it.
+	Env EnvLookup
+}
+
+// instancesFile is the layout of an instances file.
+type instancesFile struct {
+	Instances map[string]Instance          `yaml:"instances"`
+	GitHub    *GitHubConfig                `yaml:"github,omitempty"`
+	Aliases   map[string]string            `yaml:"aliases,omitempty"`
+	Profiles  map[string]map[string]string `yaml:"profiles,omitempty"`
+}
+
+// workflowFile is the layout of a workflow file.
+type workflowFile struct {
+	Schema              int                 `yaml:"schema,omitempty"` // See SchemaVersion; 0 for legacy files
+	Name                string              `yaml:"name"`
+	Description         string              `yaml:"description,omitempty"`
+	Tags                []string            `yaml:"tags,omitempty"`
+	SlackWebhook        string              `yaml:"slack_webhook,omitempty"`
+	SlackChannel        string              `yaml:"slack_channel,omitempty"`
+	SlackUsername       string              `yaml:"slack_username,omitempty"`
+	SlackTLS            *SlackTLS           `yaml:"slack_tls,omitempty"`
+	SlackWebhookHosts   []string            `yaml:"slack_webhook_hosts,omitempty"`
+	NotifyOn            []string            `yaml:"notify_on,omitempty"`
+	VerifyJobs          bool                `yaml:"verify_jobs,omitempty"`
+	VerifyParams        bool                `yaml:"verify_params,omitempty"`
+	VerifyWebhooks      bool                `yaml:"verify_webhooks,omitempty"`
+	AllowDuplicateNames bool                `yaml:"allow_duplicate_names,omitempty"`
+	Retries             int                 `yaml:"retries,omitempty"`
+	RetryDelaySecs      int                 `yaml:"retry_delay_secs,omitempty"`
+	Inputs              map[string]string   `yaml:"inputs,omitempty"`
+	Vars                map[string]string   `yaml:"vars,omitempty"`
+	Instances           map[string]Instance `yaml:"instances,omitempty"` // Overrides merged over the instances file; see mergeInstances
+	Workflow            []WorkflowItem      `yaml:"workflow"`
+	Finally             []WorkflowItem      `yaml:"finally,omitempty"`
+
+	Templates map[string]StepTemplate    `yaml:"templates,omitempty"` // Partial steps referenced by `template:`; see applyTemplates
+	Profiles  map[string]WorkflowProfile `yaml:"profiles,omitempty"`  // Per-environment overlays; see applyProfile
+}
+
+// Load reads the instances and workflow files, resolving instance aliases with
+// the default `aliases:` map. Unknown keys are errors. See LoadWithOptions.
 func Load(instancesPath, workflowPath string) (*Config, error) {
+	return LoadWithOptions(instancesPath, workflowPath, LoadOptions{})
+}
+
+// LoadWithProfile is like Load but resolves instance aliases using the named
+// profile from instances.yaml, layered over the default `aliases:` map, and
+// applies the workflow's own overlay for that profile, if it has one. The
+// profile must be defined in at least one of the two files. An empty profile
+// uses the defaults only.
+func LoadWithProfile(instancesPath, workflowPath, profile string) (*Config, error) {
+	return LoadWithOptions(instancesPath, workflowPath, LoadOptions{Profile: profile})
+}
+
+// LoadWithOptions reads the instances and workflow files. Unless opts.Lenient
+// is set, a key that does not map to a config field (such as a misspelled
+// `paralel:`) is an error naming the key and its line.
+func LoadWithOptions(instancesPath, workflowPath string, opts LoadOptions) (*Config, error) {
+	profile := opts.Profile
+
 	// 1. Load Instances
-	instancesData, err := os.ReadFile(instancesPath)
+	loaded, err := opts.Cache.instances(instancesPath, opts.Lenient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read instances config (%s): %w", instancesPath, err)
+		return nil, err
 	}
+	instancesCfg := loaded.file
 
-	var instancesCfg struct {
-		Instances map[string]Instance `yaml:"instances"`
-		GitHub    *GitHubConfig       `yaml:"github,omitempty"`
+	defaultsPath := cmp.Or(opts.DefaultsPath, DefaultsPath)
+	var defaults *loadedDefaults
+	var warnings []string
+	if defaultsPath != "" {
+		if defaults, err = opts.Cache.defaults(defaultsPath, opts.Lenient); err != nil {
+			warnings = append(warnings, DefaultsWarning(err))
+			defaults = nil
+		}
 	}
-	if err := yaml.Unmarshal(instancesData, &instancesCfg); err != nil {
-		return nil, fmt.Errorf("failed to parse instances config: %w", err)
+	globalInstances := instancesCfg.Instances
+	if defaults != nil {
+		if globalInstances, err = mergeInstances(defaults.file.Instances, globalInstances, nil, nil); err != nil {
+			return nil, err
+		}
 	}
 
 	// 2. Load Workflow
-	workflowData, err := os.ReadFile(workflowPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read workflow config (%s): %w", workflowPath, err)
+	workflowData := opts.WorkflowData
+	if workflowData == nil {
+		if workflowData, err = os.ReadFile(workflowPath); err != nil {
+			return nil, fmt.Errorf("failed to read workflow config (%s): %w", workflowPath, err)
+		}
 	}
 
-	var workflowCfg struct {
-		Name         string            `yaml:"name"`
-		SlackWebhook string            `yaml:"slack_webhook,omitempty"`
-		Inputs       map[string]string `yaml:"inputs,omitempty"`
-		Workflow     []WorkflowItem    `yaml:"workflow"`
+	workflowSrc := newSourceFile(workflowPath, workflowData)
+	schema, err := checkSchema(workflowSrc, workflowData)
+	if err != nil {
+		return nil, err
+	}
+	if schema == 0 {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", workflowPath, legacySchemaWarning))
 	}
-	if err := yaml.Unmarshal(workflowData, &workflowCfg); err != nil {
+
+	var workflowCfg workflowFile
+	if err := decodeWorkflow(workflowSrc, workflowData, &workflowCfg, opts.Lenient); err != nil {
 		return nil, fmt.Errorf("failed to parse workflow config: %w", err)
 	}
+	if t := workflowCfg.SlackTLS; t != nil && t.CACert != "" && !filepath.IsAbs(t.CACert) {
+		t.CACert = filepath.Join(filepath.Dir(workflowPath), t.CACert)
+	}
+	for _, items := range [][]WorkflowItem{workflowCfg.Workflow, workflowCfg.Finally} {
+		for _, item := range items {
+			if item.IsPRWait() && item.WaitForPR.GitHub != nil {
+				if app := item.WaitForPR.GitHub.App; app != nil && app.PrivateKeyPath != "" && !filepath.IsAbs(app.PrivateKeyPath) {
+					app.PrivateKeyPath = filepath.Join(filepath.Dir(workflowPath), app.PrivateKeyPath)
+				}
+			}
+		}
+	}
 
-	// 3. Merge
-	cfg := &Config{
-		Name:         workflowCfg.Name,
-		SlackWebhook: workflowCfg.SlackWebhook,
-		Inputs:       workflowCfg.Inputs,
-		Instances:    instancesCfg.Instances,
-		GitHub:       instancesCfg.GitHub,
-		Workflow:     workflowCfg.Workflow,
+	// 3. Apply step templates and the workflow profile overlay
+	if err := workflowCfg.applyTemplates(workflowSrc); err != nil {
+		return nil, err
+	}
+	if err := workflowCfg.validateProfiles(workflowSrc); err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		_, inInstances := instancesCfg.Profiles[profile]
+		_, inWorkflow := workflowCfg.Profiles[profile]
+		if !inInstances && !inWorkflow {
+			return nil, fmt.Errorf("unknown instance profile %q: not defined in %s or %s", profile, instancesPath, workflowPath)
+		}
+	}
+	profileInstances := workflowCfg.Profiles[profile].Instances
+	if err := workflowCfg.applyProfile(profile); err != nil {
+		return nil, workflowSrc.wrap(err, "profiles", profile, "instances")
+	}
+	if !opts.PreferGlobalInstances {
+		if err := checkInstanceConflicts(workflowSrc, instancesCfg.Instances, workflowCfg.Instances, profile, profileInstances); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := cfg.validate(); err != nil {
+	// 4. Merge
+	inline := workflowCfg.Instances
+	if opts.PreferGlobalInstances {
+		inline = preferGlobalInstances(instancesCfg.Instances, inline)
+	}
+	instances, err := mergeWorkflowInstances(workflowSrc, globalInstances, inline, instancesCfg.Aliases, instancesCfg.Profiles)
+	if err != nil {
 		return nil, err
 	}
+	cfg := &Config{
+		Name:                workflowCfg.Name,
+		Tags:                workflowCfg.Tags,
+		SlackWebhook:        workflowCfg.SlackWebhook,
+		SlackChannel:        workflowCfg.SlackChannel,
+		SlackUsername:       workflowCfg.SlackUsername,
+		SlackTLS:            workflowCfg.SlackTLS,
+		SlackWebhookHosts:   workflowCfg.SlackWebhookHosts,
+		NotifyOn:            workflowCfg.NotifyOn,
+		VerifyJobs:          workflowCfg.VerifyJobs,
+		VerifyParams:        workflowCfg.VerifyParams,
+		VerifyWebhooks:      workflowCfg.VerifyWebhooks,
+		AllowDuplicateNames: workflowCfg.AllowDuplicateNames,
+		Retries:             workflowCfg.Retries,
+		RetryDelaySecs:      workflowCfg.RetryDelaySecs,
+		Inputs:              workflowCfg.Inputs,
+		Vars:                workflowCfg.Vars,
+		Instances:           instances,
+		GitHub:              instancesCfg.GitHub,
+		Workflow:            workflowCfg.Workflow,
+		Finally:             workflowCfg.Finally,
+		Profile:             profile,
+		Warnings:            warnings,
+		instancesSrc:        loaded.src,
+		instancesMissing:    loaded.missing,
+		workflowSrc:         workflowSrc,
 
-	return cfg, nil
-}
+		allowUnknownInstances: opts.AllowUnknownInstances,
+	}
 
-// ParseWorkflowMeta reads just the metadata (name) from a workflow file.
-func ParseWorkflowMeta(path string) (string, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+	if defaults != nil {
+		cfg.DefaultsFile = defaultsPath
+		cfg.defaultsSrc = defaults.src
+		cfg.applyDefaults(&defaults.file)
 	}
+	cfg.applyPollDefaults(opts.PollDefaults)
 
-	var meta struct {
-		Name string `yaml:"name"`
+	aliases, err := instanceAliases(instancesCfg.Aliases, instancesCfg.Profiles, profile, globalInstances)
+	if err != nil {
+		return nil, err
 	}
-	if err := yaml.Unmarshal(data, &meta); err != nil {
-		return "", fmt.Errorf("failed to parse yaml: %w", err)
+	cfg.resolveInstanceAliases(aliases)
+	cfg.applyForwardedInputs()
+	cfg.applyDefaultParams()
+	sources := &secretSources{credentials: opts.Credentials, env: opts.Env}
+	cfg.sources = sources
+	if err := cfg.expandEnv(cfg.usedInstances()); err != nil {
+		return nil, err
 	}
 
-	if meta.Name == "" {
-		return "", fmt.Errorf("workflow missing 'name' field")
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
-	return meta.Name, nil
+	cfg.setSources(sources)
+	return cfg, nil
 }
 
-func (c *Config) validate() error {
-	if len(c.Instances) == 0 {
-		return fmt.Errorf("no instances defined")
+// setSources makes the config's instances and github config look their
+// tokens up in sources.
+func (c *Config) setSources(sources *secretSources) {
+	c.sources = sources
+	instances := make(map[string]Instance, len(c.Instances))
+	for name, inst := range c.Instances {
+		inst.sources = sources
+		instances[name] = inst
 	}
-	if len(c.Workflow) == 0 {
-		return fmt.Errorf("workflow is empty")
+	c.Instances = instances
+	if c.GitHub != nil {
+		gh := *c.GitHub
+		gh.sources = sources
+		c.GitHub = &gh
 	}
+}
 
-	for name, inst := range c.Instances {
-		if inst.URL == "" {
-			return fmt.Errorf("instance %q has empty URL", name)
-		}
-		if inst.AuthEnv == "" && inst.Token == "" {
-			return fmt.Errorf("instance %q must have either 'auth_env' or 'token' set", name)
-		}
+// LoadInstance returns the instance called name, or the one it aliases, from
+// the instances file merged over the defaults file, for triggering a job
+// outside of any workflow. opts.Profile selects the aliases as in
+// LoadWithOptions; the workflow-only options are ignored. Environment
+// references in the URL are expanded and the instance is validated.
+func LoadInstance(instancesPath, name string, opts LoadOptions) (Instance, error) {
+	loaded, err := opts.Cache.instances(instancesPath, opts.Lenient)
+	if err != nil {
+		return Instance{}, err
 	}
+	instancesCfg := loaded.file
 
-	seenIDs := map[string]string{} // resolved ID -> location of first occurrence
-	for i, item := range c.Workflow {
-		if item.IsPRWait() {
-			// Validate PR wait
-			if err := c.validatePRWait(item.WaitForPR, fmt.Sprintf("wait_for_pr[%d]", i)); err != nil {
-				return err
-			}
-		} else if item.IsParallel() {
-			// Validate parallel group
-			if len(item.Parallel.Steps) == 0 {
-				return fmt.Errorf("workflow item %d: parallel group is empty", i)
-			}
-			for j, step := range item.Parallel.Steps {
-				loc := fmt.Sprintf("parallel[%d].step[%d]", i, j)
-				if err := c.validateStep(step, loc); err != nil {
-					return err
-				}
-				if err := registerStepID(seenIDs, step, loc); err != nil {
-					return err
-				}
-			}
-		} else {
-			// Validate single step
-			step := item.AsStep()
-			loc := fmt.Sprintf("step %d", i)
-			if err := c.validateStep(step, loc); err != nil {
-				return err
-			}
-			if err := registerStepID(seenIDs, step, loc); err != nil {
-				return err
+	instances := instancesCfg.Instances
+	if defaultsPath := cmp.Or(opts.DefaultsPath, DefaultsPath); defaultsPath != "" {
+		// A broken defaults file is skipped silently here; CheckDefaults
+		// reports it.
+		defaults, err := opts.Cache.defaults(defaultsPath, opts.Lenient)
+		if err == nil && defaults != nil {
+			if instances, err = mergeInstances(defaults.file.Instances, instances, nil, nil); err != nil {
+				return Instance{}, err
 			}
 		}
 	}
 
-	return nil
-}
+	if opts.Profile != "" {
+		if _, ok := instancesCfg.Profiles[opts.Profile]; !ok {
+			return Instance{}, fmt.Errorf("unknown instance profile %q: not defined in %s", opts.Profile, instancesPath)
+		}
+	}
+	aliases, err := instanceAliases(instancesCfg.Aliases, instancesCfg.Profiles, opts.Profile, instances)
+	if err != nil {
+		return Instance{}, err
+	}
+	resolved := cmp.Or(aliases[name], name)
+	inst, ok := instances[resolved]
+	if !ok {
+		return Instance{}, fmt.Errorf("unknown instance or alias %q", name)
+	}
 
-// registerStepID records a step's resolved ID and errors on collision.
-func registerStepID(seen map[string]string, step Step, location string) error {
-	id := step.ResolvedID()
-	if id == "" {
-		return nil // empty name + no explicit id; validateStep already caught the missing name
+	sources := &secretSources{credentials: opts.Credentials, env: opts.Env}
+	cfg := &Config{Instances: map[string]Instance{resolved: inst}, sources: sources}
+	if err := cfg.expandEnv([]string{resolved}); err != nil {
+		return Instance{}, err
 	}
-	if prev, exists := seen[id]; exists {
-		return fmt.Errorf("%s (%q): duplicate step id %q (first defined at %s); add an explicit `id:` field to disambiguate", location, step.Name, id, prev)
+	inst = cfg.Instances[resolved]
+	if err := inst.validate(resolved); err != nil {
+		return Instance{}, err
+	}
+	inst.sources = sources
+	return inst, nil
+}
+
+// CountInstances reads the instances file at path, through opts.Cache, and
+// returns how many instances and aliases it defines, for reporting a reload.
+// A missing file counts as empty; one that does not parse is an error.
+func CountInstances(path string, opts LoadOptions) (instances, aliases int, err error) {
+	loaded, err := opts.Cache.instances(path, opts.Lenient)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(loaded.file.Instances), len(loaded.file.Aliases), nil
+}
+
+// mergeInstances overlays overrides on global. A new name is added as is. A
+// shared name overrides only the fields it sets; any auth field replaces the
+// global auth entirely, so credentials never mix. Headers and default_params
+// merge key by key. Overrides may not reuse an alias name, since the alias
+// would hide them.
+func mergeInstances(global, overrides map[string]Instance, aliases map[string]string, profiles map[string]map[string]string) (map[string]Instance, error) {
+	if len(overrides) == 0 {
+		return global, nil
+	}
+	merged := make(map[string]Instance, len(global)+len(overrides))
+	for name, inst := range global {
+		merged[name] = inst
+	}
+	for name, o := range overrides {
+		if isAlias(name, aliases, profiles) {
+			return nil, fmt.Errorf("workflow instance %q has the same name as an instance alias; rename it or override the aliased instance", name)
+		}
+		inst, ok := merged[name]
+		if !ok {
+			merged[name] = o
+			continue
+		}
+		if o.URL != "" {
+			inst.URL = o.URL
+		}
+		if o.AuthEnv != "" || o.AuthCommand != "" || o.AuthKeychain != "" || o.Token != "" {
+			inst.AuthEnv, inst.AuthCommand, inst.AuthKeychain, inst.Token = o.AuthEnv, o.AuthCommand, o.AuthKeychain, o.Token
+		}
+		if o.BuildTimeoutSecs != 0 {
+			inst.BuildTimeoutSecs = o.BuildTimeoutSecs
+		}
+		if o.QueueStuckGraceSecs != 0 {
+			inst.QueueStuckGraceSecs = o.QueueStuckGraceSecs
+		}
+		if o.Crumb {
+			inst.Crumb = true
+		}
+		if o.Override {
+			inst.Override = true
+		}
+		inst.Headers = overlayMap(inst.Headers, o.Headers)
+		inst.DefaultParams = overlayMap(inst.DefaultParams, o.DefaultParams)
+		merged[name] = inst
+	}
+	return merged, nil
+}
+
+// mergeWorkflowInstances is mergeInstances for the workflow file's instances.
+// An entry that gives a global instance's name another url is taken as a new
+// instance rather than an override: it inherits nothing and must set its own
+// auth, so the global credentials and headers never go to a server the
+// workflow file chooses.
+func mergeWorkflowInstances(src *sourceFile, global, inline map[string]Instance, aliases map[string]string, profiles map[string]map[string]string) (map[string]Instance, error) {
+	base, cloned := global, false
+	for _, name := range sortedKeys(inline) {
+		o := inline[name]
+		g, ok := global[name]
+		if !ok || o.URL == "" || o.URL == g.URL {
+			continue
+		}
+		if o.AuthEnv == "" && o.AuthCommand == "" && o.AuthKeychain == "" && o.Token == "" {
+			return nil, src.wrap(fmt.Errorf("workflow instance %q changes the url of the instance with that name, so it must set its own auth_env, auth_command, auth_keychain or token", name), "instances", name, "url")
+		}
+		if !cloned {
+			base, cloned = maps.Clone(global), true
+		}
+		delete(base, name)
+	}
+	return mergeInstances(base, inline, aliases, profiles)
+}
+
+// checkInstanceConflicts rejects a workflow instance that sets a url other
+// than the one the instances file gives the same name, unless the entry sets
+// override: true. Entries that only change other fields are overrides by
+// design, and self-contained workflows may redefine an instance identically.
+// It runs after the profile overlay, so inline holds the profile's changes
+// too; an error points at the profile's entry when that set the url.
+func checkInstanceConflicts(src *sourceFile, global, inline map[string]Instance, profile string, profileInstances map[string]Instance) error {
+	for _, name := range sortedKeys(inline) {
+		o := inline[name]
+		g, ok := global[name]
+		if ok && !o.Override && o.URL != "" && o.URL != g.URL {
+			err := fmt.Errorf("workflow instance %q changes the url of the instance with that name in the instances file; set override: true to replace it, or rename it", name)
+			if profileInstances[name].URL != "" {
+				return src.wrap(err, "profiles", profile, "instances", name, "url")
+			}
+			return src.wrap(err, "instances", name, "url")
+		}
+	}
+	return nil
+}
+
+// preferGlobalInstances returns the workflow instances to merge when the
+// instances file wins: those the instances file does not define, and those
+// that set override: true.
+func preferGlobalInstances(global, inline map[string]Instance) map[string]Instance {
+	kept := make(map[string]Instance, len(inline))
+	for name, o := range inline {
+		if _, ok := global[name]; !ok || o.Override {
+			kept[name] = o
+		}
+	}
+	return kept
+}
+
+// overlayMap returns base with over's entries added on top, without modifying
+// either map. base itself is returned when over is empty.
+func overlayMap(base, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(over))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
+// isAlias reports whether name is a default or profile instance alias.
+func isAlias(name string, aliases map[string]string, profiles map[string]map[string]string) bool {
+	if _, ok := aliases[name]; ok {
+		return true
+	}
+	for _, p := range profiles {
+		if _, ok := p[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceAliases merges the default aliases with the selected profile and checks
+// that every alias points at a defined instance.
+func instanceAliases(defaults map[string]string, profiles map[string]map[string]string, profile string, instances map[string]Instance) (map[string]string, error) {
+	aliases := make(map[string]string, len(defaults))
+	for alias, target := range defaults {
+		aliases[alias] = target
+	}
+	for alias, target := range profiles[profile] {
+		aliases[alias] = target
+	}
+	for alias, target := range aliases {
+		if _, ok := instances[target]; !ok {
+			if profile != "" {
+				return nil, fmt.Errorf("instance alias %q (profile %q) points to unknown instance %q", alias, profile, target)
+			}
+			return nil, fmt.Errorf("instance alias %q points to unknown instance %q", alias, target)
+		}
+	}
+	return aliases, nil
+}
+
+// resolveInstanceAliases rewrites step instances that name an alias to the
+// aliased instance. Names that are not aliases are left for validation.
+func (c *Config) resolveInstanceAliases(aliases map[string]string) {
+	if len(aliases) == 0 {
+		return
+	}
+	resolve := func(instance *string) {
+		if target, ok := aliases[*instance]; ok {
+			*instance = target
+		}
+	}
+	for _, items := range [][]WorkflowItem{c.Workflow, c.Finally} {
+		for i := range items {
+			item := &items[i]
+			if item.IsParallel() {
+				for j := range item.Parallel.Steps {
+					resolve(&item.Parallel.Steps[j].Instance)
+				}
+			} else if item.IsStep() {
+				resolve(&item.Instance)
+			}
+		}
+	}
+}
+
+// applyForwardedInputs adds a ${NAME} param for each input a step forwards,
+// unless the step sets that param itself. The templates are resolved with the
+// run's input values like any other param. It runs before applyDefaultParams,
+// so a forwarded input wins over an instance default.
+func (c *Config) applyForwardedInputs() {
+	forward := func(fwd *ForwardInputs, params *map[string]string) {
+		names := fwd.Selected(c.Inputs)
+		if len(names) == 0 {
+			return
+		}
+		if *params == nil {
+			*params = make(map[string]string, len(names))
+		}
+		for _, name := range names {
+			if _, declared := c.Inputs[name]; !declared {
+				continue // reported by validateStep
+			}
+			if _, ok := (*params)[name]; !ok {
+				(*params)[name] = "${" + name + "}"
+			}
+		}
+	}
+	for _, items := range [][]WorkflowItem{c.Workflow, c.Finally} {
+		for i := range items {
+			item := &items[i]
+			if item.IsParallel() {
+				for j := range item.Parallel.Steps {
+					step := &item.Parallel.Steps[j]
+					forward(step.ForwardInputs, &step.Params)
+				}
+			} else if item.IsStep() {
+				forward(item.ForwardInputs, &item.Params)
+			}
+		}
+	}
+}
+
+// applyDefaultParams merges each step's instance default_params under its own
+// params, so the engine, plans and previews all see the effective values.
+// It runs after alias resolution so aliased steps get the target's defaults.
+func (c *Config) applyDefaultParams() {
+	merge := func(instance string, params *map[string]string) {
+		defaults := c.Instances[instance].DefaultParams
+		if len(defaults) == 0 {
+			return
+		}
+		merged := make(map[string]string, len(defaults)+len(*params))
+		for k, v := range defaults {
+			merged[k] = v
+		}
+		for k, v := range *params {
+			merged[k] = v
+		}
+		*params = merged
+	}
+	for _, items := range [][]WorkflowItem{c.Workflow, c.Finally} {
+		for i := range items {
+			item := &items[i]
+			if item.IsParallel() {
+				for j := range item.Parallel.Steps {
+					step := &item.Parallel.Steps[j]
+					merge(step.Instance, &step.Params)
+				}
+			} else if item.IsStep() {
+				merge(item.Instance, &item.Params)
+			}
+		}
+	}
+}
+
+// readInstances reads and decodes an instances file. A missing file counts
+// as empty: workflows without Jenkins steps, or with their own instances, do
+// not need one.
+func readInstances(path string, lenient bool) (*loadedInstances, error) {
+	data, err := os.ReadFile(path)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !missing {
+		return nil, fmt.Errorf("failed to read instances config (%s): %w", path, err)
+	}
+
+	loaded := &loadedInstances{src: newSourceFile(path, data), missing: missing}
+	if err := decodeYAML(loaded.src, data, &loaded.file, lenient); err != nil {
+		return nil, fmt.Errorf("failed to parse instances config: %w", err)
+	}
+
+	if gh := loaded.file.GitHub; gh != nil && gh.App != nil && gh.App.PrivateKeyPath != "" && !filepath.IsAbs(gh.App.PrivateKeyPath) {
+		gh.App.PrivateKeyPath = filepath.Join(filepath.Dir(path), gh.App.PrivateKeyPath)
+	}
+	return loaded, nil
+}
+
+// decodeYAML unmarshals data, the contents of src, into out. Unless lenient,
+// keys that do not map to a field of out are errors. Errors name the file and
+// line; src may be nil for data that did not come from a file.
+func decodeYAML(src *sourceFile, data []byte, out interface{}, lenient bool) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(!lenient)
+	if err := dec.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return src.locate(err)
 	}
-	seen[id] = location
 	return nil
 }
 
+// decodeWorkflow unmarshals a workflow file. Unless lenient, unknown keys are
+// errors, including those inside wait_for_pr items.
+func decodeWorkflow(src *sourceFile, data []byte, wf *workflowFile, lenient bool) error {
+	if err := decodeYAML(src, data, wf, lenient); err != nil {
+		return err
+	}
+	if lenient {
+		return nil
+	}
+	var unknown []string
+	for _, items := range [][]WorkflowItem{wf.Workflow, wf.Finally} {
+		for _, item := range items {
+			if item.IsPRWait() {
+				unknown = append(unknown, item.WaitForPR.unknownFields...)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return src.locate(&yaml.TypeError{Errors: unknown})
+	}
+	return nil
+}
+
+// workflowExtensions are the file extensions of workflow files. JSON is a
+// subset of YAML, so .json files are decoded the same way.
+var workflowExtensions = []string{".yaml", ".yml", ".json"}
+
+// IsWorkflowFile reports whether name has a workflow file extension.
+func IsWorkflowFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range workflowExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowMeta is the metadata listed for a workflow file.
+type WorkflowMeta struct {
+	Name        string
+	Description string // Optional one-line summary; "" when the file has none
+	Tags        []string
+	Schema      int // Declared schema version; 0 for legacy files
+}
+
+// HasTag reports whether the workflow carries tag, ignoring case.
+func (m WorkflowMeta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWorkflowMeta reads just the metadata (name, description, tags and
+// schema) from a workflow file. The whole file is decoded so that, unless
+// lenient, unknown keys are reported as they are by Load, and a schema newer
+// than SchemaVersion is rejected the same way.
+func ParseWorkflowMeta(path string, lenient bool) (WorkflowMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WorkflowMeta{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	src := newSourceFile(path, data)
+	if _, err := checkSchema(src, data); err != nil {
+		return WorkflowMeta{}, err
+	}
+
+	var meta workflowFile
+	if err := decodeWorkflow(src, data, &meta, lenient); err != nil {
+		return WorkflowMeta{}, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+
+	if meta.Name == "" {
+		return WorkflowMeta{}, fmt.Errorf("workflow missing 'name' field")
+	}
+
+	return WorkflowMeta{Name: meta.Name, Description: strings.TrimSpace(meta.Description), Tags: meta.Tags, Schema: meta.Schema}, nil
+}
+
+// ParseWorkflowSnapshot decodes a workflow file recorded with a run, ignoring
+// unknown keys. The result has no instances and is not validated; it only
+// describes the workflow's items, e.g. to rebuild the state of a past run.
+func ParseWorkflowSnapshot(data []byte) (*Config, error) {
+	var wf workflowFile
+	if err := decodeWorkflow(nil, data, &wf, true); err != nil {
+		return nil, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+	return &Config{
+		Name:     wf.Name,
+		Inputs:   wf.Inputs,
+		Vars:     wf.Vars,
+		Workflow: wf.Workflow,
+		Finally:  wf.Finally,
+	}, nil
+}
+
+// usesJenkins reports whether any workflow or finally item is a Jenkins step
+// or parallel group with a step that is not disabled, and so needs an
+// instance. PR waits, GitHub statuses and commands do not.
+func (c *Config) usesJenkins() bool {
+	for i, item := range c.AllItems() {
+		if item.IsStep() && !item.Disabled() {
+			return true
+		}
+		if item.IsParallel() {
+			for j := range item.Parallel.Steps {
+				if !c.IsDisabled(i, j) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// validate checks the merged config. Errors are prefixed with the file and
+// line they refer to when the config was loaded from files.
+func (c *Config) validate() error {
+	if len(c.Instances) == 0 && c.usesJenkins() && !c.allowUnknownInstances {
+		if c.instancesMissing {
+			return c.instancesSrc.wrap(fmt.Errorf("no instances defined: the file does not exist and the workflow has Jenkins steps"))
+		}
+		return c.instancesSrc.wrap(fmt.Errorf("no instances defined"), "instances")
+	}
+	if c.GitHub != nil {
+		if err := c.GitHub.validate(); err != nil {
+			return c.instancesSrc.wrap(err, "github")
+		}
+	}
+	if len(c.Workflow) == 0 {
+		return c.workflowSrc.wrap(fmt.Errorf("workflow is empty"), "workflow")
+	}
+	for i, tag := range c.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return c.workflowSrc.wrap(fmt.Errorf("tags: tag %d is empty", i+1), "tags", i)
+		}
+	}
+	if c.SlackTLS != nil {
+		if err := c.SlackTLS.validate(); err != nil {
+			return c.workflowSrc.wrap(err, "slack_tls", "ca_cert")
+		}
+	}
+	if i, err := checkWebhookHosts(c.SlackWebhookHosts); err != nil {
+		return c.workflowSrc.wrap(err, "slack_webhook_hosts", i)
+	}
+	if err := checkWebhook(c.SlackWebhook, c.SlackWebhookHosts); err != nil {
+		return c.webhookSource().wrap(fmt.Errorf("slack_webhook: %w", err), "slack_webhook")
+	}
+	if c.Retries < 0 {
+		return c.workflowSrc.wrap(fmt.Errorf("retries must not be negative"), "retries")
+	}
+	if c.RetryDelaySecs < 0 {
+		return c.workflowSrc.wrap(fmt.Errorf("retry_delay_secs must not be negative"), "retry_delay_secs")
+	}
+	for i, outcome := range c.NotifyOn {
+		if !validNotifyOutcomes[outcome] {
+			return c.workflowSrc.wrap(fmt.Errorf("notify_on: unknown outcome %q (use success, failure, unstable, or aborted)", outcome), "notify_on", i)
+		}
+	}
+
+	for name, inst := range c.Instances {
+		if err := inst.validate(name); err != nil {
+			return c.instanceSource(name).wrap(err, "instances", name)
+		}
+	}
+
+	if _, err := c.resolveVars(c.Inputs); err != nil {
+		return c.workflowSrc.wrap(err, "vars")
+	}
+	if err := c.resolveInstanceURLs(false); err != nil {
+		return err
+	}
+
+	if err := c.checkParallelGroupNames(); err != nil {
+		return err
+	}
+	if err := c.checkPRRefs(); err != nil {
+		return c.workflowSrc.wrap(err)
+	}
+	seenIDs := map[string]string{}   // resolved ID -> location of first occurrence
+	seenNames := map[string]string{} // item name -> location of first occurrence
+	seenSteps := map[string]string{} // Jenkins step name -> location of first occurrence
+	for i, item := range c.Workflow {
+		if err := c.validateItem(item, i, "", seenIDs, seenNames, seenSteps); err != nil {
+			return c.workflowSrc.wrap(err, "workflow", i)
+		}
+	}
+	for i, item := range c.Finally {
+		if item.IsPRWait() {
+			return c.workflowSrc.wrap(fmt.Errorf("finally.%s (%q): wait_for_pr is not allowed in finally", itemLocation(item, i), item.WaitForPR.Name), "finally", i)
+		}
+		if err := c.validateItem(item, i, "finally.", seenIDs, seenNames, seenSteps); err != nil {
+			return c.workflowSrc.wrap(err, "finally", i)
+		}
+	}
+
+	return nil
+}
+
+// validate checks a single instance definition.
+func (inst Instance) validate(name string) error {
+	if inst.URL == "" {
+		return fmt.Errorf("instance %q has empty URL", name)
+	}
+	if inst.AuthEnv == "" && inst.AuthCommand == "" && inst.AuthKeychain == "" && inst.Token == "" {
+		return fmt.Errorf("instance %q must have one of 'auth_env', 'auth_command', 'auth_keychain' or 'token' set", name)
+	}
+	if inst.BuildTimeoutSecs < 0 {
+		return fmt.Errorf("instance %q: build_timeout_secs must not be negative", name)
+	}
+	if inst.QueueStuckGraceSecs < 0 {
+		return fmt.Errorf("instance %q: queue_stuck_grace_secs must not be negative", name)
+	}
+	for header := range inst.Headers {
+		if header == "" || strings.ContainsAny(header, " \t\r\n:") {
+			return fmt.Errorf("instance %q: invalid header name %q", name, header)
+		}
+		if strings.EqualFold(header, "Authorization") {
+			return fmt.Errorf("instance %q: set the Authorization header with auth_env, auth_command or token, not headers", name)
+		}
+	}
+	for param := range inst.DefaultParams {
+		if strings.TrimSpace(param) == "" {
+			return fmt.Errorf("instance %q: default_params has an empty parameter name", name)
+		}
+	}
+	return nil
+}
+
+// resolveInstanceURLs checks the URLs of the instances the workflow's steps
+// use that reference inputs or workflow vars: each reference must be declared
+// and the URL must resolve to an http(s) URL with the current inputs. With
+// apply set, the resolved URLs replace the templates.
+func (c *Config) resolveInstanceURLs(apply bool) error {
+	vars := c.TemplateVars(c.Inputs)
+	for _, name := range c.usedInstances() {
+		inst, ok := c.Instances[name]
+		if !ok || !strings.Contains(inst.URL, "${") {
+			continue
+		}
+		if err := c.checkInputRefs([]string{inst.URL}, fmt.Sprintf("instances.%s.url", name), name); err != nil {
+			return c.instanceSource(name).wrap(err, "instances", name, "url")
+		}
+		resolved := Substitute(inst.URL, vars)
+		if u, err := url.Parse(resolved); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return c.instanceSource(name).wrap(fmt.Errorf("instance %q: url %q does not resolve to an http(s) URL", name, resolved), "instances", name, "url")
+		}
+		if apply {
+			inst.URL = resolved
+			c.Instances[name] = inst
+		}
+	}
+	return nil
+}
+
+// usedInstances returns the sorted names of the instances the workflow's
+// Jenkins steps run on.
+func (c *Config) usedInstances() []string {
+	used := map[string]bool{}
+	for _, step := range c.jenkinsSteps() {
+		used[step.Instance] = true
+	}
+	return sortedKeys(used)
+}
+
+// instanceSource returns the file defining instance name: the workflow file
+// when it overrides or adds the instance, the defaults file when only that
+// defines it, the instances file otherwise.
+func (c *Config) instanceSource(name string) *sourceFile {
+	if c.workflowSrc.has("instances", name) {
+		return c.workflowSrc
+	}
+	if !c.instancesSrc.has("instances", name) && c.defaultsSrc.has("instances", name) {
+		return c.defaultsSrc
+	}
+	return c.instancesSrc
+}
+
+// validateItem validates a single top-level item. prefix is prepended to
+// error locations ("" for workflow items, "finally." for finally items).
+// Item names must be unique among items, and Jenkins step names among all
+// steps, including those in parallel groups.
+func (c *Config) validateItem(item WorkflowItem, i int, prefix string, seenIDs, seenNames, seenSteps map[string]string) error {
+	if err := c.registerName(seenNames, item.ItemName(), prefix+itemLocation(item, i)); err != nil {
+		return err
+	}
+	if err := c.validateNotify(item.Notify, prefix+itemLocation(item, i)); err != nil {
+		return err
+	}
+	if item.IsPRWait() {
+		// Validate PR wait against its input-substituted values so a missing
+		// or malformed input surfaces here rather than at run time.
+		loc := fmt.Sprintf("%swait_for_pr[%d]", prefix, i)
+		if err := c.checkInputRefs(item.WaitForPR.templateFields(), loc, item.WaitForPR.Name); err != nil {
+			return err
+		}
+		resolved, err := item.WaitForPR.withInputs(c.TemplateVars(c.Inputs))
+		if err != nil {
+			return fmt.Errorf("%s (%q): %w", loc, item.WaitForPR.Name, err)
+		}
+		if err := c.validatePRWait(&resolved, loc); err != nil {
+			return err
+		}
+	} else if item.IsGitHubStatus() {
+		if err := c.validateGitHubStatus(item.GitHubStatus, fmt.Sprintf("%sgithub_status[%d]", prefix, i)); err != nil {
+			return err
+		}
+	} else if item.IsCommand() {
+		loc := fmt.Sprintf("%scommand[%d]", prefix, i)
+		if err := validateCommand(item.Command, loc); err != nil {
+			return err
+		}
+		if err := registerStepID(seenIDs, Step{Name: item.Command.Name, ID: item.Command.ID}, loc); err != nil {
+			return err
+		}
+	} else if item.IsParallel() {
+		// Validate parallel group
+		if len(item.Parallel.Steps) == 0 {
+			return fmt.Errorf("%sworkflow item %d: parallel group is empty", prefix, i)
+		}
+		for j, step := range item.Parallel.Steps {
+			loc := fmt.Sprintf("%sparallel[%d].step[%d]", prefix, i, j)
+			if item.Disabled() {
+				step.Enabled = item.Enabled // validated like a step disabled on its own
+			}
+			if err := c.validateParallelStep(step, loc, seenSteps, seenIDs); err != nil {
+				return c.workflowSrc.wrap(err, itemSection(prefix), i, "parallel", "steps", j)
+			}
+		}
+	} else {
+		// Validate single step
+		step := item.AsStep()
+		loc := fmt.Sprintf("%sstep %d", prefix, i)
+		if err := c.validateStep(step, loc); err != nil {
+			return err
+		}
+		if err := c.registerName(seenSteps, step.Name, loc); err != nil {
+			return err
+		}
+		if err := registerStepID(seenIDs, step, loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParallelStep validates one step of a parallel group and registers
+// its name and ID.
+func (c *Config) validateParallelStep(step Step, loc string, seenStepNames, seenIDs map[string]string) error {
+	if err := c.validateStep(step, loc); err != nil {
+		return err
+	}
+	if err := c.registerName(seenStepNames, step.Name, loc); err != nil {
+		return err
+	}
+	return registerStepID(seenIDs, step, loc)
+}
+
+// checkParallelGroupNames requires every parallel group to be named when the
+// workflow has more than one, so logs and notifications can tell them apart.
+func (c *Config) checkParallelGroupNames() error {
+	if c.AllowDuplicateNames {
+		return nil
+	}
+	type group struct {
+		prefix string
+		index  int
+		item   WorkflowItem
+	}
+	var groups []group
+	for i, item := range c.Workflow {
+		if item.IsParallel() {
+			groups = append(groups, group{"", i, item})
+		}
+	}
+	for i, item := range c.Finally {
+		if item.IsParallel() {
+			groups = append(groups, group{"finally.", i, item})
+		}
+	}
+	if len(groups) < 2 {
+		return nil
+	}
+	for _, g := range groups {
+		if strings.TrimSpace(g.item.Parallel.Name) == "" {
+			err := fmt.Errorf("%sparallel[%d]: parallel groups need a name when the workflow has more than one", g.prefix, g.index)
+			return c.workflowSrc.wrap(err, itemSection(g.prefix), g.index, "parallel")
+		}
+	}
+	return nil
+}
+
+// checkPRRefs rejects ${pr.<id>.<field>} references to an id that more than
+// one wait_for_pr item has, which allow_duplicate_names makes possible, since
+// they could mean either PR.
+func (c *Config) checkPRRefs() error {
+	if !c.AllowDuplicateNames {
+		return nil
+	}
+	prs := map[string]int{}
+	for _, item := range c.Workflow {
+		if item.IsPRWait() {
+			prs[Slugify(item.WaitForPR.Name)]++
+		}
+	}
+	// Every string of the items may hold a reference, so search them all.
+	text, err := yaml.Marshal([][]WorkflowItem{c.Workflow, c.Finally})
+	if err != nil {
+		return err
+	}
+	for _, ref := range FindTemplateVars(string(text)) {
+		rest, ok := strings.CutPrefix(ref, "pr.")
+		if !ok {
+			continue
+		}
+		if id, _, _ := strings.Cut(rest, "."); prs[id] > 1 {
+			return fmt.Errorf("ambiguous reference ${%s}: %d wait_for_pr items have the id %q; give them distinct names", ref, prs[id], id)
+		}
+	}
+	return nil
+}
+
+// itemSection returns the workflow file key holding items validated with prefix.
+func itemSection(prefix string) string {
+	if prefix == "finally." {
+		return "finally"
+	}
+	return "workflow"
+}
+
+// checkInputRefs errors when any ${var} placeholder in values names an input
+// or workflow var that is not declared in the workflow.
+func (c *Config) checkInputRefs(values []string, location, name string) error {
+	for _, v := range values {
+		for _, varName := range FindTemplateVars(v) {
+			if strings.HasPrefix(varName, varsPrefix) {
+				if _, ok := c.Vars[strings.TrimPrefix(varName, varsPrefix)]; !ok {
+					return fmt.Errorf("%s (%q): references undefined var %q", location, name, strings.TrimPrefix(varName, varsPrefix))
+				}
+				continue
+			}
+			if _, ok := c.Inputs[strings.TrimPrefix(varName, inputsPrefix)]; !ok {
+				return fmt.Errorf("%s (%q): references undefined input %q", location, name, varName)
+			}
+		}
+	}
+	return nil
+}
+
+// itemLocation describes a top-level workflow item for validation errors.
+func itemLocation(item WorkflowItem, index int) string {
+	switch {
+	case item.IsPRWait():
+		return fmt.Sprintf("wait_for_pr[%d]", index)
+	case item.IsGitHubStatus():
+		return fmt.Sprintf("github_status[%d]", index)
+	case item.IsCommand():
+		return fmt.Sprintf("command[%d]", index)
+	case item.IsParallel():
+		return fmt.Sprintf("parallel[%d]", index)
+	default:
+		return fmt.Sprintf("step %d", index)
+	}
+}
+
+// registerName records an item or step name and errors on collision, naming
+// both locations. Empty names are ignored; missing names are reported elsewhere.
+// Nothing is checked when allow_duplicate_names is set.
+func (c *Config) registerName(seen map[string]string, name, location string) error {
+	if name == "" || c.AllowDuplicateNames {
+		return nil
+	}
+	if prev, exists := seen[name]; exists {
+		return fmt.Errorf("duplicate name %q: defined at %s and %s", name, prev, location)
+	}
+	seen[name] = location
+	return nil
+}
+
+// registerStepID records a step's resolved ID and errors on collision.
+func registerStepID(seen map[string]string, step Step, location string) error {
+	id := step.ResolvedID()
+	if id == "" {
+		return nil // empty name + no explicit id; validateStep already caught the missing name
+	}
+	if prev, exists := seen[id]; exists {
+		return fmt.Errorf("%s (%q): duplicate step id %q (first defined at %s); add an explicit `id:` field to disambiguate", location, step.Name, id, prev)
+	}
+	seen[id] = location
+	return nil
+}
+
+// unknownInstanceError reports that step names an instance that is not defined.
+func (c *Config) unknownInstanceError(step Step, location string) error {
+	if c.Profile != "" {
+		return fmt.Errorf("%s (%q): unknown instance or alias %q (profile %q)", location, step.Name, step.Instance, c.Profile)
+	}
+	return fmt.Errorf("%s (%q): unknown instance or alias %q", location, step.Name, step.Instance)
+}
+
 // validateStep validates a single step configuration.
 func (c *Config) validateStep(step Step, location string) error {
 	if step.Name == "" {
 		return fmt.Errorf("%s: missing name", location)
 	}
-	if step.Instance == "" {
+	// A disabled step never contacts Jenkins, so its instance may be missing
+	// or unknown, e.g. while the instance is being set up.
+	if step.Instance == "" && !step.Disabled() {
 		return fmt.Errorf("%s (%q): missing instance", location, step.Name)
 	}
-	if _, ok := c.Instances[step.Instance]; !ok {
-		return fmt.Errorf("%s (%q): unknown instance %q", location, step.Name, step.Instance)
+	if _, ok := c.Instances[step.Instance]; !ok && !step.Disabled() && !c.allowUnknownInstances {
+		return c.unknownInstanceError(step, location)
 	}
 	if step.Job == "" {
 		return fmt.Errorf("%s (%q): missing job path", location, step.Name)
 	}
-	return nil
+	switch step.Wait {
+	case "", WaitQueued, WaitStarted, WaitCompleted:
+	default:
+		return fmt.Errorf("%s (%q): wait must be 'queued', 'started' or 'completed', got %q", location, step.Name, step.Wait)
+	}
+	switch step.IfRunning {
+	case "", IfRunningTrigger, IfRunningWait, IfRunningFail:
+	default:
+		return fmt.Errorf("%s (%q): if_running must be 'trigger', 'wait' or 'fail', got %q", location, step.Name, step.IfRunning)
+	}
+	if step.ForwardInputs != nil {
+		for _, name := range step.ForwardInputs.Names {
+			if _, ok := c.Inputs[name]; !ok {
+				return fmt.Errorf("%s (%q): forward_inputs names %q, which is not a declared input", location, step.Name, name)
+			}
+		}
+	}
+	if err := validateParamRules(step, location); err != nil {
+		return err
+	}
+	return validateSecretParams(step, location)
 }
 
 // validatePRWait validates a PR wait configuration.
@@ -335,16 +1952,135 @@ func (c *Config) validatePRWait(pr *PRWait, location string) error {
 	if pr.WaitFor != "merged" && pr.WaitFor != "closed" {
 		return fmt.Errorf("%s (%q): wait_for must be 'merged' or 'closed', got %q", location, pr.Name, pr.WaitFor)
 	}
+	if pr.PollSecs < 0 || pr.PollMaxSecs < 0 {
+		return fmt.Errorf("%s (%q): poll_secs and poll_max_secs must not be negative", location, pr.Name)
+	}
+	if pr.PollMaxSecs > 0 && !pr.PollBackoff {
+		return fmt.Errorf("%s (%q): poll_max_secs requires poll_backoff: true", location, pr.Name)
+	}
+	if pollSecs := cmp.Or(pr.PollSecs, 30); pr.PollMaxSecs > 0 && pr.PollMaxSecs < pollSecs {
+		return fmt.Errorf("%s (%q): poll_max_secs (%d) must not be below the poll interval (%ds)", location, pr.Name, pr.PollMaxSecs, pollSecs)
+	}
+	if pr.GitHub != nil {
+		if err := pr.GitHub.validate(); err != nil {
+			return fmt.Errorf("%s (%q): %w", location, pr.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateNotify validates a per-item notify block.
+func (c *Config) validateNotify(n *ItemNotify, location string) error {
+	if n == nil {
+		return nil
+	}
+	if len(n.Events) == 0 {
+		return fmt.Errorf("%s: notify requires at least one event", location)
+	}
+	for _, e := range n.Events {
+		if e != NotifyOnStart && e != NotifyOnSuccess && e != NotifyOnFailure {
+			return fmt.Errorf("%s: unknown notify event %q (use on_start, on_success, or on_failure)", location, e)
+		}
+	}
+	if n.Webhook == "" && c.SlackWebhook == "" {
+		return fmt.Errorf("%s: notify requires a webhook or a workflow-level slack_webhook", location)
+	}
+	if err := checkWebhook(n.Webhook, c.SlackWebhookHosts); err != nil {
+		return fmt.Errorf("%s: notify webhook: %w", location, err)
+	}
 	return nil
 }
 
+// validateCommand validates a local command item.
+func validateCommand(cmd *Command, location string) error {
+	if cmd.Name == "" {
+		return fmt.Errorf("%s: missing name", location)
+	}
+	if strings.TrimSpace(cmd.Run) == "" {
+		return fmt.Errorf("%s (%q): missing run", location, cmd.Name)
+	}
+	if cmd.TimeoutSecs < 0 {
+		return fmt.Errorf("%s (%q): timeout_secs must not be negative", location, cmd.Name)
+	}
+	return nil
+}
+
+// validateGitHubStatus validates a GitHub status configuration. At least one of
+// a commit status (sha + state) or a PR comment (pr_number + comment) is required.
+func (c *Config) validateGitHubStatus(gs *GitHubStatus, location string) error {
+	if gs.Name == "" {
+		return fmt.Errorf("%s: missing name", location)
+	}
+	if c.GitHub == nil {
+		return fmt.Errorf("%s (%q): github configuration is required for github_status steps", location, gs.Name)
+	}
+	if gs.Owner == "" {
+		return fmt.Errorf("%s (%q): missing owner", location, gs.Name)
+	}
+	if gs.Repo == "" {
+		return fmt.Errorf("%s (%q): missing repo", location, gs.Name)
+	}
+	hasStatus := gs.SHA != "" || gs.State != ""
+	hasComment := gs.PRNumber > 0 || gs.Comment != ""
+	if !hasStatus && !hasComment {
+		return fmt.Errorf("%s (%q): either sha/state or pr_number/comment must be provided", location, gs.Name)
+	}
+	if hasStatus {
+		if gs.SHA == "" {
+			return fmt.Errorf("%s (%q): missing sha", location, gs.Name)
+		}
+		if !validGitHubStates[gs.State] && !strings.Contains(gs.State, "${") {
+			return fmt.Errorf("%s (%q): state must be one of error, failure, pending, success; got %q", location, gs.Name, gs.State)
+		}
+	}
+	if hasComment {
+		if gs.PRNumber <= 0 {
+			return fmt.Errorf("%s (%q): missing pr_number for comment", location, gs.Name)
+		}
+		if gs.Comment == "" {
+			return fmt.Errorf("%s (%q): missing comment", location, gs.Name)
+		}
+	}
+	return nil
+}
+
+// GetToken returns the instance token. A direct token wins over
+// auth_keychain, then auth_command, then auth_env.
 func (i Instance) GetToken() (string, error) {
 	if i.Token != "" {
 		return i.Token, nil
 	}
-	val := os.Getenv(i.AuthEnv)
-	if val == "" {
-		return "", fmt.Errorf("environment variable %q is not set", i.AuthEnv)
+	if i.AuthKeychain != "" {
+		return i.sources.lookupKeychain(i.AuthKeychain)
+	}
+	if i.AuthCommand != "" {
+		return runAuthCommand(i.AuthCommand)
+	}
+	return i.sources.lookupAuthEnv(i.AuthEnv)
+}
+
+// runAuthCommand runs command through the shell, like a git credential helper,
+// and returns its trimmed stdout.
+func runAuthCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), authCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("auth_command %q timed out after %s", command, authCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("auth_command %q failed: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("auth_command %q failed: %w", command, err)
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("auth_command %q returned an empty token", command)
 	}
-	return val, nil
+	return token, nil
 }
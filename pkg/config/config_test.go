@@ -1,10 +1,22 @@
 package config
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	// Keep a defaults file in the developer's home out of the tests.
+	DefaultsPath = ""
+	os.Exit(m.Run())
+}
+
 func td(name string) string {
 	return filepath.Join("testdata", name)
 }
@@ -32,6 +44,48 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoad_JSONWorkflow(t *testing.T) {
+	cfg, err := Load(td("load_instances.yaml"), td("json_workflow.json"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Name != "Generated Deploy" || len(cfg.Workflow) != 2 {
+		t.Fatalf("unexpected config: name %q, %d items", cfg.Name, len(cfg.Workflow))
+	}
+	if got := cfg.Workflow[0].Params["BRANCH"]; got != "${branch}" {
+		t.Errorf("expected BRANCH param, got %q", got)
+	}
+	if steps := cfg.Workflow[1].Parallel.Steps; len(steps) != 2 || steps[1].Name != "Deploy EU" {
+		t.Errorf("unexpected parallel steps: %+v", steps)
+	}
+
+	meta, err := ParseWorkflowMeta(td("json_workflow.json"), false)
+	if err != nil || meta.Name != "Generated Deploy" || meta.Description != "Emitted by the pipeline generator" || !meta.HasTag("generated") {
+		t.Errorf("unexpected metadata %+v, %v", meta, err)
+	}
+
+	// Validation errors in JSON files carry positions like YAML ones.
+	want := td("json_invalid_workflow.json") + `:4: step 0 ("Build"): unknown instance or alias "nowhere"`
+	if _, err := Load(td("load_instances.yaml"), td("json_invalid_workflow.json")); err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestIsWorkflowFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"deploy.yaml": true,
+		"deploy.yml":  true,
+		"deploy.json": true,
+		"DEPLOY.JSON": true,
+		"readme.txt":  false,
+		"json":        false,
+	} {
+		if got := IsWorkflowFile(name); got != want {
+			t.Errorf("IsWorkflowFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
 func TestLoad_SlackWebhook(t *testing.T) {
 	cfg, err := Load(td("slack_instances.yaml"), td("slack_workflow.yaml"))
 	if err != nil {
@@ -91,6 +145,218 @@ func TestValidate_MissingAuth(t *testing.T) {
 	}
 }
 
+func TestInstanceGetToken_AuthCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		inst    Instance
+		want    string
+		wantErr string
+	}{
+		{"trims stdout", Instance{AuthCommand: "echo '  cmd-token  '"}, "cmd-token", ""},
+		{"token wins", Instance{Token: "direct", AuthCommand: "exit 1"}, "direct", ""},
+		{"wins over env", Instance{AuthCommand: "echo cmd-token", AuthEnv: "JF_UNSET_TOKEN_VAR"}, "cmd-token", ""},
+		{"failure includes stderr", Instance{AuthCommand: "echo denied >&2; exit 3"}, "", "denied"},
+		{"empty output", Instance{AuthCommand: "true"}, "", "empty token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.inst.GetToken()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeCredentialStore is an in-memory CredentialStore; locked makes every
+// lookup fail as a locked store would.
+type fakeCredentialStore struct {
+	items  map[string]string
+	locked bool
+}
+
+func (f fakeCredentialStore) Name() string { return "fake store" }
+
+func (f fakeCredentialStore) Lookup(key string) (string, error) {
+	if f.locked {
+		return "", ErrCredentialStoreLocked
+	}
+	v, ok := f.items[key]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return v, nil
+}
+
+func TestGetToken_AuthKeychain(t *testing.T) {
+	store := fakeCredentialStore{items: map[string]string{"jenkins-prod": "kc-token", "github": "kc-gh"}}
+	sources := &secretSources{credentials: store}
+	t.Setenv("JF_KEYCHAIN_TEST_TOKEN", "env-token")
+
+	if got, err := (Instance{AuthKeychain: "jenkins-prod", AuthEnv: "JF_KEYCHAIN_TEST_TOKEN", sources: sources}).GetToken(); err != nil || got != "kc-token" {
+		t.Errorf("expected keychain to win over env, got %q, %v", got, err)
+	}
+	if got, err := (Instance{Token: "direct", AuthKeychain: "jenkins-prod", sources: sources}).GetToken(); err != nil || got != "direct" {
+		t.Errorf("expected token to win over keychain, got %q, %v", got, err)
+	}
+	if got, err := (GitHubConfig{AuthKeychain: "github", AuthEnv: "JF_KEYCHAIN_TEST_TOKEN", sources: sources}).GetToken(); err != nil || got != "kc-gh" {
+		t.Errorf("expected GitHub keychain token, got %q, %v", got, err)
+	}
+
+	_, err := (Instance{AuthKeychain: "missing", sources: sources}).GetToken()
+	if !errors.Is(err, ErrCredentialNotFound) || !strings.Contains(err.Error(), `auth_keychain "missing" (fake store): key not found`) {
+		t.Errorf("expected key not found error, got %v", err)
+	}
+
+	locked := &secretSources{credentials: fakeCredentialStore{locked: true}}
+	_, err = (Instance{AuthKeychain: "jenkins-prod", sources: locked}).GetToken()
+	if !errors.Is(err, ErrCredentialStoreLocked) || errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("expected locked store error, got %v", err)
+	}
+}
+
+func TestLoad_Credentials(t *testing.T) {
+	instancesPath := filepath.Join(t.TempDir(), "instances.yaml")
+	instances := "instances:\n  prod:\n    url: http://prod.example.com\n    auth_keychain: jenkins-prod\ngithub:\n  auth_keychain: github\n"
+	if err := os.WriteFile(instancesPath, []byte(instances), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflow := []byte("schema: 2\nworkflow:\n  - name: Build\n    instance: prod\n    job: /job/build\n")
+	opts := LoadOptions{
+		WorkflowData: workflow,
+		Credentials:  fakeCredentialStore{items: map[string]string{"jenkins-prod": "kc-token", "github": "kc-gh", "other-org": "kc-other"}},
+	}
+
+	cfg, err := LoadWithOptions(instancesPath, "workflow.yaml", opts)
+	if err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if got, err := cfg.Instances["prod"].GetToken(); err != nil || got != "kc-token" {
+		t.Errorf("expected the instance token from the load's store, got %q, %v", got, err)
+	}
+	if got, err := cfg.GitHub.GetToken(); err != nil || got != "kc-gh" {
+		t.Errorf("expected the github token from the load's store, got %q, %v", got, err)
+	}
+	pr := &PRWait{GitHub: &GitHubConfig{AuthKeychain: "other-org"}}
+	if got, err := cfg.GitHubFor(pr).GetToken(); err != nil || got != "kc-other" {
+		t.Errorf("expected a PR wait's github token from the load's store, got %q, %v", got, err)
+	}
+
+	inst, err := LoadInstance(instancesPath, "prod", opts)
+	if err != nil {
+		t.Fatalf("LoadInstance failed: %v", err)
+	}
+	if got, err := inst.GetToken(); err != nil || got != "kc-token" {
+		t.Errorf("expected LoadInstance to use the store too, got %q, %v", got, err)
+	}
+}
+
+func TestKeychainError(t *testing.T) {
+	notFound := keychainError(44, "security: SecKeychainSearchCopyNext: The specified item could not be found in the keychain.")
+	if !errors.Is(notFound, ErrCredentialNotFound) {
+		t.Errorf("expected not found, got %v", notFound)
+	}
+	locked := keychainError(36, "security: SecKeychainItemCopyContent: User interaction is not allowed.")
+	if !errors.Is(locked, ErrCredentialStoreLocked) {
+		t.Errorf("expected locked, got %v", locked)
+	}
+	other := keychainError(1, "boom")
+	if errors.Is(other, ErrCredentialNotFound) || errors.Is(other, ErrCredentialStoreLocked) || !strings.Contains(other.Error(), "boom") {
+		t.Errorf("expected generic error with stderr, got %v", other)
+	}
+}
+
+// fakeEnv is an in-memory EnvLookup.
+type fakeEnv map[string]string
+
+func (f fakeEnv) LookupEnv(key string) (string, bool) {
+	v, ok := f[key]
+	return v, ok
+}
+
+func TestGetToken_EnvOverlay(t *testing.T) {
+	sources := &secretSources{env: EnvOverlay{
+		Base:   fakeEnv{"JENKINS_TOKEN": "exported", "EMPTY_TOKEN": ""},
+		Values: map[string]string{"JENKINS_TOKEN": "from-file", "GH_TOKEN": "gh-from-file", "EMPTY_TOKEN": "file-fallback"},
+	}}
+
+	if got, err := (Instance{AuthEnv: "JENKINS_TOKEN", sources: sources}).GetToken(); err != nil || got != "exported" {
+		t.Errorf("expected a set variable to win over the env file, got %q, %v", got, err)
+	}
+	if got, err := (Instance{AuthEnv: "EMPTY_TOKEN", sources: sources}).GetToken(); err != nil || got != "file-fallback" {
+		t.Errorf("expected the env file to fill an empty variable, got %q, %v", got, err)
+	}
+	if got, err := (GitHubConfig{AuthEnv: "GH_TOKEN", sources: sources}).GetToken(); err != nil || got != "gh-from-file" {
+		t.Errorf("expected the GitHub token from the env file, got %q, %v", got, err)
+	}
+	if _, err := (Instance{AuthEnv: "MISSING_TOKEN", sources: sources}).GetToken(); err == nil || !strings.Contains(err.Error(), `"MISSING_TOKEN" is not set`) {
+		t.Errorf("expected an unset variable error, got %v", err)
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	input := `# tokens for local runs
+JENKINS_TOKEN=abc123
+export GH_TOKEN = ghp_x  # trailing comment
+SINGLE='literal \n #kept'
+DOUBLE="line1\nquoted \"x\"" # comment
+EMPTY=
+URL=http://host/#frag
+`
+	got, err := ParseDotEnv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotEnv failed: %v", err)
+	}
+	want := map[string]string{
+		"JENKINS_TOKEN": "abc123",
+		"GH_TOKEN":      "ghp_x",
+		"SINGLE":        `literal \n #kept`,
+		"DOUBLE":        "line1\nquoted \"x\"",
+		"EMPTY":         "",
+		"URL":           "http://host/#frag",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotEnv =\n %q\nwant\n %q", got, want)
+	}
+
+	for _, bad := range []string{"NO_EQUALS", "1BAD=x", `OPEN="unterminated`, `X='a' b`} {
+		if _, err := ParseDotEnv(strings.NewReader(bad)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ParseDotEnv(%q): expected a line 1 error, got %v", bad, err)
+		}
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.env")
+	if err := os.WriteFile(path, []byte("JF_FILE_TOKEN=from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env, err := LoadEnvFile(path, fakeEnv{})
+	if err != nil || len(env.Values) != 1 {
+		t.Fatalf("LoadEnvFile = %v, %v", env.Values, err)
+	}
+	inst := Instance{AuthEnv: "JF_FILE_TOKEN", sources: &secretSources{env: env}}
+	if got, err := inst.GetToken(); err != nil || got != "from-file" {
+		t.Errorf("expected the token from the loaded file, got %q, %v", got, err)
+	}
+	if _, err := (Instance{AuthEnv: "JF_FILE_TOKEN"}).GetToken(); err == nil {
+		t.Error("expected the env file to be used only where it is passed")
+	}
+
+	if _, err := LoadEnvFile(filepath.Join(t.TempDir(), "missing.env"), nil); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
+
 func TestValidate_EmptyParallelGroup(t *testing.T) {
 	_, err := Load(td("single_local_instance.yaml"), td("empty_parallel_workflow.yaml"))
 	if err == nil {
@@ -223,6 +489,70 @@ func TestValidatePRWait_MissingIdentifiers(t *testing.T) {
 	}
 }
 
+func TestValidatePRWait_PollBackoff(t *testing.T) {
+	cases := []struct {
+		name    string
+		pr      PRWait
+		wantErr string
+	}{
+		{"backoff with default cap", PRWait{PollBackoff: true}, ""},
+		{"backoff with cap", PRWait{PollSecs: 10, PollBackoff: true, PollMaxSecs: 120}, ""},
+		{"cap without backoff", PRWait{PollMaxSecs: 120}, "requires poll_backoff"},
+		{"cap below interval", PRWait{PollSecs: 60, PollBackoff: true, PollMaxSecs: 30}, "must not be below the poll interval (60s)"},
+		{"cap below default interval", PRWait{PollBackoff: true, PollMaxSecs: 20}, "must not be below the poll interval (30s)"},
+		{"negative interval", PRWait{PollSecs: -1}, "must not be negative"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := tc.pr
+			pr.Name, pr.Owner, pr.Repo, pr.PRNumber, pr.WaitFor = "Wait", "org", "repo", 1, "merged"
+			err := (&Config{}).validatePRWait(&pr, "wait_for_pr[0]")
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_PRWaitGitHubOverride(t *testing.T) {
+	cfg, err := Load(td("pr_instances.yaml"), td("pr_github_override_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	platform := cfg.GitHubFor(cfg.Workflow[0].WaitForPR)
+	if platform.AuthEnv != "PLATFORM_GITHUB_TOKEN" || platform.Token != "" || platform.APIURL != "https://github.example.com/api/v3" {
+		t.Errorf("expected the override's credential to replace the global token, got %+v", platform)
+	}
+	docs := cfg.GitHubFor(cfg.Workflow[1].WaitForPR)
+	if docs.Token != "gh-token" || docs.RequestTimeoutSecs != 60 {
+		t.Errorf("expected an override without a credential to keep the global one, got %+v", docs)
+	}
+	apps := cfg.GitHubFor(cfg.Workflow[2].WaitForPR)
+	if apps.Token != "" || apps.App == nil || apps.App.PrivateKeyPath != td("keys/apps.pem") {
+		t.Errorf("expected the app with its key resolved against the workflow file, got %+v", apps)
+	}
+	if main := cfg.GitHubFor(cfg.Workflow[3].WaitForPR); main != cfg.GitHub {
+		t.Errorf("expected a wait without an override to use the global config, got %+v", main)
+	}
+	if cfg.GitHub.Token != "gh-token" || cfg.GitHub.AuthEnv != "" {
+		t.Errorf("expected the global config to be left alone, got %+v", cfg.GitHub)
+	}
+
+	_, err = Load(td("pr_instances.yaml"), td("pr_github_override_invalid_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `wait_for_pr[0] ("Platform Release"): github: api_url must be an http(s) URL`) {
+		t.Errorf("expected the override's api_url to be validated, got %v", err)
+	}
+	if got := (&Config{}).GitHubFor(&PRWait{GitHub: &GitHubConfig{Token: "t"}}); got == nil || got.Token != "t" {
+		t.Errorf("expected an override to work without a global config, got %+v", got)
+	}
+}
+
 func TestSlugify(t *testing.T) {
 	cases := map[string]string{
 		"Build NOS Docker Image": "build_nos_docker_image",
@@ -276,7 +606,7 @@ func TestValidate_DuplicateStepID(t *testing.T) {
 		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
 		Workflow: []WorkflowItem{
 			{Name: "Build NOS", Instance: "local", Job: "/job/a"},
-			{Name: "Build NOS", Instance: "local", Job: "/job/b"},
+			{Name: "Build-NOS", Instance: "local", Job: "/job/b"},
 		},
 	}
 	if err := cfg.validate(); err == nil {
@@ -289,7 +619,7 @@ func TestValidate_DuplicateStepID_ResolvedByExplicitID(t *testing.T) {
 		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
 		Workflow: []WorkflowItem{
 			{Name: "Build NOS", Instance: "local", Job: "/job/a"},
-			{Name: "Build NOS", ID: "build_nos_2", Instance: "local", Job: "/job/b"},
+			{Name: "Build-NOS", ID: "build_nos_2", Instance: "local", Job: "/job/b"},
 		},
 	}
 	if err := cfg.validate(); err != nil {
@@ -297,48 +627,1858 @@ func TestValidate_DuplicateStepID_ResolvedByExplicitID(t *testing.T) {
 	}
 }
 
-func TestParseWorkflowMeta(t *testing.T) {
-	name, err := ParseWorkflowMeta(td("workflow_meta.yaml"))
-	if err != nil {
-		t.Fatalf("ParseWorkflowMeta failed: %v", err)
+func TestValidate_NegativeBuildTimeout(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t", BuildTimeoutSecs: -1}},
+		Workflow:  []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/a"}},
 	}
-	if name != "My Workflow" {
-		t.Errorf("expected name 'My Workflow', got %q", name)
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), "build_timeout_secs") {
+		t.Fatalf("expected build_timeout_secs validation error, got %v", err)
 	}
+}
 
-	if _, err := ParseWorkflowMeta(td("workflow_meta_missing_name.yaml")); err == nil {
-		t.Error("expected error for missing name, got nil")
+func TestValidate_StepWait(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Start service", Instance: "local", Job: "/job/a", Wait: WaitStarted},
+			{Parallel: &ParallelGroup{Name: "Kick off", Steps: []Step{
+				{Name: "Worker", Instance: "local", Job: "/job/b", Wait: WaitQueued},
+			}}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected valid wait modes, got %v", err)
+	}
+	if got := cfg.Workflow[0].AsStep().WaitMode(); got != WaitStarted {
+		t.Errorf("expected AsStep to carry wait, got %q", got)
+	}
+	if got := (Step{}).WaitMode(); got != WaitCompleted {
+		t.Errorf("expected default wait %q, got %q", WaitCompleted, got)
+	}
+
+	cfg.Workflow[1].Parallel.Steps[0].Wait = "finished"
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), `wait must be 'queued', 'started' or 'completed', got "finished"`) {
+		t.Fatalf("expected wait validation error, got %v", err)
 	}
 }
 
-func TestPRWaitShouldAutoUpdate(t *testing.T) {
-	t.Run("nil receiver", func(t *testing.T) {
-		var p *PRWait
-		if !p.ShouldAutoUpdate() {
-			t.Fatal("nil PRWait should default to auto-update on")
+func TestValidate_IfRunning(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Migrate", Instance: "local", Job: "/job/a", IfRunning: IfRunningWait},
+			{Parallel: &ParallelGroup{Name: "Deploy", Steps: []Step{
+				{Name: "Worker", Instance: "local", Job: "/job/b", IfRunning: IfRunningFail},
+			}}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected valid if_running modes, got %v", err)
+	}
+	if got := cfg.Workflow[0].AsStep().IfRunningMode(); got != IfRunningWait {
+		t.Errorf("expected AsStep to carry if_running, got %q", got)
+	}
+	if got := (Step{}).IfRunningMode(); got != IfRunningTrigger {
+		t.Errorf("expected default if_running %q, got %q", IfRunningTrigger, got)
+	}
+
+	cfg.Workflow[1].Parallel.Steps[0].IfRunning = "skip"
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), `if_running must be 'trigger', 'wait' or 'fail', got "skip"`) {
+		t.Fatalf("expected if_running validation error, got %v", err)
+	}
+}
+
+func TestStep_CheckParams(t *testing.T) {
+	step := Step{Name: "Deploy", ParamRules: map[string]ParamRule{
+		"VERSION": {Pattern: `^v\d+\.\d+\.\d+$`, Required: true},
+		"NOTE":    {Pattern: `^[^\n]*$`},
+	}}
+	tests := []struct {
+		params  map[string]string
+		pending bool
+		want    string
+	}{
+		{map[string]string{"VERSION": "v1.2.3"}, false, ""},
+		{map[string]string{"VERSION": "v1.2.3", "NOTE": "hotfix"}, false, ""},
+		{map[string]string{}, false, `step "Deploy": param VERSION is required but not set`},
+		{map[string]string{"VERSION": " "}, false, `step "Deploy": param VERSION is required but empty`},
+		{map[string]string{"VERSION": "1.2"}, false, `param VERSION: value "1.2" does not match pattern ^v\d+\.\d+\.\d+$`},
+		{map[string]string{"VERSION": "v1.2.3\nrm -rf /"}, false, "does not match pattern"},
+		{map[string]string{"VERSION": "v1.2.3", "NOTE": "two\nlines"}, false, `param NOTE: value "two\nlines" does not match`},
+		{map[string]string{"VERSION": "v1.2.3", "NOTE": strings.Repeat("x", 50) + "\n"}, false, `param NOTE: value "` + strings.Repeat("x", 40) + `"... does not match pattern ^[^\n]*$`},
+		{map[string]string{"VERSION": "${steps.build.build_number}"}, true, ""},
+		{map[string]string{"VERSION": "${steps.build.build_number}"}, false, "does not match pattern"},
+	}
+	for _, tt := range tests {
+		err := step.CheckParams(tt.params, tt.pending)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tt.params, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.params, tt.want, err)
 		}
-	})
+	}
 
-	t.Run("unset (nil pointer) defaults true", func(t *testing.T) {
-		p := &PRWait{}
-		if !p.ShouldAutoUpdate() {
-			t.Fatal("unset AutoUpdateBranch should default to true")
+	step.SecretParams = []string{"VERSION"}
+	err := step.CheckParams(map[string]string{"VERSION": "hunter2"}, false)
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), `step "Deploy": param VERSION: value does not match pattern`) {
+		t.Errorf("expected the secret value to be left out, got %v", err)
+	}
+}
+
+func TestValidate_ParamRules(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Deploy", Instance: "local", Job: "/job/a", ParamRules: map[string]ParamRule{"VERSION": {Pattern: `^v\d+$`}}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected valid param_rules, got %v", err)
+	}
+	if len(cfg.Workflow[0].AsStep().ParamRules) != 1 {
+		t.Error("expected AsStep to carry param_rules")
+	}
+
+	cfg.Workflow[0].ParamRules["VERSION"] = ParamRule{Pattern: `^v(\d+$`}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), `step 0 ("Deploy"): param_rules VERSION: invalid pattern`) {
+		t.Fatalf("expected an invalid pattern error, got %v", err)
+	}
+}
+
+func TestValidate_InstanceHeaders(t *testing.T) {
+	tests := []struct {
+		headers map[string]string
+		want    string
+	}{
+		{map[string]string{"X-Api-Key": "k"}, ""},
+		{map[string]string{"X Api Key": "k"}, `invalid header name "X Api Key"`},
+		{map[string]string{"authorization": "Bearer k"}, "not headers"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			Instances: map[string]Instance{"local": {URL: "http://x", Token: "t", Headers: tt.headers}},
+			Workflow:  []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/a"}},
 		}
-	})
+		err := cfg.validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("headers %v: unexpected error %v", tt.headers, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("headers %v: expected error containing %q, got %v", tt.headers, tt.want, err)
+		}
+	}
+}
 
-	t.Run("explicit false", func(t *testing.T) {
-		f := false
-		p := &PRWait{AutoUpdateBranch: &f}
-		if p.ShouldAutoUpdate() {
-			t.Fatal("explicit false should disable auto-update")
+func TestInstanceBuildTimeout(t *testing.T) {
+	if got := (Instance{}).BuildTimeout(); got != 0 {
+		t.Errorf("expected no limit by default, got %s", got)
+	}
+	if got := (Instance{BuildTimeoutSecs: 90}).BuildTimeout(); got != 90*time.Second {
+		t.Errorf("expected 90s, got %s", got)
+	}
+}
+
+func TestLoad_UnknownFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances string
+		workflow  string
+		want      string
+	}{
+		{"misspelled item key", "pr_instances.yaml", "unknown_item_field_workflow.yaml", td("unknown_item_field_workflow.yaml") + `:5: unknown key "paralel", did you mean "parallel"?`},
+		{"inside a parallel step", "pr_instances.yaml", "unknown_parallel_step_field_workflow.yaml", td("unknown_parallel_step_field_workflow.yaml") + `:8: unknown key "parmas", did you mean "params"?`},
+		{"inside wait_for_pr", "pr_instances.yaml", "unknown_pr_field_workflow.yaml", td("unknown_pr_field_workflow.yaml") + `:8: unknown key "pol_secs", did you mean "poll_secs"?`},
+		{"in the instances file", "unknown_instance_field_instances.yaml", "pr_workflow.yaml", td("unknown_instance_field_instances.yaml") + `:5: unknown key "build_timout_secs", did you mean "build_timeout_secs"?`},
+		{"top-level metadata", "pr_instances.yaml", "extra_metadata_workflow.yaml", td("extra_metadata_workflow.yaml") + `:2: unknown key "owner_team"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(td(tt.instances), td(tt.workflow))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_UnknownFieldsAllReported(t *testing.T) {
+	_, err := Load(td("pr_instances.yaml"), td("unknown_keys_workflow.yaml"))
+	if err == nil {
+		t.Fatal("expected misspelled keys to be rejected")
+	}
+	for _, want := range []string{
+		td("unknown_keys_workflow.yaml") + `:6: unknown key "parmas", did you mean "params"?`,
+		td("unknown_keys_workflow.yaml") + `:8: unknown key "parrallel", did you mean "parallel"?`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
 		}
-	})
+	}
+	var located *SourceError
+	if !errors.As(err, &located) || located.Line != 6 {
+		t.Errorf("expected the first unknown key as a SourceError at line 6, got %v", located)
+	}
+}
 
-	t.Run("explicit true", func(t *testing.T) {
-		v := true
-		p := &PRWait{AutoUpdateBranch: &v}
-		if !p.ShouldAutoUpdate() {
-			t.Fatal("explicit true should enable auto-update")
+func TestClosestKey(t *testing.T) {
+	known := []string{"instance", "job", "params", "parallel", "wait_for_pr"}
+	tests := map[string]string{
+		"parrallel":  "parallel",
+		"paralel":    "parallel",
+		"instnace":   "instance",
+		"jbo":        "job",
+		"tags":       "",
+		"owner_team": "",
+		"wait_for":   "wait_for_pr",
+	}
+	for key, want := range tests {
+		if got := closestKey(key, known); got != want {
+			t.Errorf("closestKey(%q) = %q, want %q", key, got, want)
 		}
-	})
+	}
+}
+
+func TestLoad_Lenient(t *testing.T) {
+	opts := LoadOptions{Lenient: true}
+	if _, err := LoadWithOptions(td("pr_instances.yaml"), td("extra_metadata_workflow.yaml"), opts); err != nil {
+		t.Errorf("expected extra metadata to be ignored, got %v", err)
+	}
+	if _, err := LoadWithOptions(td("pr_instances.yaml"), td("unknown_pr_field_workflow.yaml"), opts); err != nil {
+		t.Errorf("expected unknown wait_for_pr key to be ignored, got %v", err)
+	}
+	if _, err := LoadWithOptions(td("unknown_instance_field_instances.yaml"), td("pr_workflow.yaml"), opts); err != nil {
+		t.Errorf("expected unknown instance key to be ignored, got %v", err)
+	}
+
+	if _, err := ParseWorkflowMeta(td("extra_metadata_workflow.yaml"), false); err == nil || !strings.Contains(err.Error(), "owner_team") {
+		t.Errorf("expected strict ParseWorkflowMeta to reject owner_team, got %v", err)
+	}
+	if meta, err := ParseWorkflowMeta(td("extra_metadata_workflow.yaml"), true); err != nil || meta.Name != "Release" {
+		t.Errorf("expected lenient ParseWorkflowMeta to return Release, got %q, %v", meta.Name, err)
+	}
+}
+
+func TestLoad_WorkflowInstances(t *testing.T) {
+	cfg, err := Load(td("load_instances.yaml"), td("instance_override_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	direct := cfg.Instances["direct"]
+	direct.sources = nil
+	want := Instance{URL: "http://jenkins.example.com", AuthEnv: "DIRECT_TOKEN", BuildTimeoutSecs: 600, Crumb: true}
+	if !reflect.DeepEqual(direct, want) {
+		t.Errorf("expected overridden direct instance %+v, got %+v", want, direct)
+	}
+	if cfg.Instances["extra"].URL != "http://extra.example.com" {
+		t.Errorf("expected workflow-only instance to be added, got %+v", cfg.Instances["extra"])
+	}
+	if cfg.Instances["local"].AuthEnv != "TEST_ENV_VAR" {
+		t.Errorf("expected untouched instance to be kept, got %+v", cfg.Instances["local"])
+	}
+
+	// The instances file itself is not modified for other workflows.
+	other, err := Load(td("load_instances.yaml"), td("load_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if other.Instances["direct"].Token != "user:token" {
+		t.Errorf("expected global direct instance to keep its token, got %+v", other.Instances["direct"])
+	}
+
+	if _, err := Load(td("alias_instances.yaml"), td("instance_override_alias_workflow.yaml")); err == nil || !strings.Contains(err.Error(), `workflow instance "ci" has the same name as an instance alias`) {
+		t.Errorf("expected alias shadowing error, got %v", err)
+	}
+	if _, err := Load(td("load_instances.yaml"), td("instance_override_invalid_workflow.yaml")); err == nil || !strings.Contains(err.Error(), `instance "extra" has empty URL`) {
+		t.Errorf("expected merged instance to be validated, got %v", err)
+	}
+}
+
+func TestLoad_InstanceConflicts(t *testing.T) {
+	_, err := Load(td("load_instances.yaml"), td("instance_conflict_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `workflow instance "direct" changes the url`) || !strings.Contains(err.Error(), "instance_conflict_workflow.yaml:4:") {
+		t.Errorf("expected a url conflict error with its line, got %v", err)
+	}
+
+	cfg, err := Load(td("load_instances.yaml"), td("instance_conflict_override_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.Instances["direct"].URL; got != "http://elsewhere.example.com" {
+		t.Errorf("expected override: true to replace the url, got %q", got)
+	}
+
+	// A profile's instances are checked the same way.
+	_, err = LoadWithProfile(td("load_instances.yaml"), td("profile_instance_conflict_workflow.yaml"), "elsewhere")
+	if err == nil || !strings.Contains(err.Error(), `workflow instance "direct" changes the url`) || !strings.Contains(err.Error(), "profile_instance_conflict_workflow.yaml:10:") {
+		t.Errorf("expected a url conflict error at the profile's entry, got %v", err)
+	}
+	cfg, err = LoadWithProfile(td("load_instances.yaml"), td("profile_instance_conflict_workflow.yaml"), "replaced")
+	if err != nil {
+		t.Fatalf("LoadWithProfile failed: %v", err)
+	}
+	if got := cfg.Instances["direct"].URL; got != "http://elsewhere.example.com" {
+		t.Errorf("expected the profile's override: true to replace the url, got %q", got)
+	}
+
+	// With the instances file winning, shared names keep the global instance
+	// unless the workflow entry sets override: true.
+	opts := LoadOptions{PreferGlobalInstances: true}
+	for file, wantURL := range map[string]string{
+		"instance_conflict_workflow.yaml":          "http://jenkins.example.com",
+		"instance_conflict_override_workflow.yaml": "http://elsewhere.example.com",
+	} {
+		cfg, err := LoadWithOptions(td("load_instances.yaml"), td(file), opts)
+		if err != nil {
+			t.Fatalf("%s: LoadWithOptions failed: %v", file, err)
+		}
+		if got := cfg.Instances["direct"].URL; got != wantURL {
+			t.Errorf("%s: expected url %q, got %q", file, wantURL, got)
+		}
+	}
+	cfg, err = LoadWithOptions(td("load_instances.yaml"), td("instance_override_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if direct := cfg.Instances["direct"]; direct.Token != "user:token" || direct.BuildTimeoutSecs != 0 {
+		t.Errorf("expected the global direct instance to be kept, got %+v", direct)
+	}
+	if cfg.Instances["extra"].URL != "http://extra.example.com" {
+		t.Errorf("expected workflow-only instances to still be added, got %+v", cfg.Instances["extra"])
+	}
+}
+
+func TestLoad_InstanceURLOverrideDropsCredentials(t *testing.T) {
+	instancesPath := filepath.Join(t.TempDir(), "instances.yaml")
+	instances := `instances:
+  prod:
+    url: http://prod.example.com
+    token: "prod:secret"
+    headers:
+      X-Gateway-Key: gateway-secret
+    default_params:
+      TEAM: platform
+`
+	if err := os.WriteFile(instancesPath, []byte(instances), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	urlOnly := []byte("instances:\n  prod:\n    url: http://attacker.example.com\n    override: true\nworkflow:\n  - name: Build\n    instance: prod\n    job: /job/build\n")
+	_, err := LoadWithOptions(instancesPath, "workflow.yaml", LoadOptions{WorkflowData: urlOnly})
+	if err == nil || !strings.Contains(err.Error(), `workflow instance "prod" changes the url of the instance with that name, so it must set its own`) {
+		t.Errorf("expected a url-only override to be rejected, got %v", err)
+	}
+
+	ownAuth := []byte("instances:\n  prod:\n    url: http://other.example.com\n    auth_env: OTHER_TOKEN\n    override: true\nworkflow:\n  - name: Build\n    instance: prod\n    job: /job/build\n")
+	cfg, err := LoadWithOptions(instancesPath, "workflow.yaml", LoadOptions{WorkflowData: ownAuth})
+	if err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	want := Instance{URL: "http://other.example.com", AuthEnv: "OTHER_TOKEN", Override: true}
+	got := cfg.Instances["prod"]
+	got.sources = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the global token, headers and default_params not to be inherited, got %+v", got)
+	}
+}
+
+func TestLoad_DefaultParams(t *testing.T) {
+	cfg, err := Load(td("default_params_instances.yaml"), td("default_params_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  map[string]string
+		want map[string]string
+	}{
+		{"aliased step", cfg.Workflow[0].Params, map[string]string{"TEAM": "platform", "REGION": "eu-west-1"}},
+		{"step value wins", cfg.Workflow[1].Parallel.Steps[0].Params, map[string]string{"TEAM": "platform", "REGION": "us-east-1"}},
+		{"instance without defaults", cfg.Workflow[1].Parallel.Steps[1].Params, nil},
+		{"finally step", cfg.Finally[0].Params, map[string]string{"TEAM": "platform", "REGION": "eu-west-1", "MODE": "full"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: expected params %v, got %v", tt.name, tt.want, tt.got)
+		}
+	}
+
+	// Steps get their own copy of the defaults.
+	cfg.Workflow[0].Params["TEAM"] = "changed"
+	if cfg.Instances["prod-jenkins"].DefaultParams["TEAM"] != "platform" {
+		t.Errorf("expected instance defaults to be unaffected by step edits")
+	}
+}
+
+func TestLoad_EnvExpansion(t *testing.T) {
+	t.Setenv("JF_TEST_PROD_URL", "http://prod.example.com")
+	t.Setenv("JF_TEST_WEBHOOK", "https://hooks.slack.com/services/T/B/X")
+	t.Setenv("JF_TEST_TEAM", "payments")
+	t.Setenv("JF_TEST_BUILD", "42")
+
+	cfg, err := Load(td("env_instances.yaml"), td("env_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.Instances["prod"].URL; got != "http://prod.example.com" {
+		t.Errorf("expected prod URL from the environment, got %q", got)
+	}
+	if got := cfg.Instances["staging"].URL; got != "http://staging.example.com" {
+		t.Errorf("expected staging URL from its default, got %q", got)
+	}
+	if cfg.SlackWebhook != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("expected webhook from ${env.NAME}, got %q", cfg.SlackWebhook)
+	}
+	if got := cfg.Workflow[0].Job; got != "/job/payments/job/build" {
+		t.Errorf("expected job path from the environment, got %q", got)
+	}
+	want := map[string]string{"VERSION": "${VERSION}", "REGION": "eu-west-1"}
+	if !reflect.DeepEqual(cfg.Workflow[0].Params, want) {
+		t.Errorf("expected inputs kept and defaults applied, got %v", cfg.Workflow[0].Params)
+	}
+	if got := cfg.Workflow[1].Job; got != "/job/deploy-${steps.build.build_number}" {
+		t.Errorf("expected step reference kept for run time, got %q", got)
+	}
+	want = map[string]string{"RELEASE": "${vars.release}", "BUILD": "42"}
+	if !reflect.DeepEqual(cfg.Workflow[1].Params, want) {
+		t.Errorf("expected vars kept and env expanded, got %v", cfg.Workflow[1].Params)
+	}
+}
+
+func TestLoad_EnvExpansionUnset(t *testing.T) {
+	t.Setenv("JF_TEST_TEAM", "payments")
+
+	_, err := Load(td("env_instances.yaml"), td("env_workflow.yaml"))
+	var envErr *EnvError
+	if !errors.As(err, &envErr) {
+		t.Fatalf("expected an *EnvError, got %v", err)
+	}
+	want := []string{
+		"instances.prod.url: JF_TEST_PROD_URL",
+		"slack_webhook: JF_TEST_WEBHOOK",
+		"workflow[1].params.BUILD: JF_TEST_BUILD",
+	}
+	if !reflect.DeepEqual(envErr.Missing, want) {
+		t.Errorf("expected every unset variable reported, got %q", envErr.Missing)
+	}
+}
+
+func TestLoad_EnvExpansionEnvFile(t *testing.T) {
+	t.Setenv("JF_TEST_TEAM", "payments")
+	env := EnvOverlay{Base: OSEnv{}, Values: map[string]string{
+		"JF_TEST_PROD_URL": "http://prod.example.com",
+		"JF_TEST_WEBHOOK":  "https://hooks.slack.com/services/T/B/X",
+		"JF_TEST_BUILD":    "7",
+	}}
+
+	cfg, err := LoadWithOptions(td("env_instances.yaml"), td("env_workflow.yaml"), LoadOptions{Env: env})
+	if err != nil {
+		t.Fatalf("expected variables from the env file to count, got %v", err)
+	}
+	if got := cfg.Instances["prod"].URL; got != "http://prod.example.com" {
+		t.Errorf("expected prod URL from the env file, got %q", got)
+	}
+	if got := cfg.Workflow[1].Params["BUILD"]; got != "7" {
+		t.Errorf("expected BUILD from the env file, got %q", got)
+	}
+}
+
+func TestLoad_SlackTLS(t *testing.T) {
+	cfg, err := Load(td("load_instances.yaml"), td("slack_tls_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := td("slack_ca.pem"); cfg.SlackTLS.CACert != want {
+		t.Errorf("expected ca_cert resolved against the workflow file to %q, got %q", want, cfg.SlackTLS.CACert)
+	}
+
+	_, err = Load(td("load_instances.yaml"), td("slack_tls_missing_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "slack_tls: reading ca_cert") {
+		t.Fatalf("expected a ca_cert read error, got %v", err)
+	}
+}
+
+func TestCheckWebhook(t *testing.T) {
+	hosts := []string{"Mattermost.example.com"}
+	tests := []struct {
+		webhook string
+		want    string // substring of the error; "" for valid
+	}{
+		{"https://hooks.slack.com/services/T000/B000/XXXX", ""},
+		{"https://hooks.slack.com/workflows/T000/A000/1/XXXX", ""},
+		{"https://mattermost.example.com/hooks/xyz", ""},
+		{"http://mattermost.example.com:8065/hooks/xyz", ""},
+		{"${env.SLACK_WEBHOOK}", ""},
+		{"", ""},
+		{"https://hooks.slak.com/services/T000/B000/XXXX", `host "hooks.slak.com" is not hooks.slack.com`},
+		{"http://hooks.slack.com/services/T000/B000/XXXX", "must use https"},
+		{"https://hooks.slack.com/", "no webhook path"},
+		{"hooks.slack.com/services/T000/B000/XXXX", "not an http(s) URL"},
+		{"https://hooks.slack.com/services/%zz", "not an http(s) URL"},
+	}
+	for _, tt := range tests {
+		err := checkWebhook(tt.webhook, hosts)
+		if tt.want == "" && err != nil {
+			t.Errorf("%q: unexpected error %v", tt.webhook, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%q: expected an error containing %q, got %v", tt.webhook, tt.want, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "XXXX") {
+			t.Errorf("%q: expected the error not to include the webhook's secret path: %v", tt.webhook, err)
+		}
+	}
+}
+
+func TestLoad_SlackWebhookShape(t *testing.T) {
+	_, err := Load(td("load_instances.yaml"), td("slack_webhook_typo_workflow.yaml"))
+	if want := td("slack_webhook_typo_workflow.yaml") + `:2: slack_webhook: host "hooks.slak.com"`; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+
+	cfg := &Config{
+		Instances:         map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		SlackWebhookHosts: []string{"https://chat.example.com"},
+		Workflow:          []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/a"}},
+	}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "slack_webhook_hosts: entry 1 must be a host name") {
+		t.Errorf("expected a URL in slack_webhook_hosts to be rejected, got %v", err)
+	}
+	cfg.SlackWebhookHosts = []string{"chat.example.com"}
+	cfg.Workflow[0].Notify = &ItemNotify{Events: []string{NotifyOnFailure}, Webhook: "https://chat.example.org/hooks/x"}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), `notify webhook: host "chat.example.org"`) {
+		t.Errorf("expected an item webhook on an unlisted host to be rejected, got %v", err)
+	}
+	cfg.Workflow[0].Notify.Webhook = "https://chat.example.com/hooks/x"
+	if err := cfg.validate(); err != nil {
+		t.Errorf("expected a listed host to be accepted, got %v", err)
+	}
+}
+
+func TestLoad_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances string
+		workflow  string
+		want      string
+	}{
+		{"parallel step", "single_local_instance.yaml", "parallel_unknown_workflow.yaml", td("parallel_unknown_workflow.yaml") + ":4: parallel[0].step[0]"},
+		{"pr wait item", "pr_instances.yaml", "pr_invalid_workflow.yaml", td("pr_invalid_workflow.yaml") + ":2: wait_for_pr[0]"},
+		{"instance", "missing_auth_instances.yaml", "missing_auth_workflow.yaml", td("missing_auth_instances.yaml") + `:2: instance "bad"`},
+		{"workflow instance", "load_instances.yaml", "instance_override_invalid_workflow.yaml", td("instance_override_invalid_workflow.yaml") + `:3: instance "extra"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(td(tt.instances), td(tt.workflow))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("expected error starting with %q, got %v", tt.want, err)
+			}
+			var located *SourceError
+			if !errors.As(err, &located) {
+				t.Errorf("expected a *SourceError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestParseWorkflowMeta(t *testing.T) {
+	meta, err := ParseWorkflowMeta(td("workflow_meta.yaml"), false)
+	if err != nil {
+		t.Fatalf("ParseWorkflowMeta failed: %v", err)
+	}
+	if meta.Name != "My Workflow" {
+		t.Errorf("expected name 'My Workflow', got %q", meta.Name)
+	}
+	if meta.Description != "Builds and deploys the API" {
+		t.Errorf("expected trimmed description, got %q", meta.Description)
+	}
+	if !reflect.DeepEqual(meta.Tags, []string{"deploy", "Prod"}) {
+		t.Errorf("expected tags [deploy Prod], got %v", meta.Tags)
+	}
+	if !meta.HasTag("prod") || meta.HasTag("staging") {
+		t.Errorf("expected HasTag to match prod case-insensitively and reject staging")
+	}
+
+	if _, err := ParseWorkflowMeta(td("workflow_meta_missing_name.yaml"), false); err == nil {
+		t.Error("expected error for missing name, got nil")
+	}
+
+	// Description is optional.
+	meta, err = ParseWorkflowMeta(td("alias_workflow.yaml"), false)
+	if err != nil || meta.Description != "" {
+		t.Errorf("expected empty description without error, got %q, %v", meta.Description, err)
+	}
+}
+
+func TestPRWaitShouldAutoUpdate(t *testing.T) {
+	t.Run("nil receiver", func(t *testing.T) {
+		var p *PRWait
+		if !p.ShouldAutoUpdate() {
+			t.Fatal("nil PRWait should default to auto-update on")
+		}
+	})
+
+	t.Run("unset (nil pointer) defaults true", func(t *testing.T) {
+		p := &PRWait{}
+		if !p.ShouldAutoUpdate() {
+			t.Fatal("unset AutoUpdateBranch should default to true")
+		}
+	})
+
+	t.Run("explicit false", func(t *testing.T) {
+		f := false
+		p := &PRWait{AutoUpdateBranch: &f}
+		if p.ShouldAutoUpdate() {
+			t.Fatal("explicit false should disable auto-update")
+		}
+	})
+
+	t.Run("explicit true", func(t *testing.T) {
+		v := true
+		p := &PRWait{AutoUpdateBranch: &v}
+		if !p.ShouldAutoUpdate() {
+			t.Fatal("explicit true should enable auto-update")
+		}
+	})
+}
+
+func TestApplyInputs_PRWaitHeadBranch(t *testing.T) {
+	cfg := &Config{
+		Inputs: map[string]string{
+			"git_branch_to_merge": "PAYMENTS-3096_update_threshold",
+		},
+		Workflow: []WorkflowItem{
+			{
+				WaitForPR: &PRWait{
+					Name:       "Wait for Release PR",
+					Owner:      "chargepoint-emu",
+					Repo:       "nos",
+					HeadBranch: "${git_branch_to_merge}",
+					WaitFor:    "merged",
+				},
+			},
+		},
+	}
+
+	if err := cfg.ApplyInputs(); err != nil {
+		t.Fatalf("ApplyInputs failed: %v", err)
+	}
+
+	got := cfg.Workflow[0].WaitForPR.HeadBranch
+	if got != "PAYMENTS-3096_update_threshold" {
+		t.Fatalf("expected head_branch to be substituted, got %q", got)
+	}
+}
+
+func TestLoad_PRWaitInputTemplates(t *testing.T) {
+	cfg, err := Load(td("pr_instances.yaml"), td("pr_input_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Load validates against substituted values but leaves templates in place
+	// so run-time input overrides can still be applied.
+	if got := cfg.Workflow[0].WaitForPR.HeadBranch; got != "release/${VERSION}" {
+		t.Fatalf("expected head_branch template to be preserved, got %q", got)
+	}
+	if got := cfg.Workflow[1].WaitForPR.PRNumberTemplate; got != "${PR}" {
+		t.Fatalf("expected pr_number template to be preserved, got %q", got)
+	}
+
+	cfg.Inputs["VERSION"] = "2.0"
+	if err := cfg.ApplyInputs(); err != nil {
+		t.Fatalf("ApplyInputs failed: %v", err)
+	}
+	if got := cfg.Workflow[0].WaitForPR.HeadBranch; got != "release/2.0" {
+		t.Errorf("expected head_branch 'release/2.0', got %q", got)
+	}
+	if got := cfg.Workflow[0].WaitForPR.Name; got != "Wait for Release 2.0" {
+		t.Errorf("expected substituted name, got %q", got)
+	}
+	if got := cfg.Workflow[1].WaitForPR.PRNumber; got != 77 {
+		t.Errorf("expected pr_number 77, got %d", got)
+	}
+}
+
+func TestLoad_InstanceURLInputs(t *testing.T) {
+	cfg, err := Load(td("instance_url_input_instances.yaml"), td("instance_url_input_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.Instances["regional"].URL; got != "https://jenkins-${region}.example.com" {
+		t.Fatalf("expected the url template to be preserved, got %q", got)
+	}
+
+	cfg.Inputs["region"] = "us"
+	if err := cfg.ApplyInputs(); err != nil {
+		t.Fatalf("ApplyInputs failed: %v", err)
+	}
+	if got := cfg.Instances["regional"].URL; got != "https://jenkins-us.example.com" {
+		t.Errorf("expected the resolved url, got %q", got)
+	}
+
+	cfg, err = Load(td("instance_url_input_instances.yaml"), td("instance_url_input_workflow.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Inputs["region"] = "eu:bad"
+	if err := cfg.ApplyInputs(); err == nil || !strings.Contains(err.Error(), "does not resolve to an http(s) URL") {
+		t.Errorf("expected a malformed url to be rejected, got %v", err)
+	}
+
+	// A workflow that does not use the templated instance need not declare
+	// its input.
+	other := []byte("schema: 2\ninstances:\n  local:\n    url: http://localhost:8080\n    token: user:token\nworkflow:\n  - name: Build\n    instance: local\n    job: /job/build\n")
+	cfg, err = LoadWithOptions(td("instance_url_input_instances.yaml"), "other.yaml", LoadOptions{WorkflowData: other})
+	if err != nil {
+		t.Fatalf("expected an unused templated instance to be ignored, got %v", err)
+	}
+	if got := cfg.Instances["regional"].URL; got != "https://jenkins-${region}.example.com" {
+		t.Errorf("expected the unused instance's url to be left alone, got %q", got)
+	}
+
+	dir := t.TempDir()
+	for name, tt := range map[string]struct{ url, wantErr string }{
+		"undefined input": {url: "https://jenkins-${inputs.zone}.example.com", wantErr: `references undefined input "inputs.zone"`},
+		"not http":        {url: "${region}://jenkins.example.com", wantErr: `url "eu://jenkins.example.com" does not resolve to an http(s) URL`},
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".yaml")
+		content := "schema: 2\ninputs:\n  region: eu\ninstances:\n  regional:\n    url: " + tt.url + "\n    token: user:token\nworkflow:\n  - name: Build\n    instance: regional\n    job: /job/build\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(filepath.Join(dir, "none.yaml"), path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	instances := td("instance_url_input_instances.yaml")
+	content := "inputs:\n  region: eu\nworkflow:\n  - name: Build\n    instance: regional\n    job: /job/build\n  - name: Deploy\n    instance: elsewhere\n    job: /job/deploy\n"
+
+	errs, warnings := Validate(instances, "draft.yaml", LoadOptions{WorkflowData: []byte(content)})
+	if len(errs) != 1 || errs[0].File != "draft.yaml" || errs[0].Line != 7 || !strings.Contains(errs[0].Message, `unknown instance or alias "elsewhere"`) {
+		t.Errorf("expected the unknown instance as a located error, got %+v", errs)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "declares no schema version") {
+		t.Errorf("expected the legacy schema warning, got %+v", warnings)
+	}
+
+	errs, warnings = Validate(instances, "draft.yaml", LoadOptions{WorkflowData: []byte(content), AllowUnknownInstances: true})
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+	if len(warnings) != 2 || warnings[1].Line != 8 || !strings.Contains(warnings[1].Message, `step 1 ("Deploy"): unknown instance or alias "elsewhere"`) {
+		t.Errorf("expected the unknown instance as a located warning, got %+v", warnings)
+	}
+
+	content = "schema: 2\ninstances:\n  local:\n    url: ${JF_TEST_UNSET_A}\n    token: user:token\nworkflow:\n  - name: Build\n    instance: local\n    job: /job/${JF_TEST_UNSET_B}\n"
+	errs, warnings = Validate(filepath.Join(t.TempDir(), "none.yaml"), "draft.yaml", LoadOptions{WorkflowData: []byte(content)})
+	if len(errs) != 2 || !strings.Contains(errs[0].Message, "JF_TEST_UNSET_A") {
+		t.Errorf("expected one error per unset variable, got %+v", errs)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %+v", warnings)
+	}
+
+	errs, _ = Validate(instances, filepath.Join(t.TempDir(), "missing.yaml"), LoadOptions{})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "failed to read workflow config") {
+		t.Errorf("expected a read error, got %+v", errs)
+	}
+}
+
+func TestValidatePRWait_UndefinedInput(t *testing.T) {
+	_, err := Load(td("pr_instances.yaml"), td("pr_missing_input_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `undefined input "RELEASE"`) {
+		t.Fatalf("expected undefined input error, got %v", err)
+	}
+}
+
+func TestLoad_Vars(t *testing.T) {
+	t.Setenv("JF_TEST_PATCH", "3")
+	cfg, err := Load(td("pr_instances.yaml"), td("vars_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	vars := cfg.TemplateVars(cfg.Inputs)
+	if got := vars["vars.release"]; got != "v1.4.3" {
+		t.Errorf("expected vars.release 'v1.4.3', got %q", got)
+	}
+	if got := vars["inputs.VERSION"]; got != "1.4" {
+		t.Errorf("expected inputs.VERSION '1.4', got %q", got)
+	}
+	if got := cfg.Expand(cfg.Workflow[1].Job); got != "/job/build-v1.4.3" {
+		t.Errorf("expected expanded job path, got %q", got)
+	}
+	if refs := cfg.InputRefs(cfg.Workflow[1].Params["TAG"]); len(refs) != 1 || refs[0] != "VERSION" {
+		t.Errorf("expected TAG to depend on input VERSION through vars, got %v", refs)
+	}
+
+	cfg.Inputs["VERSION"] = "2.0"
+	if err := cfg.ApplyInputs(); err != nil {
+		t.Fatalf("ApplyInputs failed: %v", err)
+	}
+	pr := cfg.Workflow[0].WaitForPR
+	if pr.HeadBranch != "release/v2.0.3" || pr.Name != "Wait for v2.0.3" {
+		t.Errorf("expected PR wait to use re-resolved vars, got name %q branch %q", pr.Name, pr.HeadBranch)
+	}
+}
+
+func TestLoad_VarsErrors(t *testing.T) {
+	tests := []struct {
+		workflow string
+		wantErr  string
+	}{
+		{"vars_cycle_workflow.yaml", "circular reference vars.a -> vars.b -> vars.c -> vars.a"},
+		{"vars_undefined_workflow.yaml", `vars.release: references undefined input "VERSION"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.workflow, func(t *testing.T) {
+			_, err := Load(td("pr_instances.yaml"), td(tt.workflow))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// An empty environment leaves JF_TEST_PATCH unset
+	if _, err := LoadWithOptions(td("pr_instances.yaml"), td("vars_workflow.yaml"), LoadOptions{Env: fakeEnv{}}); err == nil || !strings.Contains(err.Error(), `environment variable "JF_TEST_PATCH" is not set`) {
+		t.Fatalf("expected unset environment variable error, got %v", err)
+	}
+}
+
+func TestApplyInputs_NonNumericPRNumber(t *testing.T) {
+	cfg := &Config{
+		Inputs: map[string]string{"PR": "abc"},
+		Workflow: []WorkflowItem{
+			{
+				WaitForPR: &PRWait{
+					Name:             "Wait",
+					Owner:            "o",
+					Repo:             "r",
+					PRNumberTemplate: "${PR}",
+					WaitFor:          "merged",
+				},
+			},
+		},
+	}
+	if err := cfg.ApplyInputs(); err == nil {
+		t.Fatal("expected error for non-numeric pr_number, got nil")
+	}
+}
+
+func TestValidate_DuplicateItemNames(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Deploy", ID: "deploy_step", Instance: "local", Job: "/job/a"},
+			{Parallel: &ParallelGroup{
+				Name:  "Deploy",
+				Steps: []Step{{Name: "Deploy US", Instance: "local", Job: "/job/b"}},
+			}},
+		},
+	}
+	err := cfg.validate()
+	if err == nil {
+		t.Fatal("expected duplicate name error, got nil")
+	}
+	if !strings.Contains(err.Error(), "step 0") || !strings.Contains(err.Error(), "parallel[1]") {
+		t.Errorf("expected both duplicate locations in error, got %q", err)
+	}
+}
+
+func TestValidate_DuplicateStepNamesInParallelGroup(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Parallel: &ParallelGroup{
+				Steps: []Step{
+					{Name: "Deploy", ID: "deploy_us", Instance: "local", Job: "/job/a"},
+					{Name: "Deploy", ID: "deploy_eu", Instance: "local", Job: "/job/b"},
+				},
+			}},
+		},
+	}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), "parallel[0].step[0] and parallel[0].step[1]") {
+		t.Fatalf("expected duplicate step name error naming both steps, got %v", err)
+	}
+}
+
+func TestValidate_DuplicateStepNamesAcrossGroups(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Deploy", ID: "deploy", Instance: "local", Job: "/job/a"},
+			{Parallel: &ParallelGroup{
+				Name:  "Regions",
+				Steps: []Step{{Name: "Deploy", ID: "deploy_us", Instance: "local", Job: "/job/b"}},
+			}},
+		},
+	}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), `duplicate name "Deploy": defined at step 0 and parallel[1].step[0]`) {
+		t.Fatalf("expected duplicate step name error naming both steps, got %v", err)
+	}
+
+	cfg.AllowDuplicateNames = true
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected allow_duplicate_names to accept duplicates, got %v", err)
+	}
+	cfg.Workflow[1].Parallel.Steps[0].ID = "deploy"
+	if err := cfg.validate(); err == nil {
+		t.Fatal("expected duplicate step IDs to be rejected even with allow_duplicate_names")
+	}
+}
+
+func TestValidate_UnnamedParallelGroups(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Parallel: &ParallelGroup{Steps: []Step{{Name: "A", Instance: "local", Job: "/job/a"}}}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected a single unnamed group to be accepted, got %v", err)
+	}
+
+	cfg.Finally = []WorkflowItem{
+		{Parallel: &ParallelGroup{Name: "Cleanup", Steps: []Step{{Name: "B", Instance: "local", Job: "/job/b"}}}},
+	}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), "parallel[0]: parallel groups need a name") {
+		t.Fatalf("expected unnamed group error, got %v", err)
+	}
+
+	cfg.AllowDuplicateNames = true
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected allow_duplicate_names to accept unnamed groups, got %v", err)
+	}
+}
+
+func TestLoad_ForwardInputs(t *testing.T) {
+	cfg, err := Load(td("default_params_instances.yaml"), td("forward_inputs_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Explicit params win over forwarded inputs, which win over default_params.
+	want := map[string]string{"VERSION": "${VERSION}", "ENV": "production", "TEAM": "${TEAM}", "REGION": "eu-west-1"}
+	if got := cfg.Workflow[0].Params; !reflect.DeepEqual(got, want) {
+		t.Errorf("Build params = %v, want %v", got, want)
+	}
+	want = map[string]string{"VERSION": "${VERSION}", "TEAM": "platform", "REGION": "eu-west-1"}
+	if got := cfg.Workflow[1].Parallel.Steps[0].Params; !reflect.DeepEqual(got, want) {
+		t.Errorf("Deploy params = %v, want %v", got, want)
+	}
+	if got := cfg.Workflow[1].Parallel.Steps[1].Params; len(got) != 0 {
+		t.Errorf("expected forward_inputs: false to forward nothing, got %v", got)
+	}
+
+	_, err = Load(td("default_params_instances.yaml"), td("forward_inputs_unknown_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `forward_inputs names "RELEASE", which is not a declared input`) {
+		t.Errorf("expected an undeclared input error, got %v", err)
+	}
+}
+
+func graphTestConfig() *Config {
+	return &Config{
+		Name: `Release "v2"`,
+		Workflow: []WorkflowItem{
+			{WaitForPR: &PRWait{Name: "Wait for PR", Owner: "acme", Repo: "api", PRNumber: 7, WaitFor: "merged"}},
+			{Name: "Build", Instance: "ci", Job: "/job/build"},
+			{Parallel: &ParallelGroup{Name: "Deploy", Steps: []Step{
+				{Name: "Deploy EU", Instance: "eu", Job: "/job/deploy"},
+				{Name: "Deploy US", Instance: "us", Job: "/job/deploy"},
+			}}},
+			{Command: &Command{Name: "Purge", Run: "true"}},
+		},
+		Finally: []WorkflowItem{
+			{GitHubStatus: &GitHubStatus{Name: "Report", Owner: "acme", Repo: "api"}},
+		},
+	}
+}
+
+func TestToMermaid(t *testing.T) {
+	want := `flowchart TD
+    n0{{"Wait for PR<br/>wait for acme/api#7 merged"}}
+    n1["Build<br/>ci"]
+    subgraph p0 ["Deploy"]
+        n2["Deploy EU<br/>eu"]
+        n3["Deploy US<br/>us"]
+    end
+    n4[/"Purge<br/>local"/]
+    n5(["Report<br/>acme/api"])
+    n0 --> n1
+    n1 --> n2
+    n1 --> n3
+    n2 --> n4
+    n3 --> n4
+    n4 -.->|finally| n5
+`
+	if got := ToMermaid(graphTestConfig()); got != want {
+		t.Errorf("ToMermaid =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToDOT(t *testing.T) {
+	want := `digraph workflow {
+    label="Release \"v2\"";
+    rankdir=TB;
+    n0 [shape=hexagon, label="Wait for PR\nwait for acme/api#7 merged"];
+    n1 [shape=box, label="Build\nci"];
+    subgraph cluster_p0 {
+        label="Deploy";
+        n2 [shape=box, label="Deploy EU\neu"];
+        n3 [shape=box, label="Deploy US\nus"];
+    }
+    n4 [shape=parallelogram, label="Purge\nlocal"];
+    n5 [shape=ellipse, label="Report\nacme/api"];
+    n0 -> n1;
+    n1 -> n2;
+    n1 -> n3;
+    n2 -> n4;
+    n3 -> n4;
+    n4 -> n5 [style=dashed, label="finally"];
+}
+`
+	if got := ToDOT(graphTestConfig()); got != want {
+		t.Errorf("ToDOT =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLoad_Templates(t *testing.T) {
+	cfg, err := Load(td("default_params_instances.yaml"), td("template_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	api := cfg.Workflow[0].AsStep()
+	if api.Instance != "prod-jenkins" || api.Job != "/job/deploy" || api.IfRunning != IfRunningWait {
+		t.Errorf("expected the template's instance, job and if_running, got %+v", api)
+	}
+	want := map[string]string{"SERVICE": "api", "REGION": "eu-west-1", "STRATEGY": "rolling", "TEAM": "platform"}
+	if !reflect.DeepEqual(api.Params, want) {
+		t.Errorf("Deploy API params = %v, want %v", api.Params, want)
+	}
+
+	worker := cfg.Workflow[1].Parallel.Steps[0]
+	if worker.Instance != "other-jenkins" || worker.Job != "/job/deploy" {
+		t.Errorf("expected the step's instance to win over the template's, got %+v", worker)
+	}
+	want = map[string]string{"SERVICE": "worker", "REGION": "us-east-1", "STRATEGY": "rolling"}
+	if !reflect.DeepEqual(worker.Params, want) {
+		t.Errorf("Deploy US worker params = %v, want %v", worker.Params, want)
+	}
+
+	// Profile step overrides apply to the merged params.
+	canary, err := LoadWithProfile(td("default_params_instances.yaml"), td("template_workflow.yaml"), "canary")
+	if err != nil {
+		t.Fatalf("LoadWithProfile failed: %v", err)
+	}
+	if got := canary.Workflow[0].Params["STRATEGY"]; got != "canary" {
+		t.Errorf("expected the profile to override a template param, got %q", got)
+	}
+	if got := canary.Workflow[1].Parallel.Steps[0].Params["STRATEGY"]; got != "rolling" {
+		t.Errorf("expected steps sharing a template not to share params, got %q", got)
+	}
+
+	_, err = Load(td("default_params_instances.yaml"), td("template_unknown_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `workflow[1].parallel.steps[0] ("Deploy"): unknown template "deploi"`) || !strings.Contains(err.Error(), "template_unknown_workflow.yaml:13:") {
+		t.Errorf("expected an unknown template error with its line, got %v", err)
+	}
+}
+
+func TestLoad_Command(t *testing.T) {
+	cfg, err := Load(td("load_instances.yaml"), td("command_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	item := cfg.Workflow[1]
+	if !item.IsCommand() || item.IsStep() {
+		t.Fatalf("expected a command item, got %+v", item)
+	}
+	want := Command{Name: "Invalidate cache", Run: "./scripts/invalidate-cache.sh ${ENV}", Dir: "/tmp", TimeoutSecs: 60}
+	if *item.Command != want {
+		t.Errorf("expected %+v, got %+v", want, *item.Command)
+	}
+	if item.ItemName() != "Invalidate cache" {
+		t.Errorf("expected the command name as item name, got %q", item.ItemName())
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	tests := []struct {
+		cmd     Command
+		wantErr string
+	}{
+		{Command{Name: "Purge", Run: "true"}, ""},
+		{Command{Run: "true"}, "command[0]: missing name"},
+		{Command{Name: "Purge", Run: "  "}, "missing run"},
+		{Command{Name: "Purge", Run: "true", TimeoutSecs: -1}, "timeout_secs must not be negative"},
+	}
+	for _, tt := range tests {
+		err := validateCommand(&tt.cmd, "command[0]")
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tt.cmd, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.cmd, tt.wantErr, err)
+		}
+	}
+}
+
+func TestItemByName(t *testing.T) {
+	cfg := &Config{
+		Workflow: []WorkflowItem{
+			{Name: "Build", Instance: "local", Job: "/job/a"},
+			{Parallel: &ParallelGroup{Name: "Deploy"}},
+			{WaitForPR: &PRWait{Name: "Wait for PR"}},
+			{Parallel: &ParallelGroup{}},
+		},
+	}
+
+	for want, name := range []string{"Build", "Deploy", "Wait for PR"} {
+		item, idx, ok := cfg.ItemByName(name)
+		if !ok || idx != want || item != &cfg.Workflow[want] {
+			t.Errorf("ItemByName(%q) = (%v, %d, %v), want index %d", name, item, idx, ok, want)
+		}
+	}
+	if _, _, ok := cfg.ItemByName(""); ok {
+		t.Error("unnamed items must not be addressable by the empty name")
+	}
+	if _, _, ok := cfg.ItemByName("Missing"); ok {
+		t.Error("expected lookup of unknown name to fail")
+	}
+
+	cfg.Finally = []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/b"}}
+	if _, _, ok := cfg.ItemByName("Build"); ok {
+		t.Error("expected lookup of a name two items share to fail")
+	}
+}
+
+func TestValidate_AmbiguousPRRefs(t *testing.T) {
+	pr := func(branch string) WorkflowItem {
+		return WorkflowItem{WaitForPR: &PRWait{Name: "Wait for PR", Owner: "org", Repo: "repo", HeadBranch: branch, WaitFor: "merged"}}
+	}
+	cfg := &Config{
+		Instances:           map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		GitHub:              &GitHubConfig{Token: "t"},
+		AllowDuplicateNames: true,
+		Workflow: []WorkflowItem{
+			pr("a"),
+			pr("b"),
+			{Name: "Deploy", Instance: "local", Job: "/job/deploy"},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("expected unreferenced duplicate PR waits to be accepted, got %v", err)
+	}
+
+	cfg.Workflow[2].Params = map[string]string{"SHA": "${pr.wait_for_pr.merge_sha}"}
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), `ambiguous reference ${pr.wait_for_pr.merge_sha}: 2 wait_for_pr items have the id "wait_for_pr"`) {
+		t.Fatalf("expected an ambiguous reference error, got %v", err)
+	}
+}
+
+func TestValidateGitHubStatus(t *testing.T) {
+	base := func(gs *GitHubStatus) *Config {
+		return &Config{
+			Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+			GitHub:    &GitHubConfig{Token: "gh"},
+			Workflow:  []WorkflowItem{{GitHubStatus: gs}},
+		}
+	}
+
+	valid := &GitHubStatus{Name: "Report", Owner: "o", Repo: "r", SHA: "${sha}", State: "success"}
+	if err := base(valid).validate(); err != nil {
+		t.Fatalf("unexpected error for valid status: %v", err)
+	}
+
+	comment := &GitHubStatus{Name: "Comment", Owner: "o", Repo: "r", PRNumber: 3, Comment: "Deployed"}
+	if err := base(comment).validate(); err != nil {
+		t.Fatalf("unexpected error for valid comment: %v", err)
+	}
+
+	cases := map[string]*GitHubStatus{
+		"neither status nor comment": {Name: "x", Owner: "o", Repo: "r"},
+		"bad state":                  {Name: "x", Owner: "o", Repo: "r", SHA: "abc", State: "done"},
+		"comment without pr":         {Name: "x", Owner: "o", Repo: "r", Comment: "hi"},
+		"missing owner":              {Name: "x", Repo: "r", SHA: "abc", State: "success"},
+	}
+	for name, gs := range cases {
+		if err := base(gs).validate(); err == nil {
+			t.Errorf("%s: expected validation error, got nil", name)
+		}
+	}
+
+	noGitHub := base(valid)
+	noGitHub.GitHub = nil
+	if err := noGitHub.validate(); err == nil {
+		t.Error("expected error when github config is missing")
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		Workflow: []WorkflowItem{
+			{Name: "Build", Instance: "local", Job: "/job/a", Notify: &ItemNotify{Events: []string{"on_success"}}},
+		},
+	}
+	if err := cfg.validate(); err == nil {
+		t.Fatal("expected error when no webhook is available")
+	}
+
+	cfg.SlackWebhook = "https://hooks.slack.com/services/T/B/X"
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected error with workflow webhook: %v", err)
+	}
+
+	cfg.Workflow[0].Notify.Events = []string{"on_finish"}
+	if err := cfg.validate(); err == nil {
+		t.Fatal("expected error for unknown notify event")
+	}
+}
+
+func TestValidate_NotifyOn(t *testing.T) {
+	cfg := &Config{
+		Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+		NotifyOn:  []string{"failure", "unstable", "aborted", "success"},
+		Workflow:  []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/a"}},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.NotifyOn = []string{"failure", "failed"}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), `unknown outcome "failed"`) {
+		t.Errorf("expected unknown outcome error, got %v", err)
+	}
+}
+
+func TestGitHubConfig_RequestTimeout(t *testing.T) {
+	var nilCfg *GitHubConfig
+	if got := nilCfg.RequestTimeout(); got != 0 {
+		t.Errorf("nil config: expected 0, got %s", got)
+	}
+	if got := (&GitHubConfig{RequestTimeoutSecs: 90}).RequestTimeout(); got != 90*time.Second {
+		t.Errorf("expected 90s, got %s", got)
+	}
+}
+
+func TestLoad_GitHubApp(t *testing.T) {
+	cfg, err := Load(td("github_app_instances.yaml"), td("pr_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	app := cfg.GitHub.App
+	if app == nil || app.AppID != 12345 || app.InstallationID != 678 {
+		t.Fatalf("unexpected app config: %+v", app)
+	}
+	if want := filepath.Join("testdata", "keys", "app.pem"); app.PrivateKeyPath != want {
+		t.Errorf("expected key path resolved against the instances file (%q), got %q", want, app.PrivateKeyPath)
+	}
+}
+
+func TestValidateGitHubApp(t *testing.T) {
+	tests := []struct {
+		name    string
+		github  GitHubConfig
+		wantErr string
+	}{
+		{"valid", GitHubConfig{App: &GitHubApp{AppID: 1, InstallationID: 2, PrivateKeyPath: "k.pem"}}, ""},
+		{"mixed with token", GitHubConfig{Token: "gh", App: &GitHubApp{AppID: 1, InstallationID: 2, PrivateKeyPath: "k.pem"}}, "cannot be combined"},
+		{"missing app_id", GitHubConfig{App: &GitHubApp{InstallationID: 2, PrivateKeyPath: "k.pem"}}, "app_id is required"},
+		{"missing installation_id", GitHubConfig{App: &GitHubApp{AppID: 1, PrivateKeyPath: "k.pem"}}, "installation_id is required"},
+		{"missing key", GitHubConfig{App: &GitHubApp{AppID: 1, InstallationID: 2}}, "private_key_path is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			github := tt.github
+			cfg := &Config{
+				Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+				GitHub:    &github,
+				Workflow:  []WorkflowItem{{Name: "Build", Instance: "local", Job: "/job/build"}},
+			}
+			err := cfg.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Finally(t *testing.T) {
+	cfg, err := Load(td("load_instances.yaml"), td("finally_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Finally) != 2 {
+		t.Fatalf("expected 2 finally items, got %d", len(cfg.Finally))
+	}
+	all := cfg.AllItems()
+	if len(all) != 3 || all[1].ItemName() != "Release Lock" || all[2].ItemName() != "Cleanup" {
+		t.Fatalf("unexpected AllItems order: %+v", all)
+	}
+	if cfg.IsFinallyIndex(0) || !cfg.IsFinallyIndex(1) || !cfg.IsFinallyIndex(2) || cfg.IsFinallyIndex(3) {
+		t.Error("IsFinallyIndex does not match AllItems layout")
+	}
+	if item, idx, ok := cfg.ItemByName("Cleanup"); !ok || idx != 2 || item != &cfg.Finally[1] {
+		t.Errorf("ItemByName(Cleanup) = (%v, %d, %v), want finally item at index 2", item, idx, ok)
+	}
+}
+
+func TestValidate_Finally(t *testing.T) {
+	base := func(finally ...WorkflowItem) *Config {
+		return &Config{
+			Instances: map[string]Instance{"local": {URL: "http://x", Token: "t"}},
+			Workflow:  []WorkflowItem{{Name: "Deploy", Instance: "local", Job: "/job/deploy"}},
+			Finally:   finally,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		finally []WorkflowItem
+		wantErr string
+	}{
+		{
+			name:    "pr wait not allowed",
+			finally: []WorkflowItem{{WaitForPR: &PRWait{Name: "Wait", Owner: "o", Repo: "r", PRNumber: 1, WaitFor: "merged"}}},
+			wantErr: "wait_for_pr is not allowed in finally",
+		},
+		{
+			name:    "name shared with workflow item",
+			finally: []WorkflowItem{{Name: "Deploy", ID: "deploy_again", Instance: "local", Job: "/job/x"}},
+			wantErr: `duplicate name "Deploy": defined at step 0 and finally.step 0`,
+		},
+		{
+			name:    "invalid step",
+			finally: []WorkflowItem{{Name: "Unlock", Instance: "missing", Job: "/job/x"}},
+			wantErr: "finally.step 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := base(tt.finally...).validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadWithProfile_InstanceAliases(t *testing.T) {
+	tests := []struct {
+		profile     string
+		wantBuild   string
+		wantDeploy  string
+		wantSmoke   string
+		wantProfile string
+	}{
+		{profile: "", wantBuild: "staging-jenkins", wantDeploy: "staging-jenkins", wantSmoke: "staging-jenkins"},
+		{profile: "prod", wantBuild: "prod-jenkins", wantDeploy: "prod-jenkins", wantSmoke: "staging-jenkins", wantProfile: "prod"},
+	}
+	for _, tt := range tests {
+		t.Run("profile="+tt.profile, func(t *testing.T) {
+			cfg, err := LoadWithProfile(td("alias_instances.yaml"), td("alias_workflow.yaml"), tt.profile)
+			if err != nil {
+				t.Fatalf("LoadWithProfile failed: %v", err)
+			}
+			if got := cfg.Workflow[0].Instance; got != tt.wantBuild {
+				t.Errorf("Build instance = %q, want %q", got, tt.wantBuild)
+			}
+			if got := cfg.Workflow[1].Parallel.Steps[0].Instance; got != tt.wantDeploy {
+				t.Errorf("Deploy instance = %q, want %q", got, tt.wantDeploy)
+			}
+			if got := cfg.Workflow[1].Parallel.Steps[1].Instance; got != tt.wantSmoke {
+				t.Errorf("Smoke instance = %q, want %q (real instance names are not remapped)", got, tt.wantSmoke)
+			}
+			if cfg.Profile != tt.wantProfile {
+				t.Errorf("Profile = %q, want %q", cfg.Profile, tt.wantProfile)
+			}
+		})
+	}
+}
+
+func TestLoadWithProfile_WorkflowOverlay(t *testing.T) {
+	load := func(profile string) *Config {
+		t.Helper()
+		cfg, err := LoadWithProfile(td("alias_instances.yaml"), td("profile_workflow.yaml"), profile)
+		if err != nil {
+			t.Fatalf("LoadWithProfile(%q) failed: %v", profile, err)
+		}
+		return cfg
+	}
+
+	base := load("")
+	if base.Vars["env"] != "staging" || base.Workflow[1].Parallel.Steps[0].Params["CANARY"] != "false" {
+		t.Errorf("expected base values without a profile, got vars %v and params %v", base.Vars, base.Workflow[1].Parallel.Steps[0].Params)
+	}
+
+	prod := load("prod")
+	if prod.Vars["env"] != "production" || prod.Vars["replicas"] != "1" {
+		t.Errorf("expected profile vars merged over the workflow's, got %v", prod.Vars)
+	}
+	deploy := prod.Workflow[1].Parallel.Steps[0]
+	if deploy.Params["CANARY"] != "true" || deploy.Params["REPLICAS"] != "${vars.replicas}" {
+		t.Errorf("expected profile step params merged over the step's, got %v", deploy.Params)
+	}
+	if deploy.Instance != "prod-jenkins" {
+		t.Errorf("expected the instances file's prod alias to apply too, got %q", deploy.Instance)
+	}
+	if prod.SlackWebhook != "https://hooks.slack.com/services/T/B/prod" {
+		t.Errorf("expected profile slack_webhook, got %q", prod.SlackWebhook)
+	}
+	if inst := prod.Instances["prod-jenkins"]; inst.BuildTimeoutSecs != 3600 || inst.URL != "http://prod.example.com" {
+		t.Errorf("expected profile instance override merged over the instances file, got %+v", inst)
+	}
+
+	// A profile defined only in the workflow file keeps the default aliases.
+	eu := load("eu")
+	if got := eu.Vars["replicas"]; got != "3" {
+		t.Errorf("expected eu replicas, got %q", got)
+	}
+	if eu.Workflow[0].Instance != "staging-jenkins" || eu.Profile != "eu" {
+		t.Errorf("expected default aliases and profile eu, got instance %q, profile %q", eu.Workflow[0].Instance, eu.Profile)
+	}
+}
+
+func TestLoadWithProfile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow string
+		profile  string
+		wantErr  string
+	}{
+		{"unknown profile", "alias_workflow.yaml", "qa", `unknown instance profile "qa"`},
+		{"alias to missing instance", "alias_workflow.yaml", "broken", `instance alias "ci" (profile "broken") points to unknown instance "missing-jenkins"`},
+		{"unresolvable alias", "alias_unknown_workflow.yaml", "prod", `unknown instance or alias "cd" (profile "prod")`},
+		{"unknown profile step", "profile_unknown_step_workflow.yaml", "", td("profile_unknown_step_workflow.yaml") + `:9: profiles.prod.steps: no Jenkins step named "Biuld"`},
+		{"ambiguous profile step", "profile_ambiguous_step_workflow.yaml", "", `profiles.prod.steps: 2 Jenkins steps are named "Build"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadWithProfile(td("alias_instances.yaml"), td(tt.workflow), tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Schema(t *testing.T) {
+	cfg, err := Load(td("default_params_instances.yaml"), td("schema_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Errorf("expected no warning for a file with a schema, got %q", cfg.Warnings)
+	}
+	meta, err := ParseWorkflowMeta(td("schema_workflow.yaml"), false)
+	if err != nil || meta.Schema != 2 {
+		t.Errorf("expected schema 2 from ParseWorkflowMeta, got %d, %v", meta.Schema, err)
+	}
+
+	// A newer schema is rejected before its unknown keys are.
+	for _, lenient := range []bool{false, true} {
+		_, err := LoadWithOptions(td("default_params_instances.yaml"), td("schema_future_workflow.yaml"), LoadOptions{Lenient: lenient})
+		if err == nil || !strings.Contains(err.Error(), "uses schema 3, but this jenkins-flow supports up to schema 2; upgrade jenkins-flow") || !strings.Contains(err.Error(), "schema_future_workflow.yaml:1:") {
+			t.Errorf("lenient=%v: expected an upgrade error with its line, got %v", lenient, err)
+		}
+	}
+	if _, err := ParseWorkflowMeta(td("schema_future_workflow.yaml"), false); err == nil || !strings.Contains(err.Error(), "upgrade jenkins-flow") {
+		t.Errorf("expected ParseWorkflowMeta to reject a newer schema, got %v", err)
+	}
+
+	// Legacy files load, with a warning naming the file.
+	legacy := td("alias_workflow.yaml")
+	cfg, err = Load(td("alias_instances.yaml"), legacy)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.HasPrefix(cfg.Warnings[0], legacy+": workflow declares no schema version") {
+		t.Errorf("expected one legacy schema warning, got %q", cfg.Warnings)
+	}
+}
+
+func TestLoad_WithoutInstances(t *testing.T) {
+	// PR waits and GitHub statuses need no Jenkins instance.
+	cfg, err := Load(td("github_only_instances.yaml"), td("pr_only_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Instances) != 0 || cfg.GitHub == nil {
+		t.Errorf("expected no instances and the github config, got %v, %+v", cfg.Instances, cfg.GitHub)
+	}
+
+	missing := filepath.Join(t.TempDir(), "instances.yaml")
+	if _, err := Load(missing, td("pr_only_workflow.yaml")); err == nil || !strings.Contains(err.Error(), "github configuration is required") {
+		t.Errorf("expected a missing instances file to load as empty, got %v", err)
+	}
+	if cfg, err := Load(missing, td("instance_conflict_override_workflow.yaml")); err != nil || cfg.Instances["direct"].URL != "http://elsewhere.example.com" {
+		t.Errorf("expected a self-contained workflow to load without an instances file, got %v", err)
+	}
+
+	if _, err := Load(td("github_only_instances.yaml"), td("pr_workflow.yaml")); err == nil || !strings.Contains(err.Error(), "no instances defined") {
+		t.Errorf("expected Jenkins steps to need instances, got %v", err)
+	}
+	if _, err := Load(missing, td("pr_workflow.yaml")); err == nil || !strings.Contains(err.Error(), missing+": no instances defined: the file does not exist") {
+		t.Errorf("expected the missing instances file to be named, got %v", err)
+	}
+}
+
+func TestCountInstances(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "instances.yaml")
+	if err := os.WriteFile(path, []byte("instances:\n  dev:\n    url: http://dev\n  prod:\n    url: http://prod\naliases:\n  ci: dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if instances, aliases, err := CountInstances(path, LoadOptions{}); err != nil || instances != 2 || aliases != 1 {
+		t.Errorf("expected 2 instances and 1 alias, got %d, %d, %v", instances, aliases, err)
+	}
+	if instances, _, err := CountInstances(filepath.Join(dir, "missing.yaml"), LoadOptions{}); err != nil || instances != 0 {
+		t.Errorf("expected a missing file to count as empty, got %d, %v", instances, err)
+	}
+	if err := os.WriteFile(path, []byte("instances:\n  dev:\n    urll: http://dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CountInstances(path, LoadOptions{}); err == nil {
+		t.Error("expected an unknown key to be rejected")
+	}
+}
+
+func TestLoad_DisabledSteps(t *testing.T) {
+	cfg, err := Load(td("parallel_instances.yaml"), td("disabled_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("expected disabled steps not to need a known instance, got %v", err)
+	}
+	cases := []struct {
+		item, step int
+		want       bool
+	}{
+		{0, 0, false},
+		{1, 0, true},
+		{2, 0, false},
+		{2, 1, true},
+		{3, 0, true},
+		{4, 0, false},
+	}
+	for _, c := range cases {
+		if got := cfg.IsDisabled(c.item, c.step); got != c.want {
+			t.Errorf("IsDisabled(%d, %d) = %v, want %v", c.item, c.step, got, c.want)
+		}
+	}
+
+	if _, err := Load(td("parallel_instances.yaml"), td("disabled_invalid_workflow.yaml")); err == nil || !strings.Contains(err.Error(), "job") {
+		t.Errorf("expected disabled steps to be validated otherwise, got %v", err)
+	}
+	missing := filepath.Join(t.TempDir(), "instances.yaml")
+	if _, err := Load(missing, td("disabled_only_workflow.yaml")); err != nil {
+		t.Errorf("expected a workflow whose only Jenkins step is disabled to need no instances, got %v", err)
+	}
+}
+
+func TestBuildToken(t *testing.T) {
+	t.Setenv("JF_TEST_BUILD_TOKEN", "env-build-token")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build_token.yaml")
+	content := `schema: 2
+instances:
+  dev:
+    url: http://jenkins.example.com
+    token: user:token
+workflow:
+  - name: Build
+    instance: dev
+    job: /job/build
+    build_token: literal-build-token
+  - parallel:
+      steps:
+        - name: Deploy
+          instance: dev
+          job: /job/deploy
+          build_token: ${JF_TEST_BUILD_TOKEN}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(filepath.Join(dir, "none.yaml"), path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.Workflow[0].AsStep().BuildToken; got != "literal-build-token" {
+		t.Errorf("expected the inline step's token, got %q", got)
+	}
+	if got := cfg.Workflow[1].Parallel.Steps[0].BuildToken; got != "env-build-token" {
+		t.Errorf("expected the token to be expanded from the environment, got %q", got)
+	}
+	if values := cfg.SecretValues(); !slices.Contains(values, "literal-build-token") || !slices.Contains(values, "env-build-token") {
+		t.Errorf("expected build tokens to be secret values, got %v", values)
+	}
+	snapshot, err := cfg.MaskSnapshot([]byte(content))
+	if err != nil {
+		t.Fatalf("MaskSnapshot failed: %v", err)
+	}
+	if strings.Contains(string(snapshot), "literal-build-token") {
+		t.Errorf("expected the token to be masked in the snapshot, got:\n%s", snapshot)
+	}
+}
+
+func TestSecretParams(t *testing.T) {
+	cfg, err := Load(td("default_params_instances.yaml"), td("secret_params_workflow.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	deploy := cfg.Workflow[0].AsStep()
+	masked := deploy.MaskParams(map[string]string{"DEPLOY_KEY": "real", "LITERAL": "real", "REGION": "eu-west-1"})
+	if masked["DEPLOY_KEY"] != SecretMask || masked["LITERAL"] != SecretMask || masked["REGION"] != "eu-west-1" {
+		t.Errorf("expected only secret params to be masked, got %v", masked)
+	}
+
+	// deploy_key reaches DEPLOY_KEY directly and AUTH through vars.auth.
+	if secret := cfg.SecretInputs(); !reflect.DeepEqual(secret, map[string]bool{"deploy_key": true}) {
+		t.Errorf("expected deploy_key to be the only secret input, got %v", secret)
+	}
+	inputs := cfg.MaskInputs(cfg.Inputs)
+	if inputs["deploy_key"] != SecretMask || inputs["region"] != "eu-west-1" || cfg.Inputs["deploy_key"] != "input-secret-key" {
+		t.Errorf("expected a masked copy of the inputs, got %v (original %v)", inputs, cfg.Inputs)
+	}
+	want := []string{"input-secret-key", "literal-secret-value", "key=input-secret-key"}
+	if values := cfg.SecretValues(); !reflect.DeepEqual(values, want) {
+		t.Errorf("expected secret values %v, got %v", want, values)
+	}
+
+	data, err := os.ReadFile(td("secret_params_workflow.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Inputs["deploy_key"] = "typed-for-this-run"
+	snapshot, err := cfg.MaskSnapshot(data)
+	if err != nil {
+		t.Fatalf("MaskSnapshot failed: %v", err)
+	}
+	for _, secret := range []string{"input-secret-key", "literal-secret-value"} {
+		if strings.Contains(string(snapshot), secret) {
+			t.Errorf("expected %q to be masked in the snapshot, got:\n%s", secret, snapshot)
+		}
+	}
+	parsed, err := ParseWorkflowSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("masked snapshot does not parse: %v", err)
+	}
+	if got := parsed.Workflow[0].Params["LITERAL"]; got != SecretMask {
+		t.Errorf("expected LITERAL to read back as %q, got %q", SecretMask, got)
+	}
+	if plain, err := (&Config{}).MaskSnapshot(data); err != nil || string(plain) != string(data) {
+		t.Errorf("expected a workflow without secrets to be stored as is, got %v", err)
+	}
+
+	_, err = Load(td("default_params_instances.yaml"), td("secret_params_unknown_workflow.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `step 0 ("Deploy"): secret_params names "DEPLOY_KEYS", which is not a param of the step`) {
+		t.Errorf("expected an unknown secret param to be rejected, got %v", err)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	opts := LoadOptions{DefaultsPath: td("defaults.yaml")}
+	cfg, err := LoadWithOptions(td("pr_instances.yaml"), td("defaults_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DefaultsFile != td("defaults.yaml") {
+		t.Errorf("expected the defaults file to be recorded, got %q", cfg.DefaultsFile)
+	}
+
+	// The instances file wins field by field; the defaults fill the rest.
+	local := cfg.Instances["local"]
+	if local.URL != "http://localhost:8080" || local.Token != "user:token" || local.BuildTimeoutSecs != 900 {
+		t.Errorf("expected the instances file's local over the defaults', got %+v", local)
+	}
+	if cfg.Instances["shared"].URL != "http://shared.example.com" {
+		t.Errorf("expected the defaults-only instance to be added, got %+v", cfg.Instances)
+	}
+	if cfg.GitHub == nil || cfg.GitHub.Token != "gh-token" || cfg.GitHub.AuthEnv != "" {
+		t.Errorf("expected the instances file's github block to replace the defaults', got %+v", cfg.GitHub)
+	}
+
+	// The workflow file wins over the defaults.
+	if cfg.SlackWebhook != "https://hooks.slack.com/services/T000/B000/DEFAULTS" || cfg.SlackChannel != "workflow-channel" || cfg.SlackUsername != "Defaults Bot" {
+		t.Errorf("unexpected slack settings: %q, %q, %q", cfg.SlackWebhook, cfg.SlackChannel, cfg.SlackUsername)
+	}
+	if len(cfg.SlackWebhookHosts) != 1 || cfg.SlackWebhookHosts[0] != "chat.example.com" {
+		t.Errorf("expected the defaults' webhook hosts, got %v", cfg.SlackWebhookHosts)
+	}
+	release, docs := cfg.Workflow[0].WaitForPR, cfg.Workflow[1].WaitForPR
+	if release.PollSecs != 15 || release.PollMaxSecs != 0 || docs.PollSecs != 20 || docs.PollMaxSecs != 120 {
+		t.Errorf("expected default poll intervals under the items' own, and a cap only with backoff, got %d/%d and %d/%d", release.PollSecs, release.PollMaxSecs, docs.PollSecs, docs.PollMaxSecs)
+	}
+
+	// Without an instances file, the defaults provide the github block too.
+	missing := filepath.Join(t.TempDir(), "instances.yaml")
+	cfg, err = LoadWithOptions(missing, td("defaults_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("Load without an instances file failed: %v", err)
+	}
+	if cfg.GitHub == nil || cfg.GitHub.AuthEnv != "DEFAULTS_GITHUB_TOKEN" || cfg.Instances["local"].URL != "http://defaults.example.com" {
+		t.Errorf("expected the defaults' github block and instances, got %+v, %+v", cfg.GitHub, cfg.Instances)
+	}
+
+	// The cached defaults are not shared with the configs built from them.
+	cache := NewCache(0)
+	opts.Cache = cache
+	first, err := LoadWithOptions(missing, td("defaults_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("cached Load failed: %v", err)
+	}
+	first.GitHub.AuthEnv = "CHANGED"
+	if again, _ := LoadWithOptions(missing, td("defaults_workflow.yaml"), opts); again.GitHub.AuthEnv != "DEFAULTS_GITHUB_TOKEN" {
+		t.Errorf("expected callers not to share the cached github block, got %+v", again.GitHub)
+	}
+
+	// Without a defaults file nothing is merged.
+	cfg, err = LoadWithOptions(td("pr_instances.yaml"), td("defaults_workflow.yaml"), LoadOptions{DefaultsPath: filepath.Join(t.TempDir(), "defaults.yaml")})
+	if err == nil {
+		t.Errorf("expected the shared instance to be unknown without defaults, got %+v", cfg.Instances)
+	}
+}
+
+func TestLoad_PollDefaults(t *testing.T) {
+	dir := t.TempDir()
+	workflowPath := filepath.Join(dir, "workflow.yaml")
+	content := `workflow:
+  - wait_for_pr: {name: "Plain", owner: "treaz", repo: "monitor", pr_number: 1, wait_for: "merged"}
+  - wait_for_pr: {name: "Own", owner: "treaz", repo: "monitor", pr_number: 2, wait_for: "merged", poll_secs: 10}
+  - wait_for_pr: {name: "Capped", owner: "treaz", repo: "monitor", pr_number: 3, wait_for: "merged", poll_backoff: true, poll_max_secs: 45}
+`
+	if err := os.WriteFile(workflowPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := LoadOptions{DefaultsPath: filepath.Join(dir, "defaults.yaml"), PollDefaults: PollDefaults{JenkinsSecs: 3, PRSecs: 60}}
+	cfg, err := LoadWithOptions(td("pr_instances.yaml"), workflowPath, opts)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.JenkinsPollSecs != 3 {
+		t.Errorf("expected the Jenkins poll interval to be carried, got %d", cfg.JenkinsPollSecs)
+	}
+	var got []int
+	for _, item := range cfg.Workflow {
+		got = append(got, item.WaitForPR.PollSecs)
+	}
+	if !slices.Equal(got, []int{60, 10, 0}) {
+		t.Errorf("expected the default only where poll_secs is unset and fits under poll_max_secs, got %v", got)
+	}
+
+	if err := (PollDefaults{PRSecs: -1}).Validate(); err == nil {
+		t.Error("expected a negative interval to be rejected")
+	}
+}
+
+func TestLoad_MalformedDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	if err := os.WriteFile(path, []byte("slack_webhok: https://example.com/hook\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := LoadOptions{DefaultsPath: path}
+	cfg, err := LoadWithOptions(td("pr_instances.yaml"), td("pr_workflow.yaml"), opts)
+	if err != nil {
+		t.Fatalf("expected a malformed defaults file not to break loading, got %v", err)
+	}
+	if cfg.DefaultsFile != "" || cfg.SlackWebhook != "" {
+		t.Errorf("expected the malformed defaults file to be ignored, got %q, %q", cfg.DefaultsFile, cfg.SlackWebhook)
+	}
+	err = CheckDefaults(path)
+	if err == nil || !strings.Contains(err.Error(), `did you mean "slack_webhook"?`) {
+		t.Fatalf("expected CheckDefaults to report the misspelled key, got %v", err)
+	}
+	if !slices.Contains(cfg.Warnings, DefaultsWarning(err)) {
+		t.Errorf("expected a warning naming the misspelled key, got %q", cfg.Warnings)
+	}
+
+	if err := os.WriteFile(path, []byte("poll_secs: -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDefaults(path); err == nil || !strings.Contains(err.Error(), path+":1: poll_secs must not be negative") {
+		t.Errorf("expected the negative interval to be located, got %v", err)
+	}
+	if err := CheckDefaults(filepath.Join(t.TempDir(), "none.yaml")); err != nil {
+		t.Errorf("expected a missing defaults file to be fine, got %v", err)
+	}
+}
+
+func TestLoadInstance(t *testing.T) {
+	t.Setenv("JF_TEST_PROD_URL", "http://prod.example.com")
+	tests := []struct {
+		name, instances, instance, profile string
+		wantURL, wantErr                   string
+	}{
+		{name: "by name", instances: "alias_instances.yaml", instance: "prod-jenkins", wantURL: "http://prod.example.com"},
+		{name: "alias", instances: "alias_instances.yaml", instance: "ci", wantURL: "http://staging.example.com"},
+		{name: "profile alias", instances: "alias_instances.yaml", instance: "ci", profile: "prod", wantURL: "http://prod.example.com"},
+		{name: "env url", instances: "env_instances.yaml", instance: "prod", wantURL: "http://prod.example.com"},
+		{name: "unknown instance", instances: "alias_instances.yaml", instance: "qa", wantErr: `unknown instance or alias "qa"`},
+		{name: "unknown profile", instances: "alias_instances.yaml", instance: "ci", profile: "dev", wantErr: `unknown instance profile "dev"`},
+		{name: "missing auth", instances: "missing_auth_instances.yaml", instance: "bad", wantErr: "must have one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst, err := LoadInstance(td(tt.instances), tt.instance, LoadOptions{Profile: tt.profile})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadInstance failed: %v", err)
+			}
+			if inst.URL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, inst.URL)
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_WorkflowData(t *testing.T) {
+	data := []byte("workflow:\n  - name: Build\n    instance: ci\n    job: /job/build\n")
+	path := filepath.Join(t.TempDir(), "gone.yaml")
+	cfg, err := LoadWithOptions(td("alias_instances.yaml"), path, LoadOptions{Profile: "prod", WorkflowData: data})
+	if err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if len(cfg.Workflow) != 1 || cfg.Workflow[0].Instance != "prod-jenkins" {
+		t.Errorf("expected the step to resolve to prod-jenkins, got %+v", cfg.Workflow)
+	}
+
+	data = []byte("workflow:\n  - name: Build\n    instance: retired\n    job: /job/build\n")
+	if _, err := LoadWithOptions(td("alias_instances.yaml"), path, LoadOptions{WorkflowData: data}); err == nil || !strings.Contains(err.Error(), "retired") {
+		t.Errorf("expected an unknown instance error, got %v", err)
+	}
 }
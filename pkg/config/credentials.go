@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CredentialStore looks up secrets by key name in an OS credential store.
+// Lookup errors should wrap ErrCredentialNotFound or ErrCredentialStoreLocked
+// when they mean that, so callers can tell the two apart.
+type CredentialStore interface {
+	// Name describes the store in error messages, e.g. "macOS Keychain".
+	Name() string
+	Lookup(key string) (string, error)
+}
+
+var (
+	// ErrCredentialNotFound means the store has no entry for the key.
+	ErrCredentialNotFound = errors.New("key not found")
+	// ErrCredentialStoreLocked means the store exists but cannot be read until
+	// it is unlocked, e.g. a locked keychain in an SSH session.
+	ErrCredentialStoreLocked = errors.New("credential store is locked")
+)
+
+// secretSources are where the instances and github config of a load look up
+// their tokens, and the config its environment references, taken from its
+// LoadOptions. A nil *secretSources, as in values not made by a load, uses
+// the macOS Keychain and the process environment.
+type secretSources struct {
+	credentials CredentialStore // LoadOptions.Credentials
+	env         EnvLookup       // LoadOptions.Env
+}
+
+// credentialStore returns the store auth_keychain keys are looked up in.
+func (s *secretSources) credentialStore() CredentialStore {
+	if s == nil || s.credentials == nil {
+		return MacKeychain{}
+	}
+	return s.credentials
+}
+
+// lookupKeychain resolves an auth_keychain key in the credential store.
+func (s *secretSources) lookupKeychain(key string) (string, error) {
+	store := s.credentialStore()
+	token, err := store.Lookup(key)
+	if err != nil {
+		return "", fmt.Errorf("auth_keychain %q (%s): %w", key, store.Name(), err)
+	}
+	if token == "" {
+		return "", fmt.Errorf("auth_keychain %q (%s): stored token is empty", key, store.Name())
+	}
+	return token, nil
+}
+
+// MacKeychain reads generic passwords from the macOS Keychain with the
+// security CLI. The key is the item's service name.
+type MacKeychain struct{}
+
+// Exit statuses of `security find-generic-password`.
+const (
+	securityItemNotFound          = 44 // errSecItemNotFound
+	securityInteractionNotAllowed = 36 // errSecInteractionNotAllowed: keychain locked
+)
+
+func (MacKeychain) Name() string { return "macOS Keychain" }
+
+func (MacKeychain) Lookup(key string) (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("the macOS Keychain is not available on %s", runtime.GOOS)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), authCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "security", "find-generic-password", "-s", key, "-w")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("security timed out after %s", authCommandTimeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", keychainError(exitErr.ExitCode(), stderr.String())
+		}
+		return "", fmt.Errorf("running security: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keychainError classifies a failed `security find-generic-password` run.
+func keychainError(exitCode int, stderr string) error {
+	msg := strings.TrimSpace(stderr)
+	lower := strings.ToLower(msg)
+	switch {
+	case exitCode == securityItemNotFound || strings.Contains(lower, "could not be found"):
+		return ErrCredentialNotFound
+	case exitCode == securityInteractionNotAllowed || strings.Contains(lower, "interaction is not allowed") || strings.Contains(lower, "locked"):
+		return fmt.Errorf("%w; unlock it with `security unlock-keychain`", ErrCredentialStoreLocked)
+	case msg != "":
+		return fmt.Errorf("security exited with status %d: %s", exitCode, msg)
+	default:
+		return fmt.Errorf("security exited with status %d", exitCode)
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// DefaultsPath is the user-level defaults file Load merges beneath the
+// instances and workflow files, or "" for none. It is
+// ~/.config/jenkins-flow/defaults.yaml unless the home directory is unknown;
+// LoadOptions.DefaultsPath overrides it for a single load.
+var DefaultsPath = defaultDefaultsPath()
+
+func defaultDefaultsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "jenkins-flow", "defaults.yaml")
+}
+
+// defaultsFile is the layout of a defaults file: settings shared by every
+// workflow, which the instances file and then the workflow file override.
+type defaultsFile struct {
+	Instances         map[string]Instance `yaml:"instances,omitempty"` // Merged beneath the instances file's; see mergeInstances
+	GitHub            *GitHubConfig       `yaml:"github,omitempty"`    // Used when the instances file has no github block
+	SlackWebhook      string              `yaml:"slack_webhook,omitempty"`
+	SlackChannel      string              `yaml:"slack_channel,omitempty"`
+	SlackUsername     string              `yaml:"slack_username,omitempty"`
+	SlackWebhookHosts []string            `yaml:"slack_webhook_hosts,omitempty"` // Added to the workflow's slack_webhook_hosts
+	PollSecs          int                 `yaml:"poll_secs,omitempty"`           // Default poll_secs of wait_for_pr items
+	PollMaxSecs       int                 `yaml:"poll_max_secs,omitempty"`       // Default poll_max_secs of wait_for_pr items with poll_backoff
+}
+
+// PollDefaults are poll intervals set by the operator for every workflow, in
+// seconds. Zero keeps the built-in interval.
+type PollDefaults struct {
+	JenkinsSecs int // Between Jenkins queue and build checks (built in: 2s for queue items, 5s for builds)
+	PRSecs      int // poll_secs of wait_for_pr items (built in: 30)
+}
+
+// Validate checks that no interval is negative.
+func (p PollDefaults) Validate() error {
+	if p.JenkinsSecs < 0 || p.PRSecs < 0 {
+		return fmt.Errorf("poll intervals must not be negative")
+	}
+	return nil
+}
+
+// loadedDefaults is a decoded defaults file.
+type loadedDefaults struct {
+	file defaultsFile
+	src  *sourceFile
+}
+
+// readDefaults reads and checks a defaults file. It returns nil without an
+// error when the file does not exist.
+func readDefaults(path string, lenient bool) (*loadedDefaults, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read defaults file (%s): %w", path, err)
+	}
+
+	loaded := &loadedDefaults{src: newSourceFile(path, data)}
+	if err := decodeYAML(loaded.src, data, &loaded.file, lenient); err != nil {
+		return nil, fmt.Errorf("failed to parse defaults file: %w", err)
+	}
+	d := &loaded.file
+	if gh := d.GitHub; gh != nil {
+		if gh.RequestTimeoutSecs < 0 {
+			return nil, loaded.src.wrap(fmt.Errorf("github: request_timeout_secs must not be negative"), "github", "request_timeout_secs")
+		}
+		if gh.App != nil {
+			if err := gh.validateApp(); err != nil {
+				return nil, loaded.src.wrap(err, "github", "app")
+			}
+			if !filepath.IsAbs(gh.App.PrivateKeyPath) {
+				gh.App.PrivateKeyPath = filepath.Join(filepath.Dir(path), gh.App.PrivateKeyPath)
+			}
+		}
+	}
+	if i, err := checkWebhookHosts(d.SlackWebhookHosts); err != nil {
+		return nil, loaded.src.wrap(err, "slack_webhook_hosts", i)
+	}
+	if d.PollSecs < 0 {
+		return nil, loaded.src.wrap(fmt.Errorf("poll_secs must not be negative"), "poll_secs")
+	}
+	if d.PollMaxSecs < 0 {
+		return nil, loaded.src.wrap(fmt.Errorf("poll_max_secs must not be negative"), "poll_max_secs")
+	}
+	return loaded, nil
+}
+
+// CheckDefaults reports why the defaults file at path cannot be used, or nil
+// when it is valid or does not exist. Loads ignore such a file and add the
+// error to Config.Warnings, so callers check it at startup to report it early.
+func CheckDefaults(path string) error {
+	if path == "" {
+		return nil
+	}
+	_, err := readDefaults(path, false)
+	return err
+}
+
+// DefaultsWarning is the Config.Warnings entry for a defaults file ignored
+// because of err.
+func DefaultsWarning(err error) string {
+	return fmt.Sprintf("ignoring defaults file: %v", err)
+}
+
+// applyDefaults fills the settings the workflow and instances files left
+// unset from d. Instances are merged separately, before validation needs them.
+func (c *Config) applyDefaults(d *defaultsFile) {
+	if c.GitHub == nil {
+		c.GitHub = d.GitHub
+	}
+	if c.SlackWebhook == "" {
+		c.SlackWebhook = d.SlackWebhook
+	}
+	if c.SlackChannel == "" {
+		c.SlackChannel = d.SlackChannel
+	}
+	if c.SlackUsername == "" {
+		c.SlackUsername = d.SlackUsername
+	}
+	for _, host := range d.SlackWebhookHosts {
+		if !slices.Contains(c.SlackWebhookHosts, host) {
+			c.SlackWebhookHosts = append(c.SlackWebhookHosts, host)
+		}
+	}
+	for _, items := range [][]WorkflowItem{c.Workflow, c.Finally} {
+		for _, item := range items {
+			if !item.IsPRWait() {
+				continue
+			}
+			pr := item.WaitForPR
+			if pr.PollSecs == 0 {
+				pr.PollSecs = d.PollSecs
+			}
+			// A default cap below the item's own interval would make the item
+			// invalid, so it is left to the built-in default instead.
+			if pr.PollBackoff && pr.PollMaxSecs == 0 && d.PollMaxSecs >= cmp.Or(pr.PollSecs, 30) {
+				pr.PollMaxSecs = d.PollMaxSecs
+			}
+		}
+	}
+}
+
+// applyPollDefaults fills the poll intervals that the workflow and defaults
+// files left unset from p. An item whose own poll_max_secs is below p.PRSecs
+// keeps the built-in interval, since p.PRSecs would make it invalid.
+func (c *Config) applyPollDefaults(p PollDefaults) {
+	c.JenkinsPollSecs = p.JenkinsSecs
+	if p.PRSecs == 0 {
+		return
+	}
+	for _, items := range [][]WorkflowItem{c.Workflow, c.Finally} {
+		for _, item := range items {
+			if !item.IsPRWait() {
+				continue
+			}
+			if pr := item.WaitForPR; pr.PollSecs == 0 && (pr.PollMaxSecs == 0 || pr.PollMaxSecs >= p.PRSecs) {
+				pr.PollSecs = p.PRSecs
+			}
+		}
+	}
+}
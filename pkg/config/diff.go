@@ -0,0 +1,318 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change kinds reported by DiffWorkflows.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ItemChange is a workflow or finally item that differs between two versions
+// of a workflow file.
+type ItemChange struct {
+	Section string        // "workflow" or "finally"
+	Index   int           // Index in the newer file, or in the older one for removed items
+	Name    string        // ItemName; "" for an unnamed parallel group
+	Change  string        // ChangeAdded, ChangeRemoved or ChangeChanged
+	Fields  []string      // Keys of a changed item whose values differ, other than params
+	Params  []ParamChange // Param changes of a changed step, or of the steps of a changed parallel group
+}
+
+// ParamChange is a step param that was added, removed or given a new value.
+type ParamChange struct {
+	Step   string // Step of a parallel group the param belongs to; "" for the item's own params
+	Name   string
+	Change string // ChangeAdded, ChangeRemoved or ChangeChanged
+	Old    string
+	New    string
+}
+
+// DiffWorkflows compares the items of two versions of a workflow, as decoded
+// by ParseWorkflowSnapshot. Items are matched by section and name; items
+// sharing a name, and unnamed parallel groups, are matched in order. Items
+// that only moved are not reported. Changed and added items are listed in the
+// newer file's order, followed by the removed ones.
+func DiffWorkflows(old, new *Config) ([]ItemChange, error) {
+	changes, err := diffSection("workflow", old.Workflow, new.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	finally, err := diffSection("finally", old.Finally, new.Finally)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, finally...), nil
+}
+
+// itemKey identifies an item by name and occurrence of that name, so repeated
+// names are matched in order.
+type itemKey struct {
+	name string
+	nth  int
+}
+
+func itemKeys(items []WorkflowItem) []itemKey {
+	seen := map[string]int{}
+	keys := make([]itemKey, len(items))
+	for i := range items {
+		name := items[i].ItemName()
+		keys[i] = itemKey{name, seen[name]}
+		seen[name]++
+	}
+	return keys
+}
+
+func diffSection(section string, old, new []WorkflowItem) ([]ItemChange, error) {
+	oldIndex := map[itemKey]int{}
+	for i, key := range itemKeys(old) {
+		oldIndex[key] = i
+	}
+	matched := map[int]bool{}
+
+	var changes []ItemChange
+	for i, key := range itemKeys(new) {
+		j, ok := oldIndex[key]
+		if !ok {
+			changes = append(changes, ItemChange{Section: section, Index: i, Name: key.name, Change: ChangeAdded})
+			continue
+		}
+		matched[j] = true
+		fields, params, err := diffItem(old[j], new[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", section, i, err)
+		}
+		if len(fields) > 0 || len(params) > 0 {
+			changes = append(changes, ItemChange{Section: section, Index: i, Name: key.name, Change: ChangeChanged, Fields: fields, Params: params})
+		}
+	}
+	for j := range old {
+		if !matched[j] {
+			changes = append(changes, ItemChange{Section: section, Index: j, Name: old[j].ItemName(), Change: ChangeRemoved})
+		}
+	}
+	return changes, nil
+}
+
+// diffItem returns the keys whose values differ between two versions of an
+// item, and its param changes. The params of parallel steps are compared step
+// by step; the group's other differences are reported as the parallel key.
+func diffItem(old, new WorkflowItem) ([]string, []ParamChange, error) {
+	params := diffParams("", old.Params, new.Params)
+	old.Params, new.Params = nil, nil
+	if old.Parallel != nil && new.Parallel != nil {
+		oldSteps := make(map[string]Step, len(old.Parallel.Steps))
+		for _, step := range old.Parallel.Steps {
+			oldSteps[step.Name] = step
+		}
+		for _, step := range new.Parallel.Steps {
+			if prev, ok := oldSteps[step.Name]; ok {
+				params = append(params, diffParams(step.Name, prev.Params, step.Params)...)
+			}
+		}
+		old.Parallel, new.Parallel = withoutStepParams(old.Parallel), withoutStepParams(new.Parallel)
+	}
+
+	oldFields, err := yamlFields(old)
+	if err != nil {
+		return nil, nil, err
+	}
+	newFields, err := yamlFields(new)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields []string
+	for _, key := range sortedKeys(oldFields) {
+		if !reflect.DeepEqual(oldFields[key], newFields[key]) {
+			fields = append(fields, key)
+		}
+	}
+	for _, key := range sortedKeys(newFields) {
+		if _, ok := oldFields[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	return fields, params, nil
+}
+
+// withoutStepParams returns a copy of group with its steps' params cleared.
+func withoutStepParams(group *ParallelGroup) *ParallelGroup {
+	stripped := &ParallelGroup{Name: group.Name, Steps: make([]Step, len(group.Steps))}
+	for i, step := range group.Steps {
+		step.Params = nil
+		stripped.Steps[i] = step
+	}
+	return stripped
+}
+
+// yamlFields returns item's YAML keys and their decoded values.
+func yamlFields(item WorkflowItem) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode item: %w", err)
+	}
+	fields := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode item: %w", err)
+	}
+	return fields, nil
+}
+
+// diffParams returns the changes from old to new params, by name.
+func diffParams(step string, old, new map[string]string) []ParamChange {
+	var changes []ParamChange
+	for _, name := range sortedKeys(old) {
+		if v, ok := new[name]; !ok {
+			changes = append(changes, ParamChange{Step: step, Name: name, Change: ChangeRemoved, Old: old[name]})
+		} else if v != old[name] {
+			changes = append(changes, ParamChange{Step: step, Name: name, Change: ChangeChanged, Old: old[name], New: v})
+		}
+	}
+	for _, name := range sortedKeys(new) {
+		if _, ok := old[name]; !ok {
+			changes = append(changes, ParamChange{Step: step, Name: name, Change: ChangeAdded, New: new[name]})
+		}
+	}
+	return changes
+}
+
+// diffContext is the number of unchanged lines UnifiedDiff shows around each
+// change.
+const diffContext = 3
+
+// UnifiedDiff returns a unified diff from old to new, whose file headers name
+// oldName and newName, or "" when the two are equal. Lines are compared
+// exactly; it is meant for files of a few thousand lines at most.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	a, b := splitLines(string(old)), splitLines(string(new))
+	ops := diffLines(a, b)
+
+	// Line numbers in old and new before each op.
+	oldPos, newPos := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		oldPos[k+1], newPos[k+1] = oldPos[k], newPos[k]
+		if op.kind != '+' {
+			oldPos[k+1]++
+		}
+		if op.kind != '-' {
+			newPos[k+1]++
+		}
+	}
+
+	var out strings.Builder
+	for k := 0; k < len(ops); {
+		for k < len(ops) && ops[k].kind == ' ' {
+			k++
+		}
+		if k == len(ops) {
+			break
+		}
+		// Join changes separated by at most twice the context into one hunk.
+		last := k
+		for next := last + 1; next < len(ops); next++ {
+			if ops[next].kind == ' ' {
+				continue
+			}
+			if next-last-1 > 2*diffContext {
+				break
+			}
+			last = next
+		}
+		start, stop := max(k-diffContext, 0), min(last+diffContext+1, len(ops))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldPos[stop]-oldPos[start]), hunkRange(newPos[start], newPos[stop]-newPos[start]))
+		for _, op := range ops[start:stop] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+		}
+		k = stop
+	}
+	return out.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk header. start
+// is the 0-based line before the hunk.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns an edit script from a to b built from their longest
+// common subsequence, after trimming the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	var prefix, suffix []diffOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]diffOp{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := prefix
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return append(ops, suffix...)
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffOldWorkflow = `name: Deploy
+workflow:
+  - name: Build
+    instance: ci
+    job: /job/build
+    params:
+      BRANCH: main
+      DEBUG: "false"
+  - parallel:
+      steps:
+        - name: Unit
+          instance: ci
+          job: /job/unit
+          params:
+            SHARD: "1"
+        - name: Lint
+          instance: ci
+          job: /job/lint
+  - name: Smoke
+    instance: ci
+    job: /job/smoke
+finally:
+  - name: Cleanup
+    instance: ci
+    job: /job/cleanup
+`
+
+const diffNewWorkflow = `name: Deploy
+workflow:
+  - name: Build
+    instance: ci
+    job: /job/build
+    wait: started
+    params:
+      BRANCH: release
+      VERSION: "2"
+  - parallel:
+      steps:
+        - name: Unit
+          instance: ci
+          job: /job/unit
+          params:
+            SHARD: "2"
+        - name: Lint
+          instance: ci
+          job: /job/lint
+  - name: Deploy
+    instance: prod
+    job: /job/deploy
+finally:
+  - name: Cleanup
+    instance: ci
+    job: /job/cleanup
+`
+
+func TestDiffWorkflows(t *testing.T) {
+	old, err := ParseWorkflowSnapshot([]byte(diffOldWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := ParseWorkflowSnapshot([]byte(diffNewWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := DiffWorkflows(old, new)
+	if err != nil {
+		t.Fatalf("DiffWorkflows failed: %v", err)
+	}
+	want := []ItemChange{
+		{Section: "workflow", Index: 0, Name: "Build", Change: ChangeChanged, Fields: []string{"wait"}, Params: []ParamChange{
+			{Name: "BRANCH", Change: ChangeChanged, Old: "main", New: "release"},
+			{Name: "DEBUG", Change: ChangeRemoved, Old: "false"},
+			{Name: "VERSION", Change: ChangeAdded, New: "2"},
+		}},
+		{Section: "workflow", Index: 1, Change: ChangeChanged, Params: []ParamChange{
+			{Step: "Unit", Name: "SHARD", Change: ChangeChanged, Old: "1", New: "2"},
+		}},
+		{Section: "workflow", Index: 2, Name: "Deploy", Change: ChangeAdded},
+		{Section: "workflow", Index: 2, Name: "Smoke", Change: ChangeRemoved},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", changes, want)
+	}
+
+	if changes, err := DiffWorkflows(old, old); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes between identical workflows, got %+v, %v", changes, err)
+	}
+}
+
+func TestDiffWorkflows_ParallelSteps(t *testing.T) {
+	old, err := ParseWorkflowSnapshot([]byte(diffOldWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := ParseWorkflowSnapshot([]byte(strings.Replace(diffOldWorkflow, "job: /job/lint", "job: /job/lint-strict", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := DiffWorkflows(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Index != 1 || !reflect.DeepEqual(changes[0].Fields, []string{"parallel"}) || len(changes[0].Params) != 0 {
+		t.Errorf("expected the parallel group to be reported as changed, got %+v", changes)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	want := `--- old.yaml
++++ new.yaml
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := UnifiedDiff("old.yaml", "new.yaml", []byte(old), []byte(new)); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Changes close together share a hunk.
+	got := UnifiedDiff("old.yaml", "new.yaml", []byte("a\nb\nc\nd\n"), []byte("a\nc\nd\ne\n"))
+	want = "--- old.yaml\n+++ new.yaml\n@@ -1,4 +1,4 @@\n a\n-b\n c\n d\n+e\n"
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("old.yaml", "new.yaml", []byte(old), []byte(old)); got != "" {
+		t.Errorf("expected no diff for identical files, got:\n%s", got)
+	}
+	if got := UnifiedDiff("old.yaml", "new.yaml", nil, []byte("x\n")); got != "--- old.yaml\n+++ new.yaml\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("unexpected diff against an empty file:\n%s", got)
+	}
+}
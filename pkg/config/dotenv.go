@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvLookup resolves auth_env variables for Instance.GetToken and
+// GitHubConfig.GetToken, and environment references in config values.
+type EnvLookup interface {
+	LookupEnv(key string) (string, bool)
+}
+
+// OSEnv looks variables up in the process environment.
+type OSEnv struct{}
+
+func (OSEnv) LookupEnv(key string) (string, bool) { return os.LookupEnv(key) }
+
+// EnvOverlay adds the values of a .env file to another lookup. Variables set
+// in Base win over the file, so an exported variable is never clobbered.
+type EnvOverlay struct {
+	Base   EnvLookup
+	Values map[string]string
+}
+
+func (o EnvOverlay) LookupEnv(key string) (string, bool) {
+	if v, ok := o.Base.LookupEnv(key); ok && v != "" {
+		return v, true
+	}
+	v, ok := o.Values[key]
+	return v, ok
+}
+
+// LoadEnvFile parses the .env file at path and returns it layered over base,
+// for LoadOptions.Env. A nil base is the process environment.
+func LoadEnvFile(path string, base EnvLookup) (EnvOverlay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return EnvOverlay{}, fmt.Errorf("opening env file: %w", err)
+	}
+	defer f.Close()
+
+	values, err := ParseDotEnv(f)
+	if err != nil {
+		return EnvOverlay{}, fmt.Errorf("env file %s: %w", path, err)
+	}
+	if base == nil {
+		base = OSEnv{}
+	}
+	return EnvOverlay{Base: base, Values: values}, nil
+}
+
+// ParseDotEnv reads KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped, and an optional "export " prefix is allowed. Values may be
+// wrapped in single quotes (taken literally) or double quotes (\n, \t, \" and
+// \\ are unescaped); an unquoted value ends at " #".
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value, err := dotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// dotEnvValue unquotes a .env value or strips its trailing comment.
+func dotEnvValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	switch q := v[0]; q {
+	case '\'', '"':
+		end := closingQuote(v, q)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %c quote", q)
+		}
+		if rest := strings.TrimSpace(v[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected text after closing quote")
+		}
+		if q == '\'' {
+			return v[1:end], nil
+		}
+		return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(v[1:end]), nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// closingQuote returns the index of the quote closing v[0], skipping
+// backslash-escaped double quotes, or -1.
+func closingQuote(v string, q byte) int {
+	for i := 1; i < len(v); i++ {
+		switch {
+		case q == '"' && v[i] == '\\':
+			i++
+		case v[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// isEnvName reports whether s is a valid environment variable name.
+func isEnvName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupEnv resolves an environment reference in a config value with
+// LoadOptions.Env, so variables from a .env file count too.
+func (s *secretSources) lookupEnv(name string) (string, bool) {
+	if s == nil || s.env == nil {
+		return os.LookupEnv(name)
+	}
+	return s.env.LookupEnv(name)
+}
+
+// lookupAuthEnv resolves an auth_env variable with LoadOptions.Env.
+func (s *secretSources) lookupAuthEnv(name string) (string, error) {
+	val, _ := s.lookupEnv(name)
+	if val == "" {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	return val, nil
+}
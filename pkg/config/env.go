@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvError lists config values referencing environment variables that are not
+// set and have no ${NAME:-default} fallback.
+type EnvError struct {
+	Missing []string // "<location>: <NAME>", in file order
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("%d environment variable reference(s) not set (use ${NAME:-default} for a fallback):\n  - %s", len(e.Missing), strings.Join(e.Missing, "\n  - "))
+}
+
+// expandEnv substitutes environment variables into the URLs of the named
+// instances, job paths, build tokens, step params and Slack webhook URLs at
+// load time. Instances the workflow does not use are left alone, so another
+// workflow's input in a shared instance's url is no error here. Bare ${NAME}
+// references that name a workflow input, and namespaced ones such as
+// ${vars.name} or ${steps.<id>.build_number}, are left for run-time
+// substitution. Every unset variable without a default is reported in one
+// *EnvError. References are read as Substitute reads them, and variables are
+// looked up in c.sources, so a .env file counts too.
+func (c *Config) expandEnv(instances []string) error {
+	var missing []string
+	expand := func(value *string, location string) {
+		if !strings.Contains(*value, "$") {
+			return
+		}
+		*value = substituteFunc(*value, func(key string) string {
+			name, fallback, hasDefault := strings.Cut(key, ":-")
+			switch {
+			case strings.HasPrefix(name, envPrefix):
+				name = strings.TrimPrefix(name, envPrefix)
+			case strings.Contains(name, "."):
+				return "${" + key + "}"
+			case !isEnvName(name):
+				// Not a variable, e.g. the $5 in a param value
+				return "$" + key
+			default:
+				if _, ok := c.Inputs[name]; ok {
+					return "${" + key + "}"
+				}
+			}
+			if v, ok := c.sources.lookupEnv(name); ok {
+				return v
+			}
+			if hasDefault {
+				return fallback
+			}
+			missing = append(missing, fmt.Sprintf("%s: %s", location, name))
+			return "${" + key + "}"
+		})
+	}
+	expandStep := func(job, buildToken *string, params map[string]string, location string) {
+		expand(job, location+".job")
+		expand(buildToken, location+".build_token")
+		for _, k := range sortedKeys(params) {
+			v := params[k]
+			expand(&v, fmt.Sprintf("%s.params.%s", location, k))
+			params[k] = v
+		}
+	}
+
+	for _, name := range instances {
+		inst, ok := c.Instances[name]
+		if !ok {
+			continue
+		}
+		expand(&inst.URL, fmt.Sprintf("instances.%s.url", name))
+		c.Instances[name] = inst
+	}
+	expand(&c.SlackWebhook, "slack_webhook")
+	for _, s := range []struct {
+		name  string
+		items []WorkflowItem
+	}{{"workflow", c.Workflow}, {"finally", c.Finally}} {
+		for i := range s.items {
+			item := &s.items[i]
+			loc := fmt.Sprintf("%s[%d]", s.name, i)
+			switch {
+			case item.IsParallel():
+				for j := range item.Parallel.Steps {
+					step := &item.Parallel.Steps[j]
+					expandStep(&step.Job, &step.BuildToken, step.Params, fmt.Sprintf("%s.parallel.steps[%d]", loc, j))
+				}
+			case item.IsStep():
+				expandStep(&item.Job, &item.BuildToken, item.Params, loc)
+			}
+			if item.Notify != nil {
+				expand(&item.Notify.Webhook, loc+".notify.webhook")
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return &EnvError{Missing: missing}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// Node kinds of a workflow graph. Each is drawn with its own shape.
+const (
+	graphStep         = "step"
+	graphPRWait       = "pr_wait"
+	graphGitHubStatus = "github_status"
+	graphCommand      = "command"
+)
+
+// graphNode is one step, PR wait, GitHub status or command of a workflow.
+type graphNode struct {
+	id    string
+	kind  string
+	lines []string // Label lines: the name, then where it runs
+	group int      // Index into workflowGraph.groups, or -1
+}
+
+// graphEdge connects two nodes. Finally edges lead into the finally block,
+// which runs whatever the outcome.
+type graphEdge struct {
+	from, to string
+	finally  bool
+}
+
+// workflowGraph is the shape of a workflow, shared by the Mermaid and DOT
+// renderers.
+type workflowGraph struct {
+	nodes  []graphNode
+	groups []string // Parallel group titles
+	edges  []graphEdge
+}
+
+// buildGraph lays out the workflow and finally items. Sequential items are
+// chained; a parallel group fans out from everything before it and fans in to
+// the item after it.
+func buildGraph(c *Config) *workflowGraph {
+	g := &workflowGraph{}
+	var prev []string
+	chain := func(items []WorkflowItem, finally bool) {
+		first := true
+		for i := range items {
+			item := &items[i]
+			var ids []string
+			if item.IsParallel() {
+				if len(item.Parallel.Steps) == 0 {
+					continue
+				}
+				group := len(g.groups)
+				g.groups = append(g.groups, cmp.Or(item.Parallel.Name, "parallel"))
+				for _, step := range item.Parallel.Steps {
+					ids = append(ids, g.add(graphStep, group, step.Name, step.Instance))
+				}
+			} else {
+				ids = []string{g.add(itemKind(item), -1, itemLabel(item)...)}
+			}
+			for _, from := range prev {
+				for _, to := range ids {
+					g.edges = append(g.edges, graphEdge{from: from, to: to, finally: finally && first})
+				}
+			}
+			prev, first = ids, false
+		}
+	}
+	chain(c.Workflow, false)
+	chain(c.Finally, true)
+	return g
+}
+
+// add appends a node and returns its ID.
+func (g *workflowGraph) add(kind string, group int, lines ...string) string {
+	id := fmt.Sprintf("n%d", len(g.nodes))
+	g.nodes = append(g.nodes, graphNode{id: id, kind: kind, lines: lines, group: group})
+	return id
+}
+
+// itemKind returns the graph node kind of a non-parallel item.
+func itemKind(item *WorkflowItem) string {
+	switch {
+	case item.IsPRWait():
+		return graphPRWait
+	case item.IsGitHubStatus():
+		return graphGitHubStatus
+	case item.IsCommand():
+		return graphCommand
+	default:
+		return graphStep
+	}
+}
+
+// itemLabel returns the label lines of a non-parallel item.
+func itemLabel(item *WorkflowItem) []string {
+	switch {
+	case item.IsPRWait():
+		pr := item.WaitForPR
+		target := fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.PRNumber)
+		if pr.PRNumber == 0 && pr.PRNumberTemplate != "" {
+			target = fmt.Sprintf("%s/%s#%s", pr.Owner, pr.Repo, pr.PRNumberTemplate)
+		}
+		if pr.HeadBranch != "" {
+			target = fmt.Sprintf("%s/%s@%s", pr.Owner, pr.Repo, pr.HeadBranch)
+		}
+		return []string{pr.Name, fmt.Sprintf("wait for %s %s", target, pr.WaitFor)}
+	case item.IsGitHubStatus():
+		gs := item.GitHubStatus
+		return []string{gs.Name, gs.Owner + "/" + gs.Repo}
+	case item.IsCommand():
+		return []string{item.Command.Name, "local"}
+	default:
+		return []string{item.Name, item.Instance}
+	}
+}
+
+// ToMermaid renders the workflow as a Mermaid flowchart. Steps are boxes
+// labelled with their name and instance, PR waits are hexagons, GitHub
+// statuses are stadiums and commands are parallelograms. Parallel groups are
+// subgraphs, and the edge into the finally block is dotted.
+func ToMermaid(c *Config) string {
+	g := buildGraph(c)
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	node := func(n graphNode, indent string) {
+		label := mermaidText(strings.Join(n.lines, "\n"))
+		switch n.kind {
+		case graphPRWait:
+			fmt.Fprintf(&b, "%s%s{{\"%s\"}}\n", indent, n.id, label)
+		case graphGitHubStatus:
+			fmt.Fprintf(&b, "%s%s([\"%s\"])\n", indent, n.id, label)
+		case graphCommand:
+			fmt.Fprintf(&b, "%s%s[/\"%s\"/]\n", indent, n.id, label)
+		default:
+			fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, n.id, label)
+		}
+	}
+	for i, n := range g.nodes {
+		if n.group < 0 {
+			node(n, "    ")
+			continue
+		}
+		if i > 0 && g.nodes[i-1].group == n.group {
+			continue
+		}
+		fmt.Fprintf(&b, "    subgraph p%d [\"%s\"]\n", n.group, mermaidText(g.groups[n.group]))
+		for _, m := range g.nodes[i:] {
+			if m.group != n.group {
+				break
+			}
+			node(m, "        ")
+		}
+		b.WriteString("    end\n")
+	}
+	for _, e := range g.edges {
+		if e.finally {
+			fmt.Fprintf(&b, "    %s -.->|finally| %s\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", e.from, e.to)
+		}
+	}
+	return b.String()
+}
+
+// mermaidText escapes s for a quoted Mermaid label.
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s)
+}
+
+// ToDOT renders the workflow as a Graphviz digraph, with the same shapes and
+// grouping as ToMermaid.
+func ToDOT(c *Config) string {
+	g := buildGraph(c)
+	shapes := map[string]string{
+		graphStep:         "box",
+		graphPRWait:       "hexagon",
+		graphGitHubStatus: "ellipse",
+		graphCommand:      "parallelogram",
+	}
+	var b strings.Builder
+	b.WriteString("digraph workflow {\n")
+	if c.Name != "" {
+		fmt.Fprintf(&b, "    label=\"%s\";\n", dotText(c.Name))
+	}
+	b.WriteString("    rankdir=TB;\n")
+	node := func(n graphNode, indent string) {
+		fmt.Fprintf(&b, "%s%s [shape=%s, label=\"%s\"];\n", indent, n.id, shapes[n.kind], dotText(strings.Join(n.lines, "\n")))
+	}
+	for i, n := range g.nodes {
+		if n.group < 0 {
+			node(n, "    ")
+			continue
+		}
+		if i > 0 && g.nodes[i-1].group == n.group {
+			continue
+		}
+		fmt.Fprintf(&b, "    subgraph cluster_p%d {\n        label=\"%s\";\n", n.group, dotText(g.groups[n.group]))
+		for _, m := range g.nodes[i:] {
+			if m.group != n.group {
+				break
+			}
+			node(m, "        ")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, e := range g.edges {
+		if e.finally {
+			fmt.Fprintf(&b, "    %s -> %s [style=dashed, label=\"finally\"];\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s;\n", e.from, e.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotText escapes s for a quoted DOT string.
+func dotText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
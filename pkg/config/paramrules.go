@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParamRule constrains the value a step sends for one job parameter. Rules
+// are opt-in, under a step's `param_rules:`, and are checked against the
+// value after substitution; see Step.CheckParams.
+type ParamRule struct {
+	Pattern  string `yaml:"pattern,omitempty"`  // Go regular expression the value must match; anchor it with ^ and $
+	Required bool   `yaml:"required,omitempty"` // The param must be set to a non-blank value
+}
+
+// maxShownParamValue is how many characters of a rejected value a param_rules
+// error quotes.
+const maxShownParamValue = 40
+
+// CheckParams checks params, the step's params as they will be sent, against
+// its param_rules and returns the first violation, naming the step, the param
+// and the pattern. The rejected value is quoted and truncated, and left out
+// for secret_params. With pending set, values that still hold a ${...}
+// reference are skipped: they are only known once an upstream step or PR
+// wait has run.
+func (s Step) CheckParams(params map[string]string, pending bool) error {
+	for _, name := range sortedKeys(s.ParamRules) {
+		rule := s.ParamRules[name]
+		value, ok := params[name]
+		if pending && strings.Contains(value, "${") {
+			continue
+		}
+		if rule.Required && strings.TrimSpace(value) == "" {
+			if !ok {
+				return fmt.Errorf("step %q: param %s is required but not set", s.Name, name)
+			}
+			return fmt.Errorf("step %q: param %s is required but empty", s.Name, name)
+		}
+		if rule.Pattern == "" || !ok {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("step %q: param %s: invalid pattern: %v", s.Name, name, err)
+		}
+		if !re.MatchString(value) {
+			if slices.Contains(s.SecretParams, name) {
+				return fmt.Errorf("step %q: param %s: value does not match pattern %s", s.Name, name, rule.Pattern)
+			}
+			return fmt.Errorf("step %q: param %s: value %s does not match pattern %s", s.Name, name, quoteParamValue(value), rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// quoteParamValue quotes value for an error on one line, cut to
+// maxShownParamValue characters.
+func quoteParamValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxShownParamValue {
+		return strconv.Quote(value)
+	}
+	return strconv.Quote(string(runes[:maxShownParamValue])) + "..."
+}
+
+// validateParamRules checks that every param_rules pattern compiles, so a
+// typo is reported when the workflow is loaded rather than when it runs.
+func validateParamRules(step Step, location string) error {
+	for _, name := range sortedKeys(step.ParamRules) {
+		if pattern := step.ParamRules[name].Pattern; pattern != "" {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("%s (%q): param_rules %s: invalid pattern: %v", location, step.Name, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// WorkflowProfile overlays a workflow file for one environment. It is selected
+// by the same profile name as the instances file's alias profiles, so one
+// workflow can serve e.g. both staging and prod.
+type WorkflowProfile struct {
+	Vars         map[string]string       `yaml:"vars,omitempty"`          // Merged over the workflow's vars
+	Instances    map[string]Instance     `yaml:"instances,omitempty"`     // Merged over the workflow's instances; see mergeInstances
+	SlackWebhook string                  `yaml:"slack_webhook,omitempty"` // Replaces the workflow's slack_webhook
+	Steps        map[string]StepOverride `yaml:"steps,omitempty"`         // Keyed by step name
+}
+
+// StepOverride changes a step, found by name, for a workflow profile.
+type StepOverride struct {
+	Params map[string]string `yaml:"params,omitempty"` // Merged over the step's params
+}
+
+// validateProfiles checks that every step named in a profile's `steps:`
+// exists in the workflow, and is the only step with that name (see
+// allow_duplicate_names), for all profiles rather than just the selected one,
+// so a typo in a rarely used profile is caught early.
+func (wf *workflowFile) validateProfiles(src *sourceFile) error {
+	stepNames := map[string]int{}
+	for _, items := range [][]WorkflowItem{wf.Workflow, wf.Finally} {
+		for _, item := range items {
+			if item.IsParallel() {
+				for _, step := range item.Parallel.Steps {
+					stepNames[step.Name]++
+				}
+			} else if item.IsStep() {
+				stepNames[item.Name]++
+			}
+		}
+	}
+
+	for _, profile := range sortedKeys(wf.Profiles) {
+		for _, name := range sortedKeys(wf.Profiles[profile].Steps) {
+			switch n := stepNames[name]; {
+			case n == 0:
+				return src.wrap(fmt.Errorf("profiles.%s.steps: no Jenkins step named %q", profile, name), "profiles", profile, "steps", name)
+			case n > 1:
+				return src.wrap(fmt.Errorf("profiles.%s.steps: %d Jenkins steps are named %q; give them distinct names", profile, n, name), "profiles", profile, "steps", name)
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfile overlays the named profile, if the workflow defines it, onto
+// the workflow's vars, instances, Slack webhook and step params.
+func (wf *workflowFile) applyProfile(profile string) error {
+	p, ok := wf.Profiles[profile]
+	if !ok {
+		return nil
+	}
+
+	wf.Vars = overlayMap(wf.Vars, p.Vars)
+	if p.SlackWebhook != "" {
+		wf.SlackWebhook = p.SlackWebhook
+	}
+	// Overrides compose: the profile's fields win over the workflow's, which
+	// win over the instances file's when mergeInstances runs next.
+	instances, err := mergeInstances(wf.Instances, p.Instances, nil, nil)
+	if err != nil {
+		return err
+	}
+	wf.Instances = instances
+
+	override := func(name string, params *map[string]string) {
+		if o, ok := p.Steps[name]; ok {
+			*params = overlayMap(*params, o.Params)
+		}
+	}
+	for _, items := range [][]WorkflowItem{wf.Workflow, wf.Finally} {
+		for i := range items {
+			item := &items[i]
+			if item.IsParallel() {
+				for j := range item.Parallel.Steps {
+					step := &item.Parallel.Steps[j]
+					override(step.Name, &step.Params)
+				}
+			} else if item.IsStep() {
+				override(item.Name, &item.Params)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns m's keys in order, for deterministic error reporting.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
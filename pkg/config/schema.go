@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the newest workflow file schema this binary understands.
+// Files declare theirs with a top-level `schema:`; files without one are
+// legacy files and are read as schema 1.
+const SchemaVersion = 2
+
+// checkSchema reads the schema declared by a workflow file before the file
+// is decoded, so a file written for a newer jenkins-flow is rejected with an
+// upgrade hint instead of errors about keys this binary does not know. It
+// returns 0 for legacy files. Malformed YAML is left to the full decode.
+func checkSchema(src *sourceFile, data []byte) (int, error) {
+	var head struct {
+		Schema *int `yaml:"schema"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil || head.Schema == nil {
+		return 0, nil
+	}
+	switch schema := *head.Schema; {
+	case schema < 1:
+		return 0, src.wrap(fmt.Errorf("schema must be a positive version, got %d", schema), "schema")
+	case schema > SchemaVersion:
+		return 0, src.wrap(fmt.Errorf("workflow file uses schema %d, but this jenkins-flow supports up to schema %d; upgrade jenkins-flow to load it", schema, SchemaVersion), "schema")
+	default:
+		return schema, nil
+	}
+}
+
+// legacySchemaWarning is the warning for a workflow file that declares no
+// schema.
+var legacySchemaWarning = fmt.Sprintf("workflow declares no schema version; add \"schema: %d\" at the top so older jenkins-flow binaries refuse it instead of misreading it", SchemaVersion)
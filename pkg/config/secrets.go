@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretMask replaces the values of secret params, and of the inputs that
+// feed them, wherever a run is shown or stored. Jenkins still receives the
+// real values.
+const SecretMask = "***"
+
+// MaskParams returns a copy of params, the step's params as sent to Jenkins,
+// with the values of its secret_params replaced by SecretMask.
+func (s Step) MaskParams(params map[string]string) map[string]string {
+	if params == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(params))
+	for k, v := range params {
+		if slices.Contains(s.SecretParams, k) {
+			v = SecretMask
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// jenkinsSteps returns every Jenkins step of the workflow, inline or inside a
+// parallel group, including finally items.
+func (c *Config) jenkinsSteps() []Step {
+	var steps []Step
+	for _, item := range c.AllItems() {
+		if item.IsParallel() {
+			steps = append(steps, item.Parallel.Steps...)
+		} else if item.IsStep() {
+			steps = append(steps, item.AsStep())
+		}
+	}
+	return steps
+}
+
+// SecretInputs returns the inputs whose values reach a secret param, directly
+// or through workflow vars. Their values are masked like the params'.
+func (c *Config) SecretInputs() map[string]bool {
+	secret := map[string]bool{}
+	for _, step := range c.jenkinsSteps() {
+		for _, name := range step.SecretParams {
+			for _, input := range c.InputRefs(step.Params[name]) {
+				if _, ok := c.Inputs[input]; ok {
+					secret[input] = true
+				}
+			}
+		}
+	}
+	return secret
+}
+
+// MaskInputs returns a copy of inputs with the values of SecretInputs
+// replaced by SecretMask.
+func (c *Config) MaskInputs(inputs map[string]string) map[string]string {
+	secret := c.SecretInputs()
+	if len(secret) == 0 || inputs == nil {
+		return inputs
+	}
+	masked := make(map[string]string, len(inputs))
+	for k, v := range inputs {
+		if secret[k] {
+			v = SecretMask
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// SecretValues returns the current values of every secret param, with inputs
+// and vars substituted, of every build token, and of every secret input. Values that still depend
+// on an upstream step or PR are left out.
+func (c *Config) SecretValues() []string {
+	vars := c.TemplateVars(c.Inputs)
+	seen := map[string]bool{}
+	var values []string
+	add := func(v string) {
+		if v != "" && v != SecretMask && !strings.Contains(v, "${") && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	for _, step := range c.jenkinsSteps() {
+		for _, name := range step.SecretParams {
+			add(substituteIfTemplate(step.Params[name], vars))
+		}
+		add(step.BuildToken)
+	}
+	for _, name := range sortedKeys(c.SecretInputs()) {
+		add(c.Inputs[name])
+	}
+	return values
+}
+
+// MaskSnapshot returns data, a workflow file, for storing with a run: the
+// saved values of SecretInputs and every scalar equal to one of SecretValues
+// are replaced by SecretMask. The file is re-encoded only when something was
+// masked; otherwise data is returned unchanged.
+func (c *Config) MaskSnapshot(data []byte) ([]byte, error) {
+	values, inputs := c.SecretValues(), c.SecretInputs()
+	if len(values) == 0 && len(inputs) == 0 {
+		return data, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow for masking: %w", err)
+	}
+	masked := false
+	mask := func(n *yaml.Node) {
+		// A plain *** would read as an alias, so it is quoted.
+		n.Value, n.Style, n.Tag = SecretMask, yaml.DoubleQuotedStyle, "!!str"
+		masked = true
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && slices.Contains(values, n.Value) {
+			mask(n)
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(&doc)
+	if len(doc.Content) > 0 {
+		saved := mappingValue(doc.Content[0], "inputs")
+		for name := range inputs {
+			if n := mappingValue(saved, name); n != nil && n.Kind == yaml.ScalarNode {
+				mask(n)
+			}
+		}
+	}
+	if !masked {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode masked workflow: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value of key in the mapping node n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// validateSecretParams checks that every secret_params entry names one of the
+// step's params, after forwarded inputs and instance defaults are merged in.
+func validateSecretParams(step Step, location string) error {
+	for _, name := range step.SecretParams {
+		if _, ok := step.Params[name]; !ok {
+			return fmt.Errorf("%s (%q): secret_params names %q, which is not a param of the step", location, step.Name, name)
+		}
+	}
+	return nil
+}
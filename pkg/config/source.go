@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceError is a validation error located in a config file. Line is 0 when
+// the position is unknown.
+type SourceError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *SourceError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// sourceFile keeps a parsed config file's node tree so validation errors can
+// name the line they refer to.
+type sourceFile struct {
+	path string
+	root *yaml.Node // top-level mapping; nil if the file could not be parsed
+}
+
+// newSourceFile indexes data, the contents of path. Parse errors are left to
+// the decoder; the result then only knows the file name.
+func newSourceFile(path string, data []byte) *sourceFile {
+	src := &sourceFile{path: path}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err == nil && len(doc.Content) > 0 {
+		src.root = doc.Content[0]
+	}
+	return src
+}
+
+// line returns the line of the node reached by following keys from the root:
+// strings index mappings and ints index sequences. For a mapping key the
+// key's own line is used. If the path ends early, the line of the deepest node
+// found is returned, or 0 if none was.
+func (f *sourceFile) line(keys ...interface{}) int {
+	line, _ := f.find(keys...)
+	return line
+}
+
+// has reports whether the whole path of keys exists in the file.
+func (f *sourceFile) has(keys ...interface{}) bool {
+	_, found := f.find(keys...)
+	return found
+}
+
+// find follows keys as described for line, also reporting whether every key
+// was found.
+func (f *sourceFile) find(keys ...interface{}) (int, bool) {
+	if f == nil || f.root == nil {
+		return 0, false
+	}
+	node, line := f.root, 0
+	for _, key := range keys {
+		var next *yaml.Node
+		switch k := key.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line, false
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == k {
+					next = node.Content[i+1]
+					line = node.Content[i].Line
+					break
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && k >= 0 && k < len(node.Content) {
+				next = node.Content[k]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line, false
+		}
+		node = next
+	}
+	return line, true
+}
+
+// wrap locates err at the node reached by keys. Errors that already carry a
+// position, and errors from configs not loaded from a file, are returned as is.
+func (f *sourceFile) wrap(err error, keys ...interface{}) error {
+	if err == nil || f == nil {
+		return err
+	}
+	var located *SourceError
+	if errors.As(err, &located) {
+		return err
+	}
+	return &SourceError{File: f.path, Line: f.line(keys...), Err: err}
+}
+
+// decodeLinePattern matches the position yaml.v3 puts in front of decode errors.
+var decodeLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// unknownFieldPattern matches yaml.v3's message for a key with no field.
+var unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type config\.(\w+)$`)
+
+// locate turns the errors yaml.v3 reports while decoding the file, which only
+// carry a line number, into SourceErrors naming the file. Unknown keys are
+// reworded and, when a known key is spelt almost the same, the message says
+// which one was probably meant. Errors without a line are returned as is.
+func (f *sourceFile) locate(err error) error {
+	if err == nil || f == nil {
+		return err
+	}
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+	var located []error
+	for _, msg := range messages {
+		m := decodeLinePattern.FindStringSubmatch(msg)
+		if m == nil {
+			return err
+		}
+		line, _ := strconv.Atoi(m[1])
+		located = append(located, &SourceError{File: f.path, Line: line, Err: errors.New(describeDecodeError(m[2]))})
+	}
+	return errors.Join(located...)
+}
+
+// describeDecodeError rewords yaml.v3's unknown field message, suggesting the
+// closest known key of the same type. Other messages are kept.
+func describeDecodeError(msg string) string {
+	m := unknownFieldPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return msg
+	}
+	key := m[1]
+	if known := configKeys()[m[2]]; len(known) > 0 {
+		if guess := closestKey(key, known); guess != "" {
+			return fmt.Sprintf("unknown key %q, did you mean %q?", key, guess)
+		}
+	}
+	return fmt.Sprintf("unknown key %q", key)
+}
+
+var (
+	configKeysOnce   sync.Once
+	configKeysByType map[string][]string
+)
+
+// configKeys returns the yaml keys of every struct reachable from the config
+// file types, by type name, so unknown keys can be matched against the keys
+// valid at the same place.
+func configKeys() map[string][]string {
+	configKeysOnce.Do(func() {
+		configKeysByType = make(map[string][]string)
+		var walk func(t reflect.Type)
+		walk = func(t reflect.Type) {
+			for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct || t.PkgPath() != reflect.TypeOf(Config{}).PkgPath() {
+				return
+			}
+			if _, seen := configKeysByType[t.Name()]; seen {
+				return
+			}
+			configKeysByType[t.Name()] = sortedKeys(yamlFieldNames(t))
+			for i := 0; i < t.NumField(); i++ {
+				if field := t.Field(i); field.IsExported() {
+					walk(field.Type)
+				}
+			}
+		}
+		walk(reflect.TypeOf(instancesFile{}))
+		walk(reflect.TypeOf(workflowFile{}))
+		walk(reflect.TypeOf(defaultsFile{}))
+	})
+	return configKeysByType
+}
+
+// closestKey returns the known key within a small edit distance of key, or ""
+// if none is close enough to be a likely typo.
+func closestKey(key string, known []string) string {
+	best, bestDist := "", len(key)/3+1
+	if bestDist > 3 {
+		bestDist = 3
+	}
+	for _, k := range known {
+		if d := editDistance(key, k); d <= bestDist && (best == "" || d < editDistance(key, best)) {
+			best = k
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,71 @@
+package config
+
+import "fmt"
+
+// StepTemplate is a named partial step under a workflow's `templates:`. A
+// step that names it with `template:` takes every field it leaves unset from
+// the template; see applyTemplates.
+type StepTemplate struct {
+	Instance      string            `yaml:"instance,omitempty"`
+	Job           string            `yaml:"job,omitempty"`
+	Params        map[string]string `yaml:"params,omitempty"` // The step's own params are merged over these
+	Wait          string            `yaml:"wait,omitempty"`
+	IfRunning     string            `yaml:"if_running,omitempty"`
+	ForwardInputs *ForwardInputs    `yaml:"forward_inputs,omitempty"`
+}
+
+// applyTemplates merges each step's template under the step's own fields, for
+// inline steps and steps in parallel groups alike. It runs before the profile
+// overlay, so profile step overrides and everything after loading see the
+// merged steps. An unknown template name is an error at the step's position.
+func (wf *workflowFile) applyTemplates(src *sourceFile) error {
+	for _, s := range []struct {
+		name  string
+		items []WorkflowItem
+	}{{"workflow", wf.Workflow}, {"finally", wf.Finally}} {
+		for i := range s.items {
+			item := &s.items[i]
+			if item.IsParallel() {
+				for j := range item.Parallel.Steps {
+					step := &item.Parallel.Steps[j]
+					loc := fmt.Sprintf("%s[%d].parallel.steps[%d]", s.name, i, j)
+					if err := wf.applyTemplate(step.Template, step.Name, loc, &step.Instance, &step.Job, &step.Params, &step.Wait, &step.IfRunning, &step.ForwardInputs); err != nil {
+						return src.wrap(err, s.name, i, "parallel", "steps", j, "template")
+					}
+				}
+			} else if item.IsStep() {
+				loc := fmt.Sprintf("%s[%d]", s.name, i)
+				if err := wf.applyTemplate(item.Template, item.Name, loc, &item.Instance, &item.Job, &item.Params, &item.Wait, &item.IfRunning, &item.ForwardInputs); err != nil {
+					return src.wrap(err, s.name, i, "template")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyTemplate fills the unset fields of one step from the named template.
+func (wf *workflowFile) applyTemplate(name, stepName, location string, instance, job *string, params *map[string]string, wait, ifRunning *string, forward **ForwardInputs) error {
+	if name == "" {
+		return nil
+	}
+	t, ok := wf.Templates[name]
+	if !ok {
+		return fmt.Errorf("%s (%q): unknown template %q", location, stepName, name)
+	}
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(instance, t.Instance)
+	fill(job, t.Job)
+	fill(wait, t.Wait)
+	fill(ifRunning, t.IfRunning)
+	if *forward == nil {
+		*forward = t.ForwardInputs
+	}
+	// Copy so steps sharing a template never share a params map.
+	*params = overlayMap(overlayMap(nil, t.Params), *params)
+	return nil
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Issue is a problem Validate found. File and Line locate it when known; Line
+// is 0 when only the file is, and File is "" when neither is.
+type Issue struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// Validate loads a workflow like LoadWithOptions and reports what is wrong
+// with it as issues instead of a single error, for editors checking a file as
+// it is written. Errors are the reason the workflow does not load: validation
+// stops at the first problem, except that every unset environment variable is
+// reported. Warnings are a missing schema version, plus, with
+// opts.AllowUnknownInstances, the steps naming instances that are not defined.
+func Validate(instancesPath, workflowPath string, opts LoadOptions) (errs, warnings []Issue) {
+	if opts.WorkflowData == nil {
+		data, err := os.ReadFile(workflowPath)
+		if err != nil {
+			return []Issue{{File: workflowPath, Message: fmt.Sprintf("failed to read workflow config: %v", err)}}, nil
+		}
+		opts.WorkflowData = data
+	}
+
+	if src := newSourceFile(workflowPath, opts.WorkflowData); src.root != nil {
+		if schema, err := checkSchema(src, opts.WorkflowData); err == nil && schema == 0 {
+			warnings = append(warnings, Issue{File: workflowPath, Message: legacySchemaWarning})
+		}
+	}
+
+	cfg, err := LoadWithOptions(instancesPath, workflowPath, opts)
+	if err != nil {
+		return issuesOf(err), warnings
+	}
+	for _, err := range cfg.unknownInstances() {
+		warnings = append(warnings, issuesOf(err)...)
+	}
+	return nil, warnings
+}
+
+// issuesOf splits a load error into issues: one per unset environment
+// variable for an *EnvError, otherwise one, located if err carries a position.
+func issuesOf(err error) []Issue {
+	var envErr *EnvError
+	if errors.As(err, &envErr) {
+		issues := make([]Issue, len(envErr.Missing))
+		for i, missing := range envErr.Missing {
+			issues[i] = Issue{Message: fmt.Sprintf("environment variable reference not set (use ${NAME:-default} for a fallback): %s", missing)}
+		}
+		return issues
+	}
+	var located *SourceError
+	if errors.As(err, &located) {
+		return []Issue{{File: located.File, Line: located.Line, Message: located.Err.Error()}}
+	}
+	return []Issue{{Message: err.Error()}}
+}
+
+// unknownInstances returns an error, located at the step, for every enabled
+// Jenkins step naming an instance that is not defined. Only configs loaded
+// with AllowUnknownInstances can have any.
+func (c *Config) unknownInstances() []error {
+	var errs []error
+	check := func(step Step, location string, keys ...interface{}) {
+		if _, ok := c.Instances[step.Instance]; ok || step.Disabled() || step.Instance == "" {
+			return
+		}
+		errs = append(errs, c.workflowSrc.wrap(c.unknownInstanceError(step, location), append(keys, "instance")...))
+	}
+	for _, prefix := range []string{"", "finally."} {
+		items := c.Workflow
+		if prefix != "" {
+			items = c.Finally
+		}
+		for i, item := range items {
+			if item.Disabled() {
+				continue
+			}
+			if item.IsParallel() {
+				for j, step := range item.Parallel.Steps {
+					check(step, fmt.Sprintf("%sparallel[%d].step[%d]", prefix, i, j), itemSection(prefix), i, "parallel", "steps", j)
+				}
+			} else if item.IsStep() {
+				check(item.AsStep(), fmt.Sprintf("%sstep %d", prefix, i), itemSection(prefix), i)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Prefixes for namespaced template references.
+const (
+	inputsPrefix = "inputs."
+	varsPrefix   = "vars."
+	envPrefix    = "env."
+)
+
+// TemplateVars returns everything ${...} substitution can reference: each input
+// under its own name and as inputs.<name>, and each workflow var resolved
+// against inputs as vars.<name>. Var resolution errors are reported by Load and
+// ApplyInputs; here an unresolvable var set is simply left out.
+func (c *Config) TemplateVars(inputs map[string]string) map[string]string {
+	vars := make(map[string]string, 2*len(inputs)+len(c.Vars))
+	for k, v := range inputs {
+		vars[k] = v
+		vars[inputsPrefix+k] = v
+	}
+	resolved, _ := c.resolveVars(inputs)
+	for k, v := range resolved {
+		vars[varsPrefix+k] = v
+	}
+	return vars
+}
+
+// Expand substitutes inputs and workflow vars into text. Text without a ${
+// placeholder is returned unchanged.
+func (c *Config) Expand(text string) string {
+	return substituteIfTemplate(text, c.TemplateVars(c.Inputs))
+}
+
+// InputRefs returns the inputs text depends on, whether referenced directly,
+// as ${inputs.<name>}, or through a ${vars.<name>} whose value uses them.
+func (c *Config) InputRefs(text string) []string {
+	seen := map[string]bool{}
+	var refs []string
+	var walk func(text string)
+	walk = func(text string) {
+		for _, name := range FindTemplateVars(text) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			switch {
+			case strings.HasPrefix(name, varsPrefix):
+				walk(c.Vars[strings.TrimPrefix(name, varsPrefix)])
+			case strings.HasPrefix(name, inputsPrefix):
+				refs = append(refs, strings.TrimPrefix(name, inputsPrefix))
+			case strings.HasPrefix(name, envPrefix), strings.HasPrefix(name, "steps."):
+			default:
+				refs = append(refs, name)
+			}
+		}
+	}
+	walk(text)
+	return refs
+}
+
+// resolveVars resolves c.Vars against inputs and the process environment.
+// Values may reference ${<input>}, ${inputs.<input>}, ${env.<NAME>} and other
+// ${vars.<name>}. Undefined references and cycles are errors; a cycle is
+// reported with its full chain.
+func (c *Config) resolveVars(inputs map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(c.Vars))
+
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		if _, ok := resolved[name]; ok {
+			return nil
+		}
+		for i, n := range chain {
+			if n == name {
+				cycle := append(append([]string(nil), chain[i:]...), name)
+				for j := range cycle {
+					cycle[j] = varsPrefix + cycle[j]
+				}
+				return fmt.Errorf("vars: circular reference %s", strings.Join(cycle, " -> "))
+			}
+		}
+		chain = append(chain, name)
+
+		raw := c.Vars[name]
+		for _, ref := range FindTemplateVars(raw) {
+			switch {
+			case strings.HasPrefix(ref, varsPrefix):
+				dep := strings.TrimPrefix(ref, varsPrefix)
+				if _, ok := c.Vars[dep]; !ok {
+					return fmt.Errorf("vars.%s: references undefined var %q", name, dep)
+				}
+				if err := visit(dep, chain); err != nil {
+					return err
+				}
+			case strings.HasPrefix(ref, envPrefix):
+				if _, ok := c.sources.lookupEnv(strings.TrimPrefix(ref, envPrefix)); !ok {
+					return fmt.Errorf("vars.%s: environment variable %q is not set", name, strings.TrimPrefix(ref, envPrefix))
+				}
+			default:
+				if _, ok := inputs[strings.TrimPrefix(ref, inputsPrefix)]; !ok {
+					return fmt.Errorf("vars.%s: references undefined input %q", name, strings.TrimPrefix(ref, inputsPrefix))
+				}
+			}
+		}
+
+		resolved[name] = os.Expand(raw, func(key string) string {
+			switch {
+			case strings.HasPrefix(key, varsPrefix):
+				return resolved[strings.TrimPrefix(key, varsPrefix)]
+			case strings.HasPrefix(key, envPrefix):
+				v, _ := c.sources.lookupEnv(strings.TrimPrefix(key, envPrefix))
+				return v
+			default:
+				return inputs[strings.TrimPrefix(key, inputsPrefix)]
+			}
+		})
+		return nil
+	}
+
+	names := make([]string, 0, len(c.Vars))
+	for name := range c.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
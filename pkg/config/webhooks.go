@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// SlackWebhookHost is the host of Slack's incoming webhooks. Webhooks on other
+// hosts, such as a self-hosted Mattermost, must be listed in
+// slack_webhook_hosts.
+const SlackWebhookHost = "hooks.slack.com"
+
+// checkWebhook reports why webhook does not look like an incoming webhook
+// URL: it must be https on SlackWebhookHost, or http(s) on one of hosts, with
+// a path. Values still holding a ${...} reference are not checked. Errors name
+// the host only, since the rest of the URL is a credential.
+func checkWebhook(webhook string, hosts []string) error {
+	if webhook == "" || strings.Contains(webhook, "${") {
+		return nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("not an http(s) URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	listed := slices.ContainsFunc(hosts, func(h string) bool { return strings.EqualFold(h, host) })
+	switch {
+	case !listed && host != SlackWebhookHost:
+		return fmt.Errorf("host %q is not %s; add it to slack_webhook_hosts if it is a Slack-compatible server", host, SlackWebhookHost)
+	case !listed && u.Scheme != "https":
+		return fmt.Errorf("%s webhooks must use https", SlackWebhookHost)
+	case strings.Trim(u.Path, "/") == "":
+		return fmt.Errorf("URL has no webhook path")
+	}
+	return nil
+}
+
+// webhookSource returns the file that set the workflow's slack_webhook: the
+// defaults file when only it has one, the workflow file otherwise.
+func (c *Config) webhookSource() *sourceFile {
+	if !c.workflowSrc.has("slack_webhook") && c.defaultsSrc.has("slack_webhook") {
+		return c.defaultsSrc
+	}
+	return c.workflowSrc
+}
+
+// checkWebhookHosts checks that a slack_webhook_hosts list holds bare host
+// names, returning the index of the first entry that is not one.
+func checkWebhookHosts(hosts []string) (int, error) {
+	for i, host := range hosts {
+		if host == "" || strings.ContainsAny(host, "/: ") {
+			return i, fmt.Errorf("slack_webhook_hosts: entry %d must be a host name such as mattermost.example.com, got %q", i+1, host)
+		}
+	}
+	return 0, nil
+}
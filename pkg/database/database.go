@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -23,6 +24,43 @@ type WorkflowRun struct {
 	InputsJSON     string            `json:"inputs_json"`
 	Inputs         map[string]string `json:"inputs,omitempty"`
 	ConfigSnapshot string            `json:"config_snapshot"`
+	Profile        string            `json:"profile,omitempty"` // Instance profile used for alias resolution
+	SkipPRCheck    bool              `json:"skip_pr_check"`
+	StopMode       string            `json:"stop_mode,omitempty"` // "graceful" or "now" for stopped runs
+	Attempt        int               `json:"attempt"`             // 1, or the attempt that ran last for a workflow with retries
+	RunMeta
+}
+
+// RunMeta is optional caller-supplied metadata describing a run.
+type RunMeta struct {
+	Initiator   string   `json:"initiator,omitempty"`   // Who or what started the run
+	Description string   `json:"description,omitempty"` // Free-form reason for the run
+	Labels      []string `json:"labels,omitempty"`      // Tags for filtering the history
+	RerunOf     int64    `json:"rerun_of,omitempty"`    // ID of the run this one reran, 0 for a fresh run
+}
+
+// RunStep is the recorded progress of one Jenkins step within a workflow run.
+// Empty fields mean the step has not reached that stage yet.
+type RunStep struct {
+	RunID       int64             `json:"run_id"`
+	ItemIndex   int               `json:"item_index"`
+	StepIndex   int               `json:"step_index"`
+	StepName    string            `json:"step_name"`
+	QueueURL    string            `json:"queue_url,omitempty"`
+	BuildURL    string            `json:"build_url,omitempty"`
+	Result      string            `json:"result,omitempty"`
+	BuildNumber int               `json:"build_number,omitempty"`
+	SkipReason  string            `json:"skip_reason,omitempty"` // Why a SKIPPED step did not run
+	Params      map[string]string `json:"params,omitempty"`      // Params as sent to Jenkins, after substitution
+	Artifacts   []StepArtifact    `json:"artifacts,omitempty"`   // Files the build archived; Jenkins keeps the content
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// StepArtifact references a file archived by a step's Jenkins build.
+type StepArtifact struct {
+	FileName     string `json:"file_name"`
+	RelativePath string `json:"relative_path"`
+	URL          string `json:"url"`
 }
 
 // DB wraps the SQLite database connection.
@@ -69,7 +107,8 @@ func NewDB(dbPath string) (*DB, error) {
 }
 
 // CreateRun creates a new workflow run record with status "running".
-func (db *DB) CreateRun(workflowName, workflowPath, configSnapshot string, inputs map[string]string) (int64, error) {
+// profile is the instance profile the run was started with ("" for the defaults).
+func (db *DB) CreateRun(workflowName, workflowPath, configSnapshot string, inputs map[string]string, profile string, meta RunMeta) (int64, error) {
 	if db.conn == nil {
 		return 0, fmt.Errorf("database connection is nil")
 	}
@@ -80,12 +119,22 @@ func (db *DB) CreateRun(workflowName, workflowPath, configSnapshot string, input
 		return 0, fmt.Errorf("failed to marshal inputs: %w", err)
 	}
 
+	labels := meta.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
 	query := `
-		INSERT INTO workflow_runs (workflow_name, workflow_path, start_time, status, inputs_json, config_snapshot)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO workflow_runs (workflow_name, workflow_path, start_time, status, inputs_json, config_snapshot, profile, initiator, description, labels_json, rerun_of)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := db.conn.Exec(query, workflowName, workflowPath, time.Now().UTC(), "running", string(inputsJSON), configSnapshot)
+	result, err := db.conn.Exec(query, workflowName, workflowPath, time.Now().UTC(), "running", string(inputsJSON), configSnapshot, profile,
+		meta.Initiator, meta.Description, string(labelsJSON), meta.RerunOf)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert workflow run: %w", err)
 	}
@@ -127,30 +176,89 @@ func (db *DB) UpdateRunComplete(runID int64, status string, endTime time.Time) e
 	return nil
 }
 
+// RunSort selects the order GetRuns returns runs in.
+type RunSort string
+
+const (
+	RunSortStartDesc    RunSort = "start_time_desc" // Newest first (default)
+	RunSortStartAsc     RunSort = "start_time_asc"
+	RunSortDurationDesc RunSort = "duration_desc" // Longest first; unfinished runs last
+	RunSortDurationAsc  RunSort = "duration_asc"  // Shortest first; unfinished runs last
+)
+
+// runSortOrder maps each RunSort to its ORDER BY clause. Ties fall back to
+// the run ID, so pages of runs started in the same instant neither repeat nor
+// skip a run.
+var runSortOrder = map[RunSort]string{
+	"":                  "start_time DESC, id DESC",
+	RunSortStartDesc:    "start_time DESC, id DESC",
+	RunSortStartAsc:     "start_time ASC, id ASC",
+	RunSortDurationDesc: "end_time IS NULL, julianday(end_time) - julianday(start_time) DESC, start_time DESC, id DESC",
+	RunSortDurationAsc:  "end_time IS NULL, julianday(end_time) - julianday(start_time) ASC, start_time DESC, id DESC",
+}
+
+// Valid reports whether s is a known sort order. The empty value selects the default.
+func (s RunSort) Valid() bool {
+	_, ok := runSortOrder[s]
+	return ok
+}
+
+// RunFilter narrows the runs returned by GetRuns. Zero-valued fields match everything.
+type RunFilter struct {
+	WorkflowPath string
+	Status       string
+	Label        string    // Matches runs carrying this exact label
+	From         time.Time // Runs started at or after From
+	To           time.Time // Runs started before To
+	Sort         RunSort
+}
+
 // GetRuns retrieves workflow runs with pagination and optional filters.
-func (db *DB) GetRuns(limit, offset int, workflowPath, status string) ([]WorkflowRun, error) {
+func (db *DB) GetRuns(limit, offset int, filter RunFilter) ([]WorkflowRun, error) {
 	if db.conn == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
 
+	order, ok := runSortOrder[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order %q", filter.Sort)
+	}
+
 	query := `
-		SELECT id, workflow_name, workflow_path, start_time, end_time, status, inputs_json, config_snapshot
+		SELECT id, workflow_name, workflow_path, start_time, end_time, status, inputs_json, config_snapshot, profile, skip_pr_check, stop_mode, attempt, initiator, description, labels_json, rerun_of
 		FROM workflow_runs
 		WHERE 1=1
 	`
 	args := []interface{}{}
 
-	if workflowPath != "" {
+	if filter.WorkflowPath != "" {
 		query += " AND workflow_path = ?"
-		args = append(args, workflowPath)
+		args = append(args, filter.WorkflowPath)
 	}
 
-	if status != "" {
+	if filter.Status != "" {
 		query += " AND status = ?"
-		args = append(args, status)
+		args = append(args, filter.Status)
+	}
+
+	if filter.Label != "" {
+		query += " AND EXISTS (SELECT 1 FROM json_each(labels_json) WHERE value = ?)"
+		args = append(args, filter.Label)
 	}
 
-	query += " ORDER BY start_time DESC LIMIT ? OFFSET ?"
+	// start_time is stored as UTC text in the driver's timestamp format, so
+	// bounds converted to UTC compare correctly as strings and keep the index.
+	if !filter.From.IsZero() {
+		query += " AND start_time >= ?"
+		args = append(args, filter.From.UTC())
+	}
+
+	if !filter.To.IsZero() {
+		query += " AND start_time < ?"
+		args = append(args, filter.To.UTC())
+	}
+
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := db.conn.Query(query, args...)
@@ -163,8 +271,10 @@ func (db *DB) GetRuns(limit, offset int, workflowPath, status string) ([]Workflo
 	for rows.Next() {
 		var run WorkflowRun
 		var endTime sql.NullTime
+		var labelsJSON string
 
-		err := rows.Scan(&run.ID, &run.WorkflowName, &run.WorkflowPath, &run.StartTime, &endTime, &run.Status, &run.InputsJSON, &run.ConfigSnapshot)
+		err := rows.Scan(&run.ID, &run.WorkflowName, &run.WorkflowPath, &run.StartTime, &endTime, &run.Status, &run.InputsJSON, &run.ConfigSnapshot, &run.Profile, &run.SkipPRCheck, &run.StopMode, &run.Attempt,
+			&run.Initiator, &run.Description, &labelsJSON, &run.RerunOf)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan workflow run: %w", err)
 		}
@@ -181,6 +291,7 @@ func (db *DB) GetRuns(limit, offset int, workflowPath, status string) ([]Workflo
 				run.Inputs = make(map[string]string)
 			}
 		}
+		run.Labels = unmarshalLabels(run.ID, labelsJSON)
 
 		runs = append(runs, run)
 	}
@@ -199,15 +310,17 @@ func (db *DB) GetRun(runID int64) (*WorkflowRun, error) {
 	}
 
 	query := `
-		SELECT id, workflow_name, workflow_path, start_time, end_time, status, inputs_json, config_snapshot
+		SELECT id, workflow_name, workflow_path, start_time, end_time, status, inputs_json, config_snapshot, profile, skip_pr_check, stop_mode, attempt, initiator, description, labels_json, rerun_of
 		FROM workflow_runs
 		WHERE id = ?
 	`
 
 	var run WorkflowRun
 	var endTime sql.NullTime
+	var labelsJSON string
 
-	err := db.conn.QueryRow(query, runID).Scan(&run.ID, &run.WorkflowName, &run.WorkflowPath, &run.StartTime, &endTime, &run.Status, &run.InputsJSON, &run.ConfigSnapshot)
+	err := db.conn.QueryRow(query, runID).Scan(&run.ID, &run.WorkflowName, &run.WorkflowPath, &run.StartTime, &endTime, &run.Status, &run.InputsJSON, &run.ConfigSnapshot, &run.Profile, &run.SkipPRCheck, &run.StopMode, &run.Attempt,
+		&run.Initiator, &run.Description, &labelsJSON, &run.RerunOf)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("workflow run with id %d not found", runID)
 	}
@@ -227,10 +340,319 @@ func (db *DB) GetRun(runID int64) (*WorkflowRun, error) {
 			run.Inputs = make(map[string]string)
 		}
 	}
+	run.Labels = unmarshalLabels(run.ID, labelsJSON)
 
 	return &run, nil
 }
 
+// unmarshalLabels decodes a run's labels_json column. Malformed values are
+// logged and treated as no labels.
+func unmarshalLabels(runID int64, labelsJSON string) []string {
+	var labels []string
+	if err := json.Unmarshal([]byte(labelsJSON), &labels); err != nil {
+		log.Printf("Warning: Failed to unmarshal labels for run %d: %v", runID, err)
+		return nil
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// SaveRunStep records progress for a step. Empty fields in step keep the
+// previously stored value, so callers can report each stage as it happens.
+func (db *DB) SaveRunStep(step RunStep) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	paramsJSON := ""
+	if len(step.Params) > 0 {
+		data, err := json.Marshal(step.Params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal step params: %w", err)
+		}
+		paramsJSON = string(data)
+	}
+
+	artifactsJSON := ""
+	if len(step.Artifacts) > 0 {
+		data, err := json.Marshal(step.Artifacts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal step artifacts: %w", err)
+		}
+		artifactsJSON = string(data)
+	}
+
+	query := `
+		INSERT INTO run_steps (run_id, item_index, step_index, step_name, queue_url, build_url, result, build_number, skip_reason, params_json, artifacts_json, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (run_id, item_index, step_index) DO UPDATE SET
+			step_name = excluded.step_name,
+			queue_url = COALESCE(NULLIF(excluded.queue_url, ''), queue_url),
+			build_url = COALESCE(NULLIF(excluded.build_url, ''), build_url),
+			result = COALESCE(NULLIF(excluded.result, ''), result),
+			build_number = COALESCE(NULLIF(excluded.build_number, 0), build_number),
+			skip_reason = COALESCE(NULLIF(excluded.skip_reason, ''), skip_reason),
+			params_json = COALESCE(NULLIF(excluded.params_json, ''), params_json),
+			artifacts_json = COALESCE(NULLIF(excluded.artifacts_json, ''), artifacts_json),
+			updated_at = excluded.updated_at
+	`
+
+	_, err := db.conn.Exec(query, step.RunID, step.ItemIndex, step.StepIndex, step.StepName,
+		step.QueueURL, step.BuildURL, step.Result, step.BuildNumber, step.SkipReason, paramsJSON, artifactsJSON, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save run step: %w", err)
+	}
+	return nil
+}
+
+// GetRunSteps returns the recorded step progress for a run, ordered by position.
+func (db *DB) GetRunSteps(runID int64) ([]RunStep, error) {
+	if db.conn == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	query := `
+		SELECT run_id, item_index, step_index, step_name, queue_url, build_url, result, build_number, skip_reason, params_json, artifacts_json, updated_at
+		FROM run_steps
+		WHERE run_id = ?
+		ORDER BY item_index, step_index
+	`
+
+	rows, err := db.conn.Query(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run steps: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []RunStep
+	for rows.Next() {
+		var step RunStep
+		var paramsJSON, artifactsJSON string
+		if err := rows.Scan(&step.RunID, &step.ItemIndex, &step.StepIndex, &step.StepName, &step.QueueURL, &step.BuildURL, &step.Result, &step.BuildNumber, &step.SkipReason, &paramsJSON, &artifactsJSON, &step.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan run step: %w", err)
+		}
+		if paramsJSON != "" {
+			if err := json.Unmarshal([]byte(paramsJSON), &step.Params); err != nil {
+				log.Printf("Warning: Failed to unmarshal params for run %d step %d/%d: %v", step.RunID, step.ItemIndex, step.StepIndex, err)
+			}
+		}
+		if artifactsJSON != "" {
+			if err := json.Unmarshal([]byte(artifactsJSON), &step.Artifacts); err != nil {
+				log.Printf("Warning: Failed to unmarshal artifacts for run %d step %d/%d: %v", step.RunID, step.ItemIndex, step.StepIndex, err)
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating run steps: %w", err)
+	}
+
+	return steps, nil
+}
+
+// WorkflowStats summarises the recent successful runs of a workflow.
+type WorkflowStats struct {
+	WorkflowPath   string
+	SampleSize     int           // Successful runs the median was taken over
+	MedianDuration time.Duration // Zero when there are no samples
+}
+
+// GetWorkflowStats computes the median duration of the last limit successful
+// runs of the workflow at workflowPath.
+func (db *DB) GetWorkflowStats(workflowPath string, limit int) (WorkflowStats, error) {
+	stats := WorkflowStats{WorkflowPath: workflowPath}
+	if db.conn == nil {
+		return stats, fmt.Errorf("database connection is nil")
+	}
+
+	query := `
+		SELECT start_time, end_time
+		FROM workflow_runs
+		WHERE workflow_path = ? AND status = 'success' AND end_time IS NOT NULL
+		ORDER BY start_time DESC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query, workflowPath, limit)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query workflow stats: %w", err)
+	}
+	defer rows.Close()
+
+	var durations []time.Duration
+	for rows.Next() {
+		var start, end time.Time
+		if err := rows.Scan(&start, &end); err != nil {
+			return stats, fmt.Errorf("failed to scan workflow run: %w", err)
+		}
+		durations = append(durations, end.Sub(start))
+	}
+
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("error iterating workflow runs: %w", err)
+	}
+
+	stats.SampleSize = len(durations)
+	if len(durations) == 0 {
+		return stats, nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mid := len(durations) / 2
+	if len(durations)%2 == 1 {
+		stats.MedianDuration = durations[mid]
+	} else {
+		stats.MedianDuration = (durations[mid-1] + durations[mid]) / 2
+	}
+	return stats, nil
+}
+
+// SaveRunLog stores the captured engine log of a run, replacing any earlier one.
+func (db *DB) SaveRunLog(runID int64, text string) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET log = ? WHERE id = ?`, text, runID)
+	if err != nil {
+		return fmt.Errorf("failed to save run log: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("workflow run with id %d not found", runID)
+	}
+
+	return nil
+}
+
+// SaveRunStopMode records how a stopped run was stopped ("graceful" or "now").
+func (db *DB) SaveRunStopMode(runID int64, mode string) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET stop_mode = ? WHERE id = ?`, mode, runID)
+	if err != nil {
+		return fmt.Errorf("failed to save run stop mode: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("workflow run with id %d not found", runID)
+	}
+
+	return nil
+}
+
+// SaveRunAttempt records that a run with workflow retries started attempt.
+func (db *DB) SaveRunAttempt(runID int64, attempt int) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET attempt = ? WHERE id = ?`, attempt, runID)
+	if err != nil {
+		return fmt.Errorf("failed to save run attempt: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("workflow run with id %d not found", runID)
+	}
+
+	return nil
+}
+
+// ClearRunSteps deletes the recorded step progress of a run, so a retry
+// starts from a clean record and a restart does not resume the failed attempt.
+func (db *DB) ClearRunSteps(runID int64) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := db.conn.Exec(`DELETE FROM run_steps WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to clear run steps: %w", err)
+	}
+	return nil
+}
+
+// GetRunLog returns the stored log of a run. It is empty until the run completes.
+func (db *DB) GetRunLog(runID int64) (string, error) {
+	if db.conn == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+
+	var text string
+	err := db.conn.QueryRow(`SELECT log FROM workflow_runs WHERE id = ?`, runID).Scan(&text)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("workflow run with id %d not found", runID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query run log: %w", err)
+	}
+
+	return text, nil
+}
+
+// SaveRunState stores the serialized item states of a run, replacing any
+// earlier snapshot. The database does not interpret stateJSON.
+func (db *DB) SaveRunState(runID int64, stateJSON string) error {
+	if db.conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET final_state_json = ? WHERE id = ?`, stateJSON, runID)
+	if err != nil {
+		return fmt.Errorf("failed to save run state: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("workflow run with id %d not found", runID)
+	}
+
+	return nil
+}
+
+// GetRunState returns the last state snapshot stored for a run. It is empty
+// for runs that never finished a step and for runs recorded before snapshots
+// were kept.
+func (db *DB) GetRunState(runID int64) (string, error) {
+	if db.conn == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+
+	var stateJSON string
+	err := db.conn.QueryRow(`SELECT final_state_json FROM workflow_runs WHERE id = ?`, runID).Scan(&stateJSON)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("workflow run with id %d not found", runID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query run state: %w", err)
+	}
+
+	return stateJSON, nil
+}
+
 // Close closes the database connection.
 func (db *DB) Close() error {
 	if db.conn != nil {
@@ -1,8 +1,10 @@
 package database
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -45,7 +47,8 @@ func TestCreateRun(t *testing.T) {
 		"version": "1.2.3",
 	}
 
-	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "name: Test Workflow\nworkflow: []", inputs)
+	meta := RunMeta{Initiator: "alice", Description: "Hotfix for login", Labels: []string{"release", "hotfix"}, RerunOf: 7}
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "name: Test Workflow\nworkflow: []", inputs, "prod", meta)
 	if err != nil {
 		t.Fatalf("CreateRun failed: %v", err)
 	}
@@ -71,6 +74,52 @@ func TestCreateRun(t *testing.T) {
 	if run.Inputs["env"] != "production" {
 		t.Errorf("expected input env='production', got %q", run.Inputs["env"])
 	}
+
+	if run.Profile != "prod" {
+		t.Errorf("expected profile 'prod', got %q", run.Profile)
+	}
+
+	if run.Initiator != "alice" || run.Description != "Hotfix for login" || strings.Join(run.Labels, ",") != "release,hotfix" || run.RerunOf != 7 {
+		t.Errorf("unexpected run metadata: %+v", run.RunMeta)
+	}
+}
+
+func TestGetRuns_LabelFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	for _, labels := range [][]string{{"release"}, {"nightly"}, {"release", "eu"}, nil, {"release-candidate"}} {
+		if _, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{Labels: labels}); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+	}
+
+	runs, err := db.GetRuns(10, 0, RunFilter{Label: "release"})
+	if err != nil {
+		t.Fatalf("GetRuns failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs labelled release, got %d", len(runs))
+	}
+	for _, run := range runs {
+		if run.Labels[0] != "release" {
+			t.Errorf("unexpected labels %v", run.Labels)
+		}
+	}
+
+	runs, err = db.GetRuns(10, 0, RunFilter{})
+	if err != nil {
+		t.Fatalf("GetRuns failed: %v", err)
+	}
+	if len(runs) != 5 {
+		t.Errorf("expected 5 runs without a label filter, got %d", len(runs))
+	}
 }
 
 func TestUpdateRunComplete(t *testing.T) {
@@ -84,7 +133,7 @@ func TestUpdateRunComplete(t *testing.T) {
 	defer db.Close()
 
 	inputs := map[string]string{"key": "value"}
-	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", inputs)
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", inputs, "", RunMeta{})
 	if err != nil {
 		t.Fatalf("CreateRun failed: %v", err)
 	}
@@ -124,7 +173,7 @@ func TestGetRuns(t *testing.T) {
 	// Create multiple runs
 	inputs := map[string]string{"key": "value"}
 	for i := 0; i < 5; i++ {
-		_, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", inputs)
+		_, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", inputs, "", RunMeta{})
 		if err != nil {
 			t.Fatalf("CreateRun failed: %v", err)
 		}
@@ -132,7 +181,7 @@ func TestGetRuns(t *testing.T) {
 	}
 
 	// Test pagination
-	runs, err := db.GetRuns(2, 0, "", "")
+	runs, err := db.GetRuns(2, 0, RunFilter{})
 	if err != nil {
 		t.Fatalf("GetRuns failed: %v", err)
 	}
@@ -142,7 +191,7 @@ func TestGetRuns(t *testing.T) {
 	}
 
 	// Test offset
-	runs, err = db.GetRuns(2, 2, "", "")
+	runs, err = db.GetRuns(2, 2, RunFilter{})
 	if err != nil {
 		t.Fatalf("GetRuns with offset failed: %v", err)
 	}
@@ -152,7 +201,7 @@ func TestGetRuns(t *testing.T) {
 	}
 
 	// Test status filter
-	runs, err = db.GetRuns(10, 0, "", "running")
+	runs, err = db.GetRuns(10, 0, RunFilter{Status: "running"})
 	if err != nil {
 		t.Fatalf("GetRuns with status filter failed: %v", err)
 	}
@@ -162,7 +211,7 @@ func TestGetRuns(t *testing.T) {
 	}
 
 	// Test workflow path filter
-	runs, err = db.GetRuns(10, 0, "workflows/test.yaml", "")
+	runs, err = db.GetRuns(10, 0, RunFilter{WorkflowPath: "workflows/test.yaml"})
 	if err != nil {
 		t.Fatalf("GetRuns with workflow_path filter failed: %v", err)
 	}
@@ -172,6 +221,304 @@ func TestGetRuns(t *testing.T) {
 	}
 }
 
+func TestGetRuns_DateRangeAndSort(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	// Runs start on the hour; durations are deliberately out of start order.
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	durations := []time.Duration{5 * time.Minute, 20 * time.Minute, time.Minute, 0}
+	ids := make([]int64, len(durations))
+	for i, d := range durations {
+		id, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{})
+		if err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		start := base.Add(time.Duration(i) * time.Hour)
+		if _, err := db.conn.Exec("UPDATE workflow_runs SET start_time = ? WHERE id = ?", start, id); err != nil {
+			t.Fatal(err)
+		}
+		// The last run is still going and has no duration.
+		if d > 0 {
+			if err := db.UpdateRunComplete(id, "success", start.Add(d)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[i] = id
+	}
+
+	runIDs := func(filter RunFilter) []int64 {
+		t.Helper()
+		runs, err := db.GetRuns(10, 0, filter)
+		if err != nil {
+			t.Fatalf("GetRuns(%+v) failed: %v", filter, err)
+		}
+		got := make([]int64, len(runs))
+		for i, r := range runs {
+			got[i] = r.ID
+		}
+		return got
+	}
+
+	// From is inclusive and To exclusive, in any time zone.
+	est := time.FixedZone("EST", -5*3600)
+	tests := []struct {
+		name   string
+		filter RunFilter
+		want   []int64
+	}{
+		{"from boundary is inclusive", RunFilter{From: base.Add(time.Hour)}, []int64{ids[3], ids[2], ids[1]}},
+		{"from just after a start excludes it", RunFilter{From: base.Add(time.Hour + time.Nanosecond)}, []int64{ids[3], ids[2]}},
+		{"to boundary is exclusive", RunFilter{To: base.Add(2 * time.Hour)}, []int64{ids[1], ids[0]}},
+		{"range in another zone", RunFilter{From: base.Add(time.Hour).In(est), To: base.Add(3 * time.Hour).In(est)}, []int64{ids[2], ids[1]}},
+		{"empty range", RunFilter{From: base.Add(time.Hour), To: base.Add(time.Hour)}, []int64{}},
+		{"start ascending", RunFilter{Sort: RunSortStartAsc}, ids},
+		{"duration descending", RunFilter{Sort: RunSortDurationDesc}, []int64{ids[1], ids[0], ids[2], ids[3]}},
+		{"duration ascending", RunFilter{Sort: RunSortDurationAsc}, []int64{ids[2], ids[0], ids[1], ids[3]}},
+		{"range with sort", RunFilter{To: base.Add(3 * time.Hour), Sort: RunSortStartAsc}, []int64{ids[0], ids[1], ids[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runIDs(tt.filter)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got runs %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.GetRuns(10, 0, RunFilter{Sort: "name"}); err == nil {
+		t.Error("expected error for unknown sort order")
+	}
+}
+
+func TestGetWorkflowStats(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	addRun := func(path, status string, start time.Time, d time.Duration) {
+		t.Helper()
+		id, err := db.CreateRun("Test Workflow", path, "config", nil, "", RunMeta{})
+		if err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		if _, err := db.conn.Exec("UPDATE workflow_runs SET start_time = ? WHERE id = ?", start, id); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateRunComplete(id, status, start.Add(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The oldest success falls outside the sample; failures and other
+	// workflows are ignored.
+	addRun("workflows/test.yaml", "success", base, time.Hour)
+	addRun("workflows/test.yaml", "success", base.Add(1*time.Hour), 30*time.Minute)
+	addRun("workflows/test.yaml", "failed", base.Add(2*time.Hour), time.Minute)
+	addRun("workflows/test.yaml", "success", base.Add(3*time.Hour), 10*time.Minute)
+	addRun("workflows/test.yaml", "success", base.Add(4*time.Hour), 20*time.Minute)
+	addRun("workflows/other.yaml", "success", base.Add(5*time.Hour), 2*time.Hour)
+
+	stats, err := db.GetWorkflowStats("workflows/test.yaml", 3)
+	if err != nil {
+		t.Fatalf("GetWorkflowStats failed: %v", err)
+	}
+	if stats.SampleSize != 3 || stats.MedianDuration != 20*time.Minute {
+		t.Errorf("expected median 20m over 3 runs, got %s over %d", stats.MedianDuration, stats.SampleSize)
+	}
+
+	// An even sample averages the two middle durations.
+	stats, err = db.GetWorkflowStats("workflows/test.yaml", 2)
+	if err != nil {
+		t.Fatalf("GetWorkflowStats failed: %v", err)
+	}
+	if stats.MedianDuration != 15*time.Minute {
+		t.Errorf("expected median 15m, got %s", stats.MedianDuration)
+	}
+
+	stats, err = db.GetWorkflowStats("workflows/none.yaml", 3)
+	if err != nil {
+		t.Fatalf("GetWorkflowStats failed: %v", err)
+	}
+	if stats.SampleSize != 0 || stats.MedianDuration != 0 {
+		t.Errorf("expected empty stats, got %+v", stats)
+	}
+}
+
+func TestSaveRunStep(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{})
+	if err != nil {
+		t.Fatalf("CreateRun failed: %v", err)
+	}
+
+	// Each stage reports only what it knows; earlier fields must survive.
+	for _, step := range []RunStep{
+		{RunID: runID, ItemIndex: 1, StepIndex: 2, StepName: "Deploy", QueueURL: "http://ci/queue/item/5/", Params: map[string]string{"PR": "42"}},
+		{RunID: runID, ItemIndex: 1, StepIndex: 2, StepName: "Deploy", BuildURL: "http://ci/job/deploy/9/"},
+		{RunID: runID, ItemIndex: 1, StepIndex: 2, StepName: "Deploy", Artifacts: []StepArtifact{{FileName: "app.jar", RelativePath: "target/app.jar", URL: "http://ci/job/deploy/9/artifact/target/app.jar"}}},
+		{RunID: runID, ItemIndex: 1, StepIndex: 2, StepName: "Deploy", Result: "SUCCESS", BuildNumber: 9},
+		{RunID: runID, ItemIndex: 0, StepIndex: 0, StepName: "Build", Result: "SKIPPED", SkipReason: "skipped: disabled by user"},
+	} {
+		if err := db.SaveRunStep(step); err != nil {
+			t.Fatalf("SaveRunStep failed: %v", err)
+		}
+	}
+
+	steps, err := db.GetRunSteps(runID)
+	if err != nil {
+		t.Fatalf("GetRunSteps failed: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(steps))
+	}
+	if steps[0].StepName != "Build" || steps[0].Result != "SKIPPED" || steps[0].SkipReason != "skipped: disabled by user" {
+		t.Errorf("unexpected first step: %+v", steps[0])
+	}
+	deploy := steps[1]
+	if deploy.QueueURL != "http://ci/queue/item/5/" || deploy.BuildURL != "http://ci/job/deploy/9/" ||
+		deploy.Result != "SUCCESS" || deploy.BuildNumber != 9 || deploy.Params["PR"] != "42" {
+		t.Errorf("expected merged progress, got %+v", deploy)
+	}
+	if len(deploy.Artifacts) != 1 || deploy.Artifacts[0].RelativePath != "target/app.jar" ||
+		deploy.Artifacts[0].URL != "http://ci/job/deploy/9/artifact/target/app.jar" {
+		t.Errorf("expected the artifact to survive later updates, got %+v", deploy.Artifacts)
+	}
+	if steps[0].Artifacts != nil {
+		t.Errorf("expected no artifacts for a skipped step, got %+v", steps[0].Artifacts)
+	}
+}
+
+func TestSaveRunAttempt(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{})
+	if err != nil {
+		t.Fatalf("CreateRun failed: %v", err)
+	}
+	run, err := db.GetRun(runID)
+	if err != nil || run.Attempt != 1 {
+		t.Fatalf("expected a new run to be attempt 1, got %+v, %v", run, err)
+	}
+
+	if err := db.SaveRunStep(RunStep{RunID: runID, StepName: "Build", Result: "FAILURE"}); err != nil {
+		t.Fatalf("SaveRunStep failed: %v", err)
+	}
+	if err := db.ClearRunSteps(runID); err != nil {
+		t.Fatalf("ClearRunSteps failed: %v", err)
+	}
+	if err := db.SaveRunAttempt(runID, 2); err != nil {
+		t.Fatalf("SaveRunAttempt failed: %v", err)
+	}
+
+	runs, err := db.GetRuns(10, 0, RunFilter{})
+	if err != nil || len(runs) != 1 || runs[0].Attempt != 2 {
+		t.Errorf("expected attempt 2 in the history, got %+v, %v", runs, err)
+	}
+	if steps, err := db.GetRunSteps(runID); err != nil || len(steps) != 0 {
+		t.Errorf("expected the failed attempt's steps to be cleared, got %+v, %v", steps, err)
+	}
+	if err := db.SaveRunAttempt(999, 2); err == nil {
+		t.Error("expected an error for a missing run")
+	}
+}
+
+func TestSaveRunLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{})
+	if err != nil {
+		t.Fatalf("CreateRun failed: %v", err)
+	}
+
+	if log, err := db.GetRunLog(runID); err != nil || log != "" {
+		t.Fatalf("expected empty log before completion, got %q, %v", log, err)
+	}
+
+	if err := db.SaveRunLog(runID, "line 1\nline 2\n"); err != nil {
+		t.Fatalf("SaveRunLog failed: %v", err)
+	}
+	log, err := db.GetRunLog(runID)
+	if err != nil {
+		t.Fatalf("GetRunLog failed: %v", err)
+	}
+	if log != "line 1\nline 2\n" {
+		t.Errorf("unexpected log: %q", log)
+	}
+
+	if err := db.SaveRunLog(99999, "x"); err == nil {
+		t.Error("expected error for missing run")
+	}
+	if _, err := db.GetRunLog(99999); err == nil {
+		t.Error("expected error for missing run")
+	}
+}
+
+func TestSaveRunState(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	runID, err := db.CreateRun("Test Workflow", "workflows/test.yaml", "config", nil, "", RunMeta{})
+	if err != nil {
+		t.Fatalf("CreateRun failed: %v", err)
+	}
+
+	if state, err := db.GetRunState(runID); err != nil || state != "" {
+		t.Fatalf("expected no state before a snapshot, got %q, %v", state, err)
+	}
+
+	for _, snapshot := range []string{`{"status":"running"}`, `{"status":"failed"}`} {
+		if err := db.SaveRunState(runID, snapshot); err != nil {
+			t.Fatalf("SaveRunState failed: %v", err)
+		}
+	}
+	state, err := db.GetRunState(runID)
+	if err != nil {
+		t.Fatalf("GetRunState failed: %v", err)
+	}
+	if state != `{"status":"failed"}` {
+		t.Errorf("expected the latest snapshot, got %q", state)
+	}
+
+	if err := db.SaveRunState(99999, "{}"); err == nil {
+		t.Error("expected error for missing run")
+	}
+	if _, err := db.GetRunState(99999); err == nil {
+		t.Error("expected error for missing run")
+	}
+}
+
 func TestGetRun_NotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -0,0 +1,4 @@
+-- Migration: 000002_add_run_profile (down)
+-- Description: Drop the run profile column
+
+ALTER TABLE workflow_runs DROP COLUMN profile;
@@ -0,0 +1,4 @@
+-- Migration: 000002_add_run_profile
+-- Description: Record the instance profile a run was started with
+
+ALTER TABLE workflow_runs ADD COLUMN profile TEXT NOT NULL DEFAULT '';
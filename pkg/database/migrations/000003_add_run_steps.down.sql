@@ -0,0 +1,4 @@
+-- Migration: 000003_add_run_steps (down)
+-- Description: Drop per-step progress
+
+DROP TABLE IF EXISTS run_steps;
@@ -0,0 +1,15 @@
+-- Migration: 000003_add_run_steps
+-- Description: Record per-step progress so interrupted runs can reattach to their builds
+
+CREATE TABLE IF NOT EXISTS run_steps (
+    run_id INTEGER NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    item_index INTEGER NOT NULL,
+    step_index INTEGER NOT NULL,
+    step_name TEXT NOT NULL,
+    queue_url TEXT NOT NULL DEFAULT '',
+    build_url TEXT NOT NULL DEFAULT '',
+    result TEXT NOT NULL DEFAULT '',
+    build_number INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (run_id, item_index, step_index)
+);
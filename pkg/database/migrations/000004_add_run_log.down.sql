@@ -0,0 +1,4 @@
+-- Migration: 000004_add_run_log (down)
+-- Description: Drop the run log column
+
+ALTER TABLE workflow_runs DROP COLUMN log;
@@ -0,0 +1,4 @@
+-- Migration: 000004_add_run_log
+-- Description: Store the captured engine log of each run
+
+ALTER TABLE workflow_runs ADD COLUMN log TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- Migration: 000005_add_run_metadata (down)
+-- Description: Drop run metadata columns
+
+ALTER TABLE workflow_runs DROP COLUMN labels_json;
+ALTER TABLE workflow_runs DROP COLUMN description;
+ALTER TABLE workflow_runs DROP COLUMN initiator;
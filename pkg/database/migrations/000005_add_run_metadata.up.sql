@@ -0,0 +1,6 @@
+-- Migration: 000005_add_run_metadata
+-- Description: Record who started a run, why, and free-form labels for filtering
+
+ALTER TABLE workflow_runs ADD COLUMN initiator TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_runs ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_runs ADD COLUMN labels_json TEXT NOT NULL DEFAULT '[]';
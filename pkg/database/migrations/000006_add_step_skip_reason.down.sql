@@ -0,0 +1,4 @@
+-- Migration: 000006_add_step_skip_reason (down)
+-- Description: Drop the step skip reason column
+
+ALTER TABLE run_steps DROP COLUMN skip_reason;
@@ -0,0 +1,4 @@
+-- Migration: 000006_add_step_skip_reason
+-- Description: Record why a step was skipped so run history explains itself
+
+ALTER TABLE run_steps ADD COLUMN skip_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Migration: 000007_add_run_stop_mode (down)
+-- Description: Drop the run stop mode column
+
+ALTER TABLE workflow_runs DROP COLUMN stop_mode;
@@ -0,0 +1,4 @@
+-- Migration: 000007_add_run_stop_mode
+-- Description: Record whether a stopped run was stopped gracefully or immediately
+
+ALTER TABLE workflow_runs ADD COLUMN stop_mode TEXT NOT NULL DEFAULT '';
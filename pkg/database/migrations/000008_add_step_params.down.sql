@@ -0,0 +1,4 @@
+-- Migration: 000008_add_step_params (down)
+-- Description: Drop the step params column
+
+ALTER TABLE run_steps DROP COLUMN params_json;
@@ -0,0 +1,4 @@
+-- Migration: 000008_add_step_params
+-- Description: Record the params each step was triggered with, after substitution
+
+ALTER TABLE run_steps ADD COLUMN params_json TEXT NOT NULL DEFAULT '';
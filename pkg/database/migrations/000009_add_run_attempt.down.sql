@@ -0,0 +1,4 @@
+-- Migration: 000009_add_run_attempt (down)
+-- Description: Drop the run attempt column
+
+ALTER TABLE workflow_runs DROP COLUMN attempt;
@@ -0,0 +1,4 @@
+-- Migration: 000009_add_run_attempt
+-- Description: Record which attempt of a run with workflow retries is running or ran last
+
+ALTER TABLE workflow_runs ADD COLUMN attempt INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Migration: 000010_add_run_final_state (down)
+-- Description: Drop the run final state column
+
+ALTER TABLE workflow_runs DROP COLUMN final_state_json;
@@ -0,0 +1,4 @@
+-- Migration: 000010_add_run_final_state
+-- Description: Store the item states of a run, as of its last finished step and once it completes
+
+ALTER TABLE workflow_runs ADD COLUMN final_state_json TEXT NOT NULL DEFAULT '';
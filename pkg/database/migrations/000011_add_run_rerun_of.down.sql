@@ -0,0 +1,4 @@
+-- Migration: 000011_add_run_rerun_of (down)
+-- Description: Drop the rerun link column
+
+ALTER TABLE workflow_runs DROP COLUMN rerun_of;
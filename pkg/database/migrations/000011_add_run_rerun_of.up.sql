@@ -0,0 +1,4 @@
+-- Migration: 000011_add_run_rerun_of
+-- Description: Link a rerun to the historical run it was started from
+
+ALTER TABLE workflow_runs ADD COLUMN rerun_of INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Migration: 000012_add_step_artifacts (down)
+-- Description: Drop the step artifacts column
+
+ALTER TABLE run_steps DROP COLUMN artifacts_json;
@@ -0,0 +1,4 @@
+-- Migration: 000012_add_step_artifacts
+-- Description: Record references to the artifacts each step's build archived
+
+ALTER TABLE run_steps ADD COLUMN artifacts_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,181 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// appTokenRefreshMargin is how long before expiry an installation token is
+// replaced, so a request never goes out with a token about to lapse.
+const appTokenRefreshMargin = 5 * time.Minute
+
+// AppAuth authenticates as a GitHub App installation. Installation tokens are
+// minted on first use and refreshed shortly before they expire (hourly).
+type AppAuth struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// appAuthKey identifies an App installation and the key it authenticates with.
+type appAuthKey struct {
+	appID, installationID int64
+	keyPath               string
+}
+
+// appAuths holds the AppAuth SharedAppAuth returned for each installation and
+// key file, with the key file's contents it was parsed from.
+var appAuths = struct {
+	sync.Mutex
+	m map[appAuthKey]sharedAppAuth
+}{m: map[appAuthKey]sharedAppAuth{}}
+
+type sharedAppAuth struct {
+	auth *AppAuth
+	key  []byte
+}
+
+// LoadAppAuth reads the App's PEM private key (PKCS#1 or PKCS#8) from keyPath.
+func LoadAppAuth(appID, installationID int64, keyPath string) (*AppAuth, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+	return parseAppAuth(appID, installationID, keyPath, data)
+}
+
+// SharedAppAuth is LoadAppAuth, but returns the same AppAuth for the same App
+// installation and key file for as long as the file is unchanged, so clients
+// made for separate requests reuse its installation token until it is about
+// to expire.
+func SharedAppAuth(appID, installationID int64, keyPath string) (*AppAuth, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+
+	appAuths.Lock()
+	defer appAuths.Unlock()
+	k := appAuthKey{appID: appID, installationID: installationID, keyPath: keyPath}
+	if cached, ok := appAuths.m[k]; ok && bytes.Equal(cached.key, data) {
+		return cached.auth, nil
+	}
+	auth, err := parseAppAuth(appID, installationID, keyPath, data)
+	if err != nil {
+		return nil, err
+	}
+	appAuths.m[k] = sharedAppAuth{auth: auth, key: data}
+	return auth, nil
+}
+
+// parseAppAuth parses data, the contents of the key file at keyPath.
+func parseAppAuth(appID, installationID int64, keyPath string, data []byte) (*AppAuth, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key %s is not PEM encoded", keyPath)
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err8 != nil {
+			return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("GitHub App private key %s is not an RSA key", keyPath)
+		}
+		key = rsaKey
+	}
+
+	return &AppAuth{AppID: appID, InstallationID: installationID, PrivateKey: key}, nil
+}
+
+// jwt returns a short-lived RS256 JWT identifying the App. iat is backdated to
+// allow for clock drift, as GitHub recommends.
+func (a *AppAuth) jwt(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]int64{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// installationToken returns a cached installation token, minting a new one
+// through httpClient from the API at apiURL when none is cached or it is
+// about to expire.
+func (a *AppAuth) installationToken(ctx context.Context, httpClient *http.Client, apiURL string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.token != "" && now.Add(appTokenRefreshMargin).Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(now)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, a.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := do(httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("installation token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("installation token request failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode installation token response: %w", err)
+	}
+	if out.Token == "" {
+		return "", fmt.Errorf("installation token response did not include a token")
+	}
+
+	a.token, a.expiresAt = out.Token, out.ExpiresAt
+	return a.token, nil
+}
@@ -0,0 +1,167 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeTestAppKey(t *testing.T) (string, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+// verifyAppJWT checks the RS256 signature and issuer of an App JWT.
+func verifyAppJWT(t *testing.T, token string, pub *rsa.PublicKey, appID int64) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT: %q", token)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("JWT signature invalid: %v", err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]int64
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != appID || claims["exp"] <= claims["iat"] {
+		t.Fatalf("unexpected JWT claims: %v", claims)
+	}
+}
+
+func TestAppAuth_MintsAndRefreshesInstallationToken(t *testing.T) {
+	keyPath, key := writeTestAppKey(t)
+
+	var minted int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/42/access_tokens":
+			verifyAppJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey, 7)
+			n := atomic.AddInt32(&minted, 1)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, n, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case "/repos/org/repo/pulls/1":
+			w.Write([]byte(`{"number": 1, "state": "open", "title": ` + fmt.Sprintf("%q", r.Header.Get("Authorization")) + `}`))
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	auth, err := LoadAppAuth(7, 42, keyPath)
+	if err != nil {
+		t.Fatalf("LoadAppAuth: %v", err)
+	}
+	client := newTestClient(server.URL)
+	client.App = auth
+
+	for i := 0; i < 2; i++ {
+		pr, err := client.GetPRStatus(context.Background(), "org", "repo", 1)
+		if err != nil {
+			t.Fatalf("GetPRStatus: %v", err)
+		}
+		if pr.Title != "Bearer ghs_1" {
+			t.Errorf("expected cached installation token, got %q", pr.Title)
+		}
+	}
+
+	// A token close to expiry is replaced before use.
+	auth.expiresAt = time.Now().Add(time.Minute)
+	token, err := client.GetToken(context.Background())
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if token != "ghs_2" || atomic.LoadInt32(&minted) != 2 {
+		t.Errorf("expected a refreshed token, got %q after %d mints", token, minted)
+	}
+}
+
+func TestAppAuth_MintFailure(t *testing.T) {
+	keyPath, _ := writeTestAppKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "bad credentials"}`))
+	}))
+	defer server.Close()
+
+	auth, err := LoadAppAuth(7, 42, keyPath)
+	if err != nil {
+		t.Fatalf("LoadAppAuth: %v", err)
+	}
+	client := newTestClient(server.URL)
+	client.App = auth
+
+	_, err = client.GetPRStatus(context.Background(), "org", "repo", 1)
+	if err == nil || !strings.Contains(err.Error(), "GitHub App auth failed") || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("expected App auth error, got %v", err)
+	}
+}
+
+func TestLoadAppAuth_InvalidKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.pem")
+	os.WriteFile(path, []byte("not a key"), 0600)
+	if _, err := LoadAppAuth(1, 2, path); err == nil {
+		t.Error("expected error for non-PEM key")
+	}
+	if _, err := LoadAppAuth(1, 2, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("expected error for missing key file")
+	}
+}
+
+func TestSharedAppAuth_ReusedUntilKeyChanges(t *testing.T) {
+	keyPath, _ := writeTestAppKey(t)
+
+	first, err := SharedAppAuth(7, 42, keyPath)
+	if err != nil {
+		t.Fatalf("SharedAppAuth: %v", err)
+	}
+	if again, err := SharedAppAuth(7, 42, keyPath); err != nil || again != first {
+		t.Errorf("expected the same AppAuth for the same installation and key, got %p, %v", again, err)
+	}
+	if other, err := SharedAppAuth(7, 43, keyPath); err != nil || other == first {
+		t.Errorf("expected another AppAuth for another installation, got %p, %v", other, err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(keyPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if rotated, err := SharedAppAuth(7, 42, keyPath); err != nil || rotated == first || !rotated.PrivateKey.Equal(key) {
+		t.Errorf("expected a new AppAuth after the key file changed, got %p, %v", rotated, err)
+	}
+}
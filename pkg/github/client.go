@@ -1,10 +1,14 @@
 package github
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"strings"
 	"time"
@@ -14,20 +18,38 @@ import (
 
 const defaultPollInterval = 30 * time.Second
 
+// DefaultRequestTimeout bounds a single GitHub API request. It is independent
+// of the poll interval used by WaitForPRStatus.
+const DefaultRequestTimeout = 30 * time.Second
+
+// DefaultAPIURL is the root of the github.com REST API.
+const DefaultAPIURL = "https://api.github.com"
+
 // Client handles interaction with the GitHub API
 type Client struct {
 	Token      string
+	App        *AppAuth // When set, requests use App installation tokens instead of Token
+	BaseURL    string   // API root, such as https://github.example.com/api/v3; "" for DefaultAPIURL
 	HTTPClient *http.Client
 	Logger     *logger.Logger
 }
 
 // NewClient creates a new GitHub API client
 func NewClient(token string, l *logger.Logger) *Client {
+	return NewClientWithTimeout(token, DefaultRequestTimeout, l)
+}
+
+// NewClientWithTimeout creates a GitHub API client whose individual HTTP requests
+// time out after timeout. A zero timeout uses DefaultRequestTimeout.
+func NewClientWithTimeout(token string, timeout time.Duration, l *logger.Logger) *Client {
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
 	return &Client{
 		Token:  token,
 		Logger: l,
 		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: timeout,
 			Transport: &logger.LoggingRoundTripper{
 				Wrapped: http.DefaultTransport,
 				Logger:  l,
@@ -36,6 +58,47 @@ func NewClient(token string, l *logger.Logger) *Client {
 	}
 }
 
+// NewAppClient creates a GitHub API client that authenticates as a GitHub App
+// installation. A zero timeout uses DefaultRequestTimeout.
+func NewAppClient(app *AppAuth, timeout time.Duration, l *logger.Logger) *Client {
+	c := NewClientWithTimeout("", timeout, l)
+	c.App = app
+	return c
+}
+
+// GetToken returns the token to authenticate with: an installation token when
+// App is set (minted or refreshed as needed), otherwise Token, which may be
+// empty for public repos.
+func (c *Client) GetToken(ctx context.Context) (string, error) {
+	if c.App == nil {
+		return c.Token, nil
+	}
+	token, err := c.App.installationToken(ctx, c.HTTPClient, c.apiURL(""))
+	if err != nil {
+		return "", fmt.Errorf("GitHub App auth failed: %w", err)
+	}
+	return token, nil
+}
+
+// apiURL returns the URL of the API path formatted from format and args,
+// under BaseURL.
+func (c *Client) apiURL(format string, args ...any) string {
+	base := strings.TrimSuffix(cmp.Or(c.BaseURL, DefaultAPIURL), "/")
+	return base + fmt.Sprintf(format, args...)
+}
+
+// authorize sets the Authorization header on req, if there is a token.
+func (c *Client) authorize(req *http.Request) error {
+	token, err := c.GetToken(req.Context())
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
 // PRStatus represents the state of a Pull Request
 type PRStatus struct {
 	Number         int        `json:"number"`
@@ -45,14 +108,47 @@ type PRStatus struct {
 	Title          string     `json:"title"`
 	HTMLURL        string     `json:"html_url"`
 	MergeableState string     `json:"mergeable_state"` // "clean", "behind", "blocked", "dirty", "unstable", "unknown"
+	MergeCommitSHA string     `json:"merge_commit_sha"`
 	Head           struct {
 		Ref string `json:"ref"`
 	} `json:"head"`
 }
 
+// ErrRepoNotVisible is returned by CheckRepoAccess when GitHub answers 404:
+// the repository does not exist, or it is private and the client's
+// credentials cannot see it.
+var ErrRepoNotVisible = errors.New("repository not found or not visible with these credentials")
+
+// CheckRepoAccess fetches the repository to confirm the client can see it.
+func (c *Client) CheckRepoAccess(ctx context.Context, owner, repo string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.apiURL("/repos/%s/%s", owner, repo), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := do(c.HTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s/%s: %w", owner, repo, ErrRepoNotVisible)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 // GetPRStatus fetches the current status of a Pull Request
 func (c *Client) GetPRStatus(ctx context.Context, owner, repo string, prNumber int) (*PRStatus, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", owner, repo, prNumber)
+	url := c.apiURL("/repos/%s/%s/pulls/%d", owner, repo, prNumber)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -60,11 +156,11 @@ func (c *Client) GetPRStatus(ctx context.Context, owner, repo string, prNumber i
 	}
 
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+	if err := c.authorize(req); err != nil {
+		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := do(c.HTTPClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("GitHub API request failed: %w", err)
 	}
@@ -94,7 +190,7 @@ func (c *Client) FindPRByBranch(ctx context.Context, owner, repo, branch string)
 		return nil, fmt.Errorf("branch name must be provided")
 	}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls?state=open&per_page=100", owner, repo)
+	url := c.apiURL("/repos/%s/%s/pulls?state=open&per_page=100", owner, repo)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -102,11 +198,11 @@ func (c *Client) FindPRByBranch(ctx context.Context, owner, repo, branch string)
 	}
 
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+	if err := c.authorize(req); err != nil {
+		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := do(c.HTTPClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("GitHub API request failed: %w", err)
 	}
@@ -143,7 +239,7 @@ func (c *Client) FindPRByBranch(ctx context.Context, owner, repo, branch string)
 // Uses GitHub's default merge strategy (no rebase). The endpoint returns 202 Accepted on success.
 // 422 is treated as a no-op (head already up to date).
 func (c *Client) UpdateBranch(ctx context.Context, owner, repo string, prNumber int) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d/update-branch", owner, repo, prNumber)
+	url := c.apiURL("/repos/%s/%s/pulls/%d/update-branch", owner, repo, prNumber)
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader("{}"))
 	if err != nil {
@@ -152,11 +248,11 @@ func (c *Client) UpdateBranch(ctx context.Context, owner, repo string, prNumber
 
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("Content-Type", "application/json")
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+	if err := c.authorize(req); err != nil {
+		return err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := do(c.HTTPClient, req)
 	if err != nil {
 		return fmt.Errorf("update-branch request failed: %w", err)
 	}
@@ -174,18 +270,82 @@ func (c *Client) UpdateBranch(ctx context.Context, owner, repo string, prNumber
 	}
 }
 
+// CommitStatus is the payload for a commit status. State is one of
+// "error", "failure", "pending", "success".
+type CommitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context,omitempty"`
+}
+
+// CreateStatus sets a commit status on the given SHA.
+func (c *Client) CreateStatus(ctx context.Context, owner, repo, sha string, status CommitStatus) error {
+	url := c.apiURL("/repos/%s/%s/statuses/%s", owner, repo, sha)
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode commit status: %w", err)
+	}
+
+	return c.postJSON(ctx, url, payload, "create status")
+}
+
+// CreateComment posts a comment on a PR (PRs share the issues comment API).
+func (c *Client) CreateComment(ctx context.Context, owner, repo string, prNumber int, body string) error {
+	url := c.apiURL("/repos/%s/%s/issues/%d/comments", owner, repo, prNumber)
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("failed to encode comment: %w", err)
+	}
+
+	return c.postJSON(ctx, url, payload, "create comment")
+}
+
+// postJSON POSTs payload to url and expects 201 Created.
+func (c *Client) postJSON(ctx context.Context, url string, payload []byte, action string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := do(c.HTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed (status %d): %s", action, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 // WaitForPRStatus polls until the PR reaches the target state and returns the final PR status.
 // Supported target states: "merged", "closed".
 // When autoUpdateBranch is true and target is "merged", the head branch is auto-updated
 // from the base whenever the PR is detected as "behind". An update failure aborts the wait.
 func (c *Client) WaitForPRStatus(ctx context.Context, owner, repo string, prNumber int, targetState string, pollInterval time.Duration, autoUpdateBranch bool) (*PRStatus, error) {
+	return c.WaitForPRStatusWithBackoff(ctx, owner, repo, prNumber, targetState, pollInterval, 0, autoUpdateBranch)
+}
+
+// WaitForPRStatusWithBackoff is WaitForPRStatus with a poll interval that
+// doubles after each check, up to maxInterval, with jitter so that several
+// waits on the same repository do not poll in step. A maxInterval not above
+// pollInterval polls at the fixed pollInterval, without jitter.
+func (c *Client) WaitForPRStatusWithBackoff(ctx context.Context, owner, repo string, prNumber int, targetState string, pollInterval, maxInterval time.Duration, autoUpdateBranch bool) (*PRStatus, error) {
 	if pollInterval == 0 {
 		pollInterval = defaultPollInterval
 	}
 
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
-
 	// Check immediately first
 	if done, pr, err := c.checkPRState(ctx, owner, repo, prNumber, targetState, autoUpdateBranch); err != nil {
 		return nil, err
@@ -193,11 +353,15 @@ func (c *Client) WaitForPRStatus(ctx context.Context, owner, repo string, prNumb
 		return pr, nil
 	}
 
+	interval := pollInterval
+	timer := time.NewTimer(pollDelay(interval, maxInterval > pollInterval))
+	defer timer.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
-		case <-ticker.C:
+		case <-timer.C:
 			done, pr, err := c.checkPRState(ctx, owner, repo, prNumber, targetState, autoUpdateBranch)
 			if err != nil {
 				return nil, err
@@ -205,11 +369,30 @@ func (c *Client) WaitForPRStatus(ctx context.Context, owner, repo string, prNumb
 			if done {
 				return pr, nil
 			}
-			c.Logger.Debugf("  -> PR #%d: still waiting for state %q...", prNumber, targetState)
+			if maxInterval > pollInterval {
+				interval = min(interval*2, maxInterval)
+			}
+			delay := pollDelay(interval, maxInterval > pollInterval)
+			c.Logger.Debugf("  -> PR #%d: still waiting for state %q, next check in %s...", prNumber, targetState, delay.Round(time.Second))
+			timer.Reset(delay)
 		}
 	}
 }
 
+// pollJitter is the fraction of the interval a backed-off poll is moved by,
+// either way.
+const pollJitter = 0.2
+
+// pollDelay returns interval, moved by up to pollJitter of it at random when
+// jitter is set.
+func pollDelay(interval time.Duration, jitter bool) time.Duration {
+	if !jitter {
+		return interval
+	}
+	spread := float64(interval) * pollJitter
+	return interval + time.Duration(spread*(2*rand.Float64()-1))
+}
+
 // checkPRState checks if PR has reached target state.
 // If autoUpdateBranch is true and the PR is behind base, triggers update-branch first.
 func (c *Client) checkPRState(ctx context.Context, owner, repo string, prNumber int, targetState string, autoUpdateBranch bool) (bool, *PRStatus, error) {
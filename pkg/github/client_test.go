@@ -2,6 +2,7 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -231,3 +232,98 @@ func TestWaitForPRStatus_AutoUpdateFailureAborts(t *testing.T) {
 		t.Fatalf("expected auto-update error, got %v", err)
 	}
 }
+
+func TestCreateStatus(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/org/repo/statuses/abc123" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	err := client.CreateStatus(context.Background(), "org", "repo", "abc123", CommitStatus{
+		State:       "success",
+		Description: "Deployed",
+		Context:     "jenkins-flow/deploy",
+	})
+	if err != nil {
+		t.Fatalf("CreateStatus returned error: %v", err)
+	}
+	if got["state"] != "success" || got["context"] != "jenkins-flow/deploy" || got["description"] != "Deployed" {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+}
+
+func TestCreateComment_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/org/repo/issues/5/comments" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	err := client.CreateComment(context.Background(), "org", "repo", 5, "done")
+	if err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
+}
+
+func TestNewClientWithTimeout(t *testing.T) {
+	l := logger.New(logger.Error)
+	if got := NewClient("", l).HTTPClient.Timeout; got != DefaultRequestTimeout {
+		t.Errorf("expected default timeout %s, got %s", DefaultRequestTimeout, got)
+	}
+	if got := NewClientWithTimeout("", 2*time.Minute, l).HTTPClient.Timeout; got != 2*time.Minute {
+		t.Errorf("expected 2m timeout, got %s", got)
+	}
+	if got := NewClientWithTimeout("", 0, l).HTTPClient.Timeout; got != DefaultRequestTimeout {
+		t.Errorf("expected zero timeout to fall back to default, got %s", got)
+	}
+}
+
+func TestWaitForPRStatusWithBackoff(t *testing.T) {
+	var getCalls int32
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&getCalls, 1)
+		times = append(times, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		if n < 4 {
+			w.Write([]byte(`{"number":9,"state":"open","merged":false,"mergeable_state":"clean"}`))
+		} else {
+			w.Write([]byte(`{"number":9,"state":"closed","merged":true,"mergeable_state":"clean"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if _, err := client.WaitForPRStatusWithBackoff(context.Background(), "org", "repo", 9, "merged", 20*time.Millisecond, 50*time.Millisecond, false); err != nil {
+		t.Fatalf("WaitForPRStatusWithBackoff returned error: %v", err)
+	}
+	if len(times) != 4 {
+		t.Fatalf("expected 4 checks, got %d", len(times))
+	}
+	// Delays are 20ms, 40ms, then capped at 50ms, each within 20% jitter.
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond} {
+		if got := times[i+1].Sub(times[i]); got < want*8/10 {
+			t.Errorf("delay before check %d = %s, want at least %s", i+2, got, want*8/10)
+		}
+	}
+}
+
+func TestPollDelay(t *testing.T) {
+	if got := pollDelay(time.Second, false); got != time.Second {
+		t.Errorf("expected no jitter, got %s", got)
+	}
+	for range 100 {
+		if got := pollDelay(time.Second, true); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jittered delay %s outside 20%% of 1s", got)
+		}
+	}
+}
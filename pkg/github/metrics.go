@@ -0,0 +1,48 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Process-wide GitHub API usage, shared by every Client: clients are created
+// per workflow run, while the rate limit belongs to the credentials.
+var (
+	apiCalls           atomic.Int64
+	rateLimitRemaining atomic.Int64
+	rateLimitSeen      atomic.Bool
+)
+
+// Metrics is a snapshot of the GitHub API usage of the process.
+type Metrics struct {
+	APICalls int64 // Requests sent, including failed ones and App token requests
+	// RateLimitRemaining is the X-RateLimit-Remaining of the latest response
+	// that carried one; valid only when RateLimitKnown is set.
+	RateLimitRemaining int64
+	RateLimitKnown     bool
+}
+
+// CurrentMetrics returns the GitHub API usage so far.
+func CurrentMetrics() Metrics {
+	return Metrics{
+		APICalls:           apiCalls.Load(),
+		RateLimitRemaining: rateLimitRemaining.Load(),
+		RateLimitKnown:     rateLimitSeen.Load(),
+	}
+}
+
+// do sends req through httpClient, counting it and recording the rate limit
+// remaining from the response.
+func do(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	apiCalls.Add(1)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if v, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		rateLimitRemaining.Store(v)
+		rateLimitSeen.Store(true)
+	}
+	return resp, nil
+}
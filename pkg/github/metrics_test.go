@@ -0,0 +1,47 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	var noHeader atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !noHeader.Load() {
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+		}
+		switch r.URL.Path {
+		case "/repos/org/repo/pulls/7":
+			w.Write([]byte(`{"number": 7, "state": "open"}`))
+		case "/repos/org/repo/statuses/abc":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+	before := CurrentMetrics()
+
+	if _, err := client.GetPRStatus(context.Background(), "org", "repo", 7); err != nil {
+		t.Fatal(err)
+	}
+	m := CurrentMetrics()
+	if m.APICalls != before.APICalls+1 || !m.RateLimitKnown || m.RateLimitRemaining != 4999 {
+		t.Errorf("expected one call and 4999 remaining, got %+v", m)
+	}
+
+	// A failed request still counts, and a response without the header keeps
+	// the last value.
+	noHeader.Store(true)
+	client.CreateStatus(context.Background(), "org", "repo", "abc", CommitStatus{State: "success"})
+	client.CheckRepoAccess(context.Background(), "org", "missing")
+	m = CurrentMetrics()
+	if m.APICalls != before.APICalls+3 || m.RateLimitRemaining != 4999 {
+		t.Errorf("expected three calls and 4999 remaining, got %+v", m)
+	}
+}
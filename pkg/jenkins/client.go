@@ -1,12 +1,16 @@
 package jenkins
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"strings"
 	"time"
 
@@ -19,6 +23,71 @@ type Client struct {
 	AuthToken  string // Can be "user:token" or just "token" (for Bearer)
 	HTTPClient *http.Client
 	Logger     *logger.Logger
+
+	// BuildTimeout caps how long WaitForBuild and WaitForJobIdle wait for a
+	// running build; 0 waits indefinitely. Time spent in the queue does not
+	// count.
+	BuildTimeout time.Duration
+
+	// QueueStuckGrace is how long WaitForQueue waits on a queue item Jenkins
+	// flags as stuck before cancelling it; 0 waits indefinitely.
+	QueueStuckGrace time.Duration
+
+	// PollInterval is how often WaitForQueue, WaitForBuild and WaitForJobIdle
+	// check on Jenkins; 0 checks queue items every 2s and builds every 5s.
+	PollInterval time.Duration
+
+	// TokenSource re-resolves AuthToken when a poll is rejected with 401 or
+	// 403, e.g. because the token was rotated mid-run. Nil disables the retry.
+	TokenSource func() (string, error)
+
+	// Headers are extra headers sent with every request, e.g. a key required
+	// by an API gateway in front of Jenkins. Authorization is always set from
+	// AuthToken.
+	Headers map[string]string
+
+	crumbs bool // Send a CSRF crumb with POST requests; see EnableCrumbs
+}
+
+// ErrBuildTimeout is returned (wrapped) by WaitForBuild when a build is still
+// running after Client.BuildTimeout. The build is aborted in Jenkins. It is
+// also returned by WaitForJobIdle, which leaves the build running.
+var ErrBuildTimeout = errors.New("build exceeded max wait")
+
+// ErrQueueStuck is returned (wrapped) by WaitForQueue when a queue item stays
+// stuck for Client.QueueStuckGrace. The item is cancelled in Jenkins.
+var ErrQueueStuck = errors.New("job stuck in queue")
+
+// ErrJobDisabled is returned (wrapped) by WaitForQueue when the queue item
+// cannot start because its job is disabled. The item is cancelled in Jenkins.
+var ErrJobDisabled = errors.New("job is disabled")
+
+// disabledPolls is how many consecutive polls must find a queue item held
+// back by a disabled job before WaitForQueue gives up on it, so a job that is
+// re-enabled right away still gets its build.
+const disabledPolls = 3
+
+// QueueStatus holds the flags Jenkins reports for a queue item that has not
+// started yet.
+type QueueStatus struct {
+	Blocked   bool // Held back, e.g. by a running build of a job that does not allow concurrent builds
+	Buildable bool // Ready to run and waiting for an executor
+	Stuck     bool // Buildable, but no executor has been able to take it for a long time
+}
+
+// String returns the most telling flag: "stuck", "blocked" or "buildable",
+// or "" when none is set.
+func (s QueueStatus) String() string {
+	switch {
+	case s.Stuck:
+		return "stuck"
+	case s.Blocked:
+		return "blocked"
+	case s.Buildable:
+		return "buildable"
+	default:
+		return ""
+	}
 }
 
 // NewClient creates a newly configured Jenkins client
@@ -38,8 +107,65 @@ func NewClient(baseURL, authToken string, l *logger.Logger) *Client {
 	}
 }
 
-// Helper to add authentication headers
+// EnableCrumbs makes the client fetch a CSRF crumb from Jenkins' crumb issuer
+// before each POST and send it in the header Jenkins names. It also gives the
+// client a cookie jar: Jenkins ties crumbs to the web session, and behind some
+// reverse proxies the POST is only accepted with the session cookie set by the
+// crumb request.
+func (c *Client) EnableCrumbs() {
+	c.crumbs = true
+	if c.HTTPClient.Jar == nil {
+		jar, _ := cookiejar.New(nil) // Only fails for invalid options
+		c.HTTPClient.Jar = jar
+	}
+}
+
+// addCrumb adds a CSRF crumb to req when crumbs are enabled. Jenkins without
+// CSRF protection has no crumb issuer; the request is then sent without one.
+func (c *Client) addCrumb(ctx context.Context, req *http.Request) error {
+	if !c.crumbs {
+		return nil
+	}
+	resp, err := c.get(ctx, c.BaseURL+"/crumbIssuer/api/json")
+	if err != nil {
+		return c.redactf("crumb request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		c.Logger.Debugf("Jenkins has no crumb issuer; sending %s without a crumb", req.URL.Path)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return c.redactf("crumb request status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var crumb struct {
+		Crumb             string `json:"crumb"`
+		CrumbRequestField string `json:"crumbRequestField"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&crumb); err != nil {
+		return fmt.Errorf("failed to decode crumb: %w", err)
+	}
+	if crumb.Crumb == "" || crumb.CrumbRequestField == "" {
+		return fmt.Errorf("crumb issuer returned no crumb")
+	}
+	c.Logger.RegisterSecret(crumb.Crumb)
+	req.Header.Set(crumb.CrumbRequestField, crumb.Crumb)
+	return nil
+}
+
+// redactf formats an error like fmt.Errorf, masking credentials that request
+// URLs or response bodies may carry; see logger.Logger.Redact.
+func (c *Client) redactf(format string, args ...interface{}) error {
+	return c.Logger.RedactError(fmt.Errorf(format, args...))
+}
+
+// Helper to add authentication and custom headers
 func (c *Client) addAuth(req *http.Request) {
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
 	if strings.Contains(c.AuthToken, ":") {
 		// Basic Auth (User:APIToken)
 		auth := base64.StdEncoding.EncodeToString([]byte(c.AuthToken))
@@ -50,9 +176,43 @@ func (c *Client) addAuth(req *http.Request) {
 	}
 }
 
+// pollGet issues an authenticated GET for a polling loop. If Jenkins rejects
+// it with 401 or 403 and a TokenSource is set, the token is resolved again and
+// the request retried once before the response is returned to the caller.
+func (c *Client) pollGet(ctx context.Context, url string) (*http.Response, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil || c.TokenSource == nil {
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	token, err := c.TokenSource()
+	if err != nil {
+		return nil, fmt.Errorf("status %d; re-resolving token failed: %w", resp.StatusCode, err)
+	}
+	c.Logger.Infof("Jenkins rejected credentials with status %d; retrying with a re-resolved token", resp.StatusCode)
+	c.Logger.RegisterSecret(token)
+	c.AuthToken = token
+	return c.get(ctx, url)
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addAuth(req)
+	return c.HTTPClient.Do(req)
+}
+
 // TriggerJob starts a job and returns the Queue Item URL
 // If params is non-empty, uses /buildWithParameters endpoint
-func (c *Client) TriggerJob(ctx context.Context, jobPath string, params map[string]string) (string, error) {
+// A non-empty buildToken, the job's "Trigger builds remotely" token, is sent
+// as the token query param in addition to the Authorization header.
+func (c *Client) TriggerJob(ctx context.Context, jobPath string, params map[string]string, buildToken string) (string, error) {
 	if !strings.HasPrefix(jobPath, "/") {
 		jobPath = "/" + jobPath
 	}
@@ -69,25 +229,29 @@ func (c *Client) TriggerJob(ctx context.Context, jobPath string, params map[stri
 		return "", err
 	}
 	c.addAuth(req)
+	if err := c.addCrumb(ctx, req); err != nil {
+		return "", err
+	}
 
-	// Add parameters as query string
-	if len(params) > 0 {
-		q := req.URL.Query()
-		for k, v := range params {
-			q.Add(k, v)
-		}
-		req.URL.RawQuery = q.Encode()
+	// Add parameters and the build token as query string
+	q := req.URL.Query()
+	for k, v := range params {
+		q.Add(k, v)
 	}
+	if buildToken != "" {
+		q.Set("token", buildToken)
+	}
+	req.URL.RawQuery = q.Encode()
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("trigger job request failed: %w", err)
+		return "", c.redactf("trigger job request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("trigger failed with status %d: %s", resp.StatusCode, string(body))
+		return "", c.redactf("trigger failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	queueItemURL := resp.Header.Get("Location")
@@ -100,9 +264,21 @@ func (c *Client) TriggerJob(ctx context.Context, jobPath string, params map[stri
 
 // WaitForQueue waits for a queue item to become a build and returns the Build URL
 func (c *Client) WaitForQueue(ctx context.Context, queueItemURL string) (string, error) {
-	ticker := time.NewTicker(2 * time.Second)
+	return c.WaitForQueueStatus(ctx, queueItemURL, nil)
+}
+
+// WaitForQueueStatus is like WaitForQueue, and also calls onStatus, if not
+// nil, with the item's flags on the first poll and whenever they change. An
+// item that stays stuck for c.QueueStuckGrace is cancelled and an error
+// wrapping ErrQueueStuck is returned; one that cannot start because its job
+// is disabled is cancelled after disabledPolls polls with ErrJobDisabled.
+func (c *Client) WaitForQueueStatus(ctx context.Context, queueItemURL string, onStatus func(QueueStatus)) (string, error) {
+	ticker := time.NewTicker(cmp.Or(c.PollInterval, 2*time.Second))
 	defer ticker.Stop()
 
+	var last *QueueStatus
+	var stuckSince time.Time
+	var disabled int
 	for {
 		select {
 		case <-ctx.Done():
@@ -114,15 +290,9 @@ func (c *Client) WaitForQueue(ctx context.Context, queueItemURL string) (string,
 				qURL += "/"
 			}
 
-			req, err := http.NewRequestWithContext(ctx, "GET", qURL+"api/json", nil)
+			resp, err := c.pollGet(ctx, qURL+"api/json")
 			if err != nil {
-				return "", err
-			}
-			c.addAuth(req)
-
-			resp, err := c.HTTPClient.Do(req)
-			if err != nil {
-				return "", fmt.Errorf("poll queue request failed: %w", err)
+				return "", c.redactf("poll queue request failed: %w", err)
 			}
 
 			if resp.StatusCode == http.StatusNotFound {
@@ -135,14 +305,19 @@ func (c *Client) WaitForQueue(ctx context.Context, queueItemURL string) (string,
 			if resp.StatusCode != 200 {
 				body, _ := io.ReadAll(resp.Body)
 				resp.Body.Close()
-				return "", fmt.Errorf("poll queue status %d: %s", resp.StatusCode, string(body))
+				return "", c.redactf("poll queue status %d: %s", resp.StatusCode, string(body))
 			}
 
 			var result struct {
+				ID         int `json:"id"`
 				Executable struct {
 					URL string `json:"url"`
 				} `json:"executable"`
-				Cancelled bool `json:"cancelled"`
+				Cancelled bool   `json:"cancelled"`
+				Blocked   bool   `json:"blocked"`
+				Buildable bool   `json:"buildable"`
+				Stuck     bool   `json:"stuck"`
+				Why       string `json:"why"`
 			}
 
 			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -158,59 +333,571 @@ func (c *Client) WaitForQueue(ctx context.Context, queueItemURL string) (string,
 			if result.Executable.URL != "" {
 				return result.Executable.URL, nil
 			}
+
 			// Still waiting in queue...
+			status := QueueStatus{Blocked: result.Blocked, Buildable: result.Buildable, Stuck: result.Stuck}
+			if onStatus != nil && (last == nil || *last != status) {
+				onStatus(status)
+			}
+			last = &status
+			if !result.Buildable && strings.Contains(strings.ToLower(result.Why), "disabled") {
+				if disabled++; disabled >= disabledPolls {
+					if err := c.CancelQueueItem(ctx, result.ID); err != nil {
+						return "", fmt.Errorf("%w: %s (cancelling the queue item failed: %v)", ErrJobDisabled, result.Why, err)
+					}
+					return "", fmt.Errorf("%w: %s; queue item cancelled", ErrJobDisabled, result.Why)
+				}
+			} else {
+				disabled = 0
+			}
+			if !status.Stuck {
+				stuckSince = time.Time{}
+				continue
+			}
+			if stuckSince.IsZero() {
+				stuckSince = time.Now()
+			}
+			if c.QueueStuckGrace > 0 && time.Since(stuckSince) >= c.QueueStuckGrace {
+				if err := c.CancelQueueItem(ctx, result.ID); err != nil {
+					return "", fmt.Errorf("%w for %s (cancelling it failed: %v)", ErrQueueStuck, c.QueueStuckGrace, err)
+				}
+				return "", fmt.Errorf("%w for %s; queue item cancelled", ErrQueueStuck, c.QueueStuckGrace)
+			}
 		}
 	}
 }
 
 // WaitForBuild waits for the build to complete and returns the Result (e.g., SUCCESS, FAILURE)
 // along with the Jenkins build number.
+// If c.BuildTimeout is set and the build is still running when it elapses, the
+// build is aborted and an error wrapping ErrBuildTimeout is returned.
 func (c *Client) WaitForBuild(ctx context.Context, buildURL string) (string, int, error) {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(cmp.Or(c.PollInterval, 5*time.Second))
 	defer ticker.Stop()
 
-	if !strings.HasSuffix(buildURL, "/") {
-		buildURL += "/"
+	var timeout <-chan time.Time
+	if c.BuildTimeout > 0 {
+		timer := time.NewTimer(c.BuildTimeout)
+		defer timer.Stop()
+		timeout = timer.C
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return "", 0, ctx.Err()
+		case <-timeout:
+			if err := c.StopBuild(ctx, buildURL); err != nil {
+				return "", 0, fmt.Errorf("%w of %s (aborting it failed: %v)", ErrBuildTimeout, c.BuildTimeout, err)
+			}
+			return "", 0, fmt.Errorf("%w of %s; build aborted", ErrBuildTimeout, c.BuildTimeout)
 		case <-ticker.C:
-			req, err := http.NewRequestWithContext(ctx, "GET", buildURL+"api/json", nil)
+			building, result, number, err := c.buildStatus(ctx, buildURL)
 			if err != nil {
 				return "", 0, err
 			}
-			c.addAuth(req)
+			if !building {
+				return result, number, nil
+			}
+			// Still building...
+		}
+	}
+}
+
+// ReattachBuild resumes monitoring a build started by an earlier process. It
+// checks the build immediately, so a build that finished while nobody was
+// watching returns at once, and otherwise continues as WaitForBuild.
+func (c *Client) ReattachBuild(ctx context.Context, buildURL string) (string, int, error) {
+	building, result, number, err := c.buildStatus(ctx, buildURL)
+	if err != nil {
+		return "", 0, fmt.Errorf("reattach to %s: %w", buildURL, err)
+	}
+	if !building {
+		return result, number, nil
+	}
+	return c.WaitForBuild(ctx, buildURL)
+}
+
+// JobExists reports whether jobPath names a job, by fetching its api/json.
+// A 404 means it does not; any other failure is returned as an error.
+func (c *Client) JobExists(ctx context.Context, jobPath string) (bool, error) {
+	if !strings.HasPrefix(jobPath, "/") {
+		jobPath = "/" + jobPath
+	}
+
+	resp, err := c.get(ctx, c.BaseURL+strings.TrimRight(jobPath, "/")+"/api/json?tree=name")
+	if err != nil {
+		return false, c.redactf("job check request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, c.redactf("job check status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// ErrJobNotFound is returned (wrapped) by GetJobParameters when Jenkins has no
+// job at the path.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobParameter is a parameter a job declares.
+type JobParameter struct {
+	Name       string
+	Type       string // Jenkins' class name, e.g. StringParameterDefinition
+	HasDefault bool   // False when Jenkins reports no default value, e.g. for file parameters
+}
+
+// GetJobParameters fetches the parameters jobPath declares, from its
+// api/json. A job without parameters returns none; one that does not exist
+// returns an error wrapping ErrJobNotFound.
+func (c *Client) GetJobParameters(ctx context.Context, jobPath string) ([]JobParameter, error) {
+	if !strings.HasPrefix(jobPath, "/") {
+		jobPath = "/" + jobPath
+	}
+
+	resp, err := c.get(ctx, c.BaseURL+strings.TrimRight(jobPath, "/")+"/api/json?tree=property[parameterDefinitions[name,type,defaultParameterValue[value]]]")
+	if err != nil {
+		return nil, c.redactf("job parameters request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-			resp, err := c.HTTPClient.Do(req)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobPath)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, c.redactf("job parameters status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var job struct {
+		Property []struct {
+			ParameterDefinitions []struct {
+				Name                  string           `json:"name"`
+				Type                  string           `json:"type"`
+				DefaultParameterValue *json.RawMessage `json:"defaultParameterValue"`
+			} `json:"parameterDefinitions"`
+		} `json:"property"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode job json: %w", err)
+	}
+	// Parameters are one of the job's properties; the others decode empty.
+	var params []JobParameter
+	for _, prop := range job.Property {
+		for _, def := range prop.ParameterDefinitions {
+			params = append(params, JobParameter{
+				Name:       def.Name,
+				Type:       def.Type,
+				HasDefault: def.DefaultParameterValue != nil,
+			})
+		}
+	}
+	return params, nil
+}
+
+// BuildStatus is the state of one build of a job.
+type BuildStatus struct {
+	Number   int
+	URL      string
+	Building bool
+	Result   string // Empty while building
+}
+
+// LastBuildStatus fetches the most recent build of jobPath from its
+// lastBuild/api/json. It returns nil when the job has never been built.
+func (c *Client) LastBuildStatus(ctx context.Context, jobPath string) (*BuildStatus, error) {
+	if !strings.HasPrefix(jobPath, "/") {
+		jobPath = "/" + jobPath
+	}
+
+	resp, err := c.pollGet(ctx, c.BaseURL+strings.TrimRight(jobPath, "/")+"/lastBuild/api/json?tree=number,url,building,result")
+	if err != nil {
+		return nil, c.redactf("last build request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, c.redactf("last build status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var build struct {
+		Number   int    `json:"number"`
+		URL      string `json:"url"`
+		Building bool   `json:"building"`
+		Result   string `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&build); err != nil {
+		return nil, fmt.Errorf("failed to decode build json: %w", err)
+	}
+	return &BuildStatus{Number: build.Number, URL: build.URL, Building: build.Building, Result: build.Result}, nil
+}
+
+// WaitForJobIdle waits until the most recent build of jobPath is no longer
+// running. Unlike WaitForBuild it never aborts the build it waits for: after
+// c.BuildTimeout it gives up with an error wrapping ErrBuildTimeout.
+func (c *Client) WaitForJobIdle(ctx context.Context, jobPath string) error {
+	ticker := time.NewTicker(cmp.Or(c.PollInterval, 5*time.Second))
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time
+	if c.BuildTimeout > 0 {
+		timer := time.NewTimer(c.BuildTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("%w of %s; the running build was left running", ErrBuildTimeout, c.BuildTimeout)
+		case <-ticker.C:
+			last, err := c.LastBuildStatus(ctx, jobPath)
 			if err != nil {
-				return "", 0, fmt.Errorf("poll build request failed: %w", err)
+				return err
 			}
-
-			if resp.StatusCode != 200 {
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				return "", 0, fmt.Errorf("poll build status %d: %s", resp.StatusCode, string(body))
+			if last == nil || !last.Building {
+				return nil
 			}
+		}
+	}
+}
 
-			var result struct {
-				Building bool   `json:"building"`
-				Result   string `json:"result"`
-				Number   int    `json:"number"`
-			}
+// CancelQueueItem asks Jenkins to remove queue item id from the queue.
+func (c *Client) CancelQueueItem(ctx context.Context, id int) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/queue/cancelItem?id=%d", c.BaseURL, id), nil)
+	if err != nil {
+		return err
+	}
+	c.addAuth(req)
+	if err := c.addCrumb(ctx, req); err != nil {
+		return err
+	}
 
-			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-				resp.Body.Close()
-				return "", 0, fmt.Errorf("failed to decode build json: %w", err)
-			}
-			resp.Body.Close()
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.redactf("cancel queue item request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Like stop, Jenkins answers with a redirect; older versions with a 404
+	// even though the item was cancelled.
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return c.redactf("cancel queue item status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// StopBuild asks Jenkins to abort a running build.
+func (c *Client) StopBuild(ctx context.Context, buildURL string) error {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", buildURL+"stop", nil)
+	if err != nil {
+		return err
+	}
+	c.addAuth(req)
+	if err := c.addCrumb(ctx, req); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.redactf("stop build request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-			if !result.Building {
-				return result.Result, result.Number, nil
+	// Jenkins answers a successful stop with a redirect to the build page.
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return c.redactf("stop build status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// AbortCause returns who aborted a build, taken from the user interruption
+// recorded in its actions. It returns "" when Jenkins does not say, e.g. for a
+// build aborted by a timeout or by the system.
+func (c *Client) AbortCause(ctx context.Context, buildURL string) (string, error) {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", buildURL+"api/json?tree=actions[causes[userId,userName]]", nil)
+	if err != nil {
+		return "", err
+	}
+	c.addAuth(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", c.redactf("abort cause request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", c.redactf("abort cause status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var build struct {
+		Actions []struct {
+			Causes []struct {
+				UserID   string `json:"userId"`
+				UserName string `json:"userName"`
+			} `json:"causes"`
+		} `json:"actions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&build); err != nil {
+		return "", fmt.Errorf("failed to decode build json: %w", err)
+	}
+	for _, action := range build.Actions {
+		for _, cause := range action.Causes {
+			if cause.UserName != "" {
+				return cause.UserName, nil
 			}
-			// Still building...
+			if cause.UserID != "" {
+				return cause.UserID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// TestResults summarises the JUnit report of a build.
+type TestResults struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Total returns the number of tests in the report.
+func (r TestResults) Total() int {
+	return r.Passed + r.Failed + r.Skipped
+}
+
+// GetTestResults fetches the pass, fail and skip counts of a build's test
+// report from its testReport/api/json. It returns nil without an error when
+// the build has no test report. Aggregated reports, such as those of matrix
+// builds, only give a total, from which the passed count is derived.
+func (c *Client) GetTestResults(ctx context.Context, buildURL string) (*TestResults, error) {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	resp, err := c.pollGet(ctx, buildURL+"testReport/api/json?tree=passCount,failCount,skipCount,totalCount")
+	if err != nil {
+		return nil, c.redactf("test report request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, c.redactf("test report status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var report struct {
+		PassCount  *int `json:"passCount"`
+		FailCount  *int `json:"failCount"`
+		SkipCount  *int `json:"skipCount"`
+		TotalCount *int `json:"totalCount"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode test report json: %w", err)
+	}
+	if report.FailCount == nil && report.PassCount == nil && report.TotalCount == nil {
+		return nil, nil
+	}
+	value := func(n *int) int {
+		if n == nil {
+			return 0
+		}
+		return *n
+	}
+	results := &TestResults{Failed: value(report.FailCount), Skipped: value(report.SkipCount)}
+	if report.PassCount != nil {
+		results.Passed = *report.PassCount
+	} else {
+		results.Passed = max(value(report.TotalCount)-results.Failed-results.Skipped, 0)
+	}
+	return results, nil
+}
+
+// Artifact is a file a build archived.
+type Artifact struct {
+	FileName     string
+	RelativePath string // Path inside the build's archive
+	URL          string // Download link, under the build's artifact/ path
+}
+
+// maxArtifacts caps how many artifacts GetArtifacts lists for one build.
+const maxArtifacts = 1000
+
+// GetArtifacts lists the files archived by the build at buildURL, from its
+// api/json, with their download links. At most maxArtifacts are returned. A
+// build without artifacts returns none.
+func (c *Client) GetArtifacts(ctx context.Context, buildURL string) ([]Artifact, error) {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	resp, err := c.pollGet(ctx, buildURL+fmt.Sprintf("api/json?tree=artifacts[fileName,relativePath]{0,%d}", maxArtifacts))
+	if err != nil {
+		return nil, c.redactf("artifacts request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, c.redactf("artifacts status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var build struct {
+		Artifacts []struct {
+			FileName     string `json:"fileName"`
+			RelativePath string `json:"relativePath"`
+		} `json:"artifacts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&build); err != nil {
+		return nil, fmt.Errorf("failed to decode artifacts json: %w", err)
+	}
+	var artifacts []Artifact
+	for _, a := range build.Artifacts {
+		segments := strings.Split(a.RelativePath, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
 		}
+		artifacts = append(artifacts, Artifact{
+			FileName:     a.FileName,
+			RelativePath: a.RelativePath,
+			URL:          buildURL + "artifact/" + strings.Join(segments, "/"),
+		})
+	}
+	if len(artifacts) > maxArtifacts {
+		artifacts = artifacts[:maxArtifacts]
+	}
+	return artifacts, nil
+}
+
+// SubBuild is the build of one configuration of a multi-configuration
+// (matrix) build.
+type SubBuild struct {
+	Name   string // Configuration, e.g. "jdk=17,label=linux"
+	URL    string
+	Number int
+	Result string // Empty while building
+}
+
+// SubBuilds fetches the configuration builds of the matrix build at buildURL
+// from its runs. Jenkins also lists the latest build of configurations the
+// parent did not run; those, numbered differently, are left out. A build
+// without runs returns none.
+func (c *Client) SubBuilds(ctx context.Context, buildURL string) ([]SubBuild, error) {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	resp, err := c.pollGet(ctx, buildURL+"api/json?tree=number,runs[number,url,result]")
+	if err != nil {
+		return nil, c.redactf("sub-builds request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, c.redactf("sub-builds status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var build struct {
+		Number int `json:"number"`
+		Runs   []struct {
+			Number int    `json:"number"`
+			URL    string `json:"url"`
+			Result string `json:"result"`
+		} `json:"runs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&build); err != nil {
+		return nil, fmt.Errorf("failed to decode sub-builds json: %w", err)
+	}
+	var subs []SubBuild
+	for _, run := range build.Runs {
+		if run.Number != build.Number {
+			continue
+		}
+		subs = append(subs, SubBuild{Name: configurationName(run.URL), URL: run.URL, Number: run.Number, Result: run.Result})
+	}
+	return subs, nil
+}
+
+// configurationName returns the configuration segment of a matrix run URL
+// such as ".../job/app/jdk=17,label=linux/5/", or the URL if it has none.
+func configurationName(runURL string) string {
+	parts := strings.Split(strings.TrimRight(runURL, "/"), "/")
+	if len(parts) < 2 || !strings.Contains(parts[len(parts)-2], "=") {
+		return runURL
+	}
+	return parts[len(parts)-2]
+}
+
+// resultSeverity orders build results as Jenkins does when combining them.
+var resultSeverity = map[string]int{"SUCCESS": 1, "UNSTABLE": 2, "FAILURE": 3, "NOT_BUILT": 4, "ABORTED": 5}
+
+// WorstResult returns the most severe of results, as Jenkins ranks them:
+// SUCCESS, UNSTABLE, FAILURE, NOT_BUILT, ABORTED. Empty and unknown results
+// are ignored; with none left it returns "".
+func WorstResult(results ...string) string {
+	worst := ""
+	for _, result := range results {
+		if resultSeverity[result] > resultSeverity[worst] {
+			worst = result
+		}
+	}
+	return worst
+}
+
+// buildStatus fetches a build's api/json once.
+func (c *Client) buildStatus(ctx context.Context, buildURL string) (bool, string, int, error) {
+	if !strings.HasSuffix(buildURL, "/") {
+		buildURL += "/"
+	}
+
+	resp, err := c.pollGet(ctx, buildURL+"api/json")
+	if err != nil {
+		return false, "", 0, c.redactf("poll build request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return false, "", 0, c.redactf("poll build status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Building bool   `json:"building"`
+		Result   string `json:"result"`
+		Number   int    `json:"number"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, "", 0, fmt.Errorf("failed to decode build json: %w", err)
 	}
+	return result.Building, result.Result, result.Number, nil
 }
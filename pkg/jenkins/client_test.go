@@ -2,10 +2,15 @@ package jenkins
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/treaz/jenkins-flow/pkg/logger"
 )
@@ -28,3 +33,604 @@ func TestWaitForBuild_ReturnsBuildNumber(t *testing.T) {
 		t.Errorf("expected build number 1234, got %d", number)
 	}
 }
+
+func TestWaitForBuild_PollInterval(t *testing.T) {
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) < 3 {
+			fmt.Fprint(w, `{"building": true, "number": 7}`)
+			return
+		}
+		fmt.Fprint(w, `{"building": false, "result": "SUCCESS", "number": 7}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.PollInterval = 10 * time.Millisecond
+	start := time.Now()
+	if _, _, err := c.WaitForBuild(context.Background(), srv.URL+"/"); err != nil {
+		t.Fatalf("WaitForBuild failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected three polls at the configured interval, took %s", elapsed)
+	}
+}
+
+func TestClient_TriggerJobSendsBuildToken(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		got = append(got, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, "job-secret"); err != nil {
+		t.Fatalf("TriggerJob failed: %v", err)
+	}
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", map[string]string{"VERSION": "1.2"}, "job-secret"); err != nil {
+		t.Fatalf("TriggerJob with params failed: %v", err)
+	}
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, ""); err != nil {
+		t.Fatalf("TriggerJob without a token failed: %v", err)
+	}
+
+	want := []string{"/job/deploy/build?token=job-secret", "/job/deploy/buildWithParameters?VERSION=1.2&token=job-secret", "/job/deploy/build?"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected requests %q, got %q", want, got)
+	}
+}
+
+func TestClient_SendsCustomHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "gateway-secret" || r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	var out strings.Builder
+	l := logger.New(logger.Trace)
+	l.SetOutput(&out)
+	c := NewClient(srv.URL, "user:token", l)
+	c.Headers = map[string]string{"X-Api-Key": "gateway-secret", "X-Team": "payments"}
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, ""); err != nil {
+		t.Fatalf("TriggerJob failed: %v", err)
+	}
+
+	logged := out.String()
+	if strings.Contains(logged, "gateway-secret") || !strings.Contains(logged, "X-Api-Key: [REDACTED]") {
+		t.Errorf("expected X-Api-Key to be redacted in trace log, got:\n%s", logged)
+	}
+	if !strings.Contains(logged, "X-Team: payments") {
+		t.Errorf("expected non-sensitive header to be logged, got:\n%s", logged)
+	}
+}
+
+func TestTriggerJob_CrumbWithSessionCookie(t *testing.T) {
+	var crumbRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/crumbIssuer/api/json":
+			crumbRequests++
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID.abc", Value: "session-1", Path: "/"})
+			fmt.Fprint(w, `{"crumb":"crumb-for-session-1","crumbRequestField":"Jenkins-Crumb"}`)
+		case "/job/deploy/build":
+			// Like a proxy that routes by session: the crumb is only valid
+			// with the cookie it was issued with.
+			cookie, err := r.Cookie("JSESSIONID.abc")
+			if err != nil || cookie.Value != "session-1" || r.Header.Get("Jenkins-Crumb") != "crumb-for-session-1" {
+				http.Error(w, "No valid crumb was included in the request", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Location", "http://jenkins/queue/item/1/")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, ""); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("expected the trigger to be rejected without crumbs, got %v", err)
+	}
+
+	c.EnableCrumbs()
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, ""); err != nil {
+		t.Fatalf("TriggerJob failed: %v", err)
+	}
+	if crumbRequests != 1 {
+		t.Errorf("expected one crumb request, got %d", crumbRequests)
+	}
+}
+
+func TestTriggerJob_NoCrumbIssuer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/deploy/build" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.EnableCrumbs()
+	if _, err := c.TriggerJob(context.Background(), "/job/deploy", nil, ""); err != nil {
+		t.Errorf("expected Jenkins without CSRF protection to accept the trigger, got %v", err)
+	}
+}
+
+func TestJobExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/folder/job/app/api/json":
+			w.Write([]byte(`{"name": "app"}`))
+		case "/job/locked/api/json":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	tests := []struct {
+		job     string
+		want    bool
+		wantErr bool
+	}{
+		{"/job/folder/job/app/", true, false},
+		{"job/folder/job/app", true, false},
+		{"/job/typo", false, false},
+		{"/job/locked", false, true},
+	}
+	for _, tt := range tests {
+		got, err := c.JobExists(context.Background(), tt.job)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("JobExists(%q) = %v, %v; want %v, error %v", tt.job, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetJobParameters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/api/json":
+			if !strings.Contains(r.URL.Query().Get("tree"), "parameterDefinitions[") {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			w.Write([]byte(`{"property": [{"_class": "hudson.model.JobProperty"}, {"parameterDefinitions": [
+				{"name": "VERSION", "type": "StringParameterDefinition", "defaultParameterValue": {"value": ""}},
+				{"name": "ARTIFACT", "type": "FileParameterDefinition", "defaultParameterValue": null}
+			]}]}`))
+		case "/job/plain/api/json":
+			w.Write([]byte(`{"property": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	got, err := c.GetJobParameters(context.Background(), "job/deploy/")
+	if err != nil {
+		t.Fatalf("GetJobParameters failed: %v", err)
+	}
+	want := []JobParameter{
+		{Name: "VERSION", Type: "StringParameterDefinition", HasDefault: true},
+		{Name: "ARTIFACT", Type: "FileParameterDefinition", HasDefault: false},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected both parameters, got %+v", got)
+	}
+	if got, err := c.GetJobParameters(context.Background(), "/job/plain"); err != nil || len(got) != 0 {
+		t.Errorf("expected no parameters, got %+v, %v", got, err)
+	}
+	if _, err := c.GetJobParameters(context.Background(), "/job/typo"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestLastBuildStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/folder/job/app/lastBuild/api/json":
+			w.Write([]byte(`{"number": 12, "url": "http://jenkins/job/folder/job/app/12/", "building": true, "result": null}`))
+		case "/job/locked/lastBuild/api/json":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	got, err := c.LastBuildStatus(context.Background(), "job/folder/job/app/")
+	want := &BuildStatus{Number: 12, URL: "http://jenkins/job/folder/job/app/12/", Building: true}
+	if err != nil || got == nil || *got != *want {
+		t.Errorf("LastBuildStatus = %+v, %v; want %+v", got, err, want)
+	}
+	if got, err := c.LastBuildStatus(context.Background(), "/job/never-built"); got != nil || err != nil {
+		t.Errorf("expected nil for a job without builds, got %+v, %v", got, err)
+	}
+	if _, err := c.LastBuildStatus(context.Background(), "/job/locked"); err == nil {
+		t.Error("expected an error for a 403")
+	}
+}
+
+func TestTriggerJob_RedactsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "cannot schedule %s", r.URL.RequestURI())
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	_, err := c.TriggerJob(context.Background(), "/job/deploy", map[string]string{"DEPLOY_TOKEN": "s3cr3t-value"}, "")
+	if err == nil {
+		t.Fatal("expected trigger to fail")
+	}
+	if strings.Contains(err.Error(), "s3cr3t-value") || !strings.Contains(err.Error(), "DEPLOY_TOKEN=[REDACTED]") {
+		t.Errorf("expected param value to be redacted, got %q", err)
+	}
+}
+
+func TestWaitForBuild_BuildTimeoutAbortsBuild(t *testing.T) {
+	var stopped int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/job/deploy/7/stop" {
+			atomic.AddInt32(&stopped, 1)
+			return
+		}
+		fmt.Fprint(w, `{"building": true, "number": 7}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.BuildTimeout = 50 * time.Millisecond
+	_, _, err := c.WaitForBuild(context.Background(), srv.URL+"/job/deploy/7")
+	if !errors.Is(err, ErrBuildTimeout) {
+		t.Fatalf("expected ErrBuildTimeout, got %v", err)
+	}
+	if err.Error() != "build exceeded max wait of 50ms; build aborted" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if atomic.LoadInt32(&stopped) != 1 {
+		t.Errorf("expected the build to be aborted once, got %d stop requests", stopped)
+	}
+}
+
+func TestWaitForJobIdle_BuildTimeout(t *testing.T) {
+	var stopped int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&stopped, 1)
+			return
+		}
+		fmt.Fprint(w, `{"building": true, "number": 7}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.PollInterval = 10 * time.Millisecond
+	c.BuildTimeout = 50 * time.Millisecond
+	err := c.WaitForJobIdle(context.Background(), "/job/deploy")
+	if !errors.Is(err, ErrBuildTimeout) {
+		t.Fatalf("expected ErrBuildTimeout, got %v", err)
+	}
+	if atomic.LoadInt32(&stopped) != 0 {
+		t.Errorf("expected the running build to be left alone, got %d stop requests", stopped)
+	}
+}
+
+func TestWaitForQueueStatus_CancelsStuckItem(t *testing.T) {
+	var polls, cancelled int32
+	var cancelQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/queue/cancelItem" {
+			atomic.AddInt32(&cancelled, 1)
+			cancelQuery = r.URL.RawQuery
+			return
+		}
+		if atomic.AddInt32(&polls, 1) == 1 {
+			fmt.Fprint(w, `{"id": 42, "blocked": true, "buildable": false, "stuck": false}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": 42, "blocked": false, "buildable": true, "stuck": true}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.QueueStuckGrace = time.Millisecond
+	var seen []string
+	_, err := c.WaitForQueueStatus(context.Background(), srv.URL+"/queue/item/42/", func(s QueueStatus) {
+		seen = append(seen, s.String())
+	})
+	if !errors.Is(err, ErrQueueStuck) {
+		t.Fatalf("expected ErrQueueStuck, got %v", err)
+	}
+	if err.Error() != "job stuck in queue for 1ms; queue item cancelled" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 || cancelQuery != "id=42" {
+		t.Errorf("expected queue item 42 to be cancelled once, got %d requests (%q)", cancelled, cancelQuery)
+	}
+	// The repeated stuck poll is not reported again.
+	if strings.Join(seen, ",") != "blocked,stuck" {
+		t.Errorf("expected the flags to be reported as they changed, got %v", seen)
+	}
+}
+
+func TestWaitForQueueStatus_DisabledJob(t *testing.T) {
+	var polls, cancelled int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/queue/cancelItem" {
+			atomic.AddInt32(&cancelled, 1)
+			return
+		}
+		// The job is briefly disabled, re-enabled, then disabled for good.
+		if atomic.AddInt32(&polls, 1) == 3 {
+			fmt.Fprint(w, `{"id": 42, "blocked": true, "buildable": false, "why": "Waiting for next available executor"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": 42, "blocked": true, "buildable": false, "why": "Build disabled"}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	c.PollInterval = time.Millisecond
+	_, err := c.WaitForQueue(context.Background(), srv.URL+"/queue/item/42/")
+	if !errors.Is(err, ErrJobDisabled) {
+		t.Fatalf("expected ErrJobDisabled, got %v", err)
+	}
+	if err.Error() != "job is disabled: Build disabled; queue item cancelled" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	// Two disabled polls, one that is not, then disabledPolls in a row.
+	if got := atomic.LoadInt32(&polls); got != 3+disabledPolls {
+		t.Errorf("expected %d polls, got %d", 3+disabledPolls, got)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Errorf("expected the queue item to be cancelled once, got %d requests", cancelled)
+	}
+}
+
+func TestWaitForBuild_ReResolvesExpiredToken(t *testing.T) {
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			http.Error(w, "session expired", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"building": false, "result": "SUCCESS", "number": 9}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "stale", logger.New(logger.Error))
+	var resolved int32
+	c.TokenSource = func() (string, error) {
+		atomic.AddInt32(&resolved, 1)
+		return "fresh", nil
+	}
+	result, number, err := c.ReattachBuild(context.Background(), srv.URL+"/job/x/9")
+	if err != nil {
+		t.Fatalf("ReattachBuild failed: %v", err)
+	}
+	if result != "SUCCESS" || number != 9 {
+		t.Errorf("expected SUCCESS #9, got %q #%d", result, number)
+	}
+	if polls != 2 || resolved != 1 {
+		t.Errorf("expected one retry after re-resolving the token, got %d polls and %d resolutions", polls, resolved)
+	}
+}
+
+func TestWaitForBuild_RetriesExpiredTokenOnce(t *testing.T) {
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "stale", logger.New(logger.Error))
+	c.TokenSource = func() (string, error) { return "still-bad", nil }
+	_, _, err := c.ReattachBuild(context.Background(), srv.URL+"/job/x/9")
+	if err == nil {
+		t.Fatal("expected an error when the re-resolved token is rejected too")
+	}
+	if polls != 2 {
+		t.Errorf("expected exactly one retry, got %d polls", polls)
+	}
+}
+
+func TestReattachBuild_FinishedBuildReturnsImmediately(t *testing.T) {
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		fmt.Fprint(w, `{"building": false, "result": "FAILURE", "number": 77}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	start := time.Now()
+	result, number, err := c.ReattachBuild(context.Background(), srv.URL+"/job/x/77")
+	if err != nil {
+		t.Fatalf("ReattachBuild failed: %v", err)
+	}
+	if result != "FAILURE" || number != 77 {
+		t.Errorf("expected FAILURE #77, got %q #%d", result, number)
+	}
+	if polls != 1 || time.Since(start) > time.Second {
+		t.Errorf("expected a single immediate poll, got %d polls in %s", polls, time.Since(start))
+	}
+}
+
+func TestReattachBuild_MissingBuild(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	if _, _, err := c.ReattachBuild(context.Background(), srv.URL+"/job/x/1/"); err == nil {
+		t.Fatal("expected error for a build that no longer exists")
+	}
+}
+
+func TestAbortCause(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"user name", `{"actions": [{}, {"causes": [{"userId": "bob", "userName": "Bob Smith"}]}]}`, "Bob Smith"},
+		{"user id only", `{"actions": [{"causes": [{"userId": "bob"}]}]}`, "bob"},
+		{"no interruption", `{"actions": [{}]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/job/x/3/api/json" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+			got, err := c.AbortCause(context.Background(), srv.URL+"/job/x/3")
+			if err != nil {
+				t.Fatalf("AbortCause failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetTestResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   *TestResults
+	}{
+		{"junit report", http.StatusOK, `{"_class": "hudson.tasks.junit.TestResult", "passCount": 40, "failCount": 2, "skipCount": 3}`, &TestResults{Passed: 40, Failed: 2, Skipped: 3}},
+		{"aggregated report", http.StatusOK, `{"failCount": 1, "skipCount": 2, "totalCount": 10}`, &TestResults{Passed: 7, Failed: 1, Skipped: 2}},
+		{"no report", http.StatusNotFound, `Not Found`, nil},
+		{"not a report", http.StatusOK, `{"building": false, "result": "SUCCESS"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/job/x/3/testReport/api/json" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+			got, err := c.GetTestResults(context.Background(), srv.URL+"/job/x/3")
+			if err != nil {
+				t.Fatalf("GetTestResults failed: %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	if _, err := c.GetTestResults(context.Background(), srv.URL+"/job/x/3/"); err == nil || !strings.Contains(err.Error(), "test report status 500") {
+		t.Errorf("expected the status to be reported, got %v", err)
+	}
+}
+
+func TestGetArtifacts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/app/7/api/json" || !strings.Contains(r.URL.Query().Get("tree"), "artifacts[") {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"artifacts": [
+			{"fileName": "app.jar", "relativePath": "target/app.jar"},
+			{"fileName": "release notes.txt", "relativePath": "docs/release notes.txt"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	got, err := c.GetArtifacts(context.Background(), srv.URL+"/job/app/7")
+	if err != nil {
+		t.Fatalf("GetArtifacts failed: %v", err)
+	}
+	want := []Artifact{
+		{FileName: "app.jar", RelativePath: "target/app.jar", URL: srv.URL + "/job/app/7/artifact/target/app.jar"},
+		{FileName: "release notes.txt", RelativePath: "docs/release notes.txt", URL: srv.URL + "/job/app/7/artifact/docs/release%20notes.txt"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected escaped download links, got %+v", got)
+	}
+}
+
+func TestSubBuilds(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/app/7/api/json" || !strings.Contains(r.URL.Query().Get("tree"), "runs[") {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"number": 7, "runs": [
+			{"number": 7, "url": "http://jenkins/job/app/jdk=17,label=linux/7/", "result": "SUCCESS"},
+			{"number": 7, "url": "http://jenkins/job/app/jdk=17,label=windows/7/", "result": "FAILURE"},
+			{"number": 5, "url": "http://jenkins/job/app/jdk=8,label=linux/5/", "result": "FAILURE"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user:token", logger.New(logger.Error))
+	got, err := c.SubBuilds(context.Background(), srv.URL+"/job/app/7")
+	if err != nil {
+		t.Fatalf("SubBuilds failed: %v", err)
+	}
+	want := []SubBuild{
+		{Name: "jdk=17,label=linux", URL: "http://jenkins/job/app/jdk=17,label=linux/7/", Number: 7, Result: "SUCCESS"},
+		{Name: "jdk=17,label=windows", URL: "http://jenkins/job/app/jdk=17,label=windows/7/", Number: 7, Result: "FAILURE"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected the configurations of build 7 only, got %+v", got)
+	}
+}
+
+func TestWorstResult(t *testing.T) {
+	tests := []struct {
+		results []string
+		want    string
+	}{
+		{[]string{"SUCCESS", "SUCCESS"}, "SUCCESS"},
+		{[]string{"SUCCESS", "UNSTABLE", "SUCCESS"}, "UNSTABLE"},
+		{[]string{"UNSTABLE", "FAILURE"}, "FAILURE"},
+		{[]string{"FAILURE", "ABORTED"}, "ABORTED"},
+		{[]string{"SUCCESS", ""}, "SUCCESS"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := WorstResult(tt.results...); got != tt.want {
+			t.Errorf("WorstResult(%v) = %q, want %q", tt.results, got, tt.want)
+		}
+	}
+}
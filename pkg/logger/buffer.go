@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// Buffer is a thread-safe in-memory log sink holding at most max bytes.
+// Once full, the oldest lines are dropped and String notes how much was cut.
+type Buffer struct {
+	mu      sync.Mutex
+	max     int
+	buf     []byte
+	dropped int
+}
+
+// NewBuffer creates a Buffer that keeps at most max bytes.
+func NewBuffer(max int) *Buffer {
+	return &Buffer{max: max}
+}
+
+// Write appends p, dropping whole lines from the front if the buffer would
+// exceed its limit. It never fails.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		// Trim to three quarters of the limit so that a full buffer is not
+		// shifted on every write.
+		cut := len(b.buf) - b.max*3/4
+		if i := bytes.IndexByte(b.buf[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+		b.dropped += cut
+		b.buf = b.buf[:copy(b.buf, b.buf[cut:])]
+	}
+	return len(p), nil
+}
+
+// String returns the buffered text, prefixed with a truncation note if
+// earlier output was dropped.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dropped > 0 {
+		return fmt.Sprintf("[... %d bytes truncated ...]\n", b.dropped) + string(b.buf)
+	}
+	return string(b.buf)
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBuffer_Truncates(t *testing.T) {
+	b := NewBuffer(40)
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		b.Write([]byte(line))
+	}
+
+	got := b.String()
+	if !strings.HasPrefix(got, "[... ") || !strings.Contains(got, "bytes truncated ...]\n") {
+		t.Errorf("expected truncation note, got %q", got)
+	}
+	if strings.Contains(got, "first line") {
+		t.Errorf("expected oldest line to be dropped, got %q", got)
+	}
+	if !strings.HasSuffix(got, "fourth line\n") {
+		t.Errorf("expected newest line to be kept, got %q", got)
+	}
+}
+
+func TestTee_SharesLevel(t *testing.T) {
+	l := New(Info)
+	l.SetOutput(&strings.Builder{})
+	buf := NewBuffer(1 << 10)
+	tee := l.Tee(buf)
+
+	tee.Debugf("hidden")
+	l.SetLevel(Debug)
+	tee.Debugf("shown %d", 1)
+
+	got := buf.String()
+	if strings.Contains(got, "hidden") || !strings.Contains(got, "[DEBUG] ") || !strings.Contains(got, "shown 1") {
+		t.Errorf("unexpected teed output: %q", got)
+	}
+}
+
+func TestLogger_ConcurrentPrefixes(t *testing.T) {
+	l := New(Info)
+	buf := NewBuffer(1 << 20)
+	l.SetOutput(buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); l.Infof("info line") }()
+		go func() { defer wg.Done(); l.Errorf("error line") }()
+	}
+	wg.Wait()
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "[INFO] ") != strings.HasSuffix(line, "info line") {
+			t.Errorf("line has another level's prefix: %q", line)
+		}
+	}
+}
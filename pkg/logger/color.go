@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape sequences used by the color writer.
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+var (
+	// messageStartPattern finds the end of the "[LEVEL] date time file.go:N: "
+	// header the Logger writes in front of each message.
+	messageStartPattern = regexp.MustCompile(`^\[[A-Z]+\] .*?\.go:\d+: `)
+	stepStartPattern    = regexp.MustCompile(`^\[(?:Step )?\d+/\d+\] (?:Starting|Running|Posting|Waiting) `)
+	stepSuccessPattern  = regexp.MustCompile(`(?i)completed successfully|build finished with result: SUCCESS\b|^\s*✓`)
+	stepFailurePattern  = regexp.MustCompile(`(?i)build finished with result: (?:FAILURE|UNSTABLE|ABORTED)\b|^\s*✗`)
+)
+
+// colorWriter renders log lines for a terminal; see NewColorWriter.
+type colorWriter struct {
+	w io.Writer
+}
+
+// NewColorWriter returns a writer that colors the log lines written to it
+// before passing them on to w: errors and failures in red marked ✗, steps
+// starting in cyan marked ▶, steps finishing successfully in green marked ✓,
+// and debug and trace lines dimmed. Each write must be one whole log entry,
+// as the Logger makes them. Loggers teed from one writing here still get the
+// plain lines.
+func NewColorWriter(w io.Writer) io.Writer {
+	return &colorWriter{w: w}
+}
+
+func (c *colorWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, colorize(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// colorize renders one log entry with the color and symbol of its level and
+// of what its message reports.
+func colorize(entry string) string {
+	header := messageStartPattern.FindString(entry)
+	body := strings.TrimSuffix(entry[len(header):], "\n")
+
+	var color, symbol string
+	switch {
+	case strings.HasPrefix(header, "[DEBUG]"), strings.HasPrefix(header, "[TRACE]"):
+		return ansiDim + strings.TrimSuffix(entry, "\n") + ansiReset + "\n"
+	case strings.HasPrefix(header, "[ERROR]"), stepFailurePattern.MatchString(body):
+		color, symbol = ansiRed, "✗"
+	case stepSuccessPattern.MatchString(body):
+		color, symbol = ansiGreen, "✓"
+	case stepStartPattern.MatchString(body):
+		color, symbol = ansiCyan, "▶"
+	default:
+		return entry
+	}
+
+	indent := body[:len(body)-len(strings.TrimLeft(body, " "))]
+	message := body[len(indent):]
+	if !strings.HasPrefix(message, "✓") && !strings.HasPrefix(message, "✗") {
+		message = symbol + " " + message
+	}
+	return ansiDim + header + ansiReset + indent + color + message + ansiReset + "\n"
+}
+
+// ColorSupported reports whether colors written to f are rendered: f is a
+// terminal and NO_COLOR is not set.
+func ColorSupported(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColorWriter(t *testing.T) {
+	var console strings.Builder
+	l := New(Debug)
+	l.SetOutput(NewColorWriter(&console))
+	plain := NewBuffer(1 << 10)
+	tee := l.Tee(plain)
+
+	tee.Infof("[Step 1/2] Starting step %q on instance %q...", "Build", "dev")
+	tee.Infof("  -> Build finished with result: FAILURE (#4)")
+	tee.Infof("  ✓ %s: %s", "Test", "SUCCESS")
+	tee.Errorf("Workflow failed: %s", "boom")
+	tee.Infof("  -> [Build] Waiting for queue...")
+	tee.Debugf("details")
+
+	lines := strings.Split(strings.TrimSuffix(console.String(), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines, got %q", console.String())
+	}
+	for i, want := range []string{
+		ansiCyan + `▶ [Step 1/2] Starting step "Build" on instance "dev"...` + ansiReset,
+		"  " + ansiRed + "✗ -> Build finished with result: FAILURE (#4)" + ansiReset,
+		"  " + ansiGreen + "✓ Test: SUCCESS" + ansiReset,
+		ansiRed + "✗ Workflow failed: boom" + ansiReset,
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d: expected suffix %q, got %q", i, want, lines[i])
+		}
+	}
+	if strings.Contains(lines[4], "\x1b") {
+		t.Errorf("expected a plain progress line, got %q", lines[4])
+	}
+	if !strings.HasPrefix(lines[5], ansiDim+"[DEBUG] ") {
+		t.Errorf("expected a dimmed debug line, got %q", lines[5])
+	}
+
+	if got := plain.String(); strings.Contains(got, "\x1b") || strings.Contains(got, "▶") {
+		t.Errorf("expected the teed output to stay plain, got %q", got)
+	}
+}
@@ -50,42 +50,75 @@ func ParseLevel(s string) (Level, error) {
 	}
 }
 
+// levelVar is a log level shared between a Logger and the loggers teed from it
+type levelVar struct {
+	mu    sync.RWMutex
+	level Level
+}
+
 // Logger is a thread-safe leveled logger
 type Logger struct {
-	mu     sync.RWMutex
-	level  Level
-	stdLog *log.Logger
+	mu      sync.RWMutex
+	level   *levelVar
+	stdLog  *log.Logger
+	secrets *secretSet
 }
 
 // New creates a new Logger
 func New(level Level) *Logger {
 	return &Logger{
-		level:  level,
-		stdLog: log.New(os.Stderr, "", log.LstdFlags|log.Lshortfile),
+		level:   &levelVar{level: level},
+		stdLog:  log.New(os.Stderr, "", log.LstdFlags|log.Lshortfile),
+		secrets: newSecretSet(),
+	}
+}
+
+// Tee returns a Logger that writes to both l's output and w. The two share a
+// level and registered secrets, so SetLevel or RegisterSecret on either
+// applies to both.
+func (l *Logger) Tee(w io.Writer) *Logger {
+	return &Logger{
+		level:   l.level,
+		stdLog:  log.New(io.MultiWriter(l.stdLog.Writer(), w), "", l.stdLog.Flags()),
+		secrets: l.secrets,
+	}
+}
+
+// WithSecrets returns a Logger that writes to l's output at l's level but
+// registers secrets of its own, starting with none. Use one per run, so the
+// run's secrets are dropped with it.
+func (l *Logger) WithSecrets() *Logger {
+	return &Logger{
+		level:   l.level,
+		stdLog:  log.New(l.stdLog.Writer(), "", l.stdLog.Flags()),
+		secrets: newSecretSet(),
 	}
 }
 
 // SetLevel changes the log level safely
 func (l *Logger) SetLevel(level Level) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.level = level
+	l.level.mu.Lock()
+	defer l.level.mu.Unlock()
+	l.level.level = level
 }
 
 // GetLevel returns the current log level
 func (l *Logger) GetLevel() Level {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	return l.level
+	l.level.mu.RLock()
+	defer l.level.mu.RUnlock()
+	return l.level.level
 }
 
-// Output writes the log if the level is sufficient
+// Output writes the log if the level is sufficient. Secrets are redacted; see l.Redact.
 func (l *Logger) output(level Level, format string, args ...interface{}) {
 	if l.GetLevel() >= level {
 		prefix := fmt.Sprintf("[%s] ", level.String())
+		// The prefix is shared by every line, so hold mu until it is written
+		l.mu.Lock()
+		defer l.mu.Unlock()
 		// We use Output(2, ...) to skip this function and the wrapper
 		l.stdLog.SetPrefix(prefix)
-		l.stdLog.Output(3, fmt.Sprintf(format, args...))
+		l.stdLog.Output(3, l.Redact(fmt.Sprintf(format, args...)))
 	}
 }
 
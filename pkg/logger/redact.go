@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Redacted replaces secret values in logs and error messages.
+const Redacted = "[REDACTED]"
+
+// sensitiveNameWords mark header and query parameter names whose values are
+// credentials, such as Authorization, Cookie, X-Api-Key or ?token=. A name
+// matches when one of its segments, split at punctuation and camelCase humps,
+// is one of them, so api_key matches and monkey does not.
+var sensitiveNameWords = map[string]bool{
+	"auth": true, "authorization": true, "authtoken": true, "apikey": true,
+	"cookie": true, "credential": true, "credentials": true, "key": true,
+	"passwd": true, "password": true, "secret": true, "signature": true,
+	"token": true,
+}
+
+// IsSensitiveName reports whether a header or query parameter name carries a
+// credential whose value should be redacted.
+func IsSensitiveName(name string) bool {
+	for _, segment := range nameSegments(name) {
+		if sensitiveNameWords[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// nameSegments splits name into lower-case words at non-alphanumeric
+// characters and at lower-to-upper case changes: "X-ApiKey" gives x, api, key.
+func nameSegments(name string) []string {
+	var segments []string
+	start := -1
+	var prev rune
+	for i, r := range name {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case !alnum:
+			if start >= 0 {
+				segments = append(segments, strings.ToLower(name[start:i]))
+				start = -1
+			}
+		case start < 0:
+			start = i
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			segments = append(segments, strings.ToLower(name[start:i]))
+			start = i
+		}
+		prev = r
+	}
+	if start >= 0 {
+		segments = append(segments, strings.ToLower(name[start:]))
+	}
+	return segments
+}
+
+// tokenPatterns match well-known credential formats. The submatches around the
+// secret, if any, are kept so the redacted text still shows what was there.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(gh[pousr]_)[A-Za-z0-9]{20,}`),             // GitHub tokens
+	regexp.MustCompile(`\b(github_pat_)[A-Za-z0-9_]{20,}`),           // GitHub fine-grained tokens
+	regexp.MustCompile(`(hooks\.slack\.com/services/)[A-Za-z0-9/]+`), // Slack webhook URLs
+	regexp.MustCompile(`\b()11[0-9a-f]{32}\b`),                       // Jenkins API tokens
+	regexp.MustCompile(`(://[^/:@\s]+:)[^/@\s]+(@)`),                 // user:password@ in URLs
+}
+
+// queryValuePattern matches name=value pairs in URL query strings.
+var queryValuePattern = regexp.MustCompile(`([?&;]([^=&;\s?#"']+)=)([^&;\s#"']*)`)
+
+// secretSet holds the values a Logger masks; see Logger.RegisterSecret.
+type secretSet struct {
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+func newSecretSet() *secretSet {
+	return &secretSet{values: map[string]bool{}}
+}
+
+// RegisterSecret makes l mask value wherever it appears in its output and in
+// l.Redact, e.g. a token resolved at run time. For "user:token" values the
+// token part is registered too. Secrets are kept until l is dropped, so give
+// each run its own Logger with WithSecrets. Short values are masked too, even
+// where the same characters are not the secret.
+func (l *Logger) RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	candidates := []string{value}
+	if _, token, ok := strings.Cut(value, ":"); ok && token != "" {
+		candidates = append(candidates, token)
+	}
+	l.secrets.mu.Lock()
+	defer l.secrets.mu.Unlock()
+	for _, v := range candidates {
+		l.secrets.values[v] = true
+	}
+}
+
+// Redact masks the secrets registered with l, then what the package-level
+// Redact masks.
+func (l *Logger) Redact(s string) string {
+	l.secrets.mu.RLock()
+	if len(l.secrets.values) > 0 {
+		// Longest first, so a secret containing another is masked whole
+		values := make([]string, 0, len(l.secrets.values))
+		for v := range l.secrets.values {
+			values = append(values, v)
+		}
+		sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+		for _, v := range values {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	l.secrets.mu.RUnlock()
+	return Redact(s)
+}
+
+// RedactError is RedactError with l.Redact.
+func (l *Logger) RedactError(err error) error {
+	return redactError(err, l.Redact)
+}
+
+// Redact masks well-known token formats and URL query values whose names look
+// like credentials (token, api_key, ...). Values registered with a Logger are
+// only masked by that Logger; see Logger.Redact.
+func Redact(s string) string {
+	for _, p := range tokenPatterns {
+		s = p.ReplaceAllString(s, "${1}"+Redacted+"${2}")
+	}
+	if strings.Contains(s, "=") {
+		s = queryValuePattern.ReplaceAllStringFunc(s, func(m string) string {
+			parts := queryValuePattern.FindStringSubmatch(m)
+			if !IsSensitiveName(parts[2]) || parts[3] == "" {
+				return m
+			}
+			return parts[1] + Redacted
+		})
+	}
+	return s
+}
+
+// RedactError returns err with its message redacted, or err itself when there
+// is nothing to mask. The original error stays reachable with errors.Is/As.
+func RedactError(err error) error {
+	return redactError(err, Redact)
+}
+
+func redactError(err error, redact func(string) string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if redacted := redact(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
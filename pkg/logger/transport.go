@@ -52,7 +52,7 @@ func (l *LoggingRoundTripper) dumpRequest(req *http.Request) {
 
 	l.Logger.Tracef("--- Request Headers ---")
 	for k, v := range req.Header {
-		if strings.EqualFold(k, "Authorization") {
+		if IsSensitiveName(k) {
 			l.Logger.Tracef("%s: [REDACTED]", k)
 		} else {
 			l.Logger.Tracef("%s: %s", k, strings.Join(v, ", "))
@@ -74,7 +74,11 @@ func (l *LoggingRoundTripper) dumpResponse(resp *http.Response) {
 
 	l.Logger.Tracef("--- Response Headers ---")
 	for k, v := range resp.Header {
-		l.Logger.Tracef("%s: %s", k, strings.Join(v, ", "))
+		if IsSensitiveName(k) {
+			l.Logger.Tracef("%s: [REDACTED]", k)
+		} else {
+			l.Logger.Tracef("%s: %s", k, strings.Join(v, ", "))
+		}
 	}
 
 	if len(body) > 0 {
@@ -4,10 +4,20 @@ package notifier
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"strings"
 	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
 )
 
 // SlackConfig holds configuration for Slack notifications.
@@ -15,11 +25,24 @@ type SlackConfig struct {
 	WebhookURL string // Slack incoming webhook URL
 	Channel    string // Optional: override default channel
 	Username   string // Optional: bot username
+
+	// TLS settings for self-hosted Slack-compatible servers such as Mattermost
+	CACertFile         string // Optional: PEM file of CAs trusted in addition to the system's
+	InsecureSkipVerify bool   // Optional: skip certificate verification
 }
 
+// Run outcomes passed to Notify and listed in Config.NotifyOn.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailure  = "failure"
+	OutcomeUnstable = "unstable"
+	OutcomeAborted  = "aborted"
+)
+
 // Config holds the notifier configuration.
 type Config struct {
-	Slack *SlackConfig // nil if Slack is not configured
+	Slack    *SlackConfig // nil if Slack is not configured
+	NotifyOn []string     // Outcomes Notify sends for; empty means all
 }
 
 // Notifier handles sending notifications to various channels.
@@ -32,26 +55,78 @@ func New(cfg Config) *Notifier {
 	return &Notifier{config: cfg}
 }
 
-// NewFromWebhook creates a Notifier configured with the given Slack webhook URL.
-// When webhookURL is empty Slack notifications remain disabled.
-func NewFromWebhook(webhookURL string) *Notifier {
-	if webhookURL == "" {
-		return New(Config{})
+// NewFromConfig creates a Notifier for a workflow's Slack settings and
+// notify_on outcomes. When the workflow has no slack_webhook, Slack
+// notifications remain disabled and its channel and username are unused.
+func NewFromConfig(cfg *config.Config) *Notifier {
+	nc := Config{NotifyOn: cfg.NotifyOn}
+	if cfg.SlackWebhook != "" {
+		nc.Slack = &SlackConfig{
+			WebhookURL: cfg.SlackWebhook,
+			Channel:    NormalizeChannel(cfg.SlackChannel),
+			Username:   cfg.SlackUsername,
+		}
+		if cfg.SlackTLS != nil {
+			nc.Slack.CACertFile = cfg.SlackTLS.CACert
+			nc.Slack.InsecureSkipVerify = cfg.SlackTLS.InsecureSkipVerify
+		}
+	}
+	return New(nc)
+}
+
+// NormalizeChannel adds the leading "#" Slack expects to a channel name such
+// as "deploys". Names already starting with "#", and "@user" direct messages,
+// are returned as they are.
+func NormalizeChannel(channel string) string {
+	channel = strings.TrimSpace(channel)
+	if channel == "" || strings.HasPrefix(channel, "#") || strings.HasPrefix(channel, "@") {
+		return channel
 	}
-	return New(Config{Slack: &SlackConfig{WebhookURL: webhookURL}})
+	return "#" + channel
 }
 
-// Notify sends a notification through all configured channels.
+// Notify sends a notification through all configured channels, if outcome is
+// one the notifier is configured for (see ShouldNotify).
 // It sends a macOS desktop notification and optionally a Slack message.
 // Errors from notification delivery are logged but not returned to avoid
 // breaking the CLI flow.
-func (n *Notifier) Notify(success bool, title, message string) {
+func (n *Notifier) Notify(outcome, title, message string) {
+	if !n.ShouldNotify(outcome) {
+		return
+	}
+
 	// Always send macOS notification
 	sendMacOSNotification(title, message)
 
 	// Send Slack notification if configured
 	if n.config.Slack != nil {
-		sendSlackNotification(n.config.Slack, success, title, message)
+		sendSlackNotification(n.config.Slack, outcomeColor(outcome), title, message)
+	}
+}
+
+// ShouldNotify reports whether Notify sends for outcome: true when NotifyOn
+// is empty or lists it.
+func (n *Notifier) ShouldNotify(outcome string) bool {
+	if len(n.config.NotifyOn) == 0 {
+		return true
+	}
+	for _, o := range n.config.NotifyOn {
+		if o == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifySlack sends a Slack message only, without a desktop notification.
+// It is a no-op when Slack is not configured. NotifyOn does not apply.
+func (n *Notifier) NotifySlack(success bool, title, message string) {
+	if n.HasSlack() {
+		outcome := OutcomeSuccess
+		if !success {
+			outcome = OutcomeFailure
+		}
+		sendSlackNotification(n.config.Slack, outcomeColor(outcome), title, message)
 	}
 }
 
@@ -85,42 +160,113 @@ type slackAttachment struct {
 	Text  string `json:"text"`
 }
 
-// sendSlackNotification sends a notification to Slack via webhook.
-// Errors are silently ignored to prevent notification failures from breaking the CLI.
-func sendSlackNotification(cfg *SlackConfig, success bool, title, message string) {
-	color := "#36a64f" // green for success
-	if !success {
-		color = "#dc3545" // red for failure
+// outcomeColor returns the Slack attachment color for a run outcome.
+func outcomeColor(outcome string) string {
+	switch outcome {
+	case OutcomeSuccess:
+		return "#36a64f" // green
+	case OutcomeUnstable:
+		return "#ffc107" // amber
+	case OutcomeAborted:
+		return "#6c757d" // grey
+	default:
+		return "#dc3545" // red for failure
 	}
+}
 
-	msg := slackMessage{
-		Channel:  cfg.Channel,
-		Username: cfg.Username,
-		Attachments: []slackAttachment{
-			{
-				Color: color,
-				Title: title,
-				Text:  message,
-			},
-		},
+// slackHTTPClient returns the client for posting to cfg's webhook, trusting
+// cfg.CACertFile in addition to the system roots when set.
+func slackHTTPClient(cfg *SlackConfig) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if cfg.CACertFile == "" && !cfg.InsecureSkipVerify {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s contains no PEM certificates", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+	return client, nil
+}
+
+// Verify posts a short check message to the Slack webhook and returns an
+// error unless it answers with a 2xx status, so a mistyped or revoked webhook
+// is caught before a run rather than days later. It is a no-op when Slack is
+// not configured. The error never includes the webhook URL.
+func (n *Notifier) Verify() error {
+	if !n.HasSlack() {
+		return nil
 	}
+	return postSlack(n.config.Slack, slackMessage{
+		Channel:  n.config.Slack.Channel,
+		Username: n.config.Slack.Username,
+		Text:     "Jenkins Flow webhook check: notifications for this workflow will be posted here.",
+	})
+}
 
+// postSlack sends msg to cfg's webhook and reports delivery errors and
+// non-2xx answers, with the URL stripped since its path is a credential.
+func postSlack(cfg *SlackConfig, msg slackMessage) error {
 	payload, err := json.Marshal(msg)
 	if err != nil {
-		return // Silently ignore
+		return err
+	}
+	client, err := slackHTTPClient(cfg)
+	if err != nil {
+		return err
 	}
-
-	client := &http.Client{Timeout: 10 * time.Second}
 	req, err := http.NewRequest("POST", cfg.WebhookURL, bytes.NewBuffer(payload))
 	if err != nil {
-		return // Silently ignore
+		return fmt.Errorf("invalid webhook URL")
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return // Silently ignore
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
 	}
 	defer resp.Body.Close()
-	// Response is intentionally not checked - we don't want to break CLI on Slack errors
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		if reason := strings.TrimSpace(string(body)); reason != "" {
+			return fmt.Errorf("webhook returned %s: %s", resp.Status, reason)
+		}
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendSlackNotification sends a notification to Slack via webhook.
+// Errors are silently ignored to prevent notification failures from breaking the CLI.
+func sendSlackNotification(cfg *SlackConfig, color, title, message string) {
+	msg := slackMessage{
+		Channel:  cfg.Channel,
+		Username: cfg.Username,
+		Attachments: []slackAttachment{
+			{
+				Color: color,
+				Title: title,
+				Text:  message,
+			},
+		},
+	}
+	_ = postSlack(cfg, msg) // Ignore errors - we don't want to break CLI on Slack errors
 }
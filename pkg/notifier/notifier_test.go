@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+func TestNewFromConfig_ChannelAndUsername(t *testing.T) {
+	payloads := make(chan slackMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		payloads <- msg
+	}))
+	defer server.Close()
+
+	n := NewFromConfig(&config.Config{
+		SlackWebhook:  server.URL,
+		SlackChannel:  "deploys",
+		SlackUsername: "Jenkins Flow",
+	})
+	n.NotifySlack(true, "Deploy", "Succeeded")
+
+	msg := <-payloads
+	if msg.Channel != "#deploys" || msg.Username != "Jenkins Flow" {
+		t.Errorf("expected channel #deploys and username Jenkins Flow, got %q and %q", msg.Channel, msg.Username)
+	}
+
+	if NewFromConfig(&config.Config{SlackChannel: "deploys"}).HasSlack() {
+		t.Error("expected Slack to stay disabled without a webhook")
+	}
+}
+
+func TestNormalizeChannel(t *testing.T) {
+	for in, want := range map[string]string{
+		"":          "",
+		"deploys":   "#deploys",
+		"#deploys":  "#deploys",
+		" deploys ": "#deploys",
+		"@alice":    "@alice",
+	} {
+		if got := NormalizeChannel(in); got != want {
+			t.Errorf("NormalizeChannel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSlackHTTPClient_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(cfg *SlackConfig) error {
+		client, err := slackHTTPClient(cfg)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Post(cfg.WebhookURL, "application/json", nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := post(&SlackConfig{WebhookURL: server.URL}); err == nil {
+		t.Error("expected the default client to reject the private CA")
+	}
+	if err := post(&SlackConfig{WebhookURL: server.URL, CACertFile: caFile}); err != nil {
+		t.Errorf("expected the CA file to be trusted, got %v", err)
+	}
+	if err := post(&SlackConfig{WebhookURL: server.URL, InsecureSkipVerify: true}); err != nil {
+		t.Errorf("expected verification to be skipped, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil || msg.Text == "" || msg.Channel != "#deploys" {
+			t.Errorf("expected a check message to #deploys, got %+v, %v", msg, err)
+		}
+		w.WriteHeader(status)
+		if status == http.StatusNotFound {
+			w.Write([]byte("no_service"))
+		}
+	}))
+	defer server.Close()
+
+	webhook := server.URL + "/services/T000/B000/SECRET"
+	n := NewFromConfig(&config.Config{SlackWebhook: webhook, SlackChannel: "deploys"})
+	status = http.StatusOK
+	if err := n.Verify(); err != nil {
+		t.Errorf("expected a 200 to pass, got %v", err)
+	}
+	status = http.StatusNotFound
+	err := n.Verify()
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found: no_service") {
+		t.Errorf("expected the status and Slack's reason, got %v", err)
+	}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	err = NewFromConfig(&config.Config{SlackWebhook: down.URL + "/services/T000/B000/SECRET"}).Verify()
+	if err == nil {
+		t.Error("expected an unreachable webhook to fail")
+	}
+	if err != nil && strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("expected the error not to include the webhook URL: %v", err)
+	}
+
+	if err := NewFromConfig(&config.Config{}).Verify(); err != nil {
+		t.Errorf("expected no check without a webhook, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// publicAPIPaths stay reachable without an API key: the health check, for
+// load balancers and monitors, and the OpenAPI spec the Swagger UI loads.
+var publicAPIPaths = map[string]bool{
+	"/api/health":       true,
+	"/api/openapi.json": true,
+}
+
+// SetAPIKeys makes /api routes, other than publicAPIPaths, require one of keys.
+// Blank keys are ignored, and no keys leaves the API open. Call it before
+// serving.
+func (s *Server) SetAPIKeys(keys []string) {
+	s.apiKeys = nil
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			s.apiKeys = append(s.apiKeys, sha256.Sum256([]byte(key)))
+		}
+	}
+}
+
+// requireAPIKey answers 401 to /api requests that do not carry one of the
+// server's API keys, as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+// Static assets are always served.
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.apiKeys) == 0 || !strings.HasPrefix(r.URL.Path, "/api/") || publicAPIPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if id, ok := s.acceptedAPIKey(requestAPIKey(r)); ok {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyIDKey{}, id)))
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jenkins-flow"`)
+		http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+	})
+}
+
+// apiKeyIDKey is the request context key of the ID of the API key a request
+// was accepted with; see apiKeyID.
+type apiKeyIDKey struct{}
+
+// acceptedAPIKey reports whether key is one of the server's API keys, and
+// returns its ID. Keys are compared as SHA-256 digests in constant time, and
+// every key is checked, so the response time does not tell how close a guess
+// was.
+func (s *Server) acceptedAPIKey(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	match := 0
+	for _, want := range s.apiKeys {
+		match |= subtle.ConstantTimeCompare(sum[:], want[:])
+	}
+	if match != 1 {
+		return "", false
+	}
+	return apiKeyID(sum), true
+}
+
+// apiKeyID names the API key with digest sum in the audit log by the start of
+// the digest, which tells keys apart without revealing them.
+func apiKeyID(sum [sha256.Size]byte) string {
+	return "api-key:" + hex.EncodeToString(sum[:4])
+}
+
+// SetTrustedProxies makes the audit log take the user from the
+// X-Forwarded-User header of requests sent from one of addrs, each an IP
+// address or CIDR range, such as an authenticating reverse proxy. Call it
+// before serving.
+func (s *Server) SetTrustedProxies(addrs []string) error {
+	s.trustedProxies = nil
+	for _, addr := range addrs {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(addr)
+		if err != nil {
+			ip, ipErr := netip.ParseAddr(addr)
+			if ipErr != nil {
+				return fmt.Errorf("invalid trusted proxy %q: want an IP address or CIDR range", addr)
+			}
+			prefix = netip.PrefixFrom(ip, ip.BitLen())
+		}
+		s.trustedProxies = append(s.trustedProxies, prefix.Masked())
+	}
+	return nil
+}
+
+// fromTrustedProxy reports whether r was sent from a trusted proxy.
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := addrPort.Addr().Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// editorOf names who sent r for the audit log: the remote address, preceded
+// by the user a trusted proxy forwarded in X-Forwarded-User, or else by the
+// ID of the API key r was accepted with. Nothing r claims unchecked, such as
+// basic auth, is trusted.
+func (s *Server) editorOf(r *http.Request) string {
+	if user := r.Header.Get("X-Forwarded-User"); user != "" && s.fromTrustedProxy(r) {
+		return user + " (" + r.RemoteAddr + ")"
+	}
+	if id, ok := r.Context().Value(apiKeyIDKey{}).(string); ok {
+		return id + " (" + r.RemoteAddr + ")"
+	}
+	return r.RemoteAddr
+}
+
+// requestAPIKey returns the key r carries as a bearer token, or else in its
+// X-API-Key header.
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
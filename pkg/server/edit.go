@@ -0,0 +1,295 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/api"
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+// maxWorkflowFileSize caps the workflow content accepted by CreateWorkflow and
+// UpdateWorkflow.
+const maxWorkflowFileSize = 1 << 20
+
+// CreateWorkflow writes a new workflow file after validating its content
+// against the server's instances. An existing file is never replaced.
+func (s *Server) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !s.workflowEditAllowed(w) {
+		return
+	}
+
+	var req api.CreateWorkflowRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*maxWorkflowFileSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Path == "" {
+		http.Error(w, "Workflow path is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Content) > maxWorkflowFileSize {
+		http.Error(w, "Workflow content is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	workflowPath := filepath.Clean(req.Path)
+	if !s.inWorkflowDirs(workflowPath) || !s.foldersInWorkflowDirs(workflowPath) {
+		http.Error(w, "Workflow path outside allowed directories", http.StatusForbidden)
+		return
+	}
+	if !config.IsWorkflowFile(workflowPath) {
+		http.Error(w, "Workflow file names must end in .yaml, .yml or .json", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Lstat(workflowPath); err == nil {
+		http.Error(w, "A file already exists at this path", http.StatusConflict)
+		return
+	}
+
+	data := []byte(req.Content)
+	result := s.validateWorkflowContent(workflowPath, data)
+	if !result.Valid {
+		writeValidationResult(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(workflowPath), 0755); err != nil {
+		http.Error(w, "Failed to create the workflow folder: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := writeFileAtomic(workflowPath, data, 0644, true); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			http.Error(w, "A file already exists at this path", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to write the workflow file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Infof("Audit: workflow %s created by %s", workflowPath, s.editorOf(r))
+	writeValidationResult(w, http.StatusCreated, result)
+}
+
+// UpdateWorkflow replaces an existing workflow file with the raw content of
+// the request after validating it against the server's instances.
+func (s *Server) UpdateWorkflow(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.workflowEditAllowed(w) {
+		return
+	}
+	workflowPath, ok := s.workflowPathParam(w, name)
+	if !ok {
+		return
+	}
+	if !s.foldersInWorkflowDirs(workflowPath) {
+		http.Error(w, "Workflow path outside allowed directories", http.StatusForbidden)
+		return
+	}
+	stat, err := os.Stat(workflowPath)
+	if err != nil || stat.IsDir() || !config.IsWorkflowFile(workflowPath) {
+		http.Error(w, "Workflow file not found", http.StatusNotFound)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWorkflowFileSize))
+	if err != nil {
+		http.Error(w, "Workflow content is too large or could not be read", http.StatusRequestEntityTooLarge)
+		return
+	}
+	result := s.validateWorkflowContent(workflowPath, data)
+	if !result.Valid {
+		writeValidationResult(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if err := writeFileAtomic(workflowPath, data, stat.Mode().Perm(), false); err != nil {
+		http.Error(w, "Failed to write the workflow file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Infof("Audit: workflow %s updated by %s", workflowPath, s.editorOf(r))
+	writeValidationResult(w, http.StatusOK, result)
+}
+
+// archiveDirName is the folder, directly inside a workflow directory, that
+// DeleteWorkflow moves files to. ListWorkflows does not look inside it.
+const archiveDirName = "archive"
+
+// DeleteWorkflow moves a workflow file into the archive folder of its workflow
+// directory, where it can be restored from, or deletes it with purge set. A
+// workflow that is running is left alone.
+func (s *Server) DeleteWorkflow(w http.ResponseWriter, r *http.Request, name string, params api.DeleteWorkflowParams) {
+	if !s.workflowEditAllowed(w) {
+		return
+	}
+	workflowPath, ok := s.workflowPathParam(w, name)
+	if !ok {
+		return
+	}
+	if !s.foldersInWorkflowDirs(workflowPath) {
+		http.Error(w, "Workflow path outside allowed directories", http.StatusForbidden)
+		return
+	}
+	if stat, err := os.Stat(workflowPath); err != nil || stat.IsDir() || !config.IsWorkflowFile(workflowPath) {
+		http.Error(w, "Workflow file not found", http.StatusNotFound)
+		return
+	}
+	if state := s.state.GetState(); s.state.IsRunning() && state != nil && filepath.Clean(state.Name) == workflowPath {
+		http.Error(w, "Workflow is running; stop it before deleting it", http.StatusConflict)
+		return
+	}
+
+	resp := api.DeleteWorkflowResponse{Path: workflowPath}
+	if params.Purge != nil && *params.Purge {
+		if err := os.Remove(workflowPath); err != nil {
+			http.Error(w, "Failed to delete the workflow file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Infof("Audit: workflow %s deleted by %s", workflowPath, s.editorOf(r))
+	} else {
+		target, ok := s.archivePath(workflowPath)
+		if !ok {
+			http.Error(w, "Workflow is already archived; use purge=true to delete it", http.StatusBadRequest)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			http.Error(w, "Failed to create the archive folder: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := os.Rename(workflowPath, target); err != nil {
+			http.Error(w, "Failed to archive the workflow file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Infof("Audit: workflow %s archived to %s by %s", workflowPath, target, s.editorOf(r))
+		resp.ArchivedTo = &target
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// archivePath returns where DeleteWorkflow moves workflowPath: the same path
+// relative to its workflow directory, inside that directory's archive folder.
+// A file already archived there gets a timestamp added to its name. It returns
+// false for files that are archived already.
+func (s *Server) archivePath(workflowPath string) (string, bool) {
+	root := ""
+	for _, dir := range s.workflowDirs {
+		dir = filepath.Clean(dir)
+		if strings.HasPrefix(workflowPath, dir+string(os.PathSeparator)) && len(dir) > len(root) {
+			root = dir
+		}
+	}
+	rel, err := filepath.Rel(root, workflowPath)
+	if err != nil || strings.HasPrefix(rel, archiveDirName+string(os.PathSeparator)) {
+		return "", false
+	}
+	target := filepath.Join(root, archiveDirName, rel)
+	if _, err := os.Lstat(target); err == nil {
+		ext := filepath.Ext(target)
+		target = strings.TrimSuffix(target, ext) + "." + time.Now().Format("20060102-150405") + ext
+	}
+	return target, true
+}
+
+// workflowEditAllowed answers 403 unless workflow editing was enabled.
+func (s *Server) workflowEditAllowed(w http.ResponseWriter) bool {
+	if !s.allowEdit {
+		http.Error(w, "Workflow editing is disabled; start the server with -allow-workflow-edit", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// foldersInWorkflowDirs reports whether the folder of path is still inside a
+// workflow directory once symlinks are followed, so a linked folder cannot
+// send writes elsewhere. Folders that do not exist yet are judged by the
+// nearest one that does.
+func (s *Server) foldersInWorkflowDirs(path string) bool {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+	resolved, err := resolvePath(dir)
+	if err != nil {
+		return false
+	}
+	for _, root := range s.workflowDirs {
+		root, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if resolved == root || strings.HasPrefix(resolved, root+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns the absolute path of path with symlinks followed.
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
+
+// validateWorkflowContent validates data as the content of workflowPath, as
+// ValidateWorkflow does for a saved file: steps must name instances the server
+// defines, since the file is about to be run by it.
+func (s *Server) validateWorkflowContent(workflowPath string, data []byte) api.ValidationResult {
+	opts := s.loadOptions()
+	opts.WorkflowData = data
+	return validationResult(config.Validate(s.instancesPath, workflowPath, opts))
+}
+
+// writeValidationResult sends result with status.
+func writeValidationResult(w http.ResponseWriter, status int, result api.ValidationResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeFileAtomic writes data to path through a temporary file in the same
+// folder, so readers see either the old or the new content, never a partial
+// file. With exclusive set, an existing file is not replaced and the error
+// matches fs.ErrExist.
+func writeFileAtomic(path string, data []byte, perm os.FileMode, exclusive bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if exclusive {
+		return os.Link(tmp.Name(), path)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/api"
+	"github.com/treaz/jenkins-flow/pkg/database"
+)
+
+// exportPageSize is how many runs ExportRuns reads from the database at a time.
+var exportPageSize = 500
+
+// runExportWriter encodes exported runs one at a time.
+type runExportWriter interface {
+	Write(run *database.WorkflowRun) error
+	// Flush sends everything written so far to the client.
+	Flush() error
+	// Close finishes the document.
+	Close() error
+}
+
+// ExportRuns streams every run matching the history filters as a CSV or JSON
+// download, reading the database a page at a time.
+func (s *Server) ExportRuns(w http.ResponseWriter, r *http.Request, params api.ExportRunsParams) {
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	format := "csv"
+	if params.Format != nil && *params.Format != "" {
+		format = *params.Format
+	}
+	if format != "csv" && format != "json" {
+		http.Error(w, fmt.Sprintf("Unknown export format %q (use csv or json)", format), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := runFilterFromParams(params.WorkflowPath, params.Status, params.Label, params.From, params.To, params.Sort)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Runs started while the export is paging would shift later pages, so
+	// the export covers runs started before it began.
+	if now := time.Now(); filter.To.IsZero() || filter.To.After(now) {
+		filter.To = now
+	}
+
+	// Read the first page before committing to a 200 so a broken database
+	// still gets a proper error response.
+	runs, err := s.db.GetRuns(exportPageSize, 0, filter)
+	if err != nil {
+		s.logger.Errorf("Failed to export workflow runs: %v", err)
+		http.Error(w, "Failed to retrieve workflow runs", http.StatusInternalServerError)
+		return
+	}
+
+	var out runExportWriter
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out = newCSVRunWriter(w)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		out = &jsonRunWriter{w: w}
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "runs." + format}))
+
+	for offset := 0; ; {
+		for i := range runs {
+			if err := out.Write(&runs[i]); err != nil {
+				s.logger.Errorf("Failed to write run export: %v", err)
+				return
+			}
+		}
+		if err := out.Flush(); err != nil {
+			s.logger.Errorf("Failed to write run export: %v", err)
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if len(runs) < exportPageSize {
+			break
+		}
+
+		offset += len(runs)
+		if runs, err = s.db.GetRuns(exportPageSize, offset, filter); err != nil {
+			// Headers are already sent; the client sees a truncated file.
+			s.logger.Errorf("Failed to export workflow runs after %d rows: %v", offset, err)
+			return
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		s.logger.Errorf("Failed to write run export: %v", err)
+	}
+}
+
+// csvRunWriter writes one row per run under a fixed header.
+type csvRunWriter struct {
+	w *csv.Writer
+}
+
+var csvRunHeader = []string{"id", "name", "path", "start", "end", "duration", "status", "skip_pr_check"}
+
+func newCSVRunWriter(w io.Writer) *csvRunWriter {
+	cw := &csvRunWriter{w: csv.NewWriter(w)}
+	cw.w.Write(csvRunHeader)
+	return cw
+}
+
+// Write adds a row. Times are RFC 3339 in UTC and the duration is in whole
+// seconds; both are empty for runs that have not finished.
+func (c *csvRunWriter) Write(run *database.WorkflowRun) error {
+	end, duration := "", ""
+	if run.EndTime != nil {
+		end = run.EndTime.UTC().Format(time.RFC3339)
+		duration = strconv.FormatInt(int64(run.EndTime.Sub(run.StartTime).Round(time.Second)/time.Second), 10)
+	}
+	return c.w.Write([]string{
+		strconv.FormatInt(run.ID, 10),
+		run.WorkflowName,
+		run.WorkflowPath,
+		run.StartTime.UTC().Format(time.RFC3339),
+		end,
+		duration,
+		run.Status,
+		strconv.FormatBool(run.SkipPRCheck),
+	})
+}
+
+func (c *csvRunWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRunWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonRunWriter writes a JSON array of runs in their API form, element by element.
+type jsonRunWriter struct {
+	w io.Writer
+	n int
+}
+
+func (j *jsonRunWriter) Write(run *database.WorkflowRun) error {
+	sep := ","
+	if j.n == 0 {
+		sep = "["
+	}
+	data, err := json.Marshal(runToAPI(run))
+	if err != nil {
+		return err
+	}
+	j.n++
+	_, err = fmt.Fprintf(j.w, "%s\n%s", sep, data)
+	return err
+}
+
+func (j *jsonRunWriter) Flush() error {
+	return nil
+}
+
+func (j *jsonRunWriter) Close() error {
+	if j.n == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/api"
+	"github.com/treaz/jenkins-flow/pkg/database"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+func TestExportRuns(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	// Force several pages so the paging loop is exercised.
+	defer func(n int) { exportPageSize = n }(exportPageSize)
+	exportPageSize = 2
+
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		id, err := srv.db.CreateRun("Deploy", "workflows/deploy.yaml", "", nil, "", database.RunMeta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	first, err := srv.db.GetRun(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.db.UpdateRunComplete(ids[0], "success", first.StartTime.Add(90*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	router := srv.BuildRouter()
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/export?"+query, nil))
+		return w
+	}
+
+	w := get("sort=start_time_asc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=runs.csv" {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 6 || strings.Join(rows[0], ",") != "id,name,path,start,end,duration,status,skip_pr_check" {
+		t.Fatalf("expected header and 5 rows, got %v", rows)
+	}
+	if row := rows[1]; row[0] != strconv.FormatInt(ids[0], 10) || row[1] != "Deploy" || row[5] != "90" || row[6] != "success" || row[7] != "false" {
+		t.Errorf("unexpected first row: %v", row)
+	}
+	if row := rows[5]; row[4] != "" || row[5] != "" || row[6] != "running" {
+		t.Errorf("expected unfinished run without end and duration, got %v", row)
+	}
+
+	w = get("format=json&status=running")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON export, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var runs []api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(runs) != 4 || *runs[0].Id != ids[4] {
+		t.Errorf("expected the 4 running runs newest first, got %d", len(runs))
+	}
+
+	w = get("format=json&status=stopped")
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("expected an empty array, got %q", w.Body.String())
+	}
+
+	for _, q := range []string{"format=xml", "sort=name"} {
+		if w := get(q); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %q, got %d", q, w.Code)
+		}
+	}
+}
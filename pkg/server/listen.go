@@ -0,0 +1,51 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// SetBindAddress sets the host or IP address Start and StartAsync listen on.
+// Empty, the default, listens on all interfaces. Call it before serving.
+func (s *Server) SetBindAddress(host string) {
+	s.bindAddr = host
+}
+
+// SetTLS makes the server serve HTTPS with the certificate and key in the
+// given PEM files. They are loaded now, so a missing or mismatched file is
+// reported before the server starts. Call it before serving.
+func (s *Server) SetTLS(certFile, keyFile string) error {
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("both a certificate and a key file are required for TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate %q and key %q: %w", certFile, keyFile, err)
+	}
+	s.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return nil
+}
+
+// listenAddr returns the address to listen on for port.
+func (s *Server) listenAddr(port int) string {
+	return net.JoinHostPort(s.bindAddr, strconv.Itoa(port))
+}
+
+// dashboardURL returns the URL the dashboard is reachable at on port, for the
+// startup message. An unspecified bind address is shown as localhost.
+func (s *Server) dashboardURL(port int) string {
+	scheme := "http"
+	if s.tlsConfig != nil {
+		scheme = "https"
+	}
+	host := s.bindAddr
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
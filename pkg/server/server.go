@@ -1,19 +1,27 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"maps"
+	"mime"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,27 +31,48 @@ import (
 	"github.com/treaz/jenkins-flow/pkg/api"
 	"github.com/treaz/jenkins-flow/pkg/config"
 	"github.com/treaz/jenkins-flow/pkg/database"
+	"github.com/treaz/jenkins-flow/pkg/github"
+	"github.com/treaz/jenkins-flow/pkg/jenkins"
 	"github.com/treaz/jenkins-flow/pkg/logger"
 	"github.com/treaz/jenkins-flow/pkg/notifier"
 	"github.com/treaz/jenkins-flow/pkg/settings"
+	"github.com/treaz/jenkins-flow/pkg/version"
 	"github.com/treaz/jenkins-flow/pkg/workflow"
 )
 
 // Server provides the HTTP server for the dashboard UI.
 type Server struct {
-	port          int
-	instancesPath string
-	workflowDirs  []string
-	state         *StateManager
-	logger        *logger.Logger
-	staticFS      fs.FS
-	mu            sync.Mutex
-	cancelFn      context.CancelFunc
-	db            *database.DB
-	dbPath        string
-	currentRunID  int64
+	port                  int
+	bindAddr              string      // Host to listen on; empty listens on all interfaces
+	tlsConfig             *tls.Config // Set by SetTLS to serve HTTPS
+	instancesPath         string
+	workflowDirs          []string
+	state                 *StateManager
+	logger                *logger.Logger
+	staticFS              fs.FS
+	mu                    sync.Mutex
+	runs                  map[int64]*runControl // Executing runs by run ID, for StopRun
+	db                    *database.DB
+	dbPath                string
+	currentRunID          int64               // Most recently started run, served by the legacy endpoints
+	lenient               bool                // Ignore unknown keys in config files
+	preferGlobalInstances bool                // Merge workflow file instances under the instances file's
+	allowEdit             bool                // Accept workflow file writes through the API
+	pollDefaults          config.PollDefaults // Poll intervals for what workflows leave unset
+	apiKeys               [][sha256.Size]byte // Digests of the keys /api requests must carry; none leaves the API open
+	trustedProxies        []netip.Prefix      // Senders whose X-Forwarded-User the audit log trusts
+	shutdownMode          string              // How Stop ends executing runs; see SetShutdownMode
+	httpServer            *http.Server        // Set while Start serves, for Stop
+	stopping              bool                // Set by Stop; new runs are refused
+	configCache           *config.Cache
+	credentials           config.CredentialStore // Resolves auth_keychain keys; nil uses the macOS Keychain
+	env                   config.EnvLookup       // Resolves auth_env and environment references; nil uses the process environment
+	warned                sync.Map               // Config warnings already logged; see logWarnings
 }
 
+// statsSampleSize is how many recent successful runs duration estimates are based on.
+const statsSampleSize = 10
+
 // StaticFiles will be embedded at build time.
 //
 //go:embed static/*
@@ -73,7 +102,7 @@ func NewServer(port int, instancesPath string, workflowDirs []string, dbPath str
 		// Don't fail server startup, just log the error
 	}
 
-	return &Server{
+	s := &Server{
 		port:          port,
 		instancesPath: instancesPath,
 		workflowDirs:  workflowDirs,
@@ -82,6 +111,96 @@ func NewServer(port int, instancesPath string, workflowDirs []string, dbPath str
 		staticFS:      staticFS,
 		db:            db,
 		dbPath:        dbPath,
+		configCache:   config.NewCache(config.DefaultCacheSize),
+		runs:          make(map[int64]*runControl),
+		shutdownMode:  stopModeGraceful,
+	}
+	if err := config.CheckDefaults(config.DefaultsPath); err != nil {
+		s.logWarnings([]string{config.DefaultsWarning(err)})
+	}
+	return s
+}
+
+// SetLenient makes config loading ignore unknown keys instead of rejecting
+// them, for config files that carry extra metadata. Call it before serving.
+func (s *Server) SetLenient(lenient bool) {
+	s.lenient = lenient
+}
+
+// SetPreferGlobalInstances makes the instances file win over instances
+// defined in workflow files, except entries marked override: true. Call it
+// before serving.
+func (s *Server) SetPreferGlobalInstances(prefer bool) {
+	s.preferGlobalInstances = prefer
+}
+
+// SetAllowWorkflowEdit enables creating and replacing workflow files through
+// the API. Call it before serving.
+func (s *Server) SetAllowWorkflowEdit(allow bool) {
+	s.allowEdit = allow
+}
+
+// SetCredentialStore sets the store auth_keychain keys are looked up in,
+// instead of the macOS Keychain. Call it before serving.
+func (s *Server) SetCredentialStore(store config.CredentialStore) {
+	s.credentials = store
+}
+
+// SetEnv sets the environment auth_env variables and environment references
+// in config values are looked up in, such as one from config.LoadEnvFile,
+// instead of the process environment. Call it before serving.
+func (s *Server) SetEnv(env config.EnvLookup) {
+	s.env = env
+}
+
+// SetPollDefaults sets the poll intervals used where workflows leave them
+// unset, for runs and single job triggers. Call it before serving.
+func (s *Server) SetPollDefaults(p config.PollDefaults) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	s.pollDefaults = p
+	return nil
+}
+
+// loadConfig loads a workflow with the server's instances file, resolving
+// instance aliases with profile.
+func (s *Server) loadConfig(workflowPath, profile string) (*config.Config, error) {
+	opts := s.loadOptions()
+	opts.Profile = profile
+	cfg, err := config.LoadWithOptions(s.instancesPath, workflowPath, opts)
+	if err == nil {
+		s.logWarnings(cfg.Warnings)
+	}
+	return cfg, err
+}
+
+// logWarnings logs each of warnings the first time the server sees it, since
+// workflows are loaded again on most requests.
+func (s *Server) logWarnings(warnings []string) {
+	for _, warning := range warnings {
+		if _, seen := s.warned.LoadOrStore(warning, true); !seen {
+			log.Printf("Warning: %s", warning)
+		}
+	}
+}
+
+// loadSnapshot loads data, a config snapshot of workflowPath, like loadConfig.
+func (s *Server) loadSnapshot(workflowPath, profile string, data []byte) (*config.Config, error) {
+	opts := s.loadOptions()
+	opts.Profile, opts.WorkflowData = profile, data
+	return config.LoadWithOptions(s.instancesPath, workflowPath, opts)
+}
+
+// loadOptions returns the options the server loads every config with.
+func (s *Server) loadOptions() config.LoadOptions {
+	return config.LoadOptions{
+		Lenient:               s.lenient,
+		PreferGlobalInstances: s.preferGlobalInstances,
+		Cache:                 s.configCache,
+		PollDefaults:          s.pollDefaults,
+		Credentials:           s.credentials,
+		Env:                   s.env,
 	}
 }
 
@@ -92,6 +211,7 @@ func (s *Server) BuildRouter() chi.Router {
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(s.requireAPIKey)
 
 	// API routes
 	api.HandlerFromMux(s, r)
@@ -100,6 +220,9 @@ func (s *Server) BuildRouter() chi.Router {
 	r.Get("/api/openapi.json", s.handleOpenAPISpec)
 	r.Get("/swagger", s.handleSwaggerUI)
 
+	// Prometheus metrics
+	r.Get("/metrics", s.handleMetrics)
+
 	// Static files (Vue app)
 	if s.staticFS != nil {
 		fileServer := http.FileServer(http.FS(s.staticFS))
@@ -151,106 +274,197 @@ func (s *Server) BuildRouter() chi.Router {
 	return r
 }
 
-// Start starts the HTTP server (blocking).
+// Start starts the HTTP server and blocks until it fails or Stop shuts it
+// down, in which case it returns nil.
 func (s *Server) Start() error {
-	r := s.BuildRouter()
-	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("Starting dashboard server on http://localhost%s", addr)
-	return http.ListenAndServe(addr, r)
+	httpServer := &http.Server{Addr: s.listenAddr(s.port), Handler: s.BuildRouter(), TLSConfig: s.tlsConfig}
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return nil
+	}
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
+	log.Printf("Starting dashboard server on %s", s.dashboardURL(s.port))
+	var err error
+	if s.tlsConfig != nil {
+		// The certificate is already in TLSConfig.
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // StartAsync starts the HTTP server in a goroutine and returns the actual port
 // and a shutdown function. Use port 0 to let the OS pick an available port.
 func (s *Server) StartAsync() (int, func(context.Context) error, error) {
 	r := s.BuildRouter()
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	listener, err := net.Listen("tcp", s.listenAddr(s.port))
 	if err != nil {
 		return 0, nil, fmt.Errorf("failed to listen: %w", err)
 	}
 	actualPort := listener.Addr().(*net.TCPAddr).Port
-	httpServer := &http.Server{Handler: r}
-	go httpServer.Serve(listener)
-	log.Printf("Started dashboard server on http://localhost:%d", actualPort)
+	httpServer := &http.Server{Handler: r, TLSConfig: s.tlsConfig}
+	s.mu.Lock()
+	s.httpServer = httpServer
+	s.mu.Unlock()
+	if s.tlsConfig != nil {
+		go httpServer.ServeTLS(listener, "", "")
+	} else {
+		go httpServer.Serve(listener)
+	}
+	log.Printf("Started dashboard server on %s", s.dashboardURL(actualPort))
 	return actualPort, httpServer.Shutdown, nil
 }
 
-// ListWorkflows returns available workflow files.
-func (s *Server) ListWorkflows(w http.ResponseWriter, r *http.Request) {
-	workflows := []api.WorkflowInfo{}
-
-	for _, dir := range s.workflowDirs {
-		// Look for workflow files in the directory
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			// Just log warning if one dir fails, don't fail entire request
-			log.Printf("Warning: Error reading workflows directory %q: %v", dir, err)
-			continue
-		}
+// ListWorkflows returns available workflow files, only those tagged
+// params.Tag when it is set.
+func (s *Server) ListWorkflows(w http.ResponseWriter, r *http.Request, params api.ListWorkflowsParams) {
+	tag := ""
+	if params.Tag != nil {
+		tag = strings.TrimSpace(*params.Tag)
+	}
 
-		for _, entry := range entries {
-			name := entry.Name()
-			if !entry.IsDir() && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) {
-				fullPath := filepath.Join(dir, name)
-
-				// Parse the name from the file content
-				workflowName, err := config.ParseWorkflowMeta(fullPath)
-				if err != nil {
-					// Include invalid workflows in list with error
-					workflows = append(workflows, api.WorkflowInfo{
-						Name:  strPtr(name),
-						Path:  strPtr(fullPath),
-						Valid: boolPtr(false),
-						Error: strPtr(err.Error()),
-					})
-					continue
-				}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.listWorkflows(tag))
+}
 
-				// Validate the complete workflow
-				_, validationErr := config.Load(s.instancesPath, fullPath)
-				if validationErr != nil {
-					workflows = append(workflows, api.WorkflowInfo{
-						Name:  strPtr(workflowName),
-						Path:  strPtr(fullPath),
-						Valid: boolPtr(false),
-						Error: strPtr(validationErr.Error()),
-					})
-				} else {
-					workflows = append(workflows, api.WorkflowInfo{
-						Name:  strPtr(workflowName),
-						Path:  strPtr(fullPath),
-						Valid: boolPtr(true),
-						Error: nil,
-					})
+// listWorkflows scans the workflow directories for ListWorkflows.
+func (s *Server) listWorkflows(tag string) []api.WorkflowInfo {
+	workflows := []api.WorkflowInfo{}
+	for _, dir := range s.workflowDirs {
+		// Look for workflow files in the directory and its subdirectories
+		filepath.WalkDir(dir, func(fullPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// Just log warning if one dir fails, don't fail entire request
+				log.Printf("Warning: Error reading workflows directory %q: %v", fullPath, err)
+				return nil
+			}
+			if entry.IsDir() {
+				// Skip hidden directories such as .git below the root, and
+				// the folder DeleteWorkflow archives files to
+				if fullPath != dir && (strings.HasPrefix(entry.Name(), ".") || fullPath == filepath.Join(dir, archiveDirName)) {
+					return fs.SkipDir
 				}
+				return nil
 			}
-		}
+			if !config.IsWorkflowFile(entry.Name()) {
+				return nil
+			}
+			if info, ok := s.workflowInfo(dir, fullPath, tag); ok {
+				workflows = append(workflows, info)
+			}
+			return nil
+		})
 	}
+	return workflows
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workflows)
+// workflowInfo describes the workflow file at fullPath, found below the
+// workflow directory root, for ListWorkflows. It returns false when tag is set
+// and the workflow does not carry it; invalid workflows are only listed
+// without a tag filter.
+func (s *Server) workflowInfo(root, fullPath, tag string) (api.WorkflowInfo, bool) {
+	// Parse the name, description and tags from the file content
+	meta, err := s.configCache.WorkflowMeta(fullPath, s.lenient)
+	if err != nil {
+		if tag != "" {
+			return api.WorkflowInfo{}, false
+		}
+		log.Printf("Warning: Invalid workflow %q: %v", fullPath, err)
+		// Include invalid workflows in list with error
+		return api.WorkflowInfo{
+			Name:        strPtr(filepath.Base(fullPath)),
+			Path:        strPtr(fullPath),
+			DisplayPath: strPtr(displayPath(root, fullPath)),
+			Valid:       boolPtr(false),
+			Error:       strPtr(err.Error()),
+		}, true
+	}
+	if tag != "" && !meta.HasTag(tag) {
+		return api.WorkflowInfo{}, false
+	}
+	var tags *[]string
+	if len(meta.Tags) > 0 {
+		tags = &meta.Tags
+	}
+	var schema *int
+	if meta.Schema > 0 {
+		schema = intPtr(meta.Schema)
+	}
+
+	info := api.WorkflowInfo{
+		Name:        strPtr(meta.Name),
+		Description: strPtr(meta.Description),
+		Path:        strPtr(fullPath),
+		DisplayPath: strPtr(displayPath(root, fullPath)),
+		Tags:        tags,
+		Schema:      schema,
+		Valid:       boolPtr(true),
+	}
+
+	// Validate the complete workflow
+	if _, validationErr := s.loadConfig(fullPath, ""); validationErr != nil {
+		log.Printf("Warning: Invalid workflow: %v", validationErr)
+		info.Valid = boolPtr(false)
+		info.Error = strPtr(validationErr.Error())
+	}
+	return info, true
 }
 
-// GetWorkflowDefinition returns the static definition of a workflow for preview purposes.
-func (s *Server) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, name string) {
-	workflowPath, err := url.PathUnescape(name)
+// displayPath names fullPath by its workflow directory and the path below it,
+// e.g. workflows/team-a/deploy.yaml, so files from different roots can be told
+// apart without the full path.
+func displayPath(root, fullPath string) string {
+	rel, err := filepath.Rel(root, fullPath)
 	if err != nil {
-		http.Error(w, "Invalid workflow path", http.StatusBadRequest)
-		return
+		return filepath.ToSlash(fullPath)
 	}
+	return filepath.ToSlash(filepath.Join(filepath.Base(filepath.Clean(root)), rel))
+}
 
-	workflowPath = filepath.Clean(workflowPath)
+// RefreshWorkflows drops every cached config parse and returns the workflow
+// list read afresh, for edits the cache cannot see, such as a file replaced
+// with one of the same size and modification time.
+func (s *Server) RefreshWorkflows(w http.ResponseWriter, r *http.Request) {
+	s.configCache.Clear()
+	s.ListWorkflows(w, r, api.ListWorkflowsParams{})
+}
 
-	allowed := false
-	for _, dir := range s.workflowDirs {
-		workflowsRoot := filepath.Clean(dir)
-		if strings.HasPrefix(workflowPath, workflowsRoot+string(os.PathSeparator)) || workflowPath == workflowsRoot {
-			allowed = true
-			break
+// Reload drops every cached config parse, re-reads the instances file and
+// rescans the workflow directories, logging what it found. It is what SIGHUP
+// does. It refuses while a workflow is running, and fails when the instances
+// file does not parse; invalid workflow files are only counted.
+func (s *Server) Reload() error {
+	if s.state.IsRunning() {
+		return fmt.Errorf("a workflow is running")
+	}
+	s.configCache.Clear()
+
+	instances, aliases, err := config.CountInstances(s.instancesPath, s.loadOptions())
+	if err != nil {
+		return err
+	}
+	workflows := s.listWorkflows("")
+	invalid := 0
+	for _, info := range workflows {
+		if info.Valid != nil && !*info.Valid {
+			invalid++
 		}
 	}
+	s.logger.Infof("Reloaded configuration: %d instance(s) and %d alias(es) in %s, %d workflow(s) (%d invalid)", instances, aliases, s.instancesPath, len(workflows), invalid)
+	return nil
+}
 
-	if !allowed {
-		http.Error(w, "Workflow path outside allowed directories", http.StatusForbidden)
+// GetWorkflowDefinition returns the static definition of a workflow for preview purposes.
+func (s *Server) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, name string) {
+	workflowPath, ok := s.workflowPathParam(w, name)
+	if !ok {
 		return
 	}
 
@@ -259,16 +473,19 @@ func (s *Server) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, n
 		return
 	}
 
-	cfg, err := config.Load(s.instancesPath, workflowPath)
+	cfg, err := s.loadConfig(workflowPath, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to load workflow: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	s.applyInputSubstitutions(cfg)
+	if err := cfg.ApplyInputs(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load workflow: %v", err), http.StatusBadRequest)
+		return
+	}
 
 	// Filter out inputs that are only used by PR wait steps
-	filteredInputs := filterPRWaitOnlyInputs(cfg)
+	filteredInputs := cfg.MaskInputs(filterPRWaitOnlyInputs(cfg))
 
 	// Helper to convert config items to initial internal state, then to API state
 	internalItems := s.configToStateItems(cfg)
@@ -279,13 +496,191 @@ func (s *Server) GetWorkflowDefinition(w http.ResponseWriter, r *http.Request, n
 		Inputs:    filteredInputs,
 		Items:     internalItems,
 		StartedAt: nil,
+
+		EstimatedDuration: int64(s.estimatedDuration(workflowPath) / time.Second),
 	}
 
 	response := s.internalToAPI(dummyState)
+	response.Settings = workflowSettingsToAPI(cfg, internalItems)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// workflowSettingsToAPI describes the settings cfg ended up with after the
+// defaults, instances and workflow files were merged, without credentials.
+func workflowSettingsToAPI(cfg *config.Config, items []WorkflowItemState) *api.WorkflowSettings {
+	res := &api.WorkflowSettings{}
+	if cfg.DefaultsFile != "" {
+		res.DefaultsFile = strPtr(cfg.DefaultsFile)
+	}
+	if cfg.SlackWebhook != "" {
+		res.SlackWebhook = strPtr(maskWebhook(cfg.SlackWebhook))
+	}
+	if cfg.SlackChannel != "" {
+		res.SlackChannel = strPtr(cfg.SlackChannel)
+	}
+	if cfg.SlackUsername != "" {
+		res.SlackUsername = strPtr(cfg.SlackUsername)
+	}
+	if gh := cfg.GitHub; gh != nil {
+		switch {
+		case gh.App != nil:
+			res.GithubAuth = strPtr(fmt.Sprintf("app %d", gh.App.AppID))
+		case gh.Token != "":
+			res.GithubAuth = strPtr("token")
+		case gh.AuthKeychain != "":
+			res.GithubAuth = strPtr("auth_keychain " + gh.AuthKeychain)
+		case gh.AuthEnv != "":
+			res.GithubAuth = strPtr("auth_env " + gh.AuthEnv)
+		}
+	}
+	instances := map[string]string{}
+	addInstance := func(step StepState) {
+		if inst, ok := cfg.Instances[step.Instance]; ok {
+			instances[step.Instance] = inst.URL
+		}
+	}
+	for _, item := range items {
+		if item.Step != nil {
+			addInstance(*item.Step)
+		}
+		if item.Parallel != nil {
+			for _, step := range item.Parallel.Steps {
+				addInstance(step)
+			}
+		}
+	}
+	if len(instances) > 0 {
+		res.Instances = &instances
+	}
+	return res
+}
+
+// maskWebhook keeps a webhook URL's scheme and host and masks the rest, which
+// holds its secret.
+func maskWebhook(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" {
+		return config.SecretMask
+	}
+	return u.Scheme + "://" + u.Host + "/" + config.SecretMask
+}
+
+// GetWorkflowGraph renders a workflow as a Mermaid flowchart or a Graphviz
+// digraph, with its saved inputs substituted.
+func (s *Server) GetWorkflowGraph(w http.ResponseWriter, r *http.Request, name string, params api.GetWorkflowGraphParams) {
+	format := "mermaid"
+	if params.Format != nil {
+		format = *params.Format
+	}
+	if format != "mermaid" && format != "dot" {
+		http.Error(w, fmt.Sprintf("Invalid format %q: use mermaid or dot", format), http.StatusBadRequest)
+		return
+	}
+
+	workflowPath, ok := s.workflowPathParam(w, name)
+	if !ok {
+		return
+	}
+	if stat, err := os.Stat(workflowPath); err != nil || stat.IsDir() {
+		http.Error(w, "Workflow file not found", http.StatusNotFound)
+		return
+	}
+
+	cfg, err := s.loadConfig(workflowPath, "")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load workflow: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := cfg.ApplyInputs(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load workflow: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if format == "dot" {
+		fmt.Fprint(w, config.ToDOT(cfg))
+		return
+	}
+	fmt.Fprint(w, config.ToMermaid(cfg))
+}
+
+// GetWorkflowStats returns duration statistics for a workflow, computed from
+// its recent successful runs.
+func (s *Server) GetWorkflowStats(w http.ResponseWriter, r *http.Request, name string) {
+	workflowPath, ok := s.workflowPathParam(w, name)
+	if !ok {
+		return
+	}
+
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := s.db.GetWorkflowStats(workflowPath, statsSampleSize)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute workflow stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := api.WorkflowStats{
+		WorkflowPath: stats.WorkflowPath,
+		SampleSize:   stats.SampleSize,
+	}
+	if stats.SampleSize > 0 {
+		median := int64(stats.MedianDuration / time.Second)
+		resp.MedianDuration = &median
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// workflowPathParam decodes a workflow path parameter and checks that it lies
+// inside one of the workflow directories. On failure it writes the error
+// response and returns false.
+func (s *Server) workflowPathParam(w http.ResponseWriter, name string) (string, bool) {
+	workflowPath, err := url.PathUnescape(name)
+	if err != nil {
+		http.Error(w, "Invalid workflow path", http.StatusBadRequest)
+		return "", false
+	}
+
+	workflowPath = filepath.Clean(workflowPath)
+	if !s.inWorkflowDirs(workflowPath) {
+		http.Error(w, "Workflow path outside allowed directories", http.StatusForbidden)
+		return "", false
+	}
+	return workflowPath, true
+}
+
+// inWorkflowDirs reports whether the cleaned path is one of the workflow
+// directories or lies below one.
+func (s *Server) inWorkflowDirs(path string) bool {
+	for _, dir := range s.workflowDirs {
+		workflowsRoot := filepath.Clean(dir)
+		if strings.HasPrefix(path, workflowsRoot+string(os.PathSeparator)) || path == workflowsRoot {
+			return true
+		}
+	}
+	return false
+}
+
+// estimatedDuration returns the median duration of recent successful runs of
+// the workflow, or 0 when there is no history to go on.
+func (s *Server) estimatedDuration(workflowPath string) time.Duration {
+	if s.db == nil {
+		return 0
+	}
+	stats, err := s.db.GetWorkflowStats(workflowPath, statsSampleSize)
+	if err != nil {
+		s.logger.Errorf("Failed to estimate workflow duration: %v", err)
+		return 0
+	}
+	return stats.MedianDuration
+}
+
 // GetStatus returns the current workflow execution status.
 func (s *Server) GetStatus(w http.ResponseWriter, r *http.Request) {
 	internalState := s.state.GetState()
@@ -304,8 +699,59 @@ func (s *Server) GetStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// GetLastStatus returns the final state of the most recently completed run,
+// which survives the start of the next run so a finish is not missed.
+func (s *Server) GetLastStatus(w http.ResponseWriter, r *http.Request) {
+	state := s.state.LastCompleted()
+	if state == nil {
+		http.Error(w, "No completed workflow", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.internalToAPI(state))
+}
+
+// ExportStatus serves the state of the active or most recent run as a JSON
+// download, named after the time of the export, for sharing outside the UI.
+func (s *Server) ExportStatus(w http.ResponseWriter, r *http.Request) {
+	state := s.state.GetState()
+	if state == nil {
+		http.Error(w, "No workflow has been run", http.StatusNotFound)
+		return
+	}
+
+	filename := fmt.Sprintf("workflow-state-%s.json", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(s.internalToAPI(state))
+}
+
+// ClearLastStatus forgets the state of the most recently completed run.
+func (s *Server) ClearLastStatus(w http.ResponseWriter, r *http.Request) {
+	s.state.ClearLastCompleted()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStatusLog returns the engine log of the current (or most recent) run.
+func (s *Server) GetStatusLog(w http.ResponseWriter, r *http.Request) {
+	runLog := s.state.RunLog()
+	if runLog == nil {
+		http.Error(w, "No workflow has been run", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, runLog.String())
+}
+
 // RunWorkflow starts a workflow execution.
 func (s *Server) RunWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.refuseWhileStopping(w) {
+		return
+	}
 	// Check if already running
 	if s.state.IsRunning() {
 		http.Error(w, "A workflow is already running", http.StatusConflict)
@@ -318,48 +764,215 @@ func (s *Server) RunWorkflow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Workflow == nil || *req.Workflow == "" {
-		http.Error(w, "Workflow path is required", http.StatusBadRequest)
+	cfg, disabledSet, err := s.loadRunRequest(req, true)
+	if err != nil {
+		http.Error(w, err.Error(), runRequestStatus(err))
 		return
 	}
 	workflowPath := *req.Workflow
 
-	// Load config
-	cfg, err := config.Load(s.instancesPath, workflowPath)
+	meta := runMetaFromRequest(req)
+	runID := s.createRun(cfg, workflowPath, meta)
+
+	// Initialize state from config
+	items := s.configToStateItems(cfg)
+	s.state.StartWorkflow(workflowPath, cfg.MaskInputs(cfg.Inputs), items)
+	s.state.SetRunMeta(meta.Initiator, meta.Description, meta.Labels)
+	s.state.SetEstimatedDuration(s.estimatedDuration(workflowPath))
+
+	// Run workflow in background
+	ctx := s.newRunContext(runID)
+
+	go s.executeWorkflow(ctx, cfg, workflowPath, disabledSet, runID, meta, nil, 1)
+
+	resp := map[string]any{"status": "started"}
+	if runID > 0 {
+		resp["runId"] = runID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// runMetaFromRequest extracts the optional run metadata from req. Blank labels
+// are dropped.
+func runMetaFromRequest(req api.RunRequest) database.RunMeta {
+	var meta database.RunMeta
+	if req.Initiator != nil {
+		meta.Initiator = strings.TrimSpace(*req.Initiator)
+	}
+	if req.Description != nil {
+		meta.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Labels != nil {
+		for _, label := range *req.Labels {
+			if label = strings.TrimSpace(label); label != "" {
+				meta.Labels = append(meta.Labels, label)
+			}
+		}
+	}
+	return meta
+}
+
+// PlanWorkflow resolves a run request into the items it would execute without
+// triggering anything.
+func (s *Server) PlanWorkflow(w http.ResponseWriter, r *http.Request) {
+	var req api.RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cfg, disabledSet, err := s.loadRunRequest(req, false)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load config: %v", err), http.StatusBadRequest)
+		http.Error(w, err.Error(), runRequestStatus(err))
 		return
 	}
 
-	// Update inputs if provided
-	if req.Inputs != nil && len(*req.Inputs) > 0 {
-		newInputs := *req.Inputs
-		if cfg.Inputs == nil {
-			cfg.Inputs = make(map[string]string)
-		}
+	plan, err := workflow.BuildPlan(cfg, cfg.Inputs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build plan: %v", err), http.StatusBadRequest)
+		return
+	}
+	plan.MarkDisabled(disabledSet)
 
-		// Update persistent file if values changed
-		changed := false
-		for k, v := range newInputs {
-			if cfg.Inputs[k] != v {
-				cfg.Inputs[k] = v
-				changed = true
-			}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(planToAPI(*req.Workflow, cfg.MaskInputs(cfg.Inputs), plan))
+}
+
+// ValidateWorkflow checks a workflow file, or unsaved content for one, against
+// the server's instances and reports the problems found with their location,
+// for editor integrations. Content may be meant for another instances file, so
+// steps naming instances the server does not define are only warnings there.
+func (s *Server) ValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	var req api.ValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	opts := s.loadOptions()
+	if req.Profile != nil {
+		opts.Profile = *req.Profile
+	}
+	workflowPath := "<content>"
+	if req.Path != nil && *req.Path != "" {
+		var ok bool
+		if workflowPath, ok = s.workflowPathParam(w, *req.Path); !ok {
+			return
 		}
+	} else if req.Content == nil {
+		http.Error(w, "Either path or content is required", http.StatusBadRequest)
+		return
+	}
+	if req.Content != nil {
+		opts.WorkflowData = []byte(*req.Content)
+		opts.AllowUnknownInstances = true
+	}
 
-		if changed {
-			if err := s.updateWorkflowFile(workflowPath, cfg.Inputs); err != nil {
-				s.logger.Errorf("Failed to update workflow file: %v", err)
-				// Continue running even if persistence fails?
-				// The user specifically asked for persistence. Let's error or warn.
-				// For now warn but continue with in-memory value.
-			}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(validationResult(config.Validate(s.instancesPath, workflowPath, opts)))
+}
+
+// validationResult converts the issues config.Validate found.
+func validationResult(errs, warnings []config.Issue) api.ValidationResult {
+	return api.ValidationResult{
+		Valid:    len(errs) == 0,
+		Errors:   issuesToAPI(errs),
+		Warnings: issuesToAPI(warnings),
+	}
+}
+
+// issuesToAPI converts validation issues, leaving out unknown locations.
+func issuesToAPI(issues []config.Issue) []api.ValidationIssue {
+	result := make([]api.ValidationIssue, 0, len(issues))
+	for _, issue := range issues {
+		apiIssue := api.ValidationIssue{Message: issue.Message}
+		if issue.File != "" {
+			apiIssue.File = strPtr(issue.File)
 		}
+		if issue.Line > 0 {
+			apiIssue.Line = intPtr(issue.Line)
+		}
+		result = append(result, apiIssue)
 	}
+	return result
+}
 
-	s.applyInputSubstitutions(cfg)
+// errOutsideWorkflowDirs is returned by loadRunRequest for a workflow path
+// outside every workflow directory.
+var errOutsideWorkflowDirs = errors.New("Workflow path outside allowed directories")
 
-	// Apply PR wait overrides from the request
+// runRequestStatus returns the status code of a loadRunRequest error.
+func runRequestStatus(err error) int {
+	if errors.Is(err, errOutsideWorkflowDirs) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// loadRunRequest loads the workflow named by req and applies the request's
+// profile, inputs, PR wait overrides, and disabled steps. The path must lie
+// inside a workflow directory; it is cleaned in place, so callers see the
+// path that was loaded. Changed inputs are written back to the workflow file
+// only when persistInputs is set. Returned errors are suitable for the
+// response with runRequestStatus.
+func (s *Server) loadRunRequest(req api.RunRequest, persistInputs bool) (*config.Config, workflow.DisabledSet, error) {
+	if req.Workflow == nil || *req.Workflow == "" {
+		return nil, nil, fmt.Errorf("Workflow path is required")
+	}
+	workflowPath := filepath.Clean(*req.Workflow)
+	if !s.inWorkflowDirs(workflowPath) {
+		return nil, nil, errOutsideWorkflowDirs
+	}
+	*req.Workflow = workflowPath
+
+	// Load config, resolving instance aliases with the requested profile
+	profile := ""
+	if req.Profile != nil {
+		profile = *req.Profile
+	}
+	cfg, err := s.loadConfig(workflowPath, profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to load config: %v", err)
+	}
+
+	// Update inputs if provided
+	if req.Inputs != nil && len(*req.Inputs) > 0 {
+		newInputs := *req.Inputs
+		if cfg.Inputs == nil {
+			cfg.Inputs = make(map[string]string)
+		}
+
+		// Update persistent file if values changed. A secret input sent back
+		// as shown, masked, keeps its value.
+		secret := cfg.SecretInputs()
+		changed := false
+		for k, v := range newInputs {
+			if secret[k] && v == config.SecretMask {
+				continue
+			}
+			if cfg.Inputs[k] != v {
+				cfg.Inputs[k] = v
+				changed = true
+			}
+		}
+		persistInputs = persistInputs && changed
+	} else {
+		persistInputs = false
+	}
+
+	if err := cfg.ApplyInputs(); err != nil {
+		return nil, nil, fmt.Errorf("Failed to load config: %v", err)
+	}
+
+	if req.VerifyJobs != nil && *req.VerifyJobs {
+		cfg.VerifyJobs = true
+	}
+	if req.VerifyParams != nil && *req.VerifyParams {
+		cfg.VerifyParams = true
+	}
+
+	// Apply PR wait overrides from the request
 	if req.PrWaitOverrides != nil {
 		for _, ov := range *req.PrWaitOverrides {
 			if ov.ItemIndex == nil {
@@ -405,16 +1018,6 @@ func (s *Server) RunWorkflow(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Initialize state from config
-	items := s.configToStateItems(cfg)
-	s.state.StartWorkflow(workflowPath, cfg.Inputs, items)
-
-	// Run workflow in background
-	ctx, cancel := context.WithCancel(context.Background())
-	s.mu.Lock()
-	s.cancelFn = cancel
-	s.mu.Unlock()
-
 	// Parse disabled steps
 	disabledSet := workflow.DisabledSet{}
 	if req.DisabledSteps != nil {
@@ -431,13 +1034,79 @@ func (s *Server) RunWorkflow(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	go s.runWorkflow(ctx, cfg, workflowPath, disabledSet)
+	// Reject bad param values before they are saved or sent to Jenkins.
+	if err := workflow.CheckParams(cfg, cfg.Inputs, disabledSet); err != nil {
+		return nil, nil, fmt.Errorf("Invalid params: %v", err)
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+	if persistInputs {
+		// Secret inputs are used for this run only and never written to the file.
+		saved := make(map[string]string, len(cfg.Inputs))
+		secret := cfg.SecretInputs()
+		for k, v := range cfg.Inputs {
+			if !secret[k] {
+				saved[k] = v
+			}
+		}
+		if err := s.updateWorkflowFile(workflowPath, saved); err != nil {
+			s.logger.Errorf("Failed to update workflow file: %v", err)
+			// Continue running even if persistence fails?
+			// The user specifically asked for persistence. Let's error or warn.
+			// For now warn but continue with in-memory value.
+		}
+	}
+
+	return cfg, disabledSet, nil
+}
+
+// planToAPI converts an execution plan to its API representation.
+func planToAPI(workflowPath string, inputs map[string]string, plan *workflow.Plan) api.WorkflowPlan {
+	items := make([]api.PlanItem, len(plan.Items))
+	for i, item := range plan.Items {
+		items[i] = api.PlanItem{
+			Index:     intPtr(item.Index),
+			Name:      strPtr(item.Name),
+			Kind:      strPtr(item.Kind),
+			IsFinally: boolPtr(item.Finally),
+			Skipped:   boolPtr(item.Skipped),
+		}
+		if item.PRWait != nil {
+			pr := item.PRWait
+			items[i].PrWait = &api.PRWaitState{
+				Name:             strPtr(pr.Name),
+				Owner:            strPtr(pr.Owner),
+				Repo:             strPtr(pr.Repo),
+				HeadBranch:       strPtr(pr.HeadBranch),
+				PrNumber:         intPtr(pr.PRNumber),
+				WaitFor:          strPtr(pr.WaitFor),
+				AutoUpdateBranch: boolPtr(pr.ShouldAutoUpdate()),
+			}
+		}
+		if len(item.Steps) > 0 {
+			steps := make([]api.PlanStep, len(item.Steps))
+			for j, st := range item.Steps {
+				params := st.Params
+				steps[j] = api.PlanStep{
+					Name:        strPtr(st.Name),
+					Instance:    strPtr(st.Instance),
+					InstanceUrl: strPtr(st.InstanceURL),
+					Job:         strPtr(st.Job),
+					Params:      &params,
+					Skipped:     boolPtr(st.Skipped),
+				}
+			}
+			items[i].Steps = &steps
+		}
+	}
+	return api.WorkflowPlan{
+		Workflow: strPtr(workflowPath),
+		Inputs:   &inputs,
+		Items:    &items,
+	}
 }
 
 // updateWorkflowFile updates the workflow YAML file with new inputs without destroying comments.
+// JSON workflow files are edited the same way; see updateJSONInputs.
 func (s *Server) updateWorkflowFile(path string, inputs map[string]string) error {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -445,6 +1114,9 @@ func (s *Server) updateWorkflowFile(path string, inputs map[string]string) error
 	}
 
 	text := string(content)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return os.WriteFile(path, []byte(updateJSONInputs(text, inputs)), 0644)
+	}
 
 	// Helper to simple replace value for a key
 	// Looks for "  key: old_value" or "key: old_value"
@@ -491,21 +1163,275 @@ func (s *Server) updateWorkflowFile(path string, inputs map[string]string) error
 	return os.WriteFile(path, []byte(text), 0644)
 }
 
-// StopWorkflow stops a running workflow.
-func (s *Server) StopWorkflow(w http.ResponseWriter, r *http.Request) {
+// updateJSONInputs replaces the string value of each key of the top-level
+// "inputs" object named in inputs, leaving the rest of the text, including
+// its layout, as is. Keys elsewhere, such as step params, are not touched.
+// Text that is not a JSON object is returned unchanged.
+func updateJSONInputs(text string, inputs map[string]string) string {
+	type edit struct {
+		start, end int
+		value      string
+	}
+	var edits []edit
+	dec := json.NewDecoder(strings.NewReader(text))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return text
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return text
+		}
+		if key != "inputs" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return text
+			}
+			continue
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return text
+		}
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return text
+			}
+			var old json.RawMessage
+			if err := dec.Decode(&old); err != nil {
+				return text
+			}
+			newVal, ok := inputs[name.(string)]
+			if !ok || len(old) == 0 || old[0] != '"' {
+				continue
+			}
+			end := int(dec.InputOffset())
+			valJSON, _ := json.Marshal(newVal)
+			edits = append(edits, edit{start: end - len(old), end: end, value: string(valJSON)})
+		}
+		break
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		text = text[:e.start] + e.value + text[e.end:]
+	}
+	return text
+}
+
+// Stop modes accepted by StopRun and StopWorkflow.
+const (
+	stopModeNow      = "now"      // Cancel running builds immediately
+	stopModeGraceful = "graceful" // Let the current item finish, skip the rest
+)
+
+// runControl stops an executing run.
+type runControl struct {
+	cancel       context.CancelCauseFunc // nil once the run was stopped now
+	gracefulStop func()                  // Asks the run to stop after the item in progress
+	stopMode     string                  // Stop mode requested for the run, if any
+	done         chan struct{}           // Closed once the run has recorded its outcome
+}
+
+// newRunContext returns the context for the run runID (0 if it has no
+// database record), registers it for StopRun and makes it the current run.
+// A run started while the server is stopping is stopped at once.
+func (s *Server) newRunContext(runID int64) context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ctx, gracefulStop := workflow.WithGracefulStop(ctx)
+	s.mu.Lock()
+	run := &runControl{cancel: cancel, gracefulStop: gracefulStop, done: make(chan struct{})}
+	s.runs[runID] = run
+	s.currentRunID = runID
+	if s.stopping {
+		s.stopRunLocked(runID, run, stopModeNow)
+	}
+	s.mu.Unlock()
+	return ctx
+}
+
+// runStatusInterrupted is the recorded status of a run that was left running
+// by a process that died, or abandoned by AdminReset, and did not finish.
+const runStatusInterrupted = "interrupted"
+
+// errStateReset is the cancel cause of a run abandoned by AdminReset.
+var errStateReset = errors.New("workflow state reset")
+
+// AdminReset is a force unlock for a run state stuck in running, for example
+// after the process died mid-run or a run stopped following its context. It
+// cancels the current run if one is still executing, marks its record and any
+// other run recorded as running "interrupted", and clears the live and last
+// completed state so a new run can start.
+func (s *Server) AdminReset(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	for _, run := range s.runs {
+		if run.cancel != nil {
+			run.cancel(errStateReset)
+		}
+	}
+	s.runs = make(map[int64]*runControl)
+	s.currentRunID = 0
+	s.mu.Unlock()
+
+	s.state.Reset()
+	s.logger.Infof("Workflow state reset by user")
+
+	interrupted := 0
+	if s.db != nil {
+		runs, err := s.db.GetRuns(100, 0, database.RunFilter{Status: "running"})
+		if err != nil {
+			s.logger.Errorf("Failed to look up running workflow runs: %v", err)
+			http.Error(w, "State reset, but running runs could not be looked up", http.StatusInternalServerError)
+			return
+		}
+		for _, run := range runs {
+			if err := s.db.UpdateRunComplete(run.ID, runStatusInterrupted, time.Now()); err != nil {
+				s.logger.Errorf("Failed to update workflow run record: %v", err)
+				continue
+			}
+			interrupted++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.ResetResponse{Interrupted: interrupted})
+}
+
+// StopWorkflow stops the most recently started run, as StopRun does.
+func (s *Server) StopWorkflow(w http.ResponseWriter, r *http.Request, params api.StopWorkflowParams) {
+	s.mu.Lock()
+	runID := s.currentRunID
+	s.mu.Unlock()
+	s.stopRun(w, runID, params.Mode)
+}
+
+// StopRun stops an executing run. Mode "now" (the default) cancels it
+// immediately; "graceful" lets the current item finish and skips the rest, and
+// can still be followed by "now".
+func (s *Server) StopRun(w http.ResponseWriter, r *http.Request, id int, params api.StopRunParams) {
+	s.stopRun(w, int64(id), params.Mode)
+}
+
+// stopRun stops the run runID in modeParam, "now" if unset, and writes the
+// response.
+func (s *Server) stopRun(w http.ResponseWriter, runID int64, modeParam *string) {
+	mode := stopModeNow
+	if modeParam != nil && *modeParam != "" {
+		mode = *modeParam
+	}
+	if mode != stopModeNow && mode != stopModeGraceful {
+		http.Error(w, fmt.Sprintf("Unknown stop mode %q (use now or graceful)", mode), http.StatusBadRequest)
+		return
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.cancelFn != nil {
-		s.cancelFn()
-		s.cancelFn = nil
-		s.logger.Infof("Workflow stop requested by user")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+	run := s.runs[runID]
+	if run == nil || run.cancel == nil {
+		http.Error(w, "No workflow running", http.StatusNotFound)
+		return
+	}
+
+	s.stopRunLocked(runID, run, mode)
+	status := "stopped"
+	if mode == stopModeGraceful {
+		status = "stopping"
+		s.logger.Infof("Graceful stop of run %d requested by user; waiting for the current item to finish", runID)
+	} else {
+		s.logger.Infof("Stop of run %d requested by user", runID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": status, "mode": mode})
+}
+
+// stopRunLocked asks the executing run runID to stop in mode. The caller
+// holds s.mu.
+func (s *Server) stopRunLocked(runID int64, run *runControl, mode string) {
+	run.stopMode = mode
+	if runID == s.currentRunID {
+		s.state.SetStopMode(mode)
+	}
+	if mode == stopModeGraceful {
+		run.gracefulStop()
+	} else {
+		run.cancel(nil)
+		run.cancel = nil
+	}
+}
+
+// TriggerJob triggers a single job on an instance from the instances file,
+// without a workflow, run state or history, and responds once the build has
+// reached the requested wait mode (started by default). It can be used while
+// a workflow is running.
+func (s *Server) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	var req api.TriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Instance == "" || req.Job == "" {
+		http.Error(w, "Instance and job are required", http.StatusBadRequest)
+		return
+	}
+	wait := config.WaitStarted
+	if req.Wait != nil && *req.Wait != "" {
+		wait = *req.Wait
+	}
+	switch wait {
+	case config.WaitQueued, config.WaitStarted, config.WaitCompleted:
+	default:
+		http.Error(w, fmt.Sprintf("Unknown wait mode %q (use queued, started or completed)", wait), http.StatusBadRequest)
+		return
+	}
+
+	opts := s.loadOptions()
+	if req.Profile != nil {
+		opts.Profile = *req.Profile
+	}
+	inst, err := config.LoadInstance(s.instancesPath, req.Instance, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var params map[string]string
+	if req.Params != nil {
+		params = *req.Params
+	}
+
+	res, err := workflow.TriggerJob(r.Context(), inst, req.Job, params, wait, time.Duration(s.pollDefaults.JenkinsSecs)*time.Second, s.logger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	http.Error(w, "No workflow running", http.StatusNotFound)
+	result := api.TriggerResult{QueueUrl: strPtr(res.QueueURL), Result: strPtr(res.Result)}
+	if res.BuildURL != "" {
+		result.BuildUrl = strPtr(res.BuildURL)
+	}
+	if res.BuildNumber > 0 {
+		result.BuildNumber = intPtr(res.BuildNumber)
+	}
+	if t := res.Tests; t != nil {
+		result.Tests = &api.TestResults{Passed: intPtr(t.Passed), Failed: intPtr(t.Failed), Skipped: intPtr(t.Skipped), Total: intPtr(t.Total())}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetHealth reports that the server is up. It never requires an API key.
+func (s *Server) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Health{Status: "ok"})
+}
+
+// GetVersion returns the build information of the running binary.
+func (s *Server) GetVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.VersionInfo{
+		Version:   strPtr(version.Version),
+		Commit:    strPtr(version.Commit),
+		BuildDate: strPtr(version.BuildDate),
+	})
 }
 
 // GetLogLevel gets the current log level
@@ -542,15 +1468,26 @@ func (s *Server) SetLogLevel(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(api.LogLevelRequest{Level: &levelStr})
 }
 
-// resolveUsedInputs scans param values for ${var} references and returns a map
-// of input key -> resolved value for inputs that are actually referenced.
-func resolveUsedInputs(params map[string]string, inputs map[string]string) map[string]string {
-	if len(params) == 0 || len(inputs) == 0 {
+// stepWait returns the step's wait mode for its state, or "" for the default
+// of waiting until the build completes.
+func stepWait(step config.Step) string {
+	if step.WaitMode() == config.WaitCompleted {
+		return ""
+	}
+	return step.Wait
+}
+
+// resolveUsedInputs scans param values for ${var} references (directly or via
+// workflow vars) and returns a map of input key -> resolved value for inputs
+// that are actually referenced.
+func resolveUsedInputs(cfg *config.Config, params map[string]string) map[string]string {
+	if len(params) == 0 || len(cfg.Inputs) == 0 {
 		return nil
 	}
+	inputs := cfg.MaskInputs(cfg.Inputs)
 	used := map[string]string{}
 	for _, v := range params {
-		for _, varName := range config.FindTemplateVars(v) {
+		for _, varName := range cfg.InputRefs(v) {
 			if val, ok := inputs[varName]; ok {
 				used[varName] = val
 			}
@@ -562,11 +1499,13 @@ func resolveUsedInputs(params map[string]string, inputs map[string]string) map[s
 	return used
 }
 
-// configToStateItems converts config workflow items to state items.
+// configToStateItems converts config workflow and finally items to state items,
+// indexed as in cfg.AllItems.
 func (s *Server) configToStateItems(cfg *config.Config) []WorkflowItemState {
-	items := make([]WorkflowItemState, len(cfg.Workflow))
+	all := cfg.AllItems()
+	items := make([]WorkflowItemState, len(all))
 
-	for i, item := range cfg.Workflow {
+	for i, item := range all {
 		if item.IsParallel() {
 			steps := make([]StepState, len(item.Parallel.Steps))
 			for j, step := range item.Parallel.Steps {
@@ -575,10 +1514,13 @@ func (s *Server) configToStateItems(cfg *config.Config) []WorkflowItemState {
 					Instance:   step.Instance,
 					Job:        step.Job,
 					Status:     StatusPending,
-					UsedInputs: resolveUsedInputs(step.Params, cfg.Inputs),
+					UsedInputs: resolveUsedInputs(cfg, step.Params),
+					Wait:       stepWait(step),
+					Stage:      step.Stage,
 				}
 			}
 			items[i] = WorkflowItemState{
+				Name:       item.ItemName(),
 				IsParallel: true,
 				IsPRWait:   false,
 				Parallel: &ParallelGroupState{
@@ -594,6 +1536,7 @@ func (s *Server) configToStateItems(cfg *config.Config) []WorkflowItemState {
 				htmlURL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.PRNumber)
 			}
 			items[i] = WorkflowItemState{
+				Name:       item.ItemName(),
 				IsParallel: false,
 				IsPRWait:   true,
 				PRWait: &PRWaitState{
@@ -607,11 +1550,41 @@ func (s *Server) configToStateItems(cfg *config.Config) []WorkflowItemState {
 					Status:           StatusPending,
 					HTMLURL:          htmlURL,
 					Title:            pr.ResolvedTitle,
+					PollSecs:         cmp.Or(pr.PollSecs, 30),
+					PollMaxSecs:      prPollMax(pr),
+				},
+			}
+		} else if item.IsGitHubStatus() {
+			// Rendered as a step so the existing step callbacks and UI apply.
+			gs := item.GitHubStatus
+			items[i] = WorkflowItemState{
+				Name:       item.ItemName(),
+				IsParallel: false,
+				IsPRWait:   false,
+				Step: &StepState{
+					Name:     gs.Name,
+					Instance: "github",
+					Job:      fmt.Sprintf("%s/%s", gs.Owner, gs.Repo),
+					Status:   StatusPending,
+				},
+			}
+		} else if item.IsCommand() {
+			// Rendered as a step, like GitHub statuses, with the command line as its job.
+			cmd := item.Command
+			items[i] = WorkflowItemState{
+				Name: item.ItemName(),
+				Step: &StepState{
+					Name:       cmd.Name,
+					Instance:   "local",
+					Job:        cmd.Run,
+					Status:     StatusPending,
+					UsedInputs: resolveUsedInputs(cfg, map[string]string{"run": cmd.Run}),
 				},
 			}
 		} else {
 			step := item.AsStep()
 			items[i] = WorkflowItemState{
+				Name:       item.ItemName(),
 				IsParallel: false,
 				IsPRWait:   false,
 				Step: &StepState{
@@ -619,15 +1592,50 @@ func (s *Server) configToStateItems(cfg *config.Config) []WorkflowItemState {
 					Instance:   step.Instance,
 					Job:        step.Job,
 					Status:     StatusPending,
-					UsedInputs: resolveUsedInputs(step.Params, cfg.Inputs),
+					UsedInputs: resolveUsedInputs(cfg, step.Params),
+					Wait:       stepWait(step),
+					Stage:      step.Stage,
 				},
 			}
 		}
+		items[i].IsFinally = cfg.IsFinallyIndex(i)
+		markDisabledInFile(cfg, i, &items[i])
 	}
 
 	return items
 }
 
+// markDisabledInFile shows the steps of item i that the workflow file turns
+// off as skipped from the start, since they will not run.
+func markDisabledInFile(cfg *config.Config, i int, item *WorkflowItemState) {
+	skip := func(status *StepStatus, reason *string) {
+		*status, *reason = StatusSkipped, workflow.SkipReasonDisabledInFile
+	}
+	switch {
+	case item.PRWait != nil:
+		if cfg.IsDisabled(i, 0) {
+			skip(&item.PRWait.Status, &item.PRWait.SkipReason)
+		}
+	case item.Parallel != nil:
+		all := true
+		for j := range item.Parallel.Steps {
+			step := &item.Parallel.Steps[j]
+			if cfg.IsDisabled(i, j) {
+				skip(&step.Status, &step.SkipReason)
+			} else {
+				all = false
+			}
+		}
+		if all {
+			item.Parallel.Status = StatusSkipped
+		}
+	case item.Step != nil:
+		if cfg.IsDisabled(i, 0) {
+			skip(&item.Step.Status, &item.Step.SkipReason)
+		}
+	}
+}
+
 // filterPRWaitOnlyInputs returns a copy of cfg.Inputs excluding inputs that are
 // only referenced by PR wait steps (those are editable on the PR wait card instead).
 func filterPRWaitOnlyInputs(cfg *config.Config) map[string]string {
@@ -637,18 +1645,29 @@ func filterPRWaitOnlyInputs(cfg *config.Config) map[string]string {
 
 	// Collect all input vars used by non-PR-wait steps
 	usedBySteps := map[string]bool{}
-	for _, item := range cfg.Workflow {
+	for _, item := range cfg.AllItems() {
 		if item.IsParallel() {
 			for _, step := range item.Parallel.Steps {
 				for _, v := range step.Params {
-					for _, varName := range config.FindTemplateVars(v) {
+					for _, varName := range cfg.InputRefs(v) {
 						usedBySteps[varName] = true
 					}
 				}
 			}
+		} else if item.IsGitHubStatus() {
+			gs := item.GitHubStatus
+			for _, v := range []string{gs.Owner, gs.Repo, gs.SHA, gs.State, gs.Context, gs.Description, gs.TargetURL, gs.Comment} {
+				for _, varName := range cfg.InputRefs(v) {
+					usedBySteps[varName] = true
+				}
+			}
+		} else if item.IsCommand() {
+			for _, varName := range cfg.InputRefs(item.Command.Run) {
+				usedBySteps[varName] = true
+			}
 		} else if !item.IsPRWait() {
 			for _, v := range item.Params {
-				for _, varName := range config.FindTemplateVars(v) {
+				for _, varName := range cfg.InputRefs(v) {
 					usedBySteps[varName] = true
 				}
 			}
@@ -664,50 +1683,76 @@ func filterPRWaitOnlyInputs(cfg *config.Config) map[string]string {
 	return filtered
 }
 
-func (s *Server) applyInputSubstitutions(cfg *config.Config) {
-	if cfg == nil || len(cfg.Inputs) == 0 {
-		return
+// createRun records a new run of cfg in the database and returns its ID, or 0
+// if it could not be recorded; the run goes ahead either way.
+func (s *Server) createRun(cfg *config.Config, workflowPath string, meta database.RunMeta) int64 {
+	// Read workflow YAML content for snapshot, with secret values masked
+	configSnapshot := ""
+	if content, err := os.ReadFile(workflowPath); err != nil {
+		s.logger.Infof("WARNING: Failed to read workflow file for snapshot: %v", err)
+	} else if masked, err := cfg.MaskSnapshot(content); err != nil {
+		s.logger.Infof("WARNING: Not storing a config snapshot: %v", err)
+	} else {
+		configSnapshot = string(masked)
 	}
 
-	for i := range cfg.Workflow {
-		item := &cfg.Workflow[i]
-		if !item.IsPRWait() || item.WaitForPR == nil {
-			continue
+	// Create database record if database is available
+	var runID int64
+	if s.db != nil {
+		var err error
+		runID, err = s.db.CreateRun(cfg.Name, workflowPath, configSnapshot, cfg.MaskInputs(cfg.Inputs), cfg.Profile, meta)
+		if err != nil {
+			s.logger.Errorf("Failed to create workflow run record: %v", err)
+			// Continue execution even if database write fails
+		} else {
+			s.logger.Infof("Created workflow run record with ID: %d", runID)
 		}
-
-		pr := item.WaitForPR
-		pr.Name = substituteIfTemplate(pr.Name, cfg.Inputs)
-		pr.Owner = substituteIfTemplate(pr.Owner, cfg.Inputs)
-		pr.Repo = substituteIfTemplate(pr.Repo, cfg.Inputs)
-		pr.HeadBranch = substituteIfTemplate(pr.HeadBranch, cfg.Inputs)
-		pr.WaitFor = substituteIfTemplate(pr.WaitFor, cfg.Inputs)
-	}
-}
-
-func substituteIfTemplate(value string, inputs map[string]string) string {
-	if value == "" || len(inputs) == 0 || !strings.Contains(value, "${") {
-		return value
 	}
-
-	return config.Substitute(value, inputs)
+	return runID
 }
 
-// runWorkflow executes the workflow and updates state.
-func (s *Server) runWorkflow(ctx context.Context, cfg *config.Config, workflowPath string, disabledSet workflow.DisabledSet) {
+// executeWorkflow runs cfg under the database record runID (0 if none),
+// updating state, the record, and notifications. resume is nil for fresh runs,
+// and attempt is the run's recorded attempt: 1 unless a resumed run had
+// already been retried, in which case cfg.Retries is reduced to what is left.
+func (s *Server) executeWorkflow(ctx context.Context, cfg *config.Config, workflowPath string, disabledSet workflow.DisabledSet, runID int64, meta database.RunMeta, resume workflow.ResumeState, attempt int) {
+	// A run abandoned by AdminReset leaves the server to whatever runs next.
+	wasReset := func() bool { return errors.Is(context.Cause(ctx), errStateReset) }
+	s.mu.Lock()
+	control := s.runs[runID]
+	s.mu.Unlock()
 	defer func() {
 		s.mu.Lock()
-		s.cancelFn = nil
+		if control != nil && s.runs[runID] == control {
+			delete(s.runs, runID)
+		}
 		s.mu.Unlock()
+		if control != nil {
+			close(control.done)
+		}
 	}()
 
+	// Tee the engine log into the run state so it can be viewed and stored
+	l := s.logger
+	if runLog := s.state.RunLog(); runLog != nil {
+		l = s.logger.Tee(runLog)
+	}
+
 	start := time.Now()
-	notify := notifier.NewFromWebhook(cfg.SlackWebhook)
+	notify := notifier.NewFromConfig(cfg)
+	if attempt > 1 {
+		cfg.Retries = max(cfg.Retries-(attempt-1), 0)
+	}
 
 	if !notify.HasSlack() {
-		s.logger.Infof("WARN: Slack notifications disabled for workflow %q (define slack_webhook)", workflowPath)
+		if cfg.SlackChannel != "" || cfg.SlackUsername != "" {
+			l.Infof("WARN: slack_channel/slack_username are set for workflow %q but have no effect without slack_webhook", workflowPath)
+		} else {
+			l.Infof("WARN: Slack notifications disabled for workflow %q (define slack_webhook)", workflowPath)
+		}
 	}
 
-	displayName := cfg.Name
+	displayName := cfg.Expand(cfg.Name)
 	if displayName == "" {
 		displayName = filepath.Base(workflowPath)
 	}
@@ -715,90 +1760,305 @@ func (s *Server) runWorkflow(ctx context.Context, cfg *config.Config, workflowPa
 		displayName = "Workflow"
 	}
 
-	// Read workflow YAML content for snapshot
-	configSnapshot := ""
-	if content, err := os.ReadFile(workflowPath); err == nil {
-		configSnapshot = string(content)
-	} else {
-		s.logger.Infof("WARNING: Failed to read workflow file for snapshot: %v", err)
+	// Create a state-aware runner
+	callbacks := &workflowCallbacks{
+		state:         s.state,
+		logger:        l,
+		attemptOffset: attempt - 1,
+		newItems:      func() []WorkflowItemState { return s.configToStateItems(cfg) },
 	}
-
-	// Create database record if database is available
-	var runID int64
-	if s.db != nil {
-		var err error
-		runID, err = s.db.CreateRun(cfg.Name, workflowPath, configSnapshot, cfg.Inputs)
-		if err != nil {
-			s.logger.Errorf("Failed to create workflow run record: %v", err)
-			// Continue execution even if database write fails
-		} else {
-			s.mu.Lock()
-			s.currentRunID = runID
-			s.mu.Unlock()
-			s.logger.Infof("Created workflow run record with ID: %d", runID)
-		}
+	if s.db != nil && runID > 0 {
+		callbacks.db = s.db
+		callbacks.runID = runID
 	}
-
-	// Create a state-aware runner
-	err := workflow.RunWithCallbacks(ctx, cfg, s.logger, &workflowCallbacks{
-		state: s.state,
-	}, disabledSet)
+	err := workflow.ResumeWithCallbacks(ctx, cfg, l, callbacks, disabledSet, resume)
 
 	duration := time.Since(start)
 
 	// Determine final status
 	finalStatus := "success"
 	if err != nil {
-		if ctx.Err() == context.Canceled {
+		if ctx.Err() == context.Canceled || errors.Is(err, workflow.ErrStopped) {
 			finalStatus = "stopped"
 		} else {
 			finalStatus = "failed"
 		}
 	}
+	var stopMode string
+	if control != nil {
+		s.mu.Lock()
+		stopMode = control.stopMode
+		s.mu.Unlock()
+	}
+
+	if wasReset() {
+		l.Infof("Workflow ended after a state reset; its outcome is not recorded")
+		return
+	}
+	l.Infof("Workflow finished with status %s after %s", finalStatus, duration.Round(time.Second))
 
 	// Update database record if available
 	if s.db != nil && runID > 0 {
 		if dbErr := s.db.UpdateRunComplete(runID, finalStatus, time.Now()); dbErr != nil {
 			s.logger.Errorf("Failed to update workflow run record: %v", dbErr)
 		}
+		if finalStatus == "stopped" && stopMode != "" {
+			if dbErr := s.db.SaveRunStopMode(runID, stopMode); dbErr != nil {
+				s.logger.Errorf("Failed to save workflow run stop mode: %v", dbErr)
+			}
+		}
+		if runLog := s.state.RunLog(); runLog != nil {
+			if dbErr := s.db.SaveRunLog(runID, runLog.String()); dbErr != nil {
+				s.logger.Errorf("Failed to save workflow run log: %v", dbErr)
+			}
+		}
 	}
 
+	var message string
 	if err != nil {
+		s.state.SetFailure(failureOf(err))
 		s.state.CompleteWorkflow(false, err.Error())
-		notify.Notify(false, displayName, fmt.Sprintf("Failed after %s: %v", duration.Round(time.Second), err))
+		message = fmt.Sprintf("Failed after %s: %v", duration.Round(time.Second), err)
 	} else {
 		s.state.CompleteWorkflow(true, "")
-		notify.Notify(true, displayName, fmt.Sprintf("Completed successfully in %s", duration.Round(time.Second)))
+		message = fmt.Sprintf("Completed successfully in %s", duration.Round(time.Second))
 	}
+	callbacks.saveState()
+	if meta.Description != "" {
+		message = meta.Description + "\n" + message
+	}
+	notify.Notify(runOutcome(finalStatus, err), displayName, message)
 }
 
-// Helper functions for API conversion
-
-func strPtr(s string) *string {
-	return &s
+// runOutcome classifies a finished run for notify_on: a run stopped by the
+// user or by a build aborted in Jenkins is "aborted", and one that failed
+// because a build was UNSTABLE is "unstable".
+func runOutcome(finalStatus string, err error) string {
+	var failed *workflow.StepFailedError
+	switch {
+	case err == nil:
+		return notifier.OutcomeSuccess
+	case finalStatus == "stopped" || workflow.IsBuildAborted(err):
+		return notifier.OutcomeAborted
+	case errors.As(err, &failed) && failed.Result == "UNSTABLE":
+		return notifier.OutcomeUnstable
+	default:
+		return notifier.OutcomeFailure
+	}
 }
 
-func boolPtr(b bool) *bool {
-	return &b
-}
+// Failure kinds reported as a failed run's errorKind.
+const (
+	failureStep     = "step"      // A step, command or GitHub status failed or had a bad build result
+	failurePRWait   = "pr_wait"   // A wait_for_pr item failed
+	failureQueue    = "queue"     // A queued build never started
+	failureTimeout  = "timeout"   // A build or command ran past its time limit
+	failureAborted  = "aborted"   // A build was aborted in Jenkins
+	failureJobCheck = "job_check" // verify_jobs found missing or unbuildable jobs
+)
 
-func intPtr(i int) *int {
-	return &i
+// failureOf maps a workflow error to its failure kind and the index of the
+// failed item in cfg.AllItems(), or -1 if the error names none. The kind is
+// "" for errors that are not one of the engine's typed failures.
+func failureOf(err error) (string, int) {
+	item := -1
+	var stepErr *workflow.StepError
+	var failed *workflow.StepFailedError
+	var prErr *workflow.PRWaitError
+	switch {
+	case errors.As(err, &stepErr):
+		item = stepErr.ItemIndex
+	case errors.As(err, &failed):
+		item = failed.ItemIndex
+	case errors.As(err, &prErr):
+		item = prErr.ItemIndex
+	}
+
+	var timeout *workflow.TimeoutError
+	var queue *workflow.QueueError
+	var jobs *workflow.JobCheckError
+	switch {
+	case errors.As(err, &timeout):
+		return failureTimeout, item
+	case errors.As(err, &queue):
+		return failureQueue, item
+	case workflow.IsBuildAborted(err):
+		return failureAborted, item
+	case errors.As(err, &jobs):
+		return failureJobCheck, -1
+	case prErr != nil:
+		return failurePRWait, item
+	case item >= 0:
+		return failureStep, item
+	default:
+		return "", -1
+	}
 }
 
-func (s *Server) internalToAPI(state *WorkflowState) *api.WorkflowState {
-	items := make([]api.WorkflowItemState, len(state.Items))
-	for i, item := range state.Items {
+// ResumeInterruptedRun continues the most recent run that a previous process
+// left in "running" state, reattaching to its in-flight Jenkins builds instead
+// of triggering them again. The workflow file is reloaded with the run's
+// recorded inputs and profile. Older interrupted runs, and the most recent one
+// when it cannot be resumed, are marked "interrupted".
+// Call it once after NewServer, before serving requests.
+func (s *Server) ResumeInterruptedRun() error {
+	if s.db == nil {
+		return nil
+	}
+
+	runs, err := s.db.GetRuns(100, 0, database.RunFilter{Status: "running"})
+	if err != nil {
+		return fmt.Errorf("failed to look up interrupted runs: %w", err)
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	for _, stale := range runs[1:] {
+		s.logger.Infof("Marking run %d as interrupted", stale.ID)
+		if err := s.db.UpdateRunComplete(stale.ID, runStatusInterrupted, time.Now()); err != nil {
+			s.logger.Errorf("Failed to update workflow run record: %v", err)
+		}
+	}
+
+	run := runs[0]
+	cfg, disabledSet, resume, err := s.loadInterruptedRun(run)
+	if err != nil {
+		if dbErr := s.db.UpdateRunComplete(run.ID, runStatusInterrupted, time.Now()); dbErr != nil {
+			s.logger.Errorf("Failed to update workflow run record: %v", dbErr)
+		}
+		return fmt.Errorf("cannot resume run %d: %w", run.ID, err)
+	}
+
+	s.logger.Infof("Resuming interrupted run %d of %s", run.ID, run.WorkflowPath)
+	s.state.StartWorkflow(run.WorkflowPath, cfg.MaskInputs(cfg.Inputs), s.configToStateItems(cfg))
+	s.state.SetRunMeta(run.Initiator, run.Description, run.Labels)
+	s.state.SetEstimatedDuration(s.estimatedDuration(run.WorkflowPath))
+
+	ctx := s.newRunContext(run.ID)
+
+	go s.executeWorkflow(ctx, cfg, run.WorkflowPath, disabledSet, run.ID, run.RunMeta, resume, run.Attempt)
+	return nil
+}
+
+// loadInterruptedRun rebuilds the config, disabled steps, and resume state of
+// an interrupted run from its database record.
+func (s *Server) loadInterruptedRun(run database.WorkflowRun) (*config.Config, workflow.DisabledSet, workflow.ResumeState, error) {
+	cfg, err := s.loadConfig(run.WorkflowPath, run.Profile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(run.Inputs) > 0 {
+		if cfg.Inputs == nil {
+			cfg.Inputs = make(map[string]string)
+		}
+		// Secret inputs were stored masked; the workflow file's value is used.
+		secret := cfg.SecretInputs()
+		for k, v := range run.Inputs {
+			if secret[k] && v == config.SecretMask {
+				continue
+			}
+			cfg.Inputs[k] = v
+		}
+	}
+	if err := cfg.ApplyInputs(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	steps, err := s.db.GetRunSteps(run.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	disabledSet := make(workflow.DisabledSet)
+	resume := make(workflow.ResumeState)
+	for _, step := range steps {
+		if step.Result == "SKIPPED" {
+			if disabledSet[step.ItemIndex] == nil {
+				disabledSet[step.ItemIndex] = make(map[int]bool)
+			}
+			disabledSet[step.ItemIndex][step.StepIndex] = true
+			continue
+		}
+		if resume[step.ItemIndex] == nil {
+			resume[step.ItemIndex] = make(map[int]workflow.StepProgress)
+		}
+		resume[step.ItemIndex][step.StepIndex] = workflow.StepProgress{
+			QueueURL:    step.QueueURL,
+			BuildURL:    step.BuildURL,
+			Result:      step.Result,
+			BuildNumber: step.BuildNumber,
+		}
+	}
+	return cfg, disabledSet, resume, nil
+}
+
+// Helper functions for API conversion
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func (s *Server) internalToAPI(state *WorkflowState) *api.WorkflowState {
+	items := make([]api.WorkflowItemState, len(state.Items))
+	for i, item := range state.Items {
 		items[i] = s.internalItemToAPI(item)
 	}
 
 	st := string(state.Status)
-	return &api.WorkflowState{
-		Name:   strPtr(state.Name),
-		Status: strPtr(st),
-		Inputs: &state.Inputs,
-		Items:  &items,
+	progress := state.Progress()
+	apiState := &api.WorkflowState{
+		Name:           strPtr(state.Name),
+		Status:         strPtr(st),
+		Inputs:         &state.Inputs,
+		Items:          &items,
+		TotalItems:     intPtr(progress.TotalItems),
+		CompletedItems: intPtr(progress.CompletedItems),
+		FailedItems:    intPtr(progress.FailedItems),
+		TotalSteps:     intPtr(progress.TotalSteps),
+		CompletedSteps: intPtr(progress.CompletedSteps),
+		FailedSteps:    intPtr(progress.FailedSteps),
+	}
+	if state.Initiator != "" {
+		apiState.Initiator = strPtr(state.Initiator)
+	}
+	if state.Description != "" {
+		apiState.Description = strPtr(state.Description)
+	}
+	if len(state.Labels) > 0 {
+		apiState.Labels = &state.Labels
+	}
+	if state.StopMode != "" {
+		apiState.StopMode = strPtr(state.StopMode)
+	}
+	if state.Attempt > 0 {
+		apiState.Attempt = intPtr(state.Attempt)
+	}
+	if state.ErrorKind != "" {
+		apiState.ErrorKind = strPtr(state.ErrorKind)
+	}
+	if state.FailedItem != nil {
+		apiState.FailedItem = intPtr(*state.FailedItem)
+	}
+	if state.StartedAt != nil {
+		apiState.StartedAt = state.StartedAt
+	}
+	if state.EndedAt != nil {
+		apiState.EndedAt = state.EndedAt
+	}
+	if state.Error != "" {
+		apiState.Error = strPtr(state.Error)
+	}
+	if state.EstimatedDuration > 0 {
+		apiState.EstimatedDuration = &state.EstimatedDuration
 	}
+	return apiState
 }
 
 func (s *Server) internalItemToAPI(item WorkflowItemState) api.WorkflowItemState {
@@ -806,6 +2066,12 @@ func (s *Server) internalItemToAPI(item WorkflowItemState) api.WorkflowItemState
 		IsParallel: boolPtr(item.IsParallel),
 		IsPRWait:   boolPtr(item.IsPRWait),
 	}
+	if item.Name != "" {
+		res.Name = strPtr(item.Name)
+	}
+	if item.IsFinally {
+		res.IsFinally = boolPtr(true)
+	}
 
 	if item.Step != nil {
 		res.Step = s.internalStepToAPI(item.Step)
@@ -825,17 +2091,42 @@ func (s *Server) internalItemToAPI(item WorkflowItemState) api.WorkflowItemState
 func (s *Server) internalStepToAPI(step *StepState) *api.StepState {
 	st := string(step.Status)
 	result := &api.StepState{
-		Name:     strPtr(step.Name),
-		Instance: strPtr(step.Instance),
-		Job:      strPtr(step.Job),
-		Status:   strPtr(st),
-		Result:   strPtr(step.Result),
-		Error:    strPtr(step.Error),
-		BuildUrl: strPtr(step.BuildURL),
+		Name:      strPtr(step.Name),
+		Instance:  strPtr(step.Instance),
+		Job:       strPtr(step.Job),
+		Status:    strPtr(st),
+		Result:    strPtr(step.Result),
+		Error:     strPtr(step.Error),
+		BuildUrl:  strPtr(step.BuildURL),
+		StartedAt: step.StartedAt,
+		EndedAt:   step.EndedAt,
 	}
 	if step.BuildNumber > 0 {
 		result.BuildNumber = intPtr(step.BuildNumber)
 	}
+	if step.SkipReason != "" {
+		result.SkipReason = strPtr(step.SkipReason)
+	}
+	if step.Wait != "" {
+		result.Wait = strPtr(step.Wait)
+	}
+	if step.Stage != "" {
+		result.Stage = strPtr(step.Stage)
+	}
+	if step.Output != "" {
+		result.Output = strPtr(step.Output)
+	}
+	if step.QueueStatus != "" {
+		result.QueueStatus = strPtr(step.QueueStatus)
+	}
+	if t := step.Tests; t != nil {
+		result.Tests = &api.TestResults{
+			Passed:  intPtr(t.Passed),
+			Failed:  intPtr(t.Failed),
+			Skipped: intPtr(t.Skipped),
+			Total:   intPtr(t.Passed + t.Failed + t.Skipped),
+		}
+	}
 	if len(step.UsedInputs) > 0 {
 		m := make(map[string]string, len(step.UsedInputs))
 		for k, v := range step.UsedInputs {
@@ -843,6 +2134,13 @@ func (s *Server) internalStepToAPI(step *StepState) *api.StepState {
 		}
 		result.UsedInputs = &m
 	}
+	if len(step.Params) > 0 {
+		m := make(map[string]string, len(step.Params))
+		for k, v := range step.Params {
+			m[k] = v
+		}
+		result.Params = &m
+	}
 	return result
 }
 
@@ -863,7 +2161,7 @@ func (s *Server) internalParallelToAPI(p *ParallelGroupState) *api.ParallelGroup
 func (s *Server) internalPRWaitToAPI(pr *PRWaitState) *api.PRWaitState {
 	st := string(pr.Status)
 	auto := pr.AutoUpdateBranch
-	return &api.PRWaitState{
+	res := &api.PRWaitState{
 		Name:             strPtr(pr.Name),
 		Owner:            strPtr(pr.Owner),
 		Repo:             strPtr(pr.Repo),
@@ -874,32 +2172,152 @@ func (s *Server) internalPRWaitToAPI(pr *PRWaitState) *api.PRWaitState {
 		Status:           strPtr(st),
 		HtmlUrl:          strPtr(pr.HTMLURL),
 		Title:            strPtr(pr.Title),
+		StartedAt:        pr.StartedAt,
+		EndedAt:          pr.EndedAt,
+	}
+	if pr.Error != "" {
+		res.Error = strPtr(pr.Error)
+	}
+	if pr.SkipReason != "" {
+		res.SkipReason = strPtr(pr.SkipReason)
 	}
+	if pr.PollSecs > 0 {
+		res.PollSecs = intPtr(pr.PollSecs)
+	}
+	if pr.PollMaxSecs > 0 {
+		res.PollMaxSecs = intPtr(pr.PollMaxSecs)
+	}
+	return res
+}
+
+// prPollMax returns the cap on a PR wait's backed-off poll interval, or 0
+// when it does not back off.
+func prPollMax(pr *config.PRWait) int {
+	if !pr.PollBackoff {
+		return 0
+	}
+	return cmp.Or(pr.PollMaxSecs, 300)
 }
 
 // workflowCallbacks implements the callback interface for state updates.
+// When db is set, step progress is also recorded under runID so the run can
+// be resumed after a restart, along with a snapshot of the state each time a
+// step or PR wait finishes.
 type workflowCallbacks struct {
-	state *StateManager
+	state  *StateManager
+	logger *logger.Logger
+	db     *database.DB
+	runID  int64
+
+	// attemptOffset is added to the engine's attempt numbers; it is non-zero
+	// when a resumed run had already been retried.
+	attemptOffset int
+	// newItems returns fresh item states for a retry.
+	newItems func() []WorkflowItemState
+}
+
+// saveStep records step progress; failures are logged and otherwise ignored.
+func (c *workflowCallbacks) saveStep(step database.RunStep) {
+	if c.db == nil {
+		return
+	}
+	step.RunID = c.runID
+	if err := c.db.SaveRunStep(step); err != nil {
+		c.logger.Errorf("Failed to record step progress: %v", err)
+	}
+}
+
+// saveState stores a snapshot of the run's state, so the history keeps which
+// items finished and how even if the server dies before the run completes.
+// Failures are logged and otherwise ignored.
+func (c *workflowCallbacks) saveState() {
+	if c.db == nil {
+		return
+	}
+	data, err := c.state.MarshalState()
+	if err != nil {
+		c.logger.Errorf("Failed to encode the run state: %v", err)
+		return
+	}
+	if data == nil {
+		return
+	}
+	if err := c.db.SaveRunState(c.runID, string(data)); err != nil {
+		c.logger.Errorf("Failed to record the run state: %v", err)
+	}
+}
+
+func (c *workflowCallbacks) OnRunRetry(attempt int, err error) {
+	attempt += c.attemptOffset
+	c.state.RetryWorkflow(attempt, c.newItems())
+	if c.db == nil {
+		return
+	}
+	if dbErr := c.db.ClearRunSteps(c.runID); dbErr != nil {
+		c.logger.Errorf("Failed to clear step progress for the retry: %v", dbErr)
+	}
+	if dbErr := c.db.SaveRunAttempt(c.runID, attempt); dbErr != nil {
+		c.logger.Errorf("Failed to record the run attempt: %v", dbErr)
+	}
 }
 
 func (c *workflowCallbacks) OnStepStart(itemIndex, stepIndex int, name, buildURL string) {
 	c.state.UpdateStepStatus(itemIndex, stepIndex, StatusRunning, "", "", buildURL)
 }
 
+func (c *workflowCallbacks) OnStepQueued(itemIndex, stepIndex int, name, queueURL string, params map[string]string) {
+	c.state.SetStepParams(itemIndex, stepIndex, params)
+	c.saveStep(database.RunStep{ItemIndex: itemIndex, StepIndex: stepIndex, StepName: name, QueueURL: queueURL, Params: params})
+}
+
+func (c *workflowCallbacks) OnStepBuildStarted(itemIndex, stepIndex int, name, buildURL string) {
+	c.state.UpdateStepStatus(itemIndex, stepIndex, StatusRunning, "", "", buildURL)
+	c.saveStep(database.RunStep{ItemIndex: itemIndex, StepIndex: stepIndex, StepName: name, BuildURL: buildURL})
+}
+
 func (c *workflowCallbacks) OnStepComplete(itemIndex, stepIndex int, name, result string, buildNumber int, err error) {
 	errMsg := ""
 	status := StatusSuccess
-	if err != nil {
+	if workflow.IsAborted(err) || workflow.IsBuildAborted(err) {
+		errMsg = err.Error()
+		status = StatusAborted
+	} else if err != nil {
 		errMsg = err.Error()
 		status = StatusFailed
-	} else if result != "SUCCESS" {
+	} else if !workflow.IsSuccess(result) {
 		status = StatusFailed
 	}
 	c.state.UpdateStepStatusWithBuild(itemIndex, stepIndex, status, result, errMsg, "", buildNumber)
+	if result != "" {
+		c.saveStep(database.RunStep{ItemIndex: itemIndex, StepIndex: stepIndex, StepName: name, Result: result, BuildNumber: buildNumber})
+	}
+	c.saveState()
+}
+
+func (c *workflowCallbacks) OnStepSkipped(itemIndex, stepIndex int, name, reason string) {
+	c.state.SkipStep(itemIndex, stepIndex, reason)
+	c.saveStep(database.RunStep{ItemIndex: itemIndex, StepIndex: stepIndex, StepName: name, Result: "SKIPPED", SkipReason: reason})
+	c.saveState()
+}
+
+func (c *workflowCallbacks) OnStepOutput(itemIndex, stepIndex int, name, output string) {
+	c.state.SetStepOutput(itemIndex, stepIndex, output)
+}
+
+func (c *workflowCallbacks) OnStepQueueStatus(itemIndex, stepIndex int, name string, status jenkins.QueueStatus) {
+	c.state.SetStepQueueStatus(itemIndex, stepIndex, status.String())
 }
 
-func (c *workflowCallbacks) OnStepSkipped(itemIndex, stepIndex int, name string) {
-	c.state.UpdateStepStatus(itemIndex, stepIndex, StatusSkipped, "SKIPPED", "", "")
+func (c *workflowCallbacks) OnStepTestResults(itemIndex, stepIndex int, name string, results jenkins.TestResults) {
+	c.state.SetStepTests(itemIndex, stepIndex, TestCounts{Passed: results.Passed, Failed: results.Failed, Skipped: results.Skipped})
+}
+
+func (c *workflowCallbacks) OnStepArtifacts(itemIndex, stepIndex int, name string, artifacts []jenkins.Artifact) {
+	refs := make([]database.StepArtifact, len(artifacts))
+	for i, a := range artifacts {
+		refs[i] = database.StepArtifact{FileName: a.FileName, RelativePath: a.RelativePath, URL: a.URL}
+	}
+	c.saveStep(database.RunStep{ItemIndex: itemIndex, StepIndex: stepIndex, StepName: name, Artifacts: refs})
 }
 
 func (c *workflowCallbacks) OnPRWaitStart(itemIndex int, pr *config.PRWait) {
@@ -921,6 +2339,7 @@ func (c *workflowCallbacks) OnPRWaitComplete(itemIndex int, pr *config.PRWait) {
 		c.state.UpdatePRWaitMetadata(itemIndex, pr.PRNumber, pr.ResolvedURL, pr.ResolvedTitle)
 	}
 	c.state.CompletePRWait(itemIndex)
+	c.saveState()
 }
 
 func (c *workflowCallbacks) OnPRWaitFailed(itemIndex int, pr *config.PRWait, err error) {
@@ -932,10 +2351,15 @@ func (c *workflowCallbacks) OnPRWaitFailed(itemIndex int, pr *config.PRWait, err
 		c.state.UpdatePRWaitMetadata(itemIndex, pr.PRNumber, pr.ResolvedURL, pr.ResolvedTitle)
 	}
 	c.state.FailPRWait(itemIndex, errMsg)
+	c.saveState()
 }
 
-func (c *workflowCallbacks) OnPRWaitSkipped(itemIndex int, pr *config.PRWait) {
-	c.state.SkipPRWait(itemIndex)
+func (c *workflowCallbacks) OnPRWaitSkipped(itemIndex int, pr *config.PRWait, reason string) {
+	c.state.SkipPRWait(itemIndex, reason)
+	if pr != nil {
+		c.saveStep(database.RunStep{ItemIndex: itemIndex, StepName: pr.Name, Result: "SKIPPED", SkipReason: reason})
+	}
+	c.saveState()
 }
 
 // handleOpenAPISpec serves the OpenAPI specification as JSON
@@ -976,6 +2400,21 @@ func (s *Server) handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+// handleMetrics serves the process's GitHub API usage in the Prometheus text
+// format. The rate limit gauge is left out until a response has reported it.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	m := github.CurrentMetrics()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintf(w, "# HELP jenkins_flow_github_api_calls_total GitHub API requests sent.\n")
+	fmt.Fprintf(w, "# TYPE jenkins_flow_github_api_calls_total counter\n")
+	fmt.Fprintf(w, "jenkins_flow_github_api_calls_total %d\n", m.APICalls)
+	if m.RateLimitKnown {
+		fmt.Fprintf(w, "# HELP jenkins_flow_github_rate_limit_remaining X-RateLimit-Remaining of the latest GitHub API response.\n")
+		fmt.Fprintf(w, "# TYPE jenkins_flow_github_rate_limit_remaining gauge\n")
+		fmt.Fprintf(w, "jenkins_flow_github_rate_limit_remaining %d\n", m.RateLimitRemaining)
+	}
+}
+
 // GetHistory lists workflow run history with optional filters.
 func (s *Server) GetHistory(w http.ResponseWriter, r *http.Request, params api.GetHistoryParams) {
 	if s.db == nil {
@@ -986,8 +2425,6 @@ func (s *Server) GetHistory(w http.ResponseWriter, r *http.Request, params api.G
 	// Set defaults
 	limit := 50
 	offset := 0
-	workflowPath := ""
-	status := ""
 
 	if params.Limit != nil {
 		limit = *params.Limit
@@ -995,14 +2432,14 @@ func (s *Server) GetHistory(w http.ResponseWriter, r *http.Request, params api.G
 	if params.Offset != nil {
 		offset = *params.Offset
 	}
-	if params.WorkflowPath != nil {
-		workflowPath = *params.WorkflowPath
-	}
-	if params.Status != nil {
-		status = *params.Status
+
+	filter, err := runFilterFromParams(params.WorkflowPath, params.Status, params.Label, params.From, params.To, params.Sort)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	runs, err := s.db.GetRuns(limit, offset, workflowPath, status)
+	runs, err := s.db.GetRuns(limit, offset, filter)
 	if err != nil {
 		s.logger.Errorf("Failed to get workflow runs: %v", err)
 		http.Error(w, "Failed to retrieve workflow runs", http.StatusInternalServerError)
@@ -1011,23 +2448,52 @@ func (s *Server) GetHistory(w http.ResponseWriter, r *http.Request, params api.G
 
 	// Convert to API format
 	apiRuns := make([]api.WorkflowRun, len(runs))
-	for i, run := range runs {
-		apiRuns[i] = api.WorkflowRun{
-			Id:             &run.ID,
-			WorkflowName:   &run.WorkflowName,
-			WorkflowPath:   &run.WorkflowPath,
-			StartTime:      &run.StartTime,
-			EndTime:        run.EndTime,
-			Status:         &run.Status,
-			Inputs:         &run.Inputs,
-			ConfigSnapshot: &run.ConfigSnapshot,
-		}
+	for i := range runs {
+		apiRuns[i] = runToAPI(&runs[i])
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(apiRuns)
 }
 
+// ListRuns lists workflow runs; it is /api/history under the /api/runs paths
+// of the other run endpoints.
+func (s *Server) ListRuns(w http.ResponseWriter, r *http.Request, params api.ListRunsParams) {
+	s.GetHistory(w, r, api.GetHistoryParams(params))
+}
+
+// runFilterFromParams builds a run filter from the query parameters shared by
+// the history and export endpoints. Nil parameters leave the filter open.
+func runFilterFromParams(workflowPath, status, label *string, from, to *time.Time, sort *string) (database.RunFilter, error) {
+	var filter database.RunFilter
+	if workflowPath != nil {
+		filter.WorkflowPath = *workflowPath
+	}
+	if status != nil {
+		filter.Status = *status
+	}
+	if label != nil {
+		filter.Label = *label
+	}
+	if from != nil {
+		filter.From = *from
+	}
+	if to != nil {
+		filter.To = *to
+	}
+	if sort != nil {
+		filter.Sort = database.RunSort(*sort)
+	}
+
+	if !filter.Sort.Valid() {
+		return filter, fmt.Errorf("unknown sort order %q", filter.Sort)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	return filter, nil
+}
+
 // GetHistoryRun retrieves a specific workflow run by ID.
 func (s *Server) GetHistoryRun(w http.ResponseWriter, r *http.Request, id int) {
 	if s.db == nil {
@@ -1046,7 +2512,120 @@ func (s *Server) GetHistoryRun(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 
-	// Convert to API format
+	apiRun := runToAPI(run)
+	steps, err := s.db.GetRunSteps(run.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to get steps of workflow run %d: %v", run.ID, err)
+	} else {
+		apiSteps := make([]api.RunStep, len(steps))
+		for i, step := range steps {
+			apiSteps[i] = runStepToAPI(step)
+		}
+		apiRun.Steps = &apiSteps
+	}
+	if state := s.storedRunState(run.ID); state != nil {
+		apiRun.FinalState = s.internalToAPI(state)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiRun)
+}
+
+// storedRunState decodes the state snapshot recorded for a run, or returns nil
+// when there is none or it cannot be read.
+func (s *Server) storedRunState(runID int64) *WorkflowState {
+	data, err := s.db.GetRunState(runID)
+	if err != nil {
+		s.logger.Errorf("Failed to get the state of workflow run %d: %v", runID, err)
+		return nil
+	}
+	if data == "" {
+		return nil
+	}
+	var state WorkflowState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		s.logger.Errorf("Failed to decode the state of workflow run %d: %v", runID, err)
+		return nil
+	}
+	return &state
+}
+
+// GetRun retrieves a specific workflow run by ID, like GetHistoryRun.
+func (s *Server) GetRun(w http.ResponseWriter, r *http.Request, id int) {
+	s.GetHistoryRun(w, r, id)
+}
+
+// GetRunArtifacts lists the artifact references recorded for a run's steps.
+// The files themselves stay in Jenkins.
+func (s *Server) GetRunArtifacts(w http.ResponseWriter, r *http.Request, id int) {
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := s.db.GetRun(int64(id)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Workflow run not found", http.StatusNotFound)
+		} else {
+			s.logger.Errorf("Failed to get workflow run: %v", err)
+			http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	steps, err := s.db.GetRunSteps(int64(id))
+	if err != nil {
+		s.logger.Errorf("Failed to get run steps: %v", err)
+		http.Error(w, "Failed to retrieve run steps", http.StatusInternalServerError)
+		return
+	}
+
+	artifacts := []api.RunArtifact{}
+	for _, step := range steps {
+		for _, a := range step.Artifacts {
+			artifacts = append(artifacts, api.RunArtifact{
+				ItemIndex:    step.ItemIndex,
+				StepIndex:    step.StepIndex,
+				StepName:     step.StepName,
+				FileName:     a.FileName,
+				RelativePath: a.RelativePath,
+				Url:          a.URL,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artifacts)
+}
+
+// runStepToAPI converts a recorded step to its API form.
+func runStepToAPI(step database.RunStep) api.RunStep {
+	res := api.RunStep{
+		ItemIndex: intPtr(step.ItemIndex),
+		StepIndex: intPtr(step.StepIndex),
+		StepName:  strPtr(step.StepName),
+	}
+	if step.BuildURL != "" {
+		res.BuildUrl = strPtr(step.BuildURL)
+	}
+	if step.BuildNumber > 0 {
+		res.BuildNumber = intPtr(step.BuildNumber)
+	}
+	if step.Result != "" {
+		res.Result = strPtr(step.Result)
+	}
+	if step.SkipReason != "" {
+		res.SkipReason = strPtr(step.SkipReason)
+	}
+	if len(step.Params) > 0 {
+		params := step.Params
+		res.Params = &params
+	}
+	return res
+}
+
+// runToAPI converts a database run record to its API form.
+func runToAPI(run *database.WorkflowRun) api.WorkflowRun {
 	apiRun := api.WorkflowRun{
 		Id:             &run.ID,
 		WorkflowName:   &run.WorkflowName,
@@ -1056,10 +2635,557 @@ func (s *Server) GetHistoryRun(w http.ResponseWriter, r *http.Request, id int) {
 		Status:         &run.Status,
 		Inputs:         &run.Inputs,
 		ConfigSnapshot: &run.ConfigSnapshot,
+		SkipPrCheck:    &run.SkipPRCheck,
+	}
+	if run.Profile != "" {
+		apiRun.Profile = strPtr(run.Profile)
 	}
+	if run.StopMode != "" {
+		apiRun.StopMode = strPtr(run.StopMode)
+	}
+	if run.Attempt > 0 {
+		apiRun.Attempt = intPtr(run.Attempt)
+	}
+	if run.Initiator != "" {
+		apiRun.Initiator = strPtr(run.Initiator)
+	}
+	if run.Description != "" {
+		apiRun.Description = strPtr(run.Description)
+	}
+	if len(run.Labels) > 0 {
+		apiRun.Labels = &run.Labels
+	}
+	if run.RerunOf != 0 {
+		apiRun.RerunOf = &run.RerunOf
+	}
+	return apiRun
+}
 
+// GetRunConfig serves the workflow YAML recorded when a run started, as a
+// download named after the run and workflow file.
+func (s *Server) GetRunConfig(w http.ResponseWriter, r *http.Request, id int) {
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	run, err := s.db.GetRun(int64(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Workflow run not found", http.StatusNotFound)
+		} else {
+			s.logger.Errorf("Failed to get workflow run: %v", err)
+			http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+		}
+		return
+	}
+	if run.ConfigSnapshot == "" {
+		http.Error(w, "No config snapshot recorded for this run", http.StatusNotFound)
+		return
+	}
+
+	filename := fmt.Sprintf("run-%d-%s", run.ID, filepath.Base(run.WorkflowPath))
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	io.WriteString(w, run.ConfigSnapshot)
+}
+
+// GetRunDiff compares the config snapshot of a run with its workflow file as
+// it is now. The current file is masked like snapshots are before comparing,
+// so secret values neither leak nor show up as changes.
+func (s *Server) GetRunDiff(w http.ResponseWriter, r *http.Request, id int) {
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	run, err := s.db.GetRun(int64(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Workflow run not found", http.StatusNotFound)
+		} else {
+			s.logger.Errorf("Failed to get workflow run: %v", err)
+			http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+		}
+		return
+	}
+	if run.ConfigSnapshot == "" {
+		http.Error(w, "No config snapshot recorded for this run", http.StatusNotFound)
+		return
+	}
+
+	content, err := os.ReadFile(run.WorkflowPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, "Workflow file no longer exists", http.StatusNotFound)
+		return
+	} else if err != nil {
+		s.logger.Errorf("Failed to read workflow file: %v", err)
+		http.Error(w, "Failed to read workflow file", http.StatusInternalServerError)
+		return
+	}
+	cfg, err := s.loadConfig(run.WorkflowPath, run.Profile)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Current workflow file does not load: %v", err), http.StatusConflict)
+		return
+	}
+	current, err := cfg.MaskSnapshot(content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	oldCfg, err := config.ParseWorkflowSnapshot([]byte(run.ConfigSnapshot))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse config snapshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+	newCfg, err := config.ParseWorkflowSnapshot(current)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse workflow file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	changes, err := config.DiffWorkflows(oldCfg, newCfg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compare workflows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]api.ConfigItemChange, 0, len(changes))
+	for _, c := range changes {
+		item := api.ConfigItemChange{Section: strPtr(c.Section), Index: intPtr(c.Index), Name: strPtr(c.Name), Change: strPtr(c.Change)}
+		if len(c.Fields) > 0 {
+			fields := c.Fields
+			item.Fields = &fields
+		}
+		if len(c.Params) > 0 {
+			params := make([]api.ConfigParamChange, len(c.Params))
+			for i, p := range c.Params {
+				params[i] = api.ConfigParamChange{Name: strPtr(p.Name), Change: strPtr(p.Change)}
+				if p.Step != "" {
+					params[i].Step = strPtr(p.Step)
+				}
+				if p.Change != config.ChangeAdded {
+					params[i].Old = strPtr(p.Old)
+				}
+				if p.Change != config.ChangeRemoved {
+					params[i].New = strPtr(p.New)
+				}
+			}
+			item.Params = &params
+		}
+		items = append(items, item)
+	}
+
+	base := filepath.Base(run.WorkflowPath)
+	unified := config.UnifiedDiff(fmt.Sprintf("run-%d/%s", run.ID, base), "current/"+base, []byte(run.ConfigSnapshot), current)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(apiRun)
+	json.NewEncoder(w).Encode(api.RunConfigDiff{
+		RunId:        intPtr(int(run.ID)),
+		WorkflowPath: strPtr(run.WorkflowPath),
+		Identical:    boolPtr(string(current) == run.ConfigSnapshot),
+		Items:        &items,
+		Unified:      strPtr(unified),
+	})
+}
+
+// GetRunLog returns the engine log of a run. The active run is served from
+// memory; finished runs from the database.
+func (s *Server) GetRunLog(w http.ResponseWriter, r *http.Request, id int) {
+	s.mu.Lock()
+	currentRunID := s.currentRunID
+	s.mu.Unlock()
+
+	if runLog := s.state.RunLog(); runLog != nil && currentRunID == int64(id) && s.state.IsRunning() {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, runLog.String())
+		return
+	}
+
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	text, err := s.db.GetRunLog(int64(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Workflow run not found", http.StatusNotFound)
+		} else {
+			s.logger.Errorf("Failed to get workflow run log: %v", err)
+			http.Error(w, "Failed to retrieve workflow run log", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, text)
+}
+
+// RerunRun starts a new run from the config snapshot, inputs, and profile
+// recorded for a past run, linked to it through rerun_of. Secret values were
+// stored masked, so secret inputs are taken from the current workflow file and
+// a snapshot with other masked values is refused. Differences between the
+// snapshot and the current file are returned as warnings.
+func (s *Server) RerunRun(w http.ResponseWriter, r *http.Request, id int) {
+	if s.refuseWhileStopping(w) {
+		return
+	}
+	if s.state.IsRunning() {
+		http.Error(w, "A workflow is already running", http.StatusConflict)
+		return
+	}
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	run, err := s.db.GetRun(int64(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Workflow run not found", http.StatusNotFound)
+		} else {
+			s.logger.Errorf("Failed to get workflow run: %v", err)
+			http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+		}
+		return
+	}
+	if run.ConfigSnapshot == "" {
+		http.Error(w, "No config snapshot recorded for this run", http.StatusNotFound)
+		return
+	}
+
+	// Loading the snapshot against today's instances file fails if an
+	// instance or alias it references has since been removed.
+	cfg, err := s.loadSnapshot(run.WorkflowPath, run.Profile, []byte(run.ConfigSnapshot))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Config snapshot no longer loads: %v", err), http.StatusConflict)
+		return
+	}
+	content, readErr := os.ReadFile(run.WorkflowPath)
+	var current *config.Config
+	var currentErr error
+	if readErr == nil {
+		current, currentErr = s.loadConfig(run.WorkflowPath, run.Profile)
+	}
+
+	if cfg.Inputs == nil {
+		cfg.Inputs = make(map[string]string)
+	}
+	for k, v := range run.Inputs {
+		cfg.Inputs[k] = v
+	}
+	for k, v := range cfg.Inputs {
+		if v != config.SecretMask {
+			continue
+		}
+		if current == nil || current.Inputs[k] == "" || current.Inputs[k] == config.SecretMask {
+			http.Error(w, fmt.Sprintf("Secret input %q was stored masked and the current workflow file does not provide it", k), http.StatusConflict)
+			return
+		}
+		cfg.Inputs[k] = current.Inputs[k]
+	}
+	if name := maskedSnapshotValue(cfg); name != "" {
+		http.Error(w, fmt.Sprintf("Config snapshot has a masked secret in %s that cannot be restored", name), http.StatusConflict)
+		return
+	}
+	if err := cfg.ApplyInputs(); err != nil {
+		http.Error(w, fmt.Sprintf("Config snapshot no longer loads: %v", err), http.StatusConflict)
+		return
+	}
+
+	var warnings []string
+	switch {
+	case errors.Is(readErr, fs.ErrNotExist):
+		warnings = append(warnings, "Workflow file no longer exists")
+	case readErr != nil:
+		warnings = append(warnings, fmt.Sprintf("Failed to read workflow file: %v", readErr))
+	case currentErr != nil:
+		warnings = append(warnings, fmt.Sprintf("Current workflow file does not load: %v", currentErr))
+	default:
+		warnings = snapshotWarnings(run.ConfigSnapshot, current, content)
+	}
+
+	steps, err := s.db.GetRunSteps(run.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to get workflow run steps: %v", err)
+		http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+		return
+	}
+	disabledSet := workflow.DisabledSet{}
+	for _, step := range steps {
+		if step.SkipReason != workflow.SkipReasonDisabled {
+			continue
+		}
+		if disabledSet[step.ItemIndex] == nil {
+			disabledSet[step.ItemIndex] = make(map[int]bool)
+		}
+		disabledSet[step.ItemIndex][step.StepIndex] = true
+	}
+
+	meta := run.RunMeta
+	meta.RerunOf = run.ID
+	newID, err := s.db.CreateRun(cfg.Name, run.WorkflowPath, run.ConfigSnapshot, cfg.MaskInputs(cfg.Inputs), cfg.Profile, meta)
+	if err != nil {
+		s.logger.Errorf("Failed to create workflow run record: %v", err)
+		http.Error(w, "Failed to create workflow run", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Infof("Created workflow run record with ID: %d, rerunning run %d", newID, run.ID)
+
+	s.state.StartWorkflow(run.WorkflowPath, cfg.MaskInputs(cfg.Inputs), s.configToStateItems(cfg))
+	s.state.SetRunMeta(meta.Initiator, meta.Description, meta.Labels)
+	s.state.SetEstimatedDuration(s.estimatedDuration(run.WorkflowPath))
+
+	ctx := s.newRunContext(newID)
+
+	go s.executeWorkflow(ctx, cfg, run.WorkflowPath, disabledSet, newID, meta, nil, 1)
+
+	resp := api.RerunResponse{RunId: newID, RerunOf: run.ID}
+	if len(warnings) > 0 {
+		resp.Warnings = &warnings
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// maskedSnapshotValue returns where cfg, loaded from a config snapshot, still
+// holds a SecretMask after its inputs were restored, or "" if nowhere. Such a
+// value was a literal secret in the file and is lost.
+func maskedSnapshotValue(cfg *config.Config) string {
+	for _, k := range slices.Sorted(maps.Keys(cfg.Vars)) {
+		if cfg.Vars[k] == config.SecretMask {
+			return fmt.Sprintf("var %q", k)
+		}
+	}
+	for _, item := range cfg.AllItems() {
+		var steps []config.Step
+		if item.IsParallel() {
+			steps = item.Parallel.Steps
+		} else if item.IsStep() {
+			steps = []config.Step{item.AsStep()}
+		}
+		for _, step := range steps {
+			for _, k := range slices.Sorted(maps.Keys(step.Params)) {
+				if step.Params[k] == config.SecretMask {
+					return fmt.Sprintf("param %q of step %q", k, step.Name)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// snapshotWarnings describes how content, the current workflow file loaded as
+// current, differs from a run's config snapshot. The file is masked first, as
+// in GetRunDiff.
+func snapshotWarnings(snapshot string, current *config.Config, content []byte) []string {
+	masked, err := current.MaskSnapshot(content)
+	if err != nil {
+		return []string{fmt.Sprintf("Failed to compare with the current workflow file: %v", err)}
+	}
+	if string(masked) == snapshot {
+		return nil
+	}
+	oldCfg, err := config.ParseWorkflowSnapshot([]byte(snapshot))
+	if err != nil {
+		return []string{fmt.Sprintf("Failed to compare with the current workflow file: %v", err)}
+	}
+	newCfg, err := config.ParseWorkflowSnapshot(masked)
+	if err != nil {
+		return []string{fmt.Sprintf("Failed to compare with the current workflow file: %v", err)}
+	}
+	changes, err := config.DiffWorkflows(oldCfg, newCfg)
+	if err != nil {
+		return []string{fmt.Sprintf("Failed to compare with the current workflow file: %v", err)}
+	}
+	if len(changes) == 0 {
+		return []string{"Workflow file has changed outside its items since the run"}
+	}
+	warnings := make([]string, 0, len(changes))
+	for _, c := range changes {
+		warning := fmt.Sprintf("%s[%d] %q %s since the run", c.Section, c.Index, c.Name, c.Change)
+		fields := c.Fields
+		if len(c.Params) > 0 {
+			fields = append(slices.Clone(fields), "params")
+		}
+		if len(fields) > 0 {
+			warning += " (" + strings.Join(fields, ", ") + ")"
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// GetRunStatus returns the detailed state of a run. The active run is served
+// from the live state; finished runs are rebuilt from the database.
+func (s *Server) GetRunStatus(w http.ResponseWriter, r *http.Request, id int) {
+	s.mu.Lock()
+	currentRunID := s.currentRunID
+	s.mu.Unlock()
+
+	state := s.state.GetState()
+	if state == nil || currentRunID != int64(id) {
+		if s.db == nil {
+			http.Error(w, "Database not available", http.StatusInternalServerError)
+			return
+		}
+		run, err := s.db.GetRun(int64(id))
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "Workflow run not found", http.StatusNotFound)
+			} else {
+				s.logger.Errorf("Failed to get workflow run: %v", err)
+				http.Error(w, "Failed to retrieve workflow run", http.StatusInternalServerError)
+			}
+			return
+		}
+		steps, err := s.db.GetRunSteps(int64(id))
+		if err != nil {
+			s.logger.Errorf("Failed to get run steps: %v", err)
+			http.Error(w, "Failed to retrieve run steps", http.StatusInternalServerError)
+			return
+		}
+		state = s.stateFromRun(run, steps)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.internalToAPI(state))
+}
+
+// stateFromRun rebuilds the state of a run that is not live from its recorded
+// steps. Items come from the run's config snapshot when it parses; otherwise
+// they are inferred from the step rows, which cannot tell a parallel group of
+// one step from a single step and omit items that left no record.
+func (s *Server) stateFromRun(run *database.WorkflowRun, steps []database.RunStep) *WorkflowState {
+	state := &WorkflowState{
+		Name:        run.WorkflowPath,
+		Status:      runStatusToState(run.Status),
+		Inputs:      run.Inputs,
+		StartedAt:   &run.StartTime,
+		EndedAt:     run.EndTime,
+		Initiator:   run.Initiator,
+		Description: run.Description,
+		Labels:      run.Labels,
+		StopMode:    run.StopMode,
+	}
+	if run.Attempt > 1 {
+		state.Attempt = run.Attempt
+	}
+	if state.Inputs == nil {
+		state.Inputs = map[string]string{}
+	}
+
+	if cfg, err := config.ParseWorkflowSnapshot([]byte(run.ConfigSnapshot)); err == nil && len(cfg.AllItems()) > 0 {
+		state.Items = s.configToStateItems(cfg)
+	} else {
+		state.Items = itemsFromSteps(steps)
+	}
+
+	sm := &StateManager{current: state}
+	lastWorkflowItem := -1
+	for _, rs := range steps {
+		step := sm.stepAt(rs.ItemIndex, rs.StepIndex)
+		if step == nil {
+			if rs.Result == "SKIPPED" && rs.ItemIndex < len(state.Items) && state.Items[rs.ItemIndex].PRWait != nil {
+				state.Items[rs.ItemIndex].PRWait.Status = StatusSkipped
+				state.Items[rs.ItemIndex].PRWait.SkipReason = rs.SkipReason
+			}
+			continue
+		}
+		step.Result = rs.Result
+		step.BuildURL = rs.BuildURL
+		step.BuildNumber = rs.BuildNumber
+		step.SkipReason = rs.SkipReason
+		if len(rs.Params) > 0 {
+			step.Params = rs.Params
+		}
+		step.Status = stepStatusFromResult(rs.Result, run.Status)
+		if step.Status != StatusRunning {
+			updated := rs.UpdatedAt
+			step.EndedAt = &updated
+		}
+		if rs.ItemIndex < len(state.Items) && !state.Items[rs.ItemIndex].IsFinally && rs.ItemIndex > lastWorkflowItem {
+			lastWorkflowItem = rs.ItemIndex
+		}
+	}
+
+	for i := range state.Items {
+		item := &state.Items[i]
+		if item.Parallel != nil {
+			sm.updateParallelGroupStatus(item.Parallel)
+		}
+		// PR waits leave no record when they complete; one that a later
+		// workflow item ran after must have been satisfied.
+		if item.PRWait != nil && item.PRWait.Status == StatusPending && (i < lastWorkflowItem || run.Status == "success") {
+			item.PRWait.Status = StatusSuccess
+		}
+	}
+	return state
+}
+
+// itemsFromSteps infers workflow items from recorded step rows, treating an
+// item with more than one step as a parallel group.
+func itemsFromSteps(steps []database.RunStep) []WorkflowItemState {
+	var items []WorkflowItemState
+	for _, rs := range steps {
+		for len(items) <= rs.ItemIndex {
+			items = append(items, WorkflowItemState{Step: &StepState{Status: StatusPending}})
+		}
+		item := &items[rs.ItemIndex]
+		if rs.StepIndex > 0 && !item.IsParallel {
+			first := *item.Step
+			item.IsParallel = true
+			item.Step = nil
+			item.Parallel = &ParallelGroupState{Steps: []StepState{first}}
+		}
+		if item.IsParallel {
+			for len(item.Parallel.Steps) <= rs.StepIndex {
+				item.Parallel.Steps = append(item.Parallel.Steps, StepState{Status: StatusPending})
+			}
+			item.Parallel.Steps[rs.StepIndex].Name = rs.StepName
+		} else {
+			item.Name = rs.StepName
+			item.Step.Name = rs.StepName
+		}
+	}
+	return items
+}
+
+// runStatusToState maps a recorded run status to the workflow state status.
+// Runs that were stopped or interrupted did not fail, so they are aborted.
+func runStatusToState(status string) StepStatus {
+	switch status {
+	case "running":
+		return StatusRunning
+	case "success":
+		return StatusSuccess
+	case "stopped", runStatusInterrupted:
+		return StatusAborted
+	default:
+		return StatusFailed
+	}
+}
+
+// stepStatusFromResult maps a recorded step result to a step status. A step
+// with no result was still in flight: running while the run is, otherwise cut
+// off when the run ended.
+func stepStatusFromResult(result, runStatus string) StepStatus {
+	switch {
+	case result == "":
+		if runStatus == "running" {
+			return StatusRunning
+		}
+		return StatusAborted
+	case result == "SKIPPED":
+		return StatusSkipped
+	case result == workflow.ResultAborted:
+		return StatusAborted
+	case workflow.IsSuccess(result):
+		return StatusSuccess
+	default:
+		return StatusFailed
+	}
 }
 
 // GetDBPath returns the current database path.
@@ -1,18 +1,52 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/treaz/jenkins-flow/pkg/api"
 	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/database"
+	"github.com/treaz/jenkins-flow/pkg/github"
+	"github.com/treaz/jenkins-flow/pkg/jenkins"
 	"github.com/treaz/jenkins-flow/pkg/logger"
+	"github.com/treaz/jenkins-flow/pkg/notifier"
+	"github.com/treaz/jenkins-flow/pkg/version"
+	"github.com/treaz/jenkins-flow/pkg/workflow"
 )
 
+func TestMain(m *testing.M) {
+	// Keep a defaults file in the developer's home out of the tests.
+	config.DefaultsPath = ""
+	os.Exit(m.Run())
+}
+
 func TestHandleListWorkflows(t *testing.T) {
 	// Create temporary directories
 	tmpDir, err := os.MkdirTemp("", "workflows_test_")
@@ -65,7 +99,7 @@ func TestHandleListWorkflows(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// Call handler
-	srv.ListWorkflows(w, req)
+	srv.ListWorkflows(w, req, api.ListWorkflowsParams{})
 
 	// Verify response
 	resp := w.Result()
@@ -148,34 +182,2788 @@ func TestHandleListWorkflows(t *testing.T) {
 	if unknownWF.Valid == nil || *unknownWF.Valid {
 		t.Errorf("expected unknown instance workflow to be valid=false, got %v", unknownWF.Valid)
 	}
-	if unknownWF.Error == nil || *unknownWF.Error == "" {
-		t.Error("expected error for unknown instance workflow")
+	// The unknown instance is on line 3 of unknown_instance.yaml.
+	wantPrefix := filepath.Join(workflowsDir, "unknown_instance.yaml") + ":3: "
+	if unknownWF.Error == nil || !strings.HasPrefix(*unknownWF.Error, wantPrefix) {
+		t.Errorf("expected error for unknown instance workflow prefixed with %q, got %v", wantPrefix, unknownWF.Error)
 	}
 }
 
-func TestApplyInputSubstitutions_PRWaitHeadBranch(t *testing.T) {
-	cfg := &config.Config{
-		Inputs: map[string]string{
-			"git_branch_to_merge": "PAYMENTS-3096_update_threshold",
-		},
-		Workflow: []config.WorkflowItem{
-			{
-				WaitForPR: &config.PRWait{
-					Name:       "Wait for Release PR",
-					Owner:      "chargepoint-emu",
-					Repo:       "nos",
-					HeadBranch: "${git_branch_to_merge}",
-					WaitFor:    "merged",
-				},
-			},
-		},
+func TestListWorkflows_TagFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"deploy.yaml":  "name: Deploy\ndescription: Ships the API\ntags: [deploy, prod]\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/deploy\n",
+		"nightly.yaml": "name: Nightly\ntags: [test]\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/nightly\n",
+		"plain.yaml":   "name: Plain\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/plain\n",
+		"broken.yaml":  "workflow:\n  - name: step1\n",
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workflowsDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	srv := &Server{}
-	srv.applyInputSubstitutions(cfg)
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	router := srv.BuildRouter()
+
+	list := func(query string) []api.WorkflowInfo {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/workflows%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var workflows []api.WorkflowInfo
+		if err := json.NewDecoder(w.Body).Decode(&workflows); err != nil {
+			t.Fatal(err)
+		}
+		return workflows
+	}
+
+	if all := list(""); len(all) != 4 {
+		t.Errorf("expected all 4 workflows without a tag filter, got %d", len(all))
+	}
+
+	tagged := list("?tag=Prod")
+	if len(tagged) != 1 || *tagged[0].Name != "Deploy" {
+		t.Fatalf("expected only Deploy for tag=Prod, got %+v", tagged)
+	}
+	if tagged[0].Tags == nil || strings.Join(*tagged[0].Tags, ",") != "deploy,prod" {
+		t.Errorf("expected Deploy's tags in the response, got %v", tagged[0].Tags)
+	}
+	if tagged[0].Description == nil || *tagged[0].Description != "Ships the API" {
+		t.Errorf("expected Deploy's description in the response, got %v", tagged[0].Description)
+	}
+
+	if none := list("?tag=missing"); len(none) != 0 {
+		t.Errorf("expected no workflows for an unused tag, got %+v", none)
+	}
+}
+
+func TestListWorkflows_Subdirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	files := map[string]string{
+		"root.yaml":              "name: Root\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/root\n",
+		"team-a/deploy.yaml":     "name: Team A Deploy\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/deploy\n",
+		"team-b/nightly/run.yml": "name: Team B Nightly\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/nightly\n",
+		"team-b/README.md":       "not a workflow\n",
+		".git/hooks/config.yaml": "name: Hidden\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(workflowsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, "", logger.New(logger.Error))
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var workflows []api.WorkflowInfo
+	if err := json.NewDecoder(w.Body).Decode(&workflows); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, wf := range workflows {
+		if wf.Valid == nil || !*wf.Valid {
+			t.Errorf("expected %s to be valid, got %v", *wf.Path, wf.Error)
+		}
+		paths = append(paths, *wf.Path)
+	}
+	want := []string{
+		filepath.Join(workflowsDir, "root.yaml"),
+		filepath.Join(workflowsDir, "team-a", "deploy.yaml"),
+		filepath.Join(workflowsDir, "team-b", "nightly", "run.yml"),
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("expected workflows %v, got %v", want, paths)
+	}
+
+	nested := filepath.Join(workflowsDir, "team-a", "deploy.yaml")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(nested)+"/definition", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a nested workflow, got %d: %s", w.Code, w.Body.String())
+	}
+
+	escaped := workflowsDir + "/team-a/../../instances.yaml"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(escaped)+"/definition", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a path climbing out of the workflow dir, got %d", w.Code)
+	}
+}
+
+func TestListWorkflows_MultipleDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	examplesDir := filepath.Join(tmpDir, "examples")
+	files := map[string]string{
+		filepath.Join(workflowsDir, "team-a", "deploy.yaml"):   "name: Deploy\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/deploy\n",
+		filepath.Join(examplesDir, "team-a", "deploy.yaml"):    "name: Example Deploy\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/example\n",
+		filepath.Join(examplesDir, archiveDirName, "old.yaml"): "name: Old\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/old\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir, examplesDir}, "", logger.New(logger.Error))
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var workflows []api.WorkflowInfo
+	if err := json.NewDecoder(w.Body).Decode(&workflows); err != nil {
+		t.Fatal(err)
+	}
+	var displayPaths []string
+	for _, wf := range workflows {
+		if wf.DisplayPath == nil {
+			t.Fatalf("expected a display path for %s", *wf.Path)
+		}
+		displayPaths = append(displayPaths, *wf.DisplayPath)
+	}
+	want := []string{"workflows/team-a/deploy.yaml", "examples/team-a/deploy.yaml"}
+	if strings.Join(displayPaths, ",") != strings.Join(want, ",") {
+		t.Errorf("expected display paths %v, got %v", want, displayPaths)
+	}
+
+	second := filepath.Join(examplesDir, "team-a", "deploy.yaml")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(second)+"/definition", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a workflow in the second directory, got %d: %s", w.Code, w.Body.String())
+	}
+
+	outside := filepath.Join(tmpDir, "other", "deploy.yaml")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(outside)+"/definition", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a path outside every workflow dir, got %d", w.Code)
+	}
+}
+
+func TestWorkflowDefinition_Defaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	defaultsPath := filepath.Join(tmpDir, "defaults.yaml")
+	defaults := `instances:
+  dev:
+    url: http://defaults.example.com
+    token: defaults:token
+  shared:
+    url: http://shared.example.com
+    token: shared:token
+github:
+  auth_env: ORG_GITHUB_TOKEN
+slack_webhook: https://hooks.slack.com/services/T000/B000/SECRET
+poll_secs: 15
+`
+	if err := os.WriteFile(defaultsPath, []byte(defaults), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev := config.DefaultsPath
+	config.DefaultsPath = defaultsPath
+	defer func() { config.DefaultsPath = prev }()
+
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(workflowsDir, "deploy.yaml")
+	workflow := `name: Deploy
+slack_channel: deploys
+workflow:
+  - wait_for_pr:
+      name: Release PR
+      owner: treaz
+      repo: monitor
+      pr_number: 42
+      wait_for: merged
+  - name: Build
+    instance: dev
+    job: /job/build
+  - name: Publish
+    instance: shared
+    job: /job/publish
+`
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/definition", nil))
+	var def api.WorkflowState
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&def) != nil || def.Settings == nil {
+		t.Fatalf("expected the definition with its settings, got %d: %s", w.Code, w.Body.String())
+	}
+	settings := def.Settings
+	if settings.DefaultsFile == nil || *settings.DefaultsFile != defaultsPath {
+		t.Errorf("expected the defaults file to be named, got %v", settings.DefaultsFile)
+	}
+	if settings.SlackWebhook == nil || *settings.SlackWebhook != "https://hooks.slack.com/***" {
+		t.Errorf("expected the defaults' webhook with its path masked, got %v", settings.SlackWebhook)
+	}
+	if settings.SlackChannel == nil || *settings.SlackChannel != "deploys" {
+		t.Errorf("expected the workflow's channel, got %v", settings.SlackChannel)
+	}
+	if settings.GithubAuth == nil || *settings.GithubAuth != "auth_env ORG_GITHUB_TOKEN" {
+		t.Errorf("expected the defaults' github auth, got %v", settings.GithubAuth)
+	}
+	want := map[string]string{"dev": "http://localhost:8080", "shared": "http://shared.example.com"}
+	if settings.Instances == nil || fmt.Sprint(*settings.Instances) != fmt.Sprint(want) {
+		t.Errorf("expected instances %v, got %v", want, settings.Instances)
+	}
+	if pr := (*def.Items)[0].PrWait; pr == nil || pr.PollSecs == nil || *pr.PollSecs != 15 || pr.PollMaxSecs != nil {
+		t.Errorf("expected the default poll interval on the PR wait, got %+v", pr)
+	}
+	if body := w.Body.String(); strings.Contains(body, "SECRET") || strings.Contains(body, "defaults:token") {
+		t.Errorf("expected no credentials in the definition: %s", w.Body.String())
+	}
+}
+
+func TestWorkflowDefinition_DisabledInFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(workflowsDir, "deploy.yaml")
+	data := `name: Deploy
+workflow:
+  - name: Build
+    instance: dev
+    job: /job/build
+  - name: Legacy
+    instance: retired
+    job: /job/legacy
+    enabled: false
+  - enabled: false
+    parallel:
+      name: Smoke
+      steps:
+        - name: Smoke US
+          instance: dev
+          job: /job/smoke
+`
+	if err := os.WriteFile(workflowPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/definition", nil))
+	var def api.WorkflowState
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&def) != nil || def.Items == nil || len(*def.Items) != 3 {
+		t.Fatalf("expected the definition, got %d: %s", w.Code, w.Body.String())
+	}
+	items := *def.Items
+	if build := items[0].Step; build == nil || *build.Status != string(StatusPending) {
+		t.Errorf("expected Build to be pending, got %+v", build)
+	}
+	legacy := items[1].Step
+	if legacy == nil || *legacy.Status != string(StatusSkipped) || legacy.SkipReason == nil || *legacy.SkipReason != workflow.SkipReasonDisabledInFile {
+		t.Errorf("expected Legacy to show as disabled in the file, got %+v", legacy)
+	}
+	if smoke := items[2].Parallel; smoke == nil || *smoke.Status != string(StatusSkipped) || *(*smoke.Steps)[0].Status != string(StatusSkipped) {
+		t.Errorf("expected the disabled group and its steps to be skipped, got %+v", smoke)
+	}
+}
+
+func TestWorkflowDefinition_Stages(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	data := `name: Deploy
+workflow:
+  - name: Build
+    stage: build
+    instance: dev
+    job: /job/build
+  - parallel:
+      steps:
+        - name: Unit
+          stage: test
+          instance: dev
+          job: /job/unit
+        - name: Lint
+          instance: dev
+          job: /job/lint
+`
+	if err := os.WriteFile(workflowPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/definition", nil))
+	var def api.WorkflowState
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&def) != nil || def.Items == nil || len(*def.Items) != 2 {
+		t.Fatalf("expected the definition, got %d: %s", w.Code, w.Body.String())
+	}
+	items := *def.Items
+	if build := items[0].Step; build == nil || build.Stage == nil || *build.Stage != "build" {
+		t.Errorf("expected Build in the build stage, got %+v", build)
+	}
+	steps := *items[1].Parallel.Steps
+	if steps[0].Stage == nil || *steps[0].Stage != "test" {
+		t.Errorf("expected Unit in the test stage, got %+v", steps[0])
+	}
+	if steps[1].Stage != nil {
+		t.Errorf("expected no stage on Lint, got %q", *steps[1].Stage)
+	}
+}
 
-	got := cfg.Workflow[0].WaitForPR.HeadBranch
-	if got != "PAYMENTS-3096_update_threshold" {
-		t.Fatalf("expected head_branch to be substituted, got %q", got)
+func TestRefreshWorkflows(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(workflowsDir, "deploy.yaml")
+	modTime := time.Now().Add(-time.Hour)
+	write := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("name: "+name+"\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/deploy\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Alpha")
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	router := srv.BuildRouter()
+	name := func(method, target string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var workflows []api.WorkflowInfo
+		if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&workflows) != nil || len(workflows) != 1 {
+			t.Fatalf("%s %s: status %d: %s", method, target, w.Code, w.Body.String())
+		}
+		return *workflows[0].Name
+	}
+
+	if got := name(http.MethodGet, "/api/workflows"); got != "Alpha" {
+		t.Fatalf("expected Alpha, got %q", got)
+	}
+	// An edit that keeps the size and modification time is invisible to the cache...
+	write("Omega")
+	if got := name(http.MethodGet, "/api/workflows"); got != "Alpha" {
+		t.Errorf("expected the cached name, got %q", got)
+	}
+	// ...until the list is refreshed.
+	if got := name(http.MethodPost, "/api/workflows/refresh"); got != "Omega" {
+		t.Errorf("expected the refresh to reread the file, got %q", got)
+	}
+	if got := name(http.MethodGet, "/api/workflows"); got != "Omega" {
+		t.Errorf("expected the refreshed name to be kept, got %q", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmpDir, "deploy.yaml")
+	modTime := time.Now().Add(-time.Hour)
+	write := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("name: "+name+"\nworkflow:\n  - name: step1\n    instance: dev\n    job: /job/deploy\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Alpha")
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, "", logger.New(logger.Error))
+	if got := *srv.listWorkflows("")[0].Name; got != "Alpha" {
+		t.Fatalf("expected Alpha, got %q", got)
+	}
+	write("Omega")
+
+	srv.state.StartWorkflow(path, nil, nil)
+	if err := srv.Reload(); err == nil {
+		t.Error("expected a reload to be refused while a workflow is running")
+	}
+	if got := *srv.listWorkflows("")[0].Name; got != "Alpha" {
+		t.Errorf("expected the refused reload to keep the cache, got %q", got)
+	}
+	srv.state.CompleteWorkflow(true, "")
+
+	if err := srv.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := *srv.listWorkflows("")[0].Name; got != "Omega" {
+		t.Errorf("expected the reload to reread the workflow file, got %q", got)
+	}
+
+	if err := os.WriteFile(instancesPath, []byte("instances: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err == nil {
+		t.Error("expected an instances file that does not parse to fail the reload")
+	}
+}
+
+func TestUpdateJSONInputs(t *testing.T) {
+	text := `{
+  "name": "Deploy",
+  "inputs": {
+    "env": "qa",
+    "branch": "main"
+  },
+  "workflow": [{"name": "Deploy", "params": {"env": "prod", "branch": "release"}}]
+}`
+	want := `{
+  "name": "Deploy",
+  "inputs": {
+    "env": "staging",
+    "branch": "fix/\"quoted\""
+  },
+  "workflow": [{"name": "Deploy", "params": {"env": "prod", "branch": "release"}}]
+}`
+	if got := updateJSONInputs(text, map[string]string{"env": "staging", "branch": `fix/"quoted"`}); got != want {
+		t.Errorf("expected only the inputs to change:\n%s\ngot:\n%s", want, got)
+	}
+	if got := updateJSONInputs("not json", map[string]string{"env": "x"}); got != "not json" {
+		t.Errorf("expected invalid JSON to be left alone, got %q", got)
+	}
+}
+
+func TestLoadConfig_LogsWarningsOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "legacy.yaml")
+	if err := os.WriteFile(workflowPath, []byte("name: Legacy\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	for range 2 {
+		if _, err := srv.loadConfig(workflowPath, ""); err != nil {
+			t.Fatalf("loadConfig failed: %v", err)
+		}
+	}
+	if n := strings.Count(logs.String(), "declares no schema version"); n != 1 {
+		t.Errorf("expected the legacy schema warning to be logged once, got %d: %q", n, logs.String())
+	}
+}
+
+func TestJSONWorkflowFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://localhost:8080\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(workflowsDir, "generated.json")
+	content := `{
+	"name": "Generated",
+	"inputs": {"branch": "main", "env": "qa"},
+	"workflow": [{"name": "Build", "instance": "dev", "job": "/job/build", "params": {"BRANCH": "${branch}"}}]
+}
+`
+	if err := os.WriteFile(workflowPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+	var workflows []api.WorkflowInfo
+	if err := json.NewDecoder(w.Body).Decode(&workflows); err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 1 || *workflows[0].Name != "Generated" || !*workflows[0].Valid {
+		t.Fatalf("expected the JSON workflow to be listed as valid, got %+v", workflows)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/definition", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Build"`) {
+		t.Fatalf("expected the JSON workflow definition, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/graph?format=dot", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `n0 [shape=box, label="Build\ndev"];`) {
+		t.Fatalf("expected a DOT graph of the workflow, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(workflowPath)+"/graph?format=svg", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown graph format, got %d", w.Code)
+	}
+
+	outside := filepath.Join(tmpDir, "outside.json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(outside)+"/definition", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a JSON file outside the workflow dirs, got %d", w.Code)
+	}
+
+	// Changed inputs are written back without disturbing the rest of the file.
+	if err := srv.updateWorkflowFile(workflowPath, map[string]string{"branch": `release/"2.0"`}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := os.ReadFile(workflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(content, `"branch": "main"`, `"branch": "release/\"2.0\""`, 1)
+	if string(updated) != want {
+		t.Errorf("unexpected updated file:\n%s", updated)
+	}
+}
+
+func TestCreateAndUpdateWorkflow(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://jenkins.example.com\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	valid := "schema: 2\nname: Deploy\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n"
+	invalid := "schema: 2\nworkflow:\n  - name: Build\n    instance: prod\n    job: /job/build\n"
+
+	var logs strings.Builder
+	l := logger.New(logger.Info)
+	l.SetOutput(&logs)
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, "", l)
+	srv.SetAPIKeys([]string{"editor-key"})
+	router := srv.BuildRouter()
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "editor-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(path, content string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(api.CreateWorkflowRequest{Path: path, Content: content})
+		return send(http.MethodPost, "/api/workflows", string(body))
+	}
+	newPath := filepath.Join(workflowsDir, "team-a", "deploy.yaml")
+	target := "/api/workflows/" + url.PathEscape(newPath)
+
+	if w := create(newPath, valid); w.Code != http.StatusForbidden {
+		t.Fatalf("expected edits to be refused by default, got %d", w.Code)
+	}
+	srv.SetAllowWorkflowEdit(true)
+
+	if w := create(newPath, invalid); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `unknown instance or alias \"prod\"`) {
+		t.Fatalf("expected invalid content to be rejected with its errors, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written for invalid content, got %v", err)
+	}
+	if w := create(newPath, valid); w.Code != http.StatusCreated {
+		t.Fatalf("expected the workflow to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	if got, _ := os.ReadFile(newPath); string(got) != valid {
+		t.Errorf("expected the content to be written, got %q", got)
+	}
+	if w := create(newPath, valid); w.Code != http.StatusConflict {
+		t.Errorf("expected a second create to conflict, got %d", w.Code)
+	}
+	if w := create(filepath.Join(workflowsDir, "..", "escape.yaml"), valid); w.Code != http.StatusForbidden {
+		t.Errorf("expected a path outside the workflow directories to be refused, got %d", w.Code)
+	}
+	if w := create(filepath.Join(workflowsDir, "notes.txt"), valid); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a non-workflow file name to be rejected, got %d", w.Code)
+	}
+
+	updated := strings.Replace(valid, "Deploy", "Deploy v2", 1)
+	if w := send(http.MethodPut, target, invalid); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected an invalid update to be rejected, got %d", w.Code)
+	}
+	if w := send(http.MethodPut, target, updated); w.Code != http.StatusOK {
+		t.Fatalf("expected the workflow to be updated, got %d: %s", w.Code, w.Body.String())
+	}
+	if got, _ := os.ReadFile(newPath); string(got) != updated {
+		t.Errorf("expected the content to be replaced, got %q", got)
+	}
+	if w := send(http.MethodPut, "/api/workflows/"+url.PathEscape(filepath.Join(workflowsDir, "missing.yaml")), valid); w.Code != http.StatusNotFound {
+		t.Errorf("expected updating a missing file to be a 404, got %d", w.Code)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(newPath)); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %v", entries)
+	}
+
+	editor := apiKeyID(sha256.Sum256([]byte("editor-key")))
+	for _, want := range []string{"Audit: workflow " + newPath + " created by " + editor + " (", "Audit: workflow " + newPath + " updated by " + editor + " ("} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected audit line %q in %q", want, logs.String())
+		}
+	}
+}
+
+func TestEditorOf(t *testing.T) {
+	srv := NewServer(8080, "", nil, "", logger.New(logger.Error))
+	if err := srv.SetTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("expected a host name to be rejected as a trusted proxy")
+	}
+	if err := srv.SetTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.9", ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(remoteAddr string, setup func(*http.Request)) *http.Request {
+		r := httptest.NewRequest(http.MethodPut, "/api/workflows/x", nil)
+		r.RemoteAddr = remoteAddr
+		setup(r)
+		return r
+	}
+	forwarded := func(r *http.Request) { r.Header.Set("X-Forwarded-User", "alice") }
+	withKey := func(r *http.Request) {
+		*r = *r.WithContext(context.WithValue(r.Context(), apiKeyIDKey{}, "api-key:0a1b2c3d"))
+	}
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		want string
+	}{
+		{"trusted proxy", request("10.1.2.3:5000", forwarded), "alice (10.1.2.3:5000)"},
+		{"trusted proxy address", request("192.0.2.9:5000", forwarded), "alice (192.0.2.9:5000)"},
+		{"untrusted forwarded user", request("192.0.2.1:1234", forwarded), "192.0.2.1:1234"},
+		{"basic auth", request("192.0.2.1:1234", func(r *http.Request) { r.SetBasicAuth("mallory", "x") }), "192.0.2.1:1234"},
+		{"api key", request("192.0.2.1:1234", withKey), "api-key:0a1b2c3d (192.0.2.1:1234)"},
+		{"api key and untrusted forwarded user", request("192.0.2.1:1234", func(r *http.Request) { forwarded(r); withKey(r) }), "api-key:0a1b2c3d (192.0.2.1:1234)"},
+	}
+	for _, tt := range tests {
+		if got := srv.editorOf(tt.r); got != tt.want {
+			t.Errorf("%s: editorOf = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteWorkflow(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	content := []byte("schema: 2\nname: Deploy\nworkflow:\n  - name: Build\n    command:\n      run: \"true\"\n")
+	path := filepath.Join(workflowsDir, "team-a", "deploy.yaml")
+	write := func() {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+
+	srv := NewServer(8080, filepath.Join(tmpDir, "instances.yaml"), []string{workflowsDir}, "", logger.New(logger.Error))
+	router := srv.BuildRouter()
+	remove := func(path, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/workflows/"+url.PathEscape(path)+query, nil))
+		return w
+	}
+	listed := func() []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+		var infos []api.WorkflowInfo
+		if err := json.NewDecoder(w.Body).Decode(&infos); err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, info := range infos {
+			paths = append(paths, *info.Path)
+		}
+		return paths
+	}
+
+	if w := remove(path, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected deletes to be refused by default, got %d", w.Code)
+	}
+	srv.SetAllowWorkflowEdit(true)
+
+	srv.state.StartWorkflow(path, nil, nil)
+	if w := remove(path, ""); w.Code != http.StatusConflict {
+		t.Errorf("expected a running workflow to be kept, got %d", w.Code)
+	}
+	srv.state.CompleteWorkflow(true, "")
+
+	w := remove(path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the workflow to be archived, got %d: %s", w.Code, w.Body.String())
+	}
+	archived := filepath.Join(workflowsDir, "archive", "team-a", "deploy.yaml")
+	var resp api.DeleteWorkflowResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ArchivedTo == nil || *resp.ArchivedTo != archived {
+		t.Errorf("expected the file to be archived to %s, got %+v", archived, resp)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be moved away, got %v", err)
+	}
+	if got, _ := os.ReadFile(archived); string(got) != string(content) {
+		t.Errorf("expected the archived copy to keep the content, got %q", got)
+	}
+	if paths := listed(); len(paths) != 0 {
+		t.Errorf("expected archived workflows to be hidden from the list, got %v", paths)
+	}
+
+	write()
+	w = remove(path, "")
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || resp.ArchivedTo == nil || *resp.ArchivedTo == archived || !strings.HasPrefix(*resp.ArchivedTo, strings.TrimSuffix(archived, ".yaml")+".") {
+		t.Errorf("expected a second archived copy with a timestamped name, got %d %+v", w.Code, resp)
+	}
+	if w := remove(archived, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected archiving an archived file to be rejected, got %d", w.Code)
+	}
+	if w := remove(archived, "?purge=true"); w.Code != http.StatusOK {
+		t.Errorf("expected the archived file to be purged, got %d", w.Code)
+	}
+	if _, err := os.Stat(archived); !os.IsNotExist(err) {
+		t.Errorf("expected the purged file to be gone, got %v", err)
+	}
+	if w := remove(path, "?purge=true"); w.Code != http.StatusNotFound {
+		t.Errorf("expected deleting a missing file to be a 404, got %d", w.Code)
+	}
+}
+
+func TestPlanWorkflow(t *testing.T) {
+	triggered := false
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		triggered = true
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	instancesContent := "instances:\n  dev:\n    url: " + jenkins.URL + "\n    token: test:token\n"
+	if err := os.WriteFile(instancesPath, []byte(instancesContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "plan.yaml")
+	workflowContent := "name: \"Plan\"\ninputs:\n  branch: main\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n    params:\n      BRANCH: \"${branch}\"\n  - name: Test\n    instance: dev\n    job: /job/test\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, "", logger.New(logger.Error))
+
+	body := `{"workflow":"` + workflowPath + `","inputs":{"branch":"release"},"disabledSteps":[{"itemIndex":1,"stepIndex":0}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/workflows/plan", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.PlanWorkflow(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %d: %s", w.Code, w.Body.String())
+	}
+	if triggered {
+		t.Error("plan request must not contact Jenkins")
+	}
+
+	var plan api.WorkflowPlan
+	if err := json.NewDecoder(w.Body).Decode(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Items == nil || len(*plan.Items) != 2 {
+		t.Fatalf("expected 2 plan items, got %+v", plan.Items)
+	}
+	build := (*plan.Items)[0]
+	steps := *build.Steps
+	if got := (*steps[0].Params)["BRANCH"]; got != "release" {
+		t.Errorf("expected BRANCH=release, got %q", got)
+	}
+	if *steps[0].InstanceUrl != jenkins.URL {
+		t.Errorf("expected instance URL %q, got %q", jenkins.URL, *steps[0].InstanceUrl)
+	}
+	if test := (*plan.Items)[1]; test.Skipped == nil || !*test.Skipped {
+		t.Error("expected disabled Test step to be marked skipped")
+	}
+
+	// Previewing must not persist the overridden inputs.
+	data, err := os.ReadFile(workflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != workflowContent {
+		t.Errorf("plan request modified the workflow file:\n%s", data)
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: http://jenkins.example.com\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	if err := os.WriteFile(workflowPath, []byte("schema: 2\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n  - name: Deploy\n    instance: prod\n    job: /job/deploy\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, "", logger.New(logger.Error))
+	validate := func(body string) (int, api.ValidationResult) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/validate", strings.NewReader(body)))
+		var result api.ValidationResult
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, result
+	}
+
+	code, result := validate(`{"path":"` + workflowPath + `"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status OK, got %d", code)
+	}
+	if result.Valid || len(result.Errors) != 1 || len(result.Warnings) != 0 {
+		t.Fatalf("expected a single error, got %+v", result)
+	}
+	if issue := result.Errors[0]; issue.File == nil || *issue.File != workflowPath || issue.Line == nil || *issue.Line != 6 || !strings.Contains(issue.Message, `unknown instance or alias "prod"`) {
+		t.Errorf("expected the unknown instance located in the file, got %+v", issue)
+	}
+
+	content, _ := json.Marshal("workflow:\n  - name: Deploy\n    instance: prod\n    job: /job/deploy\n")
+	code, result = validate(`{"content":` + string(content) + `}`)
+	if code != http.StatusOK || !result.Valid || len(result.Errors) != 0 {
+		t.Fatalf("expected valid content, got %d %+v", code, result)
+	}
+	if len(result.Warnings) != 2 || !strings.Contains(result.Warnings[0].Message, "no schema version") || result.Warnings[1].Line == nil || *result.Warnings[1].Line != 3 {
+		t.Errorf("expected schema and unknown instance warnings, got %+v", result.Warnings)
+	}
+
+	if code, _ := validate(`{"content":"workflow: [","path":"/etc/deploy.yaml"}`); code != http.StatusForbidden {
+		t.Errorf("expected a path outside the workflow directories to be refused, got %d", code)
+	}
+	if code, _ := validate(`{}`); code != http.StatusBadRequest {
+		t.Errorf("expected a request without path or content to be rejected, got %d", code)
+	}
+}
+
+func TestRunWorkflow_ParamRules(t *testing.T) {
+	var triggered int32
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&triggered, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "release.yaml")
+	workflowContent := "name: Release\ninputs:\n  version: v1.0.0\nworkflow:\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n    params:\n      VERSION: \"${version}\"\n    param_rules:\n      VERSION:\n        pattern: '^v\\d+\\.\\d+\\.\\d+$'\n        required: true\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, "", logger.New(logger.Error))
+
+	for _, input := range []string{"1.0", "", "v1.0.0\\nv2.0.0"} {
+		body := `{"workflow":"` + workflowPath + `","inputs":{"version":"` + input + `"}}`
+		w := httptest.NewRecorder()
+		srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `step "Deploy": param VERSION`) {
+			t.Errorf("version %q: expected 400 naming the step and param, got %d: %s", input, w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		srv.PlanWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/workflows/plan", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("version %q: expected the plan to be rejected too, got %d", input, w.Code)
+		}
+	}
+	if atomic.LoadInt32(&triggered) != 0 {
+		t.Error("rejected runs must not contact Jenkins")
+	}
+	if data, err := os.ReadFile(workflowPath); err != nil || string(data) != workflowContent {
+		t.Errorf("rejected inputs must not be saved, got:\n%s", data)
+	}
+}
+
+func TestRunWorkflow_OutsideWorkflowDirs(t *testing.T) {
+	var triggered int32
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&triggered, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowsDir := filepath.Join(tmpDir, "workflows")
+	if err := os.Mkdir(workflowsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outsidePath := filepath.Join(tmpDir, "outside.yaml")
+	outsideContent := "name: Outside\ninputs:\n  branch: main\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n    params:\n      BRANCH: \"${branch}\"\n"
+	if err := os.WriteFile(outsidePath, []byte(outsideContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{workflowsDir}, "", logger.New(logger.Error))
+
+	for _, path := range []string{outsidePath, filepath.Join(workflowsDir, "..", "outside.yaml")} {
+		body := `{"workflow":"` + path + `","inputs":{"branch":"release"}}`
+		w := httptest.NewRecorder()
+		srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("run %s: expected 403, got %d: %s", path, w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		srv.PlanWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/workflows/plan", strings.NewReader(body)))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("plan %s: expected 403, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+	if srv.state.IsRunning() || atomic.LoadInt32(&triggered) != 0 {
+		t.Error("a refused run must not start")
+	}
+	if data, err := os.ReadFile(outsidePath); err != nil || string(data) != outsideContent {
+		t.Errorf("a refused run must not change the file, got:\n%s", data)
+	}
+}
+
+func TestRunWorkflow_InstanceURLInput(t *testing.T) {
+	triggered := make(chan string, 1)
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		triggered <- r.URL.Path
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  regional:\n    url: ${jenkins_url}\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "regional.yaml")
+	if err := os.WriteFile(workflowPath, []byte("name: Regional\ninputs:\n  jenkins_url: http://jenkins-eu.example.com\nworkflow:\n  - name: Build\n    instance: regional\n    job: /job/build\n    wait: queued\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	body := `{"workflow":"` + workflowPath + `","inputs":{"jenkins_url":"not a url"}}`
+	w := httptest.NewRecorder()
+	srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "does not resolve to an http(s) URL") {
+		t.Fatalf("expected 400 for a malformed instance url, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body = `{"workflow":"` + workflowPath + `","inputs":{"jenkins_url":"` + jenkins.URL + `"}}`
+	w = httptest.NewRecorder()
+	srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("RunWorkflow: status %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case path := <-triggered:
+		if path != "/job/build/buildWithParameters" && path != "/job/build/build" {
+			t.Errorf("unexpected request %s", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("build was not triggered on the instance named by the input")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRunWorkflow_SecretParams(t *testing.T) {
+	const (
+		typedSecret   = "typed-secret-value"
+		fileSecret    = "file-secret-literal"
+		envSecret     = "env-secret-token"
+		defaultSecret = "default-secret-value"
+	)
+	t.Setenv("DEPLOY_TOKEN", envSecret)
+	sent := make(chan url.Values, 1)
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/deploy/buildWithParameters" {
+			http.NotFound(w, r)
+			return
+		}
+		sent <- r.URL.Query()
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "secret.yaml")
+	workflowContent := "name: Secret\ninputs:\n  deploy_key: " + defaultSecret + "\n  region: eu\nworkflow:\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n    wait: queued\n    params:\n      KEY: ${deploy_key}\n      LITERAL: " + fileSecret + "\n      TOKEN: ${DEPLOY_TOKEN}\n      REGION: ${region}\n    secret_params: [KEY, LITERAL, TOKEN]\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	body := `{"workflow":"` + workflowPath + `","inputs":{"deploy_key":"` + typedSecret + `","region":"us"}}`
+	w := httptest.NewRecorder()
+	srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("RunWorkflow: status %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case params := <-sent:
+		if params.Get("KEY") != typedSecret || params.Get("LITERAL") != fileSecret || params.Get("TOKEN") != envSecret {
+			t.Errorf("expected Jenkins to get the real values, got %v", params)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deploy job was not triggered")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	status := httptest.NewRecorder()
+	srv.GetStatus(status, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	run, err := srv.db.GetRun(srv.currentRunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := srv.db.GetRunSteps(run.ID)
+	if err != nil || len(steps) != 1 {
+		t.Fatalf("expected one run step, got %v, %v", steps, err)
+	}
+	stored, _ := json.Marshal(steps[0].Params)
+	finalState, err := srv.db.GetRunState(run.ID)
+	if err != nil || finalState == "" {
+		t.Fatalf("expected a stored run state, got %q, %v", finalState, err)
+	}
+	fileNow, _ := os.ReadFile(workflowPath)
+	for what, text := range map[string]string{
+		"status API":      status.Body.String(),
+		"config_snapshot": run.ConfigSnapshot,
+		"inputs_json":     run.InputsJSON,
+		"step params":     string(stored),
+		"final_state":     finalState,
+	} {
+		for _, secret := range []string{typedSecret, fileSecret, envSecret} {
+			if strings.Contains(text, secret) {
+				t.Errorf("%s contains the secret %q:\n%s", what, secret, text)
+			}
+		}
+	}
+	if !strings.Contains(run.ConfigSnapshot, `LITERAL: "***"`) || strings.Contains(run.ConfigSnapshot, defaultSecret) {
+		t.Errorf("expected secret values in the snapshot to be masked, got:\n%s", run.ConfigSnapshot)
+	}
+	if run.Inputs["deploy_key"] != config.SecretMask || run.Inputs["region"] != "us" {
+		t.Errorf("expected only the secret input to be masked, got %v", run.Inputs)
+	}
+	if steps[0].Params["KEY"] != config.SecretMask || steps[0].Params["REGION"] != "us" {
+		t.Errorf("expected only secret params to be masked, got %v", steps[0].Params)
+	}
+	if !strings.Contains(string(fileNow), "region: us") || !strings.Contains(string(fileNow), "deploy_key: "+defaultSecret) || strings.Contains(string(fileNow), typedSecret) {
+		t.Errorf("expected only the non-secret input to be saved, got:\n%s", fileNow)
+	}
+
+	// The masked value shown in the UI, sent back unchanged, keeps the real one.
+	w = httptest.NewRecorder()
+	body = `{"workflow":"` + workflowPath + `","inputs":{"deploy_key":"***"}}`
+	srv.PlanWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/workflows/plan", strings.NewReader(body)))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), defaultSecret) || !strings.Contains(w.Body.String(), `"KEY":"***"`) {
+		t.Errorf("expected a plan with masked params, got %d: %s", w.Code, w.Body.String())
+	}
+	cfg, _, err := srv.loadRunRequest(api.RunRequest{Workflow: &workflowPath, Inputs: &map[string]string{"deploy_key": config.SecretMask}}, false)
+	if err != nil || cfg.Inputs["deploy_key"] != defaultSecret {
+		t.Errorf("expected the masked input to keep its value, got %v, %v", cfg.Inputs, err)
+	}
+}
+
+func TestRunWorkflow_SecretParamRules(t *testing.T) {
+	const secret = "bad-secret-value"
+	var triggered int32
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&triggered, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "secret.yaml")
+	workflowContent := "name: Secret\ninputs:\n  deploy_key: " + secret + "\nworkflow:\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n    params:\n      KEY: ${deploy_key}\n    secret_params: [KEY]\n    param_rules:\n      KEY:\n        pattern: '^ok-'\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	w := httptest.NewRecorder()
+	srv.RunWorkflow(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{"workflow":"`+workflowPath+`"}`)))
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), secret) || !strings.Contains(w.Body.String(), "param KEY: value does not match") {
+		t.Errorf("expected a 400 without the value, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A run that skips the request checks, such as a rerun, is stopped by
+	// the engine, and the stored run must not show the value either.
+	cfg, err := srv.loadConfig(workflowPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID := srv.createRun(cfg, workflowPath, database.RunMeta{})
+	srv.state.StartWorkflow(workflowPath, cfg.MaskInputs(cfg.Inputs), srv.configToStateItems(cfg))
+	srv.executeWorkflow(srv.newRunContext(runID), cfg, workflowPath, nil, runID, database.RunMeta{}, nil, 1)
+
+	status := httptest.NewRecorder()
+	srv.GetStatus(status, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	finalState, err := srv.db.GetRunState(runID)
+	if err != nil || !strings.Contains(finalState, "param KEY: value does not match") {
+		t.Fatalf("expected the error in the stored state, got %q, %v", finalState, err)
+	}
+	runLog, err := srv.db.GetRunLog(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for what, text := range map[string]string{"status API": status.Body.String(), "final_state": finalState, "run log": runLog} {
+		if strings.Contains(text, secret) {
+			t.Errorf("%s contains the secret:\n%s", what, text)
+		}
+	}
+	if atomic.LoadInt32(&triggered) != 0 {
+		t.Error("a rejected param must not be sent to Jenkins")
+	}
+}
+
+func TestResumeInterruptedRun(t *testing.T) {
+	var triggered int32
+	var jenkins *httptest.Server
+	jenkins = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/3/api/json":
+			w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 3}`))
+		case "/job/deploy/3/testReport/api/json":
+			http.NotFound(w, r)
+		default:
+			atomic.AddInt32(&triggered, 1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	instancesContent := "instances:\n  dev:\n    url: " + jenkins.URL + "\n    token: test:token\n"
+	if err := os.WriteFile(instancesPath, []byte(instancesContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "resume.yaml")
+	workflowContent := "name: \"Resume\"\nworkflow:\n  - name: Lint\n    instance: dev\n    job: /job/lint\n  - name: Build\n    instance: dev\n    job: /job/build\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a previous process that died while Deploy was building.
+	dbPath := filepath.Join(tmpDir, "runs.db")
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, err := db.CreateRun("Resume", workflowPath, workflowContent, nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []database.RunStep{
+		{RunID: runID, ItemIndex: 0, StepName: "Lint", Result: "SKIPPED"},
+		{RunID: runID, ItemIndex: 1, StepName: "Build", BuildURL: jenkins.URL + "/job/build/8/", Result: "SUCCESS", BuildNumber: 8},
+		{RunID: runID, ItemIndex: 2, StepName: "Deploy", QueueURL: jenkins.URL + "/queue/item/4/", BuildURL: jenkins.URL + "/job/deploy/3/"},
+	} {
+		if err := db.SaveRunStep(step); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, dbPath, logger.New(logger.Error))
+	defer srv.db.Close()
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		t.Fatalf("ResumeInterruptedRun failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if srv.state.IsRunning() {
+		t.Fatal("resumed run did not finish")
+	}
+
+	run, err := srv.db.GetRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != "success" {
+		t.Errorf("expected resumed run to succeed, got %q", run.Status)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 0 {
+		t.Errorf("expected no jobs to be triggered, got %d unexpected requests", n)
+	}
+	state := srv.state.GetState()
+	if got := state.Items[0].Step; got.Status != StatusSkipped || got.SkipReason != workflow.SkipReasonDisabled {
+		t.Errorf("expected Lint to stay skipped with a reason, got %q (%q)", got.Status, got.SkipReason)
+	}
+	if got := state.Items[2].Step.BuildNumber; got != 3 {
+		t.Errorf("expected Deploy build #3, got %d", got)
+	}
+}
+
+func TestResumeInterruptedRun_MarksUnresumableInterrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		id, err := srv.db.CreateRun("Gone", filepath.Join(tmpDir, "gone.yaml"), "", nil, "", database.RunMeta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := srv.ResumeInterruptedRun(); err == nil {
+		t.Fatal("expected an error for a run whose workflow file is gone")
+	}
+	for _, id := range ids {
+		run, err := srv.db.GetRun(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != runStatusInterrupted || run.EndTime == nil {
+			t.Errorf("run %d: expected interrupted with an end time, got %q", id, run.Status)
+		}
+	}
+}
+
+func TestAdminReset(t *testing.T) {
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"building": true, "number": 5}`))
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "reset.yaml")
+	workflowContent := "name: Reset\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reattach to a build that never finishes.
+	dbPath := filepath.Join(tmpDir, "runs.db")
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, err := db.CreateRun("Reset", workflowPath, workflowContent, nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveRunStep(database.RunStep{RunID: runID, StepName: "Build", BuildURL: jenkins.URL + "/job/build/5/"}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, dbPath, logger.New(logger.Error))
+	defer srv.db.Close()
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.state.IsRunning() {
+		t.Fatal("expected the resumed run to be running")
+	}
+
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/reset", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp api.ResetResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Interrupted != 1 {
+		t.Errorf("expected 1 interrupted run, got %d", resp.Interrupted)
+	}
+	if srv.state.IsRunning() || srv.state.GetState() != nil || srv.state.LastCompleted() != nil {
+		t.Error("expected the state to be cleared")
+	}
+
+	// The cancelled run winds down without recording an outcome over the reset.
+	time.Sleep(200 * time.Millisecond)
+	run, err := srv.db.GetRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != runStatusInterrupted {
+		t.Errorf("expected the run to stay interrupted, got %q", run.Status)
+	}
+	if srv.state.GetState() != nil {
+		t.Error("expected the cancelled run to leave the state cleared")
+	}
+}
+
+func TestStopWorkflow_Graceful(t *testing.T) {
+	// The first poll of the build blocks until the stop has been requested.
+	entered, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+		w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 5}`))
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	instancesContent := "instances:\n  dev:\n    url: " + jenkins.URL + "\n    token: test:token\n"
+	if err := os.WriteFile(instancesPath, []byte(instancesContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "stop.yaml")
+	workflowContent := "name: \"Stop\"\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reattach to a running build so nothing is triggered.
+	dbPath := filepath.Join(tmpDir, "runs.db")
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, err := db.CreateRun("Stop", workflowPath, workflowContent, nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveRunStep(database.RunStep{RunID: runID, StepName: "Build", BuildURL: jenkins.URL + "/job/build/5/"}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, dbPath, logger.New(logger.Error))
+	defer srv.db.Close()
+	router := srv.BuildRouter()
+	stop := func(mode string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/stop?mode="+mode, nil))
+		return w
+	}
+
+	if w := stop("graceful"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with no run, got %d", w.Code)
+	}
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		t.Fatalf("ResumeInterruptedRun failed: %v", err)
+	}
+	<-entered
+
+	if w := stop("later"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown mode, got %d", w.Code)
+	}
+	w := stop("graceful")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"stopping"`) {
+		t.Fatalf("expected graceful stop to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := srv.state.GetState().StopMode; got != "graceful" {
+		t.Errorf("expected stop mode in state, got %q", got)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if srv.state.IsRunning() {
+		t.Fatal("run did not stop")
+	}
+
+	state := srv.state.GetState()
+	if got := state.Items[0].Step.Status; got != StatusSuccess {
+		t.Errorf("expected the running step to finish, got %q", got)
+	}
+	if got := state.Items[1].Step; got.Status != StatusSkipped || got.SkipReason != workflow.SkipReasonStopped {
+		t.Errorf("expected Deploy to be skipped by the stop, got %q (%q)", got.Status, got.SkipReason)
+	}
+	run, err := srv.db.GetRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != "stopped" || run.StopMode != "graceful" {
+		t.Errorf("expected run stopped gracefully, got %q (%q)", run.Status, run.StopMode)
+	}
+}
+
+func TestStopRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "sleep.yaml")
+	if err := os.WriteFile(workflowPath, []byte("name: Sleep\nworkflow:\n  - command:\n      name: Sleep\n      run: sleep 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+	router := srv.BuildRouter()
+	stop := func(id int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/runs/%d/stop", id), nil))
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{"workflow":"`+workflowPath+`"}`)))
+	var resp struct {
+		Status string `json:"status"`
+		RunID  int64  `json:"runId"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK || resp.RunID == 0 {
+		t.Fatalf("expected the run ID, got %d: %+v, %v", w.Code, resp, err)
+	}
+
+	if w := stop(resp.RunID + 1); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a run that is not running, got %d", w.Code)
+	}
+	status := httptest.NewRecorder()
+	router.ServeHTTP(status, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/status", resp.RunID), nil))
+	if !strings.Contains(status.Body.String(), `"status":"running"`) {
+		t.Errorf("expected the live state of the run, got %s", status.Body.String())
+	}
+	if w := stop(resp.RunID); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"stopped"`) {
+		t.Fatalf("expected the run to be stopped, got %d: %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if srv.state.IsRunning() {
+		t.Fatal("run did not stop")
+	}
+	run, err := srv.db.GetRun(resp.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != "stopped" || run.StopMode != "now" {
+		t.Errorf("expected the run stopped now, got %q (%q)", run.Status, run.StopMode)
+	}
+	if w := stop(resp.RunID); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 once the run has ended, got %d", w.Code)
+	}
+}
+
+func TestStop(t *testing.T) {
+	// start runs a workflow whose first command runs sleep for secs and
+	// returns once the command has begun.
+	start := func(t *testing.T, mode string, secs int) (*Server, string, int64) {
+		t.Helper()
+		tmpDir := t.TempDir()
+		workflowPath := filepath.Join(tmpDir, "shutdown.yaml")
+		marker := filepath.Join(tmpDir, "started")
+		workflowContent := fmt.Sprintf("name: Shutdown\nworkflow:\n  - command:\n      name: Build\n      run: touch %s && sleep %d\n  - command:\n      name: Deploy\n      run: \"true\"\n", marker, secs)
+		if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dbPath := filepath.Join(tmpDir, "runs.db")
+		srv := NewServer(0, "", []string{tmpDir}, dbPath, logger.New(logger.Error))
+		if err := srv.SetShutdownMode(mode); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{"workflow":"`+workflowPath+`"}`)))
+		var resp struct {
+			RunID int64 `json:"runId"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("expected the run to start, got %d: %v", w.Code, err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for _, err := os.Stat(marker); err != nil && time.Now().Before(deadline); _, err = os.Stat(marker) {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return srv, dbPath, resp.RunID
+	}
+	recorded := func(t *testing.T, dbPath string, runID int64) *database.WorkflowRun {
+		t.Helper()
+		db, err := database.NewDB(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		run, err := db.GetRun(runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return run
+	}
+
+	if err := NewServer(0, "", nil, filepath.Join(t.TempDir(), "runs.db"), logger.New(logger.Error)).SetShutdownMode("later"); err == nil {
+		t.Error("expected an unknown shutdown mode to be rejected")
+	}
+
+	t.Run("graceful", func(t *testing.T) {
+		srv, dbPath, runID := start(t, "graceful", 1)
+		port, _, err := srv.StartAsync()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Stop(ctx); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		if _, err := http.Get(fmt.Sprintf("http://localhost:%d/api/version", port)); err == nil {
+			t.Error("expected the HTTP server to be closed")
+		}
+		state := srv.state.GetState()
+		if state.Items[0].Step.Status != StatusSuccess || state.Items[1].Step.Status != StatusSkipped {
+			t.Errorf("expected Build to finish and Deploy to be skipped, got %q and %q", state.Items[0].Step.Status, state.Items[1].Step.Status)
+		}
+		if run := recorded(t, dbPath, runID); run.Status != "stopped" || run.StopMode != "graceful" {
+			t.Errorf("expected the run recorded as stopped gracefully, got %q (%q)", run.Status, run.StopMode)
+		}
+
+		w := httptest.NewRecorder()
+		srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{}`)))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected new runs to be refused, got %d", w.Code)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		srv, dbPath, runID := start(t, "wait", 30)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		began := time.Now()
+		if err := srv.Stop(ctx); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		if elapsed := time.Since(began); elapsed > 5*time.Second {
+			t.Errorf("expected the run to be cancelled at the timeout, took %s", elapsed)
+		}
+		if run := recorded(t, dbPath, runID); run.Status != "stopped" || run.StopMode != "now" {
+			t.Errorf("expected the run recorded as stopped now, got %q (%q)", run.Status, run.StopMode)
+		}
+	})
+}
+
+func TestGetRunLog(t *testing.T) {
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 5}`))
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	instancesContent := "instances:\n  dev:\n    url: " + jenkins.URL + "\n    token: test:token\n"
+	if err := os.WriteFile(instancesPath, []byte(instancesContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "log.yaml")
+	workflowContent := "name: \"Log\"\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n"
+	if err := os.WriteFile(workflowPath, []byte(workflowContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reattach to a finished build so the run completes without triggering.
+	dbPath := filepath.Join(tmpDir, "runs.db")
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, err := db.CreateRun("Log", workflowPath, workflowContent, nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveRunStep(database.RunStep{RunID: runID, StepName: "Build", BuildURL: jenkins.URL + "/job/build/5/"}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	l := logger.New(logger.Info)
+	l.SetOutput(io.Discard)
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, dbPath, l)
+	defer srv.db.Close()
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/status/log", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before any run, got %d", w.Code)
+	}
+
+	if err := srv.ResumeInterruptedRun(); err != nil {
+		t.Fatalf("ResumeInterruptedRun failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if srv.state.IsRunning() {
+		t.Fatal("run did not finish")
+	}
+
+	for _, path := range []string{"/api/status/log", fmt.Sprintf("/api/runs/%d/log", runID)} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, w.Code)
+		}
+		body := w.Body.String()
+		for _, want := range []string{"[INFO] ", "Reattaching to build", "Workflow finished with status success"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: expected %q in log:\n%s", path, want, body)
+			}
+		}
+	}
+
+	// The resumed run is the current one, so its status comes from the live state.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/status", runID), nil))
+	var state api.WorkflowState
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Status == nil || *state.Status != "success" || (*state.Items)[0].Step.BuildNumber == nil || *(*state.Items)[0].Step.BuildNumber != 5 {
+		t.Errorf("expected live state of the finished run, got %+v", state)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/99999/log", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestGetHistory_RunMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	req := api.RunRequest{
+		Initiator:   strPtr(" alice "),
+		Description: strPtr("Hotfix for login"),
+		Labels:      &[]string{"release", " ", "eu"},
+	}
+	meta := runMetaFromRequest(req)
+	if meta.Initiator != "alice" || len(meta.Labels) != 2 {
+		t.Fatalf("unexpected run metadata: %+v", meta)
+	}
+	if _, err := srv.db.CreateRun("Deploy", "deploy.yaml", "", nil, "", meta); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.db.CreateRun("Deploy", "deploy.yaml", "", nil, "", database.RunMeta{Labels: []string{"nightly"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history?label=release", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var runs []api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("expected 1 run labelled release, got %d", len(runs))
+	}
+	run := runs[0]
+	if run.Initiator == nil || *run.Initiator != "alice" || run.Description == nil || *run.Description != "Hotfix for login" {
+		t.Errorf("unexpected run metadata: %+v", run)
+	}
+	if run.Labels == nil || strings.Join(*run.Labels, ",") != "release,eu" {
+		t.Errorf("unexpected labels: %v", run.Labels)
+	}
+}
+
+func TestGetHistory_DateRangeAndSort(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		id, err := srv.db.CreateRun("Deploy", "deploy.yaml", "", nil, "", database.RunMeta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		time.Sleep(10 * time.Millisecond)
+	}
+	router := srv.BuildRouter()
+	get := func(q url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history?"+q.Encode(), nil))
+		return w
+	}
+
+	now := time.Now()
+	w := get(url.Values{
+		"from": {now.Add(-time.Hour).Format(time.RFC3339)},
+		"to":   {now.Add(time.Hour).Format(time.RFC3339)},
+		"sort": {"start_time_asc"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var runs []api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || *runs[0].Id != ids[0] || *runs[1].Id != ids[1] {
+		t.Errorf("expected both runs oldest first, got %+v", runs)
+	}
+
+	w = get(url.Values{"to": {now.Add(-time.Hour).Format(time.RFC3339)}})
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("expected no runs before the range, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, q := range []url.Values{
+		{"sort": {"name"}},
+		{"from": {"yesterday"}},
+		{"from": {now.Format(time.RFC3339)}, "to": {now.Format(time.RFC3339)}},
+	} {
+		if w := get(q); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestRunsEndpoints(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	deployID, err := srv.db.CreateRun("Deploy", "deploy.yaml", "", nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.db.UpdateRunComplete(deployID, "success", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.db.CreateRun("Release", "release.yaml", "", nil, "", database.RunMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	router := srv.BuildRouter()
+
+	for query, want := range map[string]int{
+		"":                           2,
+		"?workflow_path=deploy.yaml": 1,
+		"?status=running":            1,
+		"?status=success&limit=1":    1,
+		"?limit=1&offset=2":          0,
+		"?workflow_path=other.yaml":  0,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", query, w.Code)
+		}
+		var runs []api.WorkflowRun
+		if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != want {
+			t.Errorf("%q: expected %d runs, got %d", query, want, len(runs))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d", deployID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var run api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if *run.Id != deployID || *run.Status != "success" {
+		t.Errorf("unexpected run: %+v", run)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/99999", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestGetHistoryRun_StepSkipReason(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	runID, err := srv.db.CreateRun("Deploy", "deploy.yaml", "", nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := &workflowCallbacks{state: srv.state, logger: srv.logger, db: srv.db, runID: runID}
+	cb.OnStepSkipped(0, 0, "Lint", workflow.SkipReasonDisabled)
+	cb.OnStepComplete(1, 0, "Build", "SUCCESS", 8, nil)
+
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/history/%d", runID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var run api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Steps == nil || len(*run.Steps) != 2 {
+		t.Fatalf("expected 2 recorded steps, got %+v", run.Steps)
+	}
+	lint, build := (*run.Steps)[0], (*run.Steps)[1]
+	if lint.SkipReason == nil || *lint.SkipReason != workflow.SkipReasonDisabled || *lint.Result != "SKIPPED" {
+		t.Errorf("expected Lint skipped with a reason, got %+v", lint)
+	}
+	if build.SkipReason != nil || build.BuildNumber == nil || *build.BuildNumber != 8 {
+		t.Errorf("unexpected Build step: %+v", build)
+	}
+}
+
+func TestRunFinalState(t *testing.T) {
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	if err := os.WriteFile(workflowPath, []byte("name: Deploy\nworkflow:\n  - name: Build\n    instance: dev\n    job: /job/build\n  - name: Ship\n    instance: dev\n    job: /job/ship\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{"workflow":"`+workflowPath+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("RunWorkflow: status %d: %s", w.Code, w.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d", srv.currentRunID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var run api.WorkflowRun
+	if err := json.NewDecoder(w.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	state := run.FinalState
+	if state == nil {
+		t.Fatal("expected the run's final state")
+	}
+	if *state.Status != string(StatusFailed) || state.Error == nil || state.EndedAt == nil || *state.FailedItem != 0 {
+		t.Errorf("expected a failed final state naming the first item, got %+v", state)
+	}
+	items := *state.Items
+	if build := items[0].Step; *build.Status != string(StatusFailed) || build.Error == nil || !strings.Contains(*build.Error, "500") {
+		t.Errorf("expected Build to have failed with the trigger error, got %+v", build)
+	}
+	if ship := items[1].Step; *ship.Status != string(StatusPending) {
+		t.Errorf("expected Ship to be left pending, got %s", *ship.Status)
+	}
+
+	// Runs without a snapshot, such as those recorded before it was kept, have none.
+	runID, err := srv.db.CreateRun("Deploy", workflowPath, "", nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d", runID), nil))
+	if strings.Contains(w.Body.String(), "final_state") {
+		t.Errorf("expected no final state for a run without a snapshot, got %s", w.Body.String())
+	}
+
+	// A snapshot is written as steps finish, before the run completes.
+	cb := &workflowCallbacks{state: srv.state, logger: srv.logger, db: srv.db, runID: runID}
+	cfg, err := srv.loadConfig(workflowPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.state.StartWorkflow(workflowPath, nil, srv.configToStateItems(cfg))
+	cb.OnStepComplete(0, 0, "Build", "SUCCESS", 3, nil)
+	stored := srv.storedRunState(runID)
+	if stored == nil || stored.Status != StatusRunning || stored.Items[0].Step.Status != StatusSuccess || stored.Items[0].Step.BuildNumber != 3 {
+		t.Errorf("expected a snapshot of the running workflow, got %+v", stored)
+	}
+}
+
+func TestGetWorkflowStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	for _, d := range []time.Duration{10 * time.Minute, 40 * time.Minute, 30 * time.Minute} {
+		id, err := srv.db.CreateRun("Deploy", workflowPath, "", nil, "", database.RunMeta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.db.UpdateRunComplete(id, "success", time.Now().Add(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := srv.BuildRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/"+url.PathEscape(path)+"/stats", nil))
+		return w
+	}
+
+	w := get(workflowPath)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats api.WorkflowStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.SampleSize != 3 || stats.MedianDuration == nil || *stats.MedianDuration < 29*60 || *stats.MedianDuration > 30*60 {
+		t.Errorf("expected a ~30m median over 3 runs, got %+v", stats)
+	}
+
+	// Workflows without history report no estimate.
+	w = get(filepath.Join(tmpDir, "other.yaml"))
+	stats = api.WorkflowStats{}
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.SampleSize != 0 || stats.MedianDuration != nil {
+		t.Errorf("expected no estimate, got %+v", stats)
+	}
+
+	if w := get("/etc/passwd"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 outside workflow dirs, got %d", w.Code)
+	}
+
+	srv.state.StartWorkflow(workflowPath, nil, nil)
+	srv.state.SetEstimatedDuration(srv.estimatedDuration(workflowPath))
+	if est := srv.internalToAPI(srv.state.GetState()).EstimatedDuration; est == nil || *est < 29*60 || *est > 30*60 {
+		t.Errorf("expected estimatedDuration of ~30m on the run state, got %v", est)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Write([]byte(`{"number": 1, "state": "open"}`))
+	}))
+	defer gh.Close()
+	client := github.NewClient("", logger.New(logger.Error))
+	client.BaseURL = gh.URL
+	if _, err := client.GetPRStatus(context.Background(), "org", "repo", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(8080, "", nil, "", logger.New(logger.Error))
+	w := httptest.NewRecorder()
+	srv.BuildRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected a text response, got %d (%s)", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	calls := fmt.Sprintf("jenkins_flow_github_api_calls_total %d\n", github.CurrentMetrics().APICalls)
+	if !strings.Contains(body, "# TYPE jenkins_flow_github_api_calls_total counter\n"+calls) || !strings.Contains(body, "jenkins_flow_github_rate_limit_remaining 4321\n") {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+}
+
+func TestExportStatus(t *testing.T) {
+	srv := NewServer(8080, "", nil, "", logger.New(logger.Error))
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/status/export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before any run, got %d", w.Code)
+	}
+
+	srv.state.StartWorkflow("deploy.yaml", map[string]string{"ENV": "prod"}, []WorkflowItemState{
+		{Name: "Build", Step: &StepState{Name: "Build", Instance: "ci", Job: "/job/build", Status: StatusPending}},
+	})
+	srv.state.SetRunMeta("alice", "incident 42", []string{"hotfix"})
+	srv.state.UpdateStepStatusWithBuild(0, -1, StatusRunning, "", "", "http://ci/job/build/7/", 7)
+	srv.state.UpdateStepStatus(0, -1, StatusFailed, "FAILURE", "build failed", "http://ci/job/build/7/")
+	srv.state.CompleteWorkflow(false, "step Build failed")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/status/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	disposition := w.Header().Get("Content-Disposition")
+	if !regexp.MustCompile(`^attachment; filename=workflow-state-\d{8}-\d{6}\.json$`).MatchString(disposition) {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+
+	var state api.WorkflowState
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+	if state.Error == nil || *state.Error != "step Build failed" || state.StartedAt == nil || state.EndedAt == nil {
+		t.Errorf("expected the run's error and timings, got %+v", state)
+	}
+	if state.Initiator == nil || *state.Initiator != "alice" || (*state.Inputs)["ENV"] != "prod" {
+		t.Errorf("expected the run metadata and inputs, got %+v", state)
+	}
+	step := (*state.Items)[0].Step
+	if step == nil || step.StartedAt == nil || step.EndedAt == nil || *step.Error != "build failed" || *step.BuildNumber != 7 {
+		t.Errorf("expected the step's outcome and timings, got %+v", step)
+	}
+}
+
+func TestGetRunConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	snapshot := "name: Deploy\nworkflow:\n  - name: Build\n"
+	runID, err := srv.db.CreateRun("Deploy", "workflows/deploy.yaml", snapshot, nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/config", runID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != snapshot {
+		t.Errorf("unexpected snapshot:\n%s", w.Body.String())
+	}
+	want := fmt.Sprintf("attachment; filename=run-%d-deploy.yaml", runID)
+	if got := w.Header().Get("Content-Disposition"); got != want {
+		t.Errorf("expected Content-Disposition %q, got %q", want, got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/99999/config", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestGetRunArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	runID, err := srv.db.CreateRun("Deploy", "workflows/deploy.yaml", "config", nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := &workflowCallbacks{state: srv.state, logger: srv.logger, db: srv.db, runID: runID}
+	cb.OnStepComplete(0, 0, "Lint", "SUCCESS", 4, nil)
+	cb.OnStepArtifacts(1, 0, "Package", []jenkins.Artifact{{FileName: "app.jar", RelativePath: "target/app.jar", URL: "http://ci/job/app/9/artifact/target/app.jar"}})
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/artifacts", runID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var artifacts []api.RunArtifact
+	if err := json.NewDecoder(w.Body).Decode(&artifacts); err != nil {
+		t.Fatal(err)
+	}
+	want := []api.RunArtifact{{ItemIndex: 1, StepIndex: 0, StepName: "Package", FileName: "app.jar", RelativePath: "target/app.jar", Url: "http://ci/job/app/9/artifact/target/app.jar"}}
+	if !slices.Equal(artifacts, want) {
+		t.Errorf("expected only the packaged jar, got %+v", artifacts)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/99999/artifacts", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestGetRunDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	original := `name: Deploy
+instances:
+  ci:
+    url: http://ci.example.com
+    token: user:token
+workflow:
+  - name: Build
+    instance: ci
+    job: /job/build
+    secret_params: [API_KEY]
+    params:
+      API_KEY: old-secret-value
+      BRANCH: main
+  - name: Smoke
+    instance: ci
+    job: /job/smoke
+`
+	if err := os.WriteFile(workflowPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := srv.loadConfig(workflowPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := cfg.MaskSnapshot([]byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, err := srv.db.CreateRun("Deploy", workflowPath, string(snapshot), nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := srv.BuildRouter()
+	get := func(id int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/diff", id), nil))
+		return w
+	}
+
+	w := get(runID)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"identical":true`) || !strings.Contains(w.Body.String(), `"items":[]`) {
+		t.Fatalf("expected an unchanged file to match its snapshot, got %d: %s", w.Code, w.Body.String())
+	}
+
+	changed := strings.NewReplacer("old-secret-value", "new-secret-value", "BRANCH: main", "BRANCH: release", "name: Smoke", "name: Canary").Replace(original)
+	if err := os.WriteFile(workflowPath, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w = get(runID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "secret-value") {
+		t.Errorf("expected secret values to stay masked, got %s", w.Body.String())
+	}
+	var diff api.RunConfigDiff
+	if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+		t.Fatal(err)
+	}
+	if *diff.Identical || len(*diff.Items) != 3 {
+		t.Fatalf("expected three item changes, got %+v", diff.Items)
+	}
+	build := (*diff.Items)[0]
+	if *build.Name != "Build" || *build.Change != "changed" || len(*build.Params) != 1 || *(*build.Params)[0].Name != "BRANCH" || *(*build.Params)[0].New != "release" {
+		t.Errorf("expected only BRANCH to change on Build, got %+v", build)
+	}
+	if got := (*diff.Items)[1]; *got.Name != "Canary" || *got.Change != "added" {
+		t.Errorf("expected Canary to be added, got %+v", got)
+	}
+	if got := (*diff.Items)[2]; *got.Name != "Smoke" || *got.Change != "removed" {
+		t.Errorf("expected Smoke to be removed, got %+v", got)
+	}
+	if !strings.Contains(*diff.Unified, "\n-      BRANCH: main\n") || !strings.Contains(*diff.Unified, "\n+      BRANCH: release\n") || !strings.HasPrefix(*diff.Unified, fmt.Sprintf("--- run-%d/deploy.yaml\n+++ current/deploy.yaml\n", runID)) {
+		t.Errorf("unexpected unified diff:\n%s", *diff.Unified)
+	}
+
+	if err := os.WriteFile(workflowPath, []byte("name: Deploy\nworkflow:\n  - name: Build\n    instance: missing\n    job: /job/build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(runID); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a workflow file that does not load, got %d", w.Code)
+	}
+	os.Remove(workflowPath)
+	if w := get(runID); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 once the workflow file is gone, got %d", w.Code)
+	}
+	if w := get(99999); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestRerunRun(t *testing.T) {
+	const fileSecret = "file-secret-value"
+	sent := make(chan *http.Request, 2)
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent <- r
+		if r.URL.Path != "/job/deploy/buildWithParameters" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Location", "http://jenkins/queue/item/1/")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  dev:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "deploy.yaml")
+	original := "name: Deploy\ninputs:\n  branch: main\n  key: " + fileSecret + "\nworkflow:\n  - name: Lint\n    instance: dev\n    job: /job/lint\n  - name: Deploy\n    instance: dev\n    job: /job/deploy\n    wait: queued\n    params:\n      BRANCH: ${branch}\n      KEY: ${key}\n    secret_params: [KEY]\n"
+	if err := os.WriteFile(workflowPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	// Record a past run as runWorkflow would, with Lint disabled.
+	cfg, err := srv.loadConfig(workflowPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := cfg.MaskSnapshot([]byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := cfg.MaskInputs(map[string]string{"branch": "release", "key": fileSecret})
+	runID, err := srv.db.CreateRun("Deploy", workflowPath, string(snapshot), inputs, "", database.RunMeta{Initiator: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.db.SaveRunStep(database.RunStep{RunID: runID, ItemIndex: 0, StepName: "Lint", Result: "SKIPPED", SkipReason: workflow.SkipReasonDisabled}); err != nil {
+		t.Fatal(err)
+	}
+	srv.db.UpdateRunComplete(runID, "success", time.Now())
+
+	// The file has moved on since; the rerun must not pick that up.
+	changed := strings.Replace(original, "branch: main", "branch: develop", 1) + "  - name: Smoke\n    instance: dev\n    job: /job/smoke\n"
+	if err := os.WriteFile(workflowPath, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	router := srv.BuildRouter()
+	rerun := func(id int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/runs/%d/rerun", id), nil))
+		return w
+	}
+
+	w := rerun(runID)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp api.RerunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.RerunOf != runID || resp.RunId == runID || resp.RunId == 0 {
+		t.Errorf("expected a new run linked to %d, got %+v", runID, resp)
+	}
+	if resp.Warnings == nil || len(*resp.Warnings) != 1 || (*resp.Warnings)[0] != `workflow[2] "Smoke" added since the run` {
+		t.Errorf("expected a warning about the added item, got %v", resp.Warnings)
+	}
+	select {
+	case r := <-sent:
+		if r.URL.Path != "/job/deploy/buildWithParameters" {
+			t.Fatalf("expected only Deploy to be triggered, got %s", r.URL.Path)
+		}
+		if q := r.URL.Query(); q.Get("BRANCH") != "release" || q.Get("KEY") != fileSecret {
+			t.Errorf("expected the original inputs with the secret restored, got %v", q)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deploy job was not triggered")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.state.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	run, err := srv.db.GetRun(resp.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.RerunOf != runID || run.Initiator != "alice" || run.ConfigSnapshot != string(snapshot) || run.Inputs["branch"] != "release" || run.Inputs["key"] != config.SecretMask {
+		t.Errorf("unexpected rerun record: %+v", run)
+	}
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d", resp.RunId), nil))
+	if !strings.Contains(get.Body.String(), fmt.Sprintf(`"rerun_of":%d`, runID)) {
+		t.Errorf("expected rerun_of in the run, got %s", get.Body.String())
+	}
+
+	// A literal secret in the file cannot be restored from the snapshot.
+	literal, err := srv.db.CreateRun("Deploy", workflowPath, strings.Replace(string(snapshot), "${key}", `"***"`, 1), nil, "", database.RunMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := rerun(literal); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `param "KEY"`) {
+		t.Errorf("expected 409 for a masked literal secret, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := os.WriteFile(instancesPath, []byte("instances:\n  prod:\n    url: "+jenkins.URL+"\n    token: test:token\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w := rerun(runID); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "dev") {
+		t.Errorf("expected 409 once the instance is gone, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := rerun(99999); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+	select {
+	case r := <-sent:
+		t.Errorf("expected refused reruns to trigger nothing, got %s", r.URL.Path)
+	default:
+	}
+}
+
+func TestGetRunStatus_Historical(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, "", []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	snapshot := `name: Deploy
+workflow:
+  - wait_for_pr:
+      name: Release PR
+      owner: treaz
+      repo: app
+      pr_number: 7
+      wait_for: merged
+  - name: Build
+    instance: ci
+    job: /job/build
+  - parallel:
+      name: Rollout
+      steps:
+        - name: EU
+          instance: ci
+          job: /job/eu
+        - name: US
+          instance: ci
+          job: /job/us
+`
+	runID, err := srv.db.CreateRun("Deploy", "workflows/deploy.yaml", snapshot, map[string]string{"env": "prod"}, "", database.RunMeta{Initiator: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rs := range []database.RunStep{
+		{RunID: runID, ItemIndex: 1, StepName: "Build", BuildURL: "http://ci/job/build/3/", Result: "SUCCESS", BuildNumber: 3, Params: map[string]string{"ENV": "prod"}},
+		{RunID: runID, ItemIndex: 2, StepIndex: 0, StepName: "EU", Result: "SUCCESS", BuildNumber: 8},
+		{RunID: runID, ItemIndex: 2, StepIndex: 1, StepName: "US", Result: "FAILURE", BuildNumber: 9},
+	} {
+		if err := srv.db.SaveRunStep(rs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.db.UpdateRunComplete(runID, "failed", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	router := srv.BuildRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/runs/%d/status", runID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var state api.WorkflowState
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if *state.Status != "failed" || *state.Initiator != "alice" || (*state.Inputs)["env"] != "prod" {
+		t.Errorf("unexpected run fields: status %q, initiator %v, inputs %v", *state.Status, state.Initiator, state.Inputs)
+	}
+	items := *state.Items
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items from the snapshot, got %d", len(items))
+	}
+	if got := *items[0].PrWait.Status; got != "success" {
+		t.Errorf("expected PR wait passed by later items to be success, got %q", got)
+	}
+	build := items[1].Step
+	if *build.Status != "success" || *build.BuildNumber != 3 || (*build.Params)["ENV"] != "prod" {
+		t.Errorf("unexpected Build step: status %q, build %v, params %v", *build.Status, build.BuildNumber, build.Params)
+	}
+	group := items[2].Parallel
+	if *group.Status != "failed" || *(*group.Steps)[1].Status != "failed" || *(*group.Steps)[0].Status != "success" {
+		t.Errorf("unexpected parallel group status %q", *group.Status)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/99999/status", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestRunStatusToState(t *testing.T) {
+	tests := []struct {
+		status string
+		want   StepStatus
+	}{
+		{"running", StatusRunning},
+		{"success", StatusSuccess},
+		{"failed", StatusFailed},
+		{"stopped", StatusAborted},
+		{runStatusInterrupted, StatusAborted},
+	}
+	for _, tt := range tests {
+		if got := runStatusToState(tt.status); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.status, tt.want, got)
+		}
+	}
+}
+
+func TestRunOutcome(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		err    error
+		want   string
+	}{
+		{"success", "success", nil, notifier.OutcomeSuccess},
+		{"failure", "failed", fmt.Errorf("step %q failed: %w", "Build", &workflow.StepFailedError{Step: "Build", Result: "FAILURE"}), notifier.OutcomeFailure},
+		{"unstable", "failed", &workflow.StepFailedError{Step: "Test", Result: "UNSTABLE"}, notifier.OutcomeUnstable},
+		{"trigger error", "failed", fmt.Errorf("failed to trigger"), notifier.OutcomeFailure},
+		{"stopped", "stopped", workflow.ErrStopped, notifier.OutcomeAborted},
+		{"aborted in jenkins", "failed", &workflow.BuildAbortedError{Step: "Build"}, notifier.OutcomeAborted},
+	}
+	for _, tt := range tests {
+		if got := runOutcome(tt.status, tt.err); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestFailureOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind string
+		wantItem int
+	}{
+		{"step", &workflow.StepError{ItemIndex: 2, Step: "Build", Err: fmt.Errorf("failed to trigger")}, failureStep, 2},
+		{"bad result", fmt.Errorf("parallel group %q failed: %w", "Deploy", &workflow.StepFailedError{ItemIndex: 1, StepIndex: 1, Step: "EU", Result: "FAILURE"}), failureStep, 1},
+		{"pr wait", &workflow.PRWaitError{ItemIndex: 0, Name: "PR", Err: fmt.Errorf("closed")}, failurePRWait, 0},
+		{"queue", &workflow.StepError{ItemIndex: 3, Step: "Build", Err: &workflow.QueueError{Err: fmt.Errorf("cancelled")}}, failureQueue, 3},
+		{"timeout", fmt.Errorf("finally: %w", &workflow.StepError{ItemIndex: 4, Step: "Soak", Err: &workflow.TimeoutError{Limit: time.Hour, Err: fmt.Errorf("too slow")}}), failureTimeout, 4},
+		{"aborted in jenkins", &workflow.BuildAbortedError{Step: "Build"}, failureAborted, -1},
+		{"job check", &workflow.JobCheckError{}, failureJobCheck, -1},
+		{"untyped", fmt.Errorf("failed to build execution plan"), "", -1},
+	}
+	for _, tt := range tests {
+		kind, item := failureOf(tt.err)
+		if kind != tt.wantKind || item != tt.wantItem {
+			t.Errorf("%s: expected (%q, %d), got (%q, %d)", tt.name, tt.wantKind, tt.wantItem, kind, item)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	orig := version.Version
+	version.Version = "v1.2.3"
+	defer func() { version.Version = orig }()
+
+	srv := &Server{}
+	w := httptest.NewRecorder()
+	srv.GetVersion(w, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+
+	var info api.VersionInfo
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version == nil || *info.Version != "v1.2.3" {
+		t.Errorf("expected version v1.2.3, got %v", info.Version)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	srv := NewServer(8080, filepath.Join(tmpDir, "instances.yaml"), []string{tmpDir}, "", logger.New(logger.Error))
+	router := srv.BuildRouter()
+	get := func(path string, header ...string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/api/version"); code != http.StatusOK {
+		t.Fatalf("expected the API to be open without keys, got %d", code)
+	}
+
+	srv.SetAPIKeys([]string{"first-key", " ", "second-key"})
+	for _, tc := range []struct {
+		name   string
+		path   string
+		header []string
+		want   int
+	}{
+		{"no key", "/api/version", nil, http.StatusUnauthorized},
+		{"wrong key", "/api/version", []string{"Authorization", "Bearer nope"}, http.StatusUnauthorized},
+		{"prefix of a key", "/api/version", []string{"X-API-Key", "first"}, http.StatusUnauthorized},
+		{"not bearer", "/api/version", []string{"Authorization", "Basic first-key"}, http.StatusUnauthorized},
+		{"bearer", "/api/version", []string{"Authorization", "Bearer first-key"}, http.StatusOK},
+		{"lowercase bearer", "/api/version", []string{"Authorization", "bearer second-key"}, http.StatusOK},
+		{"header", "/api/version", []string{"X-API-Key", "second-key"}, http.StatusOK},
+		{"health", "/api/health", nil, http.StatusOK},
+		{"spec", "/api/openapi.json", nil, http.StatusOK},
+		{"static", "/", nil, http.StatusOK},
+	} {
+		if code := get(tc.path, tc.header...); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("expected a 401 with a WWW-Authenticate challenge, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestStartAsync_TLS(t *testing.T) {
+	tmpDir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(tmpDir, "server.crt"), filepath.Join(tmpDir, "server.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	srv := NewServer(0, "", []string{tmpDir}, "", logger.New(logger.Error))
+	if err := srv.SetTLS(certFile, filepath.Join(tmpDir, "missing.key")); err == nil {
+		t.Error("expected a missing key file to be rejected")
+	}
+	if err := srv.SetTLS(keyFile, certFile); err == nil {
+		t.Error("expected swapped certificate and key to be rejected")
+	}
+	if err := srv.SetTLS(certFile, keyFile); err != nil {
+		t.Fatalf("SetTLS failed: %v", err)
+	}
+	srv.SetBindAddress("127.0.0.1")
+	port, shutdown, err := srv.StartAsync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	if got, want := srv.dashboardURL(port), fmt.Sprintf("https://127.0.0.1:%d", port); got != want {
+		t.Errorf("expected dashboard URL %q, got %q", want, got)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/api/health", port))
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 over HTTPS, got %d", resp.StatusCode)
+	}
+}
+
+func TestDashboardURL(t *testing.T) {
+	for _, tc := range []struct {
+		bind string
+		want string
+	}{
+		{"", "http://localhost:8080"},
+		{"0.0.0.0", "http://localhost:8080"},
+		{"::", "http://localhost:8080"},
+		{"127.0.0.1", "http://127.0.0.1:8080"},
+		{"::1", "http://[::1]:8080"},
+		{"ci.example.com", "http://ci.example.com:8080"},
+	} {
+		srv := &Server{bindAddr: tc.bind}
+		if got := srv.dashboardURL(8080); got != tc.want {
+			t.Errorf("bind %q: expected %q, got %q", tc.bind, tc.want, got)
+		}
+	}
+}
+
+func TestTriggerJob(t *testing.T) {
+	var gotQuery string
+	var jenkins *httptest.Server
+	jenkins = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/buildWithParameters":
+			gotQuery = r.URL.RawQuery
+			w.Header().Set("Location", jenkins.URL+"/queue/item/5/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/5/api/json":
+			w.Write([]byte(`{"executable": {"url": "` + jenkins.URL + `/job/deploy/12/"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer jenkins.Close()
+
+	tmpDir := t.TempDir()
+	instancesPath := filepath.Join(tmpDir, "instances.yaml")
+	instancesContent := "instances:\n  dev:\n    url: " + jenkins.URL + "\n    token: test:token\n    default_params:\n      REGION: us\naliases:\n  ci: dev\n"
+	if err := os.WriteFile(instancesPath, []byte(instancesContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(8080, instancesPath, []string{tmpDir}, filepath.Join(tmpDir, "runs.db"), logger.New(logger.Error))
+	defer srv.db.Close()
+
+	trigger := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.TriggerJob(w, httptest.NewRequest(http.MethodPost, "/api/trigger", strings.NewReader(body)))
+		return w
+	}
+
+	w := trigger(`{"instance":"ci","job":"/job/deploy","params":{"VERSION":"1.2"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var res api.TriggerResult
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.BuildUrl == nil || *res.BuildUrl != jenkins.URL+"/job/deploy/12/" || *res.Result != "STARTED" || res.BuildNumber != nil {
+		t.Errorf("expected the started build, got %+v", res)
+	}
+	if gotQuery != "REGION=us&VERSION=1.2" {
+		t.Errorf("expected the params over the instance defaults, got %q", gotQuery)
+	}
+	if srv.state.IsRunning() {
+		t.Error("expected an ad-hoc trigger not to start a workflow run")
+	}
+
+	for _, tt := range []struct{ body, want string }{
+		{`{"instance":"qa","job":"/job/deploy"}`, `unknown instance or alias "qa"`},
+		{`{"instance":"dev"}`, "Instance and job are required"},
+		{`{"instance":"dev","job":"/job/deploy","wait":"finished"}`, `Unknown wait mode "finished"`},
+	} {
+		if w := trigger(tt.body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected 400 with %q, got %d: %s", tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+	if w := trigger(`{"instance":"dev","job":"/job/missing","wait":"queued"}`); w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 when Jenkins rejects the trigger, got %d: %s", w.Code, w.Body.String())
 	}
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Shutdown modes accepted by SetShutdownMode, besides the stop modes "now"
+// and "graceful".
+const shutdownModeWait = "wait" // Let executing runs finish
+
+// shutdownCancelGrace is how long Stop waits for runs it had to cancel at the
+// drain deadline to record their outcome before closing the database.
+const shutdownCancelGrace = 5 * time.Second
+
+// SetShutdownMode sets what Stop does with executing runs: "graceful" (the
+// default) lets the current item finish and skips the rest, "wait" lets the
+// whole run finish, and "now" cancels it. Call it before serving.
+func (s *Server) SetShutdownMode(mode string) error {
+	switch mode {
+	case stopModeGraceful, stopModeNow, shutdownModeWait:
+		s.shutdownMode = mode
+		return nil
+	}
+	return fmt.Errorf("unknown shutdown mode %q (use graceful, wait or now)", mode)
+}
+
+// Stop shuts the server down. It refuses new runs, stops the HTTP server,
+// ends executing runs as set by SetShutdownMode and waits for them to record
+// their outcome, and closes the database. Runs still executing when ctx is
+// done are cancelled and given a few more seconds to record that they
+// stopped.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopping = true
+	httpServer := s.httpServer
+	if s.shutdownMode != shutdownModeWait {
+		for runID, run := range s.runs {
+			if run.cancel != nil {
+				s.logger.Infof("Stopping run %d (%s) for shutdown", runID, s.shutdownMode)
+				s.stopRunLocked(runID, run, s.shutdownMode)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("failed to stop the HTTP server: %w", err))
+		}
+	}
+
+	if !s.waitForRuns(ctx) {
+		s.mu.Lock()
+		for runID, run := range s.runs {
+			if run.cancel != nil {
+				s.logger.Infof("Cancelling run %d, which did not stop before the shutdown timeout", runID)
+				s.stopRunLocked(runID, run, stopModeNow)
+			}
+		}
+		s.mu.Unlock()
+		grace, cancel := context.WithTimeout(context.Background(), shutdownCancelGrace)
+		defer cancel()
+		if !s.waitForRuns(grace) {
+			errs = append(errs, errors.New("runs were still executing when the database was closed"))
+		}
+	}
+
+	if s.db != nil {
+		if err := s.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close the database: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForRuns waits until no run is executing, and reports false if ctx is
+// done first.
+func (s *Server) waitForRuns(ctx context.Context) bool {
+	for {
+		s.mu.Lock()
+		var done chan struct{}
+		for _, run := range s.runs {
+			done = run.done
+			break
+		}
+		s.mu.Unlock()
+		if done == nil {
+			return true
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// refuseWhileStopping answers 503 once Stop has been called, and reports
+// whether it did.
+func (s *Server) refuseWhileStopping(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+	if stopping {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+	}
+	return stopping
+}
@@ -1,10 +1,16 @@
 package server
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/logger"
 )
 
+// runLogLimit caps the in-memory log kept for a run.
+const runLogLimit = 2 << 20
+
 // StepStatus represents the current status of a workflow step.
 type StepStatus string
 
@@ -14,6 +20,7 @@ const (
 	StatusSuccess StepStatus = "success"
 	StatusFailed  StepStatus = "failed"
 	StatusSkipped StepStatus = "skipped"
+	StatusAborted StepStatus = "aborted" // build aborted in Jenkins, cancelled because a parallel sibling failed, or a run stopped or interrupted
 )
 
 // StepState holds the state of a single step.
@@ -29,6 +36,20 @@ type StepState struct {
 	BuildURL    string            `json:"buildUrl,omitempty"`
 	BuildNumber int               `json:"buildNumber,omitempty"`
 	UsedInputs  map[string]string `json:"usedInputs,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`      // Params as sent to Jenkins, after substitution
+	SkipReason  string            `json:"skipReason,omitempty"`  // Why a skipped step did not run
+	Wait        string            `json:"wait,omitempty"`        // Wait mode when not "completed"; the step succeeds once its build is queued or started
+	Stage       string            `json:"stage,omitempty"`       // Grouping label from the workflow file
+	Output      string            `json:"output,omitempty"`      // What a command item printed
+	Tests       *TestCounts       `json:"tests,omitempty"`       // JUnit counts when the build published a test report
+	QueueStatus string            `json:"queueStatus,omitempty"` // Jenkins queue flag while the build waits in the queue: stuck, blocked or buildable
+}
+
+// TestCounts are the pass, fail and skip counts of a build's test report.
+type TestCounts struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
 }
 
 // PRWaitState holds the state of a PR wait item.
@@ -46,6 +67,9 @@ type PRWaitState struct {
 	EndedAt          *time.Time `json:"endedAt,omitempty"`
 	HTMLURL          string     `json:"htmlUrl,omitempty"`
 	Title            string     `json:"title,omitempty"`
+	SkipReason       string     `json:"skipReason,omitempty"`  // Why a skipped wait did not run
+	PollSecs         int        `json:"pollSecs,omitempty"`    // Interval between checks
+	PollMaxSecs      int        `json:"pollMaxSecs,omitempty"` // Cap on the backed-off interval; 0 without backoff
 }
 
 // ParallelGroupState holds the state of a parallel execution group.
@@ -57,8 +81,10 @@ type ParallelGroupState struct {
 
 // WorkflowItemState represents either a step or parallel group.
 type WorkflowItemState struct {
+	Name       string              `json:"name,omitempty"` // Stable item name; see config.WorkflowItem.ItemName
 	IsParallel bool                `json:"isParallel"`
 	IsPRWait   bool                `json:"isPRWait"`
+	IsFinally  bool                `json:"isFinally,omitempty"` // Item belongs to the workflow's finally section
 	Step       *StepState          `json:"step,omitempty"`
 	Parallel   *ParallelGroupState `json:"parallel,omitempty"`
 	PRWait     *PRWaitState        `json:"prWait,omitempty"`
@@ -66,20 +92,93 @@ type WorkflowItemState struct {
 
 // WorkflowState holds the complete state of a workflow execution.
 type WorkflowState struct {
-	Name      string              `json:"name"`
-	Status    StepStatus          `json:"status"`
-	Inputs    map[string]string   `json:"inputs"`
-	Items     []WorkflowItemState `json:"items"`
-	StartedAt *time.Time          `json:"startedAt,omitempty"`
-	EndedAt   *time.Time          `json:"endedAt,omitempty"`
-	Error     string              `json:"error,omitempty"`
+	Name        string              `json:"name"`
+	Status      StepStatus          `json:"status"`
+	Inputs      map[string]string   `json:"inputs"`
+	Items       []WorkflowItemState `json:"items"`
+	StartedAt   *time.Time          `json:"startedAt,omitempty"`
+	EndedAt     *time.Time          `json:"endedAt,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	ErrorKind   string              `json:"errorKind,omitempty"`   // What failed, see failureOf
+	FailedItem  *int                `json:"failedItem,omitempty"`  // Index of the item that failed, when known
+	Initiator   string              `json:"initiator,omitempty"`   // Who started the run, from the run request
+	Description string              `json:"description,omitempty"` // Why the run was started, from the run request
+	Labels      []string            `json:"labels,omitempty"`
+	StopMode    string              `json:"stopMode,omitempty"` // "graceful" or "now" once a stop was requested
+	Attempt     int                 `json:"attempt,omitempty"`  // Set from 2 on when a workflow with retries starts over
+	// EstimatedDuration is the median duration in seconds of recent successful runs, or 0 without history.
+	EstimatedDuration int64 `json:"estimatedDuration,omitempty"`
+}
+
+// Progress counts how far a run has got. A parallel group counts as one item
+// and a PR wait or command as one step. Completed counts everything that has
+// finished, whatever the outcome; Failed is the part of it that failed or was
+// aborted.
+type Progress struct {
+	TotalItems, CompletedItems, FailedItems int
+	TotalSteps, CompletedSteps, FailedSteps int
+}
+
+// finished reports whether status is final.
+func (s StepStatus) finished() bool {
+	return s != StatusPending && s != StatusRunning
+}
+
+// failed reports whether status is final and unsuccessful.
+func (s StepStatus) failed() bool {
+	return s == StatusFailed || s == StatusAborted
+}
+
+// Progress returns the item and step counts of the run. A parallel group is
+// completed once all of its steps have finished, and failed if any of them
+// failed.
+func (ws *WorkflowState) Progress() Progress {
+	var p Progress
+	for _, item := range ws.Items {
+		var statuses []StepStatus
+		switch {
+		case item.Parallel != nil:
+			for _, step := range item.Parallel.Steps {
+				statuses = append(statuses, step.Status)
+			}
+		case item.PRWait != nil:
+			statuses = append(statuses, item.PRWait.Status)
+		case item.Step != nil:
+			statuses = append(statuses, item.Step.Status)
+		default:
+			continue
+		}
+		p.TotalItems++
+		done, failed := true, false
+		for _, status := range statuses {
+			p.TotalSteps++
+			if !status.finished() {
+				done = false
+				continue
+			}
+			p.CompletedSteps++
+			if status.failed() {
+				p.FailedSteps++
+				failed = true
+			}
+		}
+		if done {
+			p.CompletedItems++
+			if failed {
+				p.FailedItems++
+			}
+		}
+	}
+	return p
 }
 
 // StateManager manages workflow execution state in a thread-safe manner.
 type StateManager struct {
 	mu      sync.RWMutex
 	current *WorkflowState
+	last    *WorkflowState // Most recently completed run; kept when the next run starts
 	running bool
+	log     *logger.Buffer // Engine log of the current run
 }
 
 // NewStateManager creates a new StateManager.
@@ -106,6 +205,18 @@ func (sm *StateManager) GetState() *WorkflowState {
 	return &state
 }
 
+// MarshalState returns the current workflow state as JSON, or nil if no
+// workflow has been started. It holds the lock while encoding, so steps
+// updated concurrently are not read half-written.
+func (sm *StateManager) MarshalState() ([]byte, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.current == nil {
+		return nil, nil
+	}
+	return json.Marshal(sm.current)
+}
+
 // StartWorkflow initializes state for a new workflow execution.
 func (sm *StateManager) StartWorkflow(name string, inputs map[string]string, items []WorkflowItemState) {
 	sm.mu.Lock()
@@ -120,6 +231,67 @@ func (sm *StateManager) StartWorkflow(name string, inputs map[string]string, ite
 		StartedAt: &now,
 	}
 	sm.running = true
+	sm.log = logger.NewBuffer(runLogLimit)
+}
+
+// SetRunMeta records the caller-supplied metadata of the current run.
+func (sm *StateManager) SetRunMeta(initiator, description string, labels []string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.current == nil {
+		return
+	}
+	sm.current.Initiator = initiator
+	sm.current.Description = description
+	sm.current.Labels = labels
+}
+
+// RetryWorkflow resets the current run to items for the given attempt of a
+// workflow with retries. The start time, inputs and run metadata are kept.
+func (sm *StateManager) RetryWorkflow(attempt int, items []WorkflowItemState) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.current == nil {
+		return
+	}
+	sm.current.Items = items
+	sm.current.Attempt = attempt
+	sm.current.Status = StatusRunning
+	sm.current.Error = ""
+	sm.current.ErrorKind = ""
+	sm.current.FailedItem = nil
+}
+
+// SetStopMode records that a stop was requested for the current run.
+func (sm *StateManager) SetStopMode(mode string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.current == nil {
+		return
+	}
+	sm.current.StopMode = mode
+}
+
+// SetEstimatedDuration records how long the current run is expected to take.
+func (sm *StateManager) SetEstimatedDuration(d time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.current == nil {
+		return
+	}
+	sm.current.EstimatedDuration = int64(d / time.Second)
+}
+
+// RunLog returns the log buffer of the current run, or nil if no workflow has
+// been started. It stays available after the run completes.
+func (sm *StateManager) RunLog() *logger.Buffer {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.log
 }
 
 // UpdateStepStatus updates the status of a specific step.
@@ -130,24 +302,79 @@ func (sm *StateManager) UpdateStepStatus(itemIndex int, stepIndex int, status St
 // UpdateStepStatusWithBuild is like UpdateStepStatus but also records the Jenkins build number.
 // A buildNumber of 0 leaves the existing value unchanged.
 func (sm *StateManager) UpdateStepStatusWithBuild(itemIndex int, stepIndex int, status StepStatus, result, errMsg, buildURL string, buildNumber int) {
+	sm.updateStep(itemIndex, stepIndex, status, result, errMsg, buildURL, buildNumber, "")
+}
+
+// SetStepParams records the params a step was triggered with.
+func (sm *StateManager) SetStepParams(itemIndex, stepIndex int, params map[string]string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if step := sm.stepAt(itemIndex, stepIndex); step != nil {
+		step.Params = params
+	}
+}
+
+// SetStepOutput records the output of a command item.
+func (sm *StateManager) SetStepOutput(itemIndex, stepIndex int, output string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if step := sm.stepAt(itemIndex, stepIndex); step != nil {
+		step.Output = output
+	}
+}
+
+// SetStepTests records the test report counts of a step's build.
+func (sm *StateManager) SetStepTests(itemIndex, stepIndex int, tests TestCounts) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if step := sm.stepAt(itemIndex, stepIndex); step != nil {
+		step.Tests = &tests
+	}
+}
+
+// SetStepQueueStatus records the Jenkins queue flag of a step's queued build.
+func (sm *StateManager) SetStepQueueStatus(itemIndex, stepIndex int, status string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if step := sm.stepAt(itemIndex, stepIndex); step != nil {
+		step.QueueStatus = status
+	}
+}
+
+// stepAt returns the state of a step, or nil if there is no such step. The
+// caller must hold sm.mu.
+func (sm *StateManager) stepAt(itemIndex, stepIndex int) *StepState {
 	if sm.current == nil || itemIndex >= len(sm.current.Items) {
-		return
+		return nil
 	}
 
 	item := &sm.current.Items[itemIndex]
-	var step *StepState
-
 	if item.IsParallel && item.Parallel != nil {
 		if stepIndex >= len(item.Parallel.Steps) {
-			return
+			return nil
 		}
-		step = &item.Parallel.Steps[stepIndex]
-	} else if item.Step != nil {
-		step = item.Step
-	} else {
+		return &item.Parallel.Steps[stepIndex]
+	}
+	return item.Step
+}
+
+// SkipStep marks a step as skipped and records why it did not run.
+func (sm *StateManager) SkipStep(itemIndex, stepIndex int, reason string) {
+	sm.updateStep(itemIndex, stepIndex, StatusSkipped, "SKIPPED", "", "", 0, reason)
+}
+
+// updateStep applies a status change to a step. skipReason replaces any
+// previous reason, so it is cleared when a step stops being skipped.
+func (sm *StateManager) updateStep(itemIndex, stepIndex int, status StepStatus, result, errMsg, buildURL string, buildNumber int, skipReason string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	step := sm.stepAt(itemIndex, stepIndex)
+	if step == nil {
 		return
 	}
 
@@ -155,6 +382,8 @@ func (sm *StateManager) UpdateStepStatusWithBuild(itemIndex int, stepIndex int,
 	step.Status = status
 	step.Result = result
 	step.Error = errMsg
+	step.SkipReason = skipReason
+	step.QueueStatus = "" // Only set between queueing and the next status change
 	switch {
 	case status == StatusRunning && buildURL == "":
 		step.BuildURL = ""
@@ -168,12 +397,12 @@ func (sm *StateManager) UpdateStepStatusWithBuild(itemIndex int, stepIndex int,
 	if status == StatusRunning && step.StartedAt == nil {
 		step.StartedAt = &now
 	}
-	if status == StatusSuccess || status == StatusFailed || status == StatusSkipped {
+	if status == StatusSuccess || status == StatusFailed || status == StatusSkipped || status == StatusAborted {
 		step.EndedAt = &now
 	}
 
 	// Update parallel group status if applicable
-	if item.IsParallel && item.Parallel != nil {
+	if item := &sm.current.Items[itemIndex]; item.IsParallel && item.Parallel != nil {
 		sm.updateParallelGroupStatus(item.Parallel)
 	}
 }
@@ -263,8 +492,8 @@ func (sm *StateManager) CompletePRWait(itemIndex int) {
 	prState.EndedAt = &now
 }
 
-// SkipPRWait marks the PR wait item as skipped.
-func (sm *StateManager) SkipPRWait(itemIndex int) {
+// SkipPRWait marks the PR wait item as skipped and records why.
+func (sm *StateManager) SkipPRWait(itemIndex int, reason string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -281,6 +510,7 @@ func (sm *StateManager) SkipPRWait(itemIndex int) {
 	prState := item.PRWait
 	prState.Status = StatusSkipped
 	prState.Error = ""
+	prState.SkipReason = reason
 	if prState.StartedAt == nil {
 		prState.StartedAt = &now
 	}
@@ -316,6 +546,7 @@ func (sm *StateManager) updateParallelGroupStatus(pg *ParallelGroupState) {
 	allSuccess := true
 	anyRunning := false
 	anyFailed := false
+	anyAborted := false
 
 	for _, step := range pg.Steps {
 		switch step.Status {
@@ -325,6 +556,9 @@ func (sm *StateManager) updateParallelGroupStatus(pg *ParallelGroupState) {
 		case StatusFailed:
 			anyFailed = true
 			allSuccess = false
+		case StatusAborted:
+			anyAborted = true
+			allSuccess = false
 		case StatusPending:
 			allSuccess = false
 		}
@@ -336,6 +570,8 @@ func (sm *StateManager) updateParallelGroupStatus(pg *ParallelGroupState) {
 		pg.Status = StatusRunning
 	} else if allSuccess {
 		pg.Status = StatusSuccess
+	} else if anyAborted {
+		pg.Status = StatusAborted
 	} else {
 		pg.Status = StatusPending
 	}
@@ -360,12 +596,52 @@ func (sm *StateManager) CompleteWorkflow(success bool, errMsg string) {
 		sm.current.Status = StatusFailed
 		sm.current.Error = errMsg
 	}
+	// StartWorkflow replaces current rather than mutating it, so the completed
+	// state can be shared until then.
+	sm.last = sm.current
+}
+
+// SetFailure records what made the current run fail: kind is one of the
+// failure kinds of failureOf, and itemIndex the failed item or -1 if unknown.
+func (sm *StateManager) SetFailure(kind string, itemIndex int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.current == nil {
+		return
+	}
+	sm.current.ErrorKind = kind
+	sm.current.FailedItem = nil
+	if itemIndex >= 0 {
+		sm.current.FailedItem = &itemIndex
+	}
+}
+
+// LastCompleted returns a copy of the most recently completed workflow state,
+// or nil if no run has completed since the last reset.
+func (sm *StateManager) LastCompleted() *WorkflowState {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.last == nil {
+		return nil
+	}
+	state := *sm.last
+	return &state
+}
+
+// ClearLastCompleted forgets the most recently completed workflow state.
+func (sm *StateManager) ClearLastCompleted() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.last = nil
 }
 
-// Reset clears the current state.
+// Reset clears the current and last completed state.
 func (sm *StateManager) Reset() {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	sm.current = nil
+	sm.last = nil
 	sm.running = false
+	sm.log = nil
 }
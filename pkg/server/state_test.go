@@ -2,6 +2,8 @@ package server
 
 import (
 	"testing"
+
+	"github.com/treaz/jenkins-flow/pkg/workflow"
 )
 
 func TestUpdateStepStatusBuildURLPersistence(t *testing.T) {
@@ -38,6 +40,83 @@ func TestUpdateStepStatusBuildURLPersistence(t *testing.T) {
 	}
 }
 
+func TestLastCompleted(t *testing.T) {
+	sm := NewStateManager()
+	sm.StartWorkflow("first", nil, []WorkflowItemState{{Step: &StepState{Name: "Build", Status: StatusPending}}})
+	if sm.LastCompleted() != nil {
+		t.Fatal("expected no last completed state while the first run is active")
+	}
+	sm.CompleteWorkflow(false, "boom")
+
+	sm.StartWorkflow("second", nil, []WorkflowItemState{{Step: &StepState{Name: "Deploy", Status: StatusPending}}})
+	sm.UpdateStepStatus(0, 0, StatusRunning, "", "", "")
+	last := sm.LastCompleted()
+	if last == nil || last.Name != "first" || last.Status != StatusFailed || last.Error != "boom" {
+		t.Fatalf("expected the first run's final state, got %+v", last)
+	}
+	if step := last.Items[0].Step; step.Name != "Build" || step.Status != StatusPending {
+		t.Errorf("expected the first run's steps to be untouched, got %+v", step)
+	}
+
+	sm.CompleteWorkflow(true, "")
+	if last := sm.LastCompleted(); last == nil || last.Name != "second" {
+		t.Fatalf("expected the second run's state, got %+v", last)
+	}
+
+	sm.ClearLastCompleted()
+	if sm.LastCompleted() != nil {
+		t.Error("expected the last completed state to be cleared")
+	}
+	if sm.GetState() == nil {
+		t.Error("expected clearing to keep the current state")
+	}
+}
+
+func TestRetryWorkflow(t *testing.T) {
+	sm := NewStateManager()
+	fresh := func() []WorkflowItemState {
+		return []WorkflowItemState{{Step: &StepState{Name: "Build", Status: StatusPending}}}
+	}
+	sm.StartWorkflow("flaky", map[string]string{"ENV": "qa"}, fresh())
+	sm.UpdateStepStatus(0, 0, StatusFailed, "FAILURE", "boom", "https://jenkins.example.com/job/build/1")
+
+	sm.RetryWorkflow(2, fresh())
+	state := sm.GetState()
+	if state.Attempt != 2 || state.Status != StatusRunning || state.Error != "" {
+		t.Fatalf("expected a running second attempt, got attempt %d status %q error %q", state.Attempt, state.Status, state.Error)
+	}
+	if step := state.Items[0].Step; step.Status != StatusPending || step.BuildURL != "" {
+		t.Errorf("expected the step to be reset, got %+v", step)
+	}
+	if state.Inputs["ENV"] != "qa" {
+		t.Errorf("expected the inputs to be kept, got %v", state.Inputs)
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	sm := NewStateManager()
+	sm.StartWorkflow("test", nil, []WorkflowItemState{
+		{Step: &StepState{Name: "Build", Status: StatusPending}},
+		{IsPRWait: true, PRWait: &PRWaitState{Name: "Wait", Status: StatusPending}},
+	})
+
+	sm.SkipStep(0, 0, workflow.SkipReasonDisabled)
+	sm.SkipPRWait(1, workflow.SkipReasonDisabled)
+	state := sm.GetState()
+	if step := state.Items[0].Step; step.Status != StatusSkipped || step.Result != "SKIPPED" || step.SkipReason != workflow.SkipReasonDisabled {
+		t.Errorf("unexpected skipped step: %+v", step)
+	}
+	if pr := state.Items[1].PRWait; pr.Status != StatusSkipped || pr.SkipReason != workflow.SkipReasonDisabled {
+		t.Errorf("unexpected skipped PR wait: %+v", pr)
+	}
+
+	// A step that runs after all no longer carries the reason.
+	sm.UpdateStepStatus(0, 0, StatusRunning, "", "", "")
+	if got := sm.GetState().Items[0].Step.SkipReason; got != "" {
+		t.Errorf("expected skip reason to be cleared, got %q", got)
+	}
+}
+
 func TestPRWaitErrorHandling(t *testing.T) {
 	sm := NewStateManager()
 
@@ -203,3 +282,120 @@ func TestStepErrorHandling(t *testing.T) {
 		t.Fatalf("expected build URL to be preserved, got %s", step.BuildURL)
 	}
 }
+
+func TestParallelStepAbortedBySibling(t *testing.T) {
+	sm := NewStateManager()
+
+	items := []WorkflowItemState{
+		{
+			IsParallel: true,
+			Parallel: &ParallelGroupState{
+				Name: "Deploy",
+				Steps: []StepState{
+					{Name: "US", Status: StatusPending},
+					{Name: "EU", Status: StatusPending},
+				},
+				Status: StatusPending,
+			},
+		},
+	}
+	sm.StartWorkflow("test-workflow", nil, items)
+
+	cb := &workflowCallbacks{state: sm}
+	cb.OnStepStart(0, 0, "US", "")
+	cb.OnStepStart(0, 1, "EU", "")
+	cb.OnStepComplete(0, 1, "EU", "FAILURE", 7, nil)
+	cb.OnStepComplete(0, 0, "US", workflow.ResultAborted, 0, &workflow.AbortedError{FailedStep: "EU"})
+
+	pg := sm.GetState().Items[0].Parallel
+	if pg.Steps[0].Status != StatusAborted {
+		t.Fatalf("expected aborted sibling status, got %s", pg.Steps[0].Status)
+	}
+	if pg.Steps[0].Error != `cancelled because "EU" failed` {
+		t.Fatalf("unexpected aborted error %q", pg.Steps[0].Error)
+	}
+	if pg.Steps[0].EndedAt == nil {
+		t.Fatal("expected aborted step to have an end time")
+	}
+	if pg.Steps[1].Status != StatusFailed {
+		t.Fatalf("expected failing step status failed, got %s", pg.Steps[1].Status)
+	}
+	if pg.Status != StatusFailed {
+		t.Fatalf("expected group status failed, got %s", pg.Status)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	sm := NewStateManager()
+	sm.StartWorkflow("test", nil, []WorkflowItemState{
+		{Step: &StepState{Name: "Build", Status: StatusPending}},
+		{IsParallel: true, Parallel: &ParallelGroupState{Name: "Tests", Status: StatusPending, Steps: []StepState{
+			{Name: "Unit", Status: StatusPending},
+			{Name: "Integration", Status: StatusPending},
+		}}},
+		{IsPRWait: true, PRWait: &PRWaitState{Name: "Merge", Status: StatusPending}},
+		{Step: &StepState{Name: "Deploy", Status: StatusPending}},
+	})
+
+	if got, want := sm.GetState().Progress(), (Progress{TotalItems: 4, TotalSteps: 5}); got != want {
+		t.Fatalf("expected %+v before anything ran, got %+v", want, got)
+	}
+
+	sm.UpdateStepStatus(0, 0, StatusSuccess, "SUCCESS", "", "")
+	sm.UpdateStepStatus(1, 0, StatusFailed, "FAILURE", "boom", "")
+	sm.UpdateStepStatus(1, 1, StatusRunning, "", "", "")
+	// The group has failed but is still running, so only its finished step counts.
+	want := Progress{TotalItems: 4, CompletedItems: 1, TotalSteps: 5, CompletedSteps: 2, FailedSteps: 1}
+	if got := sm.GetState().Progress(); got != want {
+		t.Fatalf("expected %+v with a group still running, got %+v", want, got)
+	}
+
+	sm.UpdateStepStatus(1, 1, StatusAborted, "ABORTED", "", "")
+	sm.SkipPRWait(2, "upstream failed")
+	sm.SkipStep(3, 0, "upstream failed")
+	want = Progress{TotalItems: 4, CompletedItems: 4, FailedItems: 1, TotalSteps: 5, CompletedSteps: 5, FailedSteps: 2}
+	if got := sm.GetState().Progress(); got != want {
+		t.Errorf("expected %+v once the run finished, got %+v", want, got)
+	}
+}
+
+func TestStepTests(t *testing.T) {
+	sm := NewStateManager()
+	sm.StartWorkflow("test", nil, []WorkflowItemState{
+		{Step: &StepState{Name: "Lint", Status: StatusPending}},
+		{IsParallel: true, Parallel: &ParallelGroupState{Name: "Tests", Status: StatusPending, Steps: []StepState{
+			{Name: "Unit", Status: StatusPending},
+			{Name: "Integration", Status: StatusPending},
+		}}},
+	})
+	sm.SetStepTests(1, 1, TestCounts{Passed: 40, Failed: 2, Skipped: 1})
+
+	state := sm.GetState()
+	if state.Items[0].Step.Tests != nil || state.Items[1].Parallel.Steps[0].Tests != nil {
+		t.Errorf("expected steps without a test report to have no counts")
+	}
+	integration := state.Items[1].Parallel.Steps[1]
+	if integration.Tests == nil || *integration.Tests != (TestCounts{Passed: 40, Failed: 2, Skipped: 1}) {
+		t.Fatalf("expected the integration step's counts, got %+v", integration.Tests)
+	}
+	tests := (&Server{}).internalStepToAPI(&integration).Tests
+	if tests == nil || *tests.Total != 43 || *tests.Failed != 2 {
+		t.Errorf("expected the API to report the counts with their total, got %+v", tests)
+	}
+}
+
+func TestStepQueueStatus(t *testing.T) {
+	sm := NewStateManager()
+	sm.StartWorkflow("test", nil, []WorkflowItemState{{Step: &StepState{Name: "Deploy", Status: StatusPending}}})
+	sm.UpdateStepStatus(0, 0, StatusRunning, "", "", "")
+	sm.SetStepQueueStatus(0, 0, "blocked")
+	if got := sm.GetState().Items[0].Step.QueueStatus; got != "blocked" {
+		t.Fatalf("expected the queued step to be blocked, got %q", got)
+	}
+
+	// The flag only describes the queue item, so it is gone once the build starts.
+	sm.UpdateStepStatus(0, 0, StatusRunning, "", "", "http://jenkins/job/deploy/1/")
+	if got := sm.GetState().Items[0].Step.QueueStatus; got != "" {
+		t.Errorf("expected no queue status once the build started, got %q", got)
+	}
+}
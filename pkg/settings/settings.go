@@ -10,6 +10,15 @@ import (
 // Settings holds user configuration that persists across restarts.
 type Settings struct {
 	DBPath string `json:"db_path,omitempty"`
+	// EnvFile is a .env file whose variables are available to auth_env.
+	EnvFile string `json:"env_file,omitempty"`
+	// APIKeys are accepted on API requests; when set, every /api route except
+	// /api/health requires one of them.
+	APIKeys []string `json:"api_keys,omitempty"`
+	// JenkinsPollSecs and PRPollSecs are the poll intervals used where
+	// workflows leave them unset; 0 keeps the built-in intervals.
+	JenkinsPollSecs int `json:"jenkins_poll_secs,omitempty"`
+	PRPollSecs      int `json:"pr_poll_secs,omitempty"`
 }
 
 // defaultSettingsPath returns the default path for the settings file.
@@ -0,0 +1,26 @@
+// Package version holds build information injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/treaz/jenkins-flow/pkg/version.Version=v1.2.0"
+package version
+
+import "fmt"
+
+// Set via -ldflags -X; "dev" or empty for local builds.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// String renders the build information on one line.
+func String() string {
+	commit := Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	date := BuildDate
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("jenkins-flow %s (commit %s, built %s)", Version, commit, date)
+}
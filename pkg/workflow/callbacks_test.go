@@ -0,0 +1,640 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/jenkins"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+// CallbackEvent is a single recorded WorkflowCallbacks invocation.
+type CallbackEvent struct {
+	Kind        string
+	ItemIndex   int
+	StepIndex   int
+	Name        string
+	BuildURL    string
+	Result      string
+	BuildNumber int
+	Err         error
+	Reason      string
+	Output      string
+	Attempt     int
+	Tests       jenkins.TestResults
+	Queue       jenkins.QueueStatus
+	Artifacts   []jenkins.Artifact
+}
+
+// String renders the event compactly for sequence assertions, e.g.
+// "StepComplete(1,0,Deploy,SUCCESS)". Build URLs and errors are omitted.
+func (e CallbackEvent) String() string {
+	switch e.Kind {
+	case "StepComplete":
+		return fmt.Sprintf("%s(%d,%d,%s,%s)", e.Kind, e.ItemIndex, e.StepIndex, e.Name, e.Result)
+	case "PRWaitStart", "PRWaitProgress", "PRWaitComplete", "PRWaitFailed", "PRWaitSkipped":
+		return fmt.Sprintf("%s(%d,%s)", e.Kind, e.ItemIndex, e.Name)
+	case "RunRetry":
+		return fmt.Sprintf("%s(%d)", e.Kind, e.Attempt)
+	case "StepTestResults":
+		return fmt.Sprintf("%s(%d,%d,%s,%d/%d/%d)", e.Kind, e.ItemIndex, e.StepIndex, e.Name, e.Tests.Passed, e.Tests.Failed, e.Tests.Skipped)
+	case "StepQueueStatus":
+		return fmt.Sprintf("%s(%d,%d,%s,%s)", e.Kind, e.ItemIndex, e.StepIndex, e.Name, e.Queue)
+	case "StepArtifacts":
+		return fmt.Sprintf("%s(%d,%d,%s,%d)", e.Kind, e.ItemIndex, e.StepIndex, e.Name, len(e.Artifacts))
+	default:
+		return fmt.Sprintf("%s(%d,%d,%s)", e.Kind, e.ItemIndex, e.StepIndex, e.Name)
+	}
+}
+
+// RecordingCallbacks is a WorkflowCallbacks that records every invocation in order.
+type RecordingCallbacks struct {
+	mu     sync.Mutex
+	events []CallbackEvent
+}
+
+func (r *RecordingCallbacks) record(e CallbackEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// Events returns a copy of the recorded events.
+func (r *RecordingCallbacks) Events() []CallbackEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CallbackEvent(nil), r.events...)
+}
+
+// Sequence returns the String form of recorded events, optionally filtered to
+// those matching keep.
+func (r *RecordingCallbacks) Sequence(keep func(CallbackEvent) bool) []string {
+	var out []string
+	for _, e := range r.Events() {
+		if keep == nil || keep(e) {
+			out = append(out, e.String())
+		}
+	}
+	return out
+}
+
+func (r *RecordingCallbacks) OnStepStart(itemIndex, stepIndex int, name, buildURL string) {
+	r.record(CallbackEvent{Kind: "StepStart", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, BuildURL: buildURL})
+}
+
+func (r *RecordingCallbacks) OnStepQueued(itemIndex, stepIndex int, name, queueURL string, params map[string]string) {
+	r.record(CallbackEvent{Kind: "StepQueued", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name})
+}
+
+func (r *RecordingCallbacks) OnStepBuildStarted(itemIndex, stepIndex int, name, buildURL string) {
+	r.record(CallbackEvent{Kind: "StepBuildStarted", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, BuildURL: buildURL})
+}
+
+func (r *RecordingCallbacks) OnStepComplete(itemIndex, stepIndex int, name, result string, buildNumber int, err error) {
+	r.record(CallbackEvent{Kind: "StepComplete", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Result: result, BuildNumber: buildNumber, Err: err})
+}
+
+func (r *RecordingCallbacks) OnStepSkipped(itemIndex, stepIndex int, name, reason string) {
+	r.record(CallbackEvent{Kind: "StepSkipped", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Reason: reason})
+}
+
+func (r *RecordingCallbacks) OnStepOutput(itemIndex, stepIndex int, name, output string) {
+	r.record(CallbackEvent{Kind: "StepOutput", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Output: output})
+}
+
+func (r *RecordingCallbacks) OnStepQueueStatus(itemIndex, stepIndex int, name string, status jenkins.QueueStatus) {
+	r.record(CallbackEvent{Kind: "StepQueueStatus", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Queue: status})
+}
+
+func (r *RecordingCallbacks) OnStepTestResults(itemIndex, stepIndex int, name string, results jenkins.TestResults) {
+	r.record(CallbackEvent{Kind: "StepTestResults", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Tests: results})
+}
+
+func (r *RecordingCallbacks) OnStepArtifacts(itemIndex, stepIndex int, name string, artifacts []jenkins.Artifact) {
+	r.record(CallbackEvent{Kind: "StepArtifacts", ItemIndex: itemIndex, StepIndex: stepIndex, Name: name, Artifacts: artifacts})
+}
+
+func (r *RecordingCallbacks) OnPRWaitStart(itemIndex int, pr *config.PRWait) {
+	r.record(CallbackEvent{Kind: "PRWaitStart", ItemIndex: itemIndex, Name: pr.Name})
+}
+
+func (r *RecordingCallbacks) OnPRWaitProgress(itemIndex int, pr *config.PRWait) {
+	r.record(CallbackEvent{Kind: "PRWaitProgress", ItemIndex: itemIndex, Name: pr.Name})
+}
+
+func (r *RecordingCallbacks) OnPRWaitComplete(itemIndex int, pr *config.PRWait) {
+	r.record(CallbackEvent{Kind: "PRWaitComplete", ItemIndex: itemIndex, Name: pr.Name})
+}
+
+func (r *RecordingCallbacks) OnPRWaitFailed(itemIndex int, pr *config.PRWait, err error) {
+	r.record(CallbackEvent{Kind: "PRWaitFailed", ItemIndex: itemIndex, Name: pr.Name, Err: err})
+}
+
+func (r *RecordingCallbacks) OnPRWaitSkipped(itemIndex int, pr *config.PRWait, reason string) {
+	r.record(CallbackEvent{Kind: "PRWaitSkipped", ItemIndex: itemIndex, Name: pr.Name, Reason: reason})
+}
+
+func (r *RecordingCallbacks) OnRunRetry(attempt int, err error) {
+	r.record(CallbackEvent{Kind: "RunRetry", Attempt: attempt, Err: err})
+}
+
+func assertSequence(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("callback sequence mismatch\n got:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestRunWithCallbacks_CallbackSequence(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{WaitForPR: &config.PRWait{Name: "Wait for PR", Owner: "o", Repo: "r", PRNumber: 1, WaitFor: "merged"}},
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{
+				Parallel: &config.ParallelGroup{
+					Name: "Deploy",
+					Steps: []config.Step{
+						{Name: "Deploy 1", Instance: "test", Job: "/job/test"},
+						{Name: "Deploy 2", Instance: "test", Job: "/job/test"},
+						{Name: "Deploy 3", Instance: "test", Job: "/job/test"},
+					},
+				},
+			},
+		},
+	}
+
+	rec := &RecordingCallbacks{}
+	disabled := DisabledSet{0: {0: true}, 2: {2: true}}
+	if err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, disabled); err != nil {
+		t.Fatalf("RunWithCallbacks failed: %v", err)
+	}
+
+	// Sequential items are strictly ordered.
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.ItemIndex < 2 }), []string{
+		"PRWaitSkipped(0,Wait for PR)",
+		"StepStart(1,0,Build)",
+		"StepQueued(1,0,Build)",
+		"StepBuildStarted(1,0,Build)",
+		"StepComplete(1,0,Build,SUCCESS)",
+	})
+
+	// Parallel siblings interleave, but each step's own events are ordered and
+	// carry the group's itemIndex.
+	for stepIndex, name := range []string{"Deploy 1", "Deploy 2"} {
+		stepIndex, name := stepIndex, name
+		assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.ItemIndex == 2 && e.StepIndex == stepIndex }), []string{
+			fmt.Sprintf("StepStart(2,%d,%s)", stepIndex, name),
+			fmt.Sprintf("StepQueued(2,%d,%s)", stepIndex, name),
+			fmt.Sprintf("StepBuildStarted(2,%d,%s)", stepIndex, name),
+			fmt.Sprintf("StepComplete(2,%d,%s,SUCCESS)", stepIndex, name),
+		})
+	}
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.ItemIndex == 2 && e.StepIndex == 2 }), []string{
+		"StepSkipped(2,2,Deploy 3)",
+	})
+
+	for _, e := range rec.Events() {
+		if e.Kind == "StepBuildStarted" && e.BuildURL != server.URL+"/job/test/1/" {
+			t.Errorf("%s: unexpected build URL %q", e, e.BuildURL)
+		}
+		if e.Kind == "StepStart" && e.BuildURL != "" {
+			t.Errorf("%s: OnStepStart must not carry a build URL, got %q", e, e.BuildURL)
+		}
+		if (e.Kind == "StepSkipped" || e.Kind == "PRWaitSkipped") && e.Reason != SkipReasonDisabled {
+			t.Errorf("%s: unexpected skip reason %q", e, e.Reason)
+		}
+	}
+}
+
+func TestRunWithCallbacks_DisabledInFile(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	off := false
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test", Enabled: &off},
+			{Name: "Test", Instance: "test", Job: "/job/test"},
+			{
+				Parallel: &config.ParallelGroup{
+					Name: "Deploy",
+					Steps: []config.Step{
+						{Name: "Deploy 1", Instance: "test", Job: "/job/test"},
+						{Name: "Deploy 2", Instance: "not-set-up-yet", Job: "/job/test", Enabled: &off},
+					},
+				},
+			},
+			{Enabled: &off, Command: &config.Command{Name: "Cleanup", Run: "exit 1"}},
+		},
+	}
+
+	rec := &RecordingCallbacks{}
+	if err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{1: {0: true}}); err != nil {
+		t.Fatalf("RunWithCallbacks failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 1 {
+		t.Errorf("expected only Deploy 1 to be triggered, got %d builds", n)
+	}
+	want := map[string]string{
+		"Build":    SkipReasonDisabledInFile,
+		"Test":     SkipReasonDisabled,
+		"Deploy 2": SkipReasonDisabledInFile,
+		"Cleanup":  SkipReasonDisabledInFile,
+	}
+	got := map[string]string{}
+	for _, e := range rec.Events() {
+		if e.Kind == "StepSkipped" {
+			got[e.Name] = e.Reason
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected skip reasons %v, got %v", want, got)
+	}
+}
+
+func TestRunAttempt_FollowsPlan(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{Name: "Test", Instance: "test", Job: "/job/test"},
+		},
+	}
+	plan, err := BuildPlan(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Items[0].Steps[0].Skipped = true
+	plan.Items[0].Steps[0].SkipReason = SkipReasonDisabled
+
+	rec := &RecordingCallbacks{}
+	if err := runAttempt(context.Background(), cfg, plan, logger.New(logger.Error), rec, nil); err != nil {
+		t.Fatalf("runAttempt failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 1 {
+		t.Errorf("expected only the step the plan runs to be triggered, got %d builds", n)
+	}
+	want := []string{"StepSkipped(0,0,Build)", "StepStart(1,0,Test)"}
+	got := rec.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepSkipped" || e.Kind == "StepStart" })
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunWithCallbacks_FailureStopsSequence(t *testing.T) {
+	server := mockFailingJenkinsServer()
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{Name: "Deploy", Instance: "test", Job: "/job/test"},
+		},
+	}
+
+	rec := &RecordingCallbacks{}
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{})
+	if err == nil {
+		t.Fatal("expected workflow error, got nil")
+	}
+
+	assertSequence(t, rec.Sequence(nil), []string{
+		"StepStart(0,0,Build)",
+		"StepQueued(0,0,Build)",
+		"StepBuildStarted(0,0,Build)",
+		"StepComplete(0,0,Build,FAILURE)",
+	})
+}
+
+func TestRunWithCallbacks_FinallyRunsAfterCancellation(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{Name: "Deploy", Instance: "test", Job: "/job/test"},
+		},
+		Finally: []config.WorkflowItem{
+			{Name: "Cleanup", Instance: "test", Job: "/job/test"},
+		},
+	}
+
+	// A cancelled run must still execute its finally items.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := &RecordingCallbacks{}
+	err := RunWithCallbacks(ctx, cfg, logger.New(logger.Error), rec, DisabledSet{})
+	if err == nil || !strings.Contains(err.Error(), `step "Build" failed`) {
+		t.Fatalf("expected the workflow error to report the Build failure, got %v", err)
+	}
+	if strings.Contains(err.Error(), "finally") {
+		t.Errorf("finally succeeded, so it should not appear in the error: %v", err)
+	}
+
+	assertSequence(t, rec.Sequence(nil), []string{
+		"StepStart(0,0,Build)",
+		"StepComplete(0,0,Build,)",
+		"StepStart(2,0,Cleanup)",
+		"StepQueued(2,0,Cleanup)",
+		"StepBuildStarted(2,0,Cleanup)",
+		"StepComplete(2,0,Cleanup,SUCCESS)",
+	})
+	if triggered != 1 {
+		t.Errorf("expected only the finally step to trigger a build, got %d", triggered)
+	}
+}
+
+func TestResumeWithCallbacks_ReattachesInsteadOfTriggering(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{Name: "Deploy", Instance: "test", Job: "/job/test"},
+			{Name: "Verify", Instance: "test", Job: "/job/test"},
+		},
+	}
+	resume := ResumeState{
+		0: {0: {QueueURL: server.URL + "/queue/item/9/", BuildURL: server.URL + "/job/test/0/", Result: "SUCCESS", BuildNumber: 7}},
+		1: {0: {QueueURL: server.URL + "/queue/item/123/", BuildURL: server.URL + "/job/test/1/"}},
+		2: {0: {QueueURL: server.URL + "/queue/item/123/"}},
+	}
+
+	rec := &RecordingCallbacks{}
+	if err := ResumeWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{}, resume); err != nil {
+		t.Fatalf("ResumeWithCallbacks failed: %v", err)
+	}
+
+	if triggered != 0 {
+		t.Errorf("expected no new builds to be triggered, got %d", triggered)
+	}
+	assertSequence(t, rec.Sequence(nil), []string{
+		"StepStart(0,0,Build)",
+		"StepBuildStarted(0,0,Build)",
+		"StepComplete(0,0,Build,SUCCESS)",
+		"StepStart(1,0,Deploy)",
+		"StepBuildStarted(1,0,Deploy)",
+		"StepComplete(1,0,Deploy,SUCCESS)",
+		"StepStart(2,0,Verify)",
+		"StepBuildStarted(2,0,Verify)",
+		"StepComplete(2,0,Verify,SUCCESS)",
+	})
+	if e := rec.Events()[2]; e.BuildNumber != 7 {
+		t.Errorf("expected the recorded build number to be reused, got %d", e.BuildNumber)
+	}
+}
+
+var _ WorkflowCallbacks = NopCallbacks{}
+
+func TestRun_WithoutCallbacks(t *testing.T) {
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"dev": {URL: "http://127.0.0.1:1", Token: "t"}},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "dev", Job: "/job/build"},
+			{Parallel: &config.ParallelGroup{Name: "Deploy", Steps: []config.Step{
+				{Name: "US", Instance: "dev", Job: "/job/deploy"},
+			}}},
+		},
+	}
+
+	// Every step is disabled, so the run succeeds without contacting Jenkins.
+	disabled := DisabledSet{0: {0: true}, 1: {0: true}}
+	if err := Run(context.Background(), cfg, logger.New(logger.Error), disabled); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
+
+// stopAfterCallbacks requests a graceful stop when the named step completes.
+type stopAfterCallbacks struct {
+	*RecordingCallbacks
+	step string
+	stop func()
+}
+
+func (c *stopAfterCallbacks) OnStepComplete(itemIndex, stepIndex int, name, result string, buildNumber int, err error) {
+	c.RecordingCallbacks.OnStepComplete(itemIndex, stepIndex, name, result, buildNumber, err)
+	if name == c.step {
+		c.stop()
+	}
+}
+
+func TestRunWithCallbacks_GracefulStop(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{
+				Parallel: &config.ParallelGroup{
+					Name: "Deploy",
+					Steps: []config.Step{
+						{Name: "Deploy 1", Instance: "test", Job: "/job/test"},
+						{Name: "Deploy 2", Instance: "test", Job: "/job/test"},
+					},
+				},
+			},
+			{WaitForPR: &config.PRWait{Name: "Wait for PR", Owner: "o", Repo: "r", PRNumber: 1, WaitFor: "merged"}},
+		},
+		Finally: []config.WorkflowItem{
+			{Name: "Cleanup", Instance: "test", Job: "/job/test"},
+		},
+	}
+
+	ctx, stop := WithGracefulStop(context.Background())
+	rec := &RecordingCallbacks{}
+	err := RunWithCallbacks(ctx, cfg, logger.New(logger.Error), &stopAfterCallbacks{RecordingCallbacks: rec, step: "Build", stop: stop}, DisabledSet{})
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+
+	// Build finishes normally, the rest of the workflow is skipped and the
+	// finally section still runs.
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.Kind != "StepQueued" && e.Kind != "StepBuildStarted" }), []string{
+		"StepStart(0,0,Build)",
+		"StepComplete(0,0,Build,SUCCESS)",
+		"StepSkipped(1,0,Deploy 1)",
+		"StepSkipped(1,1,Deploy 2)",
+		"PRWaitSkipped(2,Wait for PR)",
+		"StepStart(3,0,Cleanup)",
+		"StepComplete(3,0,Cleanup,SUCCESS)",
+	})
+	for _, e := range rec.Events() {
+		if (e.Kind == "StepSkipped" || e.Kind == "PRWaitSkipped") && e.Reason != SkipReasonStopped {
+			t.Errorf("%s: unexpected skip reason %q", e, e.Reason)
+		}
+	}
+}
+
+func TestRunWithCallbacks_TestResults(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/unit/build", "/job/lint/build":
+			job := strings.Split(r.URL.Path, "/")[2]
+			w.Header().Set("Location", server.URL+"/queue/item/"+job+"/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/unit/api/json", "/queue/item/lint/api/json":
+			job := strings.Split(r.URL.Path, "/")[3]
+			fmt.Fprintf(w, `{"executable": {"url": "%s/job/%s/1/"}}`, server.URL, job)
+		case "/job/unit/1/api/json":
+			w.Write([]byte(`{"building": false, "result": "UNSTABLE", "number": 1}`))
+		case "/job/lint/1/api/json":
+			w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 1}`))
+		case "/job/unit/1/testReport/api/json":
+			w.Write([]byte(`{"passCount": 120, "failCount": 3, "skipCount": 4}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+		Workflow: []config.WorkflowItem{
+			{Name: "Lint", Instance: "test", Job: "/job/lint"},
+			{Name: "Unit Tests", Instance: "test", Job: "/job/unit"},
+		},
+	}
+	rec := &RecordingCallbacks{}
+	RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, nil)
+
+	// Lint has no test report, so only the unit tests report counts, before
+	// the step completes.
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepTestResults" || e.Kind == "StepComplete" }), []string{
+		"StepComplete(0,0,Lint,SUCCESS)",
+		"StepTestResults(1,0,Unit Tests,120/3/4)",
+		"StepComplete(1,0,Unit Tests,UNSTABLE)",
+	})
+}
+
+func TestRunWithCallbacks_Artifacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/job/app/3/api/json":
+			http.NotFound(w, r)
+		case strings.Contains(r.URL.Query().Get("tree"), "artifacts["):
+			w.Write([]byte(`{"artifacts": [{"fileName": "app.jar", "relativePath": "target/app.jar"}]}`))
+		default:
+			w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 3}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+		Workflow:        []config.WorkflowItem{{Name: "Package", Instance: "test", Job: "/job/app"}},
+	}
+	rec := &RecordingCallbacks{}
+	resume := ResumeState{0: {0: {BuildURL: server.URL + "/job/app/3/"}}}
+	if err := ResumeWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{}, resume); err != nil {
+		t.Fatalf("ResumeWithCallbacks failed: %v", err)
+	}
+
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepArtifacts" || e.Kind == "StepComplete" }), []string{
+		"StepArtifacts(0,0,Package,1)",
+		"StepComplete(0,0,Package,SUCCESS)",
+	})
+	for _, e := range rec.Events() {
+		if e.Kind == "StepArtifacts" && e.Artifacts[0].URL != server.URL+"/job/app/3/artifact/target/app.jar" {
+			t.Errorf("unexpected artifact link %q", e.Artifacts[0].URL)
+		}
+	}
+}
+
+func TestRunWithCallbacks_QueueStuck(t *testing.T) {
+	var polls, cancelled int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/build":
+			w.Header().Set("Location", server.URL+"/queue/item/9/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/9/api/json":
+			if atomic.AddInt32(&polls, 1) == 1 {
+				w.Write([]byte(`{"id": 9, "blocked": true}`))
+				return
+			}
+			w.Write([]byte(`{"id": 9, "buildable": true, "stuck": true}`))
+		case "/queue/cancelItem":
+			atomic.AddInt32(&cancelled, 1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token", QueueStuckGraceSecs: 1}},
+		Workflow:        []config.WorkflowItem{{Name: "Deploy", Instance: "test", Job: "/job/deploy"}},
+	}
+	rec := &RecordingCallbacks{}
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, nil)
+
+	var queueErr *QueueError
+	if !errors.As(err, &queueErr) || !errors.Is(err, jenkins.ErrQueueStuck) {
+		t.Fatalf("expected a QueueError for the stuck item, got %v", err)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Errorf("expected the stuck queue item to be cancelled, got %d requests", cancelled)
+	}
+	assertSequence(t, rec.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepQueueStatus" || e.Kind == "StepComplete" }), []string{
+		"StepQueueStatus(0,0,Deploy,blocked)",
+		"StepQueueStatus(0,0,Deploy,stuck)",
+		"StepComplete(0,0,Deploy,)",
+	})
+}
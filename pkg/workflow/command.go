@@ -0,0 +1,205 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+const (
+	// defaultCommandTimeout bounds a command item without timeout_secs.
+	defaultCommandTimeout = 10 * time.Minute
+	// commandKillDelay is how long a stopped or timed-out command gets to exit
+	// after SIGTERM before it is killed.
+	commandKillDelay = 5 * time.Second
+	// commandOutputLimit caps the output kept for a command; the end is kept.
+	commandOutputLimit = 64 << 10
+)
+
+// commandRefRe matches the ${...} references substituted into a command line.
+var commandRefRe = regexp.MustCompile(`\$\{([\w.]+)\}`)
+
+// commandVarPrefix names the environment variables commandLine passes
+// substituted values in.
+const commandVarPrefix = "JF_VAR_"
+
+// commandLine prepares a command's run line for sh -c. Each reference vars
+// has a value for is replaced by a reference to an environment variable,
+// quoted to suit where it stands, and the variables are returned as
+// NAME=value. Values thus reach the command as single words and are never
+// parsed by the shell. Unlike config.Substitute it leaves references vars
+// has no value for, and bare $VAR, for the shell to expand.
+func commandLine(cmd *config.Command, vars map[string]string) (string, []string) {
+	var env []string
+	names := map[string]string{}
+	line := replaceCommandRefs(cmd.Run, vars, func(key, value string, quote byte) string {
+		name, ok := names[key]
+		if !ok {
+			name = fmt.Sprintf("%s%d", commandVarPrefix, len(names)+1)
+			names[key] = name
+			env = append(env, name+"="+value)
+		}
+		switch quote {
+		case '"':
+			return "${" + name + "}"
+		case '\'':
+			return `'"$` + name + `"'`
+		default:
+			return `"$` + name + `"`
+		}
+	})
+	return line, env
+}
+
+// displayCommandLine substitutes vars into a command's run line for the plan,
+// quoting each value so the line reads as the command receives it.
+func displayCommandLine(cmd *config.Command, vars map[string]string) string {
+	return replaceCommandRefs(cmd.Run, vars, func(_, value string, quote byte) string {
+		switch quote {
+		case '"':
+			return doubleQuoteEscaper.Replace(value)
+		case '\'':
+			return strings.ReplaceAll(value, "'", `'\''`)
+		default:
+			return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+		}
+	})
+}
+
+// doubleQuoteEscaper escapes the characters special inside double quotes.
+var doubleQuoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+
+// replaceCommandRefs replaces the references in run that vars has a value for
+// with what replace returns for them, given the quote character, ' or ", in
+// effect there, or 0 outside quotes.
+func replaceCommandRefs(run string, vars map[string]string, replace func(key, value string, quote byte) string) string {
+	var b strings.Builder
+	var quote byte
+	last := 0
+	for _, m := range commandRefRe.FindAllStringSubmatchIndex(run, -1) {
+		quote = scanQuotes(run[last:m[0]], quote)
+		b.WriteString(run[last:m[0]])
+		ref, key := run[m[0]:m[1]], run[m[2]:m[3]]
+		if v, ok := vars[key]; ok {
+			b.WriteString(replace(key, v, quote))
+		} else {
+			b.WriteString(ref)
+		}
+		last = m[1]
+	}
+	b.WriteString(run[last:])
+	return b.String()
+}
+
+// scanQuotes returns the quote character in effect after the shell reads s,
+// starting with quote in effect.
+func scanQuotes(s string, quote byte) byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++ // The next character is escaped
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return quote
+}
+
+// commandTimeout returns the configured timeout of cmd, or the default.
+func commandTimeout(cmd *config.Command) time.Duration {
+	if cmd.TimeoutSecs > 0 {
+		return time.Duration(cmd.TimeoutSecs) * time.Second
+	}
+	return defaultCommandTimeout
+}
+
+// runCommand runs a command item's prepared line with sh -c, adding env to
+// the server's environment, and returns its combined stdout and stderr. A
+// non-zero exit, a timeout, or ctx being cancelled is an error; in the latter
+// two cases the command's whole process group is terminated.
+func runCommand(ctx context.Context, cmd *config.Command, line string, env []string, l *logger.Logger) (string, error) {
+	timeout := commandTimeout(cmd)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out tailBuffer
+	c := exec.CommandContext(runCtx, "sh", "-c", line)
+	c.Dir = cmd.Dir
+	c.Env = append(os.Environ(), env...)
+	c.Stdout = &out
+	c.Stderr = &out
+	c.WaitDelay = commandKillDelay
+	setProcessGroup(c)
+
+	l.Debugf("  -> [%s] Running: %s", cmd.Name, line)
+	err := c.Run()
+	output := out.String()
+	switch {
+	case err == nil:
+		return output, nil
+	case ctx.Err() != nil:
+		return output, fmt.Errorf("command stopped: %w", ctx.Err())
+	case runCtx.Err() != nil:
+		return output, &TimeoutError{Limit: timeout, Err: fmt.Errorf("command timed out after %s", timeout)}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if last := lastLine(output); last != "" {
+			return output, fmt.Errorf("command exited with status %d: %s", exitErr.ExitCode(), last)
+		}
+		return output, fmt.Errorf("command exited with status %d", exitErr.ExitCode())
+	}
+	return output, fmt.Errorf("running command: %w", err)
+}
+
+// lastLine returns the last non-empty line of s, for error messages.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// tailBuffer is an io.Writer keeping the last commandOutputLimit bytes written
+// to it. Stdout and stderr share one, so writes are serialized.
+type tailBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - commandOutputLimit; over > 0 {
+		b.buf.Next(over)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return "[output truncated]\n" + b.buf.String()
+	}
+	return b.buf.String()
+}
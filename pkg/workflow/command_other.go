@@ -0,0 +1,9 @@
+//go:build !unix
+
+package workflow
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are not available; a
+// cancelled command's shell is killed, but not its children.
+func setProcessGroup(c *exec.Cmd) {}
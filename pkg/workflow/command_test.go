@@ -0,0 +1,157 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+func TestRunWithCallbacks_Command(t *testing.T) {
+	t.Setenv("JF_TEST_SHELL_VAR", "from-shell")
+	cfg := &config.Config{
+		Inputs: map[string]string{"ENV": "staging"},
+		Workflow: []config.WorkflowItem{
+			{Command: &config.Command{Name: "Purge", Run: `echo purge ${ENV} $JF_TEST_SHELL_VAR ${JF_TEST_SHELL_VAR}; echo warn >&2`}},
+			{Command: &config.Command{Name: "Report", Run: `echo "got ${steps.purge.output}"`}},
+		},
+	}
+
+	callbacks := &RecordingCallbacks{}
+	if err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), callbacks, nil); err != nil {
+		t.Fatalf("RunWithCallbacks failed: %v", err)
+	}
+
+	want := []string{
+		"StepStart(0,0,Purge)", "StepOutput(0,0,Purge)", "StepComplete(0,0,Purge,SUCCESS)",
+		"StepStart(1,0,Report)", "StepOutput(1,0,Report)", "StepComplete(1,0,Report,SUCCESS)",
+	}
+	if got := callbacks.Sequence(nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected callbacks:\n got %v\nwant %v", got, want)
+	}
+	events := callbacks.Events()
+	if got := events[1].Output; got != "purge staging from-shell from-shell\nwarn\n" {
+		t.Errorf("expected inputs substituted, shell variables kept and stderr captured, got %q", got)
+	}
+	if got := events[4].Output; got != "got purge staging from-shell from-shell\nwarn\n" {
+		t.Errorf("expected the first command's output as a step output, got %q", got)
+	}
+}
+
+func TestRunWithCallbacks_CommandFailure(t *testing.T) {
+	cfg := &config.Config{
+		Workflow: []config.WorkflowItem{
+			{Command: &config.Command{Name: "Fail", Run: "echo cache unavailable >&2; exit 3"}},
+		},
+	}
+
+	callbacks := &RecordingCallbacks{}
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), callbacks, nil)
+	if err == nil || !strings.Contains(err.Error(), `command "Fail" failed: command exited with status 3: cache unavailable`) {
+		t.Fatalf("expected the exit status and last output line in the error, got %v", err)
+	}
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.ItemIndex != 0 || stepErr.Kind != "command" || stepErr.Step != "Fail" {
+		t.Errorf("expected a *StepError for the command, got %#v", err)
+	}
+	if got := callbacks.Sequence(nil); got[len(got)-1] != "StepComplete(0,0,Fail,FAILURE)" {
+		t.Errorf("expected the command to complete as FAILURE, got %v", got)
+	}
+}
+
+func TestRunCommand_Timeout(t *testing.T) {
+	cmd := &config.Command{Name: "Slow", Run: "sleep 30", TimeoutSecs: 1}
+	start := time.Now()
+	_, err := runCommand(context.Background(), cmd, cmd.Run, nil, logger.New(logger.Error))
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Limit != time.Second {
+		t.Errorf("expected a *TimeoutError with a 1s limit, got %#v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to be killed promptly, took %s", elapsed)
+	}
+}
+
+func TestRunCommand_StopKillsProcessGroup(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "survived")
+	// The background child would create marker if it outlived the stop.
+	cmd := &config.Command{Name: "Long", Run: "(sleep 2; touch " + marker + ") & wait"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	_, err := runCommand(ctx, cmd, cmd.Run, nil, logger.New(logger.Error))
+	if err == nil || !strings.Contains(err.Error(), "command stopped") {
+		t.Fatalf("expected a stopped error, got %v", err)
+	}
+
+	time.Sleep(3 * time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("expected the command's child process to be killed on stop")
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	cmd := &config.Command{Run: `deploy ${ENV} ${steps.build.build_number} "on ${ENV}" 'at ${ENV}' ${HOME} $USER`}
+	vars := map[string]string{"ENV": "prod", "steps.build.build_number": "42"}
+	line, env := commandLine(cmd, vars)
+	if want := `deploy "$JF_VAR_1" "$JF_VAR_2" "on ${JF_VAR_1}" 'at '"$JF_VAR_1"'' ${HOME} $USER`; line != want {
+		t.Errorf("commandLine = %q, want %q", line, want)
+	}
+	if want := []string{"JF_VAR_1=prod", "JF_VAR_2=42"}; !reflect.DeepEqual(env, want) {
+		t.Errorf("commandLine env = %v, want %v", env, want)
+	}
+	vars["ENV"] = `it's "$x"`
+	if got, want := displayCommandLine(cmd, vars), `deploy 'it'\''s "$x"' '42' "on it's \"\$x\"" 'at it'\''s "$x"' ${HOME} $USER`; got != want {
+		t.Errorf("displayCommandLine = %q, want %q", got, want)
+	}
+}
+
+func TestRunWithCallbacks_CommandInputsNotParsed(t *testing.T) {
+	dir := t.TempDir()
+	cases := []string{
+		"x; touch pwned",
+		`it's a "quoted" $HOME value`,
+		"$(touch pwned) `touch pwned`",
+	}
+	for _, value := range cases {
+		cfg := &config.Config{
+			Inputs: map[string]string{"MSG": value},
+			Workflow: []config.WorkflowItem{
+				{Command: &config.Command{Name: "Echo", Run: `printf '%s|' ${MSG} "in quotes: ${MSG}" 'in single quotes: ${MSG}'`, Dir: dir}},
+			},
+		}
+		callbacks := &RecordingCallbacks{}
+		if err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), callbacks, nil); err != nil {
+			t.Fatalf("RunWithCallbacks(%q) failed: %v", value, err)
+		}
+		if got, want := callbacks.Events()[1].Output, value+"|in quotes: "+value+"|in single quotes: "+value+"|"; got != want {
+			t.Errorf("expected %q passed to the command verbatim, got %q", value, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Error("expected an input value not to be run as a shell command")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	var b tailBuffer
+	b.Write([]byte(strings.Repeat("a", commandOutputLimit)))
+	b.Write([]byte("end"))
+	got := b.String()
+	if !strings.HasPrefix(got, "[output truncated]\n") || !strings.HasSuffix(got, "aend") {
+		t.Errorf("expected the tail kept with a truncation note, got %q...", got[:40])
+	}
+	if len(got) != len("[output truncated]\n")+commandOutputLimit {
+		t.Errorf("expected output capped at %d bytes, got %d", commandOutputLimit, len(got)-len("[output truncated]\n"))
+	}
+}
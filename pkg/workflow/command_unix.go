@@ -0,0 +1,18 @@
+//go:build unix
+
+package workflow
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts c in its own process group and makes cancelling it
+// send SIGTERM to the whole group, so children of the shell are stopped too.
+// exec.Cmd.WaitDelay later kills the shell if it does not exit.
+func setProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGTERM)
+	}
+}
@@ -1,10 +1,12 @@
 package workflow
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +24,33 @@ type StepResult struct {
 	BuildNumber int
 	BuildURL    string
 	Error       error
+	Duration    time.Duration
+	SkipReason  string // Why a SKIPPED step did not run
+}
+
+// SkipReasonDisabled explains a step or item the user disabled for the run.
+const SkipReasonDisabled = "skipped: disabled by user"
+
+// SkipReasonDisabledInFile explains a step or item turned off with
+// `enabled: false` in the workflow file.
+const SkipReasonDisabledInFile = "skipped: disabled in workflow file"
+
+// ResultAborted is the step result recorded for parallel steps cancelled
+// because a sibling in the same group failed. Jenkins reports the same result
+// for builds aborted on its side.
+const ResultAborted = "ABORTED"
+
+// ResultQueued and ResultStarted are recorded for steps with `wait: queued`
+// and `wait: started`, which succeed without waiting for the build to finish.
+const (
+	ResultQueued  = "QUEUED"
+	ResultStarted = "STARTED"
+)
+
+// IsSuccess reports whether a step result counts as a success: a SUCCESS
+// build, or a build that reached the point its step's wait mode asked for.
+func IsSuccess(result string) bool {
+	return result == "SUCCESS" || result == ResultQueued || result == ResultStarted
 }
 
 // DisabledSet is a map of itemIndex -> set of disabled stepIndexes.
@@ -36,23 +65,66 @@ func (d DisabledSet) IsDisabled(itemIndex, stepIndex int) bool {
 }
 
 // WorkflowCallbacks provides hooks into workflow execution for state tracking.
+//
+// For every executed Jenkins step the engine calls OnStepStart once when the
+// step begins, OnStepQueued once the job is triggered (with the params as sent
+// to Jenkins, after substitution, and secret_params masked), OnStepBuildStarted
+// once the queued build has a URL, and OnStepComplete when it finishes.
+// itemIndex is the position in cfg.AllItems(); stepIndex is the position
+// inside a parallel group (0 for single steps). Command items get OnStepStart,
+// OnStepOutput with what the command printed, and OnStepComplete.
+// OnStepTestResults reports a finished build's JUnit pass, fail and skip
+// counts, before OnStepComplete, when the build published a test report.
+// OnStepArtifacts likewise reports the files a finished build archived.
+// OnStepQueueStatus reports the Jenkins queue flags of a queued build, between
+// OnStepQueued and OnStepBuildStarted, when first seen and whenever they change.
+// OnRunRetry is called when a failed run starts over because of cfg.Retries,
+// before any event of the new attempt; attempt counts from 1 for the first run.
 type WorkflowCallbacks interface {
 	OnStepStart(itemIndex, stepIndex int, name, buildURL string)
+	OnStepQueued(itemIndex, stepIndex int, name, queueURL string, params map[string]string)
+	OnStepBuildStarted(itemIndex, stepIndex int, name, buildURL string)
 	OnStepComplete(itemIndex, stepIndex int, name, result string, buildNumber int, err error)
-	OnStepSkipped(itemIndex, stepIndex int, name string)
+	OnStepSkipped(itemIndex, stepIndex int, name, reason string)
+	OnStepOutput(itemIndex, stepIndex int, name, output string)
+	OnStepTestResults(itemIndex, stepIndex int, name string, results jenkins.TestResults)
+	OnStepArtifacts(itemIndex, stepIndex int, name string, artifacts []jenkins.Artifact)
+	OnStepQueueStatus(itemIndex, stepIndex int, name string, status jenkins.QueueStatus)
 	OnPRWaitStart(itemIndex int, pr *config.PRWait)
 	OnPRWaitProgress(itemIndex int, pr *config.PRWait)
 	OnPRWaitComplete(itemIndex int, pr *config.PRWait)
 	OnPRWaitFailed(itemIndex int, pr *config.PRWait, err error)
-	OnPRWaitSkipped(itemIndex int, pr *config.PRWait)
+	OnPRWaitSkipped(itemIndex int, pr *config.PRWait, reason string)
+	OnRunRetry(attempt int, err error)
 }
 
-// mergeVars combines workflow inputs with step outputs for substitution.
+// NopCallbacks is a WorkflowCallbacks that ignores every event. It can be
+// embedded to implement only the hooks a caller cares about.
+type NopCallbacks struct{}
+
+func (NopCallbacks) OnStepStart(int, int, string, string)                     {}
+func (NopCallbacks) OnStepQueued(int, int, string, string, map[string]string) {}
+func (NopCallbacks) OnStepBuildStarted(int, int, string, string)              {}
+func (NopCallbacks) OnStepComplete(int, int, string, string, int, error)      {}
+func (NopCallbacks) OnStepSkipped(int, int, string, string)                   {}
+func (NopCallbacks) OnStepOutput(int, int, string, string)                    {}
+func (NopCallbacks) OnStepTestResults(int, int, string, jenkins.TestResults)  {}
+func (NopCallbacks) OnStepArtifacts(int, int, string, []jenkins.Artifact)     {}
+func (NopCallbacks) OnStepQueueStatus(int, int, string, jenkins.QueueStatus)  {}
+func (NopCallbacks) OnPRWaitStart(int, *config.PRWait)                        {}
+func (NopCallbacks) OnPRWaitProgress(int, *config.PRWait)                     {}
+func (NopCallbacks) OnPRWaitComplete(int, *config.PRWait)                     {}
+func (NopCallbacks) OnPRWaitFailed(int, *config.PRWait, error)                {}
+func (NopCallbacks) OnPRWaitSkipped(int, *config.PRWait, string)              {}
+func (NopCallbacks) OnRunRetry(int, error)                                    {}
+
+// mergeVars combines workflow inputs and vars with step outputs for substitution.
 // Outputs win on key collision (shouldn't happen in practice — outputs are
-// dotted "steps.x.y" keys while inputs are flat).
-func mergeVars(inputs map[string]string, outputs *Outputs) map[string]string {
-	merged := make(map[string]string, len(inputs))
-	for k, v := range inputs {
+// "steps.x.y" keys while the rest are flat or "inputs."/"vars." prefixed).
+func mergeVars(cfg *config.Config, outputs *Outputs) map[string]string {
+	base := cfg.TemplateVars(cfg.Inputs)
+	merged := make(map[string]string, len(base))
+	for k, v := range base {
 		merged[k] = v
 	}
 	if outputs != nil {
@@ -63,199 +135,684 @@ func mergeVars(inputs map[string]string, outputs *Outputs) map[string]string {
 	return merged
 }
 
-// RunWithCallbacks executes the workflow with callback notifications.
+// Run executes the workflow without callbacks, skipping the steps in disabledSet.
+func Run(ctx context.Context, cfg *config.Config, l *logger.Logger, disabledSet DisabledSet) error {
+	return RunWithCallbacks(ctx, cfg, l, NopCallbacks{}, disabledSet)
+}
+
+// RunWithCallbacks executes the workflow with callback notifications. A nil
+// callbacks is treated as NopCallbacks.
+//
+// Items in cfg.Finally run after cfg.Workflow whether it succeeded, failed or
+// was stopped (see WithGracefulStop), with a context not cancelled with ctx.
+// Every finally item runs even if an earlier one fails. They are reported to
+// callbacks with itemIndex len(cfg.Workflow)+j, matching cfg.AllItems.
+//
+// With cfg.VerifyJobs set, the run first checks that every step's job exists
+// (see VerifyJobs) and fails without running anything, finally included, if
+// one does not. cfg.VerifyParams does the same and also checks the steps'
+// params. cfg.VerifyWebhooks likewise checks every Slack webhook first
+// (see VerifyWebhooks).
 func RunWithCallbacks(ctx context.Context, cfg *config.Config, l *logger.Logger, callbacks WorkflowCallbacks, disabledSet DisabledSet) error {
-	l.Infof("Starting workflow execution...")
+	return ResumeWithCallbacks(ctx, cfg, l, callbacks, disabledSet, nil)
+}
+
+// ResumeWithCallbacks is RunWithCallbacks for a run interrupted by a restart.
+// Steps with a recorded result are not run again, steps with a build URL are
+// reattached with jenkins.Client.ReattachBuild, and steps with only a queue URL
+// resume waiting on the queue. Everything else runs normally.
+func ResumeWithCallbacks(ctx context.Context, cfg *config.Config, l *logger.Logger, callbacks WorkflowCallbacks, disabledSet DisabledSet, resume ResumeState) error {
+	if callbacks == nil {
+		callbacks = NopCallbacks{}
+	}
+	// Secrets registered for redaction during the run are dropped with it
+	l = l.WithSecrets()
+	if len(resume) > 0 {
+		l.Infof("Resuming workflow execution...")
+	} else {
+		l.Infof("Starting workflow execution...")
+	}
 	start := time.Now()
 
-	outputs := NewOutputs()
+	plan, err := BuildPlan(cfg, cfg.Inputs)
+	if err != nil {
+		return fmt.Errorf("failed to build execution plan: %w", err)
+	}
+	plan.MarkDisabled(disabledSet)
+	l.Debugf("Execution plan:\n%s", plan)
+	if err := CheckParams(cfg, cfg.Inputs, disabledSet); err != nil {
+		return err
+	}
 
-	for i, item := range cfg.Workflow {
-		if item.IsPRWait() {
-			// Execute PR wait
-			pr := item.WaitForPR
-			target := describePRTarget(pr)
+	if (cfg.VerifyJobs || cfg.VerifyParams) && len(resume) == 0 {
+		if err := VerifyJobs(ctx, cfg, plan, l); err != nil {
+			return err
+		}
+	}
+	if cfg.VerifyWebhooks && len(resume) == 0 {
+		if err := VerifyWebhooks(cfg, l); err != nil {
+			return err
+		}
+	}
 
-			if disabledSet.IsDisabled(i, 0) {
-				l.Infof("[%d/%d] Skipping PR wait %s (disabled by user).", i+1, len(cfg.Workflow), target)
-				if callbacks != nil {
-					callbacks.OnPRWaitSkipped(i, pr)
-				}
-				continue
-			}
+	attempts := cfg.Retries + 1
+	err = runAttempt(ctx, cfg, plan, l, callbacks, resume)
+	for attempt := 2; err != nil && attempt <= attempts && retryable(ctx, err); attempt++ {
+		delay := cfg.RetryDelay()
+		l.Errorf("Workflow failed: %v", err)
+		l.Infof("Retrying the workflow from the beginning in %s (attempt %d/%d)...", delay, attempt, attempts)
+		if !sleepCtx(ctx, delay) {
+			break
+		}
+		if stopRequested(ctx) {
+			err = ErrStopped
+			break
+		}
+		callbacks.OnRunRetry(attempt, err)
+		err = runAttempt(ctx, cfg, plan, l, callbacks, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	duration := time.Since(start)
+	l.Infof("Workflow completed successfully in %s.", duration)
+	return nil
+}
 
-			l.Infof("[%d/%d] Waiting for %s (%s/%s) to be %s...",
-				i+1, len(cfg.Workflow), target, pr.Owner, pr.Repo, pr.WaitFor)
+// retryable reports whether a failed run may start over: not when it was
+// stopped, cancelled, or a build was aborted in Jenkins, since a person
+// asked for it to end.
+func retryable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrStopped) && !IsBuildAborted(err)
+}
 
-			if err := runPRWait(ctx, cfg, pr, l, callbacks, i); err != nil {
-				if callbacks != nil {
-					callbacks.OnPRWaitFailed(i, pr, err)
-				}
-				return fmt.Errorf("PR wait %q failed: %w", pr.Name, err)
-			}
-			if callbacks != nil {
-				callbacks.OnPRWaitComplete(i, pr)
+// sleepCtx waits for d and reports whether it elapsed before ctx was done.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// runAttempt runs the plan's workflow items and then its finally items once,
+// with fresh step outputs, and logs the summary.
+func runAttempt(ctx context.Context, cfg *config.Config, plan *Plan, l *logger.Logger, callbacks WorkflowCallbacks, resume ResumeState) error {
+	var err error
+	outputs := NewOutputs()
+
+	summary := &runSummary{}
+	all := cfg.AllItems()
+	var main, finally []PlanItem
+	for _, pi := range plan.Items {
+		if pi.Finally {
+			finally = append(finally, pi)
+		} else {
+			main = append(main, pi)
+		}
+	}
+
+	for i, pi := range main {
+		if stopRequested(ctx) {
+			l.Infof("Graceful stop requested; skipping the remaining %d item(s).", len(main)-i)
+			for _, rest := range main[i:] {
+				summary.add(all[rest.Index], skipItem(rest.Index, all[rest.Index], callbacks, SkipReasonStopped))
 			}
+			err = ErrStopped
+			break
+		}
 
-			resolved := describeResolvedPR(pr)
-			l.Infof("[%d/%d] %s is now %s. Continuing workflow...",
-				i+1, len(cfg.Workflow), resolved, pr.WaitFor)
-		} else if item.IsParallel() {
-			// Execute parallel group
-			groupName := item.Parallel.Name
-			if groupName == "" {
-				groupName = fmt.Sprintf("Parallel Group %d", i+1)
+		var results []StepResult
+		results, err = runItem(ctx, cfg, &pi, all[pi.Index], i+1, len(main), l, callbacks, resume, outputs)
+		summary.add(all[pi.Index], results)
+		if err != nil {
+			for _, rest := range main[i+1:] {
+				summary.addNotRun(all[rest.Index])
 			}
-			l.Infof("[%d/%d] Starting %s (%d steps)...", i+1, len(cfg.Workflow), groupName, len(item.Parallel.Steps))
+			break
+		}
+	}
 
-			results, err := runParallelGroupWithCallbacks(ctx, cfg, item.Parallel.Steps, i, l, callbacks, disabledSet, outputs)
+	if len(finally) > 0 {
+		if errors.Is(err, ErrStopped) {
+			l.Infof("Running finally section (%d items) after stop...", len(finally))
+		} else if err != nil {
+			l.Infof("Running finally section (%d items) after failure...", len(finally))
+		} else {
+			l.Infof("Running finally section (%d items)...", len(finally))
+		}
+		finallyCtx := context.WithoutCancel(ctx)
+		var finallyErrs []error
+		for j, pi := range finally {
+			item := all[pi.Index]
+			results, ferr := runItem(finallyCtx, cfg, &pi, item, j+1, len(finally), l, callbacks, resume, outputs)
+			summary.add(item, results)
+			if ferr != nil {
+				l.Errorf("Finally item %q failed: %v", item.ItemName(), ferr)
+				finallyErrs = append(finallyErrs, ferr)
+			}
+		}
+		if ferr := errors.Join(finallyErrs...); ferr != nil {
 			if err != nil {
-				return fmt.Errorf("parallel group %q failed: %w", groupName, err)
+				err = fmt.Errorf("%w (finally: %v)", err, ferr)
+			} else {
+				err = fmt.Errorf("finally: %w", ferr)
 			}
+		}
+	}
+
+	l.Infof("Execution summary:\n%s", summary)
+	return err
+}
+
+// runItem executes the top-level item pi plans and returns one StepResult per
+// step it covers (one for single steps, PR waits, and GitHub statuses). item
+// is the item at pi.Index in cfg.AllItems(); steps the plan marks as skipped
+// do not run. pos/total are for logging.
+func runItem(ctx context.Context, cfg *config.Config, pi *PlanItem, item config.WorkflowItem, pos, total int, l *logger.Logger, callbacks WorkflowCallbacks, resume ResumeState, outputs *Outputs) ([]StepResult, error) {
+	i := pi.Index
+	if item.IsPRWait() {
+		// Execute PR wait
+		pr := item.WaitForPR
+		target := describePRTarget(pr)
+
+		if reason := pi.skipReason(0); reason != "" {
+			l.Infof("[%d/%d] Skipping PR wait %s (%s).", pos, total, target, strings.TrimPrefix(reason, "skipped: "))
+			callbacks.OnPRWaitSkipped(i, pr, reason)
+			return []StepResult{{StepName: pr.Name, Result: "SKIPPED", SkipReason: reason}}, nil
+		}
+
+		l.Infof("[%d/%d] Waiting for %s (%s/%s) to be %s...",
+			pos, total, target, pr.Owner, pr.Repo, pr.WaitFor)
+
+		itemNotify := newItemNotifier(cfg, &item, pr.Name)
+		itemNotify.started()
+		started := time.Now()
 
-			// Log all results, then publish outputs (post-group: parallel siblings cannot reference each other)
-			for idx, r := range results {
-				if r.Error != nil {
-					log.Printf("  ✗ %s: FAILED - %v", r.StepName, r.Error)
-					continue
+		if err := runPRWait(ctx, cfg, pr, l, callbacks, i); err != nil {
+			callbacks.OnPRWaitFailed(i, pr, err)
+			itemNotify.finished(nil, err)
+			return []StepResult{{StepName: pr.Name, Error: err, Duration: time.Since(started)}},
+				&PRWaitError{ItemIndex: i, Name: pr.Name, Err: err}
+		}
+		callbacks.OnPRWaitComplete(i, pr)
+		itemNotify.finished(nil, nil)
+		outputs.setPR(pr)
+
+		resolved := describeResolvedPR(pr)
+		l.Infof("[%d/%d] %s is now %s. Continuing workflow...",
+			pos, total, resolved, pr.WaitFor)
+		return []StepResult{{StepName: pr.Name, Result: "SUCCESS", BuildURL: pr.ResolvedURL, Duration: time.Since(started)}}, nil
+	} else if item.IsParallel() {
+		// Execute parallel group
+		groupName := item.Parallel.Name
+		if groupName == "" {
+			groupName = fmt.Sprintf("Parallel Group %d", i+1)
+		}
+		l.Infof("[%d/%d] Starting %s (%d steps)...", pos, total, groupName, len(item.Parallel.Steps))
+
+		itemNotify := newItemNotifier(cfg, &item, groupName)
+		itemNotify.started()
+
+		results, err := runParallelGroupWithCallbacks(ctx, cfg, item.Parallel.Steps, pi, l, callbacks, resume, outputs)
+		itemNotify.finished(results, err)
+		if err != nil {
+			return results, fmt.Errorf("parallel group %q failed: %w", groupName, err)
+		}
+
+		// Log all results, then publish outputs (post-group: parallel siblings cannot reference each other)
+		for idx, r := range results {
+			if r.Error != nil {
+				l.Errorf("  ✗ %s: FAILED - %v", r.StepName, r.Error)
+				continue
+			}
+			l.Infof("  ✓ %s: %s", r.StepName, r.Result)
+			if IsSuccess(r.Result) {
+				stepID := item.Parallel.Steps[idx].ResolvedID()
+				if r.BuildNumber > 0 {
+					outputs.Set(stepID, "build_number", strconv.Itoa(r.BuildNumber))
 				}
-				log.Printf("  ✓ %s: %s", r.StepName, r.Result)
-				if r.Result == "SUCCESS" {
-					stepID := item.Parallel.Steps[idx].ResolvedID()
-					if r.BuildNumber > 0 {
-						outputs.Set(stepID, "build_number", strconv.Itoa(r.BuildNumber))
-					}
-					if r.BuildURL != "" {
-						outputs.Set(stepID, "build_url", r.BuildURL)
-					}
+				if r.BuildURL != "" {
+					outputs.Set(stepID, "build_url", r.BuildURL)
 				}
 			}
+		}
 
-			log.Printf("[%d/%d] %s completed successfully.", i+1, len(cfg.Workflow), groupName)
-		} else {
-			// Execute single step
-			step := item.AsStep()
+		l.Infof("[%d/%d] %s completed successfully.", pos, total, groupName)
+		return results, nil
+	} else if item.IsGitHubStatus() {
+		// Post status/comment back to GitHub
+		gs := item.GitHubStatus
 
-			if disabledSet.IsDisabled(i, 0) {
-				l.Infof("[Step %d/%d] Skipping step %q (disabled by user).", i+1, len(cfg.Workflow), step.Name)
-				if callbacks != nil {
-					callbacks.OnStepSkipped(i, 0, step.Name)
-				}
-				continue
-			}
+		if reason := pi.skipReason(0); reason != "" {
+			l.Infof("[%d/%d] Skipping GitHub status %q (%s).", pos, total, gs.Name, strings.TrimPrefix(reason, "skipped: "))
+			callbacks.OnStepSkipped(i, 0, gs.Name, reason)
+			return []StepResult{{StepName: gs.Name, Result: "SKIPPED", SkipReason: reason}}, nil
+		}
 
-			l.Infof("[Step %d/%d] Starting step %q on instance %q...", i+1, len(cfg.Workflow), step.Name, step.Instance)
+		l.Infof("[%d/%d] Posting GitHub status %q to %s/%s...", pos, total, gs.Name, gs.Owner, gs.Repo)
+		callbacks.OnStepStart(i, 0, gs.Name, "")
+		itemNotify := newItemNotifier(cfg, &item, gs.Name)
+		itemNotify.started()
+		started := time.Now()
 
-			if callbacks != nil {
-				callbacks.OnStepStart(i, 0, step.Name, "")
-			}
+		err := runGitHubStatus(ctx, cfg, gs, l, outputs)
+		res := []StepResult{{StepName: gs.Name, Error: err, Duration: time.Since(started)}}
 
-			result, buildNumber, buildURL, err := runStep(ctx, cfg, step, l, callbacks, i, 0, outputs)
+		if err == nil {
+			res[0].Result = "SUCCESS"
+		}
+		callbacks.OnStepComplete(i, 0, gs.Name, res[0].Result, 0, err)
+		itemNotify.finished(nil, err)
+		if err != nil {
+			return res, &StepError{ItemIndex: i, Step: gs.Name, Kind: "GitHub status", Err: err}
+		}
+		return res, nil
+	} else if item.IsCommand() {
+		// Run a local shell command
+		cmd := item.Command
+
+		if reason := pi.skipReason(0); reason != "" {
+			l.Infof("[%d/%d] Skipping command %q (%s).", pos, total, cmd.Name, strings.TrimPrefix(reason, "skipped: "))
+			callbacks.OnStepSkipped(i, 0, cmd.Name, reason)
+			return []StepResult{{StepName: cmd.Name, Result: "SKIPPED", SkipReason: reason}}, nil
+		}
 
-			if callbacks != nil {
-				callbacks.OnStepComplete(i, 0, step.Name, result, buildNumber, err)
-			}
+		l.Infof("[%d/%d] Running command %q...", pos, total, cmd.Name)
+		callbacks.OnStepStart(i, 0, cmd.Name, "")
+		itemNotify := newItemNotifier(cfg, &item, cmd.Name)
+		itemNotify.started()
+		started := time.Now()
 
-			if err != nil {
-				return fmt.Errorf("step %q failed: %w", step.Name, err)
-			}
+		line, env := commandLine(cmd, mergeVars(cfg, outputs))
+		output, err := runCommand(ctx, cmd, line, env, l)
+		callbacks.OnStepOutput(i, 0, cmd.Name, output)
+		res := []StepResult{{StepName: cmd.Name, Result: "SUCCESS", Error: err, Duration: time.Since(started)}}
+		if err != nil {
+			res[0].Result = "FAILURE"
+		}
+		callbacks.OnStepComplete(i, 0, cmd.Name, res[0].Result, 0, err)
+		itemNotify.finished(res, err)
+		if err != nil {
+			return res, &StepError{ItemIndex: i, Step: cmd.Name, Kind: "command", Err: err}
+		}
 
-			l.Infof("  -> Build finished with result: %s (#%d)", result, buildNumber)
-			if result != "SUCCESS" {
-				return fmt.Errorf("step %q failed with result: %s", step.Name, result)
-			}
+		outputs.Set((config.Step{Name: cmd.Name, ID: cmd.ID}).ResolvedID(), "output", strings.TrimSpace(output))
+		l.Infof("[%d/%d] Command %q finished.", pos, total, cmd.Name)
+		return res, nil
+	} else {
+		// Execute single step
+		step := item.AsStep()
+
+		if reason := pi.skipReason(0); reason != "" {
+			l.Infof("[Step %d/%d] Skipping step %q (%s).", pos, total, step.Name, strings.TrimPrefix(reason, "skipped: "))
+			callbacks.OnStepSkipped(i, 0, step.Name, reason)
+			return []StepResult{{StepName: step.Name, Result: "SKIPPED", SkipReason: reason}}, nil
+		}
 
-			// Publish outputs for downstream substitution.
-			stepID := step.ResolvedID()
-			if buildNumber > 0 {
-				outputs.Set(stepID, "build_number", strconv.Itoa(buildNumber))
-			}
-			if buildURL != "" {
-				outputs.Set(stepID, "build_url", buildURL)
-			}
+		l.Infof("[Step %d/%d] Starting step %q on instance %q...", pos, total, step.Name, step.Instance)
+
+		callbacks.OnStepStart(i, 0, step.Name, "")
+		itemNotify := newItemNotifier(cfg, &item, step.Name)
+		itemNotify.started()
+		started := time.Now()
 
-			l.Infof("[Step %d/%d] Completed successfully.", i+1, len(cfg.Workflow))
+		result, buildNumber, buildURL, err := runStep(ctx, cfg, step, l, callbacks, i, 0, outputs, resume.progress(i, 0))
+
+		callbacks.OnStepComplete(i, 0, step.Name, result, buildNumber, err)
+		res := []StepResult{{
+			StepName:    step.Name,
+			Result:      result,
+			BuildNumber: buildNumber,
+			BuildURL:    buildURL,
+			Error:       err,
+			Duration:    time.Since(started),
+		}}
+		itemNotify.finished(res, err)
+
+		if IsBuildAborted(err) {
+			return res, err
+		}
+		if err != nil {
+			return res, &StepError{ItemIndex: i, Step: step.Name, Err: err}
 		}
-	}
 
-	duration := time.Since(start)
-	l.Infof("Workflow completed successfully in %s.", duration)
-	return nil
+		l.Infof("  -> Build finished with result: %s (#%d)", result, buildNumber)
+		if !IsSuccess(result) {
+			return res, &StepFailedError{ItemIndex: i, Step: step.Name, Result: result}
+		}
+
+		// Publish outputs for downstream substitution.
+		stepID := step.ResolvedID()
+		if buildNumber > 0 {
+			outputs.Set(stepID, "build_number", strconv.Itoa(buildNumber))
+		}
+		if buildURL != "" {
+			outputs.Set(stepID, "build_url", buildURL)
+		}
+
+		l.Infof("[Step %d/%d] Completed successfully.", pos, total)
+		return res, nil
+	}
 }
 
 // runStep executes a single step and returns the build result, build number, and build URL.
 // outputs is read for ${steps.<id>.<field>} substitution; callers update it after the call.
-func runStep(ctx context.Context, cfg *config.Config, step config.Step, l *logger.Logger, callbacks WorkflowCallbacks, itemIndex, stepIndex int, outputs *Outputs) (string, int, string, error) {
+// progress is the step's state from an interrupted run; the zero value starts from scratch.
+func runStep(ctx context.Context, cfg *config.Config, step config.Step, l *logger.Logger, callbacks WorkflowCallbacks, itemIndex, stepIndex int, outputs *Outputs, progress StepProgress) (string, int, string, error) {
+	if progress.Result != "" {
+		l.Infof("  -> [%s] Already finished before restart: %s (#%d)", step.Name, progress.Result, progress.BuildNumber)
+		if progress.BuildURL != "" {
+			callbacks.OnStepBuildStarted(itemIndex, stepIndex, step.Name, progress.BuildURL)
+		}
+		if progress.Result == ResultAborted {
+			return progress.Result, progress.BuildNumber, progress.BuildURL, &BuildAbortedError{Step: step.Name}
+		}
+		return progress.Result, progress.BuildNumber, progress.BuildURL, nil
+	}
+
 	instanceCfg, ok := cfg.Instances[step.Instance]
 	if !ok {
 		return "", 0, "", fmt.Errorf("unknown instance %q", step.Instance)
 	}
 
-	token, err := instanceCfg.GetToken()
+	client, err := newJenkinsClient(instanceCfg, l)
 	if err != nil {
-		return "", 0, "", fmt.Errorf("auth error: %w", err)
+		return "", 0, "", err
 	}
+	client.PollInterval = time.Duration(cfg.JenkinsPollSecs) * time.Second
 
-	client := jenkins.NewClient(instanceCfg.URL, token, l)
+	wait := step.WaitMode()
+	if progress.BuildURL != "" && wait != config.WaitCompleted {
+		callbacks.OnStepBuildStarted(itemIndex, stepIndex, step.Name, progress.BuildURL)
+		return ResultStarted, 0, progress.BuildURL, nil
+	}
+	if progress.QueueURL != "" && wait == config.WaitQueued {
+		return ResultQueued, 0, "", nil
+	}
 
-	// Prepare params with substitution (inputs ∪ step outputs).
-	subVars := mergeVars(cfg.Inputs, outputs)
-	jobParams := make(map[string]string)
-	for k, v := range step.Params {
-		jobParams[k] = config.Substitute(v, subVars)
+	if progress.BuildURL != "" {
+		l.Infof("  -> [%s] Reattaching to build %s", step.Name, progress.BuildURL)
+		callbacks.OnStepBuildStarted(itemIndex, stepIndex, step.Name, progress.BuildURL)
+		result, buildNumber, err := client.ReattachBuild(ctx, progress.BuildURL)
+		if err != nil {
+			return "", 0, progress.BuildURL, buildWaitError(client, err)
+		}
+		if result, err = aggregateSubBuilds(ctx, client, step, progress.BuildURL, result, l); err != nil {
+			return "", buildNumber, progress.BuildURL, err
+		}
+		reportTestResults(ctx, client, step, progress.BuildURL, l, func(r jenkins.TestResults) {
+			callbacks.OnStepTestResults(itemIndex, stepIndex, step.Name, r)
+		})
+		reportArtifacts(ctx, client, step, progress.BuildURL, l, func(a []jenkins.Artifact) {
+			callbacks.OnStepArtifacts(itemIndex, stepIndex, step.Name, a)
+		})
+		return result, buildNumber, progress.BuildURL, buildAborted(ctx, client, step, progress.BuildURL, result, l)
 	}
 
-	// 1. Trigger
-	l.Infof("  -> [%s] Triggering job %s", step.Name, step.Job)
-	queueItemURL, err := client.TriggerJob(ctx, step.Job, jobParams)
-	if err != nil {
-		return "", 0, "", fmt.Errorf("failed to trigger: %w", err)
+	queueItemURL := progress.QueueURL
+	if queueItemURL != "" {
+		l.Infof("  -> [%s] Reattaching to queue item %s", step.Name, queueItemURL)
+	} else {
+		// Prepare params with substitution (inputs ∪ step outputs).
+		vars := mergeVars(cfg, outputs)
+		if ref := unresolvedPRRef(step, vars); ref != "" {
+			return "", 0, "", fmt.Errorf("unresolved reference ${%s}: no finished wait_for_pr item provides it", ref)
+		}
+		jobParams := stepParams(step, vars)
+		for _, name := range step.SecretParams {
+			l.RegisterSecret(jobParams[name])
+		}
+		if err := step.CheckParams(jobParams, false); err != nil {
+			return "", 0, "", err
+		}
+		l.RegisterSecret(step.BuildToken)
+		shownParams := step.MaskParams(jobParams)
+		job := stepJob(step, vars)
+
+		if err := checkRunningBuild(ctx, client, step, job, l); err != nil {
+			return "", 0, "", err
+		}
+
+		// 1. Trigger
+		l.Infof("  -> [%s] Triggering job %s", step.Name, job)
+		if len(jobParams) > 0 {
+			l.Debugf("  -> [%s] Parameters:%s", step.Name, formatParams(shownParams))
+		}
+		queueItemURL, err = client.TriggerJob(ctx, job, jobParams, step.BuildToken)
+		if err != nil {
+			return "", 0, "", fmt.Errorf("failed to trigger: %w", err)
+		}
+		l.Infof("  -> [%s] Queued. Item: %s", step.Name, queueItemURL)
+
+		callbacks.OnStepQueued(itemIndex, stepIndex, step.Name, queueItemURL, shownParams)
+
+		if wait == config.WaitQueued {
+			l.Infof("  -> [%s] Not waiting for the build to start (wait: %s)", step.Name, wait)
+			return ResultQueued, 0, "", nil
+		}
 	}
-	l.Infof("  -> [%s] Queued. Item: %s", step.Name, queueItemURL)
 
 	// 2. Wait for Queue
 	l.Infof("  -> [%s] Waiting for queue...", step.Name)
-	buildURL, err := client.WaitForQueue(ctx, queueItemURL)
+	buildURL, err := client.WaitForQueueStatus(ctx, queueItemURL, func(status jenkins.QueueStatus) {
+		if status.Stuck || status.Blocked {
+			l.Infof("  -> [%s] Queue item is %s", step.Name, status)
+		}
+		callbacks.OnStepQueueStatus(itemIndex, stepIndex, step.Name, status)
+	})
 	if err != nil {
-		return "", 0, "", fmt.Errorf("failed waiting for queue: %w", err)
+		return "", 0, "", &QueueError{QueueURL: queueItemURL, Err: err}
 	}
 	l.Infof("  -> [%s] Job started: %s", step.Name, buildURL)
 
-	if callbacks != nil && buildURL != "" {
-		callbacks.OnStepStart(itemIndex, stepIndex, step.Name, buildURL)
+	if buildURL != "" {
+		callbacks.OnStepBuildStarted(itemIndex, stepIndex, step.Name, buildURL)
+	}
+	if wait == config.WaitStarted {
+		l.Infof("  -> [%s] Not waiting for the build to finish (wait: %s)", step.Name, wait)
+		return ResultStarted, 0, buildURL, nil
 	}
 
 	// 3. Wait for Build
 	l.Infof("  -> [%s] Waiting for completion...", step.Name)
 	result, buildNumber, err := client.WaitForBuild(ctx, buildURL)
 	if err != nil {
-		return "", 0, buildURL, fmt.Errorf("failed waiting for build: %w", err)
+		return "", 0, buildURL, buildWaitError(client, err)
+	}
+	if result, err = aggregateSubBuilds(ctx, client, step, buildURL, result, l); err != nil {
+		return "", buildNumber, buildURL, err
+	}
+	reportTestResults(ctx, client, step, buildURL, l, func(r jenkins.TestResults) {
+		callbacks.OnStepTestResults(itemIndex, stepIndex, step.Name, r)
+	})
+	reportArtifacts(ctx, client, step, buildURL, l, func(a []jenkins.Artifact) {
+		callbacks.OnStepArtifacts(itemIndex, stepIndex, step.Name, a)
+	})
+
+	return result, buildNumber, buildURL, buildAborted(ctx, client, step, buildURL, result, l)
+}
+
+// buildWaitError wraps an error from waiting on a build, as a *TimeoutError
+// when the build ran past the instance's build_timeout_secs.
+func buildWaitError(client *jenkins.Client, err error) error {
+	err = fmt.Errorf("failed waiting for build: %w", err)
+	if errors.Is(err, jenkins.ErrBuildTimeout) {
+		return &TimeoutError{Limit: client.BuildTimeout, Err: err}
+	}
+	return err
+}
+
+// checkRunningBuild applies the step's if_running mode before it triggers job:
+// with "fail" a running build is an error, and with "wait" the step waits for
+// it to finish, for at most the instance's build_timeout_secs (a
+// *TimeoutError). The default "trigger" skips the check.
+func checkRunningBuild(ctx context.Context, client *jenkins.Client, step config.Step, job string, l *logger.Logger) error {
+	mode := step.IfRunningMode()
+	if mode == config.IfRunningTrigger {
+		return nil
+	}
+	last, err := client.LastBuildStatus(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to check for a running build: %w", err)
+	}
+	if last == nil || !last.Building {
+		return nil
+	}
+	if mode == config.IfRunningFail {
+		return fmt.Errorf("job %s already has a running build #%d (%s)", job, last.Number, last.URL)
+	}
+	l.Infof("  -> [%s] Waiting for running build #%d to finish: %s", step.Name, last.Number, last.URL)
+	if err := client.WaitForJobIdle(ctx, job); err != nil {
+		err = fmt.Errorf("failed waiting for running build #%d: %w", last.Number, err)
+		if errors.Is(err, jenkins.ErrBuildTimeout) {
+			return &TimeoutError{Limit: client.BuildTimeout, Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// newJenkinsClient resolves inst's token and returns a client for it. The
+// token and sensitive header values are registered for log redaction.
+func newJenkinsClient(inst config.Instance, l *logger.Logger) (*jenkins.Client, error) {
+	token, err := inst.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("auth error: %w", err)
+	}
+
+	l.RegisterSecret(token)
+	for name, value := range inst.Headers {
+		if logger.IsSensitiveName(name) {
+			l.RegisterSecret(value)
+		}
+	}
+
+	client := jenkins.NewClient(inst.URL, token, l)
+	client.BuildTimeout = inst.BuildTimeout()
+	client.QueueStuckGrace = inst.QueueStuckGrace()
+	client.TokenSource = inst.GetToken
+	client.Headers = inst.Headers
+	if inst.Crumb {
+		client.EnableCrumbs()
+	}
+	return client, nil
+}
+
+// aggregateSubBuilds returns result, the finished build's own result, made
+// the worst of it and the results of the build's matrix configurations when
+// the step sets aggregate_subbuilds. Configurations that did not succeed are
+// logged. Not being able to fetch them is an error, since the step could
+// otherwise pass on a failed configuration.
+func aggregateSubBuilds(ctx context.Context, client *jenkins.Client, step config.Step, buildURL, result string, l *logger.Logger) (string, error) {
+	if !step.AggregateSubbuilds {
+		return result, nil
+	}
+	subs, err := client.SubBuilds(ctx, buildURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to check matrix configurations: %w", err)
+	}
+	results := []string{result}
+	for _, sub := range subs {
+		if sub.Result != "SUCCESS" {
+			l.Infof("  -> [%s] Configuration %s finished with result: %s (%s)", step.Name, sub.Name, cmp.Or(sub.Result, "unknown"), sub.URL)
+		}
+		results = append(results, sub.Result)
+	}
+	worst := jenkins.WorstResult(results...)
+	if worst != result {
+		l.Infof("  -> [%s] %d configuration(s) checked; step result is %s (build reported %s)", step.Name, len(subs), worst, result)
+	}
+	return worst, nil
+}
+
+// reportTestResults passes the finished build's test counts to report, if it
+// has a test report. Failing to fetch the report only loses the counts.
+func reportTestResults(ctx context.Context, client *jenkins.Client, step config.Step, buildURL string, l *logger.Logger, report func(jenkins.TestResults)) {
+	results, err := client.GetTestResults(ctx, buildURL)
+	if err != nil {
+		l.Debugf("  -> [%s] Could not fetch the test report: %v", step.Name, err)
+		return
+	}
+	if results == nil {
+		return
+	}
+	l.Infof("  -> [%s] Tests: %d passed, %d failed, %d skipped", step.Name, results.Passed, results.Failed, results.Skipped)
+	report(*results)
+}
+
+// reportArtifacts passes the files the finished build archived to report, if
+// there are any. Failing to list them only loses the references.
+func reportArtifacts(ctx context.Context, client *jenkins.Client, step config.Step, buildURL string, l *logger.Logger, report func([]jenkins.Artifact)) {
+	artifacts, err := client.GetArtifacts(ctx, buildURL)
+	if err != nil {
+		l.Debugf("  -> [%s] Could not list the build's artifacts: %v", step.Name, err)
+		return
 	}
+	if len(artifacts) == 0 {
+		return
+	}
+	l.Debugf("  -> [%s] Artifacts: %d", step.Name, len(artifacts))
+	report(artifacts)
+}
 
-	return result, buildNumber, buildURL, nil
+// buildAborted returns a BuildAbortedError naming who aborted the build when
+// result is ABORTED, and nil otherwise. Failing to look up the cause only
+// loses the name.
+func buildAborted(ctx context.Context, client *jenkins.Client, step config.Step, buildURL, result string, l *logger.Logger) error {
+	if result != ResultAborted {
+		return nil
+	}
+	by, err := client.AbortCause(ctx, buildURL)
+	if err != nil {
+		l.Debugf("  -> [%s] Could not determine who aborted the build: %v", step.Name, err)
+	}
+	return &BuildAbortedError{Step: step.Name, By: by}
 }
 
-// runPRWait monitors a GitHub PR until it reaches the target state.
+// defaultPRPollMax caps the poll interval of a wait_for_pr with poll_backoff
+// and no poll_max_secs.
+const defaultPRPollMax = 5 * time.Minute
+
+// runPRWait monitors a GitHub PR until it reaches the target state, with the
+// wait's own github config when it has one (see config.Config.GitHubFor).
 func runPRWait(ctx context.Context, cfg *config.Config, pr *config.PRWait, l *logger.Logger, callbacks WorkflowCallbacks, itemIndex int) error {
-	if cfg.GitHub == nil {
+	gh := cfg.GitHubFor(pr)
+	if gh == nil {
 		return fmt.Errorf("github configuration is required for wait_for_pr steps")
 	}
 
-	token, err := cfg.GitHub.GetToken()
+	client, err := newGitHubClient(gh, l)
 	if err != nil {
-		return fmt.Errorf("github auth error: %w", err)
+		return err
+	}
+	// Without credentials a private repository looks missing, which would
+	// otherwise surface as a confusing "PR not found" or no-match error.
+	if !gh.HasAuth() {
+		if err := client.CheckRepoAccess(ctx, pr.Owner, pr.Repo); errors.Is(err, github.ErrRepoNotVisible) {
+			return fmt.Errorf("%w; if it is private, set auth_env, token or app in the wait's github block or the global one", err)
+		} else if err != nil {
+			return err
+		}
 	}
-
-	client := github.NewClient(token, l)
 	pollInterval := time.Duration(pr.PollSecs) * time.Second
 	if pollInterval == 0 {
 		pollInterval = 30 * time.Second
 	}
-
-	if callbacks != nil {
-		callbacks.OnPRWaitStart(itemIndex, pr)
+	var maxInterval time.Duration
+	if pr.PollBackoff {
+		maxInterval = time.Duration(pr.PollMaxSecs) * time.Second
+		if maxInterval == 0 {
+			maxInterval = defaultPRPollMax
+		}
 	}
 
+	callbacks.OnPRWaitStart(itemIndex, pr)
+
 	prNumber := pr.PRNumber
 	if prNumber == 0 && pr.HeadBranch != "" {
 		resolved, err := client.FindPRByBranch(ctx, pr.Owner, pr.Repo, pr.HeadBranch)
@@ -266,10 +823,9 @@ func runPRWait(ctx context.Context, cfg *config.Config, pr *config.PRWait, l *lo
 		pr.PRNumber = prNumber
 		pr.ResolvedURL = resolved.HTMLURL
 		pr.ResolvedTitle = resolved.Title
+		pr.ResolvedBranch = resolved.Head.Ref
 		l.Infof("  -> Resolved branch %q to PR #%d (%s)", pr.HeadBranch, prNumber, resolved.HTMLURL)
-		if callbacks != nil {
-			callbacks.OnPRWaitProgress(itemIndex, pr)
-		}
+		callbacks.OnPRWaitProgress(itemIndex, pr)
 	}
 
 	if prNumber == 0 {
@@ -283,21 +839,79 @@ func runPRWait(ctx context.Context, cfg *config.Config, pr *config.PRWait, l *lo
 		}
 		pr.ResolvedURL = status.HTMLURL
 		pr.ResolvedTitle = status.Title
-		if callbacks != nil {
-			callbacks.OnPRWaitProgress(itemIndex, pr)
-		}
+		pr.ResolvedBranch = status.Head.Ref
+		callbacks.OnPRWaitProgress(itemIndex, pr)
 	}
 
-	finalStatus, err := client.WaitForPRStatus(ctx, pr.Owner, pr.Repo, prNumber, pr.WaitFor, pollInterval, pr.ShouldAutoUpdate())
+	finalStatus, err := client.WaitForPRStatusWithBackoff(ctx, pr.Owner, pr.Repo, prNumber, pr.WaitFor, pollInterval, maxInterval, pr.ShouldAutoUpdate())
 	if err != nil {
 		return err
 	}
 	if finalStatus != nil {
 		pr.ResolvedURL = finalStatus.HTMLURL
 		pr.ResolvedTitle = finalStatus.Title
-		if callbacks != nil {
-			callbacks.OnPRWaitProgress(itemIndex, pr)
+		pr.ResolvedBranch = finalStatus.Head.Ref
+		if finalStatus.Merged {
+			pr.ResolvedMergeSHA = finalStatus.MergeCommitSHA
+		}
+		callbacks.OnPRWaitProgress(itemIndex, pr)
+	}
+
+	return nil
+}
+
+// newGitHubClient builds a GitHub client from a github config.
+func newGitHubClient(gh *config.GitHubConfig, l *logger.Logger) (*github.Client, error) {
+	var client *github.Client
+	if app := gh.App; app != nil {
+		auth, err := github.SharedAppAuth(app.AppID, app.InstallationID, app.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("github auth error: %w", err)
+		}
+		client = github.NewAppClient(auth, gh.RequestTimeout(), l)
+	} else {
+		token, err := gh.GetToken()
+		if err != nil {
+			return nil, fmt.Errorf("github auth error: %w", err)
+		}
+		client = github.NewClientWithTimeout(token, gh.RequestTimeout(), l)
+	}
+	client.BaseURL = gh.APIURL
+	return client, nil
+}
+
+// runGitHubStatus posts a commit status and/or PR comment. Fields are substituted
+// from inputs and upstream step outputs at call time.
+func runGitHubStatus(ctx context.Context, cfg *config.Config, gs *config.GitHubStatus, l *logger.Logger, outputs *Outputs) error {
+	if cfg.GitHub == nil {
+		return fmt.Errorf("github configuration is required for github_status steps")
+	}
+
+	client, err := newGitHubClient(cfg.GitHub, l)
+	if err != nil {
+		return err
+	}
+	resolved := resolveGitHubStatus(gs, mergeVars(cfg, outputs))
+	owner, repo := resolved.Owner, resolved.Repo
+
+	if resolved.SHA != "" {
+		status := github.CommitStatus{
+			State:       resolved.State,
+			TargetURL:   resolved.TargetURL,
+			Description: resolved.Description,
+			Context:     resolved.Context,
+		}
+		if err := client.CreateStatus(ctx, owner, repo, resolved.SHA, status); err != nil {
+			return err
+		}
+		l.Infof("  -> Set %s/%s@%s status %q (%s)", owner, repo, resolved.SHA, status.State, status.Context)
+	}
+
+	if resolved.PRNumber > 0 {
+		if err := client.CreateComment(ctx, owner, repo, resolved.PRNumber, resolved.Comment); err != nil {
+			return err
 		}
+		l.Infof("  -> Commented on %s/%s#%d", owner, repo, resolved.PRNumber)
 	}
 
 	return nil
@@ -329,94 +943,82 @@ func describeResolvedPR(pr *config.PRWait) string {
 	return "PR"
 }
 
-// runParallelGroup executes multiple steps in parallel.
+// runParallelGroup executes multiple steps in parallel without callbacks.
 // Parallel siblings cannot reference each other's outputs — pass outputs collected
-// from previous (sequential) steps. Outputs collected here are returned to the caller
-// (see runParallelGroupWithCallbacks for the production path).
+// from previous (sequential) steps. See runParallelGroupWithCallbacks for the
+// production path.
 func runParallelGroup(ctx context.Context, cfg *config.Config, steps []config.Step, l *logger.Logger, outputs *Outputs) ([]StepResult, error) {
-	results := make([]StepResult, len(steps))
-	var resultsMu sync.Mutex
-
-	g, gctx := errgroup.WithContext(ctx)
-
-	for i, step := range steps {
-		i, step := i, step // capture loop variables
-		g.Go(func() error {
-			result, buildNumber, buildURL, err := runStep(gctx, cfg, step, l, nil, 0, i, outputs)
-
-			resultsMu.Lock()
-			results[i] = StepResult{
-				StepName:    step.Name,
-				Result:      result,
-				BuildNumber: buildNumber,
-				BuildURL:    buildURL,
-				Error:       err,
-			}
-			resultsMu.Unlock()
-
-			if err != nil {
-				return fmt.Errorf("step %q: %w", step.Name, err)
-			}
-
-			if result != "SUCCESS" {
-				return fmt.Errorf("step %q failed with result: %s", step.Name, result)
-			}
-
-			return nil
-		})
-	}
-
-	err := g.Wait()
-	return results, err
+	return runParallelGroupWithCallbacks(ctx, cfg, steps, nil, l, NopCallbacks{}, nil, outputs)
 }
 
-// runParallelGroupWithCallbacks executes multiple steps in parallel with callback notifications.
-func runParallelGroupWithCallbacks(ctx context.Context, cfg *config.Config, steps []config.Step, itemIndex int, l *logger.Logger, callbacks WorkflowCallbacks, disabledSet DisabledSet, outputs *Outputs) ([]StepResult, error) {
+// runParallelGroupWithCallbacks executes multiple steps in parallel with callback
+// notifications, skipping those pi marks as skipped. A nil pi runs every step
+// as item 0.
+func runParallelGroupWithCallbacks(ctx context.Context, cfg *config.Config, steps []config.Step, pi *PlanItem, l *logger.Logger, callbacks WorkflowCallbacks, resume ResumeState, outputs *Outputs) ([]StepResult, error) {
+	var itemIndex int
+	if pi != nil {
+		itemIndex = pi.Index
+	}
 	results := make([]StepResult, len(steps))
 	var resultsMu sync.Mutex
+	// firstFailure is the name of the step whose failure cancelled gctx. It is
+	// set before that step's goroutine returns, so any sibling that observes the
+	// cancellation also sees it.
+	var firstFailure string
 
 	g, gctx := errgroup.WithContext(ctx)
 
 	for i, step := range steps {
 		i, step := i, step // capture loop variables
 		g.Go(func() error {
-			if disabledSet.IsDisabled(itemIndex, i) {
-				l.Infof("  -> Skipping step %q (disabled by user).", step.Name)
-				if callbacks != nil {
-					callbacks.OnStepSkipped(itemIndex, i, step.Name)
-				}
+			if reason := pi.skipReason(i); reason != "" {
+				l.Infof("  -> Skipping step %q (%s).", step.Name, strings.TrimPrefix(reason, "skipped: "))
+				callbacks.OnStepSkipped(itemIndex, i, step.Name, reason)
 				resultsMu.Lock()
-				results[i] = StepResult{StepName: step.Name, Result: "SKIPPED"}
+				results[i] = StepResult{StepName: step.Name, Result: "SKIPPED", SkipReason: reason}
 				resultsMu.Unlock()
 				return nil
 			}
 
-			if callbacks != nil {
-				callbacks.OnStepStart(itemIndex, i, step.Name, "")
-			}
+			callbacks.OnStepStart(itemIndex, i, step.Name, "")
+			started := time.Now()
 
-			result, buildNumber, buildURL, err := runStep(gctx, cfg, step, l, callbacks, itemIndex, i, outputs)
+			result, buildNumber, buildURL, err := runStep(gctx, cfg, step, l, callbacks, itemIndex, i, outputs, resume.progress(itemIndex, i))
 
 			resultsMu.Lock()
+			failed := err != nil || !IsSuccess(result)
+			aborted := err != nil && gctx.Err() != nil && ctx.Err() == nil && firstFailure != ""
+			if aborted {
+				result = ResultAborted
+				err = &AbortedError{FailedStep: firstFailure}
+			} else if failed && firstFailure == "" {
+				firstFailure = step.Name
+			}
 			results[i] = StepResult{
 				StepName:    step.Name,
 				Result:      result,
 				BuildNumber: buildNumber,
 				BuildURL:    buildURL,
 				Error:       err,
+				Duration:    time.Since(started),
 			}
 			resultsMu.Unlock()
 
-			if callbacks != nil {
-				callbacks.OnStepComplete(itemIndex, i, step.Name, result, buildNumber, err)
-			}
+			callbacks.OnStepComplete(itemIndex, i, step.Name, result, buildNumber, err)
 
+			if aborted {
+				// The group error is the original failure, already returned by the sibling.
+				return nil
+			}
+			if IsBuildAborted(err) {
+				return err
+			}
 			if err != nil {
-				return fmt.Errorf("step %q: %w", step.Name, err)
+				return &StepError{ItemIndex: itemIndex, StepIndex: i, Step: step.Name, Err: err}
 			}
 
-			if result != "SUCCESS" {
-				return fmt.Errorf("step %q failed with result: %s", step.Name, result)
+			if !IsSuccess(result) {
+				return &StepFailedError{ItemIndex: itemIndex, StepIndex: i, Step: step.Name, Result: result}
 			}
 
 			return nil
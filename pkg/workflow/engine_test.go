@@ -3,11 +3,16 @@ package workflow
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/treaz/jenkins-flow/pkg/config"
 	"github.com/treaz/jenkins-flow/pkg/logger"
@@ -46,12 +51,86 @@ func mockJenkinsServer(triggered *int32) *httptest.Server {
 	return server
 }
 
+func TestRunWithCallbacks_BuildAbortedInJenkins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/deploy/3/api/json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("tree") != "" {
+			w.Write([]byte(`{"actions": [{"causes": [{"userId": "bob", "userName": "Bob"}]}]}`))
+			return
+		}
+		w.Write([]byte(`{"building": false, "result": "ABORTED", "number": 3}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+		Workflow:        []config.WorkflowItem{{Name: "Deploy", Instance: "test", Job: "/job/deploy"}},
+	}
+	rec := &RecordingCallbacks{}
+	resume := ResumeState{0: {0: {BuildURL: server.URL + "/job/deploy/3/"}}}
+
+	err := ResumeWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{}, resume)
+	if err == nil || err.Error() != `step "Deploy" was aborted in Jenkins by Bob` {
+		t.Fatalf("expected abort error naming the user, got %v", err)
+	}
+	if !IsBuildAborted(err) || IsAborted(err) {
+		t.Errorf("expected a BuildAbortedError, got %T", err)
+	}
+
+	var complete *CallbackEvent
+	for _, e := range rec.Events() {
+		if e.Kind == "StepComplete" {
+			complete = &e
+		}
+	}
+	if complete == nil || complete.Result != ResultAborted || !IsBuildAborted(complete.Err) {
+		t.Errorf("expected StepComplete with ABORTED result and abort error, got %+v", complete)
+	}
+}
+
+func TestRunWithCallbacks_AggregateSubbuilds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/job/matrix/7/api/json":
+			http.NotFound(w, r)
+		case strings.Contains(r.URL.Query().Get("tree"), "runs["):
+			w.Write([]byte(`{"number": 7, "runs": [{"number": 7, "url": "http://jenkins/job/matrix/label=linux/7/", "result": "SUCCESS"}, {"number": 7, "url": "http://jenkins/job/matrix/label=windows/7/", "result": "FAILURE"}]}`))
+		default:
+			w.Write([]byte(`{"building": false, "result": "SUCCESS", "number": 7}`))
+		}
+	}))
+	defer server.Close()
+
+	for _, aggregate := range []bool{false, true} {
+		cfg := &config.Config{
+			JenkinsPollSecs: 1,
+			Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+			Workflow:        []config.WorkflowItem{{Name: "Matrix", Instance: "test", Job: "/job/matrix", AggregateSubbuilds: aggregate}},
+		}
+		resume := ResumeState{0: {0: {BuildURL: server.URL + "/job/matrix/7/"}}}
+		err := ResumeWithCallbacks(context.Background(), cfg, logger.New(logger.Error), &RecordingCallbacks{}, DisabledSet{}, resume)
+
+		var failed *StepFailedError
+		switch {
+		case !aggregate && err != nil:
+			t.Errorf("expected the parent's SUCCESS without aggregate_subbuilds, got %v", err)
+		case aggregate && (!errors.As(err, &failed) || failed.Result != "FAILURE"):
+			t.Errorf("expected the failed configuration to fail the step, got %v", err)
+		}
+	}
+}
+
 func TestRunStep_Success(t *testing.T) {
 	var triggered int32
 	server := mockJenkinsServer(&triggered)
 	defer server.Close()
 
 	cfg := &config.Config{
+		JenkinsPollSecs: 1,
 		Instances: map[string]config.Instance{
 			"test": {URL: server.URL, Token: "user:token"},
 		},
@@ -64,7 +143,7 @@ func TestRunStep_Success(t *testing.T) {
 	}
 
 	l := logger.New(logger.Error)
-	result, buildNumber, _, err := runStep(context.Background(), cfg, step, l, nil, 0, 0, NewOutputs())
+	result, buildNumber, _, err := runStep(context.Background(), cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{})
 	if err != nil {
 		t.Fatalf("runStep failed: %v", err)
 	}
@@ -81,12 +160,146 @@ func TestRunStep_Success(t *testing.T) {
 	}
 }
 
+func TestRunStep_WaitModes(t *testing.T) {
+	var queuePolls, buildPolls int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/service/build":
+			w.Header().Set("Location", server.URL+"/queue/item/7/")
+			w.WriteHeader(http.StatusCreated)
+		case "/queue/item/7/api/json":
+			atomic.AddInt32(&queuePolls, 1)
+			w.Write([]byte(`{"executable": {"url": "` + server.URL + `/job/service/4/"}}`))
+		case "/job/service/4/api/json":
+			// A long-running service: the build never finishes.
+			atomic.AddInt32(&buildPolls, 1)
+			w.Write([]byte(`{"building": true, "number": 4}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+	}
+	l := logger.New(logger.Error)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	step := config.Step{Name: "Service", Instance: "test", Job: "/job/service", Wait: config.WaitQueued}
+	result, _, buildURL, err := runStep(ctx, cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{})
+	if err != nil || result != ResultQueued || buildURL != "" {
+		t.Fatalf("wait: queued: got result %q, build URL %q, err %v", result, buildURL, err)
+	}
+	if n := atomic.LoadInt32(&queuePolls); n != 0 {
+		t.Errorf("wait: queued should not poll the queue, got %d polls", n)
+	}
+
+	step.Wait = config.WaitStarted
+	rec := &RecordingCallbacks{}
+	result, _, buildURL, err = runStep(ctx, cfg, step, l, rec, 0, 0, NewOutputs(), StepProgress{})
+	if err != nil || result != ResultStarted || buildURL != server.URL+"/job/service/4/" {
+		t.Fatalf("wait: started: got result %q, build URL %q, err %v", result, buildURL, err)
+	}
+	if n := atomic.LoadInt32(&buildPolls); n != 0 {
+		t.Errorf("wait: started should not poll the build, got %d polls", n)
+	}
+	started := false
+	for _, e := range rec.Events() {
+		if e.Kind == "StepBuildStarted" {
+			started = true
+		}
+	}
+	if !started {
+		t.Error("expected OnStepBuildStarted for wait: started")
+	}
+
+	// Resuming a started step does not reattach to its still-running build.
+	result, _, _, err = runStep(ctx, cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{BuildURL: buildURL})
+	if err != nil || result != ResultStarted || atomic.LoadInt32(&buildPolls) != 0 {
+		t.Errorf("resumed wait: started: got result %q, err %v, %d build polls", result, err, buildPolls)
+	}
+	if !IsSuccess(result) {
+		t.Errorf("expected %q to count as success", result)
+	}
+}
+
+func TestRunStep_IfRunning(t *testing.T) {
+	var lastBuildPolls, triggered int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/deploy/lastBuild/api/json":
+			// The previous deploy is still running on the first check only.
+			building := atomic.AddInt32(&lastBuildPolls, 1) == 1
+			fmt.Fprintf(w, `{"number": 9, "url": "%s/job/deploy/9/", "building": %t}`, server.URL, building)
+		case "/job/deploy/build":
+			atomic.AddInt32(&triggered, 1)
+			w.Header().Set("Location", server.URL+"/queue/item/1/")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances:       map[string]config.Instance{"test": {URL: server.URL, Token: "user:token"}},
+	}
+	l := logger.New(logger.Error)
+	step := config.Step{Name: "Deploy", Instance: "test", Job: "/job/deploy", Wait: config.WaitQueued, IfRunning: config.IfRunningFail}
+
+	_, _, _, err := runStep(context.Background(), cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{})
+	if err == nil || !strings.Contains(err.Error(), "already has a running build #9") {
+		t.Fatalf("if_running: fail: expected a running build error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 0 {
+		t.Errorf("if_running: fail should not trigger, got %d triggers", n)
+	}
+
+	atomic.StoreInt32(&lastBuildPolls, 0)
+	step.IfRunning = config.IfRunningWait
+	result, _, _, err := runStep(context.Background(), cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{})
+	if err != nil || result != ResultQueued {
+		t.Fatalf("if_running: wait: got result %q, err %v", result, err)
+	}
+	if polls, n := atomic.LoadInt32(&lastBuildPolls), atomic.LoadInt32(&triggered); polls != 2 || n != 1 {
+		t.Errorf("if_running: wait: expected to poll until idle then trigger once, got %d polls and %d triggers", polls, n)
+	}
+
+	// The wait gives up after the instance's build timeout, before the next poll
+	// would find the job idle.
+	timeoutCfg := &config.Config{
+		Instances: map[string]config.Instance{"test": {URL: server.URL, Token: "user:token", BuildTimeoutSecs: 1}},
+	}
+	atomic.StoreInt32(&lastBuildPolls, 0)
+	_, _, _, err = runStep(context.Background(), timeoutCfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Limit != time.Second {
+		t.Fatalf("if_running: wait: expected a *TimeoutError after the build timeout, got %v", err)
+	}
+
+	atomic.StoreInt32(&lastBuildPolls, 0)
+	step.IfRunning = ""
+	if _, _, _, err := runStep(context.Background(), cfg, step, l, NopCallbacks{}, 0, 0, NewOutputs(), StepProgress{}); err != nil {
+		t.Fatalf("default if_running: %v", err)
+	}
+	if polls := atomic.LoadInt32(&lastBuildPolls); polls != 0 {
+		t.Errorf("default if_running should not check the last build, got %d polls", polls)
+	}
+}
+
 func TestRunParallelGroup_Success(t *testing.T) {
 	var triggered int32
 	server := mockJenkinsServer(&triggered)
 	defer server.Close()
 
 	cfg := &config.Config{
+		JenkinsPollSecs: 1,
 		Instances: map[string]config.Instance{
 			"test": {URL: server.URL, Token: "user:token"},
 		},
@@ -156,6 +369,7 @@ func TestRunParallelGroup_FailFast(t *testing.T) {
 	defer server.Close()
 
 	cfg := &config.Config{
+		JenkinsPollSecs: 1,
 		Instances: map[string]config.Instance{
 			"test": {URL: server.URL, Token: "user:token"},
 		},
@@ -171,6 +385,56 @@ func TestRunParallelGroup_FailFast(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error from runParallelGroup, got nil")
 	}
+	var failed *StepFailedError
+	if !errors.As(err, &failed) || failed.Result != "FAILURE" || failed.Step != steps[failed.StepIndex].Name {
+		t.Errorf("expected a *StepFailedError naming the failed step, got %#v", err)
+	}
+}
+
+func TestRunWithCallbacks_TypedErrors(t *testing.T) {
+	failing := mockFailingJenkinsServer()
+	defer failing.Close()
+	cancelled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/test/build":
+			w.Header().Set("Location", "http://"+r.Host+"/queue/item/9/")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cancelled.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"failing":   {URL: failing.URL, Token: "user:token"},
+			"cancelled": {URL: cancelled.URL, Token: "user:token"},
+		},
+	}
+	run := func(instance string) error {
+		cfg.Workflow = []config.WorkflowItem{
+			{Command: &config.Command{Name: "Prepare", Run: "true"}},
+			{Name: "Build", Instance: instance, Job: "/job/test"},
+		}
+		return RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), NopCallbacks{}, nil)
+	}
+
+	err := run("failing")
+	var failed *StepFailedError
+	if !errors.As(err, &failed) || failed.ItemIndex != 1 || failed.Step != "Build" {
+		t.Errorf("expected a *StepFailedError for item 1, got %#v", err)
+	}
+
+	err = run("cancelled")
+	var stepErr *StepError
+	var queueErr *QueueError
+	if !errors.As(err, &stepErr) || stepErr.ItemIndex != 1 || !errors.As(err, &queueErr) {
+		t.Fatalf("expected a *StepError for item 1 caused by a *QueueError, got %#v", err)
+	}
+	if !strings.Contains(err.Error(), `step "Build" failed: failed waiting for queue: queue item not found`) {
+		t.Errorf("unexpected message: %v", err)
+	}
 }
 
 // mockBuildAndDeployServer simulates a build job that returns build number 7777,
@@ -230,6 +494,7 @@ func TestRunWithCallbacks_StepOutputSubstitution(t *testing.T) {
 	defer server.Close()
 
 	cfg := &config.Config{
+		JenkinsPollSecs: 1,
 		Instances: map[string]config.Instance{
 			"test": {URL: server.URL, Token: "user:token"},
 		},
@@ -272,12 +537,106 @@ func TestRunWithCallbacks_StepOutputSubstitution(t *testing.T) {
 	}
 }
 
+// queuedParams records the params each step was triggered with.
+type queuedParams struct {
+	NopCallbacks
+	params map[string]string
+}
+
+func (q *queuedParams) OnStepQueued(_, _ int, _, _ string, params map[string]string) {
+	q.params = params
+}
+
+func TestRunStep_PRSubstitution(t *testing.T) {
+	var deployParams sync.Map
+	server := mockBuildAndDeployServer(t, &deployParams)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+	}
+	outputs := NewOutputs()
+	outputs.setPR(&config.PRWait{Name: "Release", PRNumber: 42, ResolvedBranch: "release/1.2", ResolvedMergeSHA: "abc123"})
+
+	step := config.Step{
+		Name:     "Deploy",
+		Instance: "test",
+		Job:      "/job/deploy",
+		Params:   map[string]string{"PR": "${pr.release.number}", "SHA": "${pr.release.merge_sha}"},
+	}
+	l := logger.New(logger.Error)
+	callbacks := &queuedParams{}
+	if _, _, _, err := runStep(context.Background(), cfg, step, l, callbacks, 0, 0, outputs, StepProgress{}); err != nil {
+		t.Fatalf("runStep failed: %v", err)
+	}
+	if got, _ := deployParams.Load("PR"); got != "42" {
+		t.Errorf("expected PR=42, got %v", got)
+	}
+	if got, _ := deployParams.Load("SHA"); got != "abc123" {
+		t.Errorf("expected SHA=abc123, got %v", got)
+	}
+	if callbacks.params["PR"] != "42" || callbacks.params["SHA"] != "abc123" {
+		t.Errorf("expected resolved params in OnStepQueued, got %v", callbacks.params)
+	}
+
+	// A reference to a PR no wait item resolved fails before triggering.
+	step.Params = map[string]string{"PR": "${pr.hotfix.number}"}
+	deployParams = sync.Map{}
+	_, _, _, err := runStep(context.Background(), cfg, step, l, NopCallbacks{}, 0, 0, outputs, StepProgress{})
+	if err == nil || !strings.Contains(err.Error(), "${pr.hotfix.number}") {
+		t.Fatalf("expected error naming pr.hotfix.number, got %v", err)
+	}
+	if _, ok := deployParams.Load("PR"); ok {
+		t.Error("step with an unresolved reference should not be triggered")
+	}
+}
+
+func TestRunStep_SecretParams(t *testing.T) {
+	var deployParams sync.Map
+	server := mockBuildAndDeployServer(t, &deployParams)
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+	}
+	step := config.Step{
+		Name:         "Deploy",
+		Instance:     "test",
+		Job:          "/job/deploy",
+		Params:       map[string]string{"DEPLOY_KEY": "s3cr3t-deploy-key", "REGION": "eu"},
+		SecretParams: []string{"DEPLOY_KEY"},
+	}
+	var out strings.Builder
+	l := logger.New(logger.Debug)
+	l.SetOutput(&out)
+	callbacks := &queuedParams{}
+	if _, _, _, err := runStep(context.Background(), cfg, step, l, callbacks, 0, 0, NewOutputs(), StepProgress{}); err != nil {
+		t.Fatalf("runStep failed: %v", err)
+	}
+	if got, _ := deployParams.Load("DEPLOY_KEY"); got != "s3cr3t-deploy-key" {
+		t.Errorf("expected Jenkins to get the real value, got %v", got)
+	}
+	if callbacks.params["DEPLOY_KEY"] != config.SecretMask || callbacks.params["REGION"] != "eu" {
+		t.Errorf("expected OnStepQueued to get masked params, got %v", callbacks.params)
+	}
+	if logged := out.String(); strings.Contains(logged, "s3cr3t-deploy-key") || !strings.Contains(logged, "DEPLOY_KEY=***") {
+		t.Errorf("expected the secret to be masked in the debug log, got:\n%s", logged)
+	}
+}
+
 func TestRunWithCallbacks_MixedWorkflow(t *testing.T) {
 	var triggered int32
 	server := mockJenkinsServer(&triggered)
 	defer server.Close()
 
 	cfg := &config.Config{
+		JenkinsPollSecs: 1,
 		Instances: map[string]config.Instance{
 			"test": {URL: server.URL, Token: "user:token"},
 		},
@@ -318,3 +677,251 @@ func TestRunWithCallbacks_MixedWorkflow(t *testing.T) {
 		t.Errorf("expected 4 triggers, got %d", triggered)
 	}
 }
+
+func TestRunWithCallbacks_ItemNotify(t *testing.T) {
+	var triggered int32
+	server := mockJenkinsServer(&triggered)
+	defer server.Close()
+
+	var messages []map[string]interface{}
+	var mu sync.Mutex
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+	}))
+	defer slack.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Name:            "Release",
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{
+				Name:     "Deploy",
+				Instance: "test",
+				Job:      "/job/test",
+				Notify: &config.ItemNotify{
+					Events:  []string{config.NotifyOnStart, config.NotifyOnSuccess},
+					Channel: "#deployments",
+					Webhook: slack.URL,
+				},
+			},
+		},
+	}
+
+	l := logger.New(logger.Error)
+	if err := RunWithCallbacks(context.Background(), cfg, l, nil, DisabledSet{}); err != nil {
+		t.Fatalf("RunWithCallbacks failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 Slack messages (start + success), got %d", len(messages))
+	}
+	if messages[0]["channel"] != "#deployments" {
+		t.Errorf("expected channel override, got %v", messages[0]["channel"])
+	}
+	att := messages[1]["attachments"].([]interface{})[0].(map[string]interface{})
+	if att["title"] != "Release: Deploy" {
+		t.Errorf("unexpected title %v", att["title"])
+	}
+	text, _ := att["text"].(string)
+	if !strings.Contains(text, "Succeeded") || !strings.Contains(text, server.URL+"/job/test/1/") {
+		t.Errorf("expected result and build URL in message, got %q", text)
+	}
+}
+
+// mockFailFastSlowServer fails /job/fail immediately while /job/slow keeps building
+// until the request context is cancelled.
+func mockFailFastSlowServer() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/fail/build", "/job/slow/build":
+			w.Header().Set("Location", server.URL+strings.TrimSuffix(r.URL.Path, "/build")+"/queue/")
+			w.WriteHeader(http.StatusCreated)
+		case "/job/fail/queue/api/json":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"executable": map[string]string{"url": server.URL + "/job/fail/1/"},
+			})
+		case "/job/slow/queue/api/json":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"executable": map[string]string{"url": server.URL + "/job/slow/1/"},
+			})
+		case "/job/fail/1/api/json":
+			json.NewEncoder(w).Encode(map[string]interface{}{"building": false, "result": "FAILURE", "number": 1})
+		case "/job/slow/1/api/json":
+			json.NewEncoder(w).Encode(map[string]interface{}{"building": true, "number": 1})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestRunParallelGroup_SiblingAborted(t *testing.T) {
+	server := mockFailFastSlowServer()
+	defer server.Close()
+
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{
+				Parallel: &config.ParallelGroup{
+					Name: "Deploy",
+					Steps: []config.Step{
+						{Name: "Slow", Instance: "test", Job: "/job/slow"},
+						{Name: "Broken", Instance: "test", Job: "/job/fail"},
+					},
+				},
+			},
+		},
+	}
+
+	rec := &RecordingCallbacks{}
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), rec, DisabledSet{})
+	if err == nil {
+		t.Fatal("expected workflow error, got nil")
+	}
+	if !strings.Contains(err.Error(), `"Broken"`) || strings.Contains(err.Error(), "cancel") {
+		t.Errorf("workflow error should name only the original failure, got %v", err)
+	}
+
+	var slow, broken *CallbackEvent
+	for _, e := range rec.Events() {
+		e := e
+		if e.Kind != "StepComplete" {
+			continue
+		}
+		switch e.Name {
+		case "Slow":
+			slow = &e
+		case "Broken":
+			broken = &e
+		}
+	}
+	if broken == nil || broken.Result != "FAILURE" || IsAborted(broken.Err) {
+		t.Errorf("expected Broken to complete with FAILURE, got %+v", broken)
+	}
+	if slow == nil || slow.Result != ResultAborted || !IsAborted(slow.Err) {
+		t.Fatalf("expected Slow to be aborted, got %+v", slow)
+	}
+	if got, want := slow.Err.Error(), `cancelled because "Broken" failed`; got != want {
+		t.Errorf("aborted error = %q, want %q", got, want)
+	}
+}
+
+func TestRunWithCallbacks_Retries(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "attempted")
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Retries:         2,
+		RetryDelaySecs:  1,
+		Workflow: []config.WorkflowItem{
+			// Fails on the first attempt only.
+			{Command: &config.Command{Name: "Flaky", Run: "if [ -f " + marker + " ]; then echo ok; else touch " + marker + "; exit 1; fi"}},
+		},
+		Finally: []config.WorkflowItem{
+			{Command: &config.Command{Name: "Cleanup", Run: "true"}},
+		},
+	}
+
+	callbacks := &RecordingCallbacks{}
+	if err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), callbacks, nil); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	want := []string{
+		"StepComplete(0,0,Flaky,FAILURE)", "StepComplete(1,0,Cleanup,SUCCESS)",
+		"RunRetry(2)",
+		"StepComplete(0,0,Flaky,SUCCESS)", "StepComplete(1,0,Cleanup,SUCCESS)",
+	}
+	got := callbacks.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepComplete" || e.Kind == "RunRetry" })
+	assertSequence(t, got, want)
+}
+
+func TestRunWithCallbacks_RetriesExhausted(t *testing.T) {
+	cfg := &config.Config{
+		JenkinsPollSecs: 1,
+		Retries:         1,
+		RetryDelaySecs:  1,
+		Workflow: []config.WorkflowItem{
+			{Command: &config.Command{Name: "Broken", Run: "exit 1"}},
+		},
+	}
+
+	callbacks := &RecordingCallbacks{}
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), callbacks, nil)
+	if err == nil || !strings.Contains(err.Error(), `command "Broken" failed`) {
+		t.Fatalf("expected the last attempt's error, got %v", err)
+	}
+	want := []string{"StepComplete(0,0,Broken,FAILURE)", "RunRetry(2)", "StepComplete(0,0,Broken,FAILURE)"}
+	got := callbacks.Sequence(func(e CallbackEvent) bool { return e.Kind == "StepComplete" || e.Kind == "RunRetry" })
+	assertSequence(t, got, want)
+
+	// A stopped run is not retried.
+	ctx, stop := WithGracefulStop(context.Background())
+	stop()
+	callbacks = &RecordingCallbacks{}
+	if err := RunWithCallbacks(ctx, cfg, logger.New(logger.Error), callbacks, nil); !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+	for _, e := range callbacks.Events() {
+		if e.Kind == "RunRetry" {
+			t.Fatal("expected a stopped run not to be retried")
+		}
+	}
+}
+
+func TestRunPRWait_GitHubOverride(t *testing.T) {
+	var auth sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.URL.Path, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/repos/platform/service/pulls/7":
+			w.Write([]byte(`{"number": 7, "state": "closed", "merged": true, "title": "Release", "merge_commit_sha": "abc123"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GitHub: &config.GitHubConfig{Token: "global-token"}}
+	pr := &config.PRWait{
+		Name: "Platform Release", Owner: "platform", Repo: "service", PRNumber: 7, WaitFor: "merged",
+		GitHub: &config.GitHubConfig{Token: "platform-token", APIURL: server.URL + "/api/v3"},
+	}
+	if err := runPRWait(context.Background(), cfg, pr, logger.New(logger.Error), NopCallbacks{}, 0); err != nil {
+		t.Fatalf("runPRWait failed: %v", err)
+	}
+	if got, _ := auth.Load("/api/v3/repos/platform/service/pulls/7"); got != "Bearer platform-token" {
+		t.Errorf("expected the override's token, got %q", got)
+	}
+	if pr.ResolvedMergeSHA != "abc123" {
+		t.Errorf("expected the merge SHA from the override's API, got %q", pr.ResolvedMergeSHA)
+	}
+
+	// Without any credential, a repository GitHub hides is reported as
+	// possibly private before waiting.
+	anonymous := &config.Config{}
+	pr = &config.PRWait{
+		Name: "Private Release", Owner: "platform", Repo: "private", PRNumber: 1, WaitFor: "merged",
+		GitHub: &config.GitHubConfig{APIURL: server.URL + "/api/v3"},
+	}
+	err := runPRWait(context.Background(), anonymous, pr, logger.New(logger.Error), NopCallbacks{}, 0)
+	if err == nil || !strings.Contains(err.Error(), "platform/private: repository not found or not visible") || !strings.Contains(err.Error(), "if it is private") {
+		t.Errorf("expected a hint about private repositories, got %v", err)
+	}
+	if _, asked := auth.Load("/api/v3/repos/platform/private/pulls/1"); asked {
+		t.Error("expected the PR not to be polled after the repository check failed")
+	}
+}
@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The engine reports failures with the error types below so callers can use
+// errors.As instead of matching messages. A failed item is a *StepError or a
+// *PRWaitError naming the item; its cause may in turn be a *QueueError or a
+// *TimeoutError. Builds that finish with a bad result are *StepFailedError,
+// and builds aborted in Jenkins are *BuildAbortedError.
+
+// StepError is returned for a Jenkins step, command or GitHub status that
+// failed with an error rather than a build result.
+type StepError struct {
+	ItemIndex int    // Position in cfg.AllItems()
+	StepIndex int    // Position inside a parallel group (0 for single items)
+	Step      string // Name of the step
+	Kind      string // What the step is in messages: "step" when empty, "command" or "GitHub status"
+	Err       error
+}
+
+func (e *StepError) Error() string {
+	kind := e.Kind
+	if kind == "" {
+		kind = "step"
+	}
+	return fmt.Sprintf("%s %q failed: %v", kind, e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// PRWaitError is returned for a wait_for_pr item that failed.
+type PRWaitError struct {
+	ItemIndex int
+	Name      string
+	Err       error
+}
+
+func (e *PRWaitError) Error() string {
+	return fmt.Sprintf("PR wait %q failed: %v", e.Name, e.Err)
+}
+
+func (e *PRWaitError) Unwrap() error { return e.Err }
+
+// QueueError is the cause of a step whose queue item never became a build,
+// e.g. because it was cancelled in Jenkins.
+type QueueError struct {
+	QueueURL string
+	Err      error
+}
+
+func (e *QueueError) Error() string {
+	return fmt.Sprintf("failed waiting for queue: %v", e.Err)
+}
+
+func (e *QueueError) Unwrap() error { return e.Err }
+
+// TimeoutError is the cause of a step stopped by a time limit: an instance's
+// build_timeout_secs or a command's timeout_secs. Err carries the message.
+type TimeoutError struct {
+	Limit time.Duration
+	Err   error
+}
+
+func (e *TimeoutError) Error() string { return e.Err.Error() }
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// AbortedError is reported to OnStepComplete for a parallel step that was
+// cancelled because FailedStep, a sibling in the same group, failed.
+type AbortedError struct {
+	FailedStep string
+}
+
+func (e *AbortedError) Error() string {
+	return fmt.Sprintf("cancelled because %q failed", e.FailedStep)
+}
+
+// IsAborted reports whether err marks a step cancelled by a failing sibling.
+func IsAborted(err error) bool {
+	var aborted *AbortedError
+	return errors.As(err, &aborted)
+}
+
+// BuildAbortedError is reported for a step whose Jenkins build was aborted
+// there, e.g. from the Jenkins UI. By is who aborted it, if Jenkins recorded it.
+type BuildAbortedError struct {
+	Step string
+	By   string
+}
+
+func (e *BuildAbortedError) Error() string {
+	if e.By == "" {
+		return fmt.Sprintf("step %q was aborted in Jenkins", e.Step)
+	}
+	return fmt.Sprintf("step %q was aborted in Jenkins by %s", e.Step, e.By)
+}
+
+// IsBuildAborted reports whether err marks a step whose build was aborted in Jenkins.
+func IsBuildAborted(err error) bool {
+	var aborted *BuildAbortedError
+	return errors.As(err, &aborted)
+}
+
+// StepFailedError is returned for a step whose build finished with a result
+// that is not a success, such as FAILURE or UNSTABLE.
+type StepFailedError struct {
+	ItemIndex int
+	StepIndex int
+	Step      string
+	Result    string
+}
+
+func (e *StepFailedError) Error() string {
+	return fmt.Sprintf("step %q failed with result: %s", e.Step, e.Result)
+}
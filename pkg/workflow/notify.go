@@ -0,0 +1,110 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/notifier"
+)
+
+// itemNotifier sends the Slack notifications configured on a workflow item's
+// `notify:` block. A nil *itemNotifier is valid and sends nothing. Delivery
+// errors are swallowed by the notifier package so they never affect the run.
+type itemNotifier struct {
+	notify *config.ItemNotify
+	sender *notifier.Notifier
+	title  string
+	start  time.Time
+}
+
+// newItemNotifier returns a notifier for item, or nil if the item has no notify block.
+func newItemNotifier(cfg *config.Config, item *config.WorkflowItem, name string) *itemNotifier {
+	if item.Notify == nil {
+		return nil
+	}
+	webhook := item.Notify.Webhook
+	if webhook == "" {
+		webhook = cfg.SlackWebhook
+	}
+	channel := item.Notify.Channel
+	if channel == "" {
+		channel = cfg.SlackChannel
+	}
+	if webhook == "" {
+		return nil
+	}
+	title := cfg.Expand(name)
+	if cfg.Name != "" {
+		title = fmt.Sprintf("%s: %s", cfg.Expand(cfg.Name), title)
+	}
+	slack := &notifier.SlackConfig{
+		WebhookURL: webhook,
+		Channel:    notifier.NormalizeChannel(channel),
+		Username:   cfg.SlackUsername,
+	}
+	if cfg.SlackTLS != nil {
+		slack.CACertFile = cfg.SlackTLS.CACert
+		slack.InsecureSkipVerify = cfg.SlackTLS.InsecureSkipVerify
+	}
+	return &itemNotifier{
+		notify: item.Notify,
+		sender: notifier.New(notifier.Config{Slack: slack}),
+		title:  title,
+		start:  time.Now(),
+	}
+}
+
+// started fires the on_start event.
+func (n *itemNotifier) started() {
+	if n == nil || !n.notify.Has(config.NotifyOnStart) {
+		return
+	}
+	n.sender.NotifySlack(true, n.title, "Started")
+}
+
+// finished fires on_success or on_failure depending on err and the step results.
+func (n *itemNotifier) finished(results []StepResult, err error) {
+	if n == nil {
+		return
+	}
+	success := err == nil
+	for _, r := range results {
+		if r.Error != nil || (!IsSuccess(r.Result) && r.Result != "SKIPPED") {
+			success = false
+		}
+	}
+
+	event := config.NotifyOnSuccess
+	if !success {
+		event = config.NotifyOnFailure
+	}
+	if !n.notify.Has(event) {
+		return
+	}
+	n.sender.NotifySlack(success, n.title, formatItemMessage(success, time.Since(n.start), results, err))
+}
+
+// formatItemMessage renders the result, duration, and per-step build URLs.
+func formatItemMessage(success bool, duration time.Duration, results []StepResult, err error) string {
+	var b strings.Builder
+	if success {
+		fmt.Fprintf(&b, "Succeeded in %s", duration.Round(time.Second))
+	} else if err != nil {
+		fmt.Fprintf(&b, "Failed after %s: %v", duration.Round(time.Second), err)
+	} else {
+		fmt.Fprintf(&b, "Failed after %s", duration.Round(time.Second))
+	}
+	for _, r := range results {
+		result := r.Result
+		if r.Error != nil && result == "" {
+			result = "ERROR"
+		}
+		fmt.Fprintf(&b, "\n• %s: %s", r.StepName, result)
+		if r.BuildURL != "" {
+			fmt.Fprintf(&b, " %s", r.BuildURL)
+		}
+	}
+	return b.String()
+}
@@ -2,19 +2,27 @@ package workflow
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
 )
 
+// prRefPrefix namespaces the PR fields published by wait_for_pr items.
+const prRefPrefix = "pr."
+
 // Outputs is a thread-safe store of per-step outputs (build_number, build_url, ...)
-// surfaced to substitution as ${steps.<id>.<field>}.
+// surfaced to substitution as ${steps.<id>.<field>}, and of the PRs resolved by
+// wait_for_pr items, surfaced as ${pr.<id>.<field>}.
 type Outputs struct {
-	mu sync.RWMutex
-	m  map[string]map[string]string
+	mu  sync.RWMutex
+	m   map[string]map[string]string
+	prs map[string]map[string]string
 }
 
 // NewOutputs creates an empty Outputs store.
 func NewOutputs() *Outputs {
-	return &Outputs{m: map[string]map[string]string{}}
+	return &Outputs{m: map[string]map[string]string{}, prs: map[string]map[string]string{}}
 }
 
 // Set records a single field for a step ID.
@@ -41,16 +49,49 @@ func (o *Outputs) Get(stepID, field string) (string, bool) {
 	return "", false
 }
 
-// Flat returns a snapshot keyed as "steps.<id>.<field>" -> value, suitable
-// for merging with cfg.Inputs and passing to config.Substitute.
+// Flat returns a snapshot keyed as "steps.<id>.<field>" and "pr.<id>.<field>"
+// -> value, suitable for merging with cfg.Inputs and passing to config.Substitute.
 func (o *Outputs) Flat() map[string]string {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
-	out := make(map[string]string, len(o.m)*2)
+	out := make(map[string]string, len(o.m)*2+len(o.prs)*4)
 	for id, fields := range o.m {
 		for field, value := range fields {
 			out[fmt.Sprintf("steps.%s.%s", id, field)] = value
 		}
 	}
+	for id, fields := range o.prs {
+		for field, value := range fields {
+			out[fmt.Sprintf("%s%s.%s", prRefPrefix, id, field)] = value
+		}
+	}
 	return out
 }
+
+// setPR publishes the resolved PR of a finished wait_for_pr item for
+// ${pr.<id>.<field>} substitution, where id is the slugified item name.
+// Empty fields are not recorded, so references to them stay unresolved.
+func (o *Outputs) setPR(pr *config.PRWait) {
+	id := config.Slugify(pr.Name)
+	if id == "" {
+		return
+	}
+	fields := map[string]string{}
+	if pr.PRNumber > 0 {
+		fields["number"] = strconv.Itoa(pr.PRNumber)
+	}
+	for field, value := range map[string]string{
+		"branch":    pr.ResolvedBranch,
+		"title":     pr.ResolvedTitle,
+		"url":       pr.ResolvedURL,
+		"merge_sha": pr.ResolvedMergeSHA,
+	} {
+		if value != "" {
+			fields[field] = value
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prs[id] = fields
+}
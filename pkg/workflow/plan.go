@@ -0,0 +1,361 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+// Plan item kinds.
+const (
+	PlanKindStep         = "step"
+	PlanKindParallel     = "parallel"
+	PlanKindPRWait       = "wait_for_pr"
+	PlanKindGitHubStatus = "github_status"
+	PlanKindCommand      = "command"
+)
+
+// PlanStep is a step as it will be triggered, with inputs substituted.
+// References to upstream step outputs (${steps.<id>.<field>}) are left in
+// place because they are only known once the upstream step has run.
+type PlanStep struct {
+	Name        string
+	Instance    string
+	InstanceURL string
+	Job         string
+	Params      map[string]string // Values of the step's secret_params are config.SecretMask
+	Skipped     bool
+	SkipReason  string // Why Skipped is set: SkipReasonDisabledInFile or SkipReasonDisabled
+}
+
+// PlanItem is one top-level item of a Plan.
+type PlanItem struct {
+	Index      int // Position in cfg.AllItems(); matches callback itemIndex
+	Name       string
+	Kind       string
+	Finally    bool
+	Skipped    bool
+	SkipReason string         // Why a PR wait is skipped; steps carry their own
+	Steps      []PlanStep     // One for single steps, GitHub statuses and commands; the group's steps for parallel items
+	PRWait     *config.PRWait // Set for PR waits
+}
+
+// Plan is the resolved, ordered list of what a run will execute.
+type Plan struct {
+	Items []PlanItem
+}
+
+// BuildPlan resolves cfg into the items a run would execute, substituting
+// inputs into params the same way the engine does at trigger time. It performs
+// no network calls. RunWithCallbacks builds the same plan and executes its
+// items, in order, skipping those the plan marks as skipped.
+func BuildPlan(cfg *config.Config, inputs map[string]string) (*Plan, error) {
+	all := cfg.AllItems()
+	plan := &Plan{Items: make([]PlanItem, 0, len(all))}
+	base := cfg.TemplateVars(inputs)
+
+	for i, item := range all {
+		vars := planVars(base, item)
+		pi := PlanItem{
+			Index:   i,
+			Name:    item.ItemName(),
+			Finally: cfg.IsFinallyIndex(i),
+		}
+		switch {
+		case item.IsPRWait():
+			pr := *item.WaitForPR
+			pi.Kind = PlanKindPRWait
+			pi.PRWait = &pr
+		case item.IsGitHubStatus():
+			gs := resolveGitHubStatus(item.GitHubStatus, vars)
+			pi.Kind = PlanKindGitHubStatus
+			pi.Steps = []PlanStep{{
+				Name:     gs.Name,
+				Instance: "github",
+				Job:      fmt.Sprintf("%s/%s", gs.Owner, gs.Repo),
+				Params:   gitHubStatusParams(gs),
+			}}
+		case item.IsCommand():
+			pi.Kind = PlanKindCommand
+			pi.Steps = []PlanStep{{
+				Name:     item.Command.Name,
+				Instance: "local",
+				Job:      displayCommandLine(item.Command, vars),
+			}}
+		case item.IsParallel():
+			pi.Kind = PlanKindParallel
+			for j, step := range item.Parallel.Steps {
+				ps, err := planStep(cfg, step, vars, cfg.IsDisabled(i, j))
+				if err != nil {
+					return nil, err
+				}
+				pi.Steps = append(pi.Steps, ps)
+			}
+		default:
+			pi.Kind = PlanKindStep
+			ps, err := planStep(cfg, item.AsStep(), vars, item.Disabled())
+			if err != nil {
+				return nil, err
+			}
+			pi.Steps = []PlanStep{ps}
+		}
+		plan.Items = append(plan.Items, pi)
+	}
+	plan.markDisabled(SkipReasonDisabledInFile, cfg.IsDisabled)
+	return plan, nil
+}
+
+// CheckParams checks the params of every Jenkins step a run will trigger
+// against the step's param_rules, with inputs substituted as BuildPlan does,
+// so bad values are rejected before anything runs. Disabled steps are not
+// checked. Values that reference upstream step outputs or PR fields are
+// checked by the engine when the step is triggered.
+func CheckParams(cfg *config.Config, inputs map[string]string, disabledSet DisabledSet) error {
+	base := cfg.TemplateVars(inputs)
+	for i, item := range cfg.AllItems() {
+		var steps []config.Step
+		switch {
+		case item.IsParallel():
+			steps = item.Parallel.Steps
+		case item.IsStep():
+			steps = []config.Step{item.AsStep()}
+		default:
+			continue
+		}
+		vars := planVars(base, item)
+		for j, step := range steps {
+			if disabledSet.IsDisabled(i, j) || cfg.IsDisabled(i, j) {
+				continue
+			}
+			if err := step.CheckParams(stepParams(step, vars), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarkDisabled flags the steps and items the run will skip because they are
+// in disabledSet. Those disabled in the workflow file are flagged by
+// BuildPlan already.
+func (p *Plan) MarkDisabled(disabledSet DisabledSet) {
+	p.markDisabled(SkipReasonDisabled, disabledSet.IsDisabled)
+}
+
+// markDisabled flags the steps for which disabled is true, with reason, and
+// the items all of whose steps are flagged, keeping flags set earlier.
+func (p *Plan) markDisabled(reason string, disabled func(itemIndex, stepIndex int) bool) {
+	for i := range p.Items {
+		item := &p.Items[i]
+		if item.Kind == PlanKindPRWait {
+			if !item.Skipped && disabled(item.Index, 0) {
+				item.Skipped, item.SkipReason = true, reason
+			}
+			continue
+		}
+		allSkipped := len(item.Steps) > 0
+		for j := range item.Steps {
+			step := &item.Steps[j]
+			if !step.Skipped && disabled(item.Index, j) {
+				step.Skipped, step.SkipReason = true, reason
+			}
+			allSkipped = allSkipped && step.Skipped
+		}
+		item.Skipped = allSkipped
+	}
+}
+
+// skipReason returns why the item's step at stepIndex is skipped without
+// running, or "" when it runs. A nil item skips nothing.
+func (item *PlanItem) skipReason(stepIndex int) string {
+	switch {
+	case item == nil:
+		return ""
+	case item.Kind == PlanKindPRWait:
+		return item.SkipReason
+	case stepIndex < len(item.Steps):
+		return item.Steps[stepIndex].SkipReason
+	}
+	return ""
+}
+
+// String renders the plan one step per line for logging.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, item := range p.Items {
+		prefix := fmt.Sprintf("%d.", item.Index+1)
+		if item.Finally {
+			prefix += " [finally]"
+		}
+		if item.Kind == PlanKindPRWait {
+			fmt.Fprintf(&b, "%s wait for %s in %s/%s to be %s%s\n", prefix, describePRTarget(item.PRWait), item.PRWait.Owner, item.PRWait.Repo, item.PRWait.WaitFor, skippedSuffix(item.Skipped))
+			continue
+		}
+		for _, s := range item.Steps {
+			name := s.Name
+			if item.Kind == PlanKindParallel && item.Name != "" {
+				name = item.Name + " / " + s.Name
+			}
+			fmt.Fprintf(&b, "%s %s: %s %s%s%s\n", prefix, name, s.Instance, s.Job, formatParams(s.Params), skippedSuffix(s.Skipped))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func skippedSuffix(skipped bool) string {
+	if skipped {
+		return " (skipped)"
+	}
+	return ""
+}
+
+func formatParams(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + params[k]
+	}
+	return " [" + strings.Join(parts, " ") + "]"
+}
+
+// planStep resolves step for the plan. The instance of a disabled step may be
+// unknown, since the step never runs.
+func planStep(cfg *config.Config, step config.Step, vars map[string]string, disabled bool) (PlanStep, error) {
+	instanceCfg, ok := cfg.Instances[step.Instance]
+	if !ok && !disabled {
+		return PlanStep{}, fmt.Errorf("step %q: unknown instance %q", step.Name, step.Instance)
+	}
+	return PlanStep{
+		Name:        step.Name,
+		Instance:    step.Instance,
+		InstanceURL: instanceCfg.URL,
+		Job:         stepJob(step, vars),
+		Params:      step.MaskParams(stepParams(step, vars)),
+	}, nil
+}
+
+// planVars returns base (inputs and vars) plus a self-referencing placeholder for
+// every step output or PR field referenced by item, so substitution keeps
+// ${steps.<id>.<field>} and ${pr.<id>.<field>} intact.
+func planVars(base map[string]string, item config.WorkflowItem) map[string]string {
+	vars := make(map[string]string, len(base))
+	for k, v := range base {
+		vars[k] = v
+	}
+	var values []string
+	switch {
+	case item.IsGitHubStatus():
+		gs := item.GitHubStatus
+		values = []string{gs.Owner, gs.Repo, gs.SHA, gs.State, gs.Context, gs.Description, gs.TargetURL, gs.Comment}
+	case item.IsCommand():
+		values = []string{item.Command.Run}
+	case item.IsParallel():
+		for _, step := range item.Parallel.Steps {
+			values = append(values, step.Job)
+			for _, v := range step.Params {
+				values = append(values, v)
+			}
+		}
+	default:
+		values = append(values, item.Job)
+		for _, v := range item.Params {
+			values = append(values, v)
+		}
+	}
+	for _, v := range values {
+		for _, name := range config.FindTemplateVars(v) {
+			if strings.HasPrefix(name, "steps.") || strings.HasPrefix(name, prRefPrefix) {
+				vars[name] = "${" + name + "}"
+			}
+		}
+	}
+	return vars
+}
+
+// unresolvedPRRef returns the first ${pr.<id>.<field>} reference in a step's
+// job or params that vars has no value for, or "" if there is none.
+func unresolvedPRRef(step config.Step, vars map[string]string) string {
+	values := []string{step.Job}
+	keys := make([]string, 0, len(step.Params))
+	for k := range step.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		values = append(values, step.Params[k])
+	}
+	for _, v := range values {
+		for _, name := range config.FindTemplateVars(v) {
+			if _, ok := vars[name]; !ok && strings.HasPrefix(name, prRefPrefix) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// stepParams substitutes vars into a step's params. Shared by the engine and
+// BuildPlan so the plan shows exactly what will be sent to Jenkins.
+func stepParams(step config.Step, vars map[string]string) map[string]string {
+	params := make(map[string]string, len(step.Params))
+	for k, v := range step.Params {
+		params[k] = config.Substitute(v, vars)
+	}
+	return params
+}
+
+// stepJob substitutes vars into a step's job path.
+func stepJob(step config.Step, vars map[string]string) string {
+	if !strings.Contains(step.Job, "${") {
+		return step.Job
+	}
+	return config.Substitute(step.Job, vars)
+}
+
+// resolveGitHubStatus substitutes vars into every templated GitHub status field
+// and applies the default status context.
+func resolveGitHubStatus(gs *config.GitHubStatus, vars map[string]string) config.GitHubStatus {
+	resolved := *gs
+	resolved.Owner = config.Substitute(gs.Owner, vars)
+	resolved.Repo = config.Substitute(gs.Repo, vars)
+	resolved.SHA = config.Substitute(gs.SHA, vars)
+	resolved.State = config.Substitute(gs.State, vars)
+	resolved.Context = config.Substitute(gs.Context, vars)
+	resolved.Description = config.Substitute(gs.Description, vars)
+	resolved.TargetURL = config.Substitute(gs.TargetURL, vars)
+	resolved.Comment = config.Substitute(gs.Comment, vars)
+	if resolved.SHA != "" && resolved.Context == "" {
+		resolved.Context = "jenkins-flow"
+	}
+	return resolved
+}
+
+// gitHubStatusParams lists the non-empty fields a GitHub status item will post.
+func gitHubStatusParams(gs config.GitHubStatus) map[string]string {
+	params := map[string]string{}
+	for k, v := range map[string]string{
+		"sha":         gs.SHA,
+		"state":       gs.State,
+		"context":     gs.Context,
+		"description": gs.Description,
+		"target_url":  gs.TargetURL,
+		"comment":     gs.Comment,
+	} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	if gs.PRNumber > 0 {
+		params["pr_number"] = fmt.Sprintf("%d", gs.PRNumber)
+	}
+	return params
+}
@@ -0,0 +1,201 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+func TestBuildPlan(t *testing.T) {
+	cfg := &config.Config{
+		Instances: map[string]config.Instance{
+			"dev": {URL: "http://dev.example.com"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", ID: "build", Instance: "dev", Job: "/job/build", Params: map[string]string{"BRANCH": "${branch}"}},
+			{Parallel: &config.ParallelGroup{Name: "Deploy", Steps: []config.Step{
+				{Name: "US", Instance: "dev", Job: "/job/deploy", Params: map[string]string{"VERSION": "${steps.build.build_number}"}},
+				{Name: "EU", Instance: "dev", Job: "/job/deploy"},
+			}}},
+			{WaitForPR: &config.PRWait{Name: "Merge", Owner: "org", Repo: "app", PRNumber: 12, WaitFor: "merged"}},
+		},
+		Finally: []config.WorkflowItem{
+			{GitHubStatus: &config.GitHubStatus{Name: "Report", Owner: "org", Repo: "app", SHA: "${sha}", State: "success"}},
+		},
+	}
+
+	plan, err := BuildPlan(cfg, map[string]string{"branch": "main", "sha": "abc123"})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	plan.MarkDisabled(DisabledSet{1: {1: true}, 2: {0: true}})
+
+	if len(plan.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(plan.Items))
+	}
+
+	build := plan.Items[0]
+	if build.Kind != PlanKindStep || build.Steps[0].InstanceURL != "http://dev.example.com" {
+		t.Errorf("unexpected build item: %+v", build)
+	}
+	if got := build.Steps[0].Params["BRANCH"]; got != "main" {
+		t.Errorf("expected BRANCH=main, got %q", got)
+	}
+
+	deploy := plan.Items[1]
+	if deploy.Kind != PlanKindParallel || len(deploy.Steps) != 2 {
+		t.Fatalf("unexpected deploy item: %+v", deploy)
+	}
+	if got := deploy.Steps[0].Params["VERSION"]; got != "${steps.build.build_number}" {
+		t.Errorf("expected step output reference to be kept, got %q", got)
+	}
+	if deploy.Steps[0].Skipped || !deploy.Steps[1].Skipped || deploy.Skipped {
+		t.Errorf("expected only EU to be skipped: %+v", deploy)
+	}
+
+	merge := plan.Items[2]
+	if merge.Kind != PlanKindPRWait || merge.PRWait == nil || !merge.Skipped {
+		t.Errorf("unexpected PR wait item: %+v", merge)
+	}
+
+	report := plan.Items[3]
+	if report.Kind != PlanKindGitHubStatus || !report.Finally || report.Index != 3 {
+		t.Errorf("unexpected finally item: %+v", report)
+	}
+	if got := report.Steps[0].Params["sha"]; got != "abc123" {
+		t.Errorf("expected sha=abc123, got %q", got)
+	}
+	if got := report.Steps[0].Params["context"]; got != "jenkins-flow" {
+		t.Errorf("expected default context, got %q", got)
+	}
+
+	out := plan.String()
+	for _, want := range []string{"1. Build: dev /job/build [BRANCH=main]", "2. Deploy / EU: dev /job/deploy (skipped)", "4. [finally] Report"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in plan:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildPlan_Vars(t *testing.T) {
+	cfg := &config.Config{
+		Instances: map[string]config.Instance{"dev": {URL: "http://dev.example.com"}},
+		Inputs:    map[string]string{"version": "1.4"},
+		Vars:      map[string]string{"release": "v${inputs.version}"},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "dev", Job: "/job/build-${vars.release}", Params: map[string]string{"TAG": "${vars.release}", "RAW": "${version}"}},
+		},
+	}
+
+	plan, err := BuildPlan(cfg, map[string]string{"version": "2.0"})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	step := plan.Items[0].Steps[0]
+	if step.Job != "/job/build-v2.0" {
+		t.Errorf("expected job path with vars substituted, got %q", step.Job)
+	}
+	if step.Params["TAG"] != "v2.0" || step.Params["RAW"] != "2.0" {
+		t.Errorf("unexpected params: %v", step.Params)
+	}
+}
+
+func TestBuildPlan_UnknownInstance(t *testing.T) {
+	cfg := &config.Config{
+		Workflow: []config.WorkflowItem{{Name: "Build", Instance: "missing", Job: "/job/build"}},
+	}
+	if _, err := BuildPlan(cfg, nil); err == nil {
+		t.Fatal("expected error for unknown instance")
+	}
+}
+
+func TestBuildPlan_DisabledInFile(t *testing.T) {
+	off := false
+	cfg := &config.Config{
+		Instances: map[string]config.Instance{"dev": {URL: "http://dev.example.com"}},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "missing", Job: "/job/build", Enabled: &off},
+			{Parallel: &config.ParallelGroup{Name: "Deploy", Steps: []config.Step{
+				{Name: "US", Instance: "dev", Job: "/job/deploy"},
+				{Name: "EU", Instance: "missing", Job: "/job/deploy", Enabled: &off},
+			}}},
+			{Enabled: &off, Parallel: &config.ParallelGroup{Name: "Smoke", Steps: []config.Step{
+				{Name: "Smoke US", Instance: "missing", Job: "/job/smoke"},
+			}}},
+		},
+	}
+
+	plan, err := BuildPlan(cfg, nil)
+	if err != nil {
+		t.Fatalf("expected disabled steps not to need a known instance, got %v", err)
+	}
+	if !plan.Items[0].Skipped || !plan.Items[0].Steps[0].Skipped {
+		t.Errorf("expected the disabled step to be skipped: %+v", plan.Items[0])
+	}
+	if deploy := plan.Items[1]; deploy.Skipped || deploy.Steps[0].Skipped || !deploy.Steps[1].Skipped {
+		t.Errorf("expected only EU to be skipped: %+v", deploy)
+	}
+	if !plan.Items[2].Skipped {
+		t.Errorf("expected the disabled group to be skipped: %+v", plan.Items[2])
+	}
+
+	// Disabling more steps for the run keeps the file's.
+	plan.MarkDisabled(DisabledSet{1: {0: true}})
+	if !plan.Items[0].Skipped || !plan.Items[1].Skipped {
+		t.Errorf("expected the run's disabled steps to add to the file's: %+v", plan.Items[:2])
+	}
+	if got := plan.Items[1].Steps[0].SkipReason; got != SkipReasonDisabled {
+		t.Errorf("expected US to be skipped for the run, got %q", got)
+	}
+	if got := plan.Items[1].Steps[1].SkipReason; got != SkipReasonDisabledInFile {
+		t.Errorf("expected EU to keep the file's skip reason, got %q", got)
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	rules := map[string]config.ParamRule{"VERSION": {Pattern: `^v\d+$`, Required: true}}
+	cfg := &config.Config{
+		Instances: map[string]config.Instance{"dev": {URL: "http://dev.example.com"}},
+		Inputs:    map[string]string{"version": "v2"},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "dev", Job: "/job/build"},
+			{Parallel: &config.ParallelGroup{Name: "Deploy", Steps: []config.Step{
+				{Name: "US", Instance: "dev", Job: "/job/deploy", Params: map[string]string{"VERSION": "${version}"}, ParamRules: rules},
+				{Name: "EU", Instance: "dev", Job: "/job/deploy", Params: map[string]string{"VERSION": "${steps.build.build_number}"}, ParamRules: rules},
+			}}},
+		},
+	}
+
+	if err := CheckParams(cfg, cfg.Inputs, nil); err != nil {
+		t.Fatalf("expected valid params, got %v", err)
+	}
+	bad := map[string]string{"version": "2\nv3"}
+	err := CheckParams(cfg, bad, nil)
+	if err == nil || !strings.Contains(err.Error(), `step "US": param VERSION: value "2\nv3" does not match pattern`) {
+		t.Errorf("expected the bad input to be rejected, got %v", err)
+	}
+	if err := CheckParams(cfg, bad, DisabledSet{1: {0: true}}); err != nil {
+		t.Errorf("expected disabled steps not to be checked, got %v", err)
+	}
+}
+
+func TestBuildPlan_SecretParams(t *testing.T) {
+	cfg := &config.Config{
+		Instances: map[string]config.Instance{"dev": {URL: "http://dev.example.com"}},
+		Inputs:    map[string]string{"key": "s3cr3t"},
+		Workflow: []config.WorkflowItem{
+			{Name: "Deploy", Instance: "dev", Job: "/job/deploy", Params: map[string]string{"KEY": "${key}", "ENV": "prod"}, SecretParams: []string{"KEY"}},
+		},
+	}
+	plan, err := BuildPlan(cfg, cfg.Inputs)
+	if err != nil {
+		t.Fatalf("BuildPlan failed: %v", err)
+	}
+	if params := plan.Items[0].Steps[0].Params; params["KEY"] != config.SecretMask || params["ENV"] != "prod" {
+		t.Errorf("expected only the secret param to be masked, got %v", params)
+	}
+	if text := plan.String(); strings.Contains(text, "s3cr3t") {
+		t.Errorf("expected the plan text not to show the secret, got:\n%s", text)
+	}
+}
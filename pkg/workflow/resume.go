@@ -0,0 +1,24 @@
+package workflow
+
+// StepProgress is how far a Jenkins step got in an earlier process. Persist it
+// from OnStepQueued, OnStepBuildStarted and OnStepComplete so a restarted run
+// can pick the step up instead of triggering it again.
+type StepProgress struct {
+	QueueURL    string // Set once the job was triggered
+	BuildURL    string // Set once the queued build started
+	Result      string // Set once the build finished
+	BuildNumber int
+}
+
+// ResumeState is the recorded progress of an interrupted run, keyed like
+// DisabledSet by itemIndex then stepIndex.
+type ResumeState map[int]map[int]StepProgress
+
+// progress returns the recorded progress for a step; the zero value means the
+// step never started.
+func (r ResumeState) progress(itemIndex, stepIndex int) StepProgress {
+	if steps, ok := r[itemIndex]; ok {
+		return steps[stepIndex]
+	}
+	return StepProgress{}
+}
@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+// ErrStopped is returned when a graceful stop halted the workflow before all
+// of its items ran.
+var ErrStopped = errors.New("workflow stopped")
+
+// SkipReasonStopped explains an item that was not started because the
+// workflow was stopped gracefully.
+const SkipReasonStopped = "skipped: workflow stopped"
+
+type gracefulStopKey struct{}
+
+// WithGracefulStop returns a copy of ctx and a function that asks a workflow
+// running under it to stop gracefully: the item in progress (including every
+// step of a parallel group) finishes normally, the remaining workflow items
+// are skipped, and the run returns ErrStopped. Finally items still run.
+// Cancel the context instead to stop immediately.
+func WithGracefulStop(ctx context.Context) (context.Context, func()) {
+	requested := new(atomic.Bool)
+	return context.WithValue(ctx, gracefulStopKey{}, requested), func() { requested.Store(true) }
+}
+
+// stopRequested reports whether a graceful stop was requested for ctx.
+func stopRequested(ctx context.Context) bool {
+	requested, _ := ctx.Value(gracefulStopKey{}).(*atomic.Bool)
+	return requested != nil && requested.Load()
+}
+
+// skipItem reports every step of an item that will not run as skipped for
+// reason and returns the matching results.
+func skipItem(i int, item config.WorkflowItem, callbacks WorkflowCallbacks, reason string) []StepResult {
+	switch {
+	case item.IsPRWait():
+		callbacks.OnPRWaitSkipped(i, item.WaitForPR, reason)
+		return []StepResult{{StepName: item.WaitForPR.Name, Result: "SKIPPED", SkipReason: reason}}
+	case item.IsParallel():
+		results := make([]StepResult, len(item.Parallel.Steps))
+		for j, step := range item.Parallel.Steps {
+			callbacks.OnStepSkipped(i, j, step.Name, reason)
+			results[j] = StepResult{StepName: step.Name, Result: "SKIPPED", SkipReason: reason}
+		}
+		return results
+	default:
+		name := item.ItemName()
+		callbacks.OnStepSkipped(i, 0, name, reason)
+		return []StepResult{{StepName: name, Result: "SKIPPED", SkipReason: reason}}
+	}
+}
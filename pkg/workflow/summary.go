@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+// summaryRow is one line of the end-of-run execution summary.
+type summaryRow struct {
+	name     string
+	status   string
+	result   string
+	duration time.Duration
+	buildURL string
+}
+
+// runSummary accumulates StepResults per item for the execution summary table.
+type runSummary struct {
+	rows []summaryRow
+}
+
+// add records the results of a completed (or failed) item. Steps of a parallel
+// group are listed as "group / step".
+func (s *runSummary) add(item config.WorkflowItem, results []StepResult) {
+	for _, r := range results {
+		name := r.StepName
+		if item.IsParallel() && item.Parallel.Name != "" {
+			name = item.Parallel.Name + " / " + r.StepName
+		}
+		s.rows = append(s.rows, summaryRow{
+			name:     name,
+			status:   summaryStatus(r),
+			result:   r.Result,
+			duration: r.Duration,
+			buildURL: r.BuildURL,
+		})
+	}
+}
+
+// addNotRun records an item that was never reached because an earlier item failed.
+func (s *runSummary) addNotRun(item config.WorkflowItem) {
+	name := item.ItemName()
+	if name == "" {
+		name = "(unnamed parallel group)"
+	}
+	s.rows = append(s.rows, summaryRow{name: name, status: "NOT RUN"})
+}
+
+// summaryStatus classifies a step result for the summary's status column.
+func summaryStatus(r StepResult) string {
+	switch {
+	case IsAborted(r.Error), IsBuildAborted(r.Error):
+		return "ABORTED"
+	case r.Result == "SKIPPED":
+		return "SKIPPED"
+	case r.Error != nil || !IsSuccess(r.Result):
+		return "FAILED"
+	default:
+		return "OK"
+	}
+}
+
+// String renders the summary as an aligned table. Failed rows are marked with ✗
+// so the failing step stands out.
+func (s *runSummary) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  \tSTEP\tSTATUS\tRESULT\tDURATION\tBUILD")
+	for _, r := range s.rows {
+		mark := " "
+		switch r.status {
+		case "OK":
+			mark = "✓"
+		case "FAILED":
+			mark = "✗"
+		}
+		duration := "-"
+		if r.duration > 0 {
+			duration = r.duration.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", mark, r.name, r.status, dash(r.result), duration, dash(r.buildURL))
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+)
+
+func TestRunSummary(t *testing.T) {
+	s := &runSummary{}
+	s.add(config.WorkflowItem{Name: "Build"}, []StepResult{
+		{StepName: "Build", Result: "SUCCESS", BuildURL: "http://ci/job/build/7/", Duration: 90 * time.Second},
+	})
+	s.add(config.WorkflowItem{Parallel: &config.ParallelGroup{Name: "Deploy"}}, []StepResult{
+		{StepName: "US", Result: "FAILURE", BuildURL: "http://ci/job/deploy/3/", Duration: 2 * time.Second},
+		{StepName: "EU", Result: ResultAborted, Error: &AbortedError{FailedStep: "US"}},
+		{StepName: "APAC", Result: "SKIPPED"},
+	})
+	s.add(config.WorkflowItem{GitHubStatus: &config.GitHubStatus{Name: "Report"}}, []StepResult{
+		{StepName: "Report", Error: errors.New("boom")},
+	})
+	s.addNotRun(config.WorkflowItem{Name: "Verify"})
+
+	lines := strings.Split(s.String(), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected header + 6 rows, got %d:\n%s", len(lines), s)
+	}
+	want := []struct {
+		prefix string
+		parts  []string
+	}{
+		{"✓", []string{"Build", "OK", "SUCCESS", "1m30s", "http://ci/job/build/7/"}},
+		{"✗", []string{"Deploy / US", "FAILED", "FAILURE", "2s"}},
+		{" ", []string{"Deploy / EU", "ABORTED"}},
+		{" ", []string{"Deploy / APAC", "SKIPPED"}},
+		{"✗", []string{"Report", "FAILED"}},
+		{" ", []string{"Verify", "NOT RUN"}},
+	}
+	for i, w := range want {
+		line := lines[i+1]
+		if !strings.HasPrefix(line, w.prefix) {
+			t.Errorf("row %d: expected prefix %q, got %q", i, w.prefix, line)
+		}
+		for _, part := range w.parts {
+			if !strings.Contains(line, part) {
+				t.Errorf("row %d: expected %q in %q", i, part, line)
+			}
+		}
+	}
+}
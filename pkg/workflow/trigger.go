@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/jenkins"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+// TriggerResult is the outcome of TriggerJob. Fields past the point TriggerJob
+// was asked to wait for are left empty.
+type TriggerResult struct {
+	QueueURL    string
+	BuildURL    string
+	BuildNumber int
+	Result      string // The build's result, or ResultQueued or ResultStarted when not waiting for it
+	Tests       *jenkins.TestResults
+}
+
+// TriggerJob triggers job on inst outside of any workflow, with the
+// instance's default_params beneath params, and waits until the build reaches
+// wait, one of the step wait modes. A build that finishes with a failing result
+// is not an error; its result is returned. Jenkins is polled every
+// pollInterval, or at the client's built-in intervals when it is 0. The
+// secrets it registers for redaction are dropped when it returns.
+func TriggerJob(ctx context.Context, inst config.Instance, job string, params map[string]string, wait string, pollInterval time.Duration, l *logger.Logger) (*TriggerResult, error) {
+	l = l.WithSecrets()
+	client, err := newJenkinsClient(inst, l)
+	if err != nil {
+		return nil, err
+	}
+	client.PollInterval = pollInterval
+
+	jobParams := make(map[string]string, len(inst.DefaultParams)+len(params))
+	for k, v := range inst.DefaultParams {
+		jobParams[k] = v
+	}
+	for k, v := range params {
+		jobParams[k] = v
+	}
+
+	l.Infof("Triggering job %s", job)
+	queueItemURL, err := client.TriggerJob(ctx, job, jobParams, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to trigger: %w", err)
+	}
+	res := &TriggerResult{QueueURL: queueItemURL, Result: ResultQueued}
+	if wait == config.WaitQueued {
+		return res, nil
+	}
+
+	buildURL, err := client.WaitForQueue(ctx, queueItemURL)
+	if err != nil {
+		return res, &QueueError{QueueURL: queueItemURL, Err: err}
+	}
+	res.BuildURL, res.Result = buildURL, ResultStarted
+	l.Infof("Job %s started: %s", job, buildURL)
+	if wait == config.WaitStarted {
+		return res, nil
+	}
+
+	result, buildNumber, err := client.WaitForBuild(ctx, buildURL)
+	if err != nil {
+		return res, buildWaitError(client, err)
+	}
+	res.Result, res.BuildNumber = result, buildNumber
+	if tests, err := client.GetTestResults(ctx, buildURL); err != nil {
+		l.Debugf("Could not fetch the test report of %s: %v", buildURL, err)
+	} else {
+		res.Tests = tests
+	}
+	l.Infof("Job %s finished: %s", job, result)
+	return res, nil
+}
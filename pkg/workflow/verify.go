@@ -0,0 +1,199 @@
+package workflow
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/jenkins"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+	"github.com/treaz/jenkins-flow/pkg/notifier"
+)
+
+// verifyJobsTimeout bounds the job checks against a single instance, so one
+// unreachable Jenkins cannot hold up the run for long.
+const verifyJobsTimeout = 15 * time.Second
+
+// JobCheckError is returned by VerifyJobs. Each failure names the step and
+// instance whose job could not be confirmed.
+type JobCheckError struct {
+	Failures []string
+}
+
+func (e *JobCheckError) Error() string {
+	return fmt.Sprintf("job check failed for %d step(s):\n  - %s", len(e.Failures), strings.Join(e.Failures, "\n  - "))
+}
+
+// VerifyJobs checks that the job of every step in plan that will run exists on
+// its instance, before anything is triggered. With cfg.VerifyParams set, it
+// also fetches the parameters each job declares (see checkParams). Checks run
+// concurrently, with verifyJobsTimeout per instance. Jobs whose path depends
+// on an upstream step are only known at trigger time and are not checked. All
+// failures are reported together in a *JobCheckError.
+func VerifyJobs(ctx context.Context, cfg *config.Config, plan *Plan, l *logger.Logger) error {
+	type check struct {
+		step    PlanStep
+		failure string
+	}
+	var checks []*check
+	byInstance := map[string][]*check{}
+	for _, item := range plan.Items {
+		if item.Kind != PlanKindStep && item.Kind != PlanKindParallel {
+			continue
+		}
+		for _, step := range item.Steps {
+			if step.Skipped {
+				continue
+			}
+			if strings.Contains(step.Job, "${") {
+				l.Debugf("  -> [%s] Not checking job %s: it depends on an upstream step", step.Name, step.Job)
+				continue
+			}
+			c := &check{step: step}
+			checks = append(checks, c)
+			byInstance[step.Instance] = append(byInstance[step.Instance], c)
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	if cfg.VerifyParams {
+		l.Infof("Checking the jobs and params of %d step(s) on %d instance(s)...", len(checks), len(byInstance))
+	} else {
+		l.Infof("Checking that %d job(s) exist on %d instance(s)...", len(checks), len(byInstance))
+	}
+
+	var wg sync.WaitGroup
+	for name, instChecks := range byInstance {
+		client, err := newJenkinsClient(cfg.Instances[name], l)
+		if err != nil {
+			for _, c := range instChecks {
+				c.failure = fmt.Sprintf("step %q (instance %q): %v", c.step.Name, name, err)
+			}
+			continue
+		}
+		instCtx, cancel := context.WithTimeout(ctx, verifyJobsTimeout)
+		var instWG sync.WaitGroup
+		for _, c := range instChecks {
+			instWG.Add(1)
+			go func() {
+				defer instWG.Done()
+				if cfg.VerifyParams {
+					c.failure = checkParams(instCtx, client, c.step, cfg.Instances[name].DefaultParams)
+					if c.failure != "" {
+						c.failure = fmt.Sprintf("step %q (instance %q): %s", c.step.Name, name, c.failure)
+					}
+					return
+				}
+				exists, err := client.JobExists(instCtx, c.step.Job)
+				switch {
+				case err != nil:
+					c.failure = fmt.Sprintf("step %q (instance %q): checking job %s: %v", c.step.Name, name, c.step.Job, err)
+				case !exists:
+					c.failure = fmt.Sprintf("step %q (instance %q): job %s not found", c.step.Name, name, c.step.Job)
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instWG.Wait()
+			cancel()
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	for _, c := range checks {
+		if c.failure != "" {
+			failures = append(failures, c.failure)
+		}
+	}
+	if len(failures) > 0 {
+		return &JobCheckError{Failures: failures}
+	}
+	l.Infof("All %d job(s) found.", len(checks))
+	return nil
+}
+
+// checkParams compares the params step sends with the parameters its job
+// declares, and describes what is wrong, or returns "" when nothing is.
+// Params the job does not declare are reported, unless they come from the
+// instance's default_params, which are sent to every job. So are declared
+// parameters the step leaves out that have no default value.
+func checkParams(ctx context.Context, client *jenkins.Client, step PlanStep, instanceDefaults map[string]string) string {
+	declared, err := client.GetJobParameters(ctx, step.Job)
+	if errors.Is(err, jenkins.ErrJobNotFound) {
+		return fmt.Sprintf("job %s not found", step.Job)
+	} else if err != nil {
+		return fmt.Sprintf("checking the params of job %s: %v", step.Job, err)
+	}
+
+	known := make(map[string]bool, len(declared))
+	var missing []string
+	for _, p := range declared {
+		known[p.Name] = true
+		if _, ok := step.Params[p.Name]; !ok && !p.HasDefault {
+			missing = append(missing, p.Name)
+		}
+	}
+	var unknown []string
+	for name := range step.Params {
+		if _, fromInstance := instanceDefaults[name]; !known[name] && !fromInstance {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+
+	var problems []string
+	if len(unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("job %s does not declare %s", step.Job, strings.Join(unknown, ", ")))
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("job %s requires %s (no default value)", step.Job, strings.Join(missing, ", ")))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// VerifyWebhooks posts a check message to the workflow's slack_webhook and to
+// every distinct item notify webhook (see notifier.Notifier.Verify), stopping
+// at the first that does not answer with a 2xx status. Items disabled in the
+// workflow file are not checked.
+func VerifyWebhooks(cfg *config.Config, l *logger.Logger) error {
+	checked := map[string]bool{}
+	verify := func(webhook, location string, sender *notifier.Notifier) error {
+		if webhook == "" || checked[webhook] {
+			return nil
+		}
+		checked[webhook] = true
+		l.Infof("Checking the Slack webhook of %s...", location)
+		if err := sender.Verify(); err != nil {
+			return fmt.Errorf("slack webhook check failed for %s: %w", location, err)
+		}
+		return nil
+	}
+
+	if err := verify(cfg.SlackWebhook, "slack_webhook", notifier.NewFromConfig(cfg)); err != nil {
+		return err
+	}
+	for _, item := range cfg.AllItems() {
+		if item.Notify == nil || item.Disabled() {
+			continue
+		}
+		n := newItemNotifier(cfg, &item, item.ItemName())
+		if n == nil {
+			continue
+		}
+		webhook := cmp.Or(item.Notify.Webhook, cfg.SlackWebhook)
+		if err := verify(webhook, fmt.Sprintf("the notify block of %q", item.ItemName()), n.sender); err != nil {
+			return err
+		}
+	}
+	return nil
+}
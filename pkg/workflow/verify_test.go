@@ -0,0 +1,170 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/treaz/jenkins-flow/pkg/config"
+	"github.com/treaz/jenkins-flow/pkg/logger"
+)
+
+func TestRunWithCallbacks_VerifyJobs(t *testing.T) {
+	var triggered, checked int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/api/json") && strings.HasPrefix(r.URL.Path, "/job/"):
+			atomic.AddInt32(&checked, 1)
+			if r.URL.Path == "/job/test/api/json" {
+				w.Write([]byte(`{"name": "test"}`))
+				return
+			}
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/build"):
+			atomic.AddInt32(&triggered, 1)
+			http.Error(w, "unexpected trigger", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := &config.Config{
+		VerifyJobs: true,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token"},
+			"down": {URL: down.URL, Token: "user:token"},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/test"},
+			{Parallel: &config.ParallelGroup{Steps: []config.Step{
+				{Name: "Deploy", Instance: "test", Job: "/job/dpeloy"},
+				{Name: "Smoke", Instance: "down", Job: "/job/smoke"},
+				{Name: "Promote", Instance: "test", Job: "/job/${steps.build.build_number}"},
+				{Name: "Disabled", Instance: "test", Job: "/job/missing"},
+			}}},
+		},
+	}
+	disabled := DisabledSet{1: {3: true}}
+
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), nil, disabled)
+	var jobErr *JobCheckError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("expected a *JobCheckError, got %v", err)
+	}
+	if len(jobErr.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %q", jobErr.Failures)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `step "Deploy" (instance "test"): job /job/dpeloy not found`) {
+		t.Errorf("expected missing job attributed to its step and instance, got:\n%s", msg)
+	}
+	if !strings.Contains(msg, `step "Smoke" (instance "down"): checking job /job/smoke`) {
+		t.Errorf("expected unreachable instance attributed to its step, got:\n%s", msg)
+	}
+	if n := atomic.LoadInt32(&checked); n != 2 {
+		t.Errorf("expected 2 job checks (dynamic and disabled jobs skipped), got %d", n)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 0 {
+		t.Errorf("expected no job to be triggered, got %d", n)
+	}
+}
+
+func TestRunWithCallbacks_VerifyParams(t *testing.T) {
+	var triggered int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/build/api/json":
+			w.Write([]byte(`{"property": [{}, {"parameterDefinitions": [
+				{"name": "VERSION", "type": "StringParameterDefinition", "defaultParameterValue": {"value": ""}}
+			]}]}`))
+		case "/job/deploy/api/json":
+			w.Write([]byte(`{"property": [{"parameterDefinitions": [
+				{"name": "VERSION", "type": "StringParameterDefinition", "defaultParameterValue": {"value": "latest"}},
+				{"name": "ARTIFACT", "type": "FileParameterDefinition", "defaultParameterValue": null}
+			]}]}`))
+		case "/job/build/build", "/job/build/buildWithParameters", "/job/deploy/buildWithParameters":
+			atomic.AddInt32(&triggered, 1)
+			http.Error(w, "unexpected trigger", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		VerifyParams: true,
+		Instances: map[string]config.Instance{
+			"test": {URL: server.URL, Token: "user:token", DefaultParams: map[string]string{"REGION": "eu"}},
+		},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/build", Params: map[string]string{"VERSION": "1.2", "REGION": "eu"}},
+			{Name: "Deploy", Instance: "test", Job: "/job/deploy", Params: map[string]string{"VERSION": "1.2", "REGION": "eu", "TAGG": "v1", "DRY_RUN": "true"}},
+			{Name: "Gone", Instance: "test", Job: "/job/gone"},
+		},
+	}
+
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), nil, nil)
+	var jobErr *JobCheckError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("expected a *JobCheckError, got %v", err)
+	}
+	if len(jobErr.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %q", jobErr.Failures)
+	}
+	want := `step "Deploy" (instance "test"): job /job/deploy does not declare DRY_RUN, TAGG; job /job/deploy requires ARTIFACT (no default value)`
+	if jobErr.Failures[0] != want {
+		t.Errorf("expected unknown and missing params, ignoring the instance's default_params, got:\n%s", jobErr.Failures[0])
+	}
+	if !strings.Contains(jobErr.Failures[1], `step "Gone" (instance "test"): job /job/gone not found`) {
+		t.Errorf("expected the missing job to be reported, got:\n%s", jobErr.Failures[1])
+	}
+	if n := atomic.LoadInt32(&triggered); n != 0 {
+		t.Errorf("expected no job to be triggered, got %d", n)
+	}
+}
+
+func TestRunWithCallbacks_VerifyWebhooks(t *testing.T) {
+	var triggered, checks int32
+	jenkins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&triggered, 1)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer jenkins.Close()
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&checks, 1)
+		if r.URL.Path == "/revoked" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("invalid_token"))
+		}
+	}))
+	defer slack.Close()
+
+	cfg := &config.Config{
+		VerifyWebhooks: true,
+		SlackWebhook:   slack.URL + "/ok",
+		Instances:      map[string]config.Instance{"test": {URL: jenkins.URL, Token: "user:token"}},
+		Workflow: []config.WorkflowItem{
+			{Name: "Build", Instance: "test", Job: "/job/build", Notify: &config.ItemNotify{Events: []string{config.NotifyOnFailure}}},
+			{Name: "Deploy", Instance: "test", Job: "/job/deploy", Notify: &config.ItemNotify{Events: []string{config.NotifyOnFailure}, Webhook: slack.URL + "/revoked"}},
+		},
+	}
+
+	err := RunWithCallbacks(context.Background(), cfg, logger.New(logger.Error), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), `slack webhook check failed for the notify block of "Deploy": webhook returned 403 Forbidden: invalid_token`) {
+		t.Fatalf("expected the revoked webhook to fail the run, got %v", err)
+	}
+	if n := atomic.LoadInt32(&checks); n != 2 {
+		t.Errorf("expected each distinct webhook to be checked once, got %d checks", n)
+	}
+	if n := atomic.LoadInt32(&triggered); n != 0 {
+		t.Errorf("expected nothing to be triggered, got %d requests", n)
+	}
+}
@@ -90,10 +90,11 @@ export async function setLogLevel(level) {
 
 /**
  * Stops the currently running workflow.
- * @returns {Promise<{status: string}>}
+ * @param {string} mode - 'now' cancels running builds; 'graceful' lets the current step finish
+ * @returns {Promise<{status: string, mode: string}>}
  */
-export async function stopWorkflow() {
-    const res = await fetch(`${API_BASE}/api/stop`, {
+export async function stopWorkflow(mode = 'now') {
+    const res = await fetch(`${API_BASE}/api/stop?mode=${encodeURIComponent(mode)}`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' }
     });